@@ -23,12 +23,18 @@ CREATE TABLE public.Cluster_risk (
 );
 CREATE TABLE public.Country (
   name character varying NOT NULL UNIQUE,
+  price_index real NOT NULL DEFAULT 1 CHECK (price_index > 0),
   CONSTRAINT Country_pkey PRIMARY KEY (name)
 );
 CREATE TABLE public.Measure (
   name character varying NOT NULL UNIQUE,
   estimatedCost real NOT NULL,
+  cost_model text NOT NULL DEFAULT 'fixed' CHECK (cost_model IN ('fixed', 'per_m2', 'fixed_plus_variable')),
+  cost_per_m2 real NOT NULL DEFAULT 0 CHECK (cost_per_m2 >= 0),
+  carbon_reduction real NOT NULL DEFAULT 0 CHECK (carbon_reduction >= 0 AND carbon_reduction < 1),
+  embodied_carbon real NOT NULL DEFAULT 0 CHECK (embodied_carbon >= 0),
   type USER-DEFINED NOT NULL,
+  deleted_at timestamp with time zone,
   CONSTRAINT Measure_pkey PRIMARY KEY (name)
 );
 CREATE TABLE public.Risk (
@@ -48,11 +54,14 @@ CREATE TABLE public.Shop (
   utm_north double precision NOT NULL,
   utm_east double precision NOT NULL,
   totalRisk real,
+  totalRiskMethod text CHECK (totalRiskMethod IN ('mean', 'max', 'weighted', 'probabilistic')),
+  totalRiskScoringVersion integer,
   taxonomyCoverage real,
   surface real,
   carbonFootprint real,
   cluster_id smallint NOT NULL,
   country character varying NOT NULL,
+  deleted_at timestamp with time zone,
   CONSTRAINT Shop_pkey PRIMARY KEY (id),
   CONSTRAINT Shop_cluster_id_fkey FOREIGN KEY (cluster_id) REFERENCES public.Cluster(id),
   CONSTRAINT Shop_country_fkey FOREIGN KEY (country) REFERENCES public.Country(name)
@@ -60,7 +69,172 @@ CREATE TABLE public.Shop (
 CREATE TABLE public.Shop_measure (
   shop_id smallint NOT NULL,
   measure_name text NOT NULL,
+  status text NOT NULL DEFAULT 'planned' CHECK (status IN ('planned', 'approved', 'in_progress', 'completed', 'verified')),
+  planned_start_date date,
+  planned_end_date date,
+  actual_start_date date,
+  actual_end_date date,
+  actual_cost real CHECK (actual_cost >= 0),
+  notes text,
+  status_updated_at timestamp with time zone NOT NULL DEFAULT now(),
   CONSTRAINT Shop_measure_pkey PRIMARY KEY (shop_id, measure_name),
   CONSTRAINT Shop_measure_measure_name_fkey FOREIGN KEY (measure_name) REFERENCES public.Measure(name),
   CONSTRAINT Shop_measure_shop_id_fkey FOREIGN KEY (shop_id) REFERENCES public.Shop(id)
-);
\ No newline at end of file
+);
+CREATE TABLE public.Risk_scoring_config (
+  version integer GENERATED ALWAYS AS IDENTITY NOT NULL,
+  name text NOT NULL,
+  method text NOT NULL CHECK (method IN ('multiplicative', 'additive_weighted', 'max', 'ipcc')),
+  parameters jsonb NOT NULL,
+  active boolean NOT NULL DEFAULT false,
+  created_at timestamp with time zone NOT NULL DEFAULT now(),
+  CONSTRAINT Risk_scoring_config_pkey PRIMARY KEY (version)
+);
+CREATE UNIQUE INDEX Risk_scoring_config_active_idx ON public.Risk_scoring_config (active) WHERE active;
+CREATE TABLE public.Shop_risk_override (
+  shop_id smallint NOT NULL,
+  risk_id smallint NOT NULL,
+  exposure USER-DEFINED,
+  sensitivity USER-DEFINED,
+  reason text NOT NULL,
+  updated_at timestamp with time zone NOT NULL DEFAULT now(),
+  CONSTRAINT Shop_risk_override_pkey PRIMARY KEY (shop_id, risk_id),
+  CONSTRAINT Shop_risk_override_shop_id_fkey FOREIGN KEY (shop_id) REFERENCES public.Shop(id),
+  CONSTRAINT Shop_risk_override_risk_id_fkey FOREIGN KEY (risk_id) REFERENCES public.Risk(id),
+  CONSTRAINT Shop_risk_override_level_check CHECK (exposure IS NOT NULL OR sensitivity IS NOT NULL)
+);
+CREATE TABLE public.Cluster_risk_scenario (
+  cluster_id smallint NOT NULL,
+  risk_id smallint NOT NULL,
+  scenario text NOT NULL CHECK (scenario IN ('ssp1-2.6', 'ssp2-4.5', 'ssp5-8.5')),
+  horizon smallint NOT NULL,
+  exposure USER-DEFINED,
+  sensitivity USER-DEFINED,
+  consequence USER-DEFINED,
+  probability USER-DEFINED,
+  CONSTRAINT Cluster_risk_scenario_pkey PRIMARY KEY (cluster_id, risk_id, scenario, horizon),
+  CONSTRAINT Cluster_risk_scenario_cluster_risk_fkey FOREIGN KEY (cluster_id, risk_id) REFERENCES public.Cluster_risk(cluster_id, risk_id)
+);
+CREATE TABLE public.Shop_risk_snapshot (
+  id bigint GENERATED ALWAYS AS IDENTITY NOT NULL,
+  shop_id smallint NOT NULL,
+  total_risk real NOT NULL,
+  aggregation_method text NOT NULL,
+  scoring_version integer NOT NULL DEFAULT 0,
+  taxonomy_coverage real NOT NULL DEFAULT 0,
+  risk_coverage real NOT NULL DEFAULT 0,
+  risks jsonb NOT NULL,
+  applied_measures jsonb NOT NULL,
+  trigger text NOT NULL,
+  created_at timestamp with time zone NOT NULL DEFAULT now(),
+  CONSTRAINT Shop_risk_snapshot_pkey PRIMARY KEY (id),
+  CONSTRAINT Shop_risk_snapshot_shop_id_fkey FOREIGN KEY (shop_id) REFERENCES public.Shop(id)
+);
+CREATE INDEX Shop_risk_snapshot_shop_created_idx ON public.Shop_risk_snapshot (shop_id, created_at DESC);
+CREATE TABLE public.User (
+  id bigint GENERATED ALWAYS AS IDENTITY NOT NULL,
+  email text NOT NULL,
+  password text NOT NULL,
+  role text NOT NULL DEFAULT 'viewer' CHECK (role IN ('admin', 'manager', 'viewer')),
+  active boolean NOT NULL DEFAULT true,
+  created_at timestamp with time zone NOT NULL DEFAULT now(),
+  updated_at timestamp with time zone NOT NULL DEFAULT now(),
+  countries jsonb NOT NULL DEFAULT '[]'::jsonb,
+  cluster_ids jsonb NOT NULL DEFAULT '[]'::jsonb,
+  oidc_issuer text,
+  oidc_subject text,
+  CONSTRAINT User_pkey PRIMARY KEY (id),
+  CONSTRAINT User_oidc_identity_check CHECK ((oidc_issuer IS NULL) = (oidc_subject IS NULL))
+);
+CREATE UNIQUE INDEX User_email_idx ON public.User (lower(email));
+CREATE UNIQUE INDEX User_oidc_identity_idx ON public.User (oidc_issuer, oidc_subject) WHERE oidc_subject IS NOT NULL;
+CREATE TABLE public.Auth_session (
+  id bigint GENERATED ALWAYS AS IDENTITY NOT NULL,
+  user_id bigint NOT NULL,
+  created_at timestamp with time zone NOT NULL DEFAULT now(),
+  revoked_at timestamp with time zone,
+  CONSTRAINT Auth_session_pkey PRIMARY KEY (id),
+  CONSTRAINT Auth_session_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.User(id)
+);
+CREATE TABLE public.Refresh_token (
+  id bigint GENERATED ALWAYS AS IDENTITY NOT NULL,
+  session_id bigint NOT NULL,
+  user_id bigint NOT NULL,
+  token_hash text NOT NULL UNIQUE,
+  expires_at timestamp with time zone NOT NULL,
+  used_at timestamp with time zone,
+  created_at timestamp with time zone NOT NULL DEFAULT now(),
+  CONSTRAINT Refresh_token_pkey PRIMARY KEY (id),
+  CONSTRAINT Refresh_token_session_id_fkey FOREIGN KEY (session_id) REFERENCES public.Auth_session(id),
+  CONSTRAINT Refresh_token_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.User(id)
+);
+CREATE TABLE public.Api_key (
+  id bigint GENERATED ALWAYS AS IDENTITY NOT NULL,
+  name text NOT NULL,
+  prefix text NOT NULL,
+  key_hash text NOT NULL UNIQUE,
+  role text NOT NULL CHECK (role IN ('admin', 'manager', 'viewer')),
+  permissions jsonb NOT NULL DEFAULT '[]'::jsonb,
+  rate_limit integer NOT NULL CHECK (rate_limit > 0),
+  created_by bigint,
+  created_at timestamp with time zone NOT NULL DEFAULT now(),
+  expires_at timestamp with time zone,
+  last_used_at timestamp with time zone,
+  revoked_at timestamp with time zone,
+  countries jsonb NOT NULL DEFAULT '[]'::jsonb,
+  cluster_ids jsonb NOT NULL DEFAULT '[]'::jsonb,
+  CONSTRAINT Api_key_pkey PRIMARY KEY (id),
+  CONSTRAINT Api_key_created_by_fkey FOREIGN KEY (created_by) REFERENCES public.User(id) ON DELETE SET NULL
+);
+CREATE TABLE public.Audit_log (
+  id bigint GENERATED ALWAYS AS IDENTITY NOT NULL,
+  actor_user_id bigint,
+  actor_api_key_id bigint,
+  actor_email text,
+  actor_role text,
+  action text NOT NULL,
+  entity_type text NOT NULL,
+  entity_id text NOT NULL,
+  before jsonb,
+  after jsonb,
+  request_id text,
+  created_at timestamp with time zone NOT NULL DEFAULT now(),
+  CONSTRAINT Audit_log_pkey PRIMARY KEY (id)
+);
+CREATE INDEX Audit_log_created_idx ON public.Audit_log (created_at DESC);
+CREATE INDEX Audit_log_entity_idx ON public.Audit_log (entity_type, entity_id);
+CREATE TABLE public.Shop_measure_invoice (
+  id bigint GENERATED ALWAYS AS IDENTITY NOT NULL,
+  shop_id smallint NOT NULL,
+  measure_name text NOT NULL,
+  amount real NOT NULL CHECK (amount > 0),
+  invoice_number text NOT NULL,
+  invoiced_at date NOT NULL,
+  created_at timestamp with time zone NOT NULL DEFAULT now(),
+  CONSTRAINT Shop_measure_invoice_pkey PRIMARY KEY (id),
+  CONSTRAINT Shop_measure_invoice_shop_measure_fkey FOREIGN KEY (shop_id, measure_name) REFERENCES public.Shop_measure(shop_id, measure_name) ON DELETE RESTRICT
+);
+CREATE UNIQUE INDEX Shop_measure_invoice_number_idx ON public.Shop_measure_invoice (shop_id, measure_name, invoice_number);
+CREATE TABLE public.Measure_taxonomy (
+  measure_name character varying NOT NULL,
+  activity_code text NOT NULL,
+  dnsh_climate_mitigation boolean NOT NULL DEFAULT false,
+  dnsh_water boolean NOT NULL DEFAULT false,
+  dnsh_circular_economy boolean NOT NULL DEFAULT false,
+  dnsh_pollution boolean NOT NULL DEFAULT false,
+  dnsh_biodiversity boolean NOT NULL DEFAULT false,
+  notes text,
+  updated_at timestamp with time zone NOT NULL DEFAULT now(),
+  CONSTRAINT Measure_taxonomy_pkey PRIMARY KEY (measure_name),
+  CONSTRAINT Measure_taxonomy_measure_name_fkey FOREIGN KEY (measure_name) REFERENCES public.Measure(name) ON DELETE CASCADE
+);
+CREATE TABLE public.Disclosure_report (
+  checksum text NOT NULL,
+  period_start date NOT NULL,
+  period_end date NOT NULL,
+  countries text[] NOT NULL,
+  cluster_ids bigint[] NOT NULL,
+  report jsonb NOT NULL,
+  created_at timestamp with time zone NOT NULL DEFAULT now(),
+  CONSTRAINT Disclosure_report_pkey PRIMARY KEY (checksum)
+);
@@ -13,6 +13,8 @@ import (
 
 	"github.com/d1mo22/climate-invest-optimizer/backend/internal/application/services"
 	"github.com/d1mo22/climate-invest-optimizer/backend/internal/config"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/infrastructure/oidc"
 	"github.com/d1mo22/climate-invest-optimizer/backend/internal/infrastructure/persistence/postgres"
 	"github.com/d1mo22/climate-invest-optimizer/backend/internal/interfaces/http/handlers"
 	"github.com/d1mo22/climate-invest-optimizer/backend/internal/interfaces/http/middleware"
@@ -57,14 +59,33 @@ func main() {
 	clusterRepo := postgres.NewClusterRepository(db)
 	measureRepo := postgres.NewMeasureRepository(db)
 	riskRepo := postgres.NewRiskRepository(db)
+	countryRepo := postgres.NewCountryRepository(db)
+	scoringConfigRepo := postgres.NewRiskScoringConfigRepository(db)
+	overrideRepo := postgres.NewShopRiskOverrideRepository(db)
+	scenarioRepo := postgres.NewClusterRiskScenarioRepository(db)
+	snapshotRepo := postgres.NewRiskSnapshotRepository(db)
+	userRepo := postgres.NewUserRepository(db)
+	sessionRepo := postgres.NewAuthSessionRepository(db)
+	apiKeyRepo := postgres.NewAPIKeyRepository(db)
+	auditRepo := postgres.NewAuditRepository(db)
+	costRepo := postgres.NewCostRepository(db)
+	taxonomyRepo := postgres.NewTaxonomyRepository(db)
+	analyticsRepo := postgres.NewAnalyticsRepository(db)
+	disclosureRepo := postgres.NewDisclosureRepository(db)
 
 	// Inicializar servicios
-	shopService := services.NewShopService(shopRepo, clusterRepo, riskRepo, measureRepo)
-	clusterService := services.NewClusterService(clusterRepo)
-	measureService := services.NewMeasureService(measureRepo, shopRepo, riskRepo)
-	riskService := services.NewRiskService(riskRepo, clusterRepo)
-	optimizationService := services.NewOptimizationService(shopRepo, measureRepo, riskRepo)
-	dashboardService := services.NewDashboardService(shopRepo)
+	auditService := services.NewAuditService(auditRepo)
+	riskRecalculator := services.NewShopRiskRecalculator(shopRepo, riskRepo, overrideRepo, scenarioRepo, snapshotRepo, taxonomyRepo)
+	riskScoringService := services.NewRiskScoringService(scoringConfigRepo, riskRecalculator, auditService)
+	shopService := services.NewShopService(shopRepo, clusterRepo, riskRepo, measureRepo, overrideRepo, scenarioRepo, snapshotRepo, taxonomyRepo, riskScoringService, auditService)
+	clusterService := services.NewClusterService(clusterRepo, scenarioRepo, riskScoringService, auditService)
+	measureService := services.NewMeasureService(measureRepo, shopRepo, riskRepo, riskScoringService, riskRecalculator, auditService)
+	riskService := services.NewRiskService(riskRepo, clusterRepo, riskScoringService)
+	costService := services.NewCostService(costRepo, shopRepo, auditService)
+	optimizationService := services.NewOptimizationService(shopRepo, measureRepo, riskRepo, countryRepo, overrideRepo, scenarioRepo, riskScoringService, costService, auditService)
+	dashboardService := services.NewDashboardService(shopRepo, snapshotRepo, analyticsRepo, riskScoringService)
+	taxonomyService := services.NewTaxonomyService(taxonomyRepo, shopRepo, measureRepo, riskRepo, overrideRepo, scenarioRepo, riskScoringService, riskRecalculator, auditService)
+	disclosureService := services.NewDisclosureService(disclosureRepo)
 
 	// Inicializar servicio JWT
 	jwtService := middleware.NewJWTService(middleware.JWTConfig{
@@ -72,7 +93,32 @@ func main() {
 		TokenExpiry:   cfg.JWT.TokenExpiry,
 		RefreshExpiry: cfg.JWT.RefreshExpiry,
 		Issuer:        cfg.JWT.Issuer,
-	})
+	}, sessionRepo)
+	authService := services.NewAuthService(userRepo, sessionRepo, jwtService, auditService)
+	userService := services.NewUserService(userRepo, sessionRepo, auditService)
+	apiKeyService := services.NewAPIKeyService(apiKeyRepo, auditService)
+
+	// Inicio de sesión único con OIDC, solo si hay un proveedor de identidad configurado
+	var ssoService services.SSOService
+	if cfg.OIDC.Enabled() {
+		oidcClient := oidc.NewClient(oidc.Config{
+			IssuerURL:    cfg.OIDC.IssuerURL,
+			ClientID:     cfg.OIDC.ClientID,
+			ClientSecret: cfg.OIDC.ClientSecret,
+			RedirectURL:  cfg.OIDC.RedirectURL,
+			Scopes:       cfg.OIDC.Scopes,
+			GroupsClaim:  cfg.OIDC.GroupsClaim,
+		})
+		ssoService = services.NewSSOService(oidcClient, services.OIDCRoleMapping{
+			AdminGroups:   cfg.OIDC.AdminGroups,
+			ManagerGroups: cfg.OIDC.ManagerGroups,
+			ViewerGroups:  cfg.OIDC.ViewerGroups,
+			DefaultRole:   models.UserRole(cfg.OIDC.DefaultRole),
+			CountryGroups: cfg.OIDC.CountryGroups,
+			ClusterGroups: cfg.OIDC.ClusterGroups,
+			GlobalGroups:  cfg.OIDC.GlobalGroups,
+		}, userRepo, sessionRepo, jwtService, auditService)
+	}
 
 	// Inicializar handlers
 	shopHandler := handlers.NewShopHandler(shopService)
@@ -81,6 +127,14 @@ func main() {
 	riskHandler := handlers.NewRiskHandler(riskService)
 	optimizationHandler := handlers.NewOptimizationHandler(optimizationService)
 	dashboardHandler := handlers.NewDashboardHandler(dashboardService)
+	riskScoringHandler := handlers.NewRiskScoringHandler(riskScoringService)
+	authHandler := handlers.NewAuthHandler(authService, ssoService)
+	userHandler := handlers.NewUserHandler(userService)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
+	auditHandler := handlers.NewAuditHandler(auditService)
+	costHandler := handlers.NewCostHandler(costService)
+	taxonomyHandler := handlers.NewTaxonomyHandler(taxonomyService)
+	disclosureHandler := handlers.NewDisclosureHandler(disclosureService)
 	healthHandler := handlers.NewHealthHandler()
 
 	// Crear router
@@ -94,6 +148,14 @@ func main() {
 		RiskHandler:         riskHandler,
 		OptimizationHandler: optimizationHandler,
 		DashboardHandler:    dashboardHandler,
+		RiskScoringHandler:  riskScoringHandler,
+		AuthHandler:         authHandler,
+		UserHandler:         userHandler,
+		APIKeyHandler:       apiKeyHandler,
+		AuditHandler:        auditHandler,
+		CostHandler:         costHandler,
+		TaxonomyHandler:     taxonomyHandler,
+		DisclosureHandler:   disclosureHandler,
 		HealthHandler:       healthHandler,
 		AllowedOrigins:      cfg.Server.AllowedOrigins,
 	}
@@ -104,6 +166,7 @@ func main() {
 		router.SetupSimple(r, routerConfig)
 	} else {
 		routerConfig.JWTService = jwtService
+		routerConfig.APIKeys = apiKeyService
 		router.Setup(r, routerConfig)
 	}
 
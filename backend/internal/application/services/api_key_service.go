@@ -0,0 +1,142 @@
+// Package services contiene la lógica de negocio de la aplicación.
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/authz"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/repository"
+)
+
+const (
+	// apiKeyPrefix identifica las API keys de la plataforma
+	apiKeyPrefix = "cio_"
+	// apiKeyDisplayLength es el número de caracteres de la clave que se guardan en claro para identificarla
+	apiKeyDisplayLength = 12
+	// defaultAPIKeyRateLimit es el límite de peticiones por minuto si no se indica otro
+	defaultAPIKeyRateLimit = 120
+	// apiKeyTouchInterval evita escribir el último uso en cada petición
+	apiKeyTouchInterval = time.Minute
+)
+
+// APIKeyService define las operaciones de gestión y validación de API keys
+type APIKeyService interface {
+	Create(ctx context.Context, createdBy int64, req *models.CreateAPIKeyRequest) (*models.APIKeyCreatedResponse, error)
+	List(ctx context.Context) ([]models.APIKey, error)
+	Revoke(ctx context.Context, id int64) (*models.APIKey, error)
+	Authenticate(ctx context.Context, key string) (*models.APIKey, error)
+}
+
+// apiKeyService implementa APIKeyService
+type apiKeyService struct {
+	apiKeyRepo repository.APIKeyRepository
+	audit      AuditRecorder
+}
+
+// NewAPIKeyService crea una nueva instancia de APIKeyService
+func NewAPIKeyService(apiKeyRepo repository.APIKeyRepository, audit AuditRecorder) APIKeyService {
+	return &apiKeyService{apiKeyRepo: apiKeyRepo, audit: audit}
+}
+
+// Create genera una nueva API key. El valor en claro solo se retorna en esta llamada.
+// La clave no puede ver más datos que quien la crea: sin ámbito propio hereda el suyo.
+func (s *apiKeyService) Create(ctx context.Context, createdBy int64, req *models.CreateAPIKeyRequest) (*models.APIKeyCreatedResponse, error) {
+	for _, perm := range req.Permissions {
+		if !authz.GrantableToAPIKey(req.Role, authz.Permission(perm)) {
+			return nil, models.ErrInvalidInput("El permiso " + perm + " no se puede conceder a una API key con rol " + string(req.Role))
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, models.ErrInvalidInput("La fecha de expiración debe ser futura")
+	}
+
+	scope := authz.Scope{Countries: req.Countries, ClusterIDs: req.ClusterIDs}
+	if creatorScope := authz.ScopeFromContext(ctx); scope.IsGlobal() {
+		scope = creatorScope
+	} else if !scope.Within(creatorScope) {
+		return nil, models.ErrInvalidInput("El ámbito de la API key debe estar dentro del suyo")
+	}
+
+	secret, err := generateRandomToken(32)
+	if err != nil {
+		return nil, models.ErrInternal.WithInternal(err)
+	}
+	value := apiKeyPrefix + secret
+
+	rateLimit := req.RateLimit
+	if rateLimit == 0 {
+		rateLimit = defaultAPIKeyRateLimit
+	}
+
+	key := &models.APIKey{
+		Name:        req.Name,
+		Prefix:      value[:apiKeyDisplayLength],
+		KeyHash:     hashSecret(value),
+		Role:        req.Role,
+		Permissions: req.Permissions,
+		RateLimit:   rateLimit,
+		CreatedBy:   createdBy,
+		ExpiresAt:   req.ExpiresAt,
+		Countries:   scope.Countries,
+		ClusterIDs:  scope.ClusterIDs,
+	}
+	if err := s.apiKeyRepo.Create(ctx, key); err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	s.audit.Record(ctx, models.AuditAPIKeyCreated, models.AuditEntityAPIKey, auditID(key.ID), nil, key)
+
+	return &models.APIKeyCreatedResponse{APIKey: key, Key: value}, nil
+}
+
+// List obtiene todas las API keys, incluidas las revocadas
+func (s *apiKeyService) List(ctx context.Context) ([]models.APIKey, error) {
+	keys, err := s.apiKeyRepo.List(ctx)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	return keys, nil
+}
+
+// Revoke revoca una API key; las peticiones posteriores con ella se rechazan
+func (s *apiKeyService) Revoke(ctx context.Context, id int64) (*models.APIKey, error) {
+	if err := s.apiKeyRepo.Revoke(ctx, id); err != nil {
+		if errors.Is(err, repository.ErrAPIKeyNotFound) {
+			return nil, models.ErrAPIKeyNotFound
+		}
+		return nil, models.ErrDatabase(err)
+	}
+
+	key, err := s.apiKeyRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	if key == nil {
+		return nil, models.ErrAPIKeyNotFound
+	}
+	s.audit.Record(ctx, models.AuditAPIKeyRevoked, models.AuditEntityAPIKey, auditID(id), nil, key)
+	return key, nil
+}
+
+// Authenticate valida una API key y registra su uso
+func (s *apiKeyService) Authenticate(ctx context.Context, value string) (*models.APIKey, error) {
+	key, err := s.apiKeyRepo.GetByHash(ctx, hashSecret(value))
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	now := time.Now()
+	if key == nil || key.RevokedAt != nil || (key.ExpiresAt != nil && now.After(*key.ExpiresAt)) {
+		return nil, models.ErrInvalidAPIKey
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+		if err := s.apiKeyRepo.TouchLastUsed(ctx, key.ID, now); err == nil {
+			key.LastUsedAt = &now
+		}
+		// Si falla el registro del último uso no se rechaza la petición
+	}
+
+	return key, nil
+}
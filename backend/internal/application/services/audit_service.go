@@ -0,0 +1,156 @@
+// Package services contiene la lógica de negocio de la aplicación.
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/audit"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/repository"
+)
+
+const (
+	// auditExportPageSize es el tamaño de página con el que se lee el log al exportarlo
+	auditExportPageSize = 500
+	// maxAuditExportEntries limita el tamaño de una exportación
+	maxAuditExportEntries = 50000
+)
+
+// AuditRecorder registra los cambios realizados por los servicios. El actor y el
+// ID de petición se obtienen del contexto.
+type AuditRecorder interface {
+	Record(ctx context.Context, action models.AuditAction, entityType models.AuditEntityType, entityID string, before, after interface{})
+}
+
+// AuditService define el registro y la consulta del log de auditoría
+type AuditService interface {
+	AuditRecorder
+	List(ctx context.Context, filter *models.AuditFilterRequest) (*models.PaginatedResponse[models.AuditEntry], error)
+	Export(ctx context.Context, filter *models.AuditFilterRequest) ([]models.AuditEntry, error)
+}
+
+// auditService implementa AuditService
+type auditService struct {
+	auditRepo repository.AuditRepository
+}
+
+// NewAuditService crea una nueva instancia de AuditService
+func NewAuditService(auditRepo repository.AuditRepository) AuditService {
+	return &auditService{auditRepo: auditRepo}
+}
+
+// Record guarda una entrada de auditoría con el estado anterior y posterior de la
+// entidad. El cambio ya se ha realizado, por lo que un fallo al registrarlo se
+// deja en el log del servidor en lugar de hacer fallar la operación.
+func (s *auditService) Record(ctx context.Context, action models.AuditAction, entityType models.AuditEntityType, entityID string, before, after interface{}) {
+	actor := audit.ActorFromContext(ctx)
+	entry := &models.AuditEntry{
+		ActorEmail: actor.Email,
+		ActorRole:  actor.Role,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		RequestID:  audit.RequestIDFromContext(ctx),
+	}
+	if actor.UserID != 0 {
+		entry.ActorUserID = &actor.UserID
+	}
+	if actor.APIKeyID != 0 {
+		entry.ActorAPIKeyID = &actor.APIKeyID
+	}
+
+	var err error
+	if entry.Before, err = encodeAuditValue(before); err == nil {
+		entry.After, err = encodeAuditValue(after)
+	}
+	if err == nil {
+		err = s.auditRepo.Create(ctx, entry)
+	}
+	if err != nil {
+		log.Printf("[AUDIT] no se pudo registrar %s %s/%s (request %s): %v", action, entityType, entityID, entry.RequestID, err)
+	}
+}
+
+// List obtiene una página del log de auditoría
+func (s *auditService) List(ctx context.Context, filter *models.AuditFilterRequest) (*models.PaginatedResponse[models.AuditEntry], error) {
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return nil, models.ErrInvalidInput("La fecha 'from' debe ser anterior a 'to'")
+	}
+
+	entries, total, err := s.auditRepo.List(ctx, filter)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	if entries == nil {
+		entries = []models.AuditEntry{}
+	}
+
+	totalPages := int(total) / filter.PageSize
+	if int(total)%filter.PageSize > 0 {
+		totalPages++
+	}
+
+	return &models.PaginatedResponse[models.AuditEntry]{
+		Items: entries,
+		Pagination: models.PaginationMeta{
+			Page:       filter.Page,
+			PageSize:   filter.PageSize,
+			TotalItems: total,
+			TotalPages: totalPages,
+			HasNext:    filter.Page < totalPages,
+			HasPrev:    filter.Page > 1,
+		},
+	}, nil
+}
+
+// Export obtiene todas las entradas que cumplen el filtro, ignorando su paginación
+func (s *auditService) Export(ctx context.Context, filter *models.AuditFilterRequest) ([]models.AuditEntry, error) {
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return nil, models.ErrInvalidInput("La fecha 'from' debe ser anterior a 'to'")
+	}
+
+	page := *filter
+	page.PageSize = auditExportPageSize
+
+	var all []models.AuditEntry
+	for page.Page = 1; ; page.Page++ {
+		entries, total, err := s.auditRepo.List(ctx, &page)
+		if err != nil {
+			return nil, models.ErrDatabase(err)
+		}
+		if total > maxAuditExportEntries {
+			return nil, models.ErrInvalidInput(fmt.Sprintf(
+				"La exportación tendría %d entradas (máximo %d); acote el filtro por fechas", total, maxAuditExportEntries))
+		}
+		all = append(all, entries...)
+		if len(entries) < auditExportPageSize || int64(len(all)) >= total {
+			break
+		}
+	}
+	return all, nil
+}
+
+// encodeAuditValue serializa el estado de una entidad; un valor nulo no se guarda
+func encodeAuditValue(value interface{}) (json.RawMessage, error) {
+	if value == nil {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(encoded, []byte("null")) {
+		return nil, nil
+	}
+	return encoded, nil
+}
+
+// auditID convierte un ID numérico en el identificador de entidad del log
+func auditID(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
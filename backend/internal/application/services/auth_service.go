@@ -0,0 +1,258 @@
+// Package services contiene la lógica de negocio de la aplicación.
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/audit"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// TokenIssuer emite los access tokens firmados de una sesión
+type TokenIssuer interface {
+	GenerateToken(user *models.User, sessionID int64) (string, int64, error)
+	RefreshExpiry() time.Duration
+}
+
+// AuthService define las operaciones de autenticación y gestión de sesiones
+type AuthService interface {
+	Login(ctx context.Context, req *models.LoginRequest) (*models.AuthResponse, error)
+	Register(ctx context.Context, req *models.RegisterRequest) (*models.AuthResponse, error)
+	Refresh(ctx context.Context, refreshToken string) (*models.AuthResponse, error)
+	Logout(ctx context.Context, userID, sessionID int64, allDevices bool) error
+	GetCurrentUser(ctx context.Context, userID int64) (*models.User, error)
+}
+
+// authService implementa AuthService
+type authService struct {
+	userRepo    repository.UserRepository
+	sessionRepo repository.AuthSessionRepository
+	tokens      TokenIssuer
+	audit       AuditRecorder
+}
+
+// NewAuthService crea una nueva instancia de AuthService
+func NewAuthService(
+	userRepo repository.UserRepository,
+	sessionRepo repository.AuthSessionRepository,
+	tokens TokenIssuer,
+	audit AuditRecorder,
+) AuthService {
+	return &authService{
+		userRepo:    userRepo,
+		sessionRepo: sessionRepo,
+		tokens:      tokens,
+		audit:       audit,
+	}
+}
+
+// Login autentica a un usuario e inicia una nueva sesión
+func (s *authService) Login(ctx context.Context, req *models.LoginRequest) (*models.AuthResponse, error) {
+	user, err := s.userRepo.GetByEmail(ctx, req.Email)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	if user == nil {
+		return nil, models.ErrInvalidCredentials
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		return nil, models.ErrInvalidCredentials
+	}
+	if !user.Active {
+		return nil, models.ErrUserInactive
+	}
+
+	return s.startSession(ctx, user)
+}
+
+// Register crea una nueva cuenta de usuario e inicia su primera sesión
+func (s *authService) Register(ctx context.Context, req *models.RegisterRequest) (*models.AuthResponse, error) {
+	exists, err := s.userRepo.EmailExists(ctx, req.Email)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	if exists {
+		return nil, models.ErrDuplicateEmail
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, models.ErrInternal.WithInternal(err)
+	}
+
+	// El registro público nunca concede privilegios: otros roles los asigna un administrador
+	user := &models.User{
+		Email:    req.Email,
+		Password: string(hashedPassword),
+		Role:     models.RoleViewer,
+		Active:   true,
+	}
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	s.recordAsUser(ctx, models.AuditUserRegistered, user, nil)
+
+	return s.startSession(ctx, user)
+}
+
+// Refresh canjea un refresh token por un nuevo par de tokens de la misma sesión.
+// Cada refresh token es de un solo uso: si se presenta uno ya canjeado se asume
+// que ha sido robado y se revoca la sesión completa.
+func (s *authService) Refresh(ctx context.Context, refreshToken string) (*models.AuthResponse, error) {
+	token, err := s.sessionRepo.GetRefreshTokenByHash(ctx, hashSecret(refreshToken))
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	if token == nil {
+		return nil, models.ErrInvalidToken
+	}
+
+	session, err := s.sessionRepo.GetSession(ctx, token.SessionID)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	if session == nil || session.RevokedAt != nil {
+		return nil, models.ErrInvalidToken
+	}
+
+	if token.UsedAt != nil {
+		return nil, s.revokeReusedSession(ctx, session.ID)
+	}
+	if time.Now().After(token.ExpiresAt) {
+		return nil, models.ErrInvalidToken
+	}
+
+	// Marcar como usado de forma atómica: si otra petición lo canjeó antes, es reutilización
+	marked, err := s.sessionRepo.MarkRefreshTokenUsed(ctx, token.ID)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	if !marked {
+		return nil, s.revokeReusedSession(ctx, session.ID)
+	}
+
+	// Recargar el usuario para reflejar cambios de rol desde el último token
+	user, err := s.userRepo.GetByID(ctx, token.UserID)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	if user == nil || !user.Active {
+		_ = s.sessionRepo.RevokeSession(ctx, session.ID)
+		return nil, models.ErrInvalidToken
+	}
+
+	return s.issueTokens(ctx, user, session.ID)
+}
+
+// Logout revoca la sesión actual o, si se indica, todas las sesiones del usuario
+func (s *authService) Logout(ctx context.Context, userID, sessionID int64, allDevices bool) error {
+	if allDevices {
+		if err := s.sessionRepo.RevokeUserSessions(ctx, userID); err != nil {
+			return models.ErrDatabase(err)
+		}
+		return nil
+	}
+
+	if err := s.sessionRepo.RevokeSession(ctx, sessionID); err != nil {
+		return models.ErrDatabase(err)
+	}
+	return nil
+}
+
+// GetCurrentUser obtiene el usuario autenticado
+func (s *authService) GetCurrentUser(ctx context.Context, userID int64) (*models.User, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	if user == nil {
+		return nil, models.ErrUserNotFound
+	}
+
+	// No exponer password
+	user.Password = ""
+	return user, nil
+}
+
+// startSession crea una nueva sesión para el usuario y emite sus tokens
+func (s *authService) startSession(ctx context.Context, user *models.User) (*models.AuthResponse, error) {
+	session := &models.AuthSession{UserID: user.ID}
+	if err := s.sessionRepo.CreateSession(ctx, session); err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	return s.issueTokens(ctx, user, session.ID)
+}
+
+// issueTokens emite un access token y un nuevo refresh token para la sesión
+func (s *authService) issueTokens(ctx context.Context, user *models.User, sessionID int64) (*models.AuthResponse, error) {
+	refreshToken, err := generateRandomToken(32)
+	if err != nil {
+		return nil, models.ErrInternal.WithInternal(err)
+	}
+
+	stored := &models.RefreshToken{
+		SessionID: sessionID,
+		UserID:    user.ID,
+		TokenHash: hashSecret(refreshToken),
+		ExpiresAt: time.Now().Add(s.tokens.RefreshExpiry()),
+	}
+	if err := s.sessionRepo.CreateRefreshToken(ctx, stored); err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+
+	accessToken, expiresAt, err := s.tokens.GenerateToken(user, sessionID)
+	if err != nil {
+		return nil, models.ErrInternal.WithInternal(err)
+	}
+
+	response := &models.AuthResponse{
+		Token:            accessToken,
+		ExpiresAt:        expiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: stored.ExpiresAt.Unix(),
+	}
+	response.User.ID = user.ID
+	response.User.Email = user.Email
+	response.User.Role = user.Role
+	response.User.Countries = user.Countries
+	response.User.ClusterIDs = user.ClusterIDs
+	return response, nil
+}
+
+// recordAsUser registra en auditoría un cambio sobre el propio usuario. Son
+// peticiones sin autenticar, por lo que el actor es el usuario afectado.
+func (s *authService) recordAsUser(ctx context.Context, action models.AuditAction, user *models.User, before interface{}) {
+	ctx = audit.WithActor(ctx, audit.Actor{UserID: user.ID, Email: user.Email, Role: user.Role})
+	s.audit.Record(ctx, action, models.AuditEntityUser, auditID(user.ID), before, user)
+}
+
+// revokeReusedSession revoca una sesión cuyo refresh token se ha reutilizado
+func (s *authService) revokeReusedSession(ctx context.Context, sessionID int64) error {
+	if err := s.sessionRepo.RevokeSession(ctx, sessionID); err != nil {
+		return models.ErrDatabase(err)
+	}
+	return models.ErrRefreshTokenReused
+}
+
+// generateRandomToken genera un token aleatorio opaco con el número de bytes indicado
+func generateRandomToken(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashSecret calcula el hash con el que se guardan los refresh tokens y las API keys
+func hashSecret(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,193 @@
+// Package services contiene el seguimiento del coste real de las medidas.
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/authz"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/repository"
+)
+
+// minCostSamples es el número mínimo de medidas con coste real necesario para corregir
+// el coste de catálogo de una medida o de un tipo de medida
+const minCostSamples = 3
+
+// CostService define las operaciones sobre el coste real de las medidas aplicadas
+type CostService interface {
+	CostEstimator
+	RecordInvoice(ctx context.Context, shopID int64, req *models.RecordInvoiceRequest) (*models.MeasureInvoice, error)
+	ListInvoices(ctx context.Context, shopID int64) ([]models.MeasureInvoice, error)
+	GetVarianceReport(ctx context.Context, groupBy models.CostVarianceGroup) (*models.CostVarianceReport, error)
+}
+
+// costService implementa CostService
+type costService struct {
+	costRepo repository.CostRepository
+	shopRepo repository.ShopRepository
+	audit    AuditRecorder
+}
+
+// NewCostService crea una nueva instancia de CostService
+func NewCostService(costRepo repository.CostRepository, shopRepo repository.ShopRepository, audit AuditRecorder) CostService {
+	return &costService{costRepo: costRepo, shopRepo: shopRepo, audit: audit}
+}
+
+// RecordInvoice registra una factura de una medida de una tienda. El coste real de la
+// medida pasa a ser la suma de sus facturas. Solo se admiten facturas de medidas en
+// ejecución o en fases posteriores.
+func (s *costService) RecordInvoice(ctx context.Context, shopID int64, req *models.RecordInvoiceRequest) (*models.MeasureInvoice, error) {
+	if err := s.checkShop(ctx, shopID); err != nil {
+		return nil, err
+	}
+	if req.InvoicedAt.After(time.Now()) {
+		return nil, models.ErrInvalidInput("invoiced_at no puede ser una fecha futura")
+	}
+
+	measure, err := s.shopRepo.GetShopMeasure(ctx, shopID, req.MeasureName)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	if measure == nil {
+		return nil, models.ErrMeasureNotApplied
+	}
+	if !measure.Status.Reached(models.MeasureStatusInProgress) {
+		return nil, models.ErrMeasureNotStarted
+	}
+
+	invoice := &models.MeasureInvoice{
+		ShopID:        shopID,
+		MeasureName:   req.MeasureName,
+		Amount:        req.Amount,
+		InvoiceNumber: req.InvoiceNumber,
+		InvoicedAt:    req.InvoicedAt,
+	}
+	if err := s.costRepo.CreateInvoice(ctx, invoice); err != nil {
+		if errors.Is(err, repository.ErrDuplicateInvoiceNumber) {
+			return nil, models.ErrDuplicateInvoice
+		}
+		return nil, models.ErrDatabase(err)
+	}
+
+	s.audit.Record(ctx, models.AuditMeasureInvoiced, models.AuditEntityMeasureInvoice, auditID(invoice.ID), nil, invoice)
+	return invoice, nil
+}
+
+// ListInvoices obtiene las facturas de las medidas de una tienda
+func (s *costService) ListInvoices(ctx context.Context, shopID int64) ([]models.MeasureInvoice, error) {
+	if err := s.checkShop(ctx, shopID); err != nil {
+		return nil, err
+	}
+
+	invoices, err := s.costRepo.ListInvoices(ctx, shopID)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	return invoices, nil
+}
+
+// GetVarianceReport compara el coste estimado con el coste real de las medidas
+// completadas con coste real registrado, agrupadas por medida, tipo o país
+func (s *costService) GetVarianceReport(ctx context.Context, groupBy models.CostVarianceGroup) (*models.CostVarianceReport, error) {
+	if groupBy == "" {
+		groupBy = models.CostVarianceByMeasure
+	}
+
+	rows, err := s.costRepo.GetVariance(ctx, groupBy)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+
+	report := &models.CostVarianceReport{
+		GroupBy: groupBy,
+		Groups:  make([]models.CostVarianceRow, 0, len(rows)),
+		Total:   models.CostVarianceRow{Group: "total"},
+	}
+	for _, row := range rows {
+		report.Groups = append(report.Groups, withVariance(row))
+		report.Total.ShopMeasures += row.ShopMeasures
+		report.Total.EstimatedCost += row.EstimatedCost
+		report.Total.ActualCost += row.ActualCost
+	}
+	report.Total = withVariance(report.Total)
+	return report, nil
+}
+
+// EstimateCosts corrige el coste de catálogo de las medidas con la relación entre coste
+// real y estimado observada en el histórico. Se usa el histórico de la propia medida
+// si tiene suficientes muestras y, si no, el de su tipo; sin histórico suficiente se
+// mantiene el coste de catálogo.
+func (s *costService) EstimateCosts(ctx context.Context, measures []models.Measure) ([]models.Measure, []models.MeasureCostEstimate, error) {
+	byMeasure, err := s.costRepo.GetVariance(ctx, models.CostVarianceByMeasure)
+	if err != nil {
+		return nil, nil, models.ErrDatabase(err)
+	}
+	byType, err := s.costRepo.GetVariance(ctx, models.CostVarianceByType)
+	if err != nil {
+		return nil, nil, models.ErrDatabase(err)
+	}
+	measureHistory := indexVariance(byMeasure)
+	typeHistory := indexVariance(byType)
+
+	corrected := make([]models.Measure, len(measures))
+	estimates := make([]models.MeasureCostEstimate, 0, len(measures))
+	for i, m := range measures {
+		estimate := models.MeasureCostEstimate{
+			MeasureName: m.Name,
+			CatalogCost: m.EstimatedCost,
+			Factor:      1,
+		}
+
+		if row, ok := measureHistory[m.Name]; ok {
+			estimate.Basis = models.CostVarianceByMeasure
+			estimate.Samples = row.ShopMeasures
+			estimate.Factor = row.ActualCost / row.EstimatedCost
+		} else if row, ok := typeHistory[string(m.Type)]; ok {
+			estimate.Basis = models.CostVarianceByType
+			estimate.Samples = row.ShopMeasures
+			estimate.Factor = row.ActualCost / row.EstimatedCost
+		}
+
+		// El factor se aplica a las dos componentes del modelo de coste
+		corrected[i] = m
+		corrected[i].EstimatedCost = m.EstimatedCost * estimate.Factor
+		corrected[i].CostPerM2 = m.CostPerM2 * estimate.Factor
+		estimate.EstimatedCost = corrected[i].EstimatedCost
+		estimates = append(estimates, estimate)
+	}
+	return corrected, estimates, nil
+}
+
+// checkShop comprueba que la tienda existe y está dentro del ámbito del usuario
+func (s *costService) checkShop(ctx context.Context, shopID int64) error {
+	shop, err := s.shopRepo.GetByID(ctx, shopID)
+	if err != nil {
+		return models.ErrDatabase(err)
+	}
+	if shop == nil || !authz.ScopeFromContext(ctx).AllowsShop(shop.Country, shop.ClusterID) {
+		return models.ErrShopNotFound
+	}
+	return nil
+}
+
+// indexVariance indexa por grupo las filas con histórico suficiente para corregir costes
+func indexVariance(rows []models.CostVarianceRow) map[string]models.CostVarianceRow {
+	index := make(map[string]models.CostVarianceRow, len(rows))
+	for _, row := range rows {
+		if row.ShopMeasures >= minCostSamples && row.EstimatedCost > 0 {
+			index[row.Group] = row
+		}
+	}
+	return index
+}
+
+// withVariance calcula la desviación absoluta y porcentual del coste real respecto al estimado
+func withVariance(row models.CostVarianceRow) models.CostVarianceRow {
+	row.Variance = row.ActualCost - row.EstimatedCost
+	if row.EstimatedCost > 0 {
+		row.VariancePercentage = row.Variance / row.EstimatedCost * 100
+	}
+	return row
+}
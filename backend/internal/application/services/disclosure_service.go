@@ -0,0 +1,347 @@
+// Package services contiene la generación del informe de divulgación de riesgos físicos.
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/repository"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/scoring"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/taxonomy"
+)
+
+// disclosureScenarios son los escenarios climáticos del análisis de escenarios del informe
+var disclosureScenarios = []models.ScenarioSelection{
+	{Scenario: models.ScenarioCurrent},
+	{Scenario: models.ScenarioSSP126, Horizon: models.Horizon2030},
+	{Scenario: models.ScenarioSSP126, Horizon: models.Horizon2050},
+	{Scenario: models.ScenarioSSP245, Horizon: models.Horizon2030},
+	{Scenario: models.ScenarioSSP245, Horizon: models.Horizon2050},
+	{Scenario: models.ScenarioSSP585, Horizon: models.Horizon2030},
+	{Scenario: models.ScenarioSSP585, Horizon: models.Horizon2050},
+}
+
+// DisclosureService define la generación del informe de divulgación de riesgos físicos
+type DisclosureService interface {
+	Generate(ctx context.Context, query *models.DisclosureReportQuery) (*models.DisclosureReport, error)
+	// Get obtiene un informe generado antes a partir de su suma de comprobación
+	Get(ctx context.Context, checksum string) (*models.DisclosureReport, error)
+}
+
+// disclosureService implementa DisclosureService
+type disclosureService struct {
+	disclosureRepo repository.DisclosureRepository
+}
+
+// NewDisclosureService crea una nueva instancia de DisclosureService
+func NewDisclosureService(disclosureRepo repository.DisclosureRepository) DisclosureService {
+	return &disclosureService{disclosureRepo: disclosureRepo}
+}
+
+// Generate genera el informe de divulgación de las tiendas dentro del ámbito del usuario.
+// La exposición y los escenarios reflejan el estado actual; la inversión facturada, las
+// medidas terminadas y la evolución del riesgo se limitan al periodo. Todos los datos
+// se leen en una misma transacción y el informe se guarda bajo su suma de comprobación.
+func (s *disclosureService) Generate(ctx context.Context, query *models.DisclosureReportQuery) (*models.DisclosureReport, error) {
+	now := time.Now().UTC()
+	period, err := reportingPeriod(query, now)
+	if err != nil {
+		return nil, err
+	}
+	// Fin exclusivo del periodo: el día siguiente al último día incluido
+	periodEnd := period.End.AddDate(0, 0, 1)
+	inPeriod := func(t time.Time) bool {
+		return !t.Before(period.Start) && t.Before(periodEnd)
+	}
+
+	inputs, err := s.disclosureRepo.LoadInputs(ctx, period.Start)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	shops := inputs.Shops
+	scorer := scoring.Default()
+	if inputs.ScoringConfig != nil {
+		scorer, err = scoring.New(*inputs.ScoringConfig)
+		if err != nil {
+			return nil, models.ErrInvalidScoringConfig(err.Error())
+		}
+	}
+	cfg := scorer.Config()
+
+	report := &models.DisclosureReport{
+		Period:      period,
+		GeneratedAt: now,
+		Data: models.DisclosureDataSnapshot{
+			ScoringVersion:    cfg.Version,
+			ScoringMethod:     cfg.Method,
+			AggregationMethod: cfg.Aggregation,
+			Thresholds:        cfg.Thresholds,
+		},
+		ExposureByRisk:    []models.RiskExposure{},
+		ExposureByCountry: []models.CountryExposure{},
+		HighRiskShops:     []models.HighRiskShop{},
+		AdaptationPlan: models.AdaptationPlan{
+			MeasuresByStatus: make(map[models.MeasureStatus]int64),
+			ByType:           []models.AdaptationSpend{},
+		},
+		Scenarios: []models.ScenarioResult{},
+		RiskTrend: []models.RiskTrendPoint{},
+	}
+
+	// Evolución del riesgo dentro del periodo
+	for _, p := range inputs.RiskTrend {
+		if p.Period.Before(periodEnd) {
+			report.RiskTrend = append(report.RiskTrend, p)
+		}
+	}
+
+	// Los datos de entrada se resumen en una suma de comprobación que incluye el periodo
+	// y la metodología, para que identifique un único informe
+	hash := sha256.New()
+	enc := json.NewEncoder(hash)
+	_ = enc.Encode([]interface{}{period, cfg, report.RiskTrend})
+
+	byRisk := make(map[int64]*models.RiskExposure)
+	byCountry := make(map[string]*models.CountryExposure)
+	byType := make(map[models.MeasureType]*models.AdaptationSpend)
+	var totalRisk float64
+
+	for i := range shops {
+		shop := &shops[i]
+		risks := portfolioShopRisks(&inputs.PortfolioShopData, nil, scorer, shop, models.ScenarioSelection{})
+		measures := inputs.ShopMeasures[shop.ID]
+		invoices := inputs.Invoices[shop.ID]
+		hashShopData(enc, shop, risks, measures, invoices)
+
+		report.Data.Shops++
+		report.Data.ShopRisks += int64(len(risks))
+		report.Data.ShopMeasures += int64(len(measures))
+		report.Data.Invoices += int64(len(invoices))
+
+		// Exposición de la tienda
+		shopRisk := scorer.Aggregate(risks)
+		level := scorer.Level(shopRisk)
+		totalRisk += shopRisk
+		if shopRisk > report.Summary.MaxRisk {
+			report.Summary.MaxRisk = shopRisk
+		}
+
+		country := byCountry[shop.Country]
+		if country == nil {
+			country = &models.CountryExposure{Country: shop.Country}
+			byCountry[shop.Country] = country
+		}
+		country.Shops++
+		country.Surface += shop.Surface
+		country.AverageRisk += shopRisk
+		if shopRisk > country.MaxRisk {
+			country.MaxRisk = shopRisk
+		}
+
+		var material []string
+		for _, r := range risks {
+			exposure := byRisk[r.ID]
+			if exposure == nil {
+				exposure = &models.RiskExposure{RiskID: r.ID, RiskName: r.Name, ShopsByLevel: make(map[models.Level]int64)}
+				byRisk[r.ID] = exposure
+			}
+			riskLevel := scorer.Level(r.RiskScore)
+			exposure.ShopsAssessed++
+			exposure.AverageScore += r.RiskScore
+			exposure.ShopsByLevel[riskLevel]++
+			if r.RiskScore > exposure.MaxScore {
+				exposure.MaxScore = r.RiskScore
+			}
+			if isHighLevel(riskLevel) {
+				exposure.HighRiskShops++
+			}
+			if taxonomy.IsMaterial(r) {
+				exposure.MaterialShops++
+				material = append(material, r.Name)
+			}
+		}
+		report.Summary.MaterialRisks += int64(len(material))
+		country.MaterialRisks += int64(len(material))
+
+		// Plan de adaptación de la tienda
+		var pending, completed int64
+		measureTypes := make(map[string]models.MeasureType, len(measures))
+		for _, m := range measures {
+			measureTypes[m.Name] = m.Type
+			spend := byType[m.Type]
+			if spend == nil {
+				spend = &models.AdaptationSpend{Type: m.Type}
+				byType[m.Type] = spend
+			}
+			spend.Measures++
+			report.AdaptationPlan.MeasuresByStatus[m.Status]++
+
+			if !m.Status.IsCompleted() {
+				pending++
+				spend.PlannedInvestment += m.EstimatedCost
+				report.AdaptationPlan.PlannedInvestment += m.EstimatedCost
+				continue
+			}
+			completed++
+			cost := m.EstimatedCost
+			if m.ActualCost != nil {
+				cost = *m.ActualCost
+			}
+			spend.ImplementedInvestment += cost
+			report.AdaptationPlan.ImplementedInvestment += cost
+			if m.ActualEndDate != nil && inPeriod(*m.ActualEndDate) {
+				report.AdaptationPlan.CompletedInPeriod++
+			}
+		}
+		for _, inv := range invoices {
+			if !inPeriod(inv.InvoicedAt) {
+				continue
+			}
+			report.AdaptationPlan.PeriodSpend += inv.Amount
+			if spend := byType[measureTypes[inv.MeasureName]]; spend != nil {
+				spend.PeriodSpend += inv.Amount
+			}
+		}
+
+		if isHighLevel(level) {
+			report.Summary.HighRiskShops++
+			country.HighRiskShops++
+			report.HighRiskShops = append(report.HighRiskShops, models.HighRiskShop{
+				ShopID:            shop.ID,
+				ShopLocation:      shop.Location,
+				Country:           shop.Country,
+				TotalRisk:         shopRisk,
+				RiskLevel:         level,
+				MaterialRisks:     material,
+				PendingMeasures:   pending,
+				CompletedMeasures: completed,
+			})
+		}
+	}
+	report.Data.Checksum = hex.EncodeToString(hash.Sum(nil))
+
+	// Resumen y agrupaciones
+	report.Summary.TotalShops = int64(len(shops))
+	report.Summary.Countries = int64(len(byCountry))
+	if len(shops) > 0 {
+		report.Summary.AverageRisk = totalRisk / float64(len(shops))
+		report.Summary.HighRiskPercentage = float64(report.Summary.HighRiskShops) / float64(len(shops)) * 100
+	}
+	for _, e := range byRisk {
+		e.AverageScore /= float64(e.ShopsAssessed)
+		report.ExposureByRisk = append(report.ExposureByRisk, *e)
+	}
+	sort.Slice(report.ExposureByRisk, func(i, j int) bool {
+		a, b := report.ExposureByRisk[i], report.ExposureByRisk[j]
+		if a.MaterialShops != b.MaterialShops {
+			return a.MaterialShops > b.MaterialShops
+		}
+		return a.RiskName < b.RiskName
+	})
+	for _, c := range byCountry {
+		c.AverageRisk /= float64(c.Shops)
+		report.ExposureByCountry = append(report.ExposureByCountry, *c)
+	}
+	sort.Slice(report.ExposureByCountry, func(i, j int) bool {
+		return report.ExposureByCountry[i].Country < report.ExposureByCountry[j].Country
+	})
+	sort.Slice(report.HighRiskShops, func(i, j int) bool {
+		return report.HighRiskShops[i].TotalRisk > report.HighRiskShops[j].TotalRisk
+	})
+	for _, t := range byType {
+		report.AdaptationPlan.ByType = append(report.AdaptationPlan.ByType, *t)
+	}
+	sort.Slice(report.AdaptationPlan.ByType, func(i, j int) bool {
+		return report.AdaptationPlan.ByType[i].Type < report.AdaptationPlan.ByType[j].Type
+	})
+
+	// Análisis de escenarios
+	for _, sel := range disclosureScenarios {
+		report.Scenarios = append(report.Scenarios, scenarioResult(inputs, scorer, sel))
+	}
+
+	if err := s.disclosureRepo.SaveReport(ctx, report, shops); err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	return report, nil
+}
+
+func (s *disclosureService) Get(ctx context.Context, checksum string) (*models.DisclosureReport, error) {
+	report, err := s.disclosureRepo.GetReport(ctx, checksum)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	if report == nil {
+		return nil, models.ErrDisclosureNotFound
+	}
+	return report, nil
+}
+
+// scenarioResult calcula el riesgo de las tiendas en un escenario climático
+func scenarioResult(inputs *models.DisclosureInputs, scorer scoring.RiskScorer, sel models.ScenarioSelection) models.ScenarioResult {
+	shops := inputs.Shops
+	result := models.ScenarioResult{Scenario: sel}
+	for i := range shops {
+		risks := portfolioShopRisks(&inputs.PortfolioShopData, inputs.Projections[shops[i].ClusterID], scorer, &shops[i], sel)
+
+		shopRisk := scorer.Aggregate(risks)
+		result.AverageRisk += shopRisk
+		if shopRisk > result.MaxRisk {
+			result.MaxRisk = shopRisk
+		}
+		if isHighLevel(scorer.Level(shopRisk)) {
+			result.HighRiskShops++
+		}
+		for _, r := range risks {
+			if taxonomy.IsMaterial(r) {
+				result.MaterialRisks++
+			}
+		}
+	}
+	if len(shops) > 0 {
+		result.AverageRisk /= float64(len(shops))
+	}
+	return result
+}
+
+// hashShopData añade los datos de una tienda a la suma de comprobación de un informe.
+// Se ordenan antes para que la suma no dependa del orden de los repositorios.
+func hashShopData(enc *json.Encoder, shop *models.Shop, risks []models.RiskDetail, measures []models.ShopMeasure, invoices []models.MeasureInvoice) {
+	risks = append([]models.RiskDetail{}, risks...)
+	sort.Slice(risks, func(i, j int) bool { return risks[i].ID < risks[j].ID })
+	measures = append([]models.ShopMeasure{}, measures...)
+	sort.Slice(measures, func(i, j int) bool { return measures[i].Name < measures[j].Name })
+	invoices = append([]models.MeasureInvoice{}, invoices...)
+	sort.Slice(invoices, func(i, j int) bool { return invoices[i].ID < invoices[j].ID })
+
+	_ = enc.Encode([]interface{}{shop, risks, measures, invoices})
+}
+
+// reportingPeriod obtiene el periodo de un informe; por defecto, el año natural en
+// curso hasta hoy
+func reportingPeriod(query *models.DisclosureReportQuery, now time.Time) (models.ReportingPeriod, error) {
+	period := models.ReportingPeriod{
+		Start: time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, time.UTC),
+		End:   time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC),
+	}
+	if query.From != nil {
+		period.Start = query.From.UTC()
+	}
+	if query.To != nil {
+		period.End = query.To.UTC()
+	}
+	if period.End.Before(period.Start) {
+		return period, models.ErrInvalidInput("El final del periodo no puede ser anterior a su inicio")
+	}
+	return period, nil
+}
+
+// isHighLevel indica si un nivel de riesgo es alto o muy alto
+func isHighLevel(level models.Level) bool {
+	return level == models.LevelHigh || level == models.LevelVeryHigh
+}
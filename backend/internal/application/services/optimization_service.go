@@ -6,8 +6,12 @@ import (
 	"sort"
 	"time"
 
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/authz"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/finance"
 	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
 	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/repository"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/scenario"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/scoring"
 )
 
 // OptimizationService define las operaciones de optimización de presupuesto
@@ -15,11 +19,22 @@ type OptimizationService interface {
 	OptimizeBudget(ctx context.Context, req *models.OptimizeBudgetRequest) (*models.OptimizationResult, error)
 }
 
+// CostEstimator corrige el coste de catálogo de las medidas con los costes reales históricos
+type CostEstimator interface {
+	EstimateCosts(ctx context.Context, measures []models.Measure) ([]models.Measure, []models.MeasureCostEstimate, error)
+}
+
 // optimizationService implementa OptimizationService
 type optimizationService struct {
-	shopRepo    repository.ShopRepository
-	measureRepo repository.MeasureRepository
-	riskRepo    repository.RiskRepository
+	shopRepo     repository.ShopRepository
+	measureRepo  repository.MeasureRepository
+	riskRepo     repository.RiskRepository
+	countryRepo  repository.CountryRepository
+	overrideRepo repository.ShopRiskOverrideRepository
+	scenarioRepo repository.ClusterRiskScenarioRepository
+	scorers      scoring.Provider
+	costs        CostEstimator
+	audit        AuditRecorder
 }
 
 // NewOptimizationService crea una nueva instancia
@@ -27,11 +42,23 @@ func NewOptimizationService(
 	shopRepo repository.ShopRepository,
 	measureRepo repository.MeasureRepository,
 	riskRepo repository.RiskRepository,
+	countryRepo repository.CountryRepository,
+	overrideRepo repository.ShopRiskOverrideRepository,
+	scenarioRepo repository.ClusterRiskScenarioRepository,
+	scorers scoring.Provider,
+	costs CostEstimator,
+	audit AuditRecorder,
 ) OptimizationService {
 	return &optimizationService{
-		shopRepo:    shopRepo,
-		measureRepo: measureRepo,
-		riskRepo:    riskRepo,
+		shopRepo:     shopRepo,
+		measureRepo:  measureRepo,
+		riskRepo:     riskRepo,
+		countryRepo:  countryRepo,
+		overrideRepo: overrideRepo,
+		scenarioRepo: scenarioRepo,
+		scorers:      scorers,
+		costs:        costs,
+		audit:        audit,
 	}
 }
 
@@ -41,11 +68,23 @@ type measureCandidate struct {
 	ShopID        int64
 	ShopLocation  string
 	RiskReduction float64
-	Efficiency    float64 // RiskReduction / Cost
+	Value         float64 // Valor del objetivo de optimización
+	Efficiency    float64 // Value / Cost
 	AffectedRisks []string
 	Priority      int
+	AvoidedLoss   float64 // Pérdida anual esperada que evita la medida
+	CarbonSaving  float64 // tCO2e anuales que evita la medida en la tienda
+}
+
+// shopBaseline contiene la situación de partida de una tienda evaluada
+type shopBaseline struct {
+	Losses          map[string]float64 // Pérdida anual esperada de cada riesgo
+	CarbonFootprint float64            // Huella de carbono anual, en tCO2e
 }
 
+// shopBaselines contiene la situación de partida de cada tienda evaluada
+type shopBaselines map[int64]shopBaseline
+
 // OptimizeBudget optimiza la distribución del presupuesto
 // Implementa tres estrategias:
 // 1. Greedy: Selecciona medidas con mejor ratio costo-beneficio
@@ -71,8 +110,38 @@ func (s *optimizationService) OptimizeBudget(ctx context.Context, req *models.Op
 		return nil, models.ErrNoMeasuresAvailable
 	}
 
+	// Costes corregidos con el histórico de costes reales
+	var costEstimates []models.MeasureCostEstimate
+	if req.UseActualCosts {
+		allMeasures, costEstimates, err = s.costs.EstimateCosts(ctx, allMeasures)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Modelo de scoring vigente
+	scorer, err := s.scorers.Current(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Escenario climático sobre el que se evalúan los riesgos
+	sel := scenario.Normalize(models.ScenarioSelection{
+		Scenario: models.ClimateScenario(req.Scenario),
+		Horizon:  req.Horizon,
+	})
+
+	// Parámetros del modelo financiero
+	params := finance.ParametersFromRequest(req)
+
+	// Objetivo que maximizan las estrategias
+	objective := req.Objective
+	if objective == "" {
+		objective = models.ObjectiveRiskReduction
+	}
+
 	// Construir lista de candidatos (medidas por tienda)
-	candidates, err := s.buildCandidates(ctx, req.ShopIDs, allMeasures, req.Priorities)
+	candidates, baselines, err := s.buildCandidates(ctx, req.ShopIDs, allMeasures, req.Priorities, scorer, sel, params, objective)
 	if err != nil {
 		return nil, err
 	}
@@ -98,58 +167,128 @@ func (s *optimizationService) OptimizeBudget(ctx context.Context, req *models.Op
 	}
 
 	// Construir resultado
-	result := s.buildResult(selectedCandidates, req.MaxBudget, strategy, startTime)
+	result := s.buildResult(selectedCandidates, baselines, req.MaxBudget, strategy, startTime, params)
+	result.Scenario = sel
+	result.OptimizationMetrics.Objective = objective
+	result.CostEstimates = costEstimates
+
+	// Las optimizaciones no modifican datos, pero se auditan sus parámetros y su resultado
+	s.audit.Record(ctx, models.AuditOptimizationRun, models.AuditEntityOptimization, strategy, nil, map[string]interface{}{
+		"request":              req,
+		"total_cost":           result.TotalCost,
+		"total_risk_reduction": result.TotalRiskReduction,
+		"measures":             len(result.RecommendedMeasures),
+	})
 
 	return result, nil
 }
 
-// buildCandidates construye la lista de medidas candidatas para cada tienda
+// buildCandidates construye la lista de medidas candidatas para cada tienda y la
+// situación de partida de las tiendas
 func (s *optimizationService) buildCandidates(
 	ctx context.Context,
 	shopIDs []int64,
 	measures []models.Measure,
 	priorities []int64,
-) ([]measureCandidate, error) {
+	scorer scoring.RiskScorer,
+	sel models.ScenarioSelection,
+	params finance.Parameters,
+	objective models.OptimizationObjective,
+) ([]measureCandidate, shopBaselines, error) {
 	var candidates []measureCandidate
+	baselines := make(shopBaselines)
 
 	prioritySet := make(map[int64]bool)
 	for _, p := range priorities {
 		prioritySet[p] = true
 	}
+	priceIndices := make(map[string]float64)
 
 	for _, shopID := range shopIDs {
 		shop, err := s.shopRepo.GetByID(ctx, shopID)
 		if err != nil {
-			return nil, models.ErrDatabase(err)
+			return nil, nil, models.ErrDatabase(err)
 		}
 		if shop == nil {
 			continue
 		}
+		// Las tiendas fuera del ámbito del usuario se tratan como inexistentes
+		if !authz.ScopeFromContext(ctx).AllowsShop(shop.Country, shop.ClusterID) {
+			return nil, nil, models.ErrShopNotFound
+		}
+
+		// Índice de precios del país de la tienda; sin índice se usa el de referencia
+		priceIndex, ok := priceIndices[shop.Country]
+		if !ok {
+			country, err := s.countryRepo.GetByName(ctx, shop.Country)
+			if err != nil {
+				return nil, nil, models.ErrDatabase(err)
+			}
+			priceIndex = 1
+			if country != nil {
+				priceIndex = country.PriceIndex
+			}
+			priceIndices[shop.Country] = priceIndex
+		}
 
 		// Obtener medidas ya aplicadas
 		appliedMeasures, err := s.shopRepo.GetAppliedMeasures(ctx, shopID)
 		if err != nil {
-			return nil, models.ErrDatabase(err)
+			return nil, nil, models.ErrDatabase(err)
 		}
 		appliedSet := make(map[string]bool)
 		for _, m := range appliedMeasures {
 			appliedSet[m.Name] = true
 		}
 
-		// Obtener riesgos del cluster
-		risks, err := s.riskRepo.GetByClusterID(ctx, shop.ClusterID)
+		// Obtener riesgos del cluster en el escenario solicitado con los ajustes propios de la tienda
+		risks, err := projectedClusterRisks(ctx, s.riskRepo, s.scenarioRepo, shop.ClusterID, sel)
 		if err != nil {
-			return nil, models.ErrDatabase(err)
+			return nil, nil, err
+		}
+		overrides, err := s.overrideRepo.GetByShop(ctx, shopID)
+		if err != nil {
+			return nil, nil, models.ErrDatabase(err)
+		}
+		risks = scoreRisks(scorer, applyRiskOverrides(risks, overrides))
+
+		// En escenarios proyectados el riesgo de partida se recalcula; el TotalRisk
+		// guardado corresponde a los niveles actuales
+		currentRisk := shop.TotalRisk
+		if !sel.IsCurrent() {
+			currentRisk = scorer.Aggregate(risks)
+		}
+
+		// Pérdida anual esperada de cada riesgo en el escenario solicitado
+		riskLosses := make(map[string]float64, len(risks))
+		for _, r := range risks {
+			riskLosses[r.Name] = params.ExpectedAnnualLoss(r, shop.Surface)
 		}
+		baselines[shopID] = shopBaseline{Losses: riskLosses, CarbonFootprint: shop.CarbonFootprint}
 
-		for _, measure := range measures {
+		// Riesgos ya cubiertos por las medidas aplicadas a la tienda
+		coveredRisks := make(map[string]bool)
+		for _, m := range appliedMeasures {
+			for _, name := range s.getAffectedRisks(m, risks) {
+				coveredRisks[name] = true
+			}
+		}
+
+		for _, catalogMeasure := range measures {
 			// Saltar si ya está aplicada
-			if appliedSet[measure.Name] {
+			if appliedSet[catalogMeasure.Name] {
+				continue
+			}
+
+			// Coste de la medida en esta tienda según su modelo de coste. Las medidas sin
+			// coste calculable (p. ej. por m² en una tienda sin superficie) se descartan.
+			measure := catalogMeasure.ResolveCost(shop.Surface, priceIndex)
+			if measure.EstimatedCost <= 0 {
 				continue
 			}
 
 			// Calcular reducción de riesgo estimada
-			riskReduction := s.estimateRiskReduction(measure, risks, shop.TotalRisk)
+			riskReduction := s.estimateRiskReduction(measure, risks, currentRisk)
 			if riskReduction <= 0 {
 				continue
 			}
@@ -158,26 +297,63 @@ func (s *optimizationService) buildCandidates(
 			affectedRisks := s.getAffectedRisks(measure, risks)
 
 			// Calcular prioridad
-			priority := s.calculatePriority(measure, risks, prioritySet)
+			priority := s.calculatePriority(measure, risks, prioritySet, scorer)
 
 			candidate := measureCandidate{
 				Measure:       measure,
 				ShopID:        shopID,
 				ShopLocation:  shop.Location,
 				RiskReduction: riskReduction,
-				Efficiency:    riskReduction / measure.EstimatedCost,
 				AffectedRisks: affectedRisks,
 				Priority:      priority,
+				AvoidedLoss: finance.AvoidedAnnualLoss(riskLosses, []finance.Mitigation{
+					{Risks: affectedRisks, Reduction: riskReduction},
+				}),
+				CarbonSaving: finance.AnnualCarbonReduction(measure, shop.CarbonFootprint),
+			}
+
+			// Las medidas que no aportan al objetivo no se consideran
+			candidate.Value = objectiveValue(objective, candidate, coveredRisks, params)
+			if candidate.Value <= 0 {
+				continue
 			}
+			candidate.Efficiency = candidate.Value / measure.EstimatedCost
 			candidates = append(candidates, candidate)
 		}
 	}
 
-	return candidates, nil
+	return candidates, baselines, nil
+}
+
+// objectiveValue calcula el valor de una medida candidata para el objetivo indicado
+func objectiveValue(objective models.OptimizationObjective, c measureCandidate, coveredRisks map[string]bool, params finance.Parameters) float64 {
+	switch objective {
+	case models.ObjectiveAvoidedLoss:
+		return c.AvoidedLoss
+	case models.ObjectiveNPV:
+		return finance.NPV(params.DiscountRate, c.Measure.EstimatedCost, c.AvoidedLoss, params.Years)
+	case models.ObjectiveCoverage:
+		// Riesgos de la tienda que la medida cubre y que aún no estaban cubiertos
+		var count float64
+		for _, name := range c.AffectedRisks {
+			if !coveredRisks[name] {
+				count++
+			}
+		}
+		return count
+	case models.ObjectiveCarbon:
+		return params.NetCarbonReduction(c.CarbonSaving, c.Measure.EmbodiedCarbon)
+	case models.ObjectiveRiskCarbon:
+		// Pérdida evitada y emisiones evitadas, ambas en valor actual
+		return finance.NPV(params.DiscountRate, 0, c.AvoidedLoss, params.Years) +
+			params.CarbonValue(c.CarbonSaving, c.Measure.EmbodiedCarbon)
+	default:
+		return c.RiskReduction
+	}
 }
 
 // greedyOptimization implementa optimización greedy
-// Algoritmo: Ordena por eficiencia (reducción/costo) y selecciona en orden
+// Algoritmo: Ordena por eficiencia (objetivo/costo) y selecciona en orden
 // Complejidad: O(n log n)
 // Ventaja: Rápido y produce buenos resultados en la mayoría de casos
 func (s *optimizationService) greedyOptimization(candidates []measureCandidate, budget float64) []measureCandidate {
@@ -216,7 +392,7 @@ func (s *optimizationService) greedyOptimization(candidates []measureCandidate,
 }
 
 // knapsackOptimization implementa el problema de la mochila 0/1
-// Algoritmo: Programación dinámica para maximizar reducción de riesgo
+// Algoritmo: Programación dinámica para maximizar el objetivo
 // Complejidad: O(n * W) donde W es el presupuesto discretizado
 // Ventaja: Solución óptima garantizada
 func (s *optimizationService) knapsackOptimization(candidates []measureCandidate, budget float64) []measureCandidate {
@@ -229,7 +405,7 @@ func (s *optimizationService) knapsackOptimization(candidates []measureCandidate
 	scale := 100.0
 	W := int(budget / scale)
 
-	// dp[i] = máxima reducción de riesgo con presupuesto i
+	// dp[i] = máximo valor del objetivo con presupuesto i
 	dp := make([]float64, W+1)
 	keep := make([][]bool, n)
 
@@ -239,7 +415,7 @@ func (s *optimizationService) knapsackOptimization(candidates []measureCandidate
 
 	for i := 0; i < n; i++ {
 		cost := int(candidates[i].Measure.EstimatedCost / scale)
-		value := candidates[i].RiskReduction
+		value := candidates[i].Value
 
 		// Iterar de mayor a menor para evitar usar el mismo item múltiples veces
 		for w := W; w >= cost; w-- {
@@ -389,15 +565,15 @@ func (s *optimizationService) getAffectedRisks(measure models.Measure, risks []m
 }
 
 // calculatePriority calcula la prioridad de una medida
-func (s *optimizationService) calculatePriority(measure models.Measure, risks []models.RiskDetail, prioritySet map[int64]bool) int {
+func (s *optimizationService) calculatePriority(measure models.Measure, risks []models.RiskDetail, prioritySet map[int64]bool, scorer scoring.RiskScorer) int {
 	priority := 0
 
 	for _, risk := range risks {
 		if prioritySet[risk.ID] {
 			priority += 10 // Bonus por riesgo prioritario
 		}
-		// Bonus adicional por riesgo alto
-		if risk.RiskScore > 0.7 {
+		// Bonus adicional por riesgo alto (según los umbrales configurados)
+		if level := scorer.Level(risk.RiskScore); level == models.LevelHigh || level == models.LevelVeryHigh {
 			priority += 5
 		}
 	}
@@ -414,51 +590,56 @@ func (s *optimizationService) calculatePriority(measure models.Measure, risks []
 }
 
 // buildResult construye el resultado de optimización
-func (s *optimizationService) buildResult(selected []measureCandidate, budget float64, strategy string, startTime time.Time) *models.OptimizationResult {
+func (s *optimizationService) buildResult(
+	selected []measureCandidate,
+	baselines shopBaselines,
+	budget float64,
+	strategy string,
+	startTime time.Time,
+	params finance.Parameters,
+) *models.OptimizationResult {
 	var totalCost float64
 	var totalReduction float64
+	var objectiveTotal float64
 
 	// Agrupar por tienda
 	shopMeasures := make(map[int64][]measureCandidate)
 	for _, c := range selected {
 		totalCost += c.Measure.EstimatedCost
 		totalReduction += c.RiskReduction
+		objectiveTotal += c.Value
 		shopMeasures[c.ShopID] = append(shopMeasures[c.ShopID], c)
 	}
 
 	// Construir recomendaciones por medida
 	recommendedMeasures := make([]models.RecommendedMeasure, 0, len(selected))
 	for i, c := range selected {
-		recommendedMeasures = append(recommendedMeasures, models.RecommendedMeasure{
-			Measure:        c.Measure,
-			Priority:       i + 1,
-			RiskReduction:  c.RiskReduction * 100,
-			CostEfficiency: c.Efficiency,
-			AffectedRisks:  c.AffectedRisks,
-			Justification:  generateJustification(c),
-		})
+		recommendedMeasures = append(recommendedMeasures, recommendMeasure(c, i+1))
 	}
 
 	// Construir recomendaciones por tienda
+	var totalAvoidedLoss float64
 	shopRecommendations := make([]models.ShopRecommendation, 0, len(shopMeasures))
 	for shopID, candidates := range shopMeasures {
 		var shopCost float64
 		var shopReduction float64
 		measures := make([]models.RecommendedMeasure, 0, len(candidates))
+		mitigations := make([]finance.Mitigation, 0, len(candidates))
+		shopMeasureList := make([]models.Measure, 0, len(candidates))
 
 		for i, c := range candidates {
 			shopCost += c.Measure.EstimatedCost
 			shopReduction += c.RiskReduction
-			measures = append(measures, models.RecommendedMeasure{
-				Measure:        c.Measure,
-				Priority:       i + 1,
-				RiskReduction:  c.RiskReduction * 100,
-				CostEfficiency: c.Efficiency,
-				AffectedRisks:  c.AffectedRisks,
-				Justification:  generateJustification(c),
-			})
+			measures = append(measures, recommendMeasure(c, i+1))
+			mitigations = append(mitigations, finance.Mitigation{Risks: c.AffectedRisks, Reduction: c.RiskReduction})
+			shopMeasureList = append(shopMeasureList, c.Measure)
 		}
 
+		// Las medidas de una misma tienda sobre un mismo riesgo no evitan más pérdida de la esperada
+		baseline := baselines[shopID]
+		shopAvoidedLoss := finance.AvoidedAnnualLoss(baseline.Losses, mitigations)
+		totalAvoidedLoss += shopAvoidedLoss
+
 		shopRecommendations = append(shopRecommendations, models.ShopRecommendation{
 			ShopID:              shopID,
 			ShopLocation:        candidates[0].ShopLocation,
@@ -466,6 +647,8 @@ func (s *optimizationService) buildResult(selected []measureCandidate, budget fl
 			ProjectedRisk:       0,
 			Measures:            measures,
 			EstimatedInvestment: shopCost,
+			Financials:          params.Evaluate(shopCost, sumLosses(baseline.Losses), shopAvoidedLoss),
+			Carbon:              params.CarbonImpact(baseline.CarbonFootprint, shopMeasureList),
 		})
 	}
 
@@ -480,8 +663,30 @@ func (s *optimizationService) buildResult(selected []measureCandidate, budget fl
 		avgReduction = totalReduction / float64(len(selected)) * 100
 	}
 
-	// ROI estimado (simplificado: reducción de riesgo * factor arbitrario)
-	roi := totalReduction * 5 // Asumimos que cada punto de reducción vale 5x la inversión
+	// Indicadores financieros de la cartera: pérdida esperada de todas las tiendas
+	// evaluadas y pérdida evitada por las medidas recomendadas
+	var totalExpectedLoss float64
+	for _, baseline := range baselines {
+		totalExpectedLoss += sumLosses(baseline.Losses)
+	}
+	financials := params.Evaluate(totalCost, totalExpectedLoss, totalAvoidedLoss)
+
+	// Impacto en la huella de carbono de la cartera, incluidas las tiendas sin medidas
+	carbon := params.CarbonImpact(0, nil)
+	for shopID, baseline := range baselines {
+		if _, ok := shopMeasures[shopID]; !ok {
+			carbon = params.AddCarbonImpact(carbon, params.CarbonImpact(baseline.CarbonFootprint, nil))
+		}
+	}
+	for _, rec := range shopRecommendations {
+		carbon = params.AddCarbonImpact(carbon, rec.Carbon)
+	}
+
+	// ROI: VAN obtenido por cada euro invertido
+	roi := 0.0
+	if totalCost > 0 {
+		roi = financials.NPV / totalCost
+	}
 
 	return &models.OptimizationResult{
 		TotalCost:           totalCost,
@@ -494,17 +699,45 @@ func (s *optimizationService) buildResult(selected []measureCandidate, budget fl
 			BudgetUtilization:    budgetUtilization,
 			AverageRiskReduction: avgReduction,
 			ROI:                  roi,
+			ObjectiveValue:       objectiveTotal,
 			ProcessingTimeMs:     time.Since(startTime).Milliseconds(),
+			Financials:           financials,
+			Carbon:               carbon,
 		},
 	}
 }
 
+// recommendMeasure construye la recomendación de una medida candidata
+func recommendMeasure(c measureCandidate, priority int) models.RecommendedMeasure {
+	return models.RecommendedMeasure{
+		Measure:               c.Measure,
+		Priority:              priority,
+		RiskReduction:         c.RiskReduction * 100,
+		CostEfficiency:        c.Efficiency,
+		AffectedRisks:         c.AffectedRisks,
+		AvoidedAnnualLoss:     c.AvoidedLoss,
+		AnnualCarbonReduction: c.CarbonSaving,
+		Justification:         generateJustification(c),
+	}
+}
+
+// sumLosses suma la pérdida anual esperada de los riesgos de una tienda
+func sumLosses(riskLosses map[string]float64) float64 {
+	var total float64
+	for _, loss := range riskLosses {
+		total += loss
+	}
+	return total
+}
+
 // generateJustification genera una justificación textual para la recomendación
 func generateJustification(c measureCandidate) string {
+	// La eficiencia se valora en reducción de riesgo por euro, sea cual sea el objetivo
+	efficiency := c.RiskReduction / c.Measure.EstimatedCost
 	effLevel := "moderada"
-	if c.Efficiency > 0.001 {
+	if efficiency > 0.001 {
 		effLevel = "alta"
-	} else if c.Efficiency < 0.0001 {
+	} else if efficiency < 0.0001 {
 		effLevel = "baja"
 	}
 
@@ -3,35 +3,51 @@ package services
 
 import (
 	"context"
+	"fmt"
+	"log"
+	"time"
 
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/authz"
 	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
 	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/repository"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/scoring"
 )
 
 // ClusterService define las operaciones para clusters
 type ClusterService interface {
 	GetByID(ctx context.Context, id int64) (*models.ClusterWithRisks, error)
 	List(ctx context.Context) ([]models.Cluster, error)
+	GetRiskProjections(ctx context.Context, clusterID int64, scenario models.ClimateScenario) ([]models.ClusterRiskProjection, error)
+	SetRiskProjection(ctx context.Context, clusterID, riskID int64, req *models.SetRiskProjectionRequest) (*models.ClusterRiskProjection, error)
 }
 
 // clusterService implementa ClusterService
 type clusterService struct {
-	clusterRepo repository.ClusterRepository
+	clusterRepo  repository.ClusterRepository
+	scenarioRepo repository.ClusterRiskScenarioRepository
+	scorers      scoring.Provider
+	audit        AuditRecorder
 }
 
 // NewClusterService crea una instancia de ClusterService
-func NewClusterService(repo repository.ClusterRepository) ClusterService {
-	return &clusterService{clusterRepo: repo}
+func NewClusterService(repo repository.ClusterRepository, scenarioRepo repository.ClusterRiskScenarioRepository, scorers scoring.Provider, audit AuditRecorder) ClusterService {
+	return &clusterService{clusterRepo: repo, scenarioRepo: scenarioRepo, scorers: scorers, audit: audit}
 }
 
 func (s *clusterService) GetByID(ctx context.Context, id int64) (*models.ClusterWithRisks, error) {
+	scorer, err := s.scorers.Current(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	cluster, err := s.clusterRepo.GetWithRisks(ctx, id)
 	if err != nil {
 		return nil, models.ErrDatabase(err)
 	}
-	if cluster == nil {
+	if cluster == nil || !authz.ScopeFromContext(ctx).AllowsCluster(id) {
 		return nil, models.ErrClusterNotFound
 	}
+	cluster.Risks = scoreRisks(scorer, cluster.Risks)
 	return cluster, nil
 }
 
@@ -43,10 +59,74 @@ func (s *clusterService) List(ctx context.Context) ([]models.Cluster, error) {
 	return clusters, nil
 }
 
+// GetRiskProjections obtiene las proyecciones de riesgo de un cluster para un escenario
+func (s *clusterService) GetRiskProjections(ctx context.Context, clusterID int64, scenario models.ClimateScenario) ([]models.ClusterRiskProjection, error) {
+	cluster, err := s.clusterRepo.GetByID(ctx, clusterID)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	if cluster == nil || !authz.ScopeFromContext(ctx).AllowsCluster(clusterID) {
+		return nil, models.ErrClusterNotFound
+	}
+
+	projections, err := s.scenarioRepo.GetByCluster(ctx, clusterID, scenario)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	return projections, nil
+}
+
+// SetRiskProjection crea o reemplaza los niveles proyectados de un riesgo del cluster
+func (s *clusterService) SetRiskProjection(ctx context.Context, clusterID, riskID int64, req *models.SetRiskProjectionRequest) (*models.ClusterRiskProjection, error) {
+	if req.Exposure == nil && req.Sensitivity == nil && req.Consequence == nil && req.Probability == nil {
+		return nil, models.ErrInvalidInput("Debe indicar al menos un nivel proyectado")
+	}
+
+	cluster, err := s.clusterRepo.GetWithRisks(ctx, clusterID)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	if cluster == nil || !authz.ScopeFromContext(ctx).AllowsCluster(clusterID) {
+		return nil, models.ErrClusterNotFound
+	}
+
+	// Solo se proyectan riesgos que ya afectan al cluster
+	found := false
+	for _, r := range cluster.Risks {
+		if r.ID == riskID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, models.ErrRiskNotFound
+	}
+
+	projection := &models.ClusterRiskProjection{
+		ClusterID:   clusterID,
+		RiskID:      riskID,
+		Scenario:    req.Scenario,
+		Horizon:     req.Horizon,
+		Exposure:    req.Exposure,
+		Sensitivity: req.Sensitivity,
+		Consequence: req.Consequence,
+		Probability: req.Probability,
+	}
+	if err := s.scenarioRepo.Upsert(ctx, projection); err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	s.audit.Record(ctx, models.AuditRiskProjectionSet, models.AuditEntityClusterProjection,
+		fmt.Sprintf("%d/%d/%s/%d", clusterID, riskID, req.Scenario, req.Horizon), nil, projection)
+	return projection, nil
+}
+
 // MeasureService define las operaciones para medidas
 type MeasureService interface {
 	GetByName(ctx context.Context, name string) (*models.Measure, error)
 	List(ctx context.Context) ([]models.Measure, error)
+	ListIncludingDeleted(ctx context.Context) ([]models.Measure, error)
+	Delete(ctx context.Context, name string) (*models.MeasureChangeResponse, error)
+	Restore(ctx context.Context, name string) (*models.MeasureChangeResponse, error)
 	GetByType(ctx context.Context, measureType models.MeasureType) ([]models.Measure, error)
 	GetApplicableForShop(ctx context.Context, shopID int64) ([]models.Measure, error)
 	GetByRiskID(ctx context.Context, riskID int64) ([]models.Measure, error)
@@ -54,14 +134,31 @@ type MeasureService interface {
 
 // measureService implementa MeasureService
 type measureService struct {
-	measureRepo repository.MeasureRepository
-	shopRepo    repository.ShopRepository
-	riskRepo    repository.RiskRepository
+	measureRepo  repository.MeasureRepository
+	shopRepo     repository.ShopRepository
+	riskRepo     repository.RiskRepository
+	scorers      scoring.Provider
+	recalculator ShopRiskRecalculator
+	audit        AuditRecorder
 }
 
 // NewMeasureService crea una instancia de MeasureService
-func NewMeasureService(measureRepo repository.MeasureRepository, shopRepo repository.ShopRepository, riskRepo repository.RiskRepository) MeasureService {
-	return &measureService{measureRepo: measureRepo, shopRepo: shopRepo, riskRepo: riskRepo}
+func NewMeasureService(
+	measureRepo repository.MeasureRepository,
+	shopRepo repository.ShopRepository,
+	riskRepo repository.RiskRepository,
+	scorers scoring.Provider,
+	recalculator ShopRiskRecalculator,
+	audit AuditRecorder,
+) MeasureService {
+	return &measureService{
+		measureRepo:  measureRepo,
+		shopRepo:     shopRepo,
+		riskRepo:     riskRepo,
+		scorers:      scorers,
+		recalculator: recalculator,
+		audit:        audit,
+	}
 }
 
 func (s *measureService) GetByName(ctx context.Context, name string) (*models.Measure, error) {
@@ -83,6 +180,82 @@ func (s *measureService) List(ctx context.Context) ([]models.Measure, error) {
 	return measures, nil
 }
 
+// ListIncludingDeleted obtiene todas las medidas, también las eliminadas
+func (s *measureService) ListIncludingDeleted(ctx context.Context) ([]models.Measure, error) {
+	measures, err := s.measureRepo.ListIncludingDeleted(ctx)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	return measures, nil
+}
+
+// Delete elimina una medida del catálogo. El borrado es lógico: deja de
+// ofrecerse y de contar en las tiendas donde estaba aplicada, pero sus
+// relaciones se conservan para poder restaurarla. El riesgo guardado de esas
+// tiendas se recalcula sin ella; si el recálculo falla la medida queda
+// eliminada igualmente y el resultado lo indica.
+func (s *measureService) Delete(ctx context.Context, name string) (*models.MeasureChangeResponse, error) {
+	measure, err := s.GetByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.measureRepo.Delete(ctx, name); err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+
+	s.audit.Record(ctx, models.AuditMeasureDeleted, models.AuditEntityMeasure, name, measure, nil)
+
+	// Retornar la medida con su fecha de borrado; si no se puede leer, tal como estaba
+	if deleted, err := s.measureRepo.GetDeletedByName(ctx, name); err == nil && deleted != nil {
+		measure = deleted
+	}
+	return s.recalculateMeasureShops(ctx, measure, models.TriggerMeasureDeleted), nil
+}
+
+// Restore restaura una medida eliminada junto con sus relaciones y recalcula el
+// riesgo guardado de las tiendas donde estaba aplicada. Como en Delete, un fallo
+// del recálculo no deshace la restauración.
+func (s *measureService) Restore(ctx context.Context, name string) (*models.MeasureChangeResponse, error) {
+	measure, err := s.measureRepo.GetDeletedByName(ctx, name)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	if measure == nil {
+		// Distinguir una medida activa de una inexistente
+		if _, err := s.GetByName(ctx, name); err != nil {
+			return nil, err
+		}
+		return nil, models.ErrMeasureNotDeleted
+	}
+
+	if err := s.measureRepo.Restore(ctx, name); err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+
+	before := *measure
+	measure.DeletedAt = nil
+	s.audit.Record(ctx, models.AuditMeasureRestored, models.AuditEntityMeasure, name, before, measure)
+
+	return s.recalculateMeasureShops(ctx, measure, models.TriggerMeasureRestored), nil
+}
+
+// recalculateMeasureShops recalcula las tiendas que tienen aplicada la medida. Se
+// llama después de guardar el cambio de la medida, por lo que un fallo no se
+// devuelve como error sino en el resultado.
+func (s *measureService) recalculateMeasureShops(ctx context.Context, measure *models.Measure, trigger models.SnapshotTrigger) *models.MeasureChangeResponse {
+	result := &models.MeasureChangeResponse{Measure: measure}
+	scorer, err := s.scorers.Current(ctx)
+	if err == nil {
+		err = s.recalculator.RecalculateMeasureShops(ctx, scorer, measure.Name, trigger)
+	}
+	if err != nil {
+		log.Printf("[RISK] no se pudo recalcular las tiendas con la medida %s (%s): %v", measure.Name, trigger, err)
+		result.RecalculationFailed = true
+	}
+	return result
+}
+
 func (s *measureService) GetByType(ctx context.Context, measureType models.MeasureType) ([]models.Measure, error) {
 	measures, err := s.measureRepo.GetByType(ctx, measureType)
 	if err != nil {
@@ -97,7 +270,7 @@ func (s *measureService) GetApplicableForShop(ctx context.Context, shopID int64)
 	if err != nil {
 		return nil, models.ErrDatabase(err)
 	}
-	if shop == nil {
+	if shop == nil || !authz.ScopeFromContext(ctx).AllowsShop(shop.Country, shop.ClusterID) {
 		return nil, models.ErrShopNotFound
 	}
 
@@ -137,11 +310,12 @@ type RiskService interface {
 type riskService struct {
 	riskRepo    repository.RiskRepository
 	clusterRepo repository.ClusterRepository
+	scorers     scoring.Provider
 }
 
 // NewRiskService crea una instancia de RiskService
-func NewRiskService(riskRepo repository.RiskRepository, clusterRepo repository.ClusterRepository) RiskService {
-	return &riskService{riskRepo: riskRepo, clusterRepo: clusterRepo}
+func NewRiskService(riskRepo repository.RiskRepository, clusterRepo repository.ClusterRepository, scorers scoring.Provider) RiskService {
+	return &riskService{riskRepo: riskRepo, clusterRepo: clusterRepo, scorers: scorers}
 }
 
 func (s *riskService) GetByID(ctx context.Context, id int64) (*models.Risk, error) {
@@ -169,36 +343,211 @@ func (s *riskService) GetByCluster(ctx context.Context, clusterID int64) ([]mode
 	if err != nil {
 		return nil, models.ErrDatabase(err)
 	}
-	if cluster == nil {
+	if cluster == nil || !authz.ScopeFromContext(ctx).AllowsCluster(clusterID) {
 		return nil, models.ErrClusterNotFound
 	}
 
+	scorer, err := s.scorers.Current(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	risks, err := s.riskRepo.GetByClusterID(ctx, clusterID)
 	if err != nil {
 		return nil, models.ErrDatabase(err)
 	}
-	return risks, nil
+	return scoreRisks(scorer, risks), nil
 }
 
 // DashboardService proporciona estadísticas del dashboard
 type DashboardService interface {
-	GetStats(ctx context.Context) (*models.DashboardStats, error)
+	GetStats(ctx context.Context, query *models.DashboardStatsQuery) (*models.DashboardStats, error)
+	GetRiskTrend(ctx context.Context, query *models.RiskTrendQuery) ([]models.RiskTrendPoint, error)
+	GetAnalytics(ctx context.Context, query *models.PortfolioAnalyticsQuery) (*models.PortfolioAnalytics, error)
+	GetRiskMatrix(ctx context.Context, query *models.RiskMatrixQuery) (*models.RiskMatrix, error)
 }
 
 // dashboardService implementa DashboardService
 type dashboardService struct {
-	shopRepo repository.ShopRepository
+	shopRepo      repository.ShopRepository
+	snapshotRepo  repository.RiskSnapshotRepository
+	analyticsRepo repository.AnalyticsRepository
+	scorers       scoring.Provider
 }
 
 // NewDashboardService crea una instancia de DashboardService
-func NewDashboardService(shopRepo repository.ShopRepository) DashboardService {
-	return &dashboardService{shopRepo: shopRepo}
+func NewDashboardService(
+	shopRepo repository.ShopRepository,
+	snapshotRepo repository.RiskSnapshotRepository,
+	analyticsRepo repository.AnalyticsRepository,
+	scorers scoring.Provider,
+) DashboardService {
+	return &dashboardService{shopRepo: shopRepo, snapshotRepo: snapshotRepo, analyticsRepo: analyticsRepo, scorers: scorers}
 }
 
-func (s *dashboardService) GetStats(ctx context.Context) (*models.DashboardStats, error) {
-	stats, err := s.shopRepo.GetStats(ctx)
+// GetStats obtiene las estadísticas del dashboard y sus series temporales. Los niveles
+// de riesgo de las tiendas usan los umbrales del scoring vigente, y las tiendas de
+// alto riesgo son las de nivel alto o muy alto.
+func (s *dashboardService) GetStats(ctx context.Context, query *models.DashboardStatsQuery) (*models.DashboardStats, error) {
+	if query.Interval == "" {
+		query.Interval = "month"
+	}
+	if query.From != nil && query.To != nil && query.To.Before(*query.From) {
+		return nil, models.ErrInvalidInput("El final del periodo no puede ser anterior a su inicio")
+	}
+
+	scorer, err := s.scorers.Current(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := s.shopRepo.GetStats(ctx, query, scorer.Config().Thresholds)
 	if err != nil {
 		return nil, models.ErrDatabase(err)
 	}
+	for level, shops := range stats.ShopsByLevel {
+		if isHighLevel(level) {
+			stats.HighRiskShops += shops
+		}
+	}
 	return stats, nil
 }
+
+// GetRiskTrend obtiene la evolución del riesgo medio de las tiendas por periodo.
+// Sin fecha de inicio se devuelve el último año.
+func (s *dashboardService) GetRiskTrend(ctx context.Context, query *models.RiskTrendQuery) ([]models.RiskTrendPoint, error) {
+	since := query.Since
+	if since.IsZero() {
+		since = time.Now().AddDate(-1, 0, 0)
+	}
+
+	points, err := s.snapshotRepo.GetTrend(ctx, query.Interval, since)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	return points, nil
+}
+
+// portfolioHistogramBins es el número de intervalos por defecto del histograma de riesgo
+const portfolioHistogramBins = 10
+
+// GetAnalytics agrega el riesgo, la cobertura y la inversión de las tiendas que cumplen
+// los filtros por país, cluster, riesgo o tipo de medida
+func (s *dashboardService) GetAnalytics(ctx context.Context, query *models.PortfolioAnalyticsQuery) (*models.PortfolioAnalytics, error) {
+	if query.GroupBy == "" {
+		query.GroupBy = models.PortfolioByCountry
+	}
+	if query.Bins == 0 {
+		query.Bins = portfolioHistogramBins
+	}
+
+	groups, err := s.analyticsRepo.GetPortfolioGroups(ctx, query)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+
+	analytics := &models.PortfolioAnalytics{
+		GroupBy: query.GroupBy,
+		Groups:  make([]models.PortfolioGroup, 0, len(groups)),
+	}
+	for _, g := range groups {
+		if g.RiskPairs > 0 {
+			g.CoveragePercentage = float64(g.CoveredPairs) / float64(g.RiskPairs) * 100
+		}
+		analytics.Groups = append(analytics.Groups, g)
+	}
+	return analytics, nil
+}
+
+// matrixLevels son los niveles de los ejes de la matriz de riesgos, de menor a mayor
+var matrixLevels = []models.Level{
+	models.LevelVeryLow,
+	models.LevelLow,
+	models.LevelMedium,
+	models.LevelHigh,
+	models.LevelVeryHigh,
+}
+
+// GetRiskMatrix cuenta los pares tienda-riesgo de cada celda de la matriz de
+// probabilidad y consecuencia, antes o después de las medidas completadas, y detalla
+// los pares de las celdas con la probabilidad o la consecuencia pedidas
+func (s *dashboardService) GetRiskMatrix(ctx context.Context, query *models.RiskMatrixQuery) (*models.RiskMatrix, error) {
+	if query.Stage == "" {
+		query.Stage = models.RiskMatrixResidual
+	}
+
+	pairs, err := s.analyticsRepo.GetRiskMatrixPairs(ctx, query)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+
+	// Filas de mayor a menor probabilidad y columnas de menor a mayor consecuencia,
+	// como se presentan habitualmente en los comités de riesgos
+	matrix := &models.RiskMatrix{Stage: query.Stage, Cells: make([]models.RiskMatrixCell, 0, len(matrixLevels)*len(matrixLevels))}
+	index := make(map[[2]models.Level]int, len(matrixLevels)*len(matrixLevels))
+	for i := len(matrixLevels) - 1; i >= 0; i-- {
+		for _, consequence := range matrixLevels {
+			index[[2]models.Level{matrixLevels[i], consequence}] = len(matrix.Cells)
+			matrix.Cells = append(matrix.Cells, models.RiskMatrixCell{Probability: matrixLevels[i], Consequence: consequence})
+		}
+	}
+
+	drillDown := query.Probability != "" || query.Consequence != ""
+	shops := make(map[int]map[int64]bool)
+	for _, p := range pairs {
+		p.ResidualProbability, p.ResidualConsequence = residualLevels(p)
+		probability, consequence := p.Probability, p.Consequence
+		if query.Stage == models.RiskMatrixResidual {
+			probability, consequence = p.ResidualProbability, p.ResidualConsequence
+		}
+
+		i, ok := index[[2]models.Level{probability, consequence}]
+		if !ok {
+			continue
+		}
+		cell := &matrix.Cells[i]
+		cell.Pairs++
+		matrix.TotalPairs++
+		if shops[i] == nil {
+			shops[i] = make(map[int64]bool)
+		}
+		if !shops[i][p.ShopID] {
+			shops[i][p.ShopID] = true
+			cell.Shops++
+		}
+
+		if drillDown && (query.Probability == "" || query.Probability == probability) &&
+			(query.Consequence == "" || query.Consequence == consequence) {
+			cell.Items = append(cell.Items, p)
+		}
+	}
+	return matrix, nil
+}
+
+// residualLevels estima los niveles de un par tras las medidas completadas que lo
+// reducen. Modelo simplificado: las medidas naturales actúan sobre el peligro y bajan
+// un nivel la probabilidad; las materiales e inmateriales protegen la tienda o
+// preparan la respuesta y bajan un nivel la consecuencia. Varias medidas del mismo
+// tipo no se acumulan.
+func residualLevels(p models.RiskMatrixPair) (probability, consequence models.Level) {
+	probability, consequence = p.Probability, p.Consequence
+	for _, t := range p.MeasureTypes {
+		switch t {
+		case models.MeasureTypeNatural:
+			probability = lowerLevel(p.Probability)
+		case models.MeasureTypeMaterial, models.MeasureTypeImmaterial:
+			consequence = lowerLevel(p.Consequence)
+		}
+	}
+	return probability, consequence
+}
+
+// lowerLevel devuelve el nivel inmediatamente inferior, sin bajar del mínimo
+func lowerLevel(l models.Level) models.Level {
+	for i, level := range matrixLevels {
+		if level == l && i > 0 {
+			return matrixLevels[i-1]
+		}
+	}
+	return l
+}
@@ -0,0 +1,185 @@
+// Package services contiene el recálculo del riesgo guardado de las tiendas.
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/repository"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/scoring"
+)
+
+// ShopRiskRecalculator recalcula el riesgo total y la cobertura de la Taxonomía guardados
+// en las tiendas y registra cada recálculo como snapshot en su historial. Recibe el
+// scorer con el que calcular para que el servicio de scoring pueda recalcular con la
+// versión que acaba de activar.
+type ShopRiskRecalculator interface {
+	Recalculate(ctx context.Context, scorer scoring.RiskScorer, shop *models.Shop, trigger models.SnapshotTrigger) error
+	// RecalculateAll recalcula todas las tiendas activas, sea cual sea el ámbito del usuario
+	RecalculateAll(ctx context.Context, scorer scoring.RiskScorer, trigger models.SnapshotTrigger) error
+	// RecalculateMeasureShops recalcula las tiendas activas que tienen aplicada la medida
+	RecalculateMeasureShops(ctx context.Context, scorer scoring.RiskScorer, measureName string, trigger models.SnapshotTrigger) error
+}
+
+// riskRecalculator implementa ShopRiskRecalculator
+type riskRecalculator struct {
+	shopRepo     repository.ShopRepository
+	riskRepo     repository.RiskRepository
+	overrideRepo repository.ShopRiskOverrideRepository
+	scenarioRepo repository.ClusterRiskScenarioRepository
+	snapshotRepo repository.RiskSnapshotRepository
+	taxonomyRepo repository.TaxonomyRepository
+}
+
+// NewShopRiskRecalculator crea una instancia de ShopRiskRecalculator
+func NewShopRiskRecalculator(
+	shopRepo repository.ShopRepository,
+	riskRepo repository.RiskRepository,
+	overrideRepo repository.ShopRiskOverrideRepository,
+	scenarioRepo repository.ClusterRiskScenarioRepository,
+	snapshotRepo repository.RiskSnapshotRepository,
+	taxonomyRepo repository.TaxonomyRepository,
+) ShopRiskRecalculator {
+	return &riskRecalculator{
+		shopRepo:     shopRepo,
+		riskRepo:     riskRepo,
+		overrideRepo: overrideRepo,
+		scenarioRepo: scenarioRepo,
+		snapshotRepo: snapshotRepo,
+		taxonomyRepo: taxonomyRepo,
+	}
+}
+
+// Recalculate recalcula una tienda
+func (r *riskRecalculator) Recalculate(ctx context.Context, scorer scoring.RiskScorer, shop *models.Shop, trigger models.SnapshotTrigger) error {
+	catalog, err := loadTaxonomyCatalog(ctx, r.taxonomyRepo)
+	if err != nil {
+		return models.ErrDatabase(err)
+	}
+	return r.recalculate(ctx, scorer, catalog, shop, trigger)
+}
+
+func (r *riskRecalculator) RecalculateAll(ctx context.Context, scorer scoring.RiskScorer, trigger models.SnapshotTrigger) error {
+	ids, err := r.shopRepo.ListActiveIDs(ctx)
+	if err != nil {
+		return models.ErrDatabase(err)
+	}
+	return r.recalculateShops(ctx, scorer, ids, trigger)
+}
+
+func (r *riskRecalculator) RecalculateMeasureShops(ctx context.Context, scorer scoring.RiskScorer, measureName string, trigger models.SnapshotTrigger) error {
+	ids, err := r.shopRepo.ListIDsWithMeasure(ctx, measureName)
+	if err != nil {
+		return models.ErrDatabase(err)
+	}
+	return r.recalculateShops(ctx, scorer, ids, trigger)
+}
+
+// recalculateShops recalcula las tiendas indicadas. Un fallo en una tienda no impide
+// recalcular las demás: se registra en el log y se devuelve al terminar.
+func (r *riskRecalculator) recalculateShops(ctx context.Context, scorer scoring.RiskScorer, ids []int64, trigger models.SnapshotTrigger) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	catalog, err := loadTaxonomyCatalog(ctx, r.taxonomyRepo)
+	if err != nil {
+		return models.ErrDatabase(err)
+	}
+
+	var failed int
+	var firstErr error
+	for _, id := range ids {
+		shop, err := r.shopRepo.GetByID(ctx, id)
+		if err == nil && shop != nil {
+			err = r.recalculate(ctx, scorer, catalog, shop, trigger)
+		}
+		if err != nil {
+			log.Printf("[RISK] no se pudo recalcular la tienda %d (%s): %v", id, trigger, err)
+			failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	if failed > 0 {
+		return models.ErrDatabase(fmt.Errorf("failed to recalculate %d of %d shops: %w", failed, len(ids), firstErr))
+	}
+	return nil
+}
+
+// recalculate actualiza el riesgo total y la cobertura de la Taxonomía de una tienda y
+// registra el recálculo como snapshot en su historial
+func (r *riskRecalculator) recalculate(ctx context.Context, scorer scoring.RiskScorer, catalog *taxonomyCatalog, shop *models.Shop, trigger models.SnapshotTrigger) error {
+	risks, _, err := loadShopRisks(ctx, scoring.Static(scorer), r.riskRepo, r.scenarioRepo, r.overrideRepo, shop, models.ScenarioSelection{})
+	if err != nil {
+		return err
+	}
+
+	// Calcular riesgo total con el método de agregación del scorer y registrar la
+	// metodología usada para que el valor siga siendo interpretable
+	cfg := scorer.Config()
+	shop.TotalRisk = scorer.Aggregate(risks)
+	shop.TotalRiskMethod = cfg.Aggregation
+	shop.TotalRiskScoringVersion = cfg.Version
+
+	// Cobertura de la Taxonomía: porcentaje de riesgos materiales reducidos por medidas
+	// implantadas y alineadas
+	report, err := catalog.assess(ctx, r.shopRepo, shop, risks)
+	if err != nil {
+		return models.ErrDatabase(err)
+	}
+	shop.TaxonomyCoverage = report.AlignmentPercentage
+
+	appliedMeasures, err := r.shopRepo.GetCompletedMeasures(ctx, shop.ID)
+	if err != nil {
+		return models.ErrDatabase(err)
+	}
+
+	if err := r.shopRepo.Update(ctx, shop); err != nil {
+		return models.ErrDatabase(err)
+	}
+
+	if err := r.recordRiskSnapshot(ctx, shop, risks, appliedMeasures, trigger); err != nil {
+		return models.ErrDatabase(err)
+	}
+	return nil
+}
+
+// recordRiskSnapshot guarda el estado de riesgo recién calculado de una tienda
+func (r *riskRecalculator) recordRiskSnapshot(
+	ctx context.Context,
+	shop *models.Shop,
+	risks []models.RiskDetail,
+	appliedMeasures []models.Measure,
+	trigger models.SnapshotTrigger,
+) error {
+	snapshot := &models.RiskSnapshot{
+		ShopID:            shop.ID,
+		TotalRisk:         shop.TotalRisk,
+		AggregationMethod: shop.TotalRiskMethod,
+		ScoringVersion:    shop.TotalRiskScoringVersion,
+		TaxonomyCoverage:  shop.TaxonomyCoverage,
+		Risks:             make([]models.RiskSnapshotItem, len(risks)),
+		AppliedMeasures:   make([]string, len(appliedMeasures)),
+		Trigger:           trigger,
+	}
+	for i, risk := range risks {
+		snapshot.Risks[i] = models.RiskSnapshotItem{RiskID: risk.ID, RiskName: risk.Name, RiskScore: risk.RiskScore}
+	}
+	for i, m := range appliedMeasures {
+		snapshot.AppliedMeasures[i] = m.Name
+	}
+
+	coverage, err := r.shopRepo.GetRiskCoverage(ctx, shop.ID)
+	if err != nil {
+		return err
+	}
+	if coverage != nil {
+		snapshot.RiskCoverage = coverage.CoveragePercentage
+	}
+
+	return r.snapshotRepo.Create(ctx, snapshot)
+}
@@ -0,0 +1,186 @@
+// Package services contiene la gestión de los modelos de scoring de riesgos.
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/repository"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/scoring"
+)
+
+// scorerCacheTTL limita cuánto tiempo se reutiliza el scorer activo antes de
+// volver a consultarlo (otras instancias pueden haber activado otra versión)
+const scorerCacheTTL = time.Minute
+
+// RiskScoringService gestiona las configuraciones versionadas de scoring
+// y proporciona el scorer activo al resto de servicios
+type RiskScoringService interface {
+	scoring.Provider
+	ListConfigs(ctx context.Context) ([]models.RiskScoringConfig, error)
+	GetConfig(ctx context.Context, version int64) (*models.RiskScoringConfig, error)
+	GetActiveConfig(ctx context.Context) (*models.RiskScoringConfig, error)
+	CreateConfig(ctx context.Context, req *models.CreateRiskScoringConfigRequest) (*models.RiskScoringConfig, error)
+	ActivateConfig(ctx context.Context, version int64) (*models.RiskScoringConfig, error)
+}
+
+// riskScoringService implementa RiskScoringService
+type riskScoringService struct {
+	configRepo   repository.RiskScoringConfigRepository
+	recalculator ShopRiskRecalculator
+	audit        AuditRecorder
+
+	mu       sync.RWMutex
+	cached   scoring.RiskScorer
+	cachedAt time.Time
+}
+
+// NewRiskScoringService crea una instancia de RiskScoringService
+func NewRiskScoringService(
+	configRepo repository.RiskScoringConfigRepository,
+	recalculator ShopRiskRecalculator,
+	audit AuditRecorder,
+) RiskScoringService {
+	return &riskScoringService{configRepo: configRepo, recalculator: recalculator, audit: audit}
+}
+
+// Current retorna el scorer de la configuración activa, o el scorer por
+// defecto si todavía no se ha activado ninguna versión
+func (s *riskScoringService) Current(ctx context.Context) (scoring.RiskScorer, error) {
+	s.mu.RLock()
+	if s.cached != nil && time.Since(s.cachedAt) < scorerCacheTTL {
+		scorer := s.cached
+		s.mu.RUnlock()
+		return scorer, nil
+	}
+	s.mu.RUnlock()
+
+	cfg, err := s.configRepo.GetActive(ctx)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+
+	scorer := scoring.Default()
+	if cfg != nil {
+		scorer, err = scoring.New(*cfg)
+		if err != nil {
+			return nil, models.ErrInvalidScoringConfig(err.Error())
+		}
+	}
+
+	s.mu.Lock()
+	s.cached = scorer
+	s.cachedAt = time.Now()
+	s.mu.Unlock()
+
+	return scorer, nil
+}
+
+func (s *riskScoringService) ListConfigs(ctx context.Context) ([]models.RiskScoringConfig, error) {
+	configs, err := s.configRepo.List(ctx)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	return configs, nil
+}
+
+func (s *riskScoringService) GetConfig(ctx context.Context, version int64) (*models.RiskScoringConfig, error) {
+	cfg, err := s.configRepo.GetByVersion(ctx, version)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	if cfg == nil {
+		return nil, models.ErrScoringConfigNotFound
+	}
+	return cfg, nil
+}
+
+// GetActiveConfig retorna la configuración activa; si no hay ninguna
+// guardada se devuelve la configuración por defecto (versión 0)
+func (s *riskScoringService) GetActiveConfig(ctx context.Context) (*models.RiskScoringConfig, error) {
+	scorer, err := s.Current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cfg := scorer.Config()
+	return &cfg, nil
+}
+
+// CreateConfig crea una nueva versión partiendo de la configuración por defecto
+func (s *riskScoringService) CreateConfig(ctx context.Context, req *models.CreateRiskScoringConfigRequest) (*models.RiskScoringConfig, error) {
+	cfg := scoring.DefaultConfig()
+	cfg.Name = req.Name
+	cfg.Method = req.Method
+	for level, value := range req.LevelValues {
+		cfg.LevelValues[level] = value
+	}
+	if req.Weights != nil {
+		cfg.Weights = *req.Weights
+	}
+	if len(req.RiskWeights) > 0 {
+		cfg.RiskWeights = req.RiskWeights
+	}
+	if req.Thresholds != nil {
+		cfg.Thresholds = *req.Thresholds
+	}
+	if req.Aggregation != "" {
+		cfg.Aggregation = req.Aggregation
+	}
+	if len(req.RiskImportance) > 0 {
+		cfg.RiskImportance = req.RiskImportance
+	}
+
+	if err := scoring.Validate(cfg); err != nil {
+		return nil, models.ErrInvalidScoringConfig(err.Error())
+	}
+
+	if err := s.configRepo.Create(ctx, &cfg); err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	s.audit.Record(ctx, models.AuditScoringConfigCreated, models.AuditEntityScoringConfig, auditID(cfg.Version), nil, cfg)
+
+	if req.Activate {
+		return s.ActivateConfig(ctx, cfg.Version)
+	}
+	return &cfg, nil
+}
+
+// ActivateConfig activa una versión existente, invalida el scorer en caché y recalcula
+// con ella el riesgo guardado de todas las tiendas, para que los totales, sus snapshots
+// y las vistas que los leen (ranking, dashboard) no mezclen versiones
+func (s *riskScoringService) ActivateConfig(ctx context.Context, version int64) (*models.RiskScoringConfig, error) {
+	cfg, err := s.GetConfig(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+	scorer, err := scoring.New(*cfg)
+	if err != nil {
+		return nil, models.ErrInvalidScoringConfig(err.Error())
+	}
+
+	if err := s.configRepo.Activate(ctx, version); err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	cfg.Active = true
+	s.audit.Record(ctx, models.AuditScoringConfigActivated, models.AuditEntityScoringConfig, auditID(version), nil, cfg)
+
+	s.mu.Lock()
+	s.cached = nil
+	s.mu.Unlock()
+
+	if err := s.recalculator.RecalculateAll(ctx, scorer, models.TriggerScoringActivated); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// scoreRisks asigna a cada riesgo su score según el scorer indicado
+func scoreRisks(scorer scoring.RiskScorer, risks []models.RiskDetail) []models.RiskDetail {
+	for i := range risks {
+		risks[i].RiskScore = scorer.Score(risks[i])
+	}
+	return risks
+}
@@ -4,9 +4,16 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
 
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/authz"
 	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
 	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/repository"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/scenario"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/scoring"
 )
 
 // ShopService define las operaciones de negocio para tiendas
@@ -15,21 +22,35 @@ type ShopService interface {
 	GetByID(ctx context.Context, id int64) (*models.ShopWithDetails, error)
 	Update(ctx context.Context, id int64, req *models.UpdateShopRequest) (*models.Shop, error)
 	Delete(ctx context.Context, id int64) error
+	Restore(ctx context.Context, id int64) (*models.Shop, error)
 	List(ctx context.Context, filter *models.ShopFilterRequest) (*models.PaginatedResponse[models.ShopResponse], error)
 	GetByCluster(ctx context.Context, clusterID int64) ([]models.Shop, error)
 	ApplyMeasures(ctx context.Context, shopID int64, measureNames []string) error
 	RemoveMeasure(ctx context.Context, shopID int64, measureName string) error
-	GetRiskAssessment(ctx context.Context, shopID int64) (*models.RiskAssessmentResponse, error)
-	GetAppliedMeasures(ctx context.Context, shopID int64) ([]models.Measure, error)
+	TransitionMeasure(ctx context.Context, shopID int64, req *models.MeasureTransitionRequest) (*models.ShopMeasure, error)
+	GetRiskAssessment(ctx context.Context, shopID int64, sel models.ScenarioSelection) (*models.RiskAssessmentResponse, error)
+	GetAppliedMeasures(ctx context.Context, shopID int64) ([]models.ShopMeasure, error)
 	GetRiskCoverage(ctx context.Context, shopID int64) (*models.RiskCoverageResponse, error)
+	GetDossier(ctx context.Context, shopID int64) (*models.ShopDossier, error)
+	GetRiskOverrides(ctx context.Context, shopID int64) ([]models.ShopRiskOverride, error)
+	SetRiskOverride(ctx context.Context, shopID, riskID int64, req *models.SetShopRiskOverrideRequest) (*models.ShopRiskOverride, error)
+	RemoveRiskOverride(ctx context.Context, shopID, riskID int64) error
+	GetRiskHistory(ctx context.Context, shopID int64, limit int) ([]models.RiskSnapshot, error)
 }
 
 // shopService implementa ShopService
 type shopService struct {
-	shopRepo    repository.ShopRepository
-	clusterRepo repository.ClusterRepository
-	riskRepo    repository.RiskRepository
-	measureRepo repository.MeasureRepository
+	shopRepo     repository.ShopRepository
+	clusterRepo  repository.ClusterRepository
+	riskRepo     repository.RiskRepository
+	measureRepo  repository.MeasureRepository
+	overrideRepo repository.ShopRiskOverrideRepository
+	scenarioRepo repository.ClusterRiskScenarioRepository
+	snapshotRepo repository.RiskSnapshotRepository
+	taxonomyRepo repository.TaxonomyRepository
+	scorers      scoring.Provider
+	recalculator ShopRiskRecalculator
+	audit        AuditRecorder
 }
 
 // NewShopService crea una nueva instancia de ShopService
@@ -38,25 +59,43 @@ func NewShopService(
 	clusterRepo repository.ClusterRepository,
 	riskRepo repository.RiskRepository,
 	measureRepo repository.MeasureRepository,
+	overrideRepo repository.ShopRiskOverrideRepository,
+	scenarioRepo repository.ClusterRiskScenarioRepository,
+	snapshotRepo repository.RiskSnapshotRepository,
+	taxonomyRepo repository.TaxonomyRepository,
+	scorers scoring.Provider,
+	audit AuditRecorder,
 ) ShopService {
 	return &shopService{
-		shopRepo:    shopRepo,
-		clusterRepo: clusterRepo,
-		riskRepo:    riskRepo,
-		measureRepo: measureRepo,
+		shopRepo:     shopRepo,
+		clusterRepo:  clusterRepo,
+		riskRepo:     riskRepo,
+		measureRepo:  measureRepo,
+		overrideRepo: overrideRepo,
+		scenarioRepo: scenarioRepo,
+		snapshotRepo: snapshotRepo,
+		taxonomyRepo: taxonomyRepo,
+		scorers:      scorers,
+		recalculator: NewShopRiskRecalculator(shopRepo, riskRepo, overrideRepo, scenarioRepo, snapshotRepo, taxonomyRepo),
+		audit:        audit,
 	}
 }
 
-// Create crea una nueva tienda
+// Create crea una nueva tienda y calcula su riesgo inicial. Si el cálculo falla la
+// tienda se retorna igualmente, tal como quedó guardada.
 func (s *shopService) Create(ctx context.Context, req *models.CreateShopRequest) (*models.Shop, error) {
 	// Verificar que el cluster existe
 	cluster, err := s.clusterRepo.GetByID(ctx, req.ClusterID)
 	if err != nil {
 		return nil, models.ErrDatabase(err)
 	}
-	if cluster == nil {
+	scope := authz.ScopeFromContext(ctx)
+	if cluster == nil || !scope.AllowsCluster(req.ClusterID) {
 		return nil, models.ErrClusterNotFound
 	}
+	if !scope.AllowsCountry(req.Country) {
+		return nil, models.ErrOutOfScope
+	}
 
 	// Crear la tienda
 	shop := &models.Shop{
@@ -75,12 +114,17 @@ func (s *shopService) Create(ctx context.Context, req *models.CreateShopRequest)
 		return nil, models.ErrDatabase(err)
 	}
 
-	// Calcular riesgo inicial basado en el cluster
-	if err := s.updateShopRisk(ctx, shop); err != nil {
-		// Log del error pero no fallar
-		// El riesgo se puede calcular después
-	}
+	s.audit.Record(ctx, models.AuditShopCreated, models.AuditEntityShop, auditID(shop.ID), nil, shop)
 
+	// Calcular riesgo inicial basado en el cluster. La tienda ya está creada, por lo que
+	// un fallo del cálculo no se devuelve como error: un reintento la duplicaría.
+	if err := s.updateShopRisk(ctx, shop, models.TriggerShopCreated); err != nil {
+		log.Printf("[RISK] no se pudo calcular el riesgo inicial de la tienda %d: %v", shop.ID, err)
+		// Retornar la tienda tal como quedó guardada
+		if stored, getErr := s.shopRepo.GetByID(ctx, shop.ID); getErr == nil && stored != nil {
+			return stored, nil
+		}
+	}
 	return shop, nil
 }
 
@@ -90,12 +134,12 @@ func (s *shopService) GetByID(ctx context.Context, id int64) (*models.ShopWithDe
 	if err != nil {
 		return nil, models.ErrDatabase(err)
 	}
-	if shop == nil {
+	if shop == nil || !authz.ScopeFromContext(ctx).AllowsShop(shop.Country, shop.ClusterID) {
 		return nil, models.ErrShopNotFound
 	}
 
-	// Añadir información de riesgos del cluster
-	risks, err := s.riskRepo.GetByClusterID(ctx, shop.ClusterID)
+	// Añadir información de riesgos del cluster con los ajustes de la tienda
+	risks, _, err := s.scoredShopRisks(ctx, &shop.Shop, models.ScenarioSelection{})
 	if err == nil {
 		shop.Risks = risks
 	}
@@ -103,16 +147,15 @@ func (s *shopService) GetByID(ctx context.Context, id int64) (*models.ShopWithDe
 	return shop, nil
 }
 
-// Update actualiza una tienda
+// Update actualiza una tienda. Si cambia su cluster, su país o su superficie se
+// recalculan su riesgo y su cobertura de la Taxonomía.
 func (s *shopService) Update(ctx context.Context, id int64, req *models.UpdateShopRequest) (*models.Shop, error) {
 	// Obtener tienda existente
-	shop, err := s.shopRepo.GetByID(ctx, id)
+	shop, err := s.getShop(ctx, id)
 	if err != nil {
-		return nil, models.ErrDatabase(err)
-	}
-	if shop == nil {
-		return nil, models.ErrShopNotFound
+		return nil, err
 	}
+	before := *shop
 
 	// Aplicar cambios (partial update)
 	if req.Location != nil {
@@ -136,12 +179,16 @@ func (s *shopService) Update(ctx context.Context, id int64, req *models.UpdateSh
 		if err != nil {
 			return nil, models.ErrDatabase(err)
 		}
-		if cluster == nil {
+		if cluster == nil || !authz.ScopeFromContext(ctx).AllowsCluster(*req.ClusterID) {
 			return nil, models.ErrClusterNotFound
 		}
 		shop.ClusterID = *req.ClusterID
 	}
 	if req.Country != nil {
+		// La tienda no puede salir del ámbito del usuario que la modifica
+		if !authz.ScopeFromContext(ctx).AllowsCountry(*req.Country) {
+			return nil, models.ErrOutOfScope
+		}
 		shop.Country = *req.Country
 	}
 
@@ -149,27 +196,62 @@ func (s *shopService) Update(ctx context.Context, id int64, req *models.UpdateSh
 		return nil, models.ErrDatabase(err)
 	}
 
+	s.audit.Record(ctx, models.AuditShopUpdated, models.AuditEntityShop, auditID(shop.ID), before, shop)
+
+	// El cluster determina los riesgos de la tienda, y el país y la superficie el coste
+	// de sus medidas con que se calcula la CapEx alineada con la Taxonomía
+	if shop.ClusterID != before.ClusterID || shop.Country != before.Country || shop.Surface != before.Surface {
+		if err := s.updateShopRisk(ctx, shop, models.TriggerShopUpdated); err != nil {
+			return nil, err
+		}
+	}
 	return shop, nil
 }
 
-// Delete elimina una tienda
+// Delete elimina una tienda. El borrado es lógico: sus medidas, ajustes de
+// riesgo e historial se conservan y la tienda puede restaurarse.
 func (s *shopService) Delete(ctx context.Context, id int64) error {
 	// Verificar que existe
-	shop, err := s.shopRepo.GetByID(ctx, id)
+	shop, err := s.getShop(ctx, id)
 	if err != nil {
-		return models.ErrDatabase(err)
-	}
-	if shop == nil {
-		return models.ErrShopNotFound
+		return err
 	}
 
 	if err := s.shopRepo.Delete(ctx, id); err != nil {
 		return models.ErrDatabase(err)
 	}
 
+	s.audit.Record(ctx, models.AuditShopDeleted, models.AuditEntityShop, auditID(id), shop, nil)
 	return nil
 }
 
+// Restore restaura una tienda eliminada junto con sus relaciones
+func (s *shopService) Restore(ctx context.Context, id int64) (*models.Shop, error) {
+	shop, err := s.shopRepo.GetDeletedByID(ctx, id)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	if shop == nil {
+		// Distinguir una tienda activa de una inexistente
+		if _, err := s.getShop(ctx, id); err != nil {
+			return nil, err
+		}
+		return nil, models.ErrShopNotDeleted
+	}
+	if !authz.ScopeFromContext(ctx).AllowsShop(shop.Country, shop.ClusterID) {
+		return nil, models.ErrShopNotFound
+	}
+
+	if err := s.shopRepo.Restore(ctx, id); err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+
+	before := *shop
+	shop.DeletedAt = nil
+	s.audit.Record(ctx, models.AuditShopRestored, models.AuditEntityShop, auditID(id), before, shop)
+	return shop, nil
+}
+
 // List obtiene una lista paginada de tiendas
 func (s *shopService) List(ctx context.Context, filter *models.ShopFilterRequest) (*models.PaginatedResponse[models.ShopResponse], error) {
 	shops, total, err := s.shopRepo.List(ctx, filter)
@@ -186,11 +268,13 @@ func (s *shopService) List(ctx context.Context, filter *models.ShopFilterRequest
 			UtmNorth:         shop.UtmNorth,
 			UtmEast:          shop.UtmEast,
 			TotalRisk:        shop.TotalRisk,
+			TotalRiskMethod:  shop.TotalRiskMethod,
 			TaxonomyCoverage: shop.TaxonomyCoverage,
 			Surface:          shop.Surface,
 			CarbonFootprint:  shop.CarbonFootprint,
 			ClusterID:        shop.ClusterID,
 			Country:          shop.Country,
+			DeletedAt:        shop.DeletedAt,
 		}
 	}
 
@@ -230,10 +314,11 @@ func (s *shopService) GetByCluster(ctx context.Context, clusterID int64) ([]mode
 	if err != nil {
 		return nil, models.ErrDatabase(err)
 	}
-	if cluster == nil {
+	if cluster == nil || !authz.ScopeFromContext(ctx).AllowsCluster(clusterID) {
 		return nil, models.ErrClusterNotFound
 	}
 
+	// El repositorio limita las tiendas al ámbito del usuario
 	shops, err := s.shopRepo.GetByClusterID(ctx, clusterID)
 	if err != nil {
 		return nil, models.ErrDatabase(err)
@@ -242,18 +327,14 @@ func (s *shopService) GetByCluster(ctx context.Context, clusterID int64) ([]mode
 	return shops, nil
 }
 
-// GetAppliedMeasures obtiene las medidas ya aplicadas a una tienda
-func (s *shopService) GetAppliedMeasures(ctx context.Context, shopID int64) ([]models.Measure, error) {
+// GetAppliedMeasures obtiene las medidas aplicadas a una tienda con su estado de implantación
+func (s *shopService) GetAppliedMeasures(ctx context.Context, shopID int64) ([]models.ShopMeasure, error) {
 	// Verificar que la tienda existe
-	shop, err := s.shopRepo.GetByID(ctx, shopID)
-	if err != nil {
-		return nil, models.ErrDatabase(err)
-	}
-	if shop == nil {
-		return nil, models.ErrShopNotFound
+	if _, err := s.getShop(ctx, shopID); err != nil {
+		return nil, err
 	}
 
-	measures, err := s.shopRepo.GetAppliedMeasures(ctx, shopID)
+	measures, err := s.shopRepo.GetShopMeasures(ctx, shopID)
 	if err != nil {
 		return nil, models.ErrDatabase(err)
 	}
@@ -261,18 +342,24 @@ func (s *shopService) GetAppliedMeasures(ctx context.Context, shopID int64) ([]m
 	return measures, nil
 }
 
-// ApplyMeasures aplica medidas a una tienda
+// ApplyMeasures aplica medidas a una tienda. Las medidas empiezan planificadas y
+// no reducen el riesgo hasta que se completan.
 func (s *shopService) ApplyMeasures(ctx context.Context, shopID int64, measureNames []string) error {
 	// Verificar que la tienda existe
-	shop, err := s.shopRepo.GetByID(ctx, shopID)
+	shop, err := s.getShop(ctx, shopID)
 	if err != nil {
-		return models.ErrDatabase(err)
-	}
-	if shop == nil {
-		return models.ErrShopNotFound
+		return err
 	}
 
-	// Aplicar cada medida
+	// Aplicar cada medida. Las medidas se aplican una a una, por lo que si alguna
+	// falla se registran en auditoría las que sí llegaron a aplicarse.
+	var applied []string
+	defer func() {
+		if len(applied) > 0 {
+			s.audit.Record(ctx, models.AuditMeasuresApplied, models.AuditEntityShopMeasure, auditID(shopID),
+				nil, map[string]interface{}{"shop_id": shopID, "measures": applied})
+		}
+	}()
 	for _, measureName := range measureNames {
 		// Verificar que la medida existe
 		measure, err := s.measureRepo.GetByName(ctx, measureName)
@@ -289,11 +376,12 @@ func (s *shopService) ApplyMeasures(ctx context.Context, shopID int64, measureNa
 			}
 			return models.ErrDatabase(err)
 		}
+		applied = append(applied, measureName)
 	}
 
 	// Recalcular riesgo y cobertura
-	if err := s.updateShopRisk(ctx, shop); err != nil {
-		// Log pero no fallar
+	if err := s.updateShopRisk(ctx, shop, models.TriggerMeasuresApplied); err != nil {
+		return err
 	}
 
 	return nil
@@ -301,105 +389,314 @@ func (s *shopService) ApplyMeasures(ctx context.Context, shopID int64, measureNa
 
 // RemoveMeasure elimina una medida de una tienda
 func (s *shopService) RemoveMeasure(ctx context.Context, shopID int64, measureName string) error {
-	shop, err := s.shopRepo.GetByID(ctx, shopID)
+	shop, err := s.getShop(ctx, shopID)
 	if err != nil {
-		return models.ErrDatabase(err)
-	}
-	if shop == nil {
-		return models.ErrShopNotFound
+		return err
 	}
 
 	if err := s.shopRepo.RemoveMeasure(ctx, shopID, measureName); err != nil {
 		if errors.Is(err, repository.ErrMeasureNotAppliedToShop) {
 			return models.ErrMeasureNotApplied
 		}
+		if errors.Is(err, repository.ErrMeasureHasInvoices) {
+			return models.ErrMeasureHasInvoices
+		}
 		return models.ErrDatabase(err)
 	}
+	s.audit.Record(ctx, models.AuditMeasureRemoved, models.AuditEntityShopMeasure, auditID(shopID),
+		map[string]interface{}{"shop_id": shopID, "measures": []string{measureName}}, nil)
+
+	// Recalcular riesgo y cobertura
+	if err := s.updateShopRisk(ctx, shop, models.TriggerMeasureRemoved); err != nil {
+		return err
+	}
+
 	return nil
 }
 
-// GetRiskAssessment obtiene la evaluación de riesgos de una tienda
-func (s *shopService) GetRiskAssessment(ctx context.Context, shopID int64) (*models.RiskAssessmentResponse, error) {
-	shop, err := s.shopRepo.GetByID(ctx, shopID)
+// TransitionMeasure avanza una medida de una tienda a la siguiente fase de su ciclo de
+// vida (planned → approved → in_progress → completed → verified) y actualiza sus
+// fechas, coste real y notas. Si se indica el estado actual solo se actualizan los datos.
+// El coste real de las medidas con facturas es la suma de sus facturas y no se puede indicar.
+func (s *shopService) TransitionMeasure(ctx context.Context, shopID int64, req *models.MeasureTransitionRequest) (*models.ShopMeasure, error) {
+	shop, err := s.getShop(ctx, shopID)
+	if err != nil {
+		return nil, err
+	}
+
+	measure, err := s.shopRepo.GetShopMeasure(ctx, shopID, req.MeasureName)
 	if err != nil {
 		return nil, models.ErrDatabase(err)
 	}
-	if shop == nil {
-		return nil, models.ErrShopNotFound
+	if measure == nil {
+		return nil, models.ErrMeasureNotApplied
 	}
+	before := *measure
 
-	risks, err := s.riskRepo.GetByClusterID(ctx, shop.ClusterID)
-	if err != nil {
+	if req.Status != measure.Status && !measure.Status.CanTransitionTo(req.Status) {
+		return nil, models.ErrInvalidMeasureTransition(fmt.Sprintf(
+			"La medida no puede pasar de '%s' a '%s'", measure.Status, req.Status))
+	}
+
+	measure.Status = req.Status
+	if req.PlannedStartDate != nil {
+		measure.PlannedStartDate = req.PlannedStartDate
+	}
+	if req.PlannedEndDate != nil {
+		measure.PlannedEndDate = req.PlannedEndDate
+	}
+	if req.ActualStartDate != nil {
+		measure.ActualStartDate = req.ActualStartDate
+	}
+	if req.ActualEndDate != nil {
+		measure.ActualEndDate = req.ActualEndDate
+	}
+	if req.ActualCost != nil {
+		if measure.Invoices > 0 {
+			return nil, models.ErrInvalidMeasureTransition("La medida tiene facturas registradas: su coste real es la suma de sus facturas")
+		}
+		measure.ActualCost = req.ActualCost
+	}
+	if req.Notes != nil {
+		measure.Notes = *req.Notes
+	}
+	if err := completeMeasureLifecycle(measure, time.Now()); err != nil {
+		return nil, err
+	}
+
+	if err := s.shopRepo.UpdateShopMeasure(ctx, measure); err != nil {
+		if errors.Is(err, repository.ErrMeasureNotAppliedToShop) {
+			return nil, models.ErrMeasureNotApplied
+		}
 		return nil, models.ErrDatabase(err)
 	}
+	s.audit.Record(ctx, models.AuditMeasureTransitioned, models.AuditEntityShopMeasure, auditID(shopID), before, measure)
+
+	// Al completarse la medida pasa a contar en la cobertura de la tienda
+	if measure.Status.IsCompleted() != before.Status.IsCompleted() {
+		if err := s.updateShopRisk(ctx, shop, models.TriggerMeasureCompleted); err != nil {
+			return nil, err
+		}
+	}
+
+	return measure, nil
+}
+
+// completeMeasureLifecycle rellena las fechas reales que implica el estado de la medida
+// y valida que sus datos sean coherentes con él
+func completeMeasureLifecycle(m *models.ShopMeasure, now time.Time) error {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	if m.Status.Reached(models.MeasureStatusInProgress) && m.ActualStartDate == nil {
+		m.ActualStartDate = &today
+	}
+	if m.Status.Reached(models.MeasureStatusCompleted) && m.ActualEndDate == nil {
+		m.ActualEndDate = &today
+	}
 
-	// Calcular nivel de riesgo
-	riskLevel := getRiskLevel(shop.TotalRisk)
+	switch {
+	case !m.Status.Reached(models.MeasureStatusInProgress) && (m.ActualStartDate != nil || m.ActualCost != nil):
+		return models.ErrInvalidMeasureTransition("La fecha de inicio y el coste reales solo se registran a partir de 'in_progress'")
+	case !m.Status.Reached(models.MeasureStatusCompleted) && m.ActualEndDate != nil:
+		return models.ErrInvalidMeasureTransition("La fecha de fin real solo se registra a partir de 'completed'")
+	case m.Status.Reached(models.MeasureStatusCompleted) && m.ActualCost == nil:
+		return models.ErrInvalidMeasureTransition("Indique el coste real para completar la medida")
+	case m.PlannedStartDate != nil && m.PlannedEndDate != nil && m.PlannedEndDate.Before(*m.PlannedStartDate):
+		return models.ErrInvalidMeasureTransition("La fecha de fin planificada es anterior a la de inicio")
+	case m.ActualStartDate != nil && m.ActualEndDate != nil && m.ActualEndDate.Before(*m.ActualStartDate):
+		return models.ErrInvalidMeasureTransition("La fecha de fin real es anterior a la de inicio")
+	case (m.ActualStartDate != nil && m.ActualStartDate.After(now)) || (m.ActualEndDate != nil && m.ActualEndDate.After(now)):
+		return models.ErrInvalidMeasureTransition("Las fechas reales no pueden ser futuras")
+	}
+	return nil
+}
 
+// GetRiskAssessment obtiene la evaluación de riesgos de una tienda para el escenario
+// climático indicado (la selección vacía corresponde a los niveles actuales)
+func (s *shopService) GetRiskAssessment(ctx context.Context, shopID int64, sel models.ScenarioSelection) (*models.RiskAssessmentResponse, error) {
+	shop, err := s.getShop(ctx, shopID)
+	if err != nil {
+		return nil, err
+	}
+
+	sel = scenario.Normalize(sel)
+	risks, scorer, err := s.scoredShopRisks(ctx, shop, sel)
+	if err != nil {
+		return nil, err
+	}
+
+	// La fecha de actualización es la del último recálculo registrado
+	latest, err := s.snapshotRepo.GetLatest(ctx, shopID)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	var lastUpdated *time.Time
+	if latest != nil {
+		lastUpdated = &latest.CreatedAt
+	}
+
+	cfg := scorer.Config()
+	overall := scorer.Aggregate(risks)
 	return &models.RiskAssessmentResponse{
-		ShopID:           shopID,
-		OverallRiskScore: shop.TotalRisk,
-		RiskLevel:        riskLevel,
-		Risks:            risks,
-		LastUpdated:      "2025-12-15T00:00:00Z",
+		ShopID:            shopID,
+		OverallRiskScore:  overall,
+		RiskLevel:         scorer.Level(overall),
+		Risks:             risks,
+		ScoringVersion:    cfg.Version,
+		ScoringMethod:     cfg.Method,
+		AggregationMethod: cfg.Aggregation,
+		Scenario:          sel,
+		LastUpdated:       lastUpdated,
 	}, nil
 }
 
-// updateShopRisk actualiza el riesgo total de una tienda
-func (s *shopService) updateShopRisk(ctx context.Context, shop *models.Shop) error {
-	risks, err := s.riskRepo.GetByClusterID(ctx, shop.ClusterID)
+// scoredShopRisks obtiene los riesgos del cluster de la tienda proyectados al escenario
+// indicado, aplica los ajustes propios de la tienda y los puntúa con el scorer vigente
+func (s *shopService) scoredShopRisks(ctx context.Context, shop *models.Shop, sel models.ScenarioSelection) ([]models.RiskDetail, scoring.RiskScorer, error) {
+	return loadShopRisks(ctx, s.scorers, s.riskRepo, s.scenarioRepo, s.overrideRepo, shop, sel)
+}
+
+// loadShopRisks obtiene los riesgos de una tienda en el escenario indicado, con sus
+// ajustes propios y puntuados con el scorer vigente
+func loadShopRisks(
+	ctx context.Context,
+	scorers scoring.Provider,
+	riskRepo repository.RiskRepository,
+	scenarioRepo repository.ClusterRiskScenarioRepository,
+	overrideRepo repository.ShopRiskOverrideRepository,
+	shop *models.Shop,
+	sel models.ScenarioSelection,
+) ([]models.RiskDetail, scoring.RiskScorer, error) {
+	scorer, err := scorers.Current(ctx)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
-	// Calcular riesgo total (promedio ponderado de todos los riesgos)
-	if len(risks) == 0 {
-		shop.TotalRisk = 0
-		return s.shopRepo.Update(ctx, shop)
+	risks, err := projectedClusterRisks(ctx, riskRepo, scenarioRepo, shop.ClusterID, sel)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	var totalScore float64
-	for _, risk := range risks {
-		totalScore += risk.RiskScore
+	overrides, err := overrideRepo.GetByShop(ctx, shop.ID)
+	if err != nil {
+		return nil, nil, models.ErrDatabase(err)
 	}
-	shop.TotalRisk = totalScore / float64(len(risks))
 
-	// Calcular cobertura basada en medidas aplicadas
-	appliedMeasures, err := s.shopRepo.GetAppliedMeasures(ctx, shop.ID)
+	return scoreRisks(scorer, applyRiskOverrides(risks, overrides)), scorer, nil
+}
+
+// portfolioShopRisks calcula los riesgos de una tienda en un escenario con los datos
+// leídos de una vez para toda la cartera, como loadShopRisks con los repositorios.
+// projections son las proyecciones del cluster de la tienda.
+func portfolioShopRisks(
+	data *models.PortfolioShopData,
+	projections []models.ClusterRiskProjection,
+	scorer scoring.RiskScorer,
+	shop *models.Shop,
+	sel models.ScenarioSelection,
+) []models.RiskDetail {
+	// Copia de los riesgos del cluster: el escenario y los ajustes los modifican
+	risks := append([]models.RiskDetail(nil), data.ClusterRisks[shop.ClusterID]...)
+	if !sel.IsCurrent() {
+		risks = scenario.Apply(risks, projections, sel)
+	}
+	return scoreRisks(scorer, applyRiskOverrides(risks, data.Overrides[shop.ID]))
+}
+
+// projectedClusterRisks obtiene los riesgos de un cluster con los niveles proyectados
+// al escenario indicado
+func projectedClusterRisks(
+	ctx context.Context,
+	riskRepo repository.RiskRepository,
+	scenarioRepo repository.ClusterRiskScenarioRepository,
+	clusterID int64,
+	sel models.ScenarioSelection,
+) ([]models.RiskDetail, error) {
+	risks, err := riskRepo.GetByClusterID(ctx, clusterID)
 	if err != nil {
-		return err
+		return nil, models.ErrDatabase(err)
+	}
+	if sel.IsCurrent() {
+		return risks, nil
 	}
 
-	allMeasures, err := s.measureRepo.List(ctx)
+	projections, err := scenarioRepo.GetByCluster(ctx, clusterID, sel.Scenario)
 	if err != nil {
-		return err
+		return nil, models.ErrDatabase(err)
 	}
+	return scenario.Apply(risks, projections, sel), nil
+}
 
-	if len(allMeasures) > 0 {
-		shop.TaxonomyCoverage = float64(len(appliedMeasures)) / float64(len(allMeasures)) * 100
+// applyRiskOverrides combina los ajustes de una tienda sobre los niveles heredados del cluster
+func applyRiskOverrides(risks []models.RiskDetail, overrides []models.ShopRiskOverride) []models.RiskDetail {
+	if len(overrides) == 0 {
+		return risks
 	}
 
-	return s.shopRepo.Update(ctx, shop)
+	byRisk := make(map[int64]models.ShopRiskOverride, len(overrides))
+	for _, o := range overrides {
+		byRisk[o.RiskID] = o
+	}
+
+	for i := range risks {
+		o, ok := byRisk[risks[i].ID]
+		if !ok {
+			continue
+		}
+		if o.Exposure != nil {
+			risks[i].Exposure = *o.Exposure
+		}
+		if o.Sensitivity != nil {
+			risks[i].Sensitivity = *o.Sensitivity
+		}
+		risks[i].OverrideReason = o.Reason
+	}
+	return risks
 }
 
-// getRiskLevel convierte un score numérico a nivel de riesgo
-func getRiskLevel(score float64) models.Level {
-	switch {
-	case score < 0.2:
-		return models.LevelVeryLow
-	case score < 0.4:
-		return models.LevelLow
-	case score < 0.6:
-		return models.LevelMedium
-	case score < 0.8:
-		return models.LevelHigh
-	default:
-		return models.LevelVeryHigh
+// updateShopRisk actualiza el riesgo total de una tienda con el scorer vigente y
+// registra el recálculo como snapshot en su historial
+func (s *shopService) updateShopRisk(ctx context.Context, shop *models.Shop, trigger models.SnapshotTrigger) error {
+	scorer, err := s.scorers.Current(ctx)
+	if err != nil {
+		return err
+	}
+	return s.recalculator.Recalculate(ctx, scorer, shop, trigger)
+}
+
+// getShop obtiene una tienda dentro del ámbito del usuario. Las tiendas fuera de su
+// ámbito se tratan como inexistentes para no revelar su existencia.
+func (s *shopService) getShop(ctx context.Context, shopID int64) (*models.Shop, error) {
+	shop, err := s.shopRepo.GetByID(ctx, shopID)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	if shop == nil || !authz.ScopeFromContext(ctx).AllowsShop(shop.Country, shop.ClusterID) {
+		return nil, models.ErrShopNotFound
+	}
+	return shop, nil
+}
+
+// GetRiskHistory obtiene los snapshots de riesgo de una tienda, del más reciente al más antiguo
+func (s *shopService) GetRiskHistory(ctx context.Context, shopID int64, limit int) ([]models.RiskSnapshot, error) {
+	if _, err := s.getShop(ctx, shopID); err != nil {
+		return nil, err
+	}
+
+	snapshots, err := s.snapshotRepo.ListByShop(ctx, shopID, limit)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
 	}
+	return snapshots, nil
 }
 
 // GetRiskCoverage obtiene la cobertura de riesgos de una tienda
 func (s *shopService) GetRiskCoverage(ctx context.Context, shopID int64) (*models.RiskCoverageResponse, error) {
+	shop, err := s.getShop(ctx, shopID)
+	if err != nil {
+		return nil, err
+	}
+
 	coverage, err := s.shopRepo.GetRiskCoverage(ctx, shopID)
 	if err != nil {
 		return nil, models.ErrDatabase(err)
@@ -408,5 +705,243 @@ func (s *shopService) GetRiskCoverage(ctx context.Context, shopID int64) (*model
 		return nil, models.ErrShopNotFound
 	}
 
+	// Completar los scores con el modelo de scoring vigente
+	risks, _, err := s.scoredShopRisks(ctx, shop, models.ScenarioSelection{})
+	if err != nil {
+		return nil, err
+	}
+	scores := make(map[int64]float64, len(risks))
+	for _, r := range risks {
+		scores[r.ID] = r.RiskScore
+	}
+	for i := range coverage.Risks {
+		coverage.Risks[i].RiskScore = scores[coverage.Risks[i].RiskID]
+	}
+
 	return coverage, nil
 }
+
+// dossierRecommendations es el número máximo de medidas recomendadas en la ficha de una tienda
+const dossierRecommendations = 5
+
+// GetDossier reúne los datos de la ficha imprimible de una tienda: evaluación de
+// riesgos, cobertura, medidas aplicadas, próximas medidas recomendadas y las demás
+// tiendas de su cluster para el mapa de situación
+func (s *shopService) GetDossier(ctx context.Context, shopID int64) (*models.ShopDossier, error) {
+	shop, err := s.getShop(ctx, shopID)
+	if err != nil {
+		return nil, err
+	}
+
+	assessment, err := s.GetRiskAssessment(ctx, shopID, models.ScenarioSelection{})
+	if err != nil {
+		return nil, err
+	}
+	coverage, err := s.GetRiskCoverage(ctx, shopID)
+	if err != nil {
+		return nil, err
+	}
+	measures, err := s.shopRepo.GetShopMeasures(ctx, shopID)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+
+	cluster, err := s.clusterRepo.GetByID(ctx, shop.ClusterID)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	if cluster == nil {
+		cluster = &models.Cluster{ID: shop.ClusterID, UtmNorth: shop.UtmNorth, UtmEast: shop.UtmEast}
+	}
+
+	// El repositorio limita las tiendas al ámbito del usuario
+	clusterShops, err := s.shopRepo.GetByClusterID(ctx, shop.ClusterID)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	neighbours := make([]models.Shop, 0, len(clusterShops))
+	for _, cs := range clusterShops {
+		if cs.ID != shop.ID {
+			neighbours = append(neighbours, cs)
+		}
+	}
+
+	return &models.ShopDossier{
+		Shop:            *shop,
+		Cluster:         *cluster,
+		Neighbours:      neighbours,
+		Assessment:      *assessment,
+		Coverage:        *coverage,
+		Measures:        measures,
+		Recommendations: recommendNextMeasures(coverage, measures, dossierRecommendations),
+		GeneratedAt:     time.Now().UTC(),
+	}, nil
+}
+
+// recommendNextMeasures propone las medidas del catálogo para los riesgos de la tienda
+// que no cubre ninguna medida aplicada, ni siquiera pendiente de implantar. Se priorizan
+// las medidas que reducen los riesgos de mayor puntuación y, a igualdad, las más baratas.
+func recommendNextMeasures(coverage *models.RiskCoverageResponse, measures []models.ShopMeasure, limit int) []models.MeasureRecommendation {
+	applied := make(map[string]bool, len(measures))
+	for _, m := range measures {
+		applied[m.Name] = true
+	}
+
+	risks := append([]models.RiskCoverageItem(nil), coverage.Risks...)
+	sort.SliceStable(risks, func(i, j int) bool { return risks[i].RiskScore > risks[j].RiskScore })
+
+	byName := make(map[string]*models.MeasureRecommendation)
+	var order []string
+	for _, r := range risks {
+		if r.IsCovered || anyApplied(r.AvailableMeasures, applied) {
+			continue
+		}
+		for _, m := range r.AvailableMeasures {
+			rec, ok := byName[m.Name]
+			if !ok {
+				rec = &models.MeasureRecommendation{Measure: m}
+				byName[m.Name] = rec
+				order = append(order, m.Name)
+			}
+			rec.Risks = append(rec.Risks, r.RiskName)
+			rec.RiskScore += r.RiskScore
+		}
+	}
+
+	recommendations := make([]models.MeasureRecommendation, 0, len(order))
+	for _, name := range order {
+		recommendations = append(recommendations, *byName[name])
+	}
+	sort.SliceStable(recommendations, func(i, j int) bool {
+		a, b := recommendations[i], recommendations[j]
+		if a.RiskScore != b.RiskScore {
+			return a.RiskScore > b.RiskScore
+		}
+		return a.EstimatedCost < b.EstimatedCost
+	})
+	if len(recommendations) > limit {
+		recommendations = recommendations[:limit]
+	}
+	return recommendations
+}
+
+// anyApplied indica si alguna de las medidas ya está aplicada a la tienda
+func anyApplied(measures []models.Measure, applied map[string]bool) bool {
+	for _, m := range measures {
+		if applied[m.Name] {
+			return true
+		}
+	}
+	return false
+}
+
+// GetRiskOverrides obtiene los ajustes de riesgo de una tienda
+func (s *shopService) GetRiskOverrides(ctx context.Context, shopID int64) ([]models.ShopRiskOverride, error) {
+	if _, err := s.getShop(ctx, shopID); err != nil {
+		return nil, err
+	}
+
+	overrides, err := s.overrideRepo.GetByShop(ctx, shopID)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	return overrides, nil
+}
+
+// SetRiskOverride crea o reemplaza el ajuste de exposición/sensibilidad de una tienda
+// para uno de los riesgos de su cluster y recalcula su riesgo total
+func (s *shopService) SetRiskOverride(ctx context.Context, shopID, riskID int64, req *models.SetShopRiskOverrideRequest) (*models.ShopRiskOverride, error) {
+	if req.Exposure == nil && req.Sensitivity == nil {
+		return nil, models.ErrInvalidInput("Debe indicar la exposición y/o la sensibilidad")
+	}
+
+	shop, err := s.getShop(ctx, shopID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Solo se pueden ajustar riesgos que afectan al cluster de la tienda
+	clusterRisks, err := s.riskRepo.GetByClusterID(ctx, shop.ClusterID)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	var riskName string
+	for _, r := range clusterRisks {
+		if r.ID == riskID {
+			riskName = r.Name
+			break
+		}
+	}
+	if riskName == "" {
+		return nil, models.ErrRiskNotFound
+	}
+
+	before, err := s.findRiskOverride(ctx, shopID, riskID)
+	if err != nil {
+		return nil, err
+	}
+
+	override := &models.ShopRiskOverride{
+		ShopID:      shopID,
+		RiskID:      riskID,
+		RiskName:    riskName,
+		Exposure:    req.Exposure,
+		Sensitivity: req.Sensitivity,
+		Reason:      req.Reason,
+	}
+	if err := s.overrideRepo.Upsert(ctx, override); err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	s.audit.Record(ctx, models.AuditRiskOverrideSet, models.AuditEntityShopRiskOverride, riskOverrideAuditID(shopID, riskID), before, override)
+
+	if err := s.updateShopRisk(ctx, shop, models.TriggerOverrideChanged); err != nil {
+		return nil, err
+	}
+
+	return override, nil
+}
+
+// RemoveRiskOverride elimina un ajuste de riesgo; la tienda vuelve a heredar los valores del cluster
+func (s *shopService) RemoveRiskOverride(ctx context.Context, shopID, riskID int64) error {
+	shop, err := s.getShop(ctx, shopID)
+	if err != nil {
+		return err
+	}
+	before, err := s.findRiskOverride(ctx, shopID, riskID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.overrideRepo.Delete(ctx, shopID, riskID); err != nil {
+		if errors.Is(err, repository.ErrRiskOverrideNotFound) {
+			return models.ErrRiskOverrideNotFound
+		}
+		return models.ErrDatabase(err)
+	}
+	s.audit.Record(ctx, models.AuditRiskOverrideRemoved, models.AuditEntityShopRiskOverride, riskOverrideAuditID(shopID, riskID), before, nil)
+
+	if err := s.updateShopRisk(ctx, shop, models.TriggerOverrideChanged); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// findRiskOverride obtiene el ajuste actual de un riesgo de la tienda, o nil si no tiene
+func (s *shopService) findRiskOverride(ctx context.Context, shopID, riskID int64) (*models.ShopRiskOverride, error) {
+	overrides, err := s.overrideRepo.GetByShop(ctx, shopID)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	for i := range overrides {
+		if overrides[i].RiskID == riskID {
+			return &overrides[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// riskOverrideAuditID identifica en auditoría el ajuste de un riesgo de una tienda
+func riskOverrideAuditID(shopID, riskID int64) string {
+	return auditID(shopID) + "/" + auditID(riskID)
+}
@@ -0,0 +1,355 @@
+// Package services contiene la lógica de negocio de la aplicación.
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/repository"
+)
+
+// ssoStateTTL es el tiempo que tiene el usuario para completar el inicio de sesión en el IdP
+const ssoStateTTL = 10 * time.Minute
+
+// ssoMaxPending limita los inicios de sesión en curso que se guardan en memoria
+const ssoMaxPending = 10000
+
+// OIDCProvider abstrae el proveedor de identidad OpenID Connect
+type OIDCProvider interface {
+	AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error)
+	Exchange(ctx context.Context, code, codeVerifier string) (*models.OIDCIdentity, error)
+}
+
+// OIDCRoleMapping asigna roles de la aplicación a partir de los grupos del IdP.
+// Si el usuario pertenece a grupos de varios roles recibe el de mayor privilegio.
+// El rol solo se asigna al dar de alta al usuario; después lo gestionan los
+// administradores, y los grupos solo deciden si el usuario puede acceder.
+//
+// El ámbito de datos del alta es la unión de los países y clusters de sus grupos.
+// Como un ámbito vacío da acceso a todos los datos, solo los admins y los grupos de
+// GlobalGroups lo reciben: el resto de usuarios sin grupos de ámbito se rechaza.
+type OIDCRoleMapping struct {
+	AdminGroups   []string
+	ManagerGroups []string
+	ViewerGroups  []string
+	DefaultRole   models.UserRole // vacío rechaza a los usuarios sin grupos asignados
+	CountryGroups map[string][]string
+	ClusterGroups map[string][]int64
+	GlobalGroups  []string
+}
+
+// RoleFor retorna el rol que corresponde a los grupos indicados
+func (m OIDCRoleMapping) RoleFor(groups []string) (models.UserRole, bool) {
+	switch {
+	case containsGroup(m.AdminGroups, groups):
+		return models.RoleAdmin, true
+	case containsGroup(m.ManagerGroups, groups):
+		return models.RoleManager, true
+	case containsGroup(m.ViewerGroups, groups):
+		return models.RoleViewer, true
+	case m.DefaultRole != "":
+		return m.DefaultRole, true
+	}
+	return "", false
+}
+
+// ScopeFor retorna los países y clusters a los que dan acceso los grupos indicados.
+// ok es false si un usuario con el rol indicado no debe darse de alta sin ámbito.
+func (m OIDCRoleMapping) ScopeFor(role models.UserRole, groups []string) ([]string, []int64, bool) {
+	if role == models.RoleAdmin || containsGroup(m.GlobalGroups, groups) {
+		return nil, nil, true
+	}
+
+	var countries []string
+	var clusterIDs []int64
+	for _, g := range groups {
+		for configured, values := range m.CountryGroups {
+			if strings.EqualFold(configured, g) {
+				countries = appendUnique(countries, values...)
+			}
+		}
+		for configured, values := range m.ClusterGroups {
+			if strings.EqualFold(configured, g) {
+				clusterIDs = appendUnique(clusterIDs, values...)
+			}
+		}
+	}
+	return countries, clusterIDs, len(countries) > 0 || len(clusterIDs) > 0
+}
+
+// SSORedirect es un inicio de sesión en el IdP recién iniciado. El state debe quedar
+// ligado al navegador que lo inició (p. ej. en una cookie) y comprobarse en el callback.
+type SSORedirect struct {
+	AuthURL   string
+	State     string
+	ExpiresAt time.Time
+}
+
+// SSOService define el inicio de sesión único con OpenID Connect
+type SSOService interface {
+	Start(ctx context.Context) (*SSORedirect, error)
+	// StartLink inicia el flujo para vincular la identidad del IdP a la cuenta del usuario
+	StartLink(ctx context.Context, userID int64) (*SSORedirect, error)
+	Complete(ctx context.Context, state, code string) (*models.AuthResponse, error)
+}
+
+// pendingLogin guarda los secretos de un inicio de sesión en curso hasta el callback
+type pendingLogin struct {
+	nonce        string
+	codeVerifier string
+	linkUserID   int64 // usuario que vincula su cuenta; 0 en un inicio de sesión
+	expiresAt    time.Time
+}
+
+// ssoService implementa SSOService
+type ssoService struct {
+	provider OIDCProvider
+	roles    OIDCRoleMapping
+	userRepo repository.UserRepository
+	auth     *authService
+
+	mu      sync.Mutex
+	pending map[string]pendingLogin
+}
+
+// NewSSOService crea una nueva instancia de SSOService. Las sesiones se emiten
+// igual que en el login con contraseña.
+func NewSSOService(
+	provider OIDCProvider,
+	roles OIDCRoleMapping,
+	userRepo repository.UserRepository,
+	sessionRepo repository.AuthSessionRepository,
+	tokens TokenIssuer,
+	audit AuditRecorder,
+) SSOService {
+	return &ssoService{
+		provider: provider,
+		roles:    roles,
+		userRepo: userRepo,
+		auth: &authService{
+			userRepo:    userRepo,
+			sessionRepo: sessionRepo,
+			tokens:      tokens,
+			audit:       audit,
+		},
+		pending: make(map[string]pendingLogin),
+	}
+}
+
+// Start genera el state, el nonce y el code verifier PKCE de un nuevo inicio de
+// sesión y retorna la URL del IdP a la que redirigir al usuario
+func (s *ssoService) Start(ctx context.Context) (*SSORedirect, error) {
+	return s.start(ctx, 0)
+}
+
+// StartLink inicia el flujo como Start, pero al completarlo la identidad del IdP se
+// vincula a la cuenta del usuario autenticado en lugar de buscar o dar de alta una
+func (s *ssoService) StartLink(ctx context.Context, userID int64) (*SSORedirect, error) {
+	return s.start(ctx, userID)
+}
+
+func (s *ssoService) start(ctx context.Context, linkUserID int64) (*SSORedirect, error) {
+	state, err := generateRandomToken(32)
+	if err != nil {
+		return nil, models.ErrInternal.WithInternal(err)
+	}
+	nonce, err := generateRandomToken(32)
+	if err != nil {
+		return nil, models.ErrInternal.WithInternal(err)
+	}
+	verifier, err := generateRandomToken(32)
+	if err != nil {
+		return nil, models.ErrInternal.WithInternal(err)
+	}
+
+	authURL, err := s.provider.AuthCodeURL(ctx, state, nonce, pkceChallenge(verifier))
+	if err != nil {
+		return nil, models.ErrSSOFailed.WithInternal(err)
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(ssoStateTTL)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, login := range s.pending {
+		if now.After(login.expiresAt) {
+			delete(s.pending, key)
+		}
+	}
+	if len(s.pending) >= ssoMaxPending {
+		return nil, models.ErrSSOBusy
+	}
+	s.pending[state] = pendingLogin{nonce: nonce, codeVerifier: verifier, linkUserID: linkUserID, expiresAt: expiresAt}
+
+	return &SSORedirect{AuthURL: authURL, State: state, ExpiresAt: expiresAt}, nil
+}
+
+// Complete canjea el código del IdP e inicia una sesión al usuario vinculado a la
+// identidad (issuer y subject) del IdP. Si no hay ninguno se le da de alta, salvo que
+// ya exista una cuenta con su email: esa cuenta solo se vincula de forma explícita,
+// completando un flujo iniciado con StartLink por su propietario.
+func (s *ssoService) Complete(ctx context.Context, state, code string) (*models.AuthResponse, error) {
+	login, ok := s.takePending(state)
+	if !ok {
+		return nil, models.ErrInvalidSSOState
+	}
+
+	identity, err := s.provider.Exchange(ctx, code, login.codeVerifier)
+	if err != nil {
+		return nil, models.ErrSSOFailed.WithInternal(err)
+	}
+	if identity.Nonce != login.nonce {
+		return nil, models.ErrSSOFailed
+	}
+	if identity.Email == "" || (identity.EmailVerified != nil && !*identity.EmailVerified) {
+		return nil, models.ErrSSOFailed
+	}
+
+	role, ok := s.roles.RoleFor(identity.Groups)
+	if !ok {
+		return nil, models.ErrSSORoleNotMapped
+	}
+
+	user, err := s.userRepo.GetByOIDCIdentity(ctx, identity.Issuer, identity.Subject)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+
+	switch {
+	case login.linkUserID != 0:
+		user, err = s.link(ctx, login.linkUserID, user, identity)
+	case user == nil:
+		user, err = s.provision(ctx, identity, role)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.Active {
+		return nil, models.ErrUserInactive
+	}
+	return s.auth.startSession(ctx, user)
+}
+
+// provision da de alta al usuario de una identidad del IdP sin cuenta vinculada
+func (s *ssoService) provision(ctx context.Context, identity *models.OIDCIdentity, role models.UserRole) (*models.User, error) {
+	existing, err := s.userRepo.GetByEmail(ctx, identity.Email)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	if existing != nil {
+		return nil, models.ErrSSOAccountNotLinked
+	}
+
+	countries, clusterIDs, ok := s.roles.ScopeFor(role, identity.Groups)
+	if !ok {
+		return nil, models.ErrSSOScopeNotMapped
+	}
+
+	// Los usuarios del IdP no tienen contraseña local: el login con contraseña los rechaza
+	user := &models.User{
+		Email:       identity.Email,
+		Role:        role,
+		Active:      true,
+		Countries:   countries,
+		ClusterIDs:  clusterIDs,
+		OIDCIssuer:  identity.Issuer,
+		OIDCSubject: identity.Subject,
+	}
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	s.auth.recordAsUser(ctx, models.AuditUserProvisioned, user, nil)
+	return user, nil
+}
+
+// link vincula la identidad del IdP a la cuenta del usuario que inició el flujo.
+// linked es el usuario que ya tiene vinculada la identidad, si lo hay.
+func (s *ssoService) link(ctx context.Context, userID int64, linked *models.User, identity *models.OIDCIdentity) (*models.User, error) {
+	if linked != nil {
+		if linked.ID != userID {
+			return nil, models.ErrSSOIdentityConflict
+		}
+		return linked, nil
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	if user == nil {
+		return nil, models.ErrUserNotFound
+	}
+	if user.OIDCSubject != "" {
+		return nil, models.ErrSSOIdentityConflict
+	}
+
+	before := *user
+	user.OIDCIssuer = identity.Issuer
+	user.OIDCSubject = identity.Subject
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		if errors.Is(err, repository.ErrOIDCIdentityTaken) {
+			return nil, models.ErrSSOIdentityConflict
+		}
+		return nil, models.ErrDatabase(err)
+	}
+	s.auth.recordAsUser(ctx, models.AuditUserSSOLinked, user, before)
+	return user, nil
+}
+
+// takePending retira el inicio de sesión asociado al state; cada state es de un solo uso
+func (s *ssoService) takePending(state string) (pendingLogin, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	login, ok := s.pending[state]
+	if !ok {
+		return pendingLogin{}, false
+	}
+	delete(s.pending, state)
+	if time.Now().After(login.expiresAt) {
+		return pendingLogin{}, false
+	}
+	return login, true
+}
+
+// pkceChallenge calcula el code challenge S256 de un code verifier
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// appendUnique añade a list los valores que todavía no contiene
+func appendUnique[T comparable](list []T, values ...T) []T {
+	for _, v := range values {
+		found := false
+		for _, existing := range list {
+			if existing == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// containsGroup indica si alguno de los grupos del usuario está en la lista configurada
+func containsGroup(configured, groups []string) bool {
+	for _, c := range configured {
+		for _, g := range groups {
+			if strings.EqualFold(c, g) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,283 @@
+// Package services contiene la evaluación de la alineación con la Taxonomía de la UE.
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/authz"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/repository"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/scoring"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/taxonomy"
+)
+
+// TaxonomyService define las operaciones de la Taxonomía de la UE: la correspondencia de
+// las medidas con sus actividades y la evaluación de la alineación de las tiendas
+type TaxonomyService interface {
+	ListActivities() []models.TaxonomyActivity
+	ListMappings(ctx context.Context) ([]models.MeasureTaxonomy, error)
+	SetMapping(ctx context.Context, measureName string, req *models.SetMeasureTaxonomyRequest) (*models.MeasureTaxonomy, error)
+	DeleteMapping(ctx context.Context, measureName string) error
+	GetShopReport(ctx context.Context, shopID int64) (*models.ShopTaxonomyReport, error)
+	GetPortfolioReport(ctx context.Context) (*models.TaxonomyPortfolioReport, error)
+}
+
+// taxonomyService implementa TaxonomyService
+type taxonomyService struct {
+	taxonomyRepo repository.TaxonomyRepository
+	shopRepo     repository.ShopRepository
+	measureRepo  repository.MeasureRepository
+	riskRepo     repository.RiskRepository
+	overrideRepo repository.ShopRiskOverrideRepository
+	scenarioRepo repository.ClusterRiskScenarioRepository
+	scorers      scoring.Provider
+	recalculator ShopRiskRecalculator
+	audit        AuditRecorder
+}
+
+// NewTaxonomyService crea una nueva instancia de TaxonomyService
+func NewTaxonomyService(
+	taxonomyRepo repository.TaxonomyRepository,
+	shopRepo repository.ShopRepository,
+	measureRepo repository.MeasureRepository,
+	riskRepo repository.RiskRepository,
+	overrideRepo repository.ShopRiskOverrideRepository,
+	scenarioRepo repository.ClusterRiskScenarioRepository,
+	scorers scoring.Provider,
+	recalculator ShopRiskRecalculator,
+	audit AuditRecorder,
+) TaxonomyService {
+	return &taxonomyService{
+		taxonomyRepo: taxonomyRepo,
+		shopRepo:     shopRepo,
+		measureRepo:  measureRepo,
+		riskRepo:     riskRepo,
+		overrideRepo: overrideRepo,
+		scenarioRepo: scenarioRepo,
+		scorers:      scorers,
+		recalculator: recalculator,
+		audit:        audit,
+	}
+}
+
+// ListActivities obtiene el catálogo de actividades de adaptación
+func (s *taxonomyService) ListActivities() []models.TaxonomyActivity {
+	return taxonomy.Activities()
+}
+
+// ListMappings obtiene la correspondencia con la Taxonomía de las medidas que la tienen
+func (s *taxonomyService) ListMappings(ctx context.Context) ([]models.MeasureTaxonomy, error) {
+	mappings, err := s.taxonomyRepo.ListMappings(ctx)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	return mappings, nil
+}
+
+// SetMapping asigna a una medida una actividad de la Taxonomía y el cumplimiento de sus
+// criterios DNSH, y recalcula el TaxonomyCoverage guardado de las tiendas que tienen
+// aplicada la medida
+func (s *taxonomyService) SetMapping(ctx context.Context, measureName string, req *models.SetMeasureTaxonomyRequest) (*models.MeasureTaxonomy, error) {
+	measure, err := s.measureRepo.GetByName(ctx, measureName)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	if measure == nil {
+		return nil, models.ErrMeasureNotFound
+	}
+	if _, ok := taxonomy.FindActivity(req.ActivityCode); !ok {
+		return nil, models.ErrInvalidInput("actividad de la Taxonomía desconocida: " + req.ActivityCode)
+	}
+
+	before, err := s.taxonomyRepo.GetMapping(ctx, measureName)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+
+	mapping := &models.MeasureTaxonomy{
+		MeasureName:  measureName,
+		ActivityCode: req.ActivityCode,
+		DNSH:         req.DNSH,
+		Notes:        req.Notes,
+	}
+	if err := s.taxonomyRepo.UpsertMapping(ctx, mapping); err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+
+	s.audit.Record(ctx, models.AuditTaxonomyMappingSet, models.AuditEntityMeasureTaxonomy, measureName, before, mapping)
+
+	if err := s.recalculateMeasureShops(ctx, measureName); err != nil {
+		return nil, err
+	}
+	return mapping, nil
+}
+
+// DeleteMapping elimina la correspondencia de una medida con la Taxonomía y recalcula
+// el TaxonomyCoverage guardado de las tiendas que tienen aplicada la medida
+func (s *taxonomyService) DeleteMapping(ctx context.Context, measureName string) error {
+	before, err := s.taxonomyRepo.GetMapping(ctx, measureName)
+	if err != nil {
+		return models.ErrDatabase(err)
+	}
+
+	if err := s.taxonomyRepo.DeleteMapping(ctx, measureName); err != nil {
+		if errors.Is(err, repository.ErrTaxonomyMappingNotFound) {
+			return models.ErrTaxonomyNotMapped
+		}
+		return models.ErrDatabase(err)
+	}
+
+	s.audit.Record(ctx, models.AuditTaxonomyMappingRemoved, models.AuditEntityMeasureTaxonomy, measureName, before, nil)
+
+	return s.recalculateMeasureShops(ctx, measureName)
+}
+
+// recalculateMeasureShops recalcula las tiendas que tienen aplicada la medida
+func (s *taxonomyService) recalculateMeasureShops(ctx context.Context, measureName string) error {
+	scorer, err := s.scorers.Current(ctx)
+	if err != nil {
+		return err
+	}
+	return s.recalculator.RecalculateMeasureShops(ctx, scorer, measureName, models.TriggerTaxonomyChanged)
+}
+
+// GetShopReport evalúa la alineación de una tienda con la Taxonomía
+func (s *taxonomyService) GetShopReport(ctx context.Context, shopID int64) (*models.ShopTaxonomyReport, error) {
+	shop, err := s.shopRepo.GetByID(ctx, shopID)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	if shop == nil || !authz.ScopeFromContext(ctx).AllowsShop(shop.Country, shop.ClusterID) {
+		return nil, models.ErrShopNotFound
+	}
+
+	catalog, err := loadTaxonomyCatalog(ctx, s.taxonomyRepo)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	return s.assessShop(ctx, catalog, shop)
+}
+
+// GetPortfolioReport evalúa la alineación de todas las tiendas dentro del ámbito del usuario
+func (s *taxonomyService) GetPortfolioReport(ctx context.Context) (*models.TaxonomyPortfolioReport, error) {
+	catalog, err := loadTaxonomyCatalog(ctx, s.taxonomyRepo)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+
+	report := &models.TaxonomyPortfolioReport{
+		ShopsByStatus: map[models.TaxonomyAlignmentStatus]int64{
+			models.TaxonomyAligned:          0,
+			models.TaxonomyPartiallyAligned: 0,
+			models.TaxonomyNotAligned:       0,
+			models.TaxonomyNoMaterialRisks:  0,
+		},
+		Shops: []models.ShopTaxonomySummary{},
+	}
+
+	// Los datos de todas las tiendas se leen de una vez, no tienda a tienda
+	data, err := s.taxonomyRepo.GetPortfolioShopData(ctx)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	scorer, err := s.scorers.Current(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var totalAlignment float64
+	for i := range data.Shops {
+		shop := &data.Shops[i]
+		risks := portfolioShopRisks(data, nil, scorer, shop, models.ScenarioSelection{})
+		shopReport := catalog.assessMeasures(shop, risks, data.ShopMeasures[shop.ID])
+
+		report.TotalShops++
+		report.ShopsByStatus[shopReport.Status]++
+		totalAlignment += shopReport.AlignmentPercentage
+		report.AlignedCapEx += shopReport.AlignedCapEx
+		report.TotalCapEx += shopReport.TotalCapEx
+
+		summary := models.ShopTaxonomySummary{
+			ShopID:              shopReport.ShopID,
+			ShopLocation:        shopReport.ShopLocation,
+			Status:              shopReport.Status,
+			AlignmentPercentage: shopReport.AlignmentPercentage,
+		}
+		for _, r := range shopReport.MaterialRisks {
+			if !r.Addressed {
+				summary.UnaddressedRisks = append(summary.UnaddressedRisks, r.RiskName)
+			}
+		}
+		report.Shops = append(report.Shops, summary)
+	}
+
+	if report.TotalShops > 0 {
+		report.AverageAlignment = totalAlignment / float64(report.TotalShops)
+	}
+	if report.TotalCapEx > 0 {
+		report.AlignedCapExPercentage = report.AlignedCapEx / report.TotalCapEx * 100
+	}
+	return report, nil
+}
+
+// assessShop evalúa una tienda con sus riesgos actuales
+func (s *taxonomyService) assessShop(ctx context.Context, catalog *taxonomyCatalog, shop *models.Shop) (*models.ShopTaxonomyReport, error) {
+	risks, _, err := loadShopRisks(ctx, s.scorers, s.riskRepo, s.scenarioRepo, s.overrideRepo, shop, models.ScenarioSelection{})
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := catalog.assess(ctx, s.shopRepo, shop, risks)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	return report, nil
+}
+
+// taxonomyCatalog contiene los datos comunes a la evaluación de todas las tiendas
+type taxonomyCatalog struct {
+	mappings     map[string]models.MeasureTaxonomy
+	riskMeasures map[string][]string
+}
+
+// loadTaxonomyCatalog carga la correspondencia de las medidas con la Taxonomía y los
+// riesgos que reduce cada medida
+func loadTaxonomyCatalog(ctx context.Context, repo repository.TaxonomyRepository) (*taxonomyCatalog, error) {
+	mappings, err := repo.ListMappings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	riskMeasures, err := repo.GetRiskMeasures(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	catalog := &taxonomyCatalog{
+		mappings:     make(map[string]models.MeasureTaxonomy, len(mappings)),
+		riskMeasures: riskMeasures,
+	}
+	for _, m := range mappings {
+		catalog.mappings[m.MeasureName] = m
+	}
+	return catalog, nil
+}
+
+// assess evalúa la alineación de una tienda con los riesgos indicados
+func (c *taxonomyCatalog) assess(ctx context.Context, shopRepo repository.ShopRepository, shop *models.Shop, risks []models.RiskDetail) (*models.ShopTaxonomyReport, error) {
+	measures, err := shopRepo.GetShopMeasures(ctx, shop.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.assessMeasures(shop, risks, measures), nil
+}
+
+// assessMeasures evalúa la alineación de una tienda con los riesgos y las medidas indicados
+func (c *taxonomyCatalog) assessMeasures(shop *models.Shop, risks []models.RiskDetail, measures []models.ShopMeasure) *models.ShopTaxonomyReport {
+	report := taxonomy.Assess(risks, measures, c.riskMeasures, c.mappings)
+	report.ShopID = shop.ID
+	report.ShopLocation = shop.Location
+	report.Country = shop.Country
+	return &report
+}
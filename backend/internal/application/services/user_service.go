@@ -0,0 +1,214 @@
+// Package services contiene la lógica de negocio de la aplicación.
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// UserService define las operaciones de administración de usuarios
+type UserService interface {
+	List(ctx context.Context, filter *models.UserFilterRequest) ([]models.User, error)
+	Invite(ctx context.Context, req *models.InviteUserRequest) (*models.UserCredentialsResponse, error)
+	ChangeRole(ctx context.Context, actorID, userID int64, role models.UserRole) (*models.User, error)
+	SetScope(ctx context.Context, userID int64, req *models.SetUserScopeRequest) (*models.User, error)
+	Deactivate(ctx context.Context, actorID, userID int64) (*models.User, error)
+	Reactivate(ctx context.Context, userID int64) (*models.User, error)
+	ResetPassword(ctx context.Context, userID int64) (*models.UserCredentialsResponse, error)
+}
+
+// userService implementa UserService
+type userService struct {
+	userRepo    repository.UserRepository
+	sessionRepo repository.AuthSessionRepository
+	audit       AuditRecorder
+}
+
+// NewUserService crea una nueva instancia de UserService
+func NewUserService(userRepo repository.UserRepository, sessionRepo repository.AuthSessionRepository, audit AuditRecorder) UserService {
+	return &userService{userRepo: userRepo, sessionRepo: sessionRepo, audit: audit}
+}
+
+// List obtiene los usuarios que cumplen los filtros
+func (s *userService) List(ctx context.Context, filter *models.UserFilterRequest) ([]models.User, error) {
+	users, err := s.userRepo.List(ctx, filter)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	for i := range users {
+		users[i].Password = ""
+	}
+	return users, nil
+}
+
+// Invite da de alta un usuario con el rol indicado y una contraseña temporal
+func (s *userService) Invite(ctx context.Context, req *models.InviteUserRequest) (*models.UserCredentialsResponse, error) {
+	exists, err := s.userRepo.EmailExists(ctx, req.Email)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	if exists {
+		return nil, models.ErrDuplicateEmail
+	}
+
+	password, hash, err := temporaryPassword()
+	if err != nil {
+		return nil, models.ErrInternal.WithInternal(err)
+	}
+
+	user := &models.User{
+		Email:      req.Email,
+		Password:   hash,
+		Role:       req.Role,
+		Active:     true,
+		Countries:  req.Countries,
+		ClusterIDs: req.ClusterIDs,
+	}
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+
+	user.Password = ""
+	s.audit.Record(ctx, models.AuditUserInvited, models.AuditEntityUser, auditID(user.ID), nil, user)
+	return &models.UserCredentialsResponse{User: user, TemporaryPassword: password}, nil
+}
+
+// ChangeRole cambia el rol de un usuario. Sus sesiones se revocan para que los
+// tokens emitidos con el rol anterior dejen de ser válidos.
+func (s *userService) ChangeRole(ctx context.Context, actorID, userID int64, role models.UserRole) (*models.User, error) {
+	if actorID == userID {
+		return nil, models.ErrSelfModification
+	}
+
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Role == role {
+		user.Password = ""
+		return user, nil
+	}
+
+	before := *user
+	user.Role = role
+	return s.saveAndRevokeSessions(ctx, models.AuditUserRoleChanged, before, user)
+}
+
+// SetScope limita el usuario a los países y/o clusters indicados. Sus sesiones se
+// revocan porque el ámbito viaja en los tokens ya emitidos.
+func (s *userService) SetScope(ctx context.Context, userID int64, req *models.SetUserScopeRequest) (*models.User, error) {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	before := *user
+	user.Countries = req.Countries
+	user.ClusterIDs = req.ClusterIDs
+	return s.saveAndRevokeSessions(ctx, models.AuditUserScopeChanged, before, user)
+}
+
+// Deactivate desactiva un usuario y cierra todas sus sesiones
+func (s *userService) Deactivate(ctx context.Context, actorID, userID int64) (*models.User, error) {
+	if actorID == userID {
+		return nil, models.ErrSelfModification
+	}
+
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	before := *user
+	user.Active = false
+	return s.saveAndRevokeSessions(ctx, models.AuditUserDeactivated, before, user)
+}
+
+// Reactivate vuelve a permitir el acceso a un usuario desactivado
+func (s *userService) Reactivate(ctx context.Context, userID int64) (*models.User, error) {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	before := *user
+	user.Active = true
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, s.mapUserError(err)
+	}
+	user.Password = ""
+	s.audit.Record(ctx, models.AuditUserReactivated, models.AuditEntityUser, auditID(user.ID), before, user)
+	return user, nil
+}
+
+// ResetPassword sustituye la contraseña de un usuario por una temporal y cierra sus sesiones
+func (s *userService) ResetPassword(ctx context.Context, userID int64) (*models.UserCredentialsResponse, error) {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	password, hash, err := temporaryPassword()
+	if err != nil {
+		return nil, models.ErrInternal.WithInternal(err)
+	}
+
+	// El log solo registra que hubo un cambio de contraseña, nunca su hash
+	before := *user
+	user.Password = hash
+	user, err = s.saveAndRevokeSessions(ctx, models.AuditUserPasswordReset, before, user)
+	if err != nil {
+		return nil, err
+	}
+	return &models.UserCredentialsResponse{User: user, TemporaryPassword: password}, nil
+}
+
+// getUser obtiene un usuario o retorna ErrUserNotFound
+func (s *userService) getUser(ctx context.Context, userID int64) (*models.User, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	if user == nil {
+		return nil, models.ErrUserNotFound
+	}
+	return user, nil
+}
+
+// saveAndRevokeSessions guarda el usuario, revoca todas sus sesiones y registra
+// el cambio en auditoría
+func (s *userService) saveAndRevokeSessions(ctx context.Context, action models.AuditAction, before models.User, user *models.User) (*models.User, error) {
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, s.mapUserError(err)
+	}
+	if err := s.sessionRepo.RevokeUserSessions(ctx, user.ID); err != nil {
+		return nil, models.ErrDatabase(err)
+	}
+	user.Password = ""
+	s.audit.Record(ctx, action, models.AuditEntityUser, auditID(user.ID), before, user)
+	return user, nil
+}
+
+func (s *userService) mapUserError(err error) error {
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return models.ErrUserNotFound
+	}
+	return models.ErrDatabase(err)
+}
+
+// temporaryPassword genera una contraseña aleatoria y su hash bcrypt
+func temporaryPassword() (string, string, error) {
+	password, err := generateRandomToken(12)
+	if err != nil {
+		return "", "", err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", "", err
+	}
+	return password, string(hash), nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -13,6 +14,7 @@ type Config struct {
 	Server   ServerConfig
 	Database DatabaseConfig
 	JWT      JWTConfig
+	OIDC     OIDCConfig
 	App      AppConfig
 }
 
@@ -44,6 +46,31 @@ type JWTConfig struct {
 	Issuer        string
 }
 
+// OIDCConfig contiene la configuración del inicio de sesión único (SSO) con OpenID Connect
+type OIDCConfig struct {
+	IssuerURL     string
+	ClientID      string
+	ClientSecret  string
+	RedirectURL   string
+	Scopes        []string
+	GroupsClaim   string   // claim del ID token con los grupos del usuario
+	AdminGroups   []string // grupos del IdP que otorgan el rol admin
+	ManagerGroups []string // grupos del IdP que otorgan el rol manager
+	ViewerGroups  []string // grupos del IdP que otorgan el rol viewer
+	DefaultRole   string   // rol si ningún grupo coincide; vacío rechaza el acceso
+	// Ámbito de datos de los usuarios dados de alta por SSO, por grupo del IdP
+	// (formato grupo:valor separado por comas). Salvo los admins, un usuario sin
+	// ningún grupo de ámbito no se da de alta.
+	CountryGroups map[string][]string // grupos del IdP que dan acceso a países
+	ClusterGroups map[string][]int64  // grupos del IdP que dan acceso a clusters
+	GlobalGroups  []string            // grupos del IdP que dan acceso a todos los datos
+}
+
+// Enabled indica si el inicio de sesión con OIDC está configurado
+func (c OIDCConfig) Enabled() bool {
+	return c.IssuerURL != "" && c.ClientID != ""
+}
+
 // AppConfig contiene configuración general de la aplicación
 type AppConfig struct {
 	Environment string // development, staging, production
@@ -76,6 +103,21 @@ func Load() (*Config, error) {
 			RefreshExpiry: getDurationOrDefault("JWT_REFRESH_EXPIRY", 7*24*time.Hour),
 			Issuer:        getEnvOrDefault("JWT_ISSUER", "climate-invest-optimizer"),
 		},
+		OIDC: OIDCConfig{
+			IssuerURL:     os.Getenv("OIDC_ISSUER_URL"),
+			ClientID:      os.Getenv("OIDC_CLIENT_ID"),
+			ClientSecret:  os.Getenv("OIDC_CLIENT_SECRET"),
+			RedirectURL:   os.Getenv("OIDC_REDIRECT_URL"),
+			Scopes:        getEnvSliceOrDefault("OIDC_SCOPES", []string{"openid", "email", "profile"}),
+			GroupsClaim:   getEnvOrDefault("OIDC_GROUPS_CLAIM", "groups"),
+			AdminGroups:   getEnvSliceOrDefault("OIDC_ADMIN_GROUPS", nil),
+			ManagerGroups: getEnvSliceOrDefault("OIDC_MANAGER_GROUPS", nil),
+			ViewerGroups:  getEnvSliceOrDefault("OIDC_VIEWER_GROUPS", nil),
+			DefaultRole:   getEnvOrDefault("OIDC_DEFAULT_ROLE", "viewer"),
+			CountryGroups: getEnvGroupMap("OIDC_COUNTRY_GROUPS"),
+			ClusterGroups: getEnvGroupIDMap("OIDC_CLUSTER_GROUPS"),
+			GlobalGroups:  getEnvSliceOrDefault("OIDC_GLOBAL_GROUPS", nil),
+		},
 		App: AppConfig{
 			Environment: getEnvOrDefault("APP_ENV", "development"),
 			Debug:       getBoolOrDefault("DEBUG", true),
@@ -101,6 +143,9 @@ func (c *Config) Validate() error {
 			return fmt.Errorf("JWT_SECRET must be set in production")
 		}
 	}
+	if c.OIDC.Enabled() && c.OIDC.RedirectURL == "" {
+		return fmt.Errorf("OIDC_REDIRECT_URL is required when OIDC is enabled")
+	}
 	return nil
 }
 
@@ -169,3 +214,29 @@ func getEnvSliceOrDefault(key string, defaultValue []string) []string {
 	}
 	return defaultValue
 }
+
+// getEnvGroupMap parsea pares grupo:valor separados por comas; un grupo puede repetirse
+func getEnvGroupMap(key string) map[string][]string {
+	result := make(map[string][]string)
+	for _, pair := range getEnvSliceOrDefault(key, nil) {
+		group, value, ok := strings.Cut(pair, ":")
+		group, value = strings.TrimSpace(group), strings.TrimSpace(value)
+		if ok && group != "" && value != "" {
+			result[group] = append(result[group], value)
+		}
+	}
+	return result
+}
+
+// getEnvGroupIDMap parsea pares grupo:ID separados por comas, ignorando los IDs inválidos
+func getEnvGroupIDMap(key string) map[string][]int64 {
+	result := make(map[string][]int64)
+	for group, values := range getEnvGroupMap(key) {
+		for _, value := range values {
+			if id, err := strconv.ParseInt(value, 10, 64); err == nil {
+				result[group] = append(result[group], id)
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,43 @@
+// Package audit transporta en el contexto quién realiza cada petición y con qué
+// ID de petición, para que los servicios puedan registrar sus cambios.
+package audit
+
+import (
+	"context"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+)
+
+// Actor identifica al autor de una operación: un usuario autenticado con JWT o
+// una API key. Un actor vacío representa al propio sistema o a una petición anónima.
+type Actor struct {
+	UserID   int64
+	APIKeyID int64
+	Email    string
+	Role     models.UserRole
+}
+
+type actorKey struct{}
+type requestIDKey struct{}
+
+// WithActor retorna un contexto que transporta el actor de la petición
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext retorna el actor del contexto, o un actor vacío si no hay ninguno
+func ActorFromContext(ctx context.Context) Actor {
+	actor, _ := ctx.Value(actorKey{}).(Actor)
+	return actor
+}
+
+// WithRequestID retorna un contexto que transporta el ID de la petición
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext retorna el ID de la petición del contexto, o una cadena vacía
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
@@ -0,0 +1,123 @@
+// Package authz define la matriz de permisos por rol de la API.
+//
+// Es la única fuente de verdad sobre qué puede hacer cada rol: las rutas solo
+// declaran el permiso que requieren y el middleware consulta esta matriz.
+package authz
+
+import "github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+
+// Permission representa una acción sobre un recurso de la API
+type Permission string
+
+const (
+	// ShopsRead permite consultar tiendas, sus medidas y su evaluación de riesgos
+	ShopsRead Permission = "shops:read"
+	// ShopsWrite permite crear, modificar y eliminar tiendas
+	ShopsWrite Permission = "shops:write"
+	// ShopMeasuresWrite permite aplicar y retirar medidas de una tienda
+	ShopMeasuresWrite Permission = "shops:measures:write"
+	// ShopRisksWrite permite ajustar la exposición/sensibilidad de una tienda
+	ShopRisksWrite Permission = "shops:risks:write"
+	// CatalogRead permite consultar clusters, riesgos, medidas y el modelo de scoring
+	CatalogRead Permission = "catalog:read"
+	// CatalogWrite permite modificar el modelo de scoring y las proyecciones climáticas
+	CatalogWrite Permission = "catalog:write"
+	// OptimizationRun permite ejecutar optimizaciones de presupuesto
+	OptimizationRun Permission = "optimization:run"
+	// DashboardRead permite consultar las métricas del dashboard y los informes guardados
+	DashboardRead Permission = "dashboard:read"
+	// ReportsGenerate permite generar y guardar informes de divulgación
+	ReportsGenerate Permission = "reports:generate"
+	// UsersManage permite administrar usuarios
+	UsersManage Permission = "users:manage"
+	// APIKeysManage permite crear y revocar API keys
+	APIKeysManage Permission = "api-keys:manage"
+	// AuditRead permite consultar y exportar el log de auditoría
+	AuditRead Permission = "audit:read"
+)
+
+// viewerPermissions son los permisos de solo lectura comunes a todos los roles
+var viewerPermissions = []Permission{
+	ShopsRead,
+	CatalogRead,
+	DashboardRead,
+}
+
+// managerPermissions añaden la operativa sobre tiendas existentes
+var managerPermissions = append(append([]Permission{}, viewerPermissions...),
+	ShopMeasuresWrite,
+	ShopRisksWrite,
+	OptimizationRun,
+	ReportsGenerate,
+)
+
+// adminPermissions añaden la gestión de tiendas, catálogos y usuarios
+var adminPermissions = append(append([]Permission{}, managerPermissions...),
+	ShopsWrite,
+	CatalogWrite,
+	UsersManage,
+	APIKeysManage,
+	AuditRead,
+)
+
+// matrix asocia cada rol con sus permisos
+var matrix = map[models.UserRole]map[Permission]bool{
+	models.RoleViewer:  toSet(viewerPermissions),
+	models.RoleManager: toSet(managerPermissions),
+	models.RoleAdmin:   toSet(adminPermissions),
+}
+
+// Allowed indica si el rol tiene el permiso indicado
+func Allowed(role models.UserRole, perm Permission) bool {
+	return matrix[role][perm]
+}
+
+// Permissions retorna los permisos de un rol
+func Permissions(role models.UserRole) []Permission {
+	switch role {
+	case models.RoleAdmin:
+		return append([]Permission{}, adminPermissions...)
+	case models.RoleManager:
+		return append([]Permission{}, managerPermissions...)
+	case models.RoleViewer:
+		return append([]Permission{}, viewerPermissions...)
+	}
+	return nil
+}
+
+// apiKeyExcluded son los permisos que nunca se conceden a una API key: la gestión
+// de credenciales queda reservada a usuarios
+var apiKeyExcluded = map[Permission]bool{
+	UsersManage:   true,
+	APIKeysManage: true,
+}
+
+// GrantableToAPIKey indica si una API key con el rol indicado puede recibir el permiso
+func GrantableToAPIKey(role models.UserRole, perm Permission) bool {
+	return Allowed(role, perm) && !apiKeyExcluded[perm]
+}
+
+// AllowedForAPIKey indica si una API key tiene el permiso. La clave hereda los
+// permisos de su rol; si restrict no está vacío, solo conserva los indicados.
+func AllowedForAPIKey(role models.UserRole, restrict []Permission, perm Permission) bool {
+	if !GrantableToAPIKey(role, perm) {
+		return false
+	}
+	if len(restrict) == 0 {
+		return true
+	}
+	for _, p := range restrict {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+func toSet(perms []Permission) map[Permission]bool {
+	set := make(map[Permission]bool, len(perms))
+	for _, p := range perms {
+		set[p] = true
+	}
+	return set
+}
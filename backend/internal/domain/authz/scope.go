@@ -0,0 +1,104 @@
+package authz
+
+import (
+	"context"
+	"strings"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+)
+
+// Scope limita los datos visibles para un usuario a ciertos países y/o clusters.
+//
+// Cada dimensión no vacía debe cumplirse: un usuario con países y clusters solo
+// ve las tiendas de esos clusters situadas en esos países. Un ámbito vacío es
+// global y no restringe nada.
+type Scope struct {
+	Countries  []string `json:"countries,omitempty"`
+	ClusterIDs []int64  `json:"cluster_ids,omitempty"`
+}
+
+// IsGlobal indica si el ámbito no restringe ningún dato
+func (s Scope) IsGlobal() bool {
+	return len(s.Countries) == 0 && len(s.ClusterIDs) == 0
+}
+
+// AllowsCountry indica si el país está dentro del ámbito
+func (s Scope) AllowsCountry(country string) bool {
+	if len(s.Countries) == 0 {
+		return true
+	}
+	for _, c := range s.Countries {
+		if strings.EqualFold(c, country) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsCluster indica si el cluster está dentro del ámbito. Los clusters solo
+// se restringen por ClusterIDs; el ámbito por país se aplica a sus tiendas.
+func (s Scope) AllowsCluster(clusterID int64) bool {
+	if len(s.ClusterIDs) == 0 {
+		return true
+	}
+	for _, id := range s.ClusterIDs {
+		if id == clusterID {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsShop indica si una tienda del país y cluster indicados está dentro del ámbito
+func (s Scope) AllowsShop(country string, clusterID int64) bool {
+	return s.AllowsCountry(country) && s.AllowsCluster(clusterID)
+}
+
+// Within indica si el ámbito está contenido en parent: cada dimensión que parent
+// restringe también debe restringirse aquí, y solo a valores que parent permite
+func (s Scope) Within(parent Scope) bool {
+	if len(parent.Countries) > 0 {
+		if len(s.Countries) == 0 {
+			return false
+		}
+		for _, c := range s.Countries {
+			if !parent.AllowsCountry(c) {
+				return false
+			}
+		}
+	}
+	if len(parent.ClusterIDs) > 0 {
+		if len(s.ClusterIDs) == 0 {
+			return false
+		}
+		for _, id := range s.ClusterIDs {
+			if !parent.AllowsCluster(id) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+type scopeKey struct{}
+
+// WithScope retorna un contexto que transporta el ámbito del usuario autenticado
+func WithScope(ctx context.Context, scope Scope) context.Context {
+	return context.WithValue(ctx, scopeKey{}, scope)
+}
+
+// ScopeFromContext retorna el ámbito del contexto, o un ámbito global si no hay ninguno
+func ScopeFromContext(ctx context.Context) Scope {
+	scope, _ := ctx.Value(scopeKey{}).(Scope)
+	return scope
+}
+
+// UserScope retorna el ámbito asignado a un usuario
+func UserScope(user *models.User) Scope {
+	return Scope{Countries: user.Countries, ClusterIDs: user.ClusterIDs}
+}
+
+// APIKeyScope retorna el ámbito asignado a una API key
+func APIKeyScope(key *models.APIKey) Scope {
+	return Scope{Countries: key.Countries, ClusterIDs: key.ClusterIDs}
+}
@@ -0,0 +1,77 @@
+package finance
+
+import (
+	"math"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+)
+
+// AnnualCarbonReduction calcula las tCO2e anuales que evita una medida en una tienda
+// con la huella de carbono anual indicada
+func AnnualCarbonReduction(measure models.Measure, footprint float64) float64 {
+	return footprint * clampReduction(measure.CarbonReduction)
+}
+
+// ProjectedFootprint calcula la huella anual de una tienda tras implantar las medidas
+// indicadas. Las reducciones se combinan de forma multiplicativa, de modo que la huella
+// nunca queda por debajo de cero.
+func ProjectedFootprint(footprint float64, measures []models.Measure) float64 {
+	projected := footprint
+	for _, m := range measures {
+		projected *= 1 - clampReduction(m.CarbonReduction)
+	}
+	return projected
+}
+
+// NetCarbonReduction calcula las tCO2e que evita una reducción anual durante los años
+// de evaluación descontando las emisiones de implantación
+func (p Parameters) NetCarbonReduction(annualReduction, embodied float64) float64 {
+	return annualReduction*float64(p.Years) - embodied
+}
+
+// CarbonValue calcula el valor actual, al precio del carbono, de una reducción anual de
+// emisiones durante los años de evaluación menos las emisiones de implantación
+func (p Parameters) CarbonValue(annualReduction, embodied float64) float64 {
+	return NPV(p.DiscountRate, p.CarbonPrice*embodied, p.CarbonPrice*annualReduction, p.Years)
+}
+
+// CarbonImpact calcula el impacto en la huella de carbono de implantar las medidas
+// indicadas en una tienda con la huella anual indicada
+func (p Parameters) CarbonImpact(footprint float64, measures []models.Measure) models.CarbonImpact {
+	var embodied float64
+	for _, m := range measures {
+		embodied += m.EmbodiedCarbon
+	}
+	projected := ProjectedFootprint(footprint, measures)
+	return p.newCarbonImpact(footprint, projected, embodied)
+}
+
+// AddCarbonImpact suma el impacto de dos conjuntos de tiendas; se usa para agregar el
+// impacto de una cartera
+func (p Parameters) AddCarbonImpact(a, b models.CarbonImpact) models.CarbonImpact {
+	return p.newCarbonImpact(
+		a.CurrentFootprint+b.CurrentFootprint,
+		a.ProjectedFootprint+b.ProjectedFootprint,
+		a.EmbodiedCarbon+b.EmbodiedCarbon,
+	)
+}
+
+// newCarbonImpact construye el impacto a partir de la huella actual, la proyectada y
+// las emisiones de implantación
+func (p Parameters) newCarbonImpact(current, projected, embodied float64) models.CarbonImpact {
+	annual := current - projected
+	return models.CarbonImpact{
+		CurrentFootprint:   current,
+		ProjectedFootprint: projected,
+		AnnualReduction:    annual,
+		EmbodiedCarbon:     embodied,
+		NetReduction:       p.NetCarbonReduction(annual, embodied),
+		CarbonPrice:        p.CarbonPrice,
+		CarbonValue:        p.CarbonValue(annual, embodied),
+	}
+}
+
+// clampReduction limita una fracción de reducción al intervalo [0, 1]
+func clampReduction(reduction float64) float64 {
+	return math.Min(math.Max(reduction, 0), 1)
+}
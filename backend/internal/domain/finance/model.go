@@ -0,0 +1,195 @@
+// Package finance estima el beneficio económico de las medidas de adaptación: la
+// pérdida anual esperada (EAL) de cada tienda y riesgo, la pérdida que evita cada
+// medida y los indicadores de la inversión (VAN, TIR y plazo de recuperación), además
+// del impacto de las medidas en la huella de carbono de las tiendas.
+//
+// La pérdida anual esperada de un riesgo se calcula como
+//
+//	valor expuesto × fracción expuesta × probabilidad anual × tasa de daño
+//
+// donde el valor expuesto es la superficie de la tienda por el valor por m², y la
+// fracción expuesta, la probabilidad anual y la tasa de daño (consecuencia modulada
+// por la sensibilidad) se obtienen de los niveles cualitativos del riesgo.
+package finance
+
+import (
+	"math"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+)
+
+// Valores por defecto del modelo financiero
+const (
+	DefaultDiscountRate    = 0.06 // Tasa de descuento anual
+	DefaultYears           = 15   // Vida útil de las medidas en años
+	DefaultAssetValuePerM2 = 2500 // Valor del inmueble y existencias por m², en euros
+	DefaultCarbonPrice     = 80   // Precio de la tonelada de CO2e, en euros
+)
+
+// exposedFraction es la fracción del valor de la tienda expuesta al riesgo
+var exposedFraction = map[models.Level]float64{
+	models.LevelVeryLow:  0.2,
+	models.LevelLow:      0.4,
+	models.LevelMedium:   0.6,
+	models.LevelHigh:     0.8,
+	models.LevelVeryHigh: 1.0,
+}
+
+// annualProbability es la probabilidad anual de que el riesgo se materialice
+var annualProbability = map[models.Level]float64{
+	models.LevelVeryLow:  0.01,
+	models.LevelLow:      0.02,
+	models.LevelMedium:   0.05,
+	models.LevelHigh:     0.10,
+	models.LevelVeryHigh: 0.20,
+}
+
+// damageRatio es la fracción del valor expuesto que se pierde cuando el riesgo se materializa
+var damageRatio = map[models.Level]float64{
+	models.LevelVeryLow:  0.02,
+	models.LevelLow:      0.05,
+	models.LevelMedium:   0.10,
+	models.LevelHigh:     0.20,
+	models.LevelVeryHigh: 0.40,
+}
+
+// sensitivityFactor modula la tasa de daño según la sensibilidad de la tienda
+var sensitivityFactor = map[models.Level]float64{
+	models.LevelVeryLow:  0.50,
+	models.LevelLow:      0.75,
+	models.LevelMedium:   1.00,
+	models.LevelHigh:     1.25,
+	models.LevelVeryHigh: 1.50,
+}
+
+// Parameters contiene los parámetros de evaluación de una inversión
+type Parameters struct {
+	DiscountRate    float64 // Tasa de descuento anual (0.06 = 6 %)
+	Years           int     // Años durante los que las medidas evitan pérdidas
+	AssetValuePerM2 float64 // Valor expuesto por m² de tienda
+	CarbonPrice     float64 // Euros por tCO2e emitida o evitada
+}
+
+// DefaultParameters retorna los parámetros por defecto
+func DefaultParameters() Parameters {
+	return Parameters{
+		DiscountRate:    DefaultDiscountRate,
+		Years:           DefaultYears,
+		AssetValuePerM2: DefaultAssetValuePerM2,
+		CarbonPrice:     DefaultCarbonPrice,
+	}
+}
+
+// ParametersFromRequest combina los parámetros de una solicitud de optimización con
+// los valores por defecto
+func ParametersFromRequest(req *models.OptimizeBudgetRequest) Parameters {
+	p := DefaultParameters()
+	if req.DiscountRate != nil {
+		p.DiscountRate = *req.DiscountRate
+	}
+	if req.EvaluationYears > 0 {
+		p.Years = req.EvaluationYears
+	}
+	if req.AssetValuePerM2 > 0 {
+		p.AssetValuePerM2 = req.AssetValuePerM2
+	}
+	if req.CarbonPrice != nil {
+		p.CarbonPrice = *req.CarbonPrice
+	}
+	return p
+}
+
+// ExpectedAnnualLoss calcula la pérdida anual esperada de un riesgo en una tienda de
+// la superficie indicada
+func (p Parameters) ExpectedAnnualLoss(risk models.RiskDetail, surface float64) float64 {
+	value := surface * p.AssetValuePerM2
+	return value *
+		exposedFraction[risk.Exposure] *
+		annualProbability[risk.Probability] *
+		damageRatio[risk.Consequence] * sensitivityFactor[risk.Sensitivity]
+}
+
+// Mitigation representa el efecto de una medida: reduce en Reduction (fracción en
+// [0, 1]) la pérdida esperada de los riesgos indicados
+type Mitigation struct {
+	Risks     []string
+	Reduction float64
+}
+
+// AvoidedAnnualLoss calcula la pérdida anual evitada por un conjunto de medidas a
+// partir de la pérdida esperada de cada riesgo. Las reducciones sobre un mismo riesgo
+// se combinan de forma multiplicativa, de modo que nunca se evita más de lo esperado.
+func AvoidedAnnualLoss(losses map[string]float64, mitigations []Mitigation) float64 {
+	remaining := make(map[string]float64, len(losses))
+	for _, m := range mitigations {
+		reduction := math.Min(math.Max(m.Reduction, 0), 1)
+		for _, name := range m.Risks {
+			if _, ok := losses[name]; !ok {
+				continue
+			}
+			if _, ok := remaining[name]; !ok {
+				remaining[name] = 1
+			}
+			remaining[name] *= 1 - reduction
+		}
+	}
+
+	var avoided float64
+	for name, fraction := range remaining {
+		avoided += losses[name] * (1 - fraction)
+	}
+	return avoided
+}
+
+// Evaluate calcula los indicadores de una inversión que evita la pérdida anual indicada
+// durante los años de evaluación
+func (p Parameters) Evaluate(investment, expectedLoss, avoidedLoss float64) models.FinancialMetrics {
+	metrics := models.FinancialMetrics{
+		ExpectedAnnualLoss: expectedLoss,
+		AvoidedAnnualLoss:  avoidedLoss,
+		NPV:                NPV(p.DiscountRate, investment, avoidedLoss, p.Years),
+		DiscountRate:       p.DiscountRate,
+		Years:              p.Years,
+	}
+	if irr, ok := IRR(investment, avoidedLoss, p.Years); ok {
+		metrics.IRR = &irr
+	}
+	if avoidedLoss > 0 {
+		payback := investment / avoidedLoss
+		metrics.PaybackYears = &payback
+	}
+	return metrics
+}
+
+// NPV calcula el valor actual neto de una inversión inicial que produce un beneficio
+// anual constante al final de cada año
+func NPV(rate, investment, annualBenefit float64, years int) float64 {
+	npv := -investment
+	for t := 1; t <= years; t++ {
+		npv += annualBenefit / math.Pow(1+rate, float64(t))
+	}
+	return npv
+}
+
+// IRR calcula la tasa interna de retorno por bisección. Retorna false si no hay
+// inversión o beneficio, o si la tasa queda fuera de [-99 %, 1000 %].
+func IRR(investment, annualBenefit float64, years int) (float64, bool) {
+	if investment <= 0 || annualBenefit <= 0 || years <= 0 {
+		return 0, false
+	}
+
+	// El VAN decrece con la tasa: se busca el cambio de signo en [-0.99, 10]
+	low, high := -0.99, 10.0
+	if NPV(low, investment, annualBenefit, years) < 0 || NPV(high, investment, annualBenefit, years) > 0 {
+		return 0, false
+	}
+	for i := 0; i < 100; i++ {
+		mid := (low + high) / 2
+		if NPV(mid, investment, annualBenefit, years) > 0 {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return (low + high) / 2, true
+}
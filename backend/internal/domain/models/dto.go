@@ -1,6 +1,8 @@
 // Package models contiene los DTOs (Data Transfer Objects) para requests y responses.
 package models
 
+import "time"
+
 // ============================================================================
 // REQUEST DTOs
 // ============================================================================
@@ -32,12 +34,168 @@ type ApplyMeasuresRequest struct {
 	MeasureNames []string `json:"measure_names" binding:"required,min=1,dive,required"`
 }
 
+// MeasureTransitionRequest representa el avance de una medida aplicada a una tienda
+// en su ciclo de vida. Indicar el estado actual permite corregir fechas, coste y notas.
+// ActualCost solo se admite mientras la medida no tenga facturas.
+type MeasureTransitionRequest struct {
+	MeasureName      string        `json:"measure_name" binding:"required"`
+	Status           MeasureStatus `json:"status" binding:"required,oneof=planned approved in_progress completed verified"`
+	PlannedStartDate *time.Time    `json:"planned_start_date"`
+	PlannedEndDate   *time.Time    `json:"planned_end_date"`
+	ActualStartDate  *time.Time    `json:"actual_start_date"`
+	ActualEndDate    *time.Time    `json:"actual_end_date"`
+	ActualCost       *float64      `json:"actual_cost" binding:"omitempty,gte=0"`
+	Notes            *string       `json:"notes" binding:"omitempty,max=2000"`
+}
+
+// MeasureChangeResponse representa el resultado de eliminar o restaurar una medida del
+// catálogo. El cambio se guarda aunque falle el recálculo del riesgo de alguna de las
+// tiendas donde está aplicada; RecalculationFailed indica que su riesgo guardado puede
+// no reflejarlo todavía.
+type MeasureChangeResponse struct {
+	Measure             *Measure `json:"measure"`
+	RecalculationFailed bool     `json:"recalculation_failed"`
+}
+
+// RecordInvoiceRequest representa el registro de una factura de una medida de una tienda
+type RecordInvoiceRequest struct {
+	MeasureName   string    `json:"measure_name" binding:"required"`
+	Amount        float64   `json:"amount" binding:"required,gt=0"`
+	InvoiceNumber string    `json:"invoice_number" binding:"required,max=100"`
+	InvoicedAt    time.Time `json:"invoiced_at" binding:"required"`
+}
+
+// CostVarianceQuery representa los parámetros del informe de desviación de costes
+type CostVarianceQuery struct {
+	GroupBy CostVarianceGroup `form:"group_by,default=measure" binding:"oneof=measure type country"`
+}
+
+// CostVarianceGroup representa la dimensión por la que se agrupa la desviación de costes
+type CostVarianceGroup string
+
+const (
+	CostVarianceByMeasure CostVarianceGroup = "measure"
+	CostVarianceByType    CostVarianceGroup = "type"
+	CostVarianceByCountry CostVarianceGroup = "country"
+)
+
+// PortfolioAnalyticsQuery representa los parámetros de la analítica de la cartera
+type PortfolioAnalyticsQuery struct {
+	ShopFilter
+	GroupBy PortfolioGroupBy `form:"group_by,default=country" binding:"oneof=country cluster risk measure_type"`
+	Bins    int              `form:"bins,default=10" binding:"min=2,max=20"` // Intervalos del histograma de riesgo
+}
+
+// PortfolioGroupBy representa la dimensión por la que se agrupa la analítica de la cartera
+type PortfolioGroupBy string
+
+const (
+	PortfolioByCountry     PortfolioGroupBy = "country"
+	PortfolioByCluster     PortfolioGroupBy = "cluster"
+	PortfolioByRisk        PortfolioGroupBy = "risk"
+	PortfolioByMeasureType PortfolioGroupBy = "measure_type"
+)
+
+// RiskMatrixQuery representa los parámetros de la matriz de probabilidad y consecuencia.
+// Si se indica una probabilidad o una consecuencia se detallan los pares de sus celdas.
+type RiskMatrixQuery struct {
+	ShopFilter
+	RiskID      *int64          `form:"risk_id,omitempty"`
+	Stage       RiskMatrixStage `form:"stage,default=residual" binding:"oneof=inherent residual"`
+	Probability Level           `form:"probability" binding:"omitempty,oneof=very_low low medium high very_high"`
+	Consequence Level           `form:"consequence" binding:"omitempty,oneof=very_low low medium high very_high"`
+}
+
+// RiskMatrixStage indica si la matriz de riesgos se calcula antes o después de las
+// medidas aplicadas
+type RiskMatrixStage string
+
+const (
+	RiskMatrixInherent RiskMatrixStage = "inherent" // Antes de las medidas
+	RiskMatrixResidual RiskMatrixStage = "residual" // Después de las medidas completadas
+)
+
 // OptimizeBudgetRequest representa la solicitud de optimización de presupuesto
 type OptimizeBudgetRequest struct {
-	ShopIDs    []int64 `json:"shop_ids" binding:"required,min=1,dive,gt=0"`
-	MaxBudget  float64 `json:"max_budget" binding:"required,gt=0"`
-	Strategy   string  `json:"strategy,omitempty" binding:"omitempty,oneof=greedy knapsack weighted"`
-	Priorities []int64 `json:"risk_priorities,omitempty"` // IDs de riesgos prioritarios
+	ShopIDs   []int64 `json:"shop_ids" binding:"required,min=1,dive,gt=0"`
+	MaxBudget float64 `json:"max_budget" binding:"required,gt=0"`
+	Strategy  string  `json:"strategy,omitempty" binding:"omitempty,oneof=greedy knapsack weighted"`
+	// Objective es el valor que maximiza la estrategia; por defecto la reducción de riesgo
+	Objective  OptimizationObjective `json:"objective,omitempty" binding:"omitempty,oneof=risk_reduction avoided_loss npv coverage carbon risk_carbon"`
+	Priorities []int64               `json:"risk_priorities,omitempty"` // IDs de riesgos prioritarios
+	Scenario   string                `json:"scenario,omitempty" binding:"omitempty,oneof=current ssp1-2.6 ssp2-4.5 ssp5-8.5"`
+	Horizon    int                   `json:"horizon,omitempty" binding:"omitempty,oneof=2030 2050"`
+	// UseActualCosts corrige el coste de catálogo de cada medida con los costes reales históricos
+	UseActualCosts bool `json:"use_actual_costs,omitempty"`
+	// Parámetros del modelo financiero; si no se indican se usan los de finance.DefaultParameters
+	DiscountRate    *float64 `json:"discount_rate,omitempty" binding:"omitempty,gte=0,lte=1"`
+	EvaluationYears int      `json:"evaluation_years,omitempty" binding:"omitempty,min=1,max=50"`
+	AssetValuePerM2 float64  `json:"asset_value_per_m2,omitempty" binding:"omitempty,gt=0"`
+	CarbonPrice     *float64 `json:"carbon_price,omitempty" binding:"omitempty,gte=0"` // Euros por tCO2e
+}
+
+// OptimizationObjective representa el valor que maximizan las estrategias de optimización
+type OptimizationObjective string
+
+const (
+	ObjectiveRiskReduction OptimizationObjective = "risk_reduction" // Reducción de riesgo estimada
+	ObjectiveAvoidedLoss   OptimizationObjective = "avoided_loss"   // Pérdida anual esperada evitada
+	ObjectiveNPV           OptimizationObjective = "npv"            // Valor actual neto de cada medida
+	ObjectiveCoverage      OptimizationObjective = "coverage"       // Riesgos sin cubrir que pasan a estar cubiertos
+	ObjectiveCarbon        OptimizationObjective = "carbon"         // Emisiones netas evitadas durante los años de evaluación
+	ObjectiveRiskCarbon    OptimizationObjective = "risk_carbon"    // Valor actual de la pérdida evitada y de las emisiones al precio del carbono
+)
+
+// SetRiskProjectionRequest representa la solicitud para cargar los niveles proyectados
+// de un riesgo de un cluster en un escenario y horizonte
+type SetRiskProjectionRequest struct {
+	Scenario    ClimateScenario `json:"scenario" binding:"required,oneof=ssp1-2.6 ssp2-4.5 ssp5-8.5"`
+	Horizon     int             `json:"horizon" binding:"required,oneof=2030 2050"`
+	Exposure    *Level          `json:"exposure,omitempty" binding:"omitempty,oneof=very_low low medium high very_high"`
+	Sensitivity *Level          `json:"sensitivity,omitempty" binding:"omitempty,oneof=very_low low medium high very_high"`
+	Consequence *Level          `json:"consequence,omitempty" binding:"omitempty,oneof=very_low low medium high very_high"`
+	Probability *Level          `json:"probability,omitempty" binding:"omitempty,oneof=very_low low medium high very_high"`
+}
+
+// RiskHistoryQuery representa los parámetros del historial de riesgo de una tienda
+type RiskHistoryQuery struct {
+	Limit int `form:"limit,default=50" binding:"min=1,max=500"`
+}
+
+// RiskTrendQuery representa los parámetros de la tendencia de riesgo del dashboard
+type RiskTrendQuery struct {
+	Interval string    `form:"interval,default=month" binding:"oneof=day week month"`
+	Since    time.Time `form:"since" time_format:"2006-01-02"`
+}
+
+// DashboardStatsQuery representa los filtros de las estadísticas del dashboard. El
+// periodo limita las medidas aplicadas, la inversión y las series temporales; sin
+// fechas se usan todas.
+type DashboardStatsQuery struct {
+	ClusterID *int64     `form:"cluster_id,omitempty"`
+	Country   string     `form:"country,omitempty"`
+	From      *time.Time `form:"from" time_format:"2006-01-02"`
+	To        *time.Time `form:"to" time_format:"2006-01-02"` // Último día incluido
+	Interval  string     `form:"interval,default=month" binding:"oneof=day week month"`
+}
+
+// RiskScenarioQuery representa los parámetros de escenario climático de una evaluación
+type RiskScenarioQuery struct {
+	Scenario string `form:"scenario" binding:"omitempty,oneof=current ssp1-2.6 ssp2-4.5 ssp5-8.5"`
+	Horizon  int    `form:"horizon" binding:"omitempty,oneof=2030 2050"`
+}
+
+// DisclosureReportQuery representa los parámetros del informe de divulgación de riesgos
+// físicos. Por defecto el periodo es el año natural en curso hasta hoy.
+type DisclosureReportQuery struct {
+	From   *time.Time `form:"from" time_format:"2006-01-02"`
+	To     *time.Time `form:"to" time_format:"2006-01-02"` // Último día incluido
+	Format string     `form:"format,default=json" binding:"oneof=json markdown pdf"`
+}
+
+// DisclosureFormatQuery representa el formato de descarga de un informe guardado
+type DisclosureFormatQuery struct {
+	Format string `form:"format,default=json" binding:"oneof=json markdown pdf"`
 }
 
 // LoginRequest representa la solicitud de login
@@ -46,11 +204,84 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required,min=8"`
 }
 
-// RegisterRequest representa la solicitud de registro
+// RegisterRequest representa la solicitud de registro público. Las cuentas
+// registradas siempre reciben el rol viewer; solo un administrador puede asignar otro.
 type RegisterRequest struct {
-	Email    string   `json:"email" binding:"required,email"`
-	Password string   `json:"password" binding:"required,min=8"`
-	Role     UserRole `json:"role,omitempty"`
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required,min=8"`
+}
+
+// InviteUserRequest representa la solicitud de un administrador para dar de alta un usuario
+type InviteUserRequest struct {
+	Email      string   `json:"email" binding:"required,email"`
+	Role       UserRole `json:"role" binding:"required,oneof=admin manager viewer"`
+	Countries  []string `json:"countries" binding:"omitempty,dive,required"`
+	ClusterIDs []int64  `json:"cluster_ids" binding:"omitempty,dive,gt=0"`
+}
+
+// SetUserScopeRequest representa la solicitud para limitar un usuario a ciertos
+// países y/o clusters. Las listas vacías le dan acceso a todos.
+type SetUserScopeRequest struct {
+	Countries  []string `json:"countries" binding:"omitempty,dive,required"`
+	ClusterIDs []int64  `json:"cluster_ids" binding:"omitempty,dive,gt=0"`
+}
+
+// ChangeUserRoleRequest representa la solicitud para cambiar el rol de un usuario
+type ChangeUserRoleRequest struct {
+	Role UserRole `json:"role" binding:"required,oneof=admin manager viewer"`
+}
+
+// CreateAPIKeyRequest representa la solicitud para crear una API key. Si se indican
+// permisos, la clave queda limitada a ellos; deben estar incluidos en los del rol. Los
+// países y clusters limitan sus datos como el ámbito de un usuario; sin ellos la clave
+// tiene el ámbito de quien la crea.
+type CreateAPIKeyRequest struct {
+	Name        string     `json:"name" binding:"required,max=100"`
+	Role        UserRole   `json:"role" binding:"required,oneof=admin manager viewer"`
+	Permissions []string   `json:"permissions" binding:"omitempty,dive,required"`
+	RateLimit   int        `json:"rate_limit" binding:"omitempty,min=1,max=10000"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	Countries   []string   `json:"countries" binding:"omitempty,dive,required"`
+	ClusterIDs  []int64    `json:"cluster_ids" binding:"omitempty,dive,gt=0"`
+}
+
+// APIKeyCreatedResponse representa una API key recién creada junto con su valor,
+// que solo se muestra una vez
+type APIKeyCreatedResponse struct {
+	APIKey *APIKey `json:"api_key"`
+	Key    string  `json:"key"`
+}
+
+// RefreshTokenRequest representa la solicitud para renovar los tokens de una sesión
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// LogoutRequest representa la solicitud de cierre de sesión
+type LogoutRequest struct {
+	AllDevices bool `json:"all_devices"` // Cierra todas las sesiones del usuario
+}
+
+// SetShopRiskOverrideRequest representa la solicitud para ajustar la exposición y/o
+// sensibilidad de una tienda frente a un riesgo
+type SetShopRiskOverrideRequest struct {
+	Exposure    *Level `json:"exposure,omitempty" binding:"omitempty,oneof=very_low low medium high very_high"`
+	Sensitivity *Level `json:"sensitivity,omitempty" binding:"omitempty,oneof=very_low low medium high very_high"`
+	Reason      string `json:"reason" binding:"required,min=3,max=500"`
+}
+
+// CreateRiskScoringConfigRequest representa la solicitud para crear una nueva versión
+// de la configuración de scoring. Los campos omitidos toman los valores por defecto.
+type CreateRiskScoringConfigRequest struct {
+	Name           string                      `json:"name" binding:"required,min=3,max=100"`
+	Method         RiskScoringMethod           `json:"method" binding:"required,oneof=multiplicative additive_weighted max ipcc"`
+	LevelValues    map[Level]float64           `json:"level_values,omitempty"`
+	Weights        *RiskFactorWeights          `json:"weights,omitempty"`
+	RiskWeights    map[int64]RiskFactorWeights `json:"risk_weights,omitempty"`
+	Thresholds     *RiskLevelThresholds        `json:"thresholds,omitempty"`
+	Aggregation    RiskAggregationMethod       `json:"aggregation,omitempty" binding:"omitempty,oneof=mean max weighted probabilistic"`
+	RiskImportance map[int64]float64           `json:"risk_importance,omitempty"`
+	Activate       bool                        `json:"activate"`
 }
 
 // PaginationRequest representa los parámetros de paginación
@@ -64,12 +295,41 @@ type PaginationRequest struct {
 // ShopFilterRequest representa los filtros para búsqueda de tiendas
 type ShopFilterRequest struct {
 	PaginationRequest
-	ClusterID    *int64   `form:"cluster_id,omitempty"`
-	MinRisk      *float64 `form:"min_risk,omitempty"`
-	MaxRisk      *float64 `form:"max_risk,omitempty"`
-	MinSurface   *float64 `form:"min_surface,omitempty"`
-	MaxSurface   *float64 `form:"max_surface,omitempty"`
-	SearchQuery  string   `form:"q,omitempty"`
+	ShopFilter
+	// IncludeDeleted incluye las tiendas eliminadas; solo para administradores
+	IncludeDeleted bool `form:"include_deleted"`
+}
+
+// ShopFilter representa los filtros de tiendas comunes al listado y a la analítica de la cartera
+type ShopFilter struct {
+	ClusterID   *int64   `form:"cluster_id,omitempty"`
+	Country     string   `form:"country,omitempty"`
+	MinRisk     *float64 `form:"min_risk,omitempty"`
+	MaxRisk     *float64 `form:"max_risk,omitempty"`
+	MinSurface  *float64 `form:"min_surface,omitempty"`
+	MaxSurface  *float64 `form:"max_surface,omitempty"`
+	SearchQuery string   `form:"q,omitempty"`
+}
+
+// UserFilterRequest representa los filtros para el listado de usuarios
+type UserFilterRequest struct {
+	Role   UserRole `form:"role" binding:"omitempty,oneof=admin manager viewer"`
+	Active *bool    `form:"active"`
+	Email  string   `form:"q"`
+}
+
+// AuditFilterRequest representa los filtros del log de auditoría
+type AuditFilterRequest struct {
+	Page          int             `form:"page,default=1" binding:"min=1"`
+	PageSize      int             `form:"page_size,default=50" binding:"min=1,max=500"`
+	ActorUserID   *int64          `form:"actor_user_id"`
+	ActorAPIKeyID *int64          `form:"actor_api_key_id"`
+	Action        AuditAction     `form:"action"`
+	EntityType    AuditEntityType `form:"entity_type"`
+	EntityID      string          `form:"entity_id"`
+	RequestID     string          `form:"request_id"`
+	From          *time.Time      `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To            *time.Time      `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
 }
 
 // ============================================================================
@@ -101,88 +361,459 @@ type PaginationMeta struct {
 
 // ShopResponse representa la respuesta de una tienda
 type ShopResponse struct {
-	ID               int64   `json:"id"`
-	Location         string  `json:"location"`
-	UtmNorth         float64 `json:"utm_north"`
-	UtmEast          float64 `json:"utm_east"`
-	TotalRisk        float64 `json:"total_risk"`
-	TaxonomyCoverage float64 `json:"taxonomy_coverage"`
-	Surface          float64 `json:"surface"`
-	CarbonFootprint  float64 `json:"carbon_footprint"`
-	ClusterID        int64   `json:"cluster_id"`
-	ClusterName      string  `json:"cluster_name,omitempty"`
-	Country          string  `json:"country"`
+	ID               int64                 `json:"id"`
+	Location         string                `json:"location"`
+	UtmNorth         float64               `json:"utm_north"`
+	UtmEast          float64               `json:"utm_east"`
+	TotalRisk        float64               `json:"total_risk"`
+	TotalRiskMethod  RiskAggregationMethod `json:"total_risk_method,omitempty"`
+	TaxonomyCoverage float64               `json:"taxonomy_coverage"`
+	Surface          float64               `json:"surface"`
+	CarbonFootprint  float64               `json:"carbon_footprint"`
+	ClusterID        int64                 `json:"cluster_id"`
+	ClusterName      string                `json:"cluster_name,omitempty"`
+	Country          string                `json:"country"`
+	DeletedAt        *time.Time            `json:"deleted_at,omitempty"`
 }
 
 // OptimizationResult representa el resultado de la optimización de presupuesto
 type OptimizationResult struct {
-	TotalCost            float64                  `json:"total_cost"`
-	RemainingBudget      float64                  `json:"remaining_budget"`
-	TotalRiskReduction   float64                  `json:"total_risk_reduction"`
-	RecommendedMeasures  []RecommendedMeasure     `json:"recommended_measures"`
-	ShopRecommendations  []ShopRecommendation     `json:"shop_recommendations"`
-	Strategy             string                   `json:"strategy_used"`
-	OptimizationMetrics  OptimizationMetrics      `json:"metrics"`
+	TotalCost           float64               `json:"total_cost"`
+	RemainingBudget     float64               `json:"remaining_budget"`
+	TotalRiskReduction  float64               `json:"total_risk_reduction"`
+	RecommendedMeasures []RecommendedMeasure  `json:"recommended_measures"`
+	ShopRecommendations []ShopRecommendation  `json:"shop_recommendations"`
+	Strategy            string                `json:"strategy_used"`
+	Scenario            ScenarioSelection     `json:"scenario"`
+	OptimizationMetrics OptimizationMetrics   `json:"metrics"`
+	CostEstimates       []MeasureCostEstimate `json:"cost_estimates,omitempty"` // Solo con use_actual_costs
+}
+
+// MeasureCostEstimate representa el coste corregido de una medida a partir de sus
+// costes reales históricos
+type MeasureCostEstimate struct {
+	MeasureName   string            `json:"measure_name"`
+	CatalogCost   float64           `json:"catalog_cost"`
+	EstimatedCost float64           `json:"estimated_cost"`
+	Factor        float64           `json:"factor"`  // Relación entre coste real y estimado aplicada a los costes de catálogo
+	Basis         CostVarianceGroup `json:"basis"`   // Histórico usado: de la propia medida o de su tipo
+	Samples       int64             `json:"samples"` // Medidas con coste real en que se basa
 }
 
 // RecommendedMeasure representa una medida recomendada con su justificación
 type RecommendedMeasure struct {
-	Measure         Measure  `json:"measure"`
-	Priority        int      `json:"priority"`
-	RiskReduction   float64  `json:"risk_reduction_percentage"`
-	CostEfficiency  float64  `json:"cost_efficiency_score"`
-	AffectedRisks   []string `json:"affected_risks"`
-	Justification   string   `json:"justification"`
+	Measure        Measure  `json:"measure"`
+	Priority       int      `json:"priority"`
+	RiskReduction  float64  `json:"risk_reduction_percentage"`
+	CostEfficiency float64  `json:"cost_efficiency_score"`
+	AffectedRisks  []string `json:"affected_risks"`
+	// AvoidedAnnualLoss es la pérdida anual esperada que evita la medida por sí sola
+	AvoidedAnnualLoss float64 `json:"avoided_annual_loss"`
+	// AnnualCarbonReduction son las tCO2e anuales que evita la medida en la tienda
+	AnnualCarbonReduction float64 `json:"annual_carbon_reduction"`
+	Justification         string  `json:"justification"`
 }
 
 // ShopRecommendation representa las recomendaciones para una tienda específica
 type ShopRecommendation struct {
-	ShopID                int64                `json:"shop_id"`
-	ShopLocation          string               `json:"shop_location"`
-	CurrentRisk           float64              `json:"current_risk"`
-	ProjectedRisk         float64              `json:"projected_risk"`
-	Measures              []RecommendedMeasure `json:"measures"`
-	EstimatedInvestment   float64              `json:"estimated_investment"`
+	ShopID              int64                `json:"shop_id"`
+	ShopLocation        string               `json:"shop_location"`
+	CurrentRisk         float64              `json:"current_risk"`
+	ProjectedRisk       float64              `json:"projected_risk"`
+	Measures            []RecommendedMeasure `json:"measures"`
+	EstimatedInvestment float64              `json:"estimated_investment"`
+	Financials          FinancialMetrics     `json:"financials"`
+	Carbon              CarbonImpact         `json:"carbon"`
 }
 
 // OptimizationMetrics contiene métricas del proceso de optimización
 type OptimizationMetrics struct {
-	BudgetUtilization    float64 `json:"budget_utilization_percentage"`
-	AverageRiskReduction float64 `json:"average_risk_reduction"`
-	ROI                  float64 `json:"estimated_roi"`
-	ProcessingTimeMs     int64   `json:"processing_time_ms"`
+	BudgetUtilization    float64               `json:"budget_utilization_percentage"`
+	AverageRiskReduction float64               `json:"average_risk_reduction"`
+	ROI                  float64               `json:"estimated_roi"` // VAN por euro invertido
+	Objective            OptimizationObjective `json:"objective"`
+	ObjectiveValue       float64               `json:"objective_value"` // Suma del objetivo de las medidas recomendadas
+	ProcessingTimeMs     int64                 `json:"processing_time_ms"`
+	Financials           FinancialMetrics      `json:"financials"`
+	Carbon               CarbonImpact          `json:"carbon"`
+}
+
+// FinancialMetrics contiene los indicadores económicos de una inversión en medidas
+type FinancialMetrics struct {
+	ExpectedAnnualLoss float64  `json:"expected_annual_loss"` // Pérdida anual esperada sin las medidas
+	AvoidedAnnualLoss  float64  `json:"avoided_annual_loss"`  // Pérdida anual evitada con las medidas
+	NPV                float64  `json:"npv"`
+	IRR                *float64 `json:"irr,omitempty"`           // nil si no existe
+	PaybackYears       *float64 `json:"payback_years,omitempty"` // nil si la inversión no evita pérdidas
+	DiscountRate       float64  `json:"discount_rate"`
+	Years              int      `json:"years"`
+}
+
+// CarbonImpact contiene el impacto de un plan de medidas en la huella de carbono, en tCO2e
+type CarbonImpact struct {
+	CurrentFootprint   float64 `json:"current_footprint"`   // Huella anual sin las medidas
+	ProjectedFootprint float64 `json:"projected_footprint"` // Huella anual con las medidas implantadas
+	AnnualReduction    float64 `json:"annual_reduction"`
+	EmbodiedCarbon     float64 `json:"embodied_carbon"` // Emisiones de implantar las medidas
+	NetReduction       float64 `json:"net_reduction"`   // Reducción acumulada en los años de evaluación menos las emisiones de implantación
+	CarbonPrice        float64 `json:"carbon_price"`
+	CarbonValue        float64 `json:"carbon_value"` // Valor actual de la reducción neta al precio del carbono
 }
 
 // AuthResponse representa la respuesta de autenticación
 type AuthResponse struct {
-	Token     string `json:"token"`
-	ExpiresAt int64  `json:"expires_at"`
-	User      struct {
-		ID    int64    `json:"id"`
-		Email string   `json:"email"`
-		Role  UserRole `json:"role"`
+	Token            string `json:"token"`
+	ExpiresAt        int64  `json:"expires_at"`
+	RefreshToken     string `json:"refresh_token"`
+	RefreshExpiresAt int64  `json:"refresh_expires_at"`
+	User             struct {
+		ID         int64    `json:"id"`
+		Email      string   `json:"email"`
+		Role       UserRole `json:"role"`
+		Countries  []string `json:"countries,omitempty"`
+		ClusterIDs []int64  `json:"cluster_ids,omitempty"`
 	} `json:"user"`
 }
 
+// SSOLinkResponse representa la URL del IdP en la que el usuario confirma la
+// vinculación de su cuenta
+type SSOLinkResponse struct {
+	AuthURL string `json:"auth_url"`
+}
+
+// UserCredentialsResponse representa un usuario junto con su contraseña temporal,
+// que solo se muestra una vez
+type UserCredentialsResponse struct {
+	User              *User  `json:"user"`
+	TemporaryPassword string `json:"temporary_password"`
+}
+
 // RiskAssessmentResponse representa la evaluación de riesgos de una tienda
 type RiskAssessmentResponse struct {
-	ShopID           int64        `json:"shop_id"`
-	OverallRiskScore float64      `json:"overall_risk_score"`
-	RiskLevel        Level        `json:"risk_level"`
-	Risks            []RiskDetail `json:"risks"`
-	LastUpdated      string       `json:"last_updated"`
+	ShopID            int64                 `json:"shop_id"`
+	OverallRiskScore  float64               `json:"overall_risk_score"`
+	RiskLevel         Level                 `json:"risk_level"`
+	Risks             []RiskDetail          `json:"risks"`
+	ScoringVersion    int64                 `json:"scoring_version"`
+	ScoringMethod     RiskScoringMethod     `json:"scoring_method"`
+	AggregationMethod RiskAggregationMethod `json:"aggregation_method"`
+	Scenario          ScenarioSelection     `json:"scenario"`
+	LastUpdated       *time.Time            `json:"last_updated"` // Último recálculo guardado (nil si nunca se ha calculado)
 }
 
 // DashboardStats representa estadísticas para el dashboard
 type DashboardStats struct {
+	TotalShops          int64   `json:"total_shops"`
+	TotalClusters       int64   `json:"total_clusters"`
+	AverageRisk         float64 `json:"average_risk"`
+	HighRiskShops       int64   `json:"high_risk_shops"`
+	TotalMeasures       int64   `json:"total_measures"`
+	AppliedMeasures     int64   `json:"applied_measures"`     // Medidas completadas o verificadas
+	TotalInvestment     float64 `json:"total_investment"`     // Coste real si existe, si no el estimado
+	EstimatedInvestment float64 `json:"estimated_investment"` // Coste estimado de las medidas aplicadas en cada tienda
+	ActualInvestment    float64 `json:"actual_investment"`    // Coste real facturado
+	CoveragePercentage  float64 `json:"coverage_percentage"`  // Tiendas con alguna medida completada o verificada
+	// Tiendas evaluadas en cada nivel según los umbrales del scoring vigente
+	ShopsByLevel map[Level]int64 `json:"shops_by_level"`
+	// Huella de carbono anual de las tiendas y efecto de las medidas completadas, en tCO2e
+	CarbonFootprint          float64 `json:"carbon_footprint"`
+	AvoidedCarbon            float64 `json:"avoided_carbon"`
+	ProjectedCarbonFootprint float64 `json:"projected_carbon_footprint"`
+	EmbodiedCarbon           float64 `json:"embodied_carbon"`
+	// Series temporales del periodo
+	InvestmentSeries []InvestmentPoint `json:"investment_series"`
+	RiskSeries       []RiskTrendPoint  `json:"risk_series"`
+}
+
+// InvestmentPoint representa la inversión en medidas de un periodo. Cada medida se
+// imputa a su fecha de fin real o, si no la tiene, a la de inicio real, a la prevista
+// o a la de su último cambio de estado.
+type InvestmentPoint struct {
+	Period              time.Time `json:"period"`
+	AppliedMeasures     int64     `json:"applied_measures"` // Medidas completadas o verificadas
+	TotalInvestment     float64   `json:"total_investment"`
+	EstimatedInvestment float64   `json:"estimated_investment"`
+	ActualInvestment    float64   `json:"actual_investment"`
+}
+
+// CostVarianceRow representa la desviación entre el coste estimado y el coste real
+// de las medidas de un grupo. Solo se incluyen medidas completadas con coste real registrado.
+type CostVarianceRow struct {
+	Group              string  `json:"group"`
+	ShopMeasures       int64   `json:"shop_measures"`
+	EstimatedCost      float64 `json:"estimated_cost"`
+	ActualCost         float64 `json:"actual_cost"`
+	Variance           float64 `json:"variance"`            // Real - estimado
+	VariancePercentage float64 `json:"variance_percentage"` // Sobre el estimado
+}
+
+// CostVarianceReport representa el informe de desviación de costes
+type CostVarianceReport struct {
+	GroupBy CostVarianceGroup `json:"group_by"`
+	Groups  []CostVarianceRow `json:"groups"`
+	Total   CostVarianceRow   `json:"total"`
+}
+
+// PortfolioGroup representa las métricas agregadas de un grupo de tiendas de la cartera.
+// En la agrupación por riesgo el grupo son las tiendas expuestas a él, con el score del
+// riesgo en su último snapshot; en la agrupación por tipo de medida, las tiendas con
+// alguna medida del tipo, cuya cobertura es la que aportan las medidas de ese tipo.
+type PortfolioGroup struct {
+	Key                 string         `json:"key"`
+	Name                string         `json:"name"`
+	Shops               int64          `json:"shops"`
+	AverageRisk         float64        `json:"average_risk"`
+	MaxRisk             float64        `json:"max_risk"`
+	RiskHistogram       []HistogramBin `json:"risk_histogram"`
+	RiskPairs           int64          `json:"risk_pairs"`    // Pares tienda-riesgo del grupo
+	CoveredPairs        int64          `json:"covered_pairs"` // Pares cubiertos por alguna medida implantada
+	CoveragePercentage  float64        `json:"coverage_percentage"`
+	AppliedMeasures     int64          `json:"applied_measures"`
+	EstimatedInvestment float64        `json:"estimated_investment"` // Coste estimado para cada tienda
+	ActualInvestment    float64        `json:"actual_investment"`    // Coste real facturado
+}
+
+// HistogramBin representa un intervalo [From, To) de un histograma de riesgo; el
+// último intervalo incluye su límite superior
+type HistogramBin struct {
+	From  float64 `json:"from"`
+	To    float64 `json:"to"`
+	Count int64   `json:"count"`
+}
+
+// PortfolioAnalytics representa la analítica de la cartera agrupada por una dimensión
+type PortfolioAnalytics struct {
+	GroupBy PortfolioGroupBy `json:"group_by"`
+	Groups  []PortfolioGroup `json:"groups"`
+}
+
+// RiskMatrixPair representa un par tienda-riesgo de la matriz de riesgos, con los
+// niveles del cluster y los que quedan tras las medidas completadas que lo reducen
+type RiskMatrixPair struct {
+	ShopID              int64         `json:"shop_id"`
+	ShopLocation        string        `json:"shop_location"`
+	Country             string        `json:"country"`
+	ClusterID           int64         `json:"cluster_id"`
+	RiskID              int64         `json:"risk_id"`
+	RiskName            string        `json:"risk_name"`
+	Probability         Level         `json:"probability"`
+	Consequence         Level         `json:"consequence"`
+	MeasureTypes        []MeasureType `json:"measure_types"` // Tipos de las medidas completadas que reducen el riesgo
+	ResidualProbability Level         `json:"residual_probability"`
+	ResidualConsequence Level         `json:"residual_consequence"`
+}
+
+// RiskMatrixCell representa una celda de la matriz de riesgos
+type RiskMatrixCell struct {
+	Probability Level            `json:"probability"`
+	Consequence Level            `json:"consequence"`
+	Pairs       int64            `json:"pairs"`
+	Shops       int64            `json:"shops"`
+	Items       []RiskMatrixPair `json:"items,omitempty"` // Solo en las celdas detalladas
+}
+
+// RiskMatrix representa la matriz 5x5 de probabilidad y consecuencia de la cartera
+type RiskMatrix struct {
+	Stage      RiskMatrixStage  `json:"stage"`
+	TotalPairs int64            `json:"total_pairs"`
+	Cells      []RiskMatrixCell `json:"cells"` // De mayor a menor probabilidad y de menor a mayor consecuencia
+}
+
+// SetMeasureTaxonomyRequest representa la correspondencia de una medida con una
+// actividad de la Taxonomía
+type SetMeasureTaxonomyRequest struct {
+	ActivityCode string       `json:"activity_code" binding:"required"`
+	DNSH         DNSHCriteria `json:"dnsh"`
+	Notes        string       `json:"notes,omitempty" binding:"max=500"`
+}
+
+// ShopTaxonomyReport representa la evaluación de la alineación de una tienda con los
+// criterios de adaptación al cambio climático de la Taxonomía
+type ShopTaxonomyReport struct {
+	ShopID       int64                   `json:"shop_id"`
+	ShopLocation string                  `json:"shop_location"`
+	Country      string                  `json:"country"`
+	Status       TaxonomyAlignmentStatus `json:"status"`
+	// AlignmentPercentage es el porcentaje de riesgos materiales reducidos por medidas alineadas
+	AlignmentPercentage float64                     `json:"alignment_percentage"`
+	MaterialRisks       []TaxonomyRiskAssessment    `json:"material_risks"`
+	Measures            []TaxonomyMeasureAssessment `json:"measures"`
+	AlignedCapEx        float64                     `json:"aligned_capex"` // Inversión en medidas alineadas
+	TotalCapEx          float64                     `json:"total_capex"`   // Inversión en medidas implantadas
+}
+
+// TaxonomyRiskAssessment representa un riesgo físico material de una tienda y las
+// medidas alineadas que lo reducen
+type TaxonomyRiskAssessment struct {
+	RiskID      int64    `json:"risk_id"`
+	RiskName    string   `json:"risk_name"`
+	RiskScore   float64  `json:"risk_score"`
+	Exposure    Level    `json:"exposure"`
+	Probability Level    `json:"probability"`
+	Addressed   bool     `json:"addressed"`
+	AddressedBy []string `json:"addressed_by,omitempty"`
+}
+
+// TaxonomyMeasureAssessment representa la evaluación de una medida implantada en una tienda
+type TaxonomyMeasureAssessment struct {
+	MeasureName    string   `json:"measure_name"`
+	ActivityCode   string   `json:"activity_code,omitempty"` // Vacío si la medida no es elegible
+	Eligible       bool     `json:"eligible"`
+	DNSHCompliant  bool     `json:"dnsh_compliant"`
+	UnmetDNSH      []string `json:"unmet_dnsh,omitempty"`
+	AddressedRisks []string `json:"addressed_risks,omitempty"` // Riesgos materiales que reduce
+	Aligned        bool     `json:"aligned"`
+	Cost           float64  `json:"cost"` // Coste real si existe, si no el estimado
+}
+
+// TaxonomyPortfolioReport representa la alineación con la Taxonomía de las tiendas
+// dentro del ámbito del usuario
+type TaxonomyPortfolioReport struct {
+	TotalShops             int64                             `json:"total_shops"`
+	ShopsByStatus          map[TaxonomyAlignmentStatus]int64 `json:"shops_by_status"`
+	AverageAlignment       float64                           `json:"average_alignment_percentage"`
+	AlignedCapEx           float64                           `json:"aligned_capex"`
+	TotalCapEx             float64                           `json:"total_capex"`
+	AlignedCapExPercentage float64                           `json:"aligned_capex_percentage"`
+	Shops                  []ShopTaxonomySummary             `json:"shops"`
+}
+
+// ShopTaxonomySummary representa la alineación de una tienda en el informe de cartera
+type ShopTaxonomySummary struct {
+	ShopID              int64                   `json:"shop_id"`
+	ShopLocation        string                  `json:"shop_location"`
+	Status              TaxonomyAlignmentStatus `json:"status"`
+	AlignmentPercentage float64                 `json:"alignment_percentage"`
+	UnaddressedRisks    []string                `json:"unaddressed_risks,omitempty"`
+}
+
+// DisclosureReport representa el informe de divulgación de riesgos físicos de la
+// cartera, estructurado según los requisitos de CSRD (ESRS E1) y TCFD: exposición,
+// tiendas de alto riesgo, plan de adaptación e inversión, y análisis de escenarios
+type DisclosureReport struct {
+	Period            ReportingPeriod        `json:"period"`
+	GeneratedAt       time.Time              `json:"generated_at"`
+	Data              DisclosureDataSnapshot `json:"data"`
+	Summary           DisclosureSummary      `json:"summary"`
+	ExposureByRisk    []RiskExposure         `json:"exposure_by_risk"`
+	ExposureByCountry []CountryExposure      `json:"exposure_by_country"`
+	HighRiskShops     []HighRiskShop         `json:"high_risk_shops"`
+	AdaptationPlan    AdaptationPlan         `json:"adaptation_plan"`
+	Scenarios         []ScenarioResult       `json:"scenarios"`
+	RiskTrend         []RiskTrendPoint       `json:"risk_trend"` // Evolución del riesgo medio dentro del periodo
+}
+
+// ReportingPeriod representa el periodo de un informe; ambos días están incluidos
+type ReportingPeriod struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// DisclosureDataSnapshot describe los datos con que se generó un informe. Dos informes
+// con la misma suma de comprobación se generaron a partir de los mismos datos.
+type DisclosureDataSnapshot struct {
+	ScoringVersion    int64                 `json:"scoring_version"`
+	ScoringMethod     RiskScoringMethod     `json:"scoring_method"`
+	AggregationMethod RiskAggregationMethod `json:"aggregation_method"`
+	Thresholds        RiskLevelThresholds   `json:"thresholds"`
+	Shops             int64                 `json:"shops"`
+	ShopRisks         int64                 `json:"shop_risks"` // Pares tienda-riesgo evaluados
+	ShopMeasures      int64                 `json:"shop_measures"`
+	Invoices          int64                 `json:"invoices"`
+	Checksum          string                `json:"checksum"` // SHA-256 de los datos de entrada
+}
+
+// PortfolioShopData son los datos con que se calculan los riesgos de las tiendas de la
+// cartera y se evalúan sus medidas, leídos de una vez para todas las tiendas
+type PortfolioShopData struct {
+	Shops        []Shop                       // Tiendas activas dentro del ámbito, ordenadas por ID
+	ClusterRisks map[int64][]RiskDetail       // Riesgos de cada cluster, sin RiskScore
+	Overrides    map[int64][]ShopRiskOverride // Ajustes de riesgo de cada tienda
+	ShopMeasures map[int64][]ShopMeasure      // Medidas de cada tienda
+}
+
+// DisclosureInputs son los datos de entrada de un informe de divulgación, leídos en
+// una misma transacción para que el informe refleje un único instante
+type DisclosureInputs struct {
+	PortfolioShopData
+	ScoringConfig *RiskScoringConfig                // Configuración activa; nil si no se ha activado ninguna
+	Projections   map[int64][]ClusterRiskProjection // Proyecciones de cada cluster en todos los escenarios
+	Invoices      map[int64][]MeasureInvoice        // Facturas de cada tienda
+	RiskTrend     []RiskTrendPoint                  // Evolución mensual del riesgo de las tiendas
+}
+
+// DisclosureSummary resume la exposición de la cartera a los riesgos físicos
+type DisclosureSummary struct {
 	TotalShops         int64   `json:"total_shops"`
-	TotalClusters      int64   `json:"total_clusters"`
+	Countries          int64   `json:"countries"`
 	AverageRisk        float64 `json:"average_risk"`
+	MaxRisk            float64 `json:"max_risk"`
 	HighRiskShops      int64   `json:"high_risk_shops"`
-	TotalMeasures      int64   `json:"total_measures"`
-	AppliedMeasures    int64   `json:"applied_measures"`
-	TotalInvestment    float64 `json:"total_investment"`
-	CoveragePercentage float64 `json:"coverage_percentage"`
+	HighRiskPercentage float64 `json:"high_risk_percentage"`
+	MaterialRisks      int64   `json:"material_risks"` // Pares tienda-riesgo materiales
+}
+
+// RiskExposure representa la exposición de la cartera a un riesgo físico
+type RiskExposure struct {
+	RiskID        int64           `json:"risk_id"`
+	RiskName      string          `json:"risk_name"`
+	ShopsAssessed int64           `json:"shops_assessed"`
+	MaterialShops int64           `json:"material_shops"`  // Tiendas para las que el riesgo es material
+	HighRiskShops int64           `json:"high_risk_shops"` // Tiendas con el riesgo en nivel alto o muy alto
+	AverageScore  float64         `json:"average_score"`
+	MaxScore      float64         `json:"max_score"`
+	ShopsByLevel  map[Level]int64 `json:"shops_by_level"`
+}
+
+// CountryExposure representa la exposición de las tiendas de un país
+type CountryExposure struct {
+	Country       string  `json:"country"`
+	Shops         int64   `json:"shops"`
+	Surface       float64 `json:"surface"`
+	AverageRisk   float64 `json:"average_risk"`
+	MaxRisk       float64 `json:"max_risk"`
+	HighRiskShops int64   `json:"high_risk_shops"`
+	MaterialRisks int64   `json:"material_risks"`
+}
+
+// HighRiskShop representa una tienda cuyo riesgo total está en nivel alto o muy alto
+type HighRiskShop struct {
+	ShopID            int64    `json:"shop_id"`
+	ShopLocation      string   `json:"shop_location"`
+	Country           string   `json:"country"`
+	TotalRisk         float64  `json:"total_risk"`
+	RiskLevel         Level    `json:"risk_level"`
+	MaterialRisks     []string `json:"material_risks"`
+	PendingMeasures   int64    `json:"pending_measures"` // Planificadas, aprobadas o en ejecución
+	CompletedMeasures int64    `json:"completed_measures"`
+}
+
+// AdaptationPlan resume las medidas de adaptación de la cartera y su inversión
+type AdaptationPlan struct {
+	MeasuresByStatus      map[MeasureStatus]int64 `json:"measures_by_status"`
+	PlannedInvestment     float64                 `json:"planned_investment"`     // Coste estimado de las medidas pendientes
+	ImplementedInvestment float64                 `json:"implemented_investment"` // Coste real, o estimado, de las medidas implantadas
+	PeriodSpend           float64                 `json:"period_spend"`           // Importe facturado dentro del periodo
+	CompletedInPeriod     int64                   `json:"completed_in_period"`    // Medidas terminadas dentro del periodo
+	ByType                []AdaptationSpend       `json:"by_type"`
+}
+
+// AdaptationSpend representa las medidas de adaptación de un tipo y su inversión
+type AdaptationSpend struct {
+	Type                  MeasureType `json:"type"`
+	Measures              int64       `json:"measures"`
+	PlannedInvestment     float64     `json:"planned_investment"`
+	ImplementedInvestment float64     `json:"implemented_investment"`
+	PeriodSpend           float64     `json:"period_spend"`
+}
+
+// ScenarioResult representa el riesgo de la cartera en un escenario climático
+type ScenarioResult struct {
+	Scenario      ScenarioSelection `json:"scenario"`
+	AverageRisk   float64           `json:"average_risk"`
+	MaxRisk       float64           `json:"max_risk"`
+	HighRiskShops int64             `json:"high_risk_shops"`
+	MaterialRisks int64             `json:"material_risks"`
 }
 
 // RiskCoverageResponse representa la cobertura de riesgos de una tienda
@@ -206,3 +837,23 @@ type RiskCoverageItem struct {
 	CoveringMeasures  []Measure `json:"covering_measures,omitempty"`
 	AvailableMeasures []Measure `json:"available_measures,omitempty"`
 }
+
+// ShopDossier reúne los datos de la ficha imprimible de riesgo e inversión de una tienda
+type ShopDossier struct {
+	Shop            Shop                    `json:"shop"`
+	Cluster         Cluster                 `json:"cluster"`
+	Neighbours      []Shop                  `json:"neighbours"` // Resto de tiendas del cluster, para el mapa de situación
+	Assessment      RiskAssessmentResponse  `json:"assessment"`
+	Coverage        RiskCoverageResponse    `json:"coverage"`
+	Measures        []ShopMeasure           `json:"measures"`
+	Recommendations []MeasureRecommendation `json:"recommendations"`
+	GeneratedAt     time.Time               `json:"generated_at"`
+}
+
+// MeasureRecommendation es una medida propuesta para los riesgos de una tienda que
+// aún no cubre ninguna medida
+type MeasureRecommendation struct {
+	Measure
+	Risks     []string `json:"risks"`      // Riesgos sin cubrir que reduciría
+	RiskScore float64  `json:"risk_score"` // Suma de las puntuaciones de esos riesgos
+}
@@ -54,6 +54,7 @@ var (
 	ErrInvalidID         = NewAppError("INVALID_ID", "El ID proporcionado no es válido", http.StatusBadRequest, nil)
 	ErrInvalidPagination = NewAppError("INVALID_PAGINATION", "Parámetros de paginación inválidos", http.StatusBadRequest, nil)
 	ErrInvalidBudget     = NewAppError("INVALID_BUDGET", "El presupuesto debe ser mayor a 0", http.StatusBadRequest, nil)
+	ErrInvalidSSOState   = NewAppError("INVALID_SSO_STATE", "La solicitud de inicio de sesión único es inválida o ha expirado", http.StatusBadRequest, nil)
 )
 
 // Errores de autenticación (401)
@@ -61,22 +62,35 @@ var (
 	ErrUnauthorized       = NewAppError("UNAUTHORIZED", "No autorizado. Por favor, inicie sesión", http.StatusUnauthorized, nil)
 	ErrInvalidToken       = NewAppError("INVALID_TOKEN", "Token de autenticación inválido o expirado", http.StatusUnauthorized, nil)
 	ErrInvalidCredentials = NewAppError("INVALID_CREDENTIALS", "Email o contraseña incorrectos", http.StatusUnauthorized, nil)
+	ErrRefreshTokenReused = NewAppError("REFRESH_TOKEN_REUSED", "El refresh token ya fue utilizado; la sesión ha sido revocada", http.StatusUnauthorized, nil)
+	ErrInvalidAPIKey      = NewAppError("INVALID_API_KEY", "API key inválida, revocada o expirada", http.StatusUnauthorized, nil)
+	ErrSSOFailed          = NewAppError("SSO_FAILED", "No se pudo verificar la identidad con el proveedor de identidad", http.StatusUnauthorized, nil)
 )
 
 // Errores de autorización (403)
 var (
-	ErrForbidden        = NewAppError("FORBIDDEN", "No tiene permisos para realizar esta acción", http.StatusForbidden, nil)
-	ErrInsufficientRole = NewAppError("INSUFFICIENT_ROLE", "Su rol no permite realizar esta acción", http.StatusForbidden, nil)
+	ErrForbidden         = NewAppError("FORBIDDEN", "No tiene permisos para realizar esta acción", http.StatusForbidden, nil)
+	ErrInsufficientRole  = NewAppError("INSUFFICIENT_ROLE", "Su rol no permite realizar esta acción", http.StatusForbidden, nil)
+	ErrUserInactive      = NewAppError("USER_INACTIVE", "La cuenta de usuario está desactivada", http.StatusForbidden, nil)
+	ErrOutOfScope        = NewAppError("OUT_OF_SCOPE", "La tienda quedaría fuera de su ámbito de países y clusters", http.StatusForbidden, nil)
+	ErrSSORoleNotMapped  = NewAppError("SSO_ROLE_NOT_MAPPED", "Sus grupos del proveedor de identidad no dan acceso a la aplicación", http.StatusForbidden, nil)
+	ErrSSOScopeNotMapped = NewAppError("SSO_SCOPE_NOT_MAPPED", "Sus grupos del proveedor de identidad no dan acceso a ningún país ni cluster", http.StatusForbidden, nil)
 )
 
 // Errores de recurso no encontrado (404)
 var (
-	ErrShopNotFound     = NewAppError("SHOP_NOT_FOUND", "Tienda no encontrada", http.StatusNotFound, nil)
-	ErrClusterNotFound  = NewAppError("CLUSTER_NOT_FOUND", "Cluster no encontrado", http.StatusNotFound, nil)
-	ErrRiskNotFound     = NewAppError("RISK_NOT_FOUND", "Riesgo no encontrado", http.StatusNotFound, nil)
-	ErrMeasureNotFound  = NewAppError("MEASURE_NOT_FOUND", "Medida no encontrada", http.StatusNotFound, nil)
-	ErrUserNotFound     = NewAppError("USER_NOT_FOUND", "Usuario no encontrado", http.StatusNotFound, nil)
-	ErrResourceNotFound = func(resource string) *AppError {
+	ErrShopNotFound          = NewAppError("SHOP_NOT_FOUND", "Tienda no encontrada", http.StatusNotFound, nil)
+	ErrClusterNotFound       = NewAppError("CLUSTER_NOT_FOUND", "Cluster no encontrado", http.StatusNotFound, nil)
+	ErrRiskNotFound          = NewAppError("RISK_NOT_FOUND", "Riesgo no encontrado", http.StatusNotFound, nil)
+	ErrMeasureNotFound       = NewAppError("MEASURE_NOT_FOUND", "Medida no encontrada", http.StatusNotFound, nil)
+	ErrUserNotFound          = NewAppError("USER_NOT_FOUND", "Usuario no encontrado", http.StatusNotFound, nil)
+	ErrAPIKeyNotFound        = NewAppError("API_KEY_NOT_FOUND", "API key no encontrada", http.StatusNotFound, nil)
+	ErrSSODisabled           = NewAppError("SSO_DISABLED", "El inicio de sesión único no está configurado", http.StatusNotFound, nil)
+	ErrScoringConfigNotFound = NewAppError("SCORING_CONFIG_NOT_FOUND", "Configuración de scoring no encontrada", http.StatusNotFound, nil)
+	ErrRiskOverrideNotFound  = NewAppError("RISK_OVERRIDE_NOT_FOUND", "La tienda no tiene ajustes para este riesgo", http.StatusNotFound, nil)
+	ErrTaxonomyNotMapped     = NewAppError("TAXONOMY_NOT_MAPPED", "La medida no tiene actividad de la Taxonomía asignada", http.StatusNotFound, nil)
+	ErrDisclosureNotFound    = NewAppError("DISCLOSURE_NOT_FOUND", "Informe de divulgación no encontrado", http.StatusNotFound, nil)
+	ErrResourceNotFound      = func(resource string) *AppError {
 		return NewAppError("NOT_FOUND", fmt.Sprintf("%s no encontrado", resource), http.StatusNotFound, nil)
 	}
 )
@@ -86,6 +100,12 @@ var (
 	ErrDuplicateEmail        = NewAppError("DUPLICATE_EMAIL", "El email ya está registrado", http.StatusConflict, nil)
 	ErrMeasureAlreadyApplied = NewAppError("MEASURE_ALREADY_APPLIED", "La medida ya está aplicada a esta tienda", http.StatusConflict, nil)
 	ErrMeasureNotApplied     = NewAppError("MEASURE_NOT_APPLIED", "La medida no está aplicada a esta tienda", http.StatusNotFound, nil)
+	ErrShopNotDeleted        = NewAppError("SHOP_NOT_DELETED", "La tienda no está eliminada", http.StatusConflict, nil)
+	ErrMeasureNotDeleted     = NewAppError("MEASURE_NOT_DELETED", "La medida no está eliminada", http.StatusConflict, nil)
+	ErrDuplicateInvoice      = NewAppError("DUPLICATE_INVOICE", "El número de factura ya está registrado para esta medida de la tienda", http.StatusConflict, nil)
+	ErrMeasureHasInvoices    = NewAppError("MEASURE_HAS_INVOICES", "La medida tiene facturas registradas y no se puede eliminar de la tienda", http.StatusConflict, nil)
+	ErrSSOAccountNotLinked   = NewAppError("SSO_ACCOUNT_NOT_LINKED", "Ya existe una cuenta con este email: inicie sesión con su contraseña y vincule el proveedor de identidad desde su cuenta", http.StatusConflict, nil)
+	ErrSSOIdentityConflict   = NewAppError("SSO_IDENTITY_CONFLICT", "La identidad del proveedor ya está vinculada a otra cuenta o la cuenta ya tiene otra vinculada", http.StatusConflict, nil)
 	ErrDuplicateResource     = func(resource string) *AppError {
 		return NewAppError("DUPLICATE_RESOURCE", fmt.Sprintf("%s ya existe", resource), http.StatusConflict, nil)
 	}
@@ -100,13 +120,22 @@ var (
 	ErrOptimization = func(err error) *AppError {
 		return NewAppError("OPTIMIZATION_ERROR", "Error durante el proceso de optimización", http.StatusInternalServerError, err)
 	}
+	ErrSSOBusy = NewAppError("SSO_BUSY", "Hay demasiados inicios de sesión único en curso; inténtelo de nuevo en unos minutos", http.StatusServiceUnavailable, nil)
 )
 
 // Errores de negocio (422)
 var (
-	ErrInsufficientBudget  = NewAppError("INSUFFICIENT_BUDGET", "El presupuesto es insuficiente para cualquier medida", http.StatusUnprocessableEntity, nil)
-	ErrNoMeasuresAvailable = NewAppError("NO_MEASURES_AVAILABLE", "No hay medidas disponibles para los riesgos identificados", http.StatusUnprocessableEntity, nil)
-	ErrNoShopsSelected     = NewAppError("NO_SHOPS_SELECTED", "Debe seleccionar al menos una tienda", http.StatusUnprocessableEntity, nil)
+	ErrInsufficientBudget   = NewAppError("INSUFFICIENT_BUDGET", "El presupuesto es insuficiente para cualquier medida", http.StatusUnprocessableEntity, nil)
+	ErrNoMeasuresAvailable  = NewAppError("NO_MEASURES_AVAILABLE", "No hay medidas disponibles para los riesgos identificados", http.StatusUnprocessableEntity, nil)
+	ErrNoShopsSelected      = NewAppError("NO_SHOPS_SELECTED", "Debe seleccionar al menos una tienda", http.StatusUnprocessableEntity, nil)
+	ErrMeasureNotStarted    = NewAppError("MEASURE_NOT_STARTED", "La medida no ha empezado; los costes reales se registran a partir de 'in_progress'", http.StatusUnprocessableEntity, nil)
+	ErrSelfModification     = NewAppError("SELF_MODIFICATION", "No puede cambiar su propio rol ni desactivar su propia cuenta", http.StatusUnprocessableEntity, nil)
+	ErrInvalidScoringConfig = func(details string) *AppError {
+		return NewAppError("INVALID_SCORING_CONFIG", details, http.StatusUnprocessableEntity, nil)
+	}
+	ErrInvalidMeasureTransition = func(details string) *AppError {
+		return NewAppError("INVALID_MEASURE_TRANSITION", details, http.StatusUnprocessableEntity, nil)
+	}
 )
 
 // WithInternal añade un error interno para logging
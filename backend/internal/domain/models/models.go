@@ -2,7 +2,10 @@
 // Estas estructuras representan los objetos de negocio principales.
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // Level representa los niveles de riesgo o severidad
 type Level string
@@ -24,20 +27,32 @@ const (
 	MeasureTypeImmaterial MeasureType = "Immaterial"
 )
 
+// MeasureCostModel representa cómo se calcula el coste de una medida en una tienda
+type MeasureCostModel string
+
+const (
+	CostModelFixed             MeasureCostModel = "fixed"               // EstimatedCost por tienda
+	CostModelPerM2             MeasureCostModel = "per_m2"              // CostPerM2 por la superficie de la tienda
+	CostModelFixedPlusVariable MeasureCostModel = "fixed_plus_variable" // EstimatedCost más CostPerM2 por la superficie
+)
+
 // Shop representa un inmueble/tienda en el sistema
 type Shop struct {
-	ID               int64     `json:"id" db:"id"`
-	Location         string    `json:"location" db:"location"`
-	UtmNorth         float64   `json:"utm_north" db:"utm_north"`
-	UtmEast          float64   `json:"utm_east" db:"utm_east"`
-	TotalRisk        float64   `json:"total_risk" db:"totalRisk"`
-	TaxonomyCoverage float64   `json:"taxonomy_coverage" db:"taxonomyCoverage"`
-	Surface          float64   `json:"surface" db:"surface"`
-	CarbonFootprint  float64   `json:"carbon_footprint" db:"carbonFootprint"`
-	ClusterID        int64     `json:"cluster_id" db:"cluster_id"`
-	Country          string    `json:"country" db:"country"`
-	CreatedAt        time.Time `json:"created_at,omitempty" db:"created_at"`
-	UpdatedAt        time.Time `json:"updated_at,omitempty" db:"updated_at"`
+	ID                      int64                 `json:"id" db:"id"`
+	Location                string                `json:"location" db:"location"`
+	UtmNorth                float64               `json:"utm_north" db:"utm_north"`
+	UtmEast                 float64               `json:"utm_east" db:"utm_east"`
+	TotalRisk               float64               `json:"total_risk" db:"totalRisk"`
+	TotalRiskMethod         RiskAggregationMethod `json:"total_risk_method,omitempty" db:"totalRiskMethod"`                  // Agregación usada para TotalRisk
+	TotalRiskScoringVersion int64                 `json:"total_risk_scoring_version,omitempty" db:"totalRiskScoringVersion"` // Versión de scoring usada para TotalRisk
+	TaxonomyCoverage        float64               `json:"taxonomy_coverage" db:"taxonomyCoverage"`                           // % de riesgos materiales reducidos por medidas alineadas con la Taxonomía
+	Surface                 float64               `json:"surface" db:"surface"`
+	CarbonFootprint         float64               `json:"carbon_footprint" db:"carbonFootprint"`
+	ClusterID               int64                 `json:"cluster_id" db:"cluster_id"`
+	Country                 string                `json:"country" db:"country"`
+	CreatedAt               time.Time             `json:"created_at,omitempty" db:"created_at"`
+	UpdatedAt               time.Time             `json:"updated_at,omitempty" db:"updated_at"`
+	DeletedAt               *time.Time            `json:"deleted_at,omitempty" db:"deleted_at"` // Fecha de borrado; nil si está activa
 }
 
 // Cluster representa una agrupación geográfica de tiendas
@@ -66,9 +81,37 @@ type ClusterRisk struct {
 
 // Measure representa una medida preventiva
 type Measure struct {
-	Name          string      `json:"name" db:"name"`
-	EstimatedCost float64     `json:"estimated_cost" db:"estimatedCost"`
-	Type          MeasureType `json:"type" db:"type"`
+	Name            string           `json:"name" db:"name"`
+	EstimatedCost   float64          `json:"estimated_cost" db:"estimatedCost"` // Coste fijo por tienda
+	CostModel       MeasureCostModel `json:"cost_model" db:"cost_model"`
+	CostPerM2       float64          `json:"cost_per_m2,omitempty" db:"cost_per_m2"` // Coste por m² de superficie
+	CarbonReduction float64          `json:"carbon_reduction" db:"carbon_reduction"` // Fracción de la huella anual de la tienda que evita, en [0, 1)
+	EmbodiedCarbon  float64          `json:"embodied_carbon" db:"embodied_carbon"`   // Emisiones de implantarla en una tienda, en tCO2e
+	Type            MeasureType      `json:"type" db:"type"`
+	DeletedAt       *time.Time       `json:"deleted_at,omitempty" db:"deleted_at"` // Fecha de borrado; nil si está activa
+}
+
+// CostFor calcula el coste de la medida en una tienda de la superficie indicada y
+// cuyo país tiene el índice de precios indicado (1 es el precio de referencia)
+func (m Measure) CostFor(surface, priceIndex float64) float64 {
+	var cost float64
+	switch m.CostModel {
+	case CostModelPerM2:
+		cost = m.CostPerM2 * surface
+	case CostModelFixedPlusVariable:
+		cost = m.EstimatedCost + m.CostPerM2*surface
+	default:
+		cost = m.EstimatedCost
+	}
+	return cost * priceIndex
+}
+
+// ResolveCost retorna la medida con el coste calculado por CostFor como coste fijo
+func (m Measure) ResolveCost(surface, priceIndex float64) Measure {
+	m.EstimatedCost = m.CostFor(surface, priceIndex)
+	m.CostModel = CostModelFixed
+	m.CostPerM2 = 0
+	return m
 }
 
 // RiskMeasure representa la relación entre un riesgo y una medida
@@ -77,15 +120,135 @@ type RiskMeasure struct {
 	MeasureName string `json:"measure_name" db:"measure_name"`
 }
 
-// ShopMeasure representa las medidas aplicadas a una tienda
+// MeasureStatus representa la fase de implantación de una medida en una tienda
+type MeasureStatus string
+
+const (
+	MeasureStatusPlanned    MeasureStatus = "planned"
+	MeasureStatusApproved   MeasureStatus = "approved"
+	MeasureStatusInProgress MeasureStatus = "in_progress"
+	MeasureStatusCompleted  MeasureStatus = "completed"
+	MeasureStatusVerified   MeasureStatus = "verified"
+)
+
+// measureStatusOrder es el orden del ciclo de vida de una medida
+var measureStatusOrder = map[MeasureStatus]int{
+	MeasureStatusPlanned:    1,
+	MeasureStatusApproved:   2,
+	MeasureStatusInProgress: 3,
+	MeasureStatusCompleted:  4,
+	MeasureStatusVerified:   5,
+}
+
+// IsValid indica si el estado es uno de los definidos
+func (s MeasureStatus) IsValid() bool {
+	return measureStatusOrder[s] > 0
+}
+
+// CanTransitionTo indica si la medida puede pasar al estado indicado. El ciclo de
+// vida solo avanza de una fase a la siguiente; para descartar una medida se retira.
+func (s MeasureStatus) CanTransitionTo(next MeasureStatus) bool {
+	return s.IsValid() && measureStatusOrder[next] == measureStatusOrder[s]+1
+}
+
+// Reached indica si la medida ha alcanzado (o superado) la fase indicada
+func (s MeasureStatus) Reached(phase MeasureStatus) bool {
+	return s.IsValid() && measureStatusOrder[s] >= measureStatusOrder[phase]
+}
+
+// IsCompleted indica si la medida está implantada y por tanto reduce el riesgo
+func (s MeasureStatus) IsCompleted() bool {
+	return s.Reached(MeasureStatusCompleted)
+}
+
+// ShopMeasure representa una medida aplicada a una tienda junto con su implantación
 type ShopMeasure struct {
-	ShopID      int64  `json:"shop_id" db:"shop_id"`
-	MeasureName string `json:"measure_name" db:"measure_name"`
+	ShopID int64 `json:"shop_id" db:"shop_id"`
+	Measure
+	Status           MeasureStatus `json:"status" db:"status"`
+	PlannedStartDate *time.Time    `json:"planned_start_date,omitempty" db:"planned_start_date"`
+	PlannedEndDate   *time.Time    `json:"planned_end_date,omitempty" db:"planned_end_date"`
+	ActualStartDate  *time.Time    `json:"actual_start_date,omitempty" db:"actual_start_date"`
+	ActualEndDate    *time.Time    `json:"actual_end_date,omitempty" db:"actual_end_date"`
+	ActualCost       *float64      `json:"actual_cost,omitempty" db:"actual_cost"` // Suma de las facturas si las hay
+	Invoices         int64         `json:"invoices" db:"invoices"`                 // Facturas registradas
+	Notes            string        `json:"notes,omitempty" db:"notes"`
+	StatusUpdatedAt  time.Time     `json:"status_updated_at" db:"status_updated_at"`
 }
 
+// MeasureInvoice representa una factura del coste real de una medida aplicada a una tienda
+type MeasureInvoice struct {
+	ID            int64     `json:"id" db:"id"`
+	ShopID        int64     `json:"shop_id" db:"shop_id"`
+	MeasureName   string    `json:"measure_name" db:"measure_name"`
+	Amount        float64   `json:"amount" db:"amount"`
+	InvoiceNumber string    `json:"invoice_number" db:"invoice_number"`
+	InvoicedAt    time.Time `json:"invoiced_at" db:"invoiced_at"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
+
+// TaxonomyActivity representa una actividad económica del anexo de adaptación al cambio
+// climático del Reglamento Delegado de la Taxonomía de la UE
+type TaxonomyActivity struct {
+	Code string `json:"code"` // Número de la actividad en el anexo, p. ej. "7.2"
+	Name string `json:"name"`
+}
+
+// DNSHCriteria indica si una medida cumple los criterios de no causar un perjuicio
+// significativo (DNSH) a los demás objetivos ambientales de la Taxonomía
+type DNSHCriteria struct {
+	ClimateMitigation bool `json:"climate_mitigation" db:"dnsh_climate_mitigation"`
+	Water             bool `json:"water" db:"dnsh_water"`
+	CircularEconomy   bool `json:"circular_economy" db:"dnsh_circular_economy"`
+	Pollution         bool `json:"pollution" db:"dnsh_pollution"`
+	Biodiversity      bool `json:"biodiversity" db:"dnsh_biodiversity"`
+}
+
+// Unmet retorna los criterios DNSH que no se cumplen
+func (d DNSHCriteria) Unmet() []string {
+	var unmet []string
+	for _, c := range []struct {
+		name string
+		met  bool
+	}{
+		{"climate_mitigation", d.ClimateMitigation},
+		{"water", d.Water},
+		{"circular_economy", d.CircularEconomy},
+		{"pollution", d.Pollution},
+		{"biodiversity", d.Biodiversity},
+	} {
+		if !c.met {
+			unmet = append(unmet, c.name)
+		}
+	}
+	return unmet
+}
+
+// MeasureTaxonomy representa la correspondencia de una medida con una actividad de la
+// Taxonomía y el cumplimiento de sus criterios DNSH
+type MeasureTaxonomy struct {
+	MeasureName  string       `json:"measure_name" db:"measure_name"`
+	ActivityCode string       `json:"activity_code" db:"activity_code"`
+	DNSH         DNSHCriteria `json:"dnsh"`
+	Notes        string       `json:"notes,omitempty" db:"notes"`
+	UpdatedAt    time.Time    `json:"updated_at" db:"updated_at"`
+}
+
+// TaxonomyAlignmentStatus representa la alineación de una tienda con los criterios de
+// adaptación al cambio climático de la Taxonomía
+type TaxonomyAlignmentStatus string
+
+const (
+	TaxonomyAligned          TaxonomyAlignmentStatus = "aligned"           // Todos los riesgos materiales reducidos por medidas alineadas
+	TaxonomyPartiallyAligned TaxonomyAlignmentStatus = "partially_aligned" // Solo parte de los riesgos materiales
+	TaxonomyNotAligned       TaxonomyAlignmentStatus = "not_aligned"       // Ningún riesgo material reducido
+	TaxonomyNoMaterialRisks  TaxonomyAlignmentStatus = "no_material_risks" // La evaluación no identifica riesgos materiales
+)
+
 // Country representa un país
 type Country struct {
-	Name string `json:"name" db:"name"`
+	Name       string  `json:"name" db:"name"`
+	PriceIndex float64 `json:"price_index" db:"price_index"` // Índice de precios de construcción; 1 es el de referencia
 }
 
 // User representa un usuario del sistema
@@ -94,8 +257,15 @@ type User struct {
 	Email     string    `json:"email" db:"email"`
 	Password  string    `json:"-" db:"password"` // Nunca exponer el password en JSON
 	Role      UserRole  `json:"role" db:"role"`
+	Active    bool      `json:"active" db:"active"` // Los usuarios desactivados no pueden iniciar sesión
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	// Ámbito de datos del usuario; vacío significa acceso a todos los países y clusters
+	Countries  []string `json:"countries,omitempty" db:"countries"`
+	ClusterIDs []int64  `json:"cluster_ids,omitempty" db:"cluster_ids"`
+	// Identidad del proveedor de identidad (OIDC) vinculada a la cuenta; vacía si no tiene
+	OIDCIssuer  string `json:"oidc_issuer,omitempty" db:"oidc_issuer"`
+	OIDCSubject string `json:"oidc_subject,omitempty" db:"oidc_subject"`
 }
 
 // UserRole representa el rol del usuario
@@ -107,22 +277,147 @@ const (
 	RoleViewer  UserRole = "viewer"
 )
 
+// AuthSession representa una sesión iniciada por un usuario en un dispositivo.
+// Los access tokens y refresh tokens emitidos en la sesión dejan de ser válidos
+// cuando se revoca.
+type AuthSession struct {
+	ID        int64      `json:"id" db:"id"`
+	UserID    int64      `json:"user_id" db:"user_id"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+}
+
+// RefreshToken representa un refresh token de un solo uso. Solo se guarda el hash
+// del token; al usarse se marca como usado y se emite uno nuevo en la misma sesión.
+type RefreshToken struct {
+	ID        int64      `json:"id" db:"id"`
+	SessionID int64      `json:"session_id" db:"session_id"`
+	UserID    int64      `json:"user_id" db:"user_id"`
+	TokenHash string     `json:"-" db:"token_hash"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty" db:"used_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// APIKey representa una clave de acceso para integraciones máquina a máquina.
+// Solo se guarda el hash de la clave; Prefix permite identificarla en listados.
+// La clave actúa con los permisos de Role, opcionalmente restringidos a Permissions, y
+// solo ve los datos de su ámbito, igual que un usuario.
+type APIKey struct {
+	ID          int64      `json:"id" db:"id"`
+	Name        string     `json:"name" db:"name"`
+	Prefix      string     `json:"prefix" db:"prefix"`
+	KeyHash     string     `json:"-" db:"key_hash"`
+	Role        UserRole   `json:"role" db:"role"`
+	Permissions []string   `json:"permissions,omitempty" db:"permissions"`
+	RateLimit   int        `json:"rate_limit" db:"rate_limit"` // Peticiones por minuto
+	CreatedBy   int64      `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	// Ámbito de datos de la clave; vacío significa acceso a todos los países y clusters
+	Countries  []string `json:"countries,omitempty" db:"countries"`
+	ClusterIDs []int64  `json:"cluster_ids,omitempty" db:"cluster_ids"`
+}
+
+// AuditEntry representa un cambio registrado en el log de auditoría. El actor es un
+// usuario o una API key; ambos vacíos indican una operación del propio sistema.
+type AuditEntry struct {
+	ID            int64           `json:"id" db:"id"`
+	ActorUserID   *int64          `json:"actor_user_id,omitempty" db:"actor_user_id"`
+	ActorAPIKeyID *int64          `json:"actor_api_key_id,omitempty" db:"actor_api_key_id"`
+	ActorEmail    string          `json:"actor_email,omitempty" db:"actor_email"`
+	ActorRole     UserRole        `json:"actor_role,omitempty" db:"actor_role"`
+	Action        AuditAction     `json:"action" db:"action"`
+	EntityType    AuditEntityType `json:"entity_type" db:"entity_type"`
+	EntityID      string          `json:"entity_id" db:"entity_id"`
+	Before        json.RawMessage `json:"before,omitempty" db:"before"`
+	After         json.RawMessage `json:"after,omitempty" db:"after"`
+	RequestID     string          `json:"request_id,omitempty" db:"request_id"`
+	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+}
+
+// AuditAction representa la operación registrada en el log de auditoría
+type AuditAction string
+
+const (
+	AuditShopCreated            AuditAction = "shop.created"
+	AuditShopUpdated            AuditAction = "shop.updated"
+	AuditShopDeleted            AuditAction = "shop.deleted"
+	AuditShopRestored           AuditAction = "shop.restored"
+	AuditMeasuresApplied        AuditAction = "shop.measures_applied"
+	AuditMeasureRemoved         AuditAction = "shop.measure_removed"
+	AuditMeasureTransitioned    AuditAction = "shop.measure_transitioned"
+	AuditMeasureInvoiced        AuditAction = "shop.measure_invoiced"
+	AuditRiskOverrideSet        AuditAction = "shop.risk_override_set"
+	AuditRiskOverrideRemoved    AuditAction = "shop.risk_override_removed"
+	AuditMeasureDeleted         AuditAction = "measure.deleted"
+	AuditMeasureRestored        AuditAction = "measure.restored"
+	AuditTaxonomyMappingSet     AuditAction = "measure.taxonomy_set"
+	AuditTaxonomyMappingRemoved AuditAction = "measure.taxonomy_removed"
+	AuditRiskProjectionSet      AuditAction = "cluster.risk_projection_set"
+	AuditScoringConfigCreated   AuditAction = "scoring_config.created"
+	AuditScoringConfigActivated AuditAction = "scoring_config.activated"
+	AuditOptimizationRun        AuditAction = "optimization.run"
+	AuditUserRegistered         AuditAction = "user.registered"
+	AuditUserProvisioned        AuditAction = "user.provisioned"
+	AuditUserSSOLinked          AuditAction = "user.sso_linked"
+	AuditUserInvited            AuditAction = "user.invited"
+	AuditUserRoleChanged        AuditAction = "user.role_changed"
+	AuditUserScopeChanged       AuditAction = "user.scope_changed"
+	AuditUserDeactivated        AuditAction = "user.deactivated"
+	AuditUserReactivated        AuditAction = "user.reactivated"
+	AuditUserPasswordReset      AuditAction = "user.password_reset"
+	AuditAPIKeyCreated          AuditAction = "api_key.created"
+	AuditAPIKeyRevoked          AuditAction = "api_key.revoked"
+)
+
+// AuditEntityType representa el tipo de entidad afectada por una operación auditada
+type AuditEntityType string
+
+const (
+	AuditEntityShop              AuditEntityType = "shop"
+	AuditEntityShopMeasure       AuditEntityType = "shop_measure"
+	AuditEntityMeasureInvoice    AuditEntityType = "shop_measure_invoice"
+	AuditEntityMeasure           AuditEntityType = "measure"
+	AuditEntityMeasureTaxonomy   AuditEntityType = "measure_taxonomy"
+	AuditEntityShopRiskOverride  AuditEntityType = "shop_risk_override"
+	AuditEntityClusterProjection AuditEntityType = "cluster_risk_projection"
+	AuditEntityScoringConfig     AuditEntityType = "scoring_config"
+	AuditEntityOptimization      AuditEntityType = "optimization"
+	AuditEntityUser              AuditEntityType = "user"
+	AuditEntityAPIKey            AuditEntityType = "api_key"
+)
+
+// OIDCIdentity representa la identidad de un usuario verificada por el proveedor
+// de identidad (IdP) a partir del ID token de OpenID Connect
+type OIDCIdentity struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified *bool // nil si el IdP no informa el claim email_verified
+	Groups        []string
+	Nonce         string
+}
+
 // ShopWithDetails representa una tienda con información extendida
 type ShopWithDetails struct {
 	Shop
-	ClusterName string        `json:"cluster_name"`
-	Risks       []RiskDetail  `json:"risks,omitempty"`
-	Measures    []Measure     `json:"applied_measures,omitempty"`
+	ClusterName string       `json:"cluster_name"`
+	Risks       []RiskDetail `json:"risks,omitempty"`
+	Measures    []Measure    `json:"applied_measures,omitempty"`
 }
 
 // RiskDetail representa un riesgo con su evaluación completa
 type RiskDetail struct {
 	Risk
-	Exposure    Level   `json:"exposure"`
-	Sensitivity Level   `json:"sensitivity"`
-	Consequence Level   `json:"consequence"`
-	Probability Level   `json:"probability"`
-	RiskScore   float64 `json:"risk_score"`
+	Exposure       Level   `json:"exposure"`
+	Sensitivity    Level   `json:"sensitivity"`
+	Consequence    Level   `json:"consequence"`
+	Probability    Level   `json:"probability"`
+	RiskScore      float64 `json:"risk_score"`
+	OverrideReason string  `json:"override_reason,omitempty"` // Presente si la tienda ajusta los valores del cluster
 }
 
 // ClusterWithRisks representa un cluster con sus riesgos asociados
@@ -130,3 +425,165 @@ type ClusterWithRisks struct {
 	Cluster
 	Risks []RiskDetail `json:"risks"`
 }
+
+// ShopRiskOverride representa el ajuste de la vulnerabilidad de una tienda frente a un
+// riesgo concreto. Los niveles no nulos prevalecen sobre los heredados del cluster.
+type ShopRiskOverride struct {
+	ShopID      int64     `json:"shop_id" db:"shop_id"`
+	RiskID      int64     `json:"risk_id" db:"risk_id"`
+	RiskName    string    `json:"risk_name,omitempty"`
+	Exposure    *Level    `json:"exposure,omitempty" db:"exposure"`
+	Sensitivity *Level    `json:"sensitivity,omitempty" db:"sensitivity"`
+	Reason      string    `json:"reason" db:"reason"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// ClimateScenario representa un escenario climático (SSP-RCP del IPCC AR6)
+type ClimateScenario string
+
+const (
+	// ScenarioCurrent: niveles de riesgo actuales, sin proyección
+	ScenarioCurrent ClimateScenario = "current"
+	ScenarioSSP126  ClimateScenario = "ssp1-2.6"
+	ScenarioSSP245  ClimateScenario = "ssp2-4.5"
+	ScenarioSSP585  ClimateScenario = "ssp5-8.5"
+)
+
+// Horizontes temporales soportados para las proyecciones
+const (
+	Horizon2030 = 2030
+	Horizon2050 = 2050
+)
+
+// ScenarioSelection identifica el escenario y horizonte de una evaluación.
+// El valor cero equivale al escenario actual.
+type ScenarioSelection struct {
+	Scenario ClimateScenario `json:"scenario"`
+	Horizon  int             `json:"horizon,omitempty"`
+}
+
+// IsCurrent indica si la selección corresponde a los niveles actuales
+func (s ScenarioSelection) IsCurrent() bool {
+	return s.Scenario == "" || s.Scenario == ScenarioCurrent
+}
+
+// ClusterRiskProjection representa los niveles proyectados de un riesgo de un cluster
+// para un escenario y horizonte. Los niveles nulos no tienen proyección.
+type ClusterRiskProjection struct {
+	ClusterID   int64           `json:"cluster_id" db:"cluster_id"`
+	RiskID      int64           `json:"risk_id" db:"risk_id"`
+	Scenario    ClimateScenario `json:"scenario" db:"scenario"`
+	Horizon     int             `json:"horizon" db:"horizon"`
+	Exposure    *Level          `json:"exposure,omitempty" db:"exposure"`
+	Sensitivity *Level          `json:"sensitivity,omitempty" db:"sensitivity"`
+	Consequence *Level          `json:"consequence,omitempty" db:"consequence"`
+	Probability *Level          `json:"probability,omitempty" db:"probability"`
+}
+
+// SnapshotTrigger representa el motivo por el que se recalculó el riesgo de una tienda
+type SnapshotTrigger string
+
+const (
+	TriggerShopCreated      SnapshotTrigger = "shop_created"
+	TriggerShopUpdated      SnapshotTrigger = "shop_updated"
+	TriggerMeasuresApplied  SnapshotTrigger = "measures_applied"
+	TriggerMeasureRemoved   SnapshotTrigger = "measure_removed"
+	TriggerMeasureCompleted SnapshotTrigger = "measure_completed"
+	TriggerOverrideChanged  SnapshotTrigger = "risk_override_changed"
+	TriggerScoringActivated SnapshotTrigger = "scoring_config_activated"
+	TriggerTaxonomyChanged  SnapshotTrigger = "taxonomy_mapping_changed"
+	TriggerMeasureDeleted   SnapshotTrigger = "measure_deleted"
+	TriggerMeasureRestored  SnapshotTrigger = "measure_restored"
+)
+
+// RiskSnapshot representa el estado de riesgo de una tienda en un momento dado.
+// Se guarda uno cada vez que se recalcula el riesgo total.
+type RiskSnapshot struct {
+	ID                int64                 `json:"id" db:"id"`
+	ShopID            int64                 `json:"shop_id" db:"shop_id"`
+	TotalRisk         float64               `json:"total_risk" db:"total_risk"`
+	AggregationMethod RiskAggregationMethod `json:"aggregation_method" db:"aggregation_method"`
+	ScoringVersion    int64                 `json:"scoring_version" db:"scoring_version"`
+	TaxonomyCoverage  float64               `json:"taxonomy_coverage" db:"taxonomy_coverage"`
+	RiskCoverage      float64               `json:"risk_coverage" db:"risk_coverage"` // % de riesgos cubiertos por medidas
+	Risks             []RiskSnapshotItem    `json:"risks"`
+	AppliedMeasures   []string              `json:"applied_measures"`
+	Trigger           SnapshotTrigger       `json:"trigger" db:"trigger"`
+	CreatedAt         time.Time             `json:"created_at" db:"created_at"`
+}
+
+// RiskSnapshotItem representa el score de un riesgo dentro de un snapshot
+type RiskSnapshotItem struct {
+	RiskID    int64   `json:"risk_id"`
+	RiskName  string  `json:"risk_name"`
+	RiskScore float64 `json:"risk_score"`
+}
+
+// RiskTrendPoint representa la evolución agregada del riesgo en un periodo
+type RiskTrendPoint struct {
+	Period          time.Time `json:"period"`
+	AverageRisk     float64   `json:"average_risk"`
+	ShopsEvaluated  int64     `json:"shops_evaluated"`
+	AppliedMeasures int64     `json:"applied_measures"`
+}
+
+// RiskScoringMethod representa la metodología de cálculo del score de riesgo
+type RiskScoringMethod string
+
+const (
+	// ScoringMultiplicative: Exposure^we * Sensitivity^ws * Consequence^wc * Probability^wp
+	ScoringMultiplicative RiskScoringMethod = "multiplicative"
+	// ScoringAdditiveWeighted: media ponderada de los cuatro factores
+	ScoringAdditiveWeighted RiskScoringMethod = "additive_weighted"
+	// ScoringMaxBased: el factor ponderado más desfavorable determina el score
+	ScoringMaxBased RiskScoringMethod = "max"
+	// ScoringIPCC: Amenaza (probabilidad) × Exposición × Vulnerabilidad (sensibilidad y consecuencia)
+	ScoringIPCC RiskScoringMethod = "ipcc"
+)
+
+// RiskAggregationMethod representa cómo se combinan los scores de los riesgos
+// de una tienda en su riesgo total
+type RiskAggregationMethod string
+
+const (
+	// AggregationMean: media aritmética de los scores
+	AggregationMean RiskAggregationMethod = "mean"
+	// AggregationMax: el riesgo más severo determina el total
+	AggregationMax RiskAggregationMethod = "max"
+	// AggregationWeighted: media ponderada por la importancia definida para cada riesgo
+	AggregationWeighted RiskAggregationMethod = "weighted"
+	// AggregationProbabilistic: probabilidad de que ocurra al menos un evento, 1 - Π(1 - score)
+	AggregationProbabilistic RiskAggregationMethod = "probabilistic"
+)
+
+// RiskFactorWeights contiene los pesos de cada factor de riesgo
+type RiskFactorWeights struct {
+	Exposure    float64 `json:"exposure"`
+	Sensitivity float64 `json:"sensitivity"`
+	Consequence float64 `json:"consequence"`
+	Probability float64 `json:"probability"`
+}
+
+// RiskLevelThresholds define el límite superior (exclusivo) de cada nivel de riesgo.
+// Todo score igual o superior a High se considera very_high.
+type RiskLevelThresholds struct {
+	VeryLow float64 `json:"very_low"`
+	Low     float64 `json:"low"`
+	Medium  float64 `json:"medium"`
+	High    float64 `json:"high"`
+}
+
+// RiskScoringConfig representa una versión de la configuración del modelo de scoring
+type RiskScoringConfig struct {
+	Version        int64                       `json:"version" db:"version"`
+	Name           string                      `json:"name" db:"name"`
+	Method         RiskScoringMethod           `json:"method" db:"method"`
+	LevelValues    map[Level]float64           `json:"level_values"`
+	Weights        RiskFactorWeights           `json:"weights"`
+	RiskWeights    map[int64]RiskFactorWeights `json:"risk_weights,omitempty"` // Pesos específicos por ID de riesgo
+	Thresholds     RiskLevelThresholds         `json:"thresholds"`
+	Aggregation    RiskAggregationMethod       `json:"aggregation"`
+	RiskImportance map[int64]float64           `json:"risk_importance,omitempty"` // Importancia por ID de riesgo (agregación weighted)
+	Active         bool                        `json:"active" db:"active"`
+	CreatedAt      time.Time                   `json:"created_at" db:"created_at"`
+}
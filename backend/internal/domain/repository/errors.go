@@ -7,4 +7,11 @@ import "errors"
 var (
 	ErrMeasureNotAppliedToShop     = errors.New("measure not applied to this shop")
 	ErrMeasureAlreadyAppliedToShop = errors.New("measure already applied to this shop")
+	ErrRiskOverrideNotFound        = errors.New("risk override not found for this shop")
+	ErrUserNotFound                = errors.New("user not found")
+	ErrOIDCIdentityTaken           = errors.New("oidc identity already linked to another user")
+	ErrAPIKeyNotFound              = errors.New("api key not found")
+	ErrTaxonomyMappingNotFound     = errors.New("taxonomy mapping not found for this measure")
+	ErrMeasureHasInvoices          = errors.New("measure has invoices for this shop")
+	ErrDuplicateInvoiceNumber      = errors.New("invoice number already recorded for this shop measure")
 )
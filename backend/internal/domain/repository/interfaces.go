@@ -5,6 +5,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
 )
@@ -17,18 +18,32 @@ type ShopRepository interface {
 	Update(ctx context.Context, shop *models.Shop) error
 	Delete(ctx context.Context, id int64) error
 
+	// Borrado lógico: Delete marca la tienda como eliminada y conserva sus relaciones
+	GetDeletedByID(ctx context.Context, id int64) (*models.Shop, error)
+	Restore(ctx context.Context, id int64) error
+
 	// Consultas
 	List(ctx context.Context, filter *models.ShopFilterRequest) ([]models.Shop, int64, error)
 	GetByClusterID(ctx context.Context, clusterID int64) ([]models.Shop, error)
 	GetWithDetails(ctx context.Context, id int64) (*models.ShopWithDetails, error)
 
-	// Medidas
+	// IDs de las tiendas activas para los recálculos de riesgo, sin aplicar el ámbito
+	// del usuario: todas, o las que tienen aplicada una medida en cualquier estado
+	ListActiveIDs(ctx context.Context) ([]int64, error)
+	ListIDsWithMeasure(ctx context.Context, measureName string) ([]int64, error)
+
+	// Medidas. GetAppliedMeasures retorna todas las medidas de la tienda sea cual sea
+	// su estado; GetCompletedMeasures solo las implantadas.
 	GetAppliedMeasures(ctx context.Context, shopID int64) ([]models.Measure, error)
+	GetCompletedMeasures(ctx context.Context, shopID int64) ([]models.Measure, error)
+	GetShopMeasures(ctx context.Context, shopID int64) ([]models.ShopMeasure, error)
+	GetShopMeasure(ctx context.Context, shopID int64, measureName string) (*models.ShopMeasure, error)
+	UpdateShopMeasure(ctx context.Context, measure *models.ShopMeasure) error
 	ApplyMeasure(ctx context.Context, shopID int64, measureName string) error
 	RemoveMeasure(ctx context.Context, shopID int64, measureName string) error
 
 	// Estadísticas
-	GetStats(ctx context.Context) (*models.DashboardStats, error)
+	GetStats(ctx context.Context, query *models.DashboardStatsQuery, thresholds models.RiskLevelThresholds) (*models.DashboardStats, error)
 
 	// Cobertura de riesgos
 	GetRiskCoverage(ctx context.Context, shopID int64) (*models.RiskCoverageResponse, error)
@@ -59,7 +74,10 @@ type MeasureRepository interface {
 	GetByName(ctx context.Context, name string) (*models.Measure, error)
 	Update(ctx context.Context, measure *models.Measure) error
 	Delete(ctx context.Context, name string) error
+	GetDeletedByName(ctx context.Context, name string) (*models.Measure, error)
+	Restore(ctx context.Context, name string) error
 	List(ctx context.Context) ([]models.Measure, error)
+	ListIncludingDeleted(ctx context.Context) ([]models.Measure, error)
 	GetByType(ctx context.Context, measureType models.MeasureType) ([]models.Measure, error)
 	GetByRisk(ctx context.Context, riskName string) ([]models.Measure, error)
 	GetApplicableForShop(ctx context.Context, shopID int64) ([]models.Measure, error)
@@ -74,14 +92,93 @@ type ClusterRiskRepository interface {
 	GetByRisk(ctx context.Context, riskID int64) ([]models.ClusterRisk, error)
 }
 
+// ShopRiskOverrideRepository define las operaciones para los ajustes de riesgo por tienda
+type ShopRiskOverrideRepository interface {
+	Upsert(ctx context.Context, override *models.ShopRiskOverride) error
+	Delete(ctx context.Context, shopID, riskID int64) error
+	GetByShop(ctx context.Context, shopID int64) ([]models.ShopRiskOverride, error)
+}
+
+// ClusterRiskScenarioRepository define las operaciones para las proyecciones de riesgo por escenario
+type ClusterRiskScenarioRepository interface {
+	Upsert(ctx context.Context, projection *models.ClusterRiskProjection) error
+	// GetByCluster obtiene las proyecciones de un cluster para un escenario en todos sus horizontes
+	GetByCluster(ctx context.Context, clusterID int64, scenario models.ClimateScenario) ([]models.ClusterRiskProjection, error)
+}
+
+// RiskSnapshotRepository define las operaciones para el historial de riesgo de las tiendas
+type RiskSnapshotRepository interface {
+	Create(ctx context.Context, snapshot *models.RiskSnapshot) error
+	GetLatest(ctx context.Context, shopID int64) (*models.RiskSnapshot, error)
+	ListByShop(ctx context.Context, shopID int64, limit int) ([]models.RiskSnapshot, error)
+	// GetTrend agrega el último snapshot de cada tienda por periodo (day, week, month)
+	GetTrend(ctx context.Context, interval string, since time.Time) ([]models.RiskTrendPoint, error)
+}
+
+// RiskScoringConfigRepository define las operaciones para las configuraciones de scoring.
+// Las configuraciones son inmutables: cada cambio crea una nueva versión.
+type RiskScoringConfigRepository interface {
+	Create(ctx context.Context, cfg *models.RiskScoringConfig) error
+	GetByVersion(ctx context.Context, version int64) (*models.RiskScoringConfig, error)
+	GetActive(ctx context.Context) (*models.RiskScoringConfig, error)
+	List(ctx context.Context) ([]models.RiskScoringConfig, error)
+	Activate(ctx context.Context, version int64) error
+}
+
 // UserRepository define las operaciones de acceso a datos para usuarios
 type UserRepository interface {
 	Create(ctx context.Context, user *models.User) error
 	GetByID(ctx context.Context, id int64) (*models.User, error)
 	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	// GetByOIDCIdentity obtiene el usuario vinculado a la identidad (issuer y subject) del IdP
+	GetByOIDCIdentity(ctx context.Context, issuer, subject string) (*models.User, error)
 	Update(ctx context.Context, user *models.User) error
 	Delete(ctx context.Context, id int64) error
 	EmailExists(ctx context.Context, email string) (bool, error)
+	List(ctx context.Context, filter *models.UserFilterRequest) ([]models.User, error)
+}
+
+// CostRepository define las operaciones sobre el coste real de las medidas aplicadas a las tiendas
+type CostRepository interface {
+	// CreateInvoice registra una factura y actualiza el coste real de la medida de la
+	// tienda con el total facturado
+	CreateInvoice(ctx context.Context, invoice *models.MeasureInvoice) error
+	ListInvoices(ctx context.Context, shopID int64) ([]models.MeasureInvoice, error)
+	// GetVariance agrega el coste estimado para cada tienda y el real de las medidas
+	// completadas con coste real registrado, dentro del ámbito del usuario. Variance y VariancePercentage no se rellenan.
+	GetVariance(ctx context.Context, groupBy models.CostVarianceGroup) ([]models.CostVarianceRow, error)
+}
+
+// AuditRepository define las operaciones del log de auditoría. Las entradas solo
+// se insertan: nunca se modifican ni se borran.
+type AuditRepository interface {
+	Create(ctx context.Context, entry *models.AuditEntry) error
+	List(ctx context.Context, filter *models.AuditFilterRequest) ([]models.AuditEntry, int64, error)
+}
+
+// AuthSessionRepository define las operaciones para sesiones de usuario y sus
+// refresh tokens. Los tokens se buscan por su hash, nunca por el valor en claro.
+type AuthSessionRepository interface {
+	CreateSession(ctx context.Context, session *models.AuthSession) error
+	GetSession(ctx context.Context, id int64) (*models.AuthSession, error)
+	RevokeSession(ctx context.Context, id int64) error
+	RevokeUserSessions(ctx context.Context, userID int64) error
+	IsSessionRevoked(ctx context.Context, id int64) (bool, error)
+	CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error
+	GetRefreshTokenByHash(ctx context.Context, hash string) (*models.RefreshToken, error)
+	// MarkRefreshTokenUsed marca el token como usado; retorna false si ya lo estaba
+	MarkRefreshTokenUsed(ctx context.Context, id int64) (bool, error)
+}
+
+// APIKeyRepository define las operaciones para API keys. Las claves se buscan por
+// su hash, nunca por el valor en claro.
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *models.APIKey) error
+	GetByID(ctx context.Context, id int64) (*models.APIKey, error)
+	GetByHash(ctx context.Context, hash string) (*models.APIKey, error)
+	List(ctx context.Context) ([]models.APIKey, error)
+	Revoke(ctx context.Context, id int64) error
+	TouchLastUsed(ctx context.Context, id int64, usedAt time.Time) error
 }
 
 // CountryRepository define las operaciones de acceso a datos para países
@@ -92,6 +189,39 @@ type CountryRepository interface {
 	Delete(ctx context.Context, name string) error
 }
 
+// TaxonomyRepository define las operaciones sobre la correspondencia de las medidas con
+// la Taxonomía de la UE
+type TaxonomyRepository interface {
+	ListMappings(ctx context.Context) ([]models.MeasureTaxonomy, error)
+	GetMapping(ctx context.Context, measureName string) (*models.MeasureTaxonomy, error)
+	UpsertMapping(ctx context.Context, mapping *models.MeasureTaxonomy) error
+	DeleteMapping(ctx context.Context, measureName string) error
+	// GetRiskMeasures obtiene los nombres de las medidas que reducen cada riesgo, por nombre de riesgo
+	GetRiskMeasures(ctx context.Context) (map[string][]string, error)
+	// GetPortfolioShopData lee en una misma transacción las tiendas activas dentro del
+	// ámbito del usuario con los riesgos de sus clusters, sus ajustes y sus medidas
+	GetPortfolioShopData(ctx context.Context) (*models.PortfolioShopData, error)
+}
+
+// AnalyticsRepository define las consultas agregadas de la cartera para los dashboards
+type AnalyticsRepository interface {
+	GetPortfolioGroups(ctx context.Context, query *models.PortfolioAnalyticsQuery) ([]models.PortfolioGroup, error)
+	GetRiskMatrixPairs(ctx context.Context, query *models.RiskMatrixQuery) ([]models.RiskMatrixPair, error)
+}
+
+// DisclosureRepository define las operaciones de los informes de divulgación
+type DisclosureRepository interface {
+	// LoadInputs lee en una misma transacción los datos de las tiendas activas dentro
+	// del ámbito del usuario, con la evolución mensual del riesgo desde trendSince
+	LoadInputs(ctx context.Context, trendSince time.Time) (*models.DisclosureInputs, error)
+	// SaveReport guarda un informe bajo su suma de comprobación; si ya hay uno guardado
+	// con la misma suma se conserva el primero
+	SaveReport(ctx context.Context, report *models.DisclosureReport, shops []models.Shop) error
+	// GetReport obtiene un informe guardado cuyas tiendas están todas dentro del ámbito
+	// del usuario; nil si no existe
+	GetReport(ctx context.Context, checksum string) (*models.DisclosureReport, error)
+}
+
 // Transaction define la interfaz para manejo de transacciones
 type Transaction interface {
 	Begin(ctx context.Context) (Transaction, error)
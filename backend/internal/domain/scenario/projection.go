@@ -0,0 +1,71 @@
+// Package scenario aplica las proyecciones climáticas (SSP-RCP) sobre los niveles
+// de riesgo actuales de un cluster.
+//
+// Reglas cuando falta un valor proyectado, aplicadas factor a factor:
+//  1. Se usa la proyección del escenario para el horizonte solicitado.
+//  2. Si no existe, la del horizonte anterior más cercano del mismo escenario.
+//  3. Si tampoco existe, se mantiene el nivel actual del cluster.
+package scenario
+
+import (
+	"sort"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+)
+
+// DefaultHorizon es el horizonte usado cuando se indica un escenario sin horizonte
+const DefaultHorizon = models.Horizon2030
+
+// Normalize completa una selección de escenario con sus valores por defecto
+func Normalize(sel models.ScenarioSelection) models.ScenarioSelection {
+	if sel.IsCurrent() {
+		return models.ScenarioSelection{Scenario: models.ScenarioCurrent}
+	}
+	if sel.Horizon == 0 {
+		sel.Horizon = DefaultHorizon
+	}
+	return sel
+}
+
+// Apply sustituye los niveles actuales de cada riesgo por los proyectados para la
+// selección indicada. Las proyecciones deben pertenecer al escenario seleccionado.
+func Apply(risks []models.RiskDetail, projections []models.ClusterRiskProjection, sel models.ScenarioSelection) []models.RiskDetail {
+	sel = Normalize(sel)
+	if sel.IsCurrent() || len(projections) == 0 {
+		return risks
+	}
+
+	// Proyecciones aplicables por riesgo, del horizonte más cercano al más lejano
+	byRisk := make(map[int64][]models.ClusterRiskProjection)
+	for _, p := range projections {
+		if p.Scenario != sel.Scenario || p.Horizon > sel.Horizon {
+			continue
+		}
+		byRisk[p.RiskID] = append(byRisk[p.RiskID], p)
+	}
+	for _, ps := range byRisk {
+		sort.Slice(ps, func(i, j int) bool { return ps[i].Horizon > ps[j].Horizon })
+	}
+
+	for i := range risks {
+		ps := byRisk[risks[i].ID]
+		if len(ps) == 0 {
+			continue
+		}
+		risks[i].Exposure = resolve(ps, risks[i].Exposure, func(p models.ClusterRiskProjection) *models.Level { return p.Exposure })
+		risks[i].Sensitivity = resolve(ps, risks[i].Sensitivity, func(p models.ClusterRiskProjection) *models.Level { return p.Sensitivity })
+		risks[i].Consequence = resolve(ps, risks[i].Consequence, func(p models.ClusterRiskProjection) *models.Level { return p.Consequence })
+		risks[i].Probability = resolve(ps, risks[i].Probability, func(p models.ClusterRiskProjection) *models.Level { return p.Probability })
+	}
+	return risks
+}
+
+// resolve retorna el primer nivel proyectado disponible o el nivel actual
+func resolve(ps []models.ClusterRiskProjection, current models.Level, field func(models.ClusterRiskProjection) *models.Level) models.Level {
+	for _, p := range ps {
+		if l := field(p); l != nil {
+			return *l
+		}
+	}
+	return current
+}
@@ -0,0 +1,279 @@
+// Package scoring implementa los modelos de puntuación de riesgos climáticos.
+// Un RiskScorer convierte los niveles cualitativos de un riesgo (exposición,
+// sensibilidad, consecuencia y probabilidad) en un score numérico en [0, 1]
+// siguiendo la metodología de una models.RiskScoringConfig versionada.
+package scoring
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+)
+
+// RiskScorer calcula scores y niveles de riesgo
+type RiskScorer interface {
+	// Score calcula el score de un riesgo a partir de sus niveles
+	Score(risk models.RiskDetail) float64
+	// Aggregate combina los scores de varios riesgos en un riesgo total
+	Aggregate(risks []models.RiskDetail) float64
+	// Level convierte un score numérico a nivel de riesgo
+	Level(score float64) models.Level
+	// Config retorna la configuración usada por el scorer
+	Config() models.RiskScoringConfig
+}
+
+// Provider proporciona el RiskScorer vigente
+type Provider interface {
+	Current(ctx context.Context) (RiskScorer, error)
+}
+
+// levels enumera los niveles en orden ascendente de severidad
+var levels = []models.Level{
+	models.LevelVeryLow,
+	models.LevelLow,
+	models.LevelMedium,
+	models.LevelHigh,
+	models.LevelVeryHigh,
+}
+
+// DefaultConfig retorna la configuración histórica del sistema:
+// Risk = (Exposure * Sensitivity) * (Consequence * Probability),
+// con el riesgo total de la tienda como media de los riesgos
+func DefaultConfig() models.RiskScoringConfig {
+	return models.RiskScoringConfig{
+		Name:   "default",
+		Method: models.ScoringMultiplicative,
+		LevelValues: map[models.Level]float64{
+			models.LevelVeryLow:  0.1,
+			models.LevelLow:      0.3,
+			models.LevelMedium:   0.5,
+			models.LevelHigh:     0.7,
+			models.LevelVeryHigh: 0.9,
+		},
+		Weights: models.RiskFactorWeights{Exposure: 1, Sensitivity: 1, Consequence: 1, Probability: 1},
+		Thresholds: models.RiskLevelThresholds{
+			VeryLow: 0.2,
+			Low:     0.4,
+			Medium:  0.6,
+			High:    0.8,
+		},
+		Aggregation: models.AggregationMean,
+	}
+}
+
+// unknownLevelValue es el valor usado para niveles vacíos o desconocidos
+const unknownLevelValue = 0.5
+
+// configScorer implementa RiskScorer a partir de una RiskScoringConfig
+type configScorer struct {
+	cfg models.RiskScoringConfig
+}
+
+// New crea un RiskScorer validando la configuración
+func New(cfg models.RiskScoringConfig) (RiskScorer, error) {
+	if err := Validate(cfg); err != nil {
+		return nil, err
+	}
+	return &configScorer{cfg: cfg}, nil
+}
+
+// Default retorna el scorer con la configuración por defecto
+func Default() RiskScorer {
+	return &configScorer{cfg: DefaultConfig()}
+}
+
+// Static retorna un Provider que siempre devuelve el mismo scorer
+func Static(scorer RiskScorer) Provider {
+	return staticProvider{scorer: scorer}
+}
+
+type staticProvider struct {
+	scorer RiskScorer
+}
+
+func (p staticProvider) Current(ctx context.Context) (RiskScorer, error) {
+	return p.scorer, nil
+}
+
+// Validate verifica que una configuración de scoring sea coherente
+func Validate(cfg models.RiskScoringConfig) error {
+	switch cfg.Method {
+	case models.ScoringMultiplicative, models.ScoringAdditiveWeighted, models.ScoringMaxBased, models.ScoringIPCC:
+	default:
+		return fmt.Errorf("método de scoring desconocido: %q", cfg.Method)
+	}
+
+	for _, l := range levels {
+		v, ok := cfg.LevelValues[l]
+		if !ok {
+			return fmt.Errorf("falta el valor del nivel %q", l)
+		}
+		if v < 0 || v > 1 {
+			return fmt.Errorf("el valor del nivel %q debe estar entre 0 y 1", l)
+		}
+	}
+	for i := 1; i < len(levels); i++ {
+		if cfg.LevelValues[levels[i]] < cfg.LevelValues[levels[i-1]] {
+			return fmt.Errorf("los valores de nivel deben ser crecientes (%q < %q)", levels[i], levels[i-1])
+		}
+	}
+
+	if err := validateWeights(cfg.Weights); err != nil {
+		return fmt.Errorf("pesos por defecto: %w", err)
+	}
+	for riskID, w := range cfg.RiskWeights {
+		if err := validateWeights(w); err != nil {
+			return fmt.Errorf("pesos del riesgo %d: %w", riskID, err)
+		}
+	}
+
+	switch cfg.Aggregation {
+	case models.AggregationMean, models.AggregationMax, models.AggregationWeighted, models.AggregationProbabilistic:
+	default:
+		return fmt.Errorf("método de agregación desconocido: %q", cfg.Aggregation)
+	}
+	for riskID, importance := range cfg.RiskImportance {
+		if importance < 0 {
+			return fmt.Errorf("la importancia del riesgo %d no puede ser negativa", riskID)
+		}
+	}
+
+	t := cfg.Thresholds
+	if !(0 < t.VeryLow && t.VeryLow < t.Low && t.Low < t.Medium && t.Medium < t.High && t.High <= 1) {
+		return fmt.Errorf("los umbrales deben ser estrictamente crecientes y estar en (0, 1]")
+	}
+	return nil
+}
+
+func validateWeights(w models.RiskFactorWeights) error {
+	if w.Exposure < 0 || w.Sensitivity < 0 || w.Consequence < 0 || w.Probability < 0 {
+		return fmt.Errorf("los pesos no pueden ser negativos")
+	}
+	if w.Exposure+w.Sensitivity+w.Consequence+w.Probability == 0 {
+		return fmt.Errorf("al menos un peso debe ser mayor que 0")
+	}
+	return nil
+}
+
+// Config retorna la configuración del scorer
+func (s *configScorer) Config() models.RiskScoringConfig {
+	return s.cfg
+}
+
+// Score calcula el score del riesgo según el método configurado
+func (s *configScorer) Score(risk models.RiskDetail) float64 {
+	e := s.value(risk.Exposure)
+	sn := s.value(risk.Sensitivity)
+	c := s.value(risk.Consequence)
+	p := s.value(risk.Probability)
+	w := s.weightsFor(risk.ID)
+
+	var score float64
+	switch s.cfg.Method {
+	case models.ScoringAdditiveWeighted:
+		total := w.Exposure + w.Sensitivity + w.Consequence + w.Probability
+		score = (w.Exposure*e + w.Sensitivity*sn + w.Consequence*c + w.Probability*p) / total
+	case models.ScoringMaxBased:
+		// Cada factor se escala por su peso relativo al peso máximo
+		maxW := math.Max(math.Max(w.Exposure, w.Sensitivity), math.Max(w.Consequence, w.Probability))
+		score = math.Max(
+			math.Max(e*w.Exposure, sn*w.Sensitivity),
+			math.Max(c*w.Consequence, p*w.Probability),
+		) / maxW
+	case models.ScoringIPCC:
+		// Riesgo = Amenaza × Exposición × Vulnerabilidad (IPCC AR5/AR6).
+		// La vulnerabilidad combina sensibilidad y consecuencia según sus pesos.
+		hazard := math.Pow(p, w.Probability)
+		exposure := math.Pow(e, w.Exposure)
+		vulnerability := 1.0
+		if vw := w.Sensitivity + w.Consequence; vw > 0 {
+			vulnerability = (w.Sensitivity*sn + w.Consequence*c) / vw
+		}
+		score = hazard * exposure * vulnerability
+	default: // multiplicative
+		score = math.Pow(e, w.Exposure) * math.Pow(sn, w.Sensitivity) *
+			math.Pow(c, w.Consequence) * math.Pow(p, w.Probability)
+	}
+
+	return math.Min(math.Max(score, 0), 1)
+}
+
+// Aggregate combina los RiskScore ya calculados según el método de agregación configurado
+func (s *configScorer) Aggregate(risks []models.RiskDetail) float64 {
+	if len(risks) == 0 {
+		return 0
+	}
+
+	var total float64
+	switch s.cfg.Aggregation {
+	case models.AggregationMax:
+		for _, r := range risks {
+			total = math.Max(total, r.RiskScore)
+		}
+	case models.AggregationWeighted:
+		// Los riesgos sin importancia definida pesan 1
+		var weightSum float64
+		for _, r := range risks {
+			w := 1.0
+			if v, ok := s.cfg.RiskImportance[r.ID]; ok {
+				w = v
+			}
+			total += w * r.RiskScore
+			weightSum += w
+		}
+		if weightSum == 0 {
+			return 0
+		}
+		total /= weightSum
+	case models.AggregationProbabilistic:
+		// Probabilidad de que se materialice al menos uno de los riesgos,
+		// asumiendo independencia entre ellos
+		none := 1.0
+		for _, r := range risks {
+			none *= 1 - math.Min(math.Max(r.RiskScore, 0), 1)
+		}
+		total = 1 - none
+	default: // mean
+		for _, r := range risks {
+			total += r.RiskScore
+		}
+		total /= float64(len(risks))
+	}
+
+	return math.Min(math.Max(total, 0), 1)
+}
+
+// Level convierte un score numérico a nivel de riesgo usando los umbrales configurados
+func (s *configScorer) Level(score float64) models.Level {
+	t := s.cfg.Thresholds
+	switch {
+	case score < t.VeryLow:
+		return models.LevelVeryLow
+	case score < t.Low:
+		return models.LevelLow
+	case score < t.Medium:
+		return models.LevelMedium
+	case score < t.High:
+		return models.LevelHigh
+	default:
+		return models.LevelVeryHigh
+	}
+}
+
+// value convierte un nivel cualitativo a su valor numérico
+func (s *configScorer) value(l models.Level) float64 {
+	if v, ok := s.cfg.LevelValues[l]; ok {
+		return v
+	}
+	return unknownLevelValue
+}
+
+// weightsFor retorna los pesos específicos del riesgo o los pesos por defecto
+func (s *configScorer) weightsFor(riskID int64) models.RiskFactorWeights {
+	if w, ok := s.cfg.RiskWeights[riskID]; ok {
+		return w
+	}
+	return s.cfg.Weights
+}
@@ -0,0 +1,164 @@
+// Package taxonomy evalúa la alineación de las tiendas con los criterios de contribución
+// sustancial a la adaptación al cambio climático de la Taxonomía de la UE.
+//
+// Para la actividad 7.7 (adquisición y propiedad de edificios) una tienda contribuye a
+// la adaptación cuando una evaluación de riesgos climáticos identifica sus riesgos
+// físicos materiales y se implantan soluciones que los reducen. Aquí:
+//
+//   - Un riesgo es material si su exposición y su probabilidad son al menos medias.
+//   - Una medida está alineada si corresponde a una actividad del anexo de adaptación,
+//     cumple todos los criterios DNSH, está implantada y reduce algún riesgo material.
+//   - La tienda está alineada cuando todos sus riesgos materiales están reducidos por
+//     medidas alineadas.
+package taxonomy
+
+import (
+	"sort"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+)
+
+// activities son las actividades del anexo II (adaptación) del Reglamento Delegado
+// (UE) 2021/2139 aplicables a las medidas sobre inmuebles comerciales
+var activities = []models.TaxonomyActivity{
+	{Code: "7.1", Name: "Construcción de edificios nuevos"},
+	{Code: "7.2", Name: "Renovación de edificios existentes"},
+	{Code: "7.3", Name: "Instalación, mantenimiento y reparación de equipos de eficiencia energética"},
+	{Code: "7.5", Name: "Instalación, mantenimiento y reparación de instrumentos y dispositivos para medir, regular y controlar la eficiencia energética de los edificios"},
+	{Code: "7.6", Name: "Instalación, mantenimiento y reparación de tecnologías de energía renovable"},
+	{Code: "7.7", Name: "Adquisición y propiedad de edificios"},
+	{Code: "9.1", Name: "Actividades de ingeniería y consultoría técnica dedicadas a la adaptación al cambio climático"},
+}
+
+// levelRank ordena los niveles cualitativos de menor a mayor severidad
+var levelRank = map[models.Level]int{
+	models.LevelVeryLow:  1,
+	models.LevelLow:      2,
+	models.LevelMedium:   3,
+	models.LevelHigh:     4,
+	models.LevelVeryHigh: 5,
+}
+
+// Activities retorna el catálogo de actividades de adaptación
+func Activities() []models.TaxonomyActivity {
+	return append([]models.TaxonomyActivity{}, activities...)
+}
+
+// FindActivity busca una actividad del catálogo por su código
+func FindActivity(code string) (models.TaxonomyActivity, bool) {
+	for _, a := range activities {
+		if a.Code == code {
+			return a, true
+		}
+	}
+	return models.TaxonomyActivity{}, false
+}
+
+// IsMaterial indica si un riesgo físico es material para la tienda: el peligro es
+// plausible (probabilidad al menos media) y la tienda está expuesta (exposición al
+// menos media). No depende del modelo de scoring, cuyos umbrales son configurables.
+func IsMaterial(risk models.RiskDetail) bool {
+	return levelRank[risk.Exposure] >= levelRank[models.LevelMedium] &&
+		levelRank[risk.Probability] >= levelRank[models.LevelMedium]
+}
+
+// Assess evalúa la alineación de una tienda a partir de sus riesgos, sus medidas, las
+// medidas que reducen cada riesgo (por nombre de riesgo) y la correspondencia de las
+// medidas con la Taxonomía (por nombre de medida). Solo se evalúan las medidas
+// implantadas. El informe no incluye los datos identificativos de la tienda.
+func Assess(
+	risks []models.RiskDetail,
+	measures []models.ShopMeasure,
+	riskMeasures map[string][]string,
+	mappings map[string]models.MeasureTaxonomy,
+) models.ShopTaxonomyReport {
+	report := models.ShopTaxonomyReport{
+		MaterialRisks: []models.TaxonomyRiskAssessment{},
+		Measures:      []models.TaxonomyMeasureAssessment{},
+	}
+
+	// Riesgos materiales reducidos por cada medida
+	material := make(map[string]bool)
+	reducedBy := make(map[string][]string)
+	for _, r := range risks {
+		if !IsMaterial(r) {
+			continue
+		}
+		material[r.Name] = true
+		for _, name := range riskMeasures[r.Name] {
+			reducedBy[name] = append(reducedBy[name], r.Name)
+		}
+	}
+
+	// Medidas implantadas y su alineación
+	addressedBy := make(map[string][]string)
+	for _, m := range measures {
+		if !m.Status.IsCompleted() {
+			continue
+		}
+		cost := m.EstimatedCost
+		if m.ActualCost != nil {
+			cost = *m.ActualCost
+		}
+		assessment := models.TaxonomyMeasureAssessment{
+			MeasureName:    m.Name,
+			AddressedRisks: reducedBy[m.Name],
+			Cost:           cost,
+		}
+		if mapping, ok := mappings[m.Name]; ok {
+			assessment.ActivityCode = mapping.ActivityCode
+			assessment.Eligible = true
+			assessment.UnmetDNSH = mapping.DNSH.Unmet()
+			assessment.DNSHCompliant = len(assessment.UnmetDNSH) == 0
+		}
+		assessment.Aligned = assessment.Eligible && assessment.DNSHCompliant && len(assessment.AddressedRisks) > 0
+
+		report.TotalCapEx += cost
+		if assessment.Aligned {
+			report.AlignedCapEx += cost
+			for _, risk := range assessment.AddressedRisks {
+				addressedBy[risk] = append(addressedBy[risk], m.Name)
+			}
+		}
+		report.Measures = append(report.Measures, assessment)
+	}
+
+	// Riesgos materiales y medidas alineadas que los reducen
+	addressed := 0
+	for _, r := range risks {
+		if !material[r.Name] {
+			continue
+		}
+		by := addressedBy[r.Name]
+		sort.Strings(by)
+		if len(by) > 0 {
+			addressed++
+		}
+		report.MaterialRisks = append(report.MaterialRisks, models.TaxonomyRiskAssessment{
+			RiskID:      r.ID,
+			RiskName:    r.Name,
+			RiskScore:   r.RiskScore,
+			Exposure:    r.Exposure,
+			Probability: r.Probability,
+			Addressed:   len(by) > 0,
+			AddressedBy: by,
+		})
+	}
+
+	total := len(report.MaterialRisks)
+	switch {
+	case total == 0:
+		report.Status = models.TaxonomyNoMaterialRisks
+	case addressed == total:
+		report.Status = models.TaxonomyAligned
+	case addressed > 0:
+		report.Status = models.TaxonomyPartiallyAligned
+	default:
+		report.Status = models.TaxonomyNotAligned
+	}
+	if total > 0 {
+		report.AlignmentPercentage = float64(addressed) / float64(total) * 100
+	}
+
+	return report
+}
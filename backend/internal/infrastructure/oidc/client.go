@@ -0,0 +1,302 @@
+// Package oidc implementa un cliente de OpenID Connect para el flujo
+// authorization code con PKCE contra el proveedor de identidad corporativo.
+package oidc
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Config contiene los parámetros del cliente registrado en el IdP
+type Config struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	GroupsClaim  string
+	HTTPClient   *http.Client
+}
+
+// Client implementa services.OIDCProvider. El documento de descubrimiento y las
+// claves públicas del IdP se obtienen en el primer uso y se guardan en memoria.
+type Client struct {
+	cfg        Config
+	httpClient *http.Client
+
+	mu        sync.Mutex
+	discovery *discoveryDocument
+	keys      map[string]*rsa.PublicKey
+}
+
+// discoveryDocument contiene los campos usados de /.well-known/openid-configuration
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// NewClient crea un cliente OIDC
+func NewClient(cfg Config) *Client {
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	return &Client{cfg: cfg, httpClient: httpClient}
+}
+
+// AuthCodeURL construye la URL del IdP a la que se redirige al usuario para iniciar sesión
+func (c *Client) AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	doc, err := c.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", c.cfg.ClientID)
+	params.Set("redirect_uri", c.cfg.RedirectURL)
+	params.Set("scope", strings.Join(c.cfg.Scopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", codeChallenge)
+	params.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(doc.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return doc.AuthorizationEndpoint + separator + params.Encode(), nil
+}
+
+// Exchange canjea el código de autorización por los tokens del IdP y retorna la
+// identidad del ID token una vez verificada su firma, emisor, audiencia y expiración
+func (c *Client) Exchange(ctx context.Context, code, codeVerifier string) (*models.OIDCIdentity, error) {
+	doc, err := c.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", c.cfg.RedirectURL)
+	form.Set("client_id", c.cfg.ClientID)
+	form.Set("code_verifier", codeVerifier)
+	if c.cfg.ClientSecret != "" {
+		form.Set("client_secret", c.cfg.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, doc.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := c.doJSON(req, &tokens); err != nil {
+		return nil, fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+	if tokens.IDToken == "" {
+		return nil, fmt.Errorf("token response has no id_token")
+	}
+
+	return c.verifyIDToken(ctx, doc, tokens.IDToken)
+}
+
+// verifyIDToken valida el ID token y extrae la identidad del usuario
+func (c *Client) verifyIDToken(ctx context.Context, doc *discoveryDocument, idToken string) (*models.OIDCIdentity, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(idToken, claims,
+		func(token *jwt.Token) (interface{}, error) {
+			kid, _ := token.Header["kid"].(string)
+			return c.publicKey(ctx, doc, kid)
+		},
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithIssuer(doc.Issuer),
+		jwt.WithAudience(c.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id_token: %w", err)
+	}
+
+	identity := &models.OIDCIdentity{}
+	identity.Issuer, _ = claims["iss"].(string)
+	identity.Subject, _ = claims["sub"].(string)
+	identity.Email, _ = claims["email"].(string)
+	identity.Nonce, _ = claims["nonce"].(string)
+	if verified, ok := claims["email_verified"].(bool); ok {
+		identity.EmailVerified = &verified
+	}
+	identity.Groups = stringList(claims[c.cfg.GroupsClaim])
+
+	if identity.Subject == "" {
+		return nil, fmt.Errorf("id_token has no subject")
+	}
+	return identity, nil
+}
+
+// publicKey obtiene la clave pública del IdP con el kid indicado. Si no se conoce
+// se recargan las claves una vez, ya que el IdP puede haberlas rotado.
+func (c *Client) publicKey(ctx context.Context, doc *discoveryDocument, kid string) (*rsa.PublicKey, error) {
+	c.mu.Lock()
+	key, ok := c.lookupKey(kid)
+	c.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	keys, err := c.fetchKeys(ctx, doc.JWKSURI)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.keys = keys
+	if key, ok := c.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookupKey busca una clave por kid; sin kid solo se acepta si el IdP publica una única clave
+func (c *Client) lookupKey(kid string) (*rsa.PublicKey, bool) {
+	if kid == "" && len(c.keys) == 1 {
+		for _, key := range c.keys {
+			return key, true
+		}
+	}
+	key, ok := c.keys[kid]
+	return key, ok
+}
+
+// fetchKeys descarga el JWKS del IdP y retorna sus claves RSA de firma
+func (c *Client) fetchKeys(ctx context.Context, jwksURI string) (map[string]*rsa.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, jwksURI, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build jwks request: %w", err)
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := c.doJSON(req, &jwks); err != nil {
+		return nil, fmt.Errorf("failed to fetch jwks: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus for key %q: %w", k.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent for key %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	return keys, nil
+}
+
+// discover obtiene y guarda el documento de descubrimiento del IdP
+func (c *Client) discover(ctx context.Context) (*discoveryDocument, error) {
+	c.mu.Lock()
+	doc := c.discovery
+	c.mu.Unlock()
+	if doc != nil {
+		return doc, nil
+	}
+
+	endpoint := strings.TrimSuffix(c.cfg.IssuerURL, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build discovery request: %w", err)
+	}
+
+	doc = &discoveryDocument{}
+	if err := c.doJSON(req, doc); err != nil {
+		return nil, fmt.Errorf("failed to discover oidc provider: %w", err)
+	}
+	if strings.TrimSuffix(doc.Issuer, "/") != strings.TrimSuffix(c.cfg.IssuerURL, "/") {
+		return nil, fmt.Errorf("discovery issuer %q does not match %q", doc.Issuer, c.cfg.IssuerURL)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return nil, fmt.Errorf("discovery document is missing required endpoints")
+	}
+
+	c.mu.Lock()
+	c.discovery = doc
+	c.mu.Unlock()
+	return doc, nil
+}
+
+// doJSON ejecuta la petición y decodifica la respuesta JSON
+func (c *Client) doJSON(req *http.Request, out interface{}) error {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// stringList convierte el claim de grupos en una lista; algunos IdPs envían un
+// único grupo como cadena en lugar de como array
+func stringList(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+// Package pdf genera documentos PDF sencillos sin dependencias externas: páginas A4
+// con texto en Helvetica, líneas, rectángulos y círculos, y un modo de composición
+// en flujo (títulos, párrafos y tablas) que salta de página automáticamente.
+//
+// El texto se codifica en WinAnsiEncoding, suficiente para el castellano y el resto
+// de idiomas de Europa occidental; los caracteres no representables se sustituyen
+// por '?'.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// Dimensiones de una página A4 en puntos
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+)
+
+// Margin es el margen de las páginas en puntos
+const Margin = 50.0
+
+// Font identifica una de las fuentes estándar disponibles
+type Font int
+
+const (
+	Regular Font = iota
+	Bold
+)
+
+// Color representa un color RGB con componentes en [0, 1]
+type Color struct {
+	R, G, B float64
+}
+
+// Colores de uso frecuente
+var (
+	Black     = Color{0, 0, 0}
+	Gray      = Color{0.45, 0.45, 0.45}
+	LightGray = Color{0.92, 0.92, 0.92}
+	White     = Color{1, 1, 1}
+)
+
+// Document es un documento PDF en construcción. Las coordenadas de los métodos de
+// dibujo se expresan en puntos desde la esquina superior izquierda de la página.
+type Document struct {
+	title   string
+	created time.Time
+	pages   []*bytes.Buffer
+	y       float64 // Posición vertical del cursor de composición en flujo
+}
+
+// New crea un documento vacío con el título indicado en sus metadatos
+func New(title string) *Document {
+	return &Document{title: title, created: time.Now().UTC()}
+}
+
+// AddPage añade una página y sitúa el cursor de composición en su margen superior
+func (d *Document) AddPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+	d.y = Margin
+}
+
+// Pages retorna el número de páginas del documento
+func (d *Document) Pages() int {
+	return len(d.pages)
+}
+
+// content retorna el contenido de la página actual, creándola si no existe
+func (d *Document) content() *bytes.Buffer {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+	return d.pages[len(d.pages)-1]
+}
+
+// Text escribe una línea de texto con su línea base en (x, y)
+func (d *Document) Text(x, y float64, font Font, size float64, color Color, s string) {
+	fmt.Fprintf(d.content(), "BT %s /F%d %s Tf %s %s Td (%s) Tj ET\n",
+		fillColor(color), int(font)+1, num(size), num(x), num(PageHeight-y), escape(s))
+}
+
+// Line dibuja un segmento entre (x1, y1) y (x2, y2)
+func (d *Document) Line(x1, y1, x2, y2, width float64, color Color) {
+	fmt.Fprintf(d.content(), "%s %s w %s %s m %s %s l S\n",
+		strokeColor(color), num(width), num(x1), num(PageHeight-y1), num(x2), num(PageHeight-y2))
+}
+
+// Rect dibuja un rectángulo relleno con la esquina superior izquierda en (x, y)
+func (d *Document) Rect(x, y, w, h float64, fill Color) {
+	fmt.Fprintf(d.content(), "%s %s %s %s %s re f\n",
+		fillColor(fill), num(x), num(PageHeight-y-h), num(w), num(h))
+}
+
+// StrokeRect dibuja el borde de un rectángulo con la esquina superior izquierda en (x, y)
+func (d *Document) StrokeRect(x, y, w, h, width float64, color Color) {
+	fmt.Fprintf(d.content(), "%s %s w %s %s %s %s re S\n",
+		strokeColor(color), num(width), num(x), num(PageHeight-y-h), num(w), num(h))
+}
+
+// Circle dibuja un círculo relleno de centro (cx, cy)
+func (d *Document) Circle(cx, cy, r float64, fill Color) {
+	// Aproximación con cuatro curvas de Bézier
+	const k = 0.5523
+	y := PageHeight - cy
+	c := d.content()
+	fmt.Fprintf(c, "%s %s %s m\n", fillColor(fill), num(cx+r), num(y))
+	fmt.Fprintf(c, "%s %s %s %s %s %s c\n", num(cx+r), num(y+k*r), num(cx+k*r), num(y+r), num(cx), num(y+r))
+	fmt.Fprintf(c, "%s %s %s %s %s %s c\n", num(cx-k*r), num(y+r), num(cx-r), num(y+k*r), num(cx-r), num(y))
+	fmt.Fprintf(c, "%s %s %s %s %s %s c\n", num(cx-r), num(y-k*r), num(cx-k*r), num(y-r), num(cx), num(y-r))
+	fmt.Fprintf(c, "%s %s %s %s %s %s c f\n", num(cx+k*r), num(y-r), num(cx+r), num(y-k*r), num(cx+r), num(y))
+}
+
+// WriteTo escribe el documento completo
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+
+	var buf bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	// Objetos fijos: catálogo, árbol de páginas, fuentes y metadatos. Cada página
+	// ocupa después dos objetos: la página y su contenido.
+	const firstPage = 6
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	object(fmt.Sprintf("<< /Title (%s) /Producer (Climate Invest Optimizer) /CreationDate (D:%s) >>",
+		escape(d.title), d.created.Format("20060102150405Z")))
+	for _, page := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			num(PageWidth), num(PageHeight), len(offsets)+2))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.Len(), page.String()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	n, err := w.Write(buf.Bytes())
+	return int64(n), err
+}
+
+// num formatea un número para el contenido de una página
+func num(v float64) string {
+	s := fmt.Sprintf("%.2f", v)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+func fillColor(c Color) string {
+	return fmt.Sprintf("%s %s %s rg", num(c.R), num(c.G), num(c.B))
+}
+
+func strokeColor(c Color) string {
+	return fmt.Sprintf("%s %s %s RG", num(c.R), num(c.G), num(c.B))
+}
+
+// winAnsiExtra son los caracteres de WinAnsiEncoding fuera de Latin-1
+var winAnsiExtra = map[rune]byte{
+	'€': 0x80, '‚': 0x82, '„': 0x84, '…': 0x85, '‘': 0x91, '’': 0x92,
+	'“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '™': 0x99,
+}
+
+// escape codifica un texto en WinAnsiEncoding y escapa los caracteres especiales
+// de las cadenas PDF
+func escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n' || r == '\t':
+			b.WriteByte(' ')
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			b.WriteString(fmt.Sprintf("\\%03o", r))
+		default:
+			if c, ok := winAnsiExtra[r]; ok {
+				b.WriteString(fmt.Sprintf("\\%03o", c))
+			} else {
+				b.WriteByte('?')
+			}
+		}
+	}
+	return b.String()
+}
+
+// clamp limita un valor al intervalo [lo, hi]
+func clamp(v, lo, hi float64) float64 {
+	return math.Min(math.Max(v, lo), hi)
+}
@@ -0,0 +1,169 @@
+package pdf
+
+// Tamaños de letra de la composición en flujo
+const (
+	titleSize   = 18
+	headingSize = 13
+	bodySize    = 10
+	tableSize   = 8.5
+	lineSpacing = 1.35
+)
+
+// ContentWidth es el ancho disponible entre márgenes
+const ContentWidth = PageWidth - 2*Margin
+
+// Align indica la alineación horizontal del texto de una columna
+type Align int
+
+const (
+	AlignLeft Align = iota
+	AlignRight
+)
+
+// Column describe una columna de una tabla. Width es la fracción del ancho disponible.
+type Column struct {
+	Header string
+	Width  float64
+	Align  Align
+}
+
+// Y retorna la posición vertical del cursor de composición
+func (d *Document) Y() float64 {
+	d.content()
+	return d.y
+}
+
+// Space avanza el cursor de composición
+func (d *Document) Space(h float64) {
+	d.content()
+	d.y += h
+}
+
+// ensure salta de página si no quedan h puntos hasta el margen inferior
+func (d *Document) ensure(h float64) {
+	d.content()
+	if d.y+h > PageHeight-Margin {
+		d.AddPage()
+	}
+}
+
+// Reserve garantiza que queden h puntos en la página y retorna la posición vertical
+// en que empiezan; el cursor avanza hasta su final
+func (d *Document) Reserve(h float64) float64 {
+	d.ensure(h)
+	y := d.y
+	d.y += h
+	return y
+}
+
+// Title escribe el título del documento
+func (d *Document) Title(s string) {
+	d.block(s, Bold, titleSize, Black)
+	d.Space(4)
+}
+
+// Heading escribe el título de una sección. Si no cabe junto a unas líneas de su
+// contenido, empieza en una página nueva.
+func (d *Document) Heading(s string) {
+	d.Space(10)
+	d.ensure(headingSize*lineSpacing + 4*bodySize*lineSpacing)
+	d.block(s, Bold, headingSize, Black)
+	y := d.y - 2
+	d.Line(Margin, y, PageWidth-Margin, y, 0.5, Gray)
+	d.Space(6)
+}
+
+// Paragraph escribe un párrafo ajustado al ancho disponible
+func (d *Document) Paragraph(s string) {
+	d.block(s, Regular, bodySize, Black)
+	d.Space(4)
+}
+
+// Note escribe un texto secundario en gris
+func (d *Document) Note(s string) {
+	d.block(s, Regular, tableSize, Gray)
+	d.Space(2)
+}
+
+// KeyValues escribe pares de etiqueta y valor en dos columnas. Los valores largos
+// continúan en las líneas siguientes.
+func (d *Document) KeyValues(pairs [][2]string) {
+	const labelWidth = 0.38 * ContentWidth
+	height := bodySize * lineSpacing
+	for _, p := range pairs {
+		lines := wrap(p[1], Regular, bodySize, ContentWidth-labelWidth)
+		d.ensure(height * float64(len(lines)))
+		d.Text(Margin, d.y+bodySize, Bold, bodySize, Black, Truncate(p[0], Bold, bodySize, labelWidth-6))
+		for _, line := range lines {
+			d.Text(Margin+labelWidth, d.y+bodySize, Regular, bodySize, Black, line)
+			d.y += height
+		}
+	}
+	d.Space(4)
+}
+
+// Table escribe una tabla con cabecera. La cabecera se repite en cada página y el
+// texto que no cabe en su celda se recorta.
+func (d *Document) Table(columns []Column, rows [][]string) {
+	rowHeight := tableSize * 1.9
+	header := func() {
+		d.ensure(2 * rowHeight)
+		d.Rect(Margin, d.y, ContentWidth, rowHeight, LightGray)
+		d.row(columns, nil, Bold, rowHeight)
+	}
+
+	header()
+	for i, cells := range rows {
+		if d.y+rowHeight > PageHeight-Margin {
+			d.AddPage()
+			header()
+		}
+		if i%2 == 1 {
+			d.Rect(Margin, d.y, ContentWidth, rowHeight, Color{0.97, 0.97, 0.97})
+		}
+		d.row(columns, cells, Regular, rowHeight)
+	}
+	d.Line(Margin, d.y, PageWidth-Margin, d.y, 0.5, Gray)
+	d.Space(8)
+}
+
+// row escribe una fila de una tabla; sin celdas escribe la cabecera
+func (d *Document) row(columns []Column, cells []string, font Font, height float64) {
+	const padding = 3
+	x := Margin
+	baseline := d.y + (height+tableSize)/2 - 1
+	for i, col := range columns {
+		width := col.Width * ContentWidth
+		text := col.Header
+		if cells != nil {
+			text = ""
+			if i < len(cells) {
+				text = cells[i]
+			}
+		}
+		text = Truncate(text, font, tableSize, width-2*padding)
+		tx := x + padding
+		if col.Align == AlignRight {
+			tx = x + width - padding - TextWidth(text, font, tableSize)
+		}
+		d.Text(tx, baseline, font, tableSize, Black, text)
+		x += width
+	}
+	d.y += height
+}
+
+// Bar dibuja una barra horizontal de progreso con la fracción indicada rellena
+func (d *Document) Bar(x, y, w, h, fraction float64, fill Color) {
+	d.Rect(x, y, w, h, LightGray)
+	d.Rect(x, y, w*clamp(fraction, 0, 1), h, fill)
+}
+
+// block escribe un texto ajustado al ancho disponible, saltando de página si es necesario
+func (d *Document) block(s string, font Font, size float64, color Color) {
+	height := size * lineSpacing
+	for _, line := range wrap(s, font, size, ContentWidth) {
+		d.ensure(height)
+		d.Text(Margin, d.y+size, font, size, color, line)
+		d.y += height
+	}
+}
@@ -0,0 +1,112 @@
+package pdf
+
+import "strings"
+
+// Anchos de los caracteres ASCII imprimibles (0x20-0x7e) de Helvetica y
+// Helvetica-Bold, en milésimas del tamaño de la fuente (métricas AFM de Adobe)
+var (
+	helveticaWidths = [95]int{
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	}
+	helveticaBoldWidths = [95]int{
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	}
+)
+
+// accentBase asocia las letras acentuadas a la letra base de la que toman el ancho
+var accentBase = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ä", "a", "ã", "a",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i",
+	"ó", "o", "ò", "o", "ô", "o", "ö", "o", "õ", "o",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u",
+	"ñ", "n", "ç", "c",
+	"Á", "A", "À", "A", "Â", "A", "Ä", "A",
+	"É", "E", "È", "E", "Ê", "E", "Ë", "E",
+	"Í", "I", "Ì", "I", "Î", "I", "Ï", "I",
+	"Ó", "O", "Ò", "O", "Ô", "O", "Ö", "O",
+	"Ú", "U", "Ù", "U", "Û", "U", "Ü", "U",
+	"Ñ", "N", "Ç", "C",
+)
+
+// TextWidth calcula el ancho en puntos de un texto con la fuente y tamaño indicados
+func TextWidth(s string, font Font, size float64) float64 {
+	widths := &helveticaWidths
+	if font == Bold {
+		widths = &helveticaBoldWidths
+	}
+
+	total := 0
+	for _, r := range accentBase.Replace(s) {
+		if r >= 0x20 && r < 0x7f {
+			total += widths[r-0x20]
+		} else {
+			total += 556
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// wrap divide un texto en líneas que no superan el ancho indicado. Las palabras más
+// largas que el ancho se parten.
+func wrap(s string, font Font, size, width float64) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(s, "\n") {
+		var words []string
+		for _, w := range strings.Fields(paragraph) {
+			words = append(words, splitWord(w, font, size, width)...)
+		}
+		if len(words) == 0 {
+			lines = append(lines, "")
+			continue
+		}
+		line := words[0]
+		for _, w := range words[1:] {
+			if TextWidth(line+" "+w, font, size) <= width {
+				line += " " + w
+				continue
+			}
+			lines = append(lines, line)
+			line = w
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// splitWord parte una palabra en trozos que no superan el ancho indicado
+func splitWord(word string, font Font, size, width float64) []string {
+	var parts []string
+	runes := []rune(word)
+	for len(runes) > 0 {
+		n := len(runes)
+		for n > 1 && TextWidth(string(runes[:n]), font, size) > width {
+			n--
+		}
+		parts = append(parts, string(runes[:n]))
+		runes = runes[n:]
+	}
+	return parts
+}
+
+// Truncate recorta un texto con puntos suspensivos para que no supere el ancho indicado
+func Truncate(s string, font Font, size, width float64) string {
+	if TextWidth(s, font, size) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && TextWidth(string(runes)+"…", font, size) > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "…"
+}
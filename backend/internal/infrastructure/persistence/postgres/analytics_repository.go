@@ -0,0 +1,245 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+)
+
+// AnalyticsRepository implementa repository.AnalyticsRepository para PostgreSQL
+type AnalyticsRepository struct {
+	db *sql.DB
+}
+
+// NewAnalyticsRepository crea una nueva instancia del repositorio
+func NewAnalyticsRepository(db *sql.DB) *AnalyticsRepository {
+	return &AnalyticsRepository{db: db}
+}
+
+// portfolioDimension describe cómo se forman los grupos de una agrupación de la
+// analítica de la cartera
+type portfolioDimension struct {
+	members  string // Consulta con la clave, el nombre, la tienda y el score de cada miembro de un grupo
+	pairs    string // Condición adicional sobre los pares tienda-riesgo p del grupo g
+	covering string // Condición adicional sobre las medidas ms que cubren un par del grupo g
+	measures string // Condición adicional sobre las medidas ms del grupo g
+}
+
+// portfolioDimensions asocia cada agrupación con la forma de sus grupos
+var portfolioDimensions = map[models.PortfolioGroupBy]portfolioDimension{
+	models.PortfolioByCountry: {
+		members: `SELECT sh.country::text, sh.country::text, sh.id, sh.total_risk FROM shops sh`,
+	},
+	models.PortfolioByCluster: {
+		members: `SELECT sh.cluster_id::text, cl.name, sh.id, sh.total_risk FROM shops sh JOIN "Cluster" cl ON cl.id = sh.cluster_id`,
+	},
+	// El score de cada riesgo solo se guarda en los snapshots: se usa el del último de la tienda
+	models.PortfolioByRisk: {
+		members: `
+			SELECT p.risk_id::text, p.risk_name, p.shop_id, rs.score
+			FROM pairs p
+			LEFT JOIN LATERAL (
+				SELECT (e->>'risk_score')::float8 AS score
+				FROM (
+					SELECT risks FROM "Shop_risk_snapshot"
+					WHERE shop_id = p.shop_id
+					ORDER BY created_at DESC, id DESC
+					LIMIT 1
+				) l, jsonb_array_elements(l.risks) e
+				WHERE (e->>'risk_id')::bigint = p.risk_id
+			) rs ON true`,
+		pairs:    ` AND p.risk_id::text = g.group_key`,
+		measures: ` AND ms.name IN (SELECT measure_name FROM "Risk_measures" WHERE risk_name = g.group_name)`,
+	},
+	models.PortfolioByMeasureType: {
+		members:  `SELECT DISTINCT ms.type, ms.type, ms.shop_id, sh.total_risk FROM measures ms JOIN shops sh ON sh.id = ms.shop_id`,
+		covering: ` AND ms.type = g.group_key`,
+		measures: ` AND ms.type = g.group_key`,
+	},
+}
+
+// portfolioCTE son las tablas comunes de la analítica de la cartera: las tiendas
+// filtradas, sus pares tienda-riesgo, sus medidas con el coste estimado para cada
+// tienda y los miembros de cada grupo
+const portfolioCTE = `
+	WITH shops AS (
+		SELECT s.id, s.country, s.cluster_id, COALESCE(s."totalRisk", 0) AS total_risk
+		FROM "Shop" s%s
+	),
+	pairs AS (
+		SELECT sh.id AS shop_id, r.id AS risk_id, r.name AS risk_name
+		FROM shops sh
+		JOIN "Cluster_risk" cr ON cr.cluster_id = sh.cluster_id
+		JOIN "Risk" r ON r.id = cr.risk_id
+	),
+	measures AS (
+		SELECT sm.shop_id, m.name, m.type::text AS type, sm.status IN ('completed', 'verified') AS completed,
+		       sm.actual_cost, ` + measureShopCostSQL + ` AS estimated_cost
+		FROM "Shop_measure" sm
+		JOIN "Measure" m ON m.name = sm.measure_name AND m.deleted_at IS NULL
+		JOIN "Shop" s ON s.id = sm.shop_id
+		LEFT JOIN "Country" c ON c.name = s.country
+		WHERE sm.shop_id IN (SELECT id FROM shops)
+	),
+	members (group_key, group_name, shop_id, score) AS (%s),
+	group_shops AS (
+		SELECT DISTINCT group_key, group_name, shop_id FROM members
+	)`
+
+// GetPortfolioGroups agrega por la dimensión indicada el riesgo, la cobertura y la
+// inversión de las tiendas activas que cumplen los filtros, dentro del ámbito del usuario
+func (r *AnalyticsRepository) GetPortfolioGroups(ctx context.Context, query *models.PortfolioAnalyticsQuery) ([]models.PortfolioGroup, error) {
+	dim, ok := portfolioDimensions[query.GroupBy]
+	if !ok {
+		return nil, fmt.Errorf("unsupported portfolio group: %s", query.GroupBy)
+	}
+
+	conditions, args, argIndex := shopFilterConditions(&query.ShopFilter, "s.", 1)
+	conditions = append(conditions, "s.deleted_at IS NULL")
+	scopeConditions, scopeArgs, argIndex := shopScopeConditions(ctx, "s.", argIndex)
+	conditions = append(conditions, scopeConditions...)
+	args = append(args, scopeArgs...)
+	cte := fmt.Sprintf(portfolioCTE, where(conditions), dim.members)
+
+	rows, err := r.db.QueryContext(ctx, cte+fmt.Sprintf(`
+		SELECT mb.group_key, mb.group_name, mb.shops, mb.average_risk, mb.max_risk,
+		       COALESCE(gp.pairs, 0), COALESCE(gp.covered, 0),
+		       COALESCE(gm.measures, 0), COALESCE(gm.estimated, 0), COALESCE(gm.actual, 0)
+		FROM (
+			SELECT group_key, MIN(group_name) AS group_name, COUNT(DISTINCT shop_id) AS shops,
+			       COALESCE(AVG(score), 0) AS average_risk, COALESCE(MAX(score), 0) AS max_risk
+			FROM members
+			GROUP BY group_key
+		) mb
+		LEFT JOIN (
+			SELECT group_key, COUNT(*) AS pairs, COUNT(*) FILTER (WHERE covered) AS covered
+			FROM (
+				SELECT g.group_key, EXISTS (
+					SELECT 1
+					FROM measures ms
+					JOIN "Risk_measures" rm ON rm.measure_name = ms.name
+					WHERE ms.shop_id = p.shop_id AND rm.risk_name = p.risk_name AND ms.completed%[1]s
+				) AS covered
+				FROM group_shops g
+				JOIN pairs p ON p.shop_id = g.shop_id%[2]s
+			) x
+			GROUP BY group_key
+		) gp ON gp.group_key = mb.group_key
+		LEFT JOIN (
+			SELECT g.group_key, COUNT(*) AS measures, SUM(ms.estimated_cost) AS estimated, SUM(ms.actual_cost) AS actual
+			FROM group_shops g
+			JOIN measures ms ON ms.shop_id = g.shop_id%[3]s
+			GROUP BY g.group_key
+		) gm ON gm.group_key = mb.group_key
+		ORDER BY mb.group_name
+	`, dim.covering, dim.pairs, dim.measures), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get portfolio analytics: %w", err)
+	}
+	defer rows.Close()
+
+	var groups []models.PortfolioGroup
+	index := make(map[string]int)
+	for rows.Next() {
+		g := models.PortfolioGroup{RiskHistogram: riskHistogram(query.Bins)}
+		if err := rows.Scan(&g.Key, &g.Name, &g.Shops, &g.AverageRisk, &g.MaxRisk,
+			&g.RiskPairs, &g.CoveredPairs, &g.AppliedMeasures, &g.EstimatedInvestment, &g.ActualInvestment); err != nil {
+			return nil, fmt.Errorf("failed to scan portfolio group: %w", err)
+		}
+		index[g.Key] = len(groups)
+		groups = append(groups, g)
+	}
+
+	// Histograma del score de los miembros de cada grupo. El último intervalo incluye el 1.
+	histogram, err := r.db.QueryContext(ctx, cte+fmt.Sprintf(`
+		SELECT group_key, LEAST(GREATEST(width_bucket(score, 0, 1, $%[1]d), 1), $%[1]d), COUNT(*)
+		FROM members
+		WHERE score IS NOT NULL
+		GROUP BY 1, 2
+	`, argIndex), append(args, query.Bins)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get risk histogram: %w", err)
+	}
+	defer histogram.Close()
+
+	for histogram.Next() {
+		var key string
+		var bucket int
+		var count int64
+		if err := histogram.Scan(&key, &bucket, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan risk histogram: %w", err)
+		}
+		if i, ok := index[key]; ok && bucket >= 1 && bucket <= query.Bins {
+			groups[i].RiskHistogram[bucket-1].Count = count
+		}
+	}
+	return groups, nil
+}
+
+// riskHistogram crea un histograma vacío de bins intervalos iguales en [0, 1]
+func riskHistogram(bins int) []models.HistogramBin {
+	histogram := make([]models.HistogramBin, bins)
+	for i := range histogram {
+		histogram[i] = models.HistogramBin{From: float64(i) / float64(bins), To: float64(i+1) / float64(bins)}
+	}
+	return histogram
+}
+
+// GetRiskMatrixPairs obtiene los pares tienda-riesgo de las tiendas activas que cumplen
+// los filtros, dentro del ámbito del usuario, con los niveles de probabilidad y
+// consecuencia de su cluster y los tipos de las medidas completadas que los reducen
+func (r *AnalyticsRepository) GetRiskMatrixPairs(ctx context.Context, query *models.RiskMatrixQuery) ([]models.RiskMatrixPair, error) {
+	conditions, args, argIndex := shopFilterConditions(&query.ShopFilter, "s.", 1)
+	conditions = append(conditions, "s.deleted_at IS NULL")
+	if query.RiskID != nil {
+		conditions = append(conditions, fmt.Sprintf("r.id = $%d", argIndex))
+		args = append(args, *query.RiskID)
+		argIndex++
+	}
+	scopeConditions, scopeArgs, _ := shopScopeConditions(ctx, "s.", argIndex)
+	conditions = append(conditions, scopeConditions...)
+	args = append(args, scopeArgs...)
+
+	// Una fila por par y tipo de medida: los pares sin medidas completadas tienen tipo nulo
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT s.id, s.location, s.country, s.cluster_id, r.id, r.name, cr.probability, cr.consequence, mt.type
+		FROM "Shop" s
+		JOIN "Cluster_risk" cr ON cr.cluster_id = s.cluster_id
+		JOIN "Risk" r ON r.id = cr.risk_id
+		LEFT JOIN LATERAL (
+			SELECT DISTINCT m.type::text AS type
+			FROM "Shop_measure" sm
+			JOIN "Measure" m ON m.name = sm.measure_name AND m.deleted_at IS NULL
+			JOIN "Risk_measures" rm ON rm.measure_name = m.name
+			WHERE sm.shop_id = s.id AND rm.risk_name = r.name AND sm.status IN ('completed', 'verified')
+		) mt ON true`+where(conditions)+`
+		ORDER BY s.location, s.id, r.name, r.id, mt.type
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get risk matrix pairs: %w", err)
+	}
+	defer rows.Close()
+
+	var pairs []models.RiskMatrixPair
+	for rows.Next() {
+		var p models.RiskMatrixPair
+		var measureType sql.NullString
+		if err := rows.Scan(&p.ShopID, &p.ShopLocation, &p.Country, &p.ClusterID, &p.RiskID, &p.RiskName,
+			&p.Probability, &p.Consequence, &measureType); err != nil {
+			return nil, fmt.Errorf("failed to scan risk matrix pair: %w", err)
+		}
+
+		last := len(pairs) - 1
+		if last < 0 || pairs[last].ShopID != p.ShopID || pairs[last].RiskID != p.RiskID {
+			p.MeasureTypes = []models.MeasureType{}
+			pairs = append(pairs, p)
+			last++
+		}
+		if measureType.Valid {
+			pairs[last].MeasureTypes = append(pairs[last].MeasureTypes, models.MeasureType(measureType.String))
+		}
+	}
+	return pairs, nil
+}
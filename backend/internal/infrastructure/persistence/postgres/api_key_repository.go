@@ -0,0 +1,161 @@
+// Package postgres implementa los repositorios usando PostgreSQL/Supabase.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/repository"
+)
+
+// APIKeyRepository implementa repository.APIKeyRepository
+type APIKeyRepository struct {
+	db *sql.DB
+}
+
+// NewAPIKeyRepository crea una nueva instancia
+func NewAPIKeyRepository(db *sql.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+const apiKeyColumns = `id, name, prefix, key_hash, role, permissions, rate_limit, COALESCE(created_by, 0),
+	created_at, expires_at, last_used_at, revoked_at, countries, cluster_ids`
+
+// Create inserta una nueva API key
+func (r *APIKeyRepository) Create(ctx context.Context, key *models.APIKey) error {
+	permissions := key.Permissions
+	if permissions == nil {
+		permissions = []string{}
+	}
+	encoded, err := json.Marshal(permissions)
+	if err != nil {
+		return fmt.Errorf("failed to encode api key permissions: %w", err)
+	}
+	countries, clusterIDs, err := encodeScope(key.Countries, key.ClusterIDs)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO "Api_key" (name, prefix, key_hash, role, permissions, rate_limit, created_by, expires_at,
+			countries, cluster_ids)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, 0), $8, $9, $10)
+		RETURNING id, created_at
+	`
+	err = r.db.QueryRowContext(ctx, query,
+		key.Name,
+		key.Prefix,
+		key.KeyHash,
+		string(key.Role),
+		encoded,
+		key.RateLimit,
+		key.CreatedBy,
+		key.ExpiresAt,
+		countries,
+		clusterIDs,
+	).Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create api key: %w", err)
+	}
+	return nil
+}
+
+// GetByID obtiene una API key por su ID
+func (r *APIKeyRepository) GetByID(ctx context.Context, id int64) (*models.APIKey, error) {
+	key, err := scanAPIKey(r.db.QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM "Api_key" WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+	return key, nil
+}
+
+// GetByHash obtiene una API key por el hash de su valor
+func (r *APIKeyRepository) GetByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	key, err := scanAPIKey(r.db.QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM "Api_key" WHERE key_hash = $1`, hash))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key by hash: %w", err)
+	}
+	return key, nil
+}
+
+// List obtiene todas las API keys, de la más reciente a la más antigua
+func (r *APIKeyRepository) List(ctx context.Context) ([]models.APIKey, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+apiKeyColumns+` FROM "Api_key" ORDER BY created_at DESC, id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []models.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api key: %w", err)
+		}
+		keys = append(keys, *key)
+	}
+	return keys, nil
+}
+
+// Revoke revoca una API key. Revocar una clave ya revocada no tiene efecto.
+func (r *APIKeyRepository) Revoke(ctx context.Context, id int64) error {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE "Api_key" SET revoked_at = COALESCE(revoked_at, now()) WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return repository.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// TouchLastUsed registra el último uso de una API key
+func (r *APIKeyRepository) TouchLastUsed(ctx context.Context, id int64, usedAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE "Api_key" SET last_used_at = $1 WHERE id = $2`, usedAt, id)
+	if err != nil {
+		return fmt.Errorf("failed to update api key last use: %w", err)
+	}
+	return nil
+}
+
+func scanAPIKey(row rowScanner) (*models.APIKey, error) {
+	key := &models.APIKey{}
+	var permissions, countries, clusterIDs []byte
+	var expiresAt, lastUsedAt, revokedAt sql.NullTime
+	if err := row.Scan(&key.ID, &key.Name, &key.Prefix, &key.KeyHash, &key.Role, &permissions, &key.RateLimit,
+		&key.CreatedBy, &key.CreatedAt, &expiresAt, &lastUsedAt, &revokedAt, &countries, &clusterIDs); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(permissions, &key.Permissions); err != nil {
+		return nil, fmt.Errorf("failed to decode api key permissions: %w", err)
+	}
+	if err := json.Unmarshal(countries, &key.Countries); err != nil {
+		return nil, fmt.Errorf("failed to decode api key countries: %w", err)
+	}
+	if err := json.Unmarshal(clusterIDs, &key.ClusterIDs); err != nil {
+		return nil, fmt.Errorf("failed to decode api key clusters: %w", err)
+	}
+	if expiresAt.Valid {
+		key.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+	return key, nil
+}
@@ -0,0 +1,132 @@
+// Package postgres implementa los repositorios usando PostgreSQL/Supabase.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+)
+
+// AuditRepository implementa repository.AuditRepository
+type AuditRepository struct {
+	db *sql.DB
+}
+
+// NewAuditRepository crea una nueva instancia
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+const auditColumns = `id, actor_user_id, actor_api_key_id, COALESCE(actor_email, ''), COALESCE(actor_role, ''),
+	action, entity_type, entity_id, before, after, COALESCE(request_id, ''), created_at`
+
+// Create inserta una entrada en el log de auditoría
+func (r *AuditRepository) Create(ctx context.Context, entry *models.AuditEntry) error {
+	query := `
+		INSERT INTO "Audit_log" (actor_user_id, actor_api_key_id, actor_email, actor_role, action,
+			entity_type, entity_id, before, after, request_id)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, $6, $7, $8, $9, NULLIF($10, ''))
+		RETURNING id, created_at
+	`
+	err := r.db.QueryRowContext(ctx, query,
+		entry.ActorUserID,
+		entry.ActorAPIKeyID,
+		entry.ActorEmail,
+		string(entry.ActorRole),
+		string(entry.Action),
+		string(entry.EntityType),
+		entry.EntityID,
+		nullableJSON(entry.Before),
+		nullableJSON(entry.After),
+		entry.RequestID,
+	).Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create audit entry: %w", err)
+	}
+	return nil
+}
+
+// List obtiene las entradas que cumplen el filtro, de la más reciente a la más antigua,
+// junto con el total de entradas que lo cumplen
+func (r *AuditRepository) List(ctx context.Context, filter *models.AuditFilterRequest) ([]models.AuditEntry, int64, error) {
+	var conditions []string
+	var args []interface{}
+	argIndex := 1
+
+	add := func(condition string, value interface{}) {
+		conditions = append(conditions, fmt.Sprintf(condition, argIndex))
+		args = append(args, value)
+		argIndex++
+	}
+	if filter.ActorUserID != nil {
+		add("actor_user_id = $%d", *filter.ActorUserID)
+	}
+	if filter.ActorAPIKeyID != nil {
+		add("actor_api_key_id = $%d", *filter.ActorAPIKeyID)
+	}
+	if filter.Action != "" {
+		add("action = $%d", string(filter.Action))
+	}
+	if filter.EntityType != "" {
+		add("entity_type = $%d", string(filter.EntityType))
+	}
+	if filter.EntityID != "" {
+		add("entity_id = $%d", filter.EntityID)
+	}
+	if filter.RequestID != "" {
+		add("request_id = $%d", filter.RequestID)
+	}
+	if filter.From != nil {
+		add("created_at >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		add("created_at < $%d", *filter.To)
+	}
+	whereClause := where(conditions)
+
+	var total int64
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM "Audit_log"`+whereClause, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit entries: %w", err)
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM "Audit_log"%s ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d`,
+		auditColumns, whereClause, argIndex, argIndex+1)
+	args = append(args, filter.PageSize, (filter.Page-1)*filter.PageSize)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []models.AuditEntry
+	for rows.Next() {
+		var entry models.AuditEntry
+		var actorUserID, actorAPIKeyID sql.NullInt64
+		var before, after []byte
+		if err := rows.Scan(&entry.ID, &actorUserID, &actorAPIKeyID, &entry.ActorEmail, &entry.ActorRole,
+			&entry.Action, &entry.EntityType, &entry.EntityID, &before, &after, &entry.RequestID, &entry.CreatedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		if actorUserID.Valid {
+			entry.ActorUserID = &actorUserID.Int64
+		}
+		if actorAPIKeyID.Valid {
+			entry.ActorAPIKeyID = &actorAPIKeyID.Int64
+		}
+		entry.Before = before
+		entry.After = after
+		entries = append(entries, entry)
+	}
+	return entries, total, nil
+}
+
+// nullableJSON guarda como NULL los valores JSON vacíos
+func nullableJSON(value []byte) interface{} {
+	if len(value) == 0 {
+		return nil
+	}
+	return value
+}
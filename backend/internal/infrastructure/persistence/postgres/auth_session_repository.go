@@ -0,0 +1,133 @@
+// Package postgres implementa los repositorios usando PostgreSQL/Supabase.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+)
+
+// AuthSessionRepository implementa repository.AuthSessionRepository
+type AuthSessionRepository struct {
+	db *sql.DB
+}
+
+// NewAuthSessionRepository crea una nueva instancia
+func NewAuthSessionRepository(db *sql.DB) *AuthSessionRepository {
+	return &AuthSessionRepository{db: db}
+}
+
+// CreateSession inicia una nueva sesión para un usuario
+func (r *AuthSessionRepository) CreateSession(ctx context.Context, session *models.AuthSession) error {
+	err := r.db.QueryRowContext(ctx,
+		`INSERT INTO "Auth_session" (user_id) VALUES ($1) RETURNING id, created_at`,
+		session.UserID,
+	).Scan(&session.ID, &session.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	return nil
+}
+
+// GetSession obtiene una sesión por su ID
+func (r *AuthSessionRepository) GetSession(ctx context.Context, id int64) (*models.AuthSession, error) {
+	session := &models.AuthSession{}
+	var revokedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx,
+		`SELECT id, user_id, created_at, revoked_at FROM "Auth_session" WHERE id = $1`, id,
+	).Scan(&session.ID, &session.UserID, &session.CreatedAt, &revokedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	if revokedAt.Valid {
+		session.RevokedAt = &revokedAt.Time
+	}
+	return session, nil
+}
+
+// RevokeSession revoca una sesión y todos sus tokens
+func (r *AuthSessionRepository) RevokeSession(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE "Auth_session" SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL`, id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return nil
+}
+
+// RevokeUserSessions revoca todas las sesiones activas de un usuario
+func (r *AuthSessionRepository) RevokeUserSessions(ctx context.Context, userID int64) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE "Auth_session" SET revoked_at = now() WHERE user_id = $1 AND revoked_at IS NULL`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke user sessions: %w", err)
+	}
+	return nil
+}
+
+// IsSessionRevoked indica si una sesión está revocada. Una sesión inexistente se
+// considera revocada.
+func (r *AuthSessionRepository) IsSessionRevoked(ctx context.Context, id int64) (bool, error) {
+	var active bool
+	err := r.db.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM "Auth_session" WHERE id = $1 AND revoked_at IS NULL)`, id,
+	).Scan(&active)
+	if err != nil {
+		return false, fmt.Errorf("failed to check session: %w", err)
+	}
+	return !active, nil
+}
+
+// CreateRefreshToken guarda el hash de un nuevo refresh token
+func (r *AuthSessionRepository) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	query := `
+		INSERT INTO "Refresh_token" (session_id, user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+	err := r.db.QueryRowContext(ctx, query, token.SessionID, token.UserID, token.TokenHash, token.ExpiresAt).
+		Scan(&token.ID, &token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+	return nil
+}
+
+// GetRefreshTokenByHash obtiene un refresh token por su hash
+func (r *AuthSessionRepository) GetRefreshTokenByHash(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	query := `
+		SELECT id, session_id, user_id, token_hash, expires_at, used_at, created_at
+		FROM "Refresh_token"
+		WHERE token_hash = $1
+	`
+	token := &models.RefreshToken{}
+	var usedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, hash).Scan(
+		&token.ID, &token.SessionID, &token.UserID, &token.TokenHash, &token.ExpiresAt, &usedAt, &token.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+	if usedAt.Valid {
+		token.UsedAt = &usedAt.Time
+	}
+	return token, nil
+}
+
+// MarkRefreshTokenUsed marca un refresh token como usado de forma atómica
+func (r *AuthSessionRepository) MarkRefreshTokenUsed(ctx context.Context, id int64) (bool, error) {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE "Refresh_token" SET used_at = now() WHERE id = $1 AND used_at IS NULL`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark refresh token as used: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	return rows == 1, nil
+}
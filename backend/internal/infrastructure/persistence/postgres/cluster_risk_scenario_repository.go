@@ -0,0 +1,88 @@
+// Package postgres implementa los repositorios usando PostgreSQL/Supabase.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+)
+
+// ClusterRiskScenarioRepository implementa repository.ClusterRiskScenarioRepository
+type ClusterRiskScenarioRepository struct {
+	db *sql.DB
+}
+
+// NewClusterRiskScenarioRepository crea una nueva instancia
+func NewClusterRiskScenarioRepository(db *sql.DB) *ClusterRiskScenarioRepository {
+	return &ClusterRiskScenarioRepository{db: db}
+}
+
+// Upsert crea o reemplaza la proyección de un riesgo de un cluster
+func (r *ClusterRiskScenarioRepository) Upsert(ctx context.Context, p *models.ClusterRiskProjection) error {
+	query := `
+		INSERT INTO "Cluster_risk_scenario" (cluster_id, risk_id, scenario, horizon, exposure, sensitivity, consequence, probability)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (cluster_id, risk_id, scenario, horizon) DO UPDATE
+		SET exposure = EXCLUDED.exposure, sensitivity = EXCLUDED.sensitivity,
+		    consequence = EXCLUDED.consequence, probability = EXCLUDED.probability
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		p.ClusterID,
+		p.RiskID,
+		string(p.Scenario),
+		p.Horizon,
+		nullLevel(p.Exposure),
+		nullLevel(p.Sensitivity),
+		nullLevel(p.Consequence),
+		nullLevel(p.Probability),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to upsert risk projection: %w", err)
+	}
+	return nil
+}
+
+// riskProjectionColumns son las columnas de "Cluster_risk_scenario" que lee scanRiskProjection
+const riskProjectionColumns = `cluster_id, risk_id, scenario, horizon, exposure, sensitivity, consequence, probability`
+
+// GetByCluster obtiene las proyecciones de un cluster para un escenario
+func (r *ClusterRiskScenarioRepository) GetByCluster(ctx context.Context, clusterID int64, scenario models.ClimateScenario) ([]models.ClusterRiskProjection, error) {
+	query := `
+		SELECT ` + riskProjectionColumns + `
+		FROM "Cluster_risk_scenario"
+		WHERE cluster_id = $1 AND scenario = $2
+		ORDER BY risk_id, horizon
+	`
+	rows, err := r.db.QueryContext(ctx, query, clusterID, string(scenario))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get risk projections: %w", err)
+	}
+	defer rows.Close()
+
+	var projections []models.ClusterRiskProjection
+	for rows.Next() {
+		p, err := scanRiskProjection(rows)
+		if err != nil {
+			return nil, err
+		}
+		projections = append(projections, *p)
+	}
+	return projections, nil
+}
+
+// scanRiskProjection lee una fila de riskProjectionColumns
+func scanRiskProjection(row rowScanner) (*models.ClusterRiskProjection, error) {
+	p := &models.ClusterRiskProjection{}
+	var exposure, sensitivity, consequence, probability sql.NullString
+	if err := row.Scan(&p.ClusterID, &p.RiskID, &p.Scenario, &p.Horizon,
+		&exposure, &sensitivity, &consequence, &probability); err != nil {
+		return nil, fmt.Errorf("failed to scan risk projection: %w", err)
+	}
+	p.Exposure = levelPtr(exposure)
+	p.Sensitivity = levelPtr(sensitivity)
+	p.Consequence = levelPtr(consequence)
+	p.Probability = levelPtr(probability)
+	return p, nil
+}
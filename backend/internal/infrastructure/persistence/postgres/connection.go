@@ -87,3 +87,10 @@ func Transaction(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) err
 
 	return tx.Commit()
 }
+
+// queryer es la parte común de *sql.DB y *sql.Tx con que se ejecutan las consultas
+// que pueden formar parte de una transacción
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
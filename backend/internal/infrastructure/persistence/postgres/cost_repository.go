@@ -0,0 +1,139 @@
+// Package postgres implementa los repositorios usando PostgreSQL/Supabase.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/repository"
+)
+
+// CostRepository implementa repository.CostRepository
+type CostRepository struct {
+	db *sql.DB
+}
+
+// NewCostRepository crea una nueva instancia
+func NewCostRepository(db *sql.DB) *CostRepository {
+	return &CostRepository{db: db}
+}
+
+// costVarianceGroups asocia cada agrupación del informe con su columna
+var costVarianceGroups = map[models.CostVarianceGroup]string{
+	models.CostVarianceByMeasure: "m.name",
+	models.CostVarianceByType:    "m.type::text",
+	models.CostVarianceByCountry: "s.country",
+}
+
+// CreateInvoice registra una factura y actualiza el coste real de la medida de la
+// tienda con el total facturado, en una misma transacción. Un número de factura ya
+// registrado para la medida de la tienda devuelve repository.ErrDuplicateInvoiceNumber.
+func (r *CostRepository) CreateInvoice(ctx context.Context, invoice *models.MeasureInvoice) error {
+	return Transaction(ctx, r.db, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO "Shop_measure_invoice" (shop_id, measure_name, amount, invoice_number, invoiced_at)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, created_at
+		`, invoice.ShopID, invoice.MeasureName, invoice.Amount, invoice.InvoiceNumber, invoice.InvoicedAt,
+		).Scan(&invoice.ID, &invoice.CreatedAt)
+		if isPgError(err, pgUniqueViolation) {
+			return repository.ErrDuplicateInvoiceNumber
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create invoice: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE "Shop_measure"
+			SET actual_cost = (
+				SELECT SUM(amount) FROM "Shop_measure_invoice"
+				WHERE shop_id = $1 AND measure_name = $2
+			)
+			WHERE shop_id = $1 AND measure_name = $2
+		`, invoice.ShopID, invoice.MeasureName)
+		if err != nil {
+			return fmt.Errorf("failed to update actual cost: %w", err)
+		}
+		return nil
+	})
+}
+
+// invoiceColumns son las columnas de "Shop_measure_invoice" que lee scanInvoice
+const invoiceColumns = `id, shop_id, measure_name, amount, invoice_number, invoiced_at, created_at`
+
+// ListInvoices obtiene las facturas de las medidas de una tienda, de la más reciente a la más antigua
+func (r *CostRepository) ListInvoices(ctx context.Context, shopID int64) ([]models.MeasureInvoice, error) {
+	query := `
+		SELECT ` + invoiceColumns + `
+		FROM "Shop_measure_invoice"
+		WHERE shop_id = $1
+		ORDER BY invoiced_at DESC, id DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, shopID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invoices: %w", err)
+	}
+	defer rows.Close()
+
+	var invoices []models.MeasureInvoice
+	for rows.Next() {
+		inv, err := scanInvoice(rows)
+		if err != nil {
+			return nil, err
+		}
+		invoices = append(invoices, *inv)
+	}
+	return invoices, nil
+}
+
+// scanInvoice lee una fila de invoiceColumns
+func scanInvoice(row rowScanner) (*models.MeasureInvoice, error) {
+	inv := &models.MeasureInvoice{}
+	if err := row.Scan(&inv.ID, &inv.ShopID, &inv.MeasureName, &inv.Amount, &inv.InvoiceNumber, &inv.InvoicedAt, &inv.CreatedAt); err != nil {
+		return nil, fmt.Errorf("failed to scan invoice: %w", err)
+	}
+	return inv, nil
+}
+
+// GetVariance agrega el coste estimado para cada tienda y el real de las medidas
+// completadas con coste real registrado de las tiendas activas dentro del ámbito del
+// usuario. Las medidas en ejecución se excluyen porque su coste real solo recoge las
+// facturas recibidas hasta el momento.
+func (r *CostRepository) GetVariance(ctx context.Context, groupBy models.CostVarianceGroup) ([]models.CostVarianceRow, error) {
+	column, ok := costVarianceGroups[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("unsupported cost variance group: %s", groupBy)
+	}
+
+	conditions := []string{"sm.actual_cost IS NOT NULL", "sm.status IN ('completed', 'verified')", "s.deleted_at IS NULL", "m.deleted_at IS NULL"}
+	scopeConditions, scopeArgs, _ := shopScopeConditions(ctx, "s.", 1)
+	conditions = append(conditions, scopeConditions...)
+
+	query := fmt.Sprintf(`
+		SELECT %[1]s, COUNT(*), SUM(%[3]s), SUM(sm.actual_cost)
+		FROM "Shop_measure" sm
+		JOIN "Measure" m ON m.name = sm.measure_name
+		JOIN "Shop" s ON s.id = sm.shop_id
+		LEFT JOIN "Country" c ON c.name = s.country
+		%[2]s
+		GROUP BY %[1]s
+		ORDER BY %[1]s
+	`, column, where(conditions), measureShopCostSQL)
+	rows, err := r.db.QueryContext(ctx, query, scopeArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cost variance: %w", err)
+	}
+	defer rows.Close()
+
+	var result []models.CostVarianceRow
+	for rows.Next() {
+		var row models.CostVarianceRow
+		if err := rows.Scan(&row.Group, &row.ShopMeasures, &row.EstimatedCost, &row.ActualCost); err != nil {
+			return nil, fmt.Errorf("failed to scan cost variance: %w", err)
+		}
+		result = append(result, row)
+	}
+	return result, nil
+}
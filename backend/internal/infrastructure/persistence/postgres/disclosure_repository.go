@@ -0,0 +1,167 @@
+// Package postgres implementa los repositorios usando PostgreSQL/Supabase.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/authz"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+)
+
+// DisclosureRepository implementa repository.DisclosureRepository
+type DisclosureRepository struct {
+	db *sql.DB
+}
+
+// NewDisclosureRepository crea una nueva instancia
+func NewDisclosureRepository(db *sql.DB) *DisclosureRepository {
+	return &DisclosureRepository{db: db}
+}
+
+// LoadInputs lee los datos de un informe de divulgación en una misma transacción de
+// solo lectura con lectura repetible, por lo que todos corresponden al mismo instante.
+// Los datos de las tiendas se leen con una consulta por tabla, no por tienda.
+func (r *DisclosureRepository) LoadInputs(ctx context.Context, trendSince time.Time) (*models.DisclosureInputs, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin disclosure transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	inputs := &models.DisclosureInputs{
+		Projections: make(map[int64][]models.ClusterRiskProjection),
+		Invoices:    make(map[int64][]models.MeasureInvoice),
+	}
+
+	inputs.ScoringConfig, err = scanScoringConfig(tx.QueryRowContext(ctx,
+		`SELECT `+scoringConfigColumns+` FROM "Risk_scoring_config" WHERE active = true LIMIT 1`))
+	if err == sql.ErrNoRows {
+		inputs.ScoringConfig, err = nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active scoring config: %w", err)
+	}
+
+	if err := loadPortfolioShopData(ctx, tx, &inputs.PortfolioShopData); err != nil {
+		return nil, err
+	}
+	shopIDs, clusterIDs := shopAndClusterIDs(inputs.Shops)
+
+	// Proyecciones de los clusters en todos los escenarios
+	err = queryEach(ctx, tx, `
+		SELECT `+riskProjectionColumns+`
+		FROM "Cluster_risk_scenario"
+		WHERE cluster_id = ANY($1)
+		ORDER BY cluster_id, scenario, risk_id, horizon
+	`, clusterIDs, func(rows *sql.Rows) error {
+		p, err := scanRiskProjection(rows)
+		if err != nil {
+			return err
+		}
+		inputs.Projections[p.ClusterID] = append(inputs.Projections[p.ClusterID], *p)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get risk projections: %w", err)
+	}
+
+	// Facturas de las tiendas
+	err = queryEach(ctx, tx, `
+		SELECT `+invoiceColumns+`
+		FROM "Shop_measure_invoice"
+		WHERE shop_id = ANY($1)
+		ORDER BY shop_id, invoiced_at DESC, id DESC
+	`, shopIDs, func(rows *sql.Rows) error {
+		inv, err := scanInvoice(rows)
+		if err != nil {
+			return err
+		}
+		inputs.Invoices[inv.ShopID] = append(inputs.Invoices[inv.ShopID], *inv)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invoices: %w", err)
+	}
+
+	if inputs.RiskTrend, err = riskTrend(ctx, tx, "month", trendSince); err != nil {
+		return nil, err
+	}
+	return inputs, nil
+}
+
+// SaveReport guarda un informe bajo su suma de comprobación, con los países y clusters
+// de sus tiendas para limitar después su consulta al ámbito de cada usuario
+func (r *DisclosureRepository) SaveReport(ctx context.Context, report *models.DisclosureReport, shops []models.Shop) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to encode disclosure report: %w", err)
+	}
+
+	countrySet := make(map[string]bool)
+	clusterSet := make(map[int64]bool)
+	for _, shop := range shops {
+		countrySet[strings.ToLower(shop.Country)] = true
+		clusterSet[shop.ClusterID] = true
+	}
+	countries := make([]string, 0, len(countrySet))
+	for c := range countrySet {
+		countries = append(countries, c)
+	}
+	sort.Strings(countries)
+	clusterIDs := make([]int64, 0, len(clusterSet))
+	for id := range clusterSet {
+		clusterIDs = append(clusterIDs, id)
+	}
+	sort.Slice(clusterIDs, func(i, j int) bool { return clusterIDs[i] < clusterIDs[j] })
+
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO "Disclosure_report" (checksum, period_start, period_end, countries, cluster_ids, report)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (checksum) DO NOTHING
+	`, report.Data.Checksum, report.Period.Start, report.Period.End, countries, clusterIDs, data)
+	if err != nil {
+		return fmt.Errorf("failed to save disclosure report: %w", err)
+	}
+	return nil
+}
+
+// GetReport obtiene un informe guardado si todos los países y clusters de sus tiendas
+// están dentro del ámbito del usuario; nil si no existe o queda fuera de su ámbito
+func (r *DisclosureRepository) GetReport(ctx context.Context, checksum string) (*models.DisclosureReport, error) {
+	conditions := []string{"checksum = $1"}
+	args := []interface{}{checksum}
+	scope := authz.ScopeFromContext(ctx)
+	if len(scope.Countries) > 0 {
+		countries := make([]string, len(scope.Countries))
+		for i, c := range scope.Countries {
+			countries[i] = strings.ToLower(c)
+		}
+		args = append(args, countries)
+		conditions = append(conditions, fmt.Sprintf("countries <@ $%d", len(args)))
+	}
+	if len(scope.ClusterIDs) > 0 {
+		args = append(args, scope.ClusterIDs)
+		conditions = append(conditions, fmt.Sprintf("cluster_ids <@ $%d", len(args)))
+	}
+
+	var data []byte
+	err := r.db.QueryRowContext(ctx, `SELECT report FROM "Disclosure_report"`+where(conditions), args...).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get disclosure report: %w", err)
+	}
+
+	report := &models.DisclosureReport{}
+	if err := json.Unmarshal(data, report); err != nil {
+		return nil, fmt.Errorf("failed to decode disclosure report: %w", err)
+	}
+	return report, nil
+}
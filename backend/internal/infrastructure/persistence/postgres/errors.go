@@ -0,0 +1,26 @@
+package postgres
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Códigos de error de PostgreSQL que los repositorios traducen a errores del dominio
+const (
+	pgForeignKeyViolation = "23503"
+	pgUniqueViolation     = "23505"
+)
+
+// isPgError indica si err es un error de PostgreSQL con el código indicado
+func isPgError(err error, code string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == code
+}
+
+// isConstraintViolation indica si err es un error de PostgreSQL con el código indicado
+// sobre la restricción o el índice único indicado
+func isConstraintViolation(err error, code, constraint string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == code && pgErr.ConstraintName == constraint
+}
@@ -21,22 +21,36 @@ func NewMeasureRepository(db *sql.DB) *MeasureRepository {
 
 // Create inserta una nueva medida
 func (r *MeasureRepository) Create(ctx context.Context, measure *models.Measure) error {
+	if measure.CostModel == "" {
+		measure.CostModel = models.CostModelFixed
+	}
 	query := `
-		INSERT INTO "Measure" (name, "estimatedCost", type)
-		VALUES ($1, $2, $3)
+		INSERT INTO "Measure" (name, "estimatedCost", cost_model, cost_per_m2, carbon_reduction, embodied_carbon, type)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 	`
-	_, err := r.db.ExecContext(ctx, query, measure.Name, measure.EstimatedCost, measure.Type)
+	_, err := r.db.ExecContext(ctx, query, measure.Name, measure.EstimatedCost, measure.CostModel, measure.CostPerM2,
+		measure.CarbonReduction, measure.EmbodiedCarbon, measure.Type)
 	if err != nil {
 		return fmt.Errorf("failed to create measure: %w", err)
 	}
 	return nil
 }
 
-// GetByName obtiene una medida por su nombre
+// GetByName obtiene una medida por su nombre. Las medidas eliminadas no se retornan.
 func (r *MeasureRepository) GetByName(ctx context.Context, name string) (*models.Measure, error) {
-	query := `SELECT name, "estimatedCost", type FROM "Measure" WHERE name = $1`
+	return r.getByName(ctx, name, "deleted_at IS NULL")
+}
+
+// GetDeletedByName obtiene una medida eliminada por su nombre; nil si no existe o no está eliminada
+func (r *MeasureRepository) GetDeletedByName(ctx context.Context, name string) (*models.Measure, error) {
+	return r.getByName(ctx, name, "deleted_at IS NOT NULL")
+}
+
+// getByName obtiene una medida por su nombre que cumpla la condición de borrado indicada
+func (r *MeasureRepository) getByName(ctx context.Context, name, deletedCondition string) (*models.Measure, error) {
+	query := `SELECT name, "estimatedCost", cost_model, cost_per_m2, carbon_reduction, embodied_carbon, type, deleted_at FROM "Measure" WHERE name = $1 AND ` + deletedCondition
 	measure := &models.Measure{}
-	err := r.db.QueryRowContext(ctx, query, name).Scan(&measure.Name, &measure.EstimatedCost, &measure.Type)
+	err := r.db.QueryRowContext(ctx, query, name).Scan(&measure.Name, &measure.EstimatedCost, &measure.CostModel, &measure.CostPerM2, &measure.CarbonReduction, &measure.EmbodiedCarbon, &measure.Type, &measure.DeletedAt)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -48,8 +62,16 @@ func (r *MeasureRepository) GetByName(ctx context.Context, name string) (*models
 
 // Update actualiza una medida existente
 func (r *MeasureRepository) Update(ctx context.Context, measure *models.Measure) error {
-	query := `UPDATE "Measure" SET "estimatedCost" = $1, type = $2 WHERE name = $3`
-	result, err := r.db.ExecContext(ctx, query, measure.EstimatedCost, measure.Type, measure.Name)
+	if measure.CostModel == "" {
+		measure.CostModel = models.CostModelFixed
+	}
+	query := `
+		UPDATE "Measure" SET "estimatedCost" = $1, cost_model = $2, cost_per_m2 = $3, carbon_reduction = $4,
+		       embodied_carbon = $5, type = $6
+		WHERE name = $7 AND deleted_at IS NULL
+	`
+	result, err := r.db.ExecContext(ctx, query, measure.EstimatedCost, measure.CostModel, measure.CostPerM2,
+		measure.CarbonReduction, measure.EmbodiedCarbon, measure.Type, measure.Name)
 	if err != nil {
 		return fmt.Errorf("failed to update measure: %w", err)
 	}
@@ -60,13 +82,10 @@ func (r *MeasureRepository) Update(ctx context.Context, measure *models.Measure)
 	return nil
 }
 
-// Delete elimina una medida
+// Delete marca una medida como eliminada. Sus relaciones con tiendas y riesgos
+// se conservan para poder restaurarla.
 func (r *MeasureRepository) Delete(ctx context.Context, name string) error {
-	// Primero eliminar las relaciones
-	_, _ = r.db.ExecContext(ctx, `DELETE FROM "Shop_measure" WHERE measure_name = $1`, name)
-	_, _ = r.db.ExecContext(ctx, `DELETE FROM "Risk_measures" WHERE measure_name = $1`, name)
-
-	result, err := r.db.ExecContext(ctx, `DELETE FROM "Measure" WHERE name = $1`, name)
+	result, err := r.db.ExecContext(ctx, `UPDATE "Measure" SET deleted_at = now() WHERE name = $1 AND deleted_at IS NULL`, name)
 	if err != nil {
 		return fmt.Errorf("failed to delete measure: %w", err)
 	}
@@ -77,92 +96,72 @@ func (r *MeasureRepository) Delete(ctx context.Context, name string) error {
 	return nil
 }
 
-// List obtiene todas las medidas
-func (r *MeasureRepository) List(ctx context.Context) ([]models.Measure, error) {
-	query := `SELECT name, "estimatedCost", type FROM "Measure" ORDER BY "estimatedCost"`
-	rows, err := r.db.QueryContext(ctx, query)
+// Restore devuelve a su estado activo una medida eliminada
+func (r *MeasureRepository) Restore(ctx context.Context, name string) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE "Measure" SET deleted_at = NULL WHERE name = $1 AND deleted_at IS NOT NULL`, name)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list measures: %w", err)
+		return fmt.Errorf("failed to restore measure: %w", err)
 	}
-	defer rows.Close()
-
-	var measures []models.Measure
-	for rows.Next() {
-		var m models.Measure
-		if err := rows.Scan(&m.Name, &m.EstimatedCost, &m.Type); err != nil {
-			return nil, fmt.Errorf("failed to scan measure: %w", err)
-		}
-		measures = append(measures, m)
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return fmt.Errorf("measure not found")
 	}
-	return measures, nil
+	return nil
+}
+
+// List obtiene todas las medidas activas
+func (r *MeasureRepository) List(ctx context.Context) ([]models.Measure, error) {
+	return r.queryMeasures(ctx, `SELECT name, "estimatedCost", cost_model, cost_per_m2, carbon_reduction, embodied_carbon, type, deleted_at FROM "Measure" WHERE deleted_at IS NULL ORDER BY "estimatedCost"`)
+}
+
+// ListIncludingDeleted obtiene todas las medidas, también las eliminadas
+func (r *MeasureRepository) ListIncludingDeleted(ctx context.Context) ([]models.Measure, error) {
+	return r.queryMeasures(ctx, `SELECT name, "estimatedCost", cost_model, cost_per_m2, carbon_reduction, embodied_carbon, type, deleted_at FROM "Measure" ORDER BY "estimatedCost"`)
 }
 
 // GetByType obtiene medidas por tipo
 func (r *MeasureRepository) GetByType(ctx context.Context, measureType models.MeasureType) ([]models.Measure, error) {
-	query := `SELECT name, "estimatedCost", type FROM "Measure" WHERE type = $1 ORDER BY "estimatedCost"`
-	rows, err := r.db.QueryContext(ctx, query, measureType)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get measures by type: %w", err)
-	}
-	defer rows.Close()
-
-	var measures []models.Measure
-	for rows.Next() {
-		var m models.Measure
-		if err := rows.Scan(&m.Name, &m.EstimatedCost, &m.Type); err != nil {
-			return nil, fmt.Errorf("failed to scan measure: %w", err)
-		}
-		measures = append(measures, m)
-	}
-	return measures, nil
+	query := `SELECT name, "estimatedCost", cost_model, cost_per_m2, carbon_reduction, embodied_carbon, type, deleted_at FROM "Measure" WHERE type = $1 AND deleted_at IS NULL ORDER BY "estimatedCost"`
+	return r.queryMeasures(ctx, query, measureType)
 }
 
 // GetByRisk obtiene medidas aplicables a un riesgo específico
 func (r *MeasureRepository) GetByRisk(ctx context.Context, riskName string) ([]models.Measure, error) {
 	query := `
-		SELECT m.name, m."estimatedCost", m.type
+		SELECT m.name, m."estimatedCost", m.cost_model, m.cost_per_m2, m.carbon_reduction, m.embodied_carbon, m.type, m.deleted_at
 		FROM "Measure" m
 		JOIN "Risk_measures" rm ON m.name = rm.measure_name
-		WHERE rm.risk_name = $1
+		WHERE rm.risk_name = $1 AND m.deleted_at IS NULL
 		ORDER BY m."estimatedCost"
 	`
-	rows, err := r.db.QueryContext(ctx, query, riskName)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get measures by risk: %w", err)
-	}
-	defer rows.Close()
-
-	var measures []models.Measure
-	for rows.Next() {
-		var m models.Measure
-		if err := rows.Scan(&m.Name, &m.EstimatedCost, &m.Type); err != nil {
-			return nil, fmt.Errorf("failed to scan measure: %w", err)
-		}
-		measures = append(measures, m)
-	}
-	return measures, nil
+	return r.queryMeasures(ctx, query, riskName)
 }
 
 // GetApplicableForShop obtiene medidas aplicables a una tienda (no ya aplicadas)
 func (r *MeasureRepository) GetApplicableForShop(ctx context.Context, shopID int64) ([]models.Measure, error) {
 	query := `
-		SELECT m.name, m."estimatedCost", m.type
+		SELECT m.name, m."estimatedCost", m.cost_model, m.cost_per_m2, m.carbon_reduction, m.embodied_carbon, m.type, m.deleted_at
 		FROM "Measure" m
-		WHERE m.name NOT IN (
+		WHERE m.deleted_at IS NULL AND m.name NOT IN (
 			SELECT measure_name FROM "Shop_measure" WHERE shop_id = $1
 		)
 		ORDER BY m."estimatedCost"
 	`
-	rows, err := r.db.QueryContext(ctx, query, shopID)
+	return r.queryMeasures(ctx, query, shopID)
+}
+
+// queryMeasures ejecuta una consulta que retorna medidas
+func (r *MeasureRepository) queryMeasures(ctx context.Context, query string, args ...interface{}) ([]models.Measure, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get applicable measures: %w", err)
+		return nil, fmt.Errorf("failed to query measures: %w", err)
 	}
 	defer rows.Close()
 
 	var measures []models.Measure
 	for rows.Next() {
 		var m models.Measure
-		if err := rows.Scan(&m.Name, &m.EstimatedCost, &m.Type); err != nil {
+		if err := rows.Scan(&m.Name, &m.EstimatedCost, &m.CostModel, &m.CostPerM2, &m.CarbonReduction, &m.EmbodiedCarbon, &m.Type, &m.DeletedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan measure: %w", err)
 		}
 		measures = append(measures, m)
@@ -237,10 +236,11 @@ func (r *ClusterRepository) Delete(ctx context.Context, id int64) error {
 	return nil
 }
 
-// List obtiene todos los clusters
+// List obtiene todos los clusters dentro del ámbito del usuario
 func (r *ClusterRepository) List(ctx context.Context) ([]models.Cluster, error) {
-	query := `SELECT id, name, utm_north, utm_east FROM "Cluster" ORDER BY name`
-	rows, err := r.db.QueryContext(ctx, query)
+	conditions, args, _ := clusterScopeConditions(ctx, "", 1)
+	query := `SELECT id, name, utm_north, utm_east FROM "Cluster"` + where(conditions) + ` ORDER BY name`
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list clusters: %w", err)
 	}
@@ -257,7 +257,8 @@ func (r *ClusterRepository) List(ctx context.Context) ([]models.Cluster, error)
 	return clusters, nil
 }
 
-// GetWithRisks obtiene un cluster con sus riesgos asociados
+// GetWithRisks obtiene un cluster con sus riesgos asociados.
+// El RiskScore no se calcula aquí: lo asigna la capa de servicios con el scorer vigente.
 func (r *ClusterRepository) GetWithRisks(ctx context.Context, id int64) (*models.ClusterWithRisks, error) {
 	cluster, err := r.GetByID(ctx, id)
 	if err != nil || cluster == nil {
@@ -284,7 +285,6 @@ func (r *ClusterRepository) GetWithRisks(ctx context.Context, id int64) (*models
 		if err := rows.Scan(&rd.ID, &rd.Name, &rd.Exposure, &rd.Sensitivity, &rd.Consequence, &rd.Probability); err != nil {
 			return nil, fmt.Errorf("failed to scan risk detail: %w", err)
 		}
-		rd.RiskScore = calculateRiskScore(rd)
 		result.Risks = append(result.Risks, rd)
 	}
 
@@ -356,7 +356,8 @@ func (r *RiskRepository) List(ctx context.Context) ([]models.Risk, error) {
 	return risks, nil
 }
 
-// GetByClusterID obtiene los riesgos de un cluster con detalles
+// GetByClusterID obtiene los riesgos de un cluster con sus niveles.
+// El RiskScore no se calcula aquí: lo asigna la capa de servicios con el scorer vigente.
 func (r *RiskRepository) GetByClusterID(ctx context.Context, clusterID int64) ([]models.RiskDetail, error) {
 	query := `
 		SELECT r.id, r.name, cr.exposure, cr.sensitivity, cr.consequence, cr.probability
@@ -376,35 +377,74 @@ func (r *RiskRepository) GetByClusterID(ctx context.Context, clusterID int64) ([
 		if err := rows.Scan(&rd.ID, &rd.Name, &rd.Exposure, &rd.Sensitivity, &rd.Consequence, &rd.Probability); err != nil {
 			return nil, err
 		}
-		rd.RiskScore = calculateRiskScore(rd)
 		risks = append(risks, rd)
 	}
 	return risks, nil
 }
 
-// calculateRiskScore calcula el score de riesgo basado en los factores
-func calculateRiskScore(rd models.RiskDetail) float64 {
-	// Convertir niveles a valores numéricos
-	levelToValue := func(l models.Level) float64 {
-		switch l {
-		case models.LevelVeryLow:
-			return 0.1
-		case models.LevelLow:
-			return 0.3
-		case models.LevelMedium:
-			return 0.5
-		case models.LevelHigh:
-			return 0.7
-		case models.LevelVeryHigh:
-			return 0.9
-		default:
-			return 0.5
-		}
+// CountryRepository implementa repository.CountryRepository
+type CountryRepository struct {
+	db *sql.DB
+}
+
+// NewCountryRepository crea una nueva instancia
+func NewCountryRepository(db *sql.DB) *CountryRepository {
+	return &CountryRepository{db: db}
+}
+
+// Create inserta un nuevo país. Sin índice de precios se usa el de referencia.
+func (r *CountryRepository) Create(ctx context.Context, country *models.Country) error {
+	if country.PriceIndex <= 0 {
+		country.PriceIndex = 1
+	}
+	_, err := r.db.ExecContext(ctx, `INSERT INTO "Country" (name, price_index) VALUES ($1, $2)`, country.Name, country.PriceIndex)
+	if err != nil {
+		return fmt.Errorf("failed to create country: %w", err)
+	}
+	return nil
+}
+
+// GetByName obtiene un país por su nombre
+func (r *CountryRepository) GetByName(ctx context.Context, name string) (*models.Country, error) {
+	country := &models.Country{}
+	err := r.db.QueryRowContext(ctx, `SELECT name, price_index FROM "Country" WHERE name = $1`, name).Scan(&country.Name, &country.PriceIndex)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get country: %w", err)
+	}
+	return country, nil
+}
+
+// List obtiene todos los países
+func (r *CountryRepository) List(ctx context.Context) ([]models.Country, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT name, price_index FROM "Country" ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list countries: %w", err)
 	}
+	defer rows.Close()
 
-	// Fórmula: Risk = (Exposure * Sensitivity) * (Consequence * Probability)
-	vulnerability := levelToValue(rd.Exposure) * levelToValue(rd.Sensitivity)
-	impact := levelToValue(rd.Consequence) * levelToValue(rd.Probability)
+	var countries []models.Country
+	for rows.Next() {
+		var c models.Country
+		if err := rows.Scan(&c.Name, &c.PriceIndex); err != nil {
+			return nil, fmt.Errorf("failed to scan country: %w", err)
+		}
+		countries = append(countries, c)
+	}
+	return countries, nil
+}
 
-	return vulnerability * impact
+// Delete elimina un país
+func (r *CountryRepository) Delete(ctx context.Context, name string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM "Country" WHERE name = $1`, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete country: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return fmt.Errorf("country not found")
+	}
+	return nil
 }
@@ -0,0 +1,153 @@
+// Package postgres implementa los repositorios usando PostgreSQL/Supabase.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+)
+
+// RiskScoringConfigRepository implementa repository.RiskScoringConfigRepository
+type RiskScoringConfigRepository struct {
+	db *sql.DB
+}
+
+// NewRiskScoringConfigRepository crea una nueva instancia
+func NewRiskScoringConfigRepository(db *sql.DB) *RiskScoringConfigRepository {
+	return &RiskScoringConfigRepository{db: db}
+}
+
+// scoringParameters es la parte de la configuración que se guarda como JSONB
+type scoringParameters struct {
+	LevelValues    map[models.Level]float64           `json:"level_values"`
+	Weights        models.RiskFactorWeights           `json:"weights"`
+	RiskWeights    map[int64]models.RiskFactorWeights `json:"risk_weights,omitempty"`
+	Thresholds     models.RiskLevelThresholds         `json:"thresholds"`
+	Aggregation    models.RiskAggregationMethod       `json:"aggregation,omitempty"`
+	RiskImportance map[int64]float64                  `json:"risk_importance,omitempty"`
+}
+
+const scoringConfigColumns = `version, name, method, parameters, active, created_at`
+
+// Create inserta una nueva versión de configuración
+func (r *RiskScoringConfigRepository) Create(ctx context.Context, cfg *models.RiskScoringConfig) error {
+	params, err := json.Marshal(scoringParameters{
+		LevelValues:    cfg.LevelValues,
+		Weights:        cfg.Weights,
+		RiskWeights:    cfg.RiskWeights,
+		Thresholds:     cfg.Thresholds,
+		Aggregation:    cfg.Aggregation,
+		RiskImportance: cfg.RiskImportance,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode scoring parameters: %w", err)
+	}
+
+	query := `
+		INSERT INTO "Risk_scoring_config" (name, method, parameters, active)
+		VALUES ($1, $2, $3, false)
+		RETURNING version, created_at
+	`
+	err = r.db.QueryRowContext(ctx, query, cfg.Name, cfg.Method, params).Scan(&cfg.Version, &cfg.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create scoring config: %w", err)
+	}
+	cfg.Active = false
+	return nil
+}
+
+// GetByVersion obtiene una configuración por su versión
+func (r *RiskScoringConfigRepository) GetByVersion(ctx context.Context, version int64) (*models.RiskScoringConfig, error) {
+	query := `SELECT ` + scoringConfigColumns + ` FROM "Risk_scoring_config" WHERE version = $1`
+	cfg, err := scanScoringConfig(r.db.QueryRowContext(ctx, query, version))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scoring config: %w", err)
+	}
+	return cfg, nil
+}
+
+// GetActive obtiene la configuración activa (nil si no hay ninguna)
+func (r *RiskScoringConfigRepository) GetActive(ctx context.Context) (*models.RiskScoringConfig, error) {
+	query := `SELECT ` + scoringConfigColumns + ` FROM "Risk_scoring_config" WHERE active = true LIMIT 1`
+	cfg, err := scanScoringConfig(r.db.QueryRowContext(ctx, query))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active scoring config: %w", err)
+	}
+	return cfg, nil
+}
+
+// List obtiene todas las versiones, de la más reciente a la más antigua
+func (r *RiskScoringConfigRepository) List(ctx context.Context) ([]models.RiskScoringConfig, error) {
+	query := `SELECT ` + scoringConfigColumns + ` FROM "Risk_scoring_config" ORDER BY version DESC`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scoring configs: %w", err)
+	}
+	defer rows.Close()
+
+	var configs []models.RiskScoringConfig
+	for rows.Next() {
+		cfg, err := scanScoringConfig(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan scoring config: %w", err)
+		}
+		configs = append(configs, *cfg)
+	}
+	return configs, nil
+}
+
+// Activate marca una versión como activa y desactiva el resto en una transacción
+func (r *RiskScoringConfigRepository) Activate(ctx context.Context, version int64) error {
+	return Transaction(ctx, r.db, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `UPDATE "Risk_scoring_config" SET active = false WHERE active = true`); err != nil {
+			return fmt.Errorf("failed to deactivate scoring configs: %w", err)
+		}
+		result, err := tx.ExecContext(ctx, `UPDATE "Risk_scoring_config" SET active = true WHERE version = $1`, version)
+		if err != nil {
+			return fmt.Errorf("failed to activate scoring config: %w", err)
+		}
+		rows, _ := result.RowsAffected()
+		if rows == 0 {
+			return fmt.Errorf("scoring config not found")
+		}
+		return nil
+	})
+}
+
+// rowScanner abstrae *sql.Row y *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanScoringConfig(row rowScanner) (*models.RiskScoringConfig, error) {
+	cfg := &models.RiskScoringConfig{}
+	var raw []byte
+	if err := row.Scan(&cfg.Version, &cfg.Name, &cfg.Method, &raw, &cfg.Active, &cfg.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	var params scoringParameters
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, fmt.Errorf("failed to decode scoring parameters: %w", err)
+	}
+	cfg.LevelValues = params.LevelValues
+	cfg.Weights = params.Weights
+	cfg.RiskWeights = params.RiskWeights
+	cfg.Thresholds = params.Thresholds
+	cfg.Aggregation = params.Aggregation
+	cfg.RiskImportance = params.RiskImportance
+	// Las versiones anteriores a la agregación configurable usaban la media
+	if cfg.Aggregation == "" {
+		cfg.Aggregation = models.AggregationMean
+	}
+	return cfg, nil
+}
@@ -0,0 +1,160 @@
+// Package postgres implementa los repositorios usando PostgreSQL/Supabase.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+)
+
+// RiskSnapshotRepository implementa repository.RiskSnapshotRepository
+type RiskSnapshotRepository struct {
+	db *sql.DB
+}
+
+// NewRiskSnapshotRepository crea una nueva instancia
+func NewRiskSnapshotRepository(db *sql.DB) *RiskSnapshotRepository {
+	return &RiskSnapshotRepository{db: db}
+}
+
+const riskSnapshotColumns = `id, shop_id, total_risk, aggregation_method, scoring_version, taxonomy_coverage,
+		risk_coverage, risks, applied_measures, trigger, created_at`
+
+// Create guarda un nuevo snapshot del riesgo de una tienda
+func (r *RiskSnapshotRepository) Create(ctx context.Context, s *models.RiskSnapshot) error {
+	// Guardar arrays vacíos en lugar de null para poder operar con jsonb_array_length
+	if s.Risks == nil {
+		s.Risks = []models.RiskSnapshotItem{}
+	}
+	if s.AppliedMeasures == nil {
+		s.AppliedMeasures = []string{}
+	}
+
+	risks, err := json.Marshal(s.Risks)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot risks: %w", err)
+	}
+	measures, err := json.Marshal(s.AppliedMeasures)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot measures: %w", err)
+	}
+
+	query := `
+		INSERT INTO "Shop_risk_snapshot" (shop_id, total_risk, aggregation_method, scoring_version, taxonomy_coverage,
+		                                  risk_coverage, risks, applied_measures, trigger)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at
+	`
+	err = r.db.QueryRowContext(ctx, query,
+		s.ShopID,
+		s.TotalRisk,
+		string(s.AggregationMethod),
+		s.ScoringVersion,
+		s.TaxonomyCoverage,
+		s.RiskCoverage,
+		risks,
+		measures,
+		string(s.Trigger),
+	).Scan(&s.ID, &s.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create risk snapshot: %w", err)
+	}
+	return nil
+}
+
+// GetLatest obtiene el snapshot más reciente de una tienda (nil si no hay ninguno)
+func (r *RiskSnapshotRepository) GetLatest(ctx context.Context, shopID int64) (*models.RiskSnapshot, error) {
+	query := `SELECT ` + riskSnapshotColumns + ` FROM "Shop_risk_snapshot" WHERE shop_id = $1 ORDER BY created_at DESC, id DESC LIMIT 1`
+	s, err := scanRiskSnapshot(r.db.QueryRowContext(ctx, query, shopID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest risk snapshot: %w", err)
+	}
+	return s, nil
+}
+
+// ListByShop obtiene el historial de una tienda, del más reciente al más antiguo
+func (r *RiskSnapshotRepository) ListByShop(ctx context.Context, shopID int64, limit int) ([]models.RiskSnapshot, error) {
+	query := `SELECT ` + riskSnapshotColumns + ` FROM "Shop_risk_snapshot" WHERE shop_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2`
+	rows, err := r.db.QueryContext(ctx, query, shopID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list risk snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	var snapshots []models.RiskSnapshot
+	for rows.Next() {
+		s, err := scanRiskSnapshot(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan risk snapshot: %w", err)
+		}
+		snapshots = append(snapshots, *s)
+	}
+	return snapshots, nil
+}
+
+// GetTrend agrega por periodo el último snapshot de cada tienda dentro del periodo
+// y del ámbito del usuario
+func (r *RiskSnapshotRepository) GetTrend(ctx context.Context, interval string, since time.Time) ([]models.RiskTrendPoint, error) {
+	return riskTrend(ctx, r.db, interval, since)
+}
+
+// riskTrend implementa GetTrend sobre la conexión o la transacción indicada
+func riskTrend(ctx context.Context, q queryer, interval string, since time.Time) ([]models.RiskTrendPoint, error) {
+	query := `
+		SELECT period, AVG(total_risk), COUNT(*), COALESCE(SUM(measures), 0)
+		FROM (
+			SELECT DISTINCT ON (shop_id, date_trunc($1, created_at))
+			       date_trunc($1, created_at) AS period, shop_id, total_risk,
+			       jsonb_array_length(applied_measures) AS measures
+			FROM "Shop_risk_snapshot"
+			WHERE created_at >= $2%s
+			ORDER BY shop_id, date_trunc($1, created_at), created_at DESC, id DESC
+		) latest
+		GROUP BY period
+		ORDER BY period
+	`
+	// Limitar a las tiendas activas dentro del ámbito del usuario
+	scopeConditions, scopeArgs, _ := shopScopeConditions(ctx, "s.", 3)
+	scopeConditions = append([]string{"s.deleted_at IS NULL"}, scopeConditions...)
+	scopeClause := ` AND shop_id IN (SELECT s.id FROM "Shop" s` + where(scopeConditions) + `)`
+	args := append([]interface{}{interval, since}, scopeArgs...)
+	rows, err := q.QueryContext(ctx, fmt.Sprintf(query, scopeClause), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get risk trend: %w", err)
+	}
+	defer rows.Close()
+
+	var points []models.RiskTrendPoint
+	for rows.Next() {
+		var p models.RiskTrendPoint
+		if err := rows.Scan(&p.Period, &p.AverageRisk, &p.ShopsEvaluated, &p.AppliedMeasures); err != nil {
+			return nil, fmt.Errorf("failed to scan risk trend: %w", err)
+		}
+		points = append(points, p)
+	}
+	return points, nil
+}
+
+func scanRiskSnapshot(row rowScanner) (*models.RiskSnapshot, error) {
+	s := &models.RiskSnapshot{}
+	var risks, measures []byte
+	if err := row.Scan(&s.ID, &s.ShopID, &s.TotalRisk, &s.AggregationMethod, &s.ScoringVersion, &s.TaxonomyCoverage,
+		&s.RiskCoverage, &risks, &measures, &s.Trigger, &s.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(risks, &s.Risks); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot risks: %w", err)
+	}
+	if err := json.Unmarshal(measures, &s.AppliedMeasures); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot measures: %w", err)
+	}
+	return s, nil
+}
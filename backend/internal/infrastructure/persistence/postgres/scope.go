@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/authz"
+)
+
+// shopScopeConditions retorna las condiciones que limitan las tiendas al ámbito del
+// usuario del contexto. prefix es el alias de la tabla "Shop" (p. ej. "s.") y
+// argIndex el primer placeholder libre; retorna también el siguiente placeholder.
+func shopScopeConditions(ctx context.Context, prefix string, argIndex int) ([]string, []interface{}, int) {
+	scope := authz.ScopeFromContext(ctx)
+
+	var conditions []string
+	var args []interface{}
+	if len(scope.Countries) > 0 {
+		countries := make([]string, len(scope.Countries))
+		for i, c := range scope.Countries {
+			countries[i] = strings.ToLower(c)
+		}
+		conditions = append(conditions, fmt.Sprintf("lower(%scountry) = ANY($%d)", prefix, argIndex))
+		args = append(args, countries)
+		argIndex++
+	}
+	if len(scope.ClusterIDs) > 0 {
+		conditions = append(conditions, fmt.Sprintf("%scluster_id = ANY($%d)", prefix, argIndex))
+		args = append(args, scope.ClusterIDs)
+		argIndex++
+	}
+	return conditions, args, argIndex
+}
+
+// clusterScopeConditions retorna las condiciones que limitan los clusters al ámbito
+// del usuario del contexto. Los clusters solo se restringen por ClusterIDs.
+func clusterScopeConditions(ctx context.Context, prefix string, argIndex int) ([]string, []interface{}, int) {
+	scope := authz.ScopeFromContext(ctx)
+	if len(scope.ClusterIDs) == 0 {
+		return nil, nil, argIndex
+	}
+	return []string{fmt.Sprintf("%sid = ANY($%d)", prefix, argIndex)}, []interface{}{scope.ClusterIDs}, argIndex + 1
+}
+
+// where construye una cláusula WHERE con las condiciones indicadas, o una cadena vacía
+func where(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conditions, " AND ")
+}
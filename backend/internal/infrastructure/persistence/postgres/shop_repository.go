@@ -24,8 +24,9 @@ func NewShopRepository(db *sql.DB) *ShopRepository {
 // Create inserta una nueva tienda
 func (r *ShopRepository) Create(ctx context.Context, shop *models.Shop) error {
 	query := `
-		INSERT INTO "Shop" (location, utm_north, utm_east, surface, "carbonFootprint", cluster_id, "totalRisk", "taxonomyCoverage", country)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		INSERT INTO "Shop" (location, utm_north, utm_east, surface, "carbonFootprint", cluster_id, "totalRisk", "taxonomyCoverage", country,
+		                    "totalRiskMethod", "totalRiskScoringVersion")
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, ''), NULLIF($11, 0))
 		RETURNING id
 	`
 	err := r.db.QueryRowContext(ctx, query,
@@ -38,6 +39,8 @@ func (r *ShopRepository) Create(ctx context.Context, shop *models.Shop) error {
 		shop.TotalRisk,
 		shop.TaxonomyCoverage,
 		shop.Country,
+		string(shop.TotalRiskMethod),
+		shop.TotalRiskScoringVersion,
 	).Scan(&shop.ID)
 
 	if err != nil {
@@ -46,13 +49,23 @@ func (r *ShopRepository) Create(ctx context.Context, shop *models.Shop) error {
 	return nil
 }
 
-// GetByID obtiene una tienda por su ID
+// GetByID obtiene una tienda por su ID. Las tiendas eliminadas no se retornan.
 func (r *ShopRepository) GetByID(ctx context.Context, id int64) (*models.Shop, error) {
+	return r.getByID(ctx, id, "deleted_at IS NULL")
+}
+
+// GetDeletedByID obtiene una tienda eliminada por su ID; nil si no existe o no está eliminada
+func (r *ShopRepository) GetDeletedByID(ctx context.Context, id int64) (*models.Shop, error) {
+	return r.getByID(ctx, id, "deleted_at IS NOT NULL")
+}
+
+// getByID obtiene una tienda por su ID que cumpla la condición de borrado indicada
+func (r *ShopRepository) getByID(ctx context.Context, id int64, deletedCondition string) (*models.Shop, error) {
 	query := `
-		SELECT id, location, utm_north, utm_east, COALESCE("totalRisk", 0), COALESCE("taxonomyCoverage", 0), COALESCE(surface, 0), COALESCE("carbonFootprint", 0), cluster_id, country
+		SELECT id, location, utm_north, utm_east, COALESCE("totalRisk", 0), COALESCE("taxonomyCoverage", 0), COALESCE(surface, 0), COALESCE("carbonFootprint", 0), cluster_id, country,
+		       COALESCE("totalRiskMethod", ''), COALESCE("totalRiskScoringVersion", 0), deleted_at
 		FROM "Shop"
-		WHERE id = $1
-	`
+		WHERE id = $1 AND ` + deletedCondition
 	shop := &models.Shop{}
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&shop.ID,
@@ -65,6 +78,9 @@ func (r *ShopRepository) GetByID(ctx context.Context, id int64) (*models.Shop, e
 		&shop.CarbonFootprint,
 		&shop.ClusterID,
 		&shop.Country,
+		&shop.TotalRiskMethod,
+		&shop.TotalRiskScoringVersion,
+		&shop.DeletedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -80,8 +96,9 @@ func (r *ShopRepository) Update(ctx context.Context, shop *models.Shop) error {
 	query := `
 		UPDATE "Shop"
 		SET location = $1, utm_north = $2, utm_east = $3, surface = $4,
-		    "carbonFootprint" = $5, cluster_id = $6, "totalRisk" = $7, "taxonomyCoverage" = $8, country = $9
-		WHERE id = $10
+		    "carbonFootprint" = $5, cluster_id = $6, "totalRisk" = $7, "taxonomyCoverage" = $8, country = $9,
+		    "totalRiskMethod" = NULLIF($10, ''), "totalRiskScoringVersion" = NULLIF($11, 0)
+		WHERE id = $12 AND deleted_at IS NULL
 	`
 	result, err := r.db.ExecContext(ctx, query,
 		shop.Location,
@@ -93,6 +110,8 @@ func (r *ShopRepository) Update(ctx context.Context, shop *models.Shop) error {
 		shop.TotalRisk,
 		shop.TaxonomyCoverage,
 		shop.Country,
+		string(shop.TotalRiskMethod),
+		shop.TotalRiskScoringVersion,
 		shop.ID,
 	)
 	if err != nil {
@@ -106,17 +125,26 @@ func (r *ShopRepository) Update(ctx context.Context, shop *models.Shop) error {
 	return nil
 }
 
-// Delete elimina una tienda
+// Delete marca una tienda como eliminada. Sus medidas, ajustes de riesgo e
+// historial se conservan para poder restaurarla.
 func (r *ShopRepository) Delete(ctx context.Context, id int64) error {
-	// Primero eliminar las medidas asociadas
-	_, err := r.db.ExecContext(ctx, `DELETE FROM "Shop_measure" WHERE shop_id = $1`, id)
+	result, err := r.db.ExecContext(ctx, `UPDATE "Shop" SET deleted_at = now() WHERE id = $1 AND deleted_at IS NULL`, id)
 	if err != nil {
-		return fmt.Errorf("failed to delete shop measures: %w", err)
+		return fmt.Errorf("failed to delete shop: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return fmt.Errorf("shop not found")
 	}
+	return nil
+}
 
-	result, err := r.db.ExecContext(ctx, `DELETE FROM "Shop" WHERE id = $1`, id)
+// Restore devuelve a su estado activo una tienda eliminada
+func (r *ShopRepository) Restore(ctx context.Context, id int64) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE "Shop" SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL`, id)
 	if err != nil {
-		return fmt.Errorf("failed to delete shop: %w", err)
+		return fmt.Errorf("failed to restore shop: %w", err)
 	}
 
 	rows, _ := result.RowsAffected()
@@ -129,7 +157,7 @@ func (r *ShopRepository) Delete(ctx context.Context, id int64) error {
 // List obtiene una lista paginada de tiendas con filtros opcionales
 func (r *ShopRepository) List(ctx context.Context, filter *models.ShopFilterRequest) ([]models.Shop, int64, error) {
 	// Construir query dinámicamente
-	baseQuery := `SELECT id, location, utm_north, utm_east, COALESCE("totalRisk", 0), COALESCE("taxonomyCoverage", 0), COALESCE(surface, 0), COALESCE("carbonFootprint", 0), cluster_id, country FROM "Shop"`
+	baseQuery := `SELECT id, location, utm_north, utm_east, COALESCE("totalRisk", 0), COALESCE("taxonomyCoverage", 0), COALESCE(surface, 0), COALESCE("carbonFootprint", 0), cluster_id, country, COALESCE("totalRiskMethod", ''), COALESCE("totalRiskScoringVersion", 0), deleted_at FROM "Shop"`
 	countQuery := `SELECT COUNT(*) FROM "Shop"`
 
 	var conditions []string
@@ -137,37 +165,16 @@ func (r *ShopRepository) List(ctx context.Context, filter *models.ShopFilterRequ
 	argIndex := 1
 
 	if filter != nil {
-		if filter.ClusterID != nil {
-			conditions = append(conditions, fmt.Sprintf("cluster_id = $%d", argIndex))
-			args = append(args, *filter.ClusterID)
-			argIndex++
-		}
-		if filter.MinRisk != nil {
-			conditions = append(conditions, fmt.Sprintf(`"totalRisk" >= $%d`, argIndex))
-			args = append(args, *filter.MinRisk)
-			argIndex++
-		}
-		if filter.MaxRisk != nil {
-			conditions = append(conditions, fmt.Sprintf(`"totalRisk" <= $%d`, argIndex))
-			args = append(args, *filter.MaxRisk)
-			argIndex++
-		}
-		if filter.MinSurface != nil {
-			conditions = append(conditions, fmt.Sprintf("surface >= $%d", argIndex))
-			args = append(args, *filter.MinSurface)
-			argIndex++
-		}
-		if filter.MaxSurface != nil {
-			conditions = append(conditions, fmt.Sprintf("surface <= $%d", argIndex))
-			args = append(args, *filter.MaxSurface)
-			argIndex++
-		}
-		if filter.SearchQuery != "" {
-			conditions = append(conditions, fmt.Sprintf("location ILIKE $%d", argIndex))
-			args = append(args, "%"+filter.SearchQuery+"%")
-			argIndex++
-		}
+		conditions, args, argIndex = shopFilterConditions(&filter.ShopFilter, "", argIndex)
 	}
+	if filter == nil || !filter.IncludeDeleted {
+		conditions = append(conditions, "deleted_at IS NULL")
+	}
+
+	// Limitar al ámbito del usuario
+	scopeConditions, scopeArgs, argIndex := shopScopeConditions(ctx, "", argIndex)
+	conditions = append(conditions, scopeConditions...)
+	args = append(args, scopeArgs...)
 
 	whereClause := ""
 	if len(conditions) > 0 {
@@ -235,6 +242,9 @@ func (r *ShopRepository) List(ctx context.Context, filter *models.ShopFilterRequ
 			&shop.CarbonFootprint,
 			&shop.ClusterID,
 			&shop.Country,
+			&shop.TotalRiskMethod,
+			&shop.TotalRiskScoringVersion,
+			&shop.DeletedAt,
 		)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan shop: %w", err)
@@ -248,12 +258,18 @@ func (r *ShopRepository) List(ctx context.Context, filter *models.ShopFilterRequ
 // GetByClusterID obtiene todas las tiendas de un cluster
 func (r *ShopRepository) GetByClusterID(ctx context.Context, clusterID int64) ([]models.Shop, error) {
 	query := `
-		SELECT id, location, utm_north, utm_east, COALESCE("totalRisk", 0), COALESCE("taxonomyCoverage", 0), COALESCE(surface, 0), COALESCE("carbonFootprint", 0), cluster_id, country
+		SELECT id, location, utm_north, utm_east, COALESCE("totalRisk", 0), COALESCE("taxonomyCoverage", 0), COALESCE(surface, 0), COALESCE("carbonFootprint", 0), cluster_id, country,
+		       COALESCE("totalRiskMethod", ''), COALESCE("totalRiskScoringVersion", 0)
 		FROM "Shop"
-		WHERE cluster_id = $1
+		WHERE cluster_id = $1 AND deleted_at IS NULL%s
 		ORDER BY id
 	`
-	rows, err := r.db.QueryContext(ctx, query, clusterID)
+	scopeConditions, scopeArgs, _ := shopScopeConditions(ctx, "", 2)
+	scopeClause := ""
+	for _, cond := range scopeConditions {
+		scopeClause += " AND " + cond
+	}
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf(query, scopeClause), append([]interface{}{clusterID}, scopeArgs...)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get shops by cluster: %w", err)
 	}
@@ -273,6 +289,8 @@ func (r *ShopRepository) GetByClusterID(ctx context.Context, clusterID int64) ([
 			&shop.CarbonFootprint,
 			&shop.ClusterID,
 			&shop.Country,
+			&shop.TotalRiskMethod,
+			&shop.TotalRiskScoringVersion,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan shop: %w", err)
@@ -288,10 +306,10 @@ func (r *ShopRepository) GetWithDetails(ctx context.Context, id int64) (*models.
 	query := `
 		SELECT s.id, s.location, s.utm_north, s.utm_east, COALESCE(s."totalRisk", 0),
 		       COALESCE(s."taxonomyCoverage", 0), COALESCE(s.surface, 0), COALESCE(s."carbonFootprint", 0), s.cluster_id,
-		       s.country, c.name as cluster_name
+		       s.country, COALESCE(s."totalRiskMethod", ''), COALESCE(s."totalRiskScoringVersion", 0), c.name as cluster_name
 		FROM "Shop" s
 		JOIN "Cluster" c ON s.cluster_id = c.id
-		WHERE s.id = $1
+		WHERE s.id = $1 AND s.deleted_at IS NULL
 	`
 	shop := &models.ShopWithDetails{}
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
@@ -305,6 +323,8 @@ func (r *ShopRepository) GetWithDetails(ctx context.Context, id int64) (*models.
 		&shop.CarbonFootprint,
 		&shop.ClusterID,
 		&shop.Country,
+		&shop.TotalRiskMethod,
+		&shop.TotalRiskScoringVersion,
 		&shop.ClusterName,
 	)
 	if err == sql.ErrNoRows {
@@ -324,14 +344,26 @@ func (r *ShopRepository) GetWithDetails(ctx context.Context, id int64) (*models.
 	return shop, nil
 }
 
-// GetAppliedMeasures obtiene las medidas aplicadas a una tienda
+// GetAppliedMeasures obtiene las medidas aplicadas a una tienda, sea cual sea su estado
 func (r *ShopRepository) GetAppliedMeasures(ctx context.Context, shopID int64) ([]models.Measure, error) {
+	return r.queryAppliedMeasures(ctx, shopID, "")
+}
+
+// GetCompletedMeasures obtiene las medidas ya implantadas en una tienda
+func (r *ShopRepository) GetCompletedMeasures(ctx context.Context, shopID int64) ([]models.Measure, error) {
+	return r.queryAppliedMeasures(ctx, shopID, ` AND sm.status IN ('completed', 'verified')`)
+}
+
+// queryAppliedMeasures obtiene las medidas de una tienda que cumplen la condición adicional indicada
+func (r *ShopRepository) queryAppliedMeasures(ctx context.Context, shopID int64, statusCondition string) ([]models.Measure, error) {
 	query := `
-		SELECT m.name, m."estimatedCost", m.type
+		SELECT m.name, m."estimatedCost", m.cost_model, m.cost_per_m2, m.carbon_reduction, m.embodied_carbon, m.type,
+		       COALESCE(s.surface, 0), COALESCE(c.price_index, 1)
 		FROM "Measure" m
 		JOIN "Shop_measure" sm ON m.name = sm.measure_name
-		WHERE sm.shop_id = $1
-	`
+		JOIN "Shop" s ON s.id = sm.shop_id
+		LEFT JOIN "Country" c ON c.name = s.country
+		WHERE sm.shop_id = $1 AND m.deleted_at IS NULL` + statusCondition
 	rows, err := r.db.QueryContext(ctx, query, shopID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get applied measures: %w", err)
@@ -341,15 +373,166 @@ func (r *ShopRepository) GetAppliedMeasures(ctx context.Context, shopID int64) (
 	var measures []models.Measure
 	for rows.Next() {
 		var m models.Measure
-		if err := rows.Scan(&m.Name, &m.EstimatedCost, &m.Type); err != nil {
+		var surface, priceIndex float64
+		if err := rows.Scan(&m.Name, &m.EstimatedCost, &m.CostModel, &m.CostPerM2, &m.CarbonReduction, &m.EmbodiedCarbon, &m.Type, &surface, &priceIndex); err != nil {
 			return nil, fmt.Errorf("failed to scan measure: %w", err)
 		}
-		measures = append(measures, m)
+		measures = append(measures, m.ResolveCost(surface, priceIndex))
+	}
+
+	return measures, nil
+}
+
+// shopMeasureSelect lee las medidas aplicadas a las tiendas con su estado de
+// implantación y los datos de la tienda con que se resuelve su coste
+const shopMeasureSelect = `
+	SELECT sm.shop_id, m.name, m."estimatedCost", m.cost_model, m.cost_per_m2, m.carbon_reduction, m.embodied_carbon, m.type,
+	       COALESCE(s.surface, 0), COALESCE(c.price_index, 1), sm.status,
+	       sm.planned_start_date, sm.planned_end_date, sm.actual_start_date, sm.actual_end_date,
+	       sm.actual_cost, COALESCE(sm.notes, ''), sm.status_updated_at,
+	       (SELECT COUNT(*) FROM "Shop_measure_invoice" i WHERE i.shop_id = sm.shop_id AND i.measure_name = sm.measure_name)
+	FROM "Shop_measure" sm
+	JOIN "Measure" m ON m.name = sm.measure_name
+	JOIN "Shop" s ON s.id = sm.shop_id
+	LEFT JOIN "Country" c ON c.name = s.country`
+
+const shopMeasureQuery = shopMeasureSelect + `
+	WHERE sm.shop_id = $1 AND m.deleted_at IS NULL`
+
+// ListActiveIDs obtiene los IDs de todas las tiendas activas, sin aplicar el ámbito del usuario
+func (r *ShopRepository) ListActiveIDs(ctx context.Context) ([]int64, error) {
+	return r.queryIDs(ctx, `SELECT id FROM "Shop" WHERE deleted_at IS NULL ORDER BY id`)
+}
+
+// ListIDsWithMeasure obtiene los IDs de las tiendas activas que tienen aplicada la
+// medida en cualquier estado, sin aplicar el ámbito del usuario
+func (r *ShopRepository) ListIDsWithMeasure(ctx context.Context, measureName string) ([]int64, error) {
+	return r.queryIDs(ctx, `
+		SELECT s.id
+		FROM "Shop" s
+		JOIN "Shop_measure" sm ON sm.shop_id = s.id
+		WHERE s.deleted_at IS NULL AND sm.measure_name = $1
+		ORDER BY s.id
+	`, measureName)
+}
+
+// queryIDs ejecuta una consulta que devuelve una columna de IDs
+func (r *ShopRepository) queryIDs(ctx context.Context, query string, args ...interface{}) ([]int64, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shop ids: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan shop id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// GetShopMeasures obtiene las medidas de una tienda con su estado de implantación
+func (r *ShopRepository) GetShopMeasures(ctx context.Context, shopID int64) ([]models.ShopMeasure, error) {
+	rows, err := r.db.QueryContext(ctx, shopMeasureQuery+` ORDER BY m.name`, shopID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shop measures: %w", err)
 	}
+	defer rows.Close()
 
+	var measures []models.ShopMeasure
+	for rows.Next() {
+		m, err := scanShopMeasure(rows)
+		if err != nil {
+			return nil, err
+		}
+		measures = append(measures, *m)
+	}
 	return measures, nil
 }
 
+// GetShopMeasure obtiene una medida de una tienda con su estado de implantación;
+// nil si la medida no está aplicada a la tienda
+func (r *ShopRepository) GetShopMeasure(ctx context.Context, shopID int64, measureName string) (*models.ShopMeasure, error) {
+	m, err := scanShopMeasure(r.db.QueryRowContext(ctx, shopMeasureQuery+` AND sm.measure_name = $2`, shopID, measureName))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return m, err
+}
+
+// UpdateShopMeasure guarda el estado de implantación de una medida de una tienda. El
+// coste real de las medidas con facturas es la suma de sus facturas y no se sobrescribe.
+func (r *ShopRepository) UpdateShopMeasure(ctx context.Context, measure *models.ShopMeasure) error {
+	query := `
+		UPDATE "Shop_measure" sm
+		SET status = $1, planned_start_date = $2, planned_end_date = $3, actual_start_date = $4,
+		    actual_end_date = $5, notes = NULLIF($7, ''), status_updated_at = now(),
+		    actual_cost = CASE
+		        WHEN EXISTS (SELECT 1 FROM "Shop_measure_invoice" i WHERE i.shop_id = sm.shop_id AND i.measure_name = sm.measure_name)
+		        THEN sm.actual_cost ELSE $6
+		    END
+		WHERE shop_id = $8 AND measure_name = $9
+		RETURNING status_updated_at, actual_cost
+	`
+	err := r.db.QueryRowContext(ctx, query,
+		string(measure.Status),
+		measure.PlannedStartDate,
+		measure.PlannedEndDate,
+		measure.ActualStartDate,
+		measure.ActualEndDate,
+		measure.ActualCost,
+		measure.Notes,
+		measure.ShopID,
+		measure.Name,
+	).Scan(&measure.StatusUpdatedAt, &measure.ActualCost)
+	if err == sql.ErrNoRows {
+		return repository.ErrMeasureNotAppliedToShop
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update shop measure: %w", err)
+	}
+	return nil
+}
+
+// scanShopMeasure lee una fila de shopMeasureQuery con el coste resuelto para la tienda
+func scanShopMeasure(row rowScanner) (*models.ShopMeasure, error) {
+	m := &models.ShopMeasure{}
+	var surface, priceIndex float64
+	err := row.Scan(
+		&m.ShopID,
+		&m.Name,
+		&m.EstimatedCost,
+		&m.CostModel,
+		&m.CostPerM2,
+		&m.CarbonReduction,
+		&m.EmbodiedCarbon,
+		&m.Type,
+		&surface,
+		&priceIndex,
+		&m.Status,
+		&m.PlannedStartDate,
+		&m.PlannedEndDate,
+		&m.ActualStartDate,
+		&m.ActualEndDate,
+		&m.ActualCost,
+		&m.Notes,
+		&m.StatusUpdatedAt,
+		&m.Invoices,
+	)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan shop measure: %w", err)
+	}
+	m.Measure = m.Measure.ResolveCost(surface, priceIndex)
+	return m, nil
+}
+
 // ApplyMeasure aplica una medida a una tienda
 func (r *ShopRepository) ApplyMeasure(ctx context.Context, shopID int64, measureName string) error {
 	query := `INSERT INTO "Shop_measure" (shop_id, measure_name) VALUES ($1, $2) ON CONFLICT DO NOTHING`
@@ -365,9 +548,13 @@ func (r *ShopRepository) ApplyMeasure(ctx context.Context, shopID int64, measure
 	return nil
 }
 
-// RemoveMeasure elimina una medida de una tienda
+// RemoveMeasure elimina una medida de una tienda. Las medidas con facturas no se
+// pueden eliminar: la clave ajena de las facturas lo impide para conservar su histórico.
 func (r *ShopRepository) RemoveMeasure(ctx context.Context, shopID int64, measureName string) error {
 	result, err := r.db.ExecContext(ctx, `DELETE FROM "Shop_measure" WHERE shop_id = $1 AND measure_name = $2`, shopID, measureName)
+	if isPgError(err, pgForeignKeyViolation) {
+		return repository.ErrMeasureHasInvoices
+	}
 	if err != nil {
 		return fmt.Errorf("failed to remove measure: %w", err)
 	}
@@ -379,7 +566,8 @@ func (r *ShopRepository) RemoveMeasure(ctx context.Context, shopID int64, measur
 	return nil
 }
 
-// GetRiskCoverage obtiene la cobertura de riesgos de una tienda
+// GetRiskCoverage obtiene la cobertura de riesgos de una tienda.
+// Los RiskScore de cada item los completa la capa de servicios con el scorer vigente.
 func (r *ShopRepository) GetRiskCoverage(ctx context.Context, shopID int64) (*models.RiskCoverageResponse, error) {
 	// Obtener información de la tienda
 	shop, err := r.GetByID(ctx, shopID)
@@ -392,7 +580,7 @@ func (r *ShopRepository) GetRiskCoverage(ctx context.Context, shopID int64) (*mo
 
 	// Obtener los riesgos del cluster de la tienda
 	risksQuery := `
-		SELECT r.id, r.name, cr.exposure, cr.sensitivity, cr.consequence, cr.probability
+		SELECT r.id, r.name
 		FROM "Risk" r
 		JOIN "Cluster_risk" cr ON r.id = cr.risk_id
 		WHERE cr.cluster_id = $1
@@ -403,8 +591,15 @@ func (r *ShopRepository) GetRiskCoverage(ctx context.Context, shopID int64) (*mo
 	}
 	defer risksRows.Close()
 
-	// Obtener las medidas aplicadas a la tienda
-	appliedMeasures, err := r.GetAppliedMeasures(ctx, shopID)
+	// El coste de las medidas se resuelve con la superficie de la tienda y el índice de precios de su país
+	priceIndex := 1.0
+	err = r.db.QueryRowContext(ctx, `SELECT price_index FROM "Country" WHERE name = $1`, shop.Country).Scan(&priceIndex)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get price index: %w", err)
+	}
+
+	// Solo las medidas implantadas cubren un riesgo
+	appliedMeasures, err := r.GetCompletedMeasures(ctx, shopID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get applied measures: %w", err)
 	}
@@ -421,21 +616,17 @@ func (r *ShopRepository) GetRiskCoverage(ctx context.Context, shopID int64) (*mo
 	for risksRows.Next() {
 		var riskID int64
 		var riskName string
-		var exposure, sensitivity, consequence, probability models.Level
 
-		if err := risksRows.Scan(&riskID, &riskName, &exposure, &sensitivity, &consequence, &probability); err != nil {
+		if err := risksRows.Scan(&riskID, &riskName); err != nil {
 			return nil, fmt.Errorf("failed to scan risk: %w", err)
 		}
 
-		// Calcular el score de riesgo
-		riskScore := calculateRiskScoreFromLevels(exposure, sensitivity, consequence, probability)
-
 		// Obtener todas las medidas que cubren este riesgo
 		measuresForRiskQuery := `
-			SELECT m.name, m."estimatedCost", m.type
+			SELECT m.name, m."estimatedCost", m.cost_model, m.cost_per_m2, m.carbon_reduction, m.embodied_carbon, m.type
 			FROM "Measure" m
 			JOIN "Risk_measures" rm ON m.name = rm.measure_name
-			WHERE rm.risk_name = $1
+			WHERE rm.risk_name = $1 AND m.deleted_at IS NULL
 		`
 		measuresRows, err := r.db.QueryContext(ctx, measuresForRiskQuery, riskName)
 		if err != nil {
@@ -447,10 +638,11 @@ func (r *ShopRepository) GetRiskCoverage(ctx context.Context, shopID int64) (*mo
 
 		for measuresRows.Next() {
 			var m models.Measure
-			if err := measuresRows.Scan(&m.Name, &m.EstimatedCost, &m.Type); err != nil {
+			if err := measuresRows.Scan(&m.Name, &m.EstimatedCost, &m.CostModel, &m.CostPerM2, &m.CarbonReduction, &m.EmbodiedCarbon, &m.Type); err != nil {
 				measuresRows.Close()
 				return nil, fmt.Errorf("failed to scan measure: %w", err)
 			}
+			m = m.ResolveCost(shop.Surface, priceIndex)
 
 			if _, applied := appliedMeasureNames[m.Name]; applied {
 				coveringMeasures = append(coveringMeasures, m)
@@ -468,7 +660,6 @@ func (r *ShopRepository) GetRiskCoverage(ctx context.Context, shopID int64) (*mo
 		riskItems = append(riskItems, models.RiskCoverageItem{
 			RiskID:            riskID,
 			RiskName:          riskName,
-			RiskScore:         riskScore,
 			IsCovered:         isCovered,
 			CoveringMeasures:  coveringMeasures,
 			AvailableMeasures: availableMeasures,
@@ -493,89 +684,386 @@ func (r *ShopRepository) GetRiskCoverage(ctx context.Context, shopID int64) (*mo
 	}, nil
 }
 
-// calculateRiskScoreFromLevels calcula el score de riesgo a partir de los niveles
-func calculateRiskScoreFromLevels(exposure, sensitivity, consequence, probability models.Level) float64 {
-	levelToValue := func(l models.Level) float64 {
-		switch l {
-		case models.LevelVeryLow:
-			return 0.1
-		case models.LevelLow:
-			return 0.3
-		case models.LevelMedium:
-			return 0.5
-		case models.LevelHigh:
-			return 0.7
-		case models.LevelVeryHigh:
-			return 0.9
-		default:
-			return 0.5
-		}
+// shopFilterConditions retorna las condiciones SQL de los filtros de tiendas, con el
+// prefijo de columna indicado y numerando los parámetros desde argIndex
+func shopFilterConditions(filter *models.ShopFilter, prefix string, argIndex int) ([]string, []interface{}, int) {
+	var conditions []string
+	var args []interface{}
+	add := func(condition string, arg interface{}) {
+		conditions = append(conditions, fmt.Sprintf(condition, prefix, argIndex))
+		args = append(args, arg)
+		argIndex++
 	}
 
-	vulnerability := levelToValue(exposure) * levelToValue(sensitivity)
-	impact := levelToValue(consequence) * levelToValue(probability)
-	return vulnerability * impact
+	if filter.ClusterID != nil {
+		add("%scluster_id = $%d", *filter.ClusterID)
+	}
+	if filter.Country != "" {
+		add("lower(%scountry) = lower($%d)", filter.Country)
+	}
+	if filter.MinRisk != nil {
+		add(`%s"totalRisk" >= $%d`, *filter.MinRisk)
+	}
+	if filter.MaxRisk != nil {
+		add(`%s"totalRisk" <= $%d`, *filter.MaxRisk)
+	}
+	if filter.MinSurface != nil {
+		add("%ssurface >= $%d", *filter.MinSurface)
+	}
+	if filter.MaxSurface != nil {
+		add("%ssurface <= $%d", *filter.MaxSurface)
+	}
+	if filter.SearchQuery != "" {
+		add("%slocation ILIKE $%d", "%"+filter.SearchQuery+"%")
+	}
+	return conditions, args, argIndex
 }
 
-// GetStats obtiene estadísticas generales
-func (r *ShopRepository) GetStats(ctx context.Context) (*models.DashboardStats, error) {
-	stats := &models.DashboardStats{}
+// measureShopCostSQL calcula en SQL el coste de una medida en una tienda, igual que
+// models.Measure.CostFor. Requiere los alias m ("Measure"), s ("Shop") y c ("Country").
+const measureShopCostSQL = `(CASE m.cost_model
+			WHEN 'per_m2' THEN m.cost_per_m2 * COALESCE(s.surface, 0)
+			WHEN 'fixed_plus_variable' THEN m."estimatedCost" + m.cost_per_m2 * COALESCE(s.surface, 0)
+			ELSE m."estimatedCost"
+		END * COALESCE(c.price_index, 1))`
+
+// statsCTE son las tablas comunes de las estadísticas del dashboard: las tiendas
+// filtradas, sus medidas con el coste estimado para cada tienda y la fecha a la que
+// se imputa su inversión, y las medidas de esas tiendas dentro del periodo
+const statsCTE = `
+	WITH shops AS (
+		SELECT s.id, s.cluster_id, s."totalRisk" AS total_risk, COALESCE(s."carbonFootprint", 0) AS carbon_footprint
+		FROM "Shop" s%s
+	),
+	measures AS (
+		SELECT sm.shop_id, sm.status IN ('completed', 'verified') AS completed, sm.actual_cost,
+		       ` + measureShopCostSQL + ` AS estimated_cost, m.carbon_reduction, m.embodied_carbon,
+		       COALESCE(sm.actual_end_date, sm.actual_start_date, sm.planned_start_date, sm.status_updated_at::date) AS invested_on
+		FROM "Shop_measure" sm
+		JOIN "Measure" m ON m.name = sm.measure_name AND m.deleted_at IS NULL
+		JOIN "Shop" s ON s.id = sm.shop_id
+		LEFT JOIN "Country" c ON c.name = s.country
+		WHERE sm.shop_id IN (SELECT id FROM shops)
+	),
+	period_measures AS (
+		SELECT * FROM measures%s
+	)`
+
+// GetStats obtiene las estadísticas de las tiendas activas que cumplen los filtros,
+// dentro del ámbito del usuario, con los niveles de riesgo según los umbrales
+// indicados. Los totales y las series se leen en una misma transacción de solo
+// lectura con lectura repetible, por lo que son coherentes entre sí.
+func (r *ShopRepository) GetStats(ctx context.Context, query *models.DashboardStatsQuery, thresholds models.RiskLevelThresholds) (*models.DashboardStats, error) {
+	filter := models.ShopFilter{ClusterID: query.ClusterID, Country: query.Country}
+	shopConditions, args, argIndex := shopFilterConditions(&filter, "s.", 1)
+	shopConditions = append(shopConditions, "s.deleted_at IS NULL")
+	scopeConditions, scopeArgs, argIndex := shopScopeConditions(ctx, "s.", argIndex)
+	shopConditions = append(shopConditions, scopeConditions...)
+	args = append(args, scopeArgs...)
+
+	// Periodo de las medidas y de los snapshots de riesgo. El último día se incluye entero.
+	var periodConditions []string
+	snapshotPeriod := ""
+	if query.From != nil {
+		periodConditions = append(periodConditions, fmt.Sprintf("invested_on >= $%d", argIndex))
+		snapshotPeriod += fmt.Sprintf(" AND sn.created_at >= $%d", argIndex)
+		args = append(args, *query.From)
+		argIndex++
+	}
+	if query.To != nil {
+		periodConditions = append(periodConditions, fmt.Sprintf("invested_on < $%d", argIndex))
+		snapshotPeriod += fmt.Sprintf(" AND sn.created_at < $%d", argIndex)
+		args = append(args, query.To.AddDate(0, 0, 1))
+		argIndex++
+	}
+	cte := fmt.Sprintf(statsCTE, where(shopConditions), where(periodConditions))
 
-	// Total de tiendas
-	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM "Shop"`).Scan(&stats.TotalShops)
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
 	if err != nil {
-		return nil, fmt.Errorf("failed to count shops: %w", err)
+		return nil, fmt.Errorf("failed to begin stats transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Clusters del ámbito que cumplen los filtros; con filtro de país, solo los que
+	// tienen alguna de sus tiendas
+	statsArgs := append(append([]interface{}{}, args...), thresholds.VeryLow, thresholds.Low, thresholds.Medium, thresholds.High)
+	clusterConditions, clusterArgs, clusterIndex := clusterScopeConditions(ctx, "cl.", argIndex+4)
+	statsArgs = append(statsArgs, clusterArgs...)
+	if query.ClusterID != nil {
+		clusterConditions = append(clusterConditions, fmt.Sprintf("cl.id = $%d", clusterIndex))
+		statsArgs = append(statsArgs, *query.ClusterID)
+	}
+	if query.Country != "" {
+		clusterConditions = append(clusterConditions, "cl.id IN (SELECT cluster_id FROM shops)")
 	}
 
-	// Total de clusters
-	err = r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM "Cluster"`).Scan(&stats.TotalClusters)
+	stats := &models.DashboardStats{}
+	var veryLow, low, medium, high, veryHigh, shopsWithMeasures int64
+	err = tx.QueryRowContext(ctx, cte+fmt.Sprintf(`
+		SELECT sh.shops, cl.clusters, sh.average_risk, sh.very_low, sh.low, sh.medium, sh.high, sh.very_high,
+		       (SELECT COUNT(*) FROM "Measure" WHERE deleted_at IS NULL),
+		       pm.applied, pm.total, pm.estimated, pm.actual, pm.shops_with_measures,
+		       sh.carbon_footprint, cb.avoided, cb.embodied
+		FROM (
+			SELECT COUNT(*) AS shops, COALESCE(AVG(total_risk), 0) AS average_risk,
+			       COUNT(*) FILTER (WHERE total_risk < $%[1]d) AS very_low,
+			       COUNT(*) FILTER (WHERE total_risk >= $%[1]d AND total_risk < $%[2]d) AS low,
+			       COUNT(*) FILTER (WHERE total_risk >= $%[2]d AND total_risk < $%[3]d) AS medium,
+			       COUNT(*) FILTER (WHERE total_risk >= $%[3]d AND total_risk < $%[4]d) AS high,
+			       COUNT(*) FILTER (WHERE total_risk >= $%[4]d) AS very_high,
+			       COALESCE(SUM(carbon_footprint), 0) AS carbon_footprint
+			FROM shops
+		) sh
+		CROSS JOIN (
+			SELECT COUNT(*) AS clusters FROM "Cluster" cl%[5]s
+		) cl
+		CROSS JOIN (
+			-- Inversión: real facturada cuando existe y estimada para cada tienda en otro caso.
+			-- Las medidas aplicadas y la cobertura solo cuentan las medidas completadas:
+			-- las planificadas o aprobadas todavía no protegen la tienda.
+			SELECT COUNT(*) FILTER (WHERE completed) AS applied,
+			       COALESCE(SUM(COALESCE(actual_cost, estimated_cost)), 0) AS total,
+			       COALESCE(SUM(estimated_cost), 0) AS estimated,
+			       COALESCE(SUM(actual_cost), 0) AS actual,
+			       COUNT(DISTINCT shop_id) FILTER (WHERE completed) AS shops_with_measures
+			FROM period_measures
+		) pm
+		CROSS JOIN (
+			-- Como en finance.ProjectedFootprint, las reducciones de una tienda se combinan
+			-- de forma multiplicativa: la suma de logaritmos de (1 - reducción) es el
+			-- logaritmo de la fracción de huella que queda
+			SELECT COALESCE(SUM(sh.carbon_footprint * (1 - EXP(x.log_remaining))), 0) AS avoided,
+			       COALESCE(SUM(x.embodied), 0) AS embodied
+			FROM shops sh
+			JOIN (
+				SELECT shop_id, SUM(LN(1 - carbon_reduction)) AS log_remaining, SUM(embodied_carbon) AS embodied
+				FROM measures
+				WHERE completed
+				GROUP BY shop_id
+			) x ON x.shop_id = sh.id
+		) cb
+	`, argIndex, argIndex+1, argIndex+2, argIndex+3, where(clusterConditions)), statsArgs...).Scan(
+		&stats.TotalShops, &stats.TotalClusters, &stats.AverageRisk, &veryLow, &low, &medium, &high, &veryHigh,
+		&stats.TotalMeasures, &stats.AppliedMeasures, &stats.TotalInvestment, &stats.EstimatedInvestment,
+		&stats.ActualInvestment, &shopsWithMeasures, &stats.CarbonFootprint, &stats.AvoidedCarbon, &stats.EmbodiedCarbon)
 	if err != nil {
-		return nil, fmt.Errorf("failed to count clusters: %w", err)
+		return nil, fmt.Errorf("failed to get dashboard stats: %w", err)
+	}
+	stats.ShopsByLevel = map[models.Level]int64{
+		models.LevelVeryLow:  veryLow,
+		models.LevelLow:      low,
+		models.LevelMedium:   medium,
+		models.LevelHigh:     high,
+		models.LevelVeryHigh: veryHigh,
+	}
+	stats.ProjectedCarbonFootprint = stats.CarbonFootprint - stats.AvoidedCarbon
+	if stats.TotalShops > 0 {
+		stats.CoveragePercentage = float64(shopsWithMeasures) / float64(stats.TotalShops) * 100
 	}
 
-	// Riesgo promedio
-	err = r.db.QueryRowContext(ctx, `SELECT COALESCE(AVG("totalRisk"), 0) FROM "Shop"`).Scan(&stats.AverageRisk)
+	seriesArgs := append(append([]interface{}{}, args...), query.Interval)
+
+	// Inversión por periodo
+	rows, err := tx.QueryContext(ctx, cte+fmt.Sprintf(`
+		SELECT date_trunc($%d, invested_on) AS period, COUNT(*) FILTER (WHERE completed),
+		       COALESCE(SUM(COALESCE(actual_cost, estimated_cost)), 0),
+		       COALESCE(SUM(estimated_cost), 0),
+		       COALESCE(SUM(actual_cost), 0)
+		FROM period_measures
+		GROUP BY period
+		ORDER BY period
+	`, argIndex), seriesArgs...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to calculate average risk: %w", err)
+		return nil, fmt.Errorf("failed to get investment series: %w", err)
 	}
+	defer rows.Close()
 
-	// Tiendas de alto riesgo (riesgo > 0.7)
-	err = r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM "Shop" WHERE "totalRisk" > 0.7`).Scan(&stats.HighRiskShops)
+	stats.InvestmentSeries = []models.InvestmentPoint{}
+	for rows.Next() {
+		var p models.InvestmentPoint
+		if err := rows.Scan(&p.Period, &p.AppliedMeasures, &p.TotalInvestment, &p.EstimatedInvestment, &p.ActualInvestment); err != nil {
+			return nil, fmt.Errorf("failed to scan investment series: %w", err)
+		}
+		stats.InvestmentSeries = append(stats.InvestmentSeries, p)
+	}
+	// La transacción usa una única conexión: hay que liberarla antes de la siguiente consulta
+	rows.Close()
+
+	// Riesgo medio por periodo, con el último snapshot de cada tienda en cada periodo
+	// como en RiskSnapshotRepository.GetTrend
+	trend, err := tx.QueryContext(ctx, cte+fmt.Sprintf(`
+		SELECT period, AVG(total_risk), COUNT(*), COALESCE(SUM(measures), 0)
+		FROM (
+			SELECT DISTINCT ON (sn.shop_id, date_trunc($%[1]d, sn.created_at))
+			       date_trunc($%[1]d, sn.created_at) AS period, sn.total_risk,
+			       jsonb_array_length(sn.applied_measures) AS measures
+			FROM "Shop_risk_snapshot" sn
+			WHERE sn.shop_id IN (SELECT id FROM shops)%[2]s
+			ORDER BY sn.shop_id, date_trunc($%[1]d, sn.created_at), sn.created_at DESC, sn.id DESC
+		) latest
+		GROUP BY period
+		ORDER BY period
+	`, argIndex, snapshotPeriod), seriesArgs...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to count high risk shops: %w", err)
+		return nil, fmt.Errorf("failed to get risk series: %w", err)
+	}
+	defer trend.Close()
+
+	stats.RiskSeries = []models.RiskTrendPoint{}
+	for trend.Next() {
+		var p models.RiskTrendPoint
+		if err := trend.Scan(&p.Period, &p.AverageRisk, &p.ShopsEvaluated, &p.AppliedMeasures); err != nil {
+			return nil, fmt.Errorf("failed to scan risk series: %w", err)
+		}
+		stats.RiskSeries = append(stats.RiskSeries, p)
+	}
+	trend.Close()
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit stats transaction: %w", err)
+	}
+	return stats, nil
+}
+
+// loadPortfolioShopData lee las tiendas activas dentro del ámbito del usuario con los
+// riesgos de sus clusters, sus ajustes de riesgo y sus medidas, con una consulta por
+// tabla. Debe ejecutarse en una transacción para que los datos sean coherentes entre sí.
+func loadPortfolioShopData(ctx context.Context, tx *sql.Tx, data *models.PortfolioShopData) error {
+	data.ClusterRisks = make(map[int64][]models.RiskDetail)
+	data.Overrides = make(map[int64][]models.ShopRiskOverride)
+	data.ShopMeasures = make(map[int64][]models.ShopMeasure)
+
+	var err error
+	if data.Shops, err = listPortfolioShops(ctx, tx); err != nil {
+		return err
 	}
+	shopIDs, clusterIDs := shopAndClusterIDs(data.Shops)
 
-	// Total de medidas disponibles
-	err = r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM "Measure"`).Scan(&stats.TotalMeasures)
+	// Riesgos de los clusters
+	err = queryEach(ctx, tx, `
+		SELECT cr.cluster_id, r.id, r.name, cr.exposure, cr.sensitivity, cr.consequence, cr.probability
+		FROM "Risk" r
+		JOIN "Cluster_risk" cr ON r.id = cr.risk_id
+		WHERE cr.cluster_id = ANY($1)
+		ORDER BY cr.cluster_id, r.id
+	`, clusterIDs, func(rows *sql.Rows) error {
+		var clusterID int64
+		var rd models.RiskDetail
+		if err := rows.Scan(&clusterID, &rd.ID, &rd.Name, &rd.Exposure, &rd.Sensitivity, &rd.Consequence, &rd.Probability); err != nil {
+			return fmt.Errorf("failed to scan cluster risk: %w", err)
+		}
+		data.ClusterRisks[clusterID] = append(data.ClusterRisks[clusterID], rd)
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to count measures: %w", err)
+		return fmt.Errorf("failed to get cluster risks: %w", err)
 	}
 
-	// Medidas aplicadas
-	err = r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM "Shop_measure"`).Scan(&stats.AppliedMeasures)
+	// Ajustes de riesgo de las tiendas
+	err = queryEach(ctx, tx, riskOverrideSelect+`
+		WHERE o.shop_id = ANY($1)
+		ORDER BY o.shop_id, o.risk_id
+	`, shopIDs, func(rows *sql.Rows) error {
+		o, err := scanRiskOverride(rows)
+		if err != nil {
+			return err
+		}
+		data.Overrides[o.ShopID] = append(data.Overrides[o.ShopID], *o)
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to count applied measures: %w", err)
+		return fmt.Errorf("failed to get risk overrides: %w", err)
 	}
 
-	// Inversión total
-	err = r.db.QueryRowContext(ctx, `
-		SELECT COALESCE(SUM(m."estimatedCost"), 0)
-		FROM "Shop_measure" sm
-		JOIN "Measure" m ON sm.measure_name = m.name
-	`).Scan(&stats.TotalInvestment)
+	// Medidas de las tiendas
+	err = queryEach(ctx, tx, shopMeasureSelect+`
+		WHERE sm.shop_id = ANY($1) AND m.deleted_at IS NULL
+		ORDER BY sm.shop_id, m.name
+	`, shopIDs, func(rows *sql.Rows) error {
+		m, err := scanShopMeasure(rows)
+		if err != nil {
+			return err
+		}
+		data.ShopMeasures[m.ShopID] = append(data.ShopMeasures[m.ShopID], *m)
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to calculate total investment: %w", err)
+		return fmt.Errorf("failed to get shop measures: %w", err)
 	}
+	return nil
+}
 
-	// Porcentaje de cobertura (tiendas con al menos una medida)
-	var shopsWithMeasures int64
-	err = r.db.QueryRowContext(ctx, `SELECT COUNT(DISTINCT shop_id) FROM "Shop_measure"`).Scan(&shopsWithMeasures)
+// listPortfolioShops obtiene todas las tiendas activas dentro del ámbito del usuario,
+// ordenadas por ID
+func listPortfolioShops(ctx context.Context, q queryer) ([]models.Shop, error) {
+	conditions, args, _ := shopScopeConditions(ctx, "", 1)
+	conditions = append([]string{"deleted_at IS NULL"}, conditions...)
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT id, location, utm_north, utm_east, COALESCE("totalRisk", 0), COALESCE("taxonomyCoverage", 0), COALESCE(surface, 0), COALESCE("carbonFootprint", 0), cluster_id, country,
+		       COALESCE("totalRiskMethod", ''), COALESCE("totalRiskScoringVersion", 0), deleted_at
+		FROM "Shop"`+where(conditions)+`
+		ORDER BY id
+	`, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to count shops with measures: %w", err)
+		return nil, fmt.Errorf("failed to list shops: %w", err)
 	}
-	if stats.TotalShops > 0 {
-		stats.CoveragePercentage = float64(shopsWithMeasures) / float64(stats.TotalShops) * 100
+	defer rows.Close()
+
+	var shops []models.Shop
+	for rows.Next() {
+		var shop models.Shop
+		if err := rows.Scan(
+			&shop.ID,
+			&shop.Location,
+			&shop.UtmNorth,
+			&shop.UtmEast,
+			&shop.TotalRisk,
+			&shop.TaxonomyCoverage,
+			&shop.Surface,
+			&shop.CarbonFootprint,
+			&shop.ClusterID,
+			&shop.Country,
+			&shop.TotalRiskMethod,
+			&shop.TotalRiskScoringVersion,
+			&shop.DeletedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan shop: %w", err)
+		}
+		shops = append(shops, shop)
 	}
+	return shops, rows.Err()
+}
 
-	return stats, nil
+// shopAndClusterIDs obtiene los IDs de las tiendas y, sin repetir, los de sus clusters
+func shopAndClusterIDs(shops []models.Shop) ([]int64, []int64) {
+	shopIDs := make([]int64, len(shops))
+	var clusterIDs []int64
+	seen := make(map[int64]bool)
+	for i, shop := range shops {
+		shopIDs[i] = shop.ID
+		if !seen[shop.ClusterID] {
+			seen[shop.ClusterID] = true
+			clusterIDs = append(clusterIDs, shop.ClusterID)
+		}
+	}
+	return shopIDs, clusterIDs
+}
+
+// queryEach ejecuta una consulta con un único argumento y procesa cada fila. Cierra
+// las filas antes de volver: una transacción usa una única conexión y hay que
+// liberarla antes de la siguiente consulta.
+func queryEach(ctx context.Context, q queryer, query string, arg interface{}, fn func(rows *sql.Rows) error) error {
+	rows, err := q.QueryContext(ctx, query, arg)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := fn(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
 }
@@ -0,0 +1,117 @@
+// Package postgres implementa los repositorios usando PostgreSQL/Supabase.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/repository"
+)
+
+// ShopRiskOverrideRepository implementa repository.ShopRiskOverrideRepository
+type ShopRiskOverrideRepository struct {
+	db *sql.DB
+}
+
+// NewShopRiskOverrideRepository crea una nueva instancia
+func NewShopRiskOverrideRepository(db *sql.DB) *ShopRiskOverrideRepository {
+	return &ShopRiskOverrideRepository{db: db}
+}
+
+// Upsert crea o reemplaza el ajuste de una tienda para un riesgo
+func (r *ShopRiskOverrideRepository) Upsert(ctx context.Context, override *models.ShopRiskOverride) error {
+	query := `
+		INSERT INTO "Shop_risk_override" (shop_id, risk_id, exposure, sensitivity, reason, updated_at)
+		VALUES ($1, $2, $3, $4, $5, now())
+		ON CONFLICT (shop_id, risk_id) DO UPDATE
+		SET exposure = EXCLUDED.exposure, sensitivity = EXCLUDED.sensitivity,
+		    reason = EXCLUDED.reason, updated_at = EXCLUDED.updated_at
+		RETURNING updated_at
+	`
+	err := r.db.QueryRowContext(ctx, query,
+		override.ShopID,
+		override.RiskID,
+		nullLevel(override.Exposure),
+		nullLevel(override.Sensitivity),
+		override.Reason,
+	).Scan(&override.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to upsert risk override: %w", err)
+	}
+	return nil
+}
+
+// Delete elimina el ajuste de una tienda para un riesgo
+func (r *ShopRiskOverrideRepository) Delete(ctx context.Context, shopID, riskID int64) error {
+	result, err := r.db.ExecContext(ctx,
+		`DELETE FROM "Shop_risk_override" WHERE shop_id = $1 AND risk_id = $2`, shopID, riskID)
+	if err != nil {
+		return fmt.Errorf("failed to delete risk override: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return repository.ErrRiskOverrideNotFound
+	}
+	return nil
+}
+
+// riskOverrideSelect lee los ajustes de riesgo de las tiendas con el nombre del riesgo
+const riskOverrideSelect = `
+		SELECT o.shop_id, o.risk_id, r.name, o.exposure, o.sensitivity, o.reason, o.updated_at
+		FROM "Shop_risk_override" o
+		JOIN "Risk" r ON o.risk_id = r.id`
+
+// GetByShop obtiene todos los ajustes de riesgo de una tienda
+func (r *ShopRiskOverrideRepository) GetByShop(ctx context.Context, shopID int64) ([]models.ShopRiskOverride, error) {
+	query := riskOverrideSelect + `
+		WHERE o.shop_id = $1
+		ORDER BY o.risk_id
+	`
+	rows, err := r.db.QueryContext(ctx, query, shopID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get risk overrides: %w", err)
+	}
+	defer rows.Close()
+
+	var overrides []models.ShopRiskOverride
+	for rows.Next() {
+		o, err := scanRiskOverride(rows)
+		if err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, *o)
+	}
+	return overrides, nil
+}
+
+// scanRiskOverride lee una fila de riskOverrideSelect
+func scanRiskOverride(row rowScanner) (*models.ShopRiskOverride, error) {
+	o := &models.ShopRiskOverride{}
+	var exposure, sensitivity sql.NullString
+	if err := row.Scan(&o.ShopID, &o.RiskID, &o.RiskName, &exposure, &sensitivity, &o.Reason, &o.UpdatedAt); err != nil {
+		return nil, fmt.Errorf("failed to scan risk override: %w", err)
+	}
+	o.Exposure = levelPtr(exposure)
+	o.Sensitivity = levelPtr(sensitivity)
+	return o, nil
+}
+
+// nullLevel convierte un nivel opcional en un valor SQL nullable
+func nullLevel(l *models.Level) sql.NullString {
+	if l == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: string(*l), Valid: true}
+}
+
+// levelPtr convierte un valor SQL nullable en un nivel opcional
+func levelPtr(s sql.NullString) *models.Level {
+	if !s.Valid {
+		return nil
+	}
+	l := models.Level(s.String)
+	return &l
+}
@@ -0,0 +1,164 @@
+// Package postgres implementa los repositorios usando PostgreSQL/Supabase.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/repository"
+)
+
+// TaxonomyRepository implementa repository.TaxonomyRepository
+type TaxonomyRepository struct {
+	db *sql.DB
+}
+
+// NewTaxonomyRepository crea una nueva instancia
+func NewTaxonomyRepository(db *sql.DB) *TaxonomyRepository {
+	return &TaxonomyRepository{db: db}
+}
+
+const measureTaxonomyColumns = `measure_name, activity_code, dnsh_climate_mitigation, dnsh_water,
+	dnsh_circular_economy, dnsh_pollution, dnsh_biodiversity, COALESCE(notes, ''), updated_at`
+
+// ListMappings obtiene la correspondencia con la Taxonomía de todas las medidas que la tienen
+func (r *TaxonomyRepository) ListMappings(ctx context.Context) ([]models.MeasureTaxonomy, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+measureTaxonomyColumns+` FROM "Measure_taxonomy" ORDER BY measure_name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list taxonomy mappings: %w", err)
+	}
+	defer rows.Close()
+
+	var mappings []models.MeasureTaxonomy
+	for rows.Next() {
+		m, err := scanMeasureTaxonomy(rows)
+		if err != nil {
+			return nil, err
+		}
+		mappings = append(mappings, *m)
+	}
+	return mappings, nil
+}
+
+// GetMapping obtiene la correspondencia de una medida; nil si no tiene
+func (r *TaxonomyRepository) GetMapping(ctx context.Context, measureName string) (*models.MeasureTaxonomy, error) {
+	m, err := scanMeasureTaxonomy(r.db.QueryRowContext(ctx,
+		`SELECT `+measureTaxonomyColumns+` FROM "Measure_taxonomy" WHERE measure_name = $1`, measureName))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return m, err
+}
+
+// UpsertMapping crea o reemplaza la correspondencia de una medida
+func (r *TaxonomyRepository) UpsertMapping(ctx context.Context, mapping *models.MeasureTaxonomy) error {
+	query := `
+		INSERT INTO "Measure_taxonomy" (measure_name, activity_code, dnsh_climate_mitigation, dnsh_water,
+		                                dnsh_circular_economy, dnsh_pollution, dnsh_biodiversity, notes, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), now())
+		ON CONFLICT (measure_name) DO UPDATE
+		SET activity_code = EXCLUDED.activity_code,
+		    dnsh_climate_mitigation = EXCLUDED.dnsh_climate_mitigation,
+		    dnsh_water = EXCLUDED.dnsh_water,
+		    dnsh_circular_economy = EXCLUDED.dnsh_circular_economy,
+		    dnsh_pollution = EXCLUDED.dnsh_pollution,
+		    dnsh_biodiversity = EXCLUDED.dnsh_biodiversity,
+		    notes = EXCLUDED.notes,
+		    updated_at = EXCLUDED.updated_at
+		RETURNING updated_at
+	`
+	err := r.db.QueryRowContext(ctx, query,
+		mapping.MeasureName,
+		mapping.ActivityCode,
+		mapping.DNSH.ClimateMitigation,
+		mapping.DNSH.Water,
+		mapping.DNSH.CircularEconomy,
+		mapping.DNSH.Pollution,
+		mapping.DNSH.Biodiversity,
+		mapping.Notes,
+	).Scan(&mapping.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to upsert taxonomy mapping: %w", err)
+	}
+	return nil
+}
+
+// DeleteMapping elimina la correspondencia de una medida
+func (r *TaxonomyRepository) DeleteMapping(ctx context.Context, measureName string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM "Measure_taxonomy" WHERE measure_name = $1`, measureName)
+	if err != nil {
+		return fmt.Errorf("failed to delete taxonomy mapping: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return repository.ErrTaxonomyMappingNotFound
+	}
+	return nil
+}
+
+// GetRiskMeasures obtiene los nombres de las medidas activas que reducen cada riesgo
+func (r *TaxonomyRepository) GetRiskMeasures(ctx context.Context) (map[string][]string, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT rm.risk_name, rm.measure_name
+		FROM "Risk_measures" rm
+		JOIN "Measure" m ON m.name = rm.measure_name AND m.deleted_at IS NULL
+		ORDER BY rm.risk_name, rm.measure_name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get risk measures: %w", err)
+	}
+	defer rows.Close()
+
+	riskMeasures := make(map[string][]string)
+	for rows.Next() {
+		var risk, measure string
+		if err := rows.Scan(&risk, &measure); err != nil {
+			return nil, fmt.Errorf("failed to scan risk measure: %w", err)
+		}
+		riskMeasures[risk] = append(riskMeasures[risk], measure)
+	}
+	return riskMeasures, nil
+}
+
+// scanMeasureTaxonomy lee una fila con las columnas de measureTaxonomyColumns
+func scanMeasureTaxonomy(row rowScanner) (*models.MeasureTaxonomy, error) {
+	m := &models.MeasureTaxonomy{}
+	err := row.Scan(
+		&m.MeasureName,
+		&m.ActivityCode,
+		&m.DNSH.ClimateMitigation,
+		&m.DNSH.Water,
+		&m.DNSH.CircularEconomy,
+		&m.DNSH.Pollution,
+		&m.DNSH.Biodiversity,
+		&m.Notes,
+		&m.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan taxonomy mapping: %w", err)
+	}
+	return m, nil
+}
+
+// GetPortfolioShopData lee en una misma transacción de solo lectura con lectura
+// repetible las tiendas activas dentro del ámbito del usuario con los riesgos de sus
+// clusters, sus ajustes de riesgo y sus medidas
+func (r *TaxonomyRepository) GetPortfolioShopData(ctx context.Context) (*models.PortfolioShopData, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin portfolio transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	data := &models.PortfolioShopData{}
+	if err := loadPortfolioShopData(ctx, tx, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
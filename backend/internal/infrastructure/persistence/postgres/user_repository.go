@@ -0,0 +1,236 @@
+// Package postgres implementa los repositorios usando PostgreSQL/Supabase.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/repository"
+)
+
+// UserRepository implementa repository.UserRepository
+type UserRepository struct {
+	db *sql.DB
+}
+
+// NewUserRepository crea una nueva instancia
+func NewUserRepository(db *sql.DB) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+const userColumns = `id, email, password, role, active, created_at, updated_at, countries, cluster_ids,
+	COALESCE(oidc_issuer, ''), COALESCE(oidc_subject, '')`
+
+// Create inserta un nuevo usuario
+func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
+	countries, clusterIDs, err := encodeUserScope(user)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO "User" (email, password, role, active, countries, cluster_ids, oidc_issuer, oidc_subject)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), NULLIF($8, ''))
+		RETURNING id, created_at, updated_at
+	`
+	err = r.db.QueryRowContext(ctx, query, user.Email, user.Password, string(user.Role), user.Active, countries, clusterIDs,
+		user.OIDCIssuer, user.OIDCSubject).
+		Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+	return nil
+}
+
+// GetByID obtiene un usuario por su ID
+func (r *UserRepository) GetByID(ctx context.Context, id int64) (*models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM "User" WHERE id = $1`
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return user, nil
+}
+
+// GetByEmail obtiene un usuario por su email
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM "User" WHERE lower(email) = lower($1)`
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, email))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by email: %w", err)
+	}
+	return user, nil
+}
+
+// GetByOIDCIdentity obtiene el usuario vinculado a la identidad del IdP indicada
+func (r *UserRepository) GetByOIDCIdentity(ctx context.Context, issuer, subject string) (*models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM "User" WHERE oidc_issuer = $1 AND oidc_subject = $2`
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, issuer, subject))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by oidc identity: %w", err)
+	}
+	return user, nil
+}
+
+// Update actualiza un usuario existente
+func (r *UserRepository) Update(ctx context.Context, user *models.User) error {
+	countries, clusterIDs, err := encodeUserScope(user)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE "User" SET email = $1, password = $2, role = $3, active = $4, countries = $5, cluster_ids = $6,
+			oidc_issuer = NULLIF($7, ''), oidc_subject = NULLIF($8, ''), updated_at = now()
+		WHERE id = $9
+		RETURNING updated_at
+	`
+	err = r.db.QueryRowContext(ctx, query, user.Email, user.Password, string(user.Role), user.Active, countries, clusterIDs,
+		user.OIDCIssuer, user.OIDCSubject, user.ID).
+		Scan(&user.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return repository.ErrUserNotFound
+	}
+	if isConstraintViolation(err, pgUniqueViolation, "user_oidc_identity_idx") {
+		return repository.ErrOIDCIdentityTaken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	return nil
+}
+
+// Delete elimina un usuario y sus sesiones
+func (r *UserRepository) Delete(ctx context.Context, id int64) error {
+	return Transaction(ctx, r.db, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM "Refresh_token" WHERE user_id = $1`, id); err != nil {
+			return fmt.Errorf("failed to delete user refresh tokens: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM "Auth_session" WHERE user_id = $1`, id); err != nil {
+			return fmt.Errorf("failed to delete user sessions: %w", err)
+		}
+
+		result, err := tx.ExecContext(ctx, `DELETE FROM "User" WHERE id = $1`, id)
+		if err != nil {
+			return fmt.Errorf("failed to delete user: %w", err)
+		}
+		rows, _ := result.RowsAffected()
+		if rows == 0 {
+			return repository.ErrUserNotFound
+		}
+		return nil
+	})
+}
+
+// EmailExists verifica si ya existe un usuario con el email indicado
+func (r *UserRepository) EmailExists(ctx context.Context, email string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM "User" WHERE lower(email) = lower($1))`, email).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check email: %w", err)
+	}
+	return exists, nil
+}
+
+// List obtiene los usuarios que cumplen los filtros indicados
+func (r *UserRepository) List(ctx context.Context, filter *models.UserFilterRequest) ([]models.User, error) {
+	var conditions []string
+	var args []interface{}
+	argIndex := 1
+
+	if filter != nil {
+		if filter.Role != "" {
+			conditions = append(conditions, fmt.Sprintf("role = $%d", argIndex))
+			args = append(args, string(filter.Role))
+			argIndex++
+		}
+		if filter.Active != nil {
+			conditions = append(conditions, fmt.Sprintf("active = $%d", argIndex))
+			args = append(args, *filter.Active)
+			argIndex++
+		}
+		if filter.Email != "" {
+			conditions = append(conditions, fmt.Sprintf("email ILIKE $%d", argIndex))
+			args = append(args, "%"+filter.Email+"%")
+			argIndex++
+		}
+	}
+
+	query := `SELECT ` + userColumns + ` FROM "User"`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY email"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, *user)
+	}
+	return users, nil
+}
+
+func scanUser(row rowScanner) (*models.User, error) {
+	user := &models.User{}
+	var countries, clusterIDs []byte
+	if err := row.Scan(&user.ID, &user.Email, &user.Password, &user.Role, &user.Active, &user.CreatedAt, &user.UpdatedAt,
+		&countries, &clusterIDs, &user.OIDCIssuer, &user.OIDCSubject); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(countries, &user.Countries); err != nil {
+		return nil, fmt.Errorf("failed to decode user countries: %w", err)
+	}
+	if err := json.Unmarshal(clusterIDs, &user.ClusterIDs); err != nil {
+		return nil, fmt.Errorf("failed to decode user clusters: %w", err)
+	}
+	return user, nil
+}
+
+// encodeUserScope serializa el ámbito del usuario para las columnas jsonb
+func encodeUserScope(user *models.User) ([]byte, []byte, error) {
+	return encodeScope(user.Countries, user.ClusterIDs)
+}
+
+// encodeScope serializa un ámbito de países y clusters para las columnas jsonb
+func encodeScope(countries []string, clusterIDs []int64) ([]byte, []byte, error) {
+	if countries == nil {
+		countries = []string{}
+	}
+	if clusterIDs == nil {
+		clusterIDs = []int64{}
+	}
+
+	encodedCountries, err := json.Marshal(countries)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode scope countries: %w", err)
+	}
+	encodedClusters, err := json.Marshal(clusterIDs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode scope clusters: %w", err)
+	}
+	return encodedCountries, encodedClusters, nil
+}
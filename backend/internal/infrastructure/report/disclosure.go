@@ -0,0 +1,268 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/infrastructure/pdf"
+)
+
+// WriteDisclosureMarkdown escribe el informe de divulgación de riesgos físicos en Markdown
+func WriteDisclosureMarkdown(w io.Writer, r *models.DisclosureReport) error {
+	return disclosureDocument(r).writeMarkdown(w)
+}
+
+// WriteDisclosurePDF escribe el informe de divulgación de riesgos físicos en PDF
+func WriteDisclosurePDF(w io.Writer, r *models.DisclosureReport) error {
+	return disclosureDocument(r).writePDF(w)
+}
+
+// disclosureDocument construye las secciones del informe de divulgación
+func disclosureDocument(r *models.DisclosureReport) *document {
+	return &document{
+		title: "Informe de riesgos climáticos físicos",
+		subtitle: fmt.Sprintf("Periodo del %s al %s. Generado el %s UTC. Elaborado según ESRS E1 (CSRD) y las recomendaciones del TCFD.",
+			date(r.Period.Start), date(r.Period.End), r.GeneratedAt.Format("02/01/2006 15:04")),
+		sections: []section{
+			disclosureSummary(r),
+			disclosureExposure(r),
+			disclosureHighRiskShops(r),
+			disclosureAdaptation(r),
+			disclosureScenarios(r),
+			disclosureData(r),
+		},
+	}
+}
+
+func disclosureSummary(r *models.DisclosureReport) section {
+	s := r.Summary
+	return section{
+		title: "1. Resumen",
+		intro: "Exposición actual de la cartera a los riesgos climáticos físicos. Un riesgo es material para una tienda cuando su exposición y su probabilidad son al menos medias; una tienda es de alto riesgo cuando su riesgo total alcanza el nivel alto según los umbrales del modelo de scoring vigente.",
+		facts: [][2]string{
+			{"Tiendas evaluadas", integer(s.TotalShops)},
+			{"Países", integer(s.Countries)},
+			{"Riesgo medio", score(s.AverageRisk)},
+			{"Riesgo máximo", score(s.MaxRisk)},
+			{"Tiendas de alto riesgo", fmt.Sprintf("%s (%s)", integer(s.HighRiskShops), percent(s.HighRiskPercentage))},
+			{"Riesgos materiales (tienda-riesgo)", integer(s.MaterialRisks)},
+		},
+	}
+}
+
+func disclosureExposure(r *models.DisclosureReport) section {
+	byRisk := table{
+		caption: "Por riesgo",
+		columns: []pdf.Column{
+			{Header: "Riesgo", Width: 0.25},
+			{Header: "Tiendas", Width: 0.1, Align: pdf.AlignRight},
+			{Header: "Material", Width: 0.1, Align: pdf.AlignRight},
+			{Header: "Alto riesgo", Width: 0.12, Align: pdf.AlignRight},
+			{Header: "Score medio", Width: 0.12, Align: pdf.AlignRight},
+			{Header: "Score máx.", Width: 0.11, Align: pdf.AlignRight},
+			{Header: "Distribución por nivel", Width: 0.2},
+		},
+		empty: "No hay riesgos evaluados.",
+	}
+	for _, e := range r.ExposureByRisk {
+		var distribution string
+		for _, l := range levelOrder {
+			if n := e.ShopsByLevel[l]; n > 0 {
+				if distribution != "" {
+					distribution += ", "
+				}
+				distribution += fmt.Sprintf("%s %d", levelLabel(l), n)
+			}
+		}
+		byRisk.rows = append(byRisk.rows, []string{
+			e.RiskName,
+			integer(e.ShopsAssessed),
+			integer(e.MaterialShops),
+			integer(e.HighRiskShops),
+			score(e.AverageScore),
+			score(e.MaxScore),
+			distribution,
+		})
+	}
+
+	byCountry := table{
+		caption: "Por país",
+		columns: []pdf.Column{
+			{Header: "País", Width: 0.22},
+			{Header: "Tiendas", Width: 0.1, Align: pdf.AlignRight},
+			{Header: "Superficie (m²)", Width: 0.16, Align: pdf.AlignRight},
+			{Header: "Riesgo medio", Width: 0.13, Align: pdf.AlignRight},
+			{Header: "Riesgo máx.", Width: 0.13, Align: pdf.AlignRight},
+			{Header: "Alto riesgo", Width: 0.12, Align: pdf.AlignRight},
+			{Header: "Materiales", Width: 0.14, Align: pdf.AlignRight},
+		},
+		empty: "No hay tiendas evaluadas.",
+	}
+	for _, c := range r.ExposureByCountry {
+		byCountry.rows = append(byCountry.rows, []string{
+			c.Country,
+			integer(c.Shops),
+			formatThousands(c.Surface),
+			score(c.AverageRisk),
+			score(c.MaxRisk),
+			integer(c.HighRiskShops),
+			integer(c.MaterialRisks),
+		})
+	}
+
+	return section{
+		title:  "2. Exposición de la cartera",
+		tables: []table{byRisk, byCountry},
+	}
+}
+
+func disclosureHighRiskShops(r *models.DisclosureReport) section {
+	t := table{
+		columns: []pdf.Column{
+			{Header: "ID", Width: 0.07, Align: pdf.AlignRight},
+			{Header: "Tienda", Width: 0.2},
+			{Header: "País", Width: 0.12},
+			{Header: "Riesgo", Width: 0.09, Align: pdf.AlignRight},
+			{Header: "Nivel", Width: 0.1},
+			{Header: "Riesgos materiales", Width: 0.26},
+			{Header: "Pendientes", Width: 0.08, Align: pdf.AlignRight},
+			{Header: "Implantadas", Width: 0.08, Align: pdf.AlignRight},
+		},
+		empty: "Ninguna tienda alcanza el nivel de riesgo alto.",
+	}
+	for _, h := range r.HighRiskShops {
+		t.rows = append(t.rows, []string{
+			strconv.FormatInt(h.ShopID, 10),
+			h.ShopLocation,
+			h.Country,
+			score(h.TotalRisk),
+			levelLabel(h.RiskLevel),
+			list(h.MaterialRisks),
+			integer(h.PendingMeasures),
+			integer(h.CompletedMeasures),
+		})
+	}
+	return section{
+		title:  "3. Tiendas de alto riesgo",
+		intro:  "Tiendas con riesgo total alto o muy alto, de mayor a menor riesgo, con las medidas de adaptación pendientes e implantadas.",
+		tables: []table{t},
+	}
+}
+
+func disclosureAdaptation(r *models.DisclosureReport) section {
+	p := r.AdaptationPlan
+
+	status := table{
+		caption: "Medidas por estado",
+		columns: []pdf.Column{
+			{Header: "Estado", Width: 0.5},
+			{Header: "Medidas", Width: 0.5, Align: pdf.AlignRight},
+		},
+		empty: "No hay medidas de adaptación aplicadas.",
+	}
+	for _, st := range statusOrder {
+		if n, ok := p.MeasuresByStatus[st]; ok {
+			status.rows = append(status.rows, []string{statusLabel(st), integer(n)})
+		}
+	}
+
+	byType := table{
+		caption: "Inversión por tipo de medida",
+		columns: []pdf.Column{
+			{Header: "Tipo", Width: 0.2},
+			{Header: "Medidas", Width: 0.14, Align: pdf.AlignRight},
+			{Header: "Planificada", Width: 0.22, Align: pdf.AlignRight},
+			{Header: "Implantada", Width: 0.22, Align: pdf.AlignRight},
+			{Header: "Facturada en el periodo", Width: 0.22, Align: pdf.AlignRight},
+		},
+		empty: "No hay inversión registrada.",
+	}
+	for _, t := range p.ByType {
+		byType.rows = append(byType.rows, []string{
+			string(t.Type),
+			integer(t.Measures),
+			amount(t.PlannedInvestment),
+			amount(t.ImplementedInvestment),
+			amount(t.PeriodSpend),
+		})
+	}
+
+	return section{
+		title: "4. Plan de adaptación e inversión",
+		intro: "La inversión planificada es el coste estimado de las medidas aún no implantadas; la implantada usa el coste real cuando está registrado.",
+		facts: [][2]string{
+			{"Inversión planificada", amount(p.PlannedInvestment)},
+			{"Inversión implantada", amount(p.ImplementedInvestment)},
+			{"Importe facturado en el periodo", amount(p.PeriodSpend)},
+			{"Medidas terminadas en el periodo", integer(p.CompletedInPeriod)},
+		},
+		tables: []table{status, byType},
+	}
+}
+
+func disclosureScenarios(r *models.DisclosureReport) section {
+	scenarios := table{
+		columns: []pdf.Column{
+			{Header: "Escenario", Width: 0.28},
+			{Header: "Riesgo medio", Width: 0.18, Align: pdf.AlignRight},
+			{Header: "Riesgo máx.", Width: 0.18, Align: pdf.AlignRight},
+			{Header: "Alto riesgo", Width: 0.18, Align: pdf.AlignRight},
+			{Header: "Materiales", Width: 0.18, Align: pdf.AlignRight},
+		},
+	}
+	for _, s := range r.Scenarios {
+		scenarios.rows = append(scenarios.rows, []string{
+			scenarioLabel(s.Scenario),
+			score(s.AverageRisk),
+			score(s.MaxRisk),
+			integer(s.HighRiskShops),
+			integer(s.MaterialRisks),
+		})
+	}
+
+	trend := table{
+		caption: "Evolución del riesgo en el periodo",
+		columns: []pdf.Column{
+			{Header: "Mes", Width: 0.25},
+			{Header: "Riesgo medio", Width: 0.25, Align: pdf.AlignRight},
+			{Header: "Tiendas evaluadas", Width: 0.25, Align: pdf.AlignRight},
+			{Header: "Medidas implantadas", Width: 0.25, Align: pdf.AlignRight},
+		},
+		empty: "No hay recálculos de riesgo registrados en el periodo.",
+	}
+	for _, p := range r.RiskTrend {
+		trend.rows = append(trend.rows, []string{
+			p.Period.Format("01/2006"),
+			score(p.AverageRisk),
+			integer(p.ShopsEvaluated),
+			integer(p.AppliedMeasures),
+		})
+	}
+
+	return section{
+		title:  "5. Análisis de escenarios",
+		intro:  "Riesgo de la cartera con los niveles proyectados por escenario SSP-RCP y horizonte. Los factores sin proyección mantienen su nivel actual.",
+		tables: []table{scenarios, trend},
+	}
+}
+
+func disclosureData(r *models.DisclosureReport) section {
+	d := r.Data
+	return section{
+		title: "6. Datos utilizados",
+		intro: "Dos informes con la misma suma de comprobación se generaron a partir de los mismos datos.",
+		facts: [][2]string{
+			{"Versión del modelo de scoring", strconv.FormatInt(d.ScoringVersion, 10)},
+			{"Método de scoring", string(d.ScoringMethod)},
+			{"Agregación", string(d.AggregationMethod)},
+			{"Umbrales (muy bajo, bajo, medio, alto)", fmt.Sprintf("%.2f, %.2f, %.2f, %.2f", d.Thresholds.VeryLow, d.Thresholds.Low, d.Thresholds.Medium, d.Thresholds.High)},
+			{"Tiendas", integer(d.Shops)},
+			{"Pares tienda-riesgo", integer(d.ShopRisks)},
+			{"Medidas aplicadas", integer(d.ShopMeasures)},
+			{"Facturas", integer(d.Invoices)},
+			{"Suma de comprobación (SHA-256)", d.Checksum},
+		},
+	}
+}
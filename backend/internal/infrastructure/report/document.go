@@ -0,0 +1,112 @@
+package report
+
+import (
+	"io"
+	"strings"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/infrastructure/pdf"
+)
+
+// document es la estructura común de un informe, a partir de la que se generan sus
+// versiones en Markdown y PDF
+type document struct {
+	title    string
+	subtitle string
+	sections []section
+}
+
+// section es una sección de un informe: texto introductorio, pares de dato y valor y tablas
+type section struct {
+	title  string
+	intro  string
+	facts  [][2]string
+	tables []table
+}
+
+// table es una tabla de un informe
+type table struct {
+	caption string
+	columns []pdf.Column
+	rows    [][]string
+	empty   string // Texto mostrado en lugar de la tabla si no tiene filas
+}
+
+// writeMarkdown escribe el informe en Markdown
+func (d *document) writeMarkdown(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("# " + d.title + "\n\n")
+	if d.subtitle != "" {
+		b.WriteString("_" + d.subtitle + "_\n\n")
+	}
+
+	for _, s := range d.sections {
+		b.WriteString("## " + s.title + "\n\n")
+		if s.intro != "" {
+			b.WriteString(s.intro + "\n\n")
+		}
+		if len(s.facts) > 0 {
+			rows := make([][]string, len(s.facts))
+			for i, f := range s.facts {
+				rows[i] = []string{f[0], f[1]}
+			}
+			mdTable(&b, []string{"Dato", "Valor"}, []bool{false, true}, rows)
+		}
+		for _, t := range s.tables {
+			if t.caption != "" {
+				b.WriteString("**" + t.caption + "**\n\n")
+			}
+			if len(t.rows) == 0 {
+				b.WriteString(t.empty + "\n\n")
+				continue
+			}
+			headers := make([]string, len(t.columns))
+			right := make([]bool, len(t.columns))
+			for i, c := range t.columns {
+				headers[i] = c.Header
+				right[i] = c.Align == pdf.AlignRight
+			}
+			mdTable(&b, headers, right, t.rows)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writePDF escribe el informe en PDF
+func (d *document) writePDF(w io.Writer) error {
+	doc := pdf.New(d.title)
+	doc.AddPage()
+	doc.Title(d.title)
+	if d.subtitle != "" {
+		doc.Note(d.subtitle)
+	}
+
+	for _, s := range d.sections {
+		doc.Heading(s.title)
+		if s.intro != "" {
+			doc.Paragraph(s.intro)
+		}
+		if len(s.facts) > 0 {
+			doc.KeyValues(s.facts)
+		}
+		for _, t := range s.tables {
+			t.writePDF(doc)
+		}
+	}
+
+	_, err := doc.WriteTo(w)
+	return err
+}
+
+// writePDF escribe la tabla en un documento PDF; sin filas escribe el texto alternativo
+func (t table) writePDF(doc *pdf.Document) {
+	if t.caption != "" {
+		doc.Paragraph(t.caption)
+	}
+	if len(t.rows) == 0 {
+		doc.Note(t.empty)
+		return
+	}
+	doc.Table(t.columns, t.rows)
+}
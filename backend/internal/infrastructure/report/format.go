@@ -0,0 +1,146 @@
+// Package report genera las versiones en Markdown y PDF de los informes de la API.
+package report
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+)
+
+// levelLabels son los nombres de los niveles de riesgo en los informes
+var levelLabels = map[models.Level]string{
+	models.LevelVeryLow:  "Muy bajo",
+	models.LevelLow:      "Bajo",
+	models.LevelMedium:   "Medio",
+	models.LevelHigh:     "Alto",
+	models.LevelVeryHigh: "Muy alto",
+}
+
+// levelOrder enumera los niveles de riesgo de menor a mayor
+var levelOrder = []models.Level{
+	models.LevelVeryLow,
+	models.LevelLow,
+	models.LevelMedium,
+	models.LevelHigh,
+	models.LevelVeryHigh,
+}
+
+// statusLabels son los nombres de los estados de las medidas en los informes
+var statusLabels = map[models.MeasureStatus]string{
+	models.MeasureStatusPlanned:    "Planificada",
+	models.MeasureStatusApproved:   "Aprobada",
+	models.MeasureStatusInProgress: "En ejecución",
+	models.MeasureStatusCompleted:  "Completada",
+	models.MeasureStatusVerified:   "Verificada",
+}
+
+// statusOrder enumera los estados de las medidas en el orden de su ciclo de vida
+var statusOrder = []models.MeasureStatus{
+	models.MeasureStatusPlanned,
+	models.MeasureStatusApproved,
+	models.MeasureStatusInProgress,
+	models.MeasureStatusCompleted,
+	models.MeasureStatusVerified,
+}
+
+// levelLabel retorna el nombre de un nivel de riesgo
+func levelLabel(l models.Level) string {
+	if label, ok := levelLabels[l]; ok {
+		return label
+	}
+	return string(l)
+}
+
+// statusLabel retorna el nombre del estado de una medida
+func statusLabel(s models.MeasureStatus) string {
+	if label, ok := statusLabels[s]; ok {
+		return label
+	}
+	return string(s)
+}
+
+// scenarioLabel retorna el nombre de un escenario climático
+func scenarioLabel(sel models.ScenarioSelection) string {
+	if sel.IsCurrent() {
+		return "Actual"
+	}
+	return fmt.Sprintf("%s (%d)", strings.ToUpper(string(sel.Scenario)), sel.Horizon)
+}
+
+// date formatea una fecha
+func date(t time.Time) string {
+	return t.Format("02/01/2006")
+}
+
+// score formatea un score de riesgo
+func score(v float64) string {
+	return fmt.Sprintf("%.3f", v)
+}
+
+// percent formatea un porcentaje
+func percent(v float64) string {
+	return fmt.Sprintf("%.1f %%", v)
+}
+
+// amount formatea un importe en euros con separador de miles
+func amount(v float64) string {
+	return formatThousands(math.Round(v)) + " €"
+}
+
+// integer formatea un número entero con separador de miles
+func integer(v int64) string {
+	return formatThousands(float64(v))
+}
+
+// formatThousands formatea un número sin decimales con el punto como separador de miles
+func formatThousands(v float64) string {
+	s := fmt.Sprintf("%.0f", math.Abs(v))
+	var b strings.Builder
+	if v < 0 && s != "0" {
+		b.WriteByte('-')
+	}
+	for i, c := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// list une una lista de nombres; un guion si está vacía
+func list(items []string) string {
+	if len(items) == 0 {
+		return "-"
+	}
+	return strings.Join(items, ", ")
+}
+
+// mdEscape escapa los caracteres con significado en las celdas de una tabla Markdown
+func mdEscape(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
+
+// mdTable escribe una tabla Markdown
+func mdTable(b *strings.Builder, headers []string, rightAligned []bool, rows [][]string) {
+	b.WriteString("| " + strings.Join(headers, " | ") + " |\n|")
+	for i := range headers {
+		if i < len(rightAligned) && rightAligned[i] {
+			b.WriteString(" ---: |")
+		} else {
+			b.WriteString(" --- |")
+		}
+	}
+	b.WriteString("\n")
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for i, c := range row {
+			cells[i] = mdEscape(c)
+		}
+		b.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	}
+	b.WriteString("\n")
+}
@@ -0,0 +1,305 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/infrastructure/pdf"
+)
+
+// Dimensiones de la cabecera de la ficha de una tienda, en puntos
+const (
+	mapWidth  = 190.0
+	mapHeight = 150.0
+	// factsWidth es el ancho de los datos de la tienda, a la izquierda del mapa
+	factsWidth = pdf.ContentWidth - mapWidth - 15
+)
+
+// earthRadius es el radio de la esfera de la proyección Web Mercator, en metros
+const earthRadius = 6378137.0
+
+// Colores del mapa de situación
+var (
+	mapBackground = pdf.Color{R: 0.93, G: 0.95, B: 0.97}
+	shopColor     = pdf.Color{R: 0.8, G: 0.15, B: 0.15}
+	clusterColor  = pdf.Color{R: 0.15, G: 0.3, B: 0.6}
+	barColor      = pdf.Color{R: 0.2, G: 0.55, B: 0.3}
+)
+
+// WriteShopDossierPDF escribe la ficha de riesgo e inversión de una tienda en PDF
+func WriteShopDossierPDF(w io.Writer, d *models.ShopDossier) error {
+	title := "Ficha de riesgo e inversión"
+	doc := pdf.New(fmt.Sprintf("%s - %s", title, d.Shop.Location))
+	doc.AddPage()
+	doc.Title(fmt.Sprintf("%s: %s", title, d.Shop.Location))
+	doc.Note(fmt.Sprintf("Tienda %d. Generado el %s UTC con el modelo de scoring v%d.",
+		d.Shop.ID, d.GeneratedAt.Format("02/01/2006 15:04"), d.Assessment.ScoringVersion))
+	doc.Space(6)
+
+	top := doc.Reserve(mapHeight + 28)
+	shopFacts(doc, top, factsWidth, dossierFacts(d))
+	locationMap(doc, pdf.PageWidth-pdf.Margin-mapWidth, top, d)
+
+	doc.Heading("Riesgos evaluados")
+	riskTable(d).writePDF(doc)
+	for _, r := range d.Assessment.Risks {
+		if r.OverrideReason != "" {
+			doc.Note("* La tienda ajusta la exposición o la sensibilidad del cluster para este riesgo.")
+			break
+		}
+	}
+
+	c := d.Coverage
+	doc.Heading("Cobertura de riesgos")
+	doc.Paragraph(fmt.Sprintf("%d de %d riesgos cubiertos por medidas implantadas (%s).",
+		c.CoveredRisks, c.TotalRisks, percent(c.CoveragePercentage)))
+	doc.Bar(pdf.Margin, doc.Reserve(14), pdf.ContentWidth, 8, c.CoveragePercentage/100, barColor)
+	coverageTable(d).writePDF(doc)
+
+	doc.Heading("Medidas aplicadas")
+	measureTable(d).writePDF(doc)
+
+	doc.Heading("Próximas medidas recomendadas")
+	doc.Paragraph("Medidas del catálogo para los riesgos que aún no cubre ninguna medida, ni siquiera pendiente de implantar, priorizadas por la puntuación de los riesgos que reducen.")
+	recommendationTable(d).writePDF(doc)
+
+	_, err := doc.WriteTo(w)
+	return err
+}
+
+// dossierFacts retorna los datos generales de la tienda
+func dossierFacts(d *models.ShopDossier) [][2]string {
+	var estimated, actual float64
+	for _, m := range d.Measures {
+		estimated += m.EstimatedCost
+		if m.ActualCost != nil {
+			actual += *m.ActualCost
+		}
+	}
+
+	return [][2]string{
+		{"País", d.Shop.Country},
+		{"Cluster", d.Cluster.Name},
+		{"Superficie", formatThousands(d.Shop.Surface) + " m²"},
+		{"Huella de carbono", fmt.Sprintf("%.1f tCO2e/año", d.Shop.CarbonFootprint)},
+		{"Coordenadas", coordinates(d.Shop.UtmEast, d.Shop.UtmNorth)},
+		{"Riesgo total", fmt.Sprintf("%s (%s)", score(d.Assessment.OverallRiskScore), levelLabel(d.Assessment.RiskLevel))},
+		{"Riesgos cubiertos", fmt.Sprintf("%d de %d (%s)", d.Coverage.CoveredRisks, d.Coverage.TotalRisks, percent(d.Coverage.CoveragePercentage))},
+		{"Cobertura Taxonomía UE", percent(d.Shop.TaxonomyCoverage)},
+		{"Medidas aplicadas", integer(int64(len(d.Measures)))},
+		{"Inversión estimada", amount(estimated)},
+		{"Inversión facturada", amount(actual)},
+	}
+}
+
+// shopFacts escribe pares de etiqueta y valor en una columna del ancho indicado
+func shopFacts(doc *pdf.Document, y, width float64, facts [][2]string) {
+	const size, spacing, labelWidth = 9.0, 14.0, 120.0
+	for i, f := range facts {
+		baseline := y + size + float64(i)*spacing
+		doc.Text(pdf.Margin, baseline, pdf.Bold, size, pdf.Black, pdf.Truncate(f[0], pdf.Bold, size, labelWidth-6))
+		doc.Text(pdf.Margin+labelWidth, baseline, pdf.Regular, size, pdf.Black, pdf.Truncate(f[1], pdf.Regular, size, width-labelWidth))
+	}
+}
+
+// locationMap dibuja el mapa de situación de la tienda entre las demás tiendas de su
+// cluster, con una barra de escala y la leyenda debajo. Las coordenadas UTM de la
+// aplicación son metros en la proyección Web Mercator (EPSG:3857).
+func locationMap(doc *pdf.Document, x, y float64, d *models.ShopDossier) {
+	const padding = 14.0
+
+	// Encuadre de todos los puntos, con un mínimo de 2 km para las tiendas aisladas
+	minE, maxE := d.Shop.UtmEast, d.Shop.UtmEast
+	minN, maxN := d.Shop.UtmNorth, d.Shop.UtmNorth
+	extend := func(e, n float64) {
+		minE, maxE = math.Min(minE, e), math.Max(maxE, e)
+		minN, maxN = math.Min(minN, n), math.Max(maxN, n)
+	}
+	extend(d.Cluster.UtmEast, d.Cluster.UtmNorth)
+	for _, s := range d.Neighbours {
+		extend(s.UtmEast, s.UtmNorth)
+	}
+	spanE, spanN := math.Max(maxE-minE, 2000), math.Max(maxN-minN, 2000)
+	scale := math.Min((mapWidth-2*padding)/spanE, (mapHeight-2*padding)/spanN)
+	centerE, centerN := (minE+maxE)/2, (minN+maxN)/2
+	project := func(e, n float64) (float64, float64) {
+		return x + mapWidth/2 + (e-centerE)*scale, y + mapHeight/2 - (n-centerN)*scale
+	}
+
+	doc.Rect(x, y, mapWidth, mapHeight, mapBackground)
+	doc.StrokeRect(x, y, mapWidth, mapHeight, 0.5, pdf.Gray)
+
+	for _, s := range d.Neighbours {
+		px, py := project(s.UtmEast, s.UtmNorth)
+		doc.Circle(px, py, 2, pdf.Gray)
+	}
+	cx, cy := project(d.Cluster.UtmEast, d.Cluster.UtmNorth)
+	doc.Line(cx-4, cy, cx+4, cy, 1, clusterColor)
+	doc.Line(cx, cy-4, cx, cy+4, 1, clusterColor)
+	sx, sy := project(d.Shop.UtmEast, d.Shop.UtmNorth)
+	doc.Circle(sx, sy, 5, pdf.White)
+	doc.Circle(sx, sy, 3.5, shopColor)
+
+	// La proyección amplía las distancias por 1/cos(latitud)
+	lat, _ := latLon(d.Shop.UtmEast, d.Shop.UtmNorth)
+	pointsPerMetre := scale / math.Cos(lat*math.Pi/180)
+	metres := niceDistance(mapWidth / 4 / pointsPerMetre)
+	length := metres * pointsPerMetre
+	barY := y + mapHeight - 8
+	doc.Line(x+8, barY, x+8+length, barY, 1.5, pdf.Black)
+	doc.Line(x+8, barY-3, x+8, barY, 1, pdf.Black)
+	doc.Line(x+8+length, barY-3, x+8+length, barY, 1, pdf.Black)
+	doc.Text(x+12+length, barY+2, pdf.Regular, 7, pdf.Black, distanceLabel(metres))
+	doc.Text(x+mapWidth-13, y+14, pdf.Bold, 9, pdf.Gray, "N")
+	doc.Line(x+mapWidth-10, y+17, x+mapWidth-10, y+30, 1, pdf.Gray)
+
+	// Leyenda
+	ly := y + mapHeight + 12
+	doc.Circle(x+4, ly-2.5, 3, shopColor)
+	doc.Text(x+10, ly, pdf.Regular, 7, pdf.Black, "Tienda")
+	doc.Circle(x+46, ly-2.5, 2, pdf.Gray)
+	doc.Text(x+52, ly, pdf.Regular, 7, pdf.Black, "Otras tiendas del cluster")
+	doc.Line(x+1, ly+9.5, x+7, ly+9.5, 1, clusterColor)
+	doc.Line(x+4, ly+6.5, x+4, ly+12.5, 1, clusterColor)
+	doc.Text(x+10, ly+12, pdf.Regular, 7, pdf.Black, "Centro del cluster")
+}
+
+// latLon convierte coordenadas Web Mercator a latitud y longitud en grados
+func latLon(east, north float64) (float64, float64) {
+	lat := (2*math.Atan(math.Exp(north/earthRadius)) - math.Pi/2) * 180 / math.Pi
+	lon := east / earthRadius * 180 / math.Pi
+	return lat, lon
+}
+
+// coordinates formatea la latitud y la longitud de unas coordenadas Web Mercator
+func coordinates(east, north float64) string {
+	lat, lon := latLon(east, north)
+	ns, ew := "N", "E"
+	if lat < 0 {
+		ns = "S"
+	}
+	if lon < 0 {
+		ew = "O"
+	}
+	return fmt.Sprintf("%.4f° %s, %.4f° %s", math.Abs(lat), ns, math.Abs(lon), ew)
+}
+
+// niceDistance redondea una distancia hacia abajo a 1, 2 o 5 por una potencia de diez
+func niceDistance(metres float64) float64 {
+	if metres <= 0 {
+		return 1
+	}
+	magnitude := math.Pow(10, math.Floor(math.Log10(metres)))
+	for _, f := range []float64{5, 2, 1} {
+		if f*magnitude <= metres {
+			return f * magnitude
+		}
+	}
+	return magnitude
+}
+
+// distanceLabel formatea una distancia de la barra de escala
+func distanceLabel(metres float64) string {
+	if metres >= 1000 {
+		return fmt.Sprintf("%g km", metres/1000)
+	}
+	return fmt.Sprintf("%g m", metres)
+}
+
+func riskTable(d *models.ShopDossier) table {
+	t := table{
+		columns: []pdf.Column{
+			{Header: "Riesgo", Width: 0.3},
+			{Header: "Exposición", Width: 0.14},
+			{Header: "Sensibilidad", Width: 0.14},
+			{Header: "Probabilidad", Width: 0.14},
+			{Header: "Consecuencia", Width: 0.14},
+			{Header: "Puntuación", Width: 0.14, Align: pdf.AlignRight},
+		},
+		empty: "El cluster de la tienda no tiene riesgos asignados.",
+	}
+	for _, r := range d.Assessment.Risks {
+		name := r.Name
+		if r.OverrideReason != "" {
+			name += " *"
+		}
+		t.rows = append(t.rows, []string{
+			name,
+			levelLabel(r.Exposure),
+			levelLabel(r.Sensitivity),
+			levelLabel(r.Probability),
+			levelLabel(r.Consequence),
+			score(r.RiskScore),
+		})
+	}
+	return t
+}
+
+func coverageTable(d *models.ShopDossier) table {
+	t := table{
+		columns: []pdf.Column{
+			{Header: "Riesgo", Width: 0.3},
+			{Header: "Puntuación", Width: 0.12, Align: pdf.AlignRight},
+			{Header: "Cubierto", Width: 0.1},
+			{Header: "Medidas implantadas", Width: 0.48},
+		},
+		empty: "El cluster de la tienda no tiene riesgos asignados.",
+	}
+	for _, r := range d.Coverage.Risks {
+		covered := "No"
+		if r.IsCovered {
+			covered = "Sí"
+		}
+		names := make([]string, len(r.CoveringMeasures))
+		for i, m := range r.CoveringMeasures {
+			names[i] = m.Name
+		}
+		t.rows = append(t.rows, []string{r.RiskName, score(r.RiskScore), covered, list(names)})
+	}
+	return t
+}
+
+func measureTable(d *models.ShopDossier) table {
+	t := table{
+		columns: []pdf.Column{
+			{Header: "Medida", Width: 0.34},
+			{Header: "Tipo", Width: 0.12},
+			{Header: "Estado", Width: 0.14},
+			{Header: "Fin", Width: 0.12},
+			{Header: "Coste estimado", Width: 0.14, Align: pdf.AlignRight},
+			{Header: "Coste real", Width: 0.14, Align: pdf.AlignRight},
+		},
+		empty: "La tienda no tiene medidas aplicadas.",
+	}
+	for _, m := range d.Measures {
+		end, actual := "-", "-"
+		if m.ActualEndDate != nil {
+			end = date(*m.ActualEndDate)
+		} else if m.PlannedEndDate != nil {
+			end = date(*m.PlannedEndDate) + " (prev.)"
+		}
+		if m.ActualCost != nil {
+			actual = amount(*m.ActualCost)
+		}
+		t.rows = append(t.rows, []string{m.Name, string(m.Type), statusLabel(m.Status), end, amount(m.EstimatedCost), actual})
+	}
+	return t
+}
+
+func recommendationTable(d *models.ShopDossier) table {
+	t := table{
+		columns: []pdf.Column{
+			{Header: "Medida", Width: 0.32},
+			{Header: "Tipo", Width: 0.12},
+			{Header: "Riesgos que cubre", Width: 0.4},
+			{Header: "Coste estimado", Width: 0.16, Align: pdf.AlignRight},
+		},
+		empty: "Todos los riesgos de la tienda están cubiertos o tienen medidas en curso.",
+	}
+	for _, m := range d.Recommendations {
+		t.rows = append(t.rows, []string{m.Name, string(m.Type), list(m.Risks), amount(m.EstimatedCost)})
+	}
+	return t
+}
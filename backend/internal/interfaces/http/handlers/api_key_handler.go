@@ -0,0 +1,102 @@
+// Package handlers contiene el handler de gestión de API keys.
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/application/services"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/interfaces/http/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHandler maneja las peticiones de gestión de API keys
+type APIKeyHandler struct {
+	apiKeyService services.APIKeyService
+}
+
+// NewAPIKeyHandler crea una nueva instancia
+func NewAPIKeyHandler(service services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{apiKeyService: service}
+}
+
+// List godoc
+// @Summary Lista las API keys
+// @Description Retorna todas las API keys, incluidas las revocadas, con su último uso. El valor de las claves nunca se muestra.
+// @Tags admin-api-keys
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.APIResponse[[]models.APIKey]
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/api-keys [get]
+// @Security BearerAuth
+func (h *APIKeyHandler) List(c *gin.Context) {
+	keys, err := h.apiKeyService.List(c.Request.Context())
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, keys, "")
+}
+
+// Create godoc
+// @Summary Crea una API key
+// @Description Crea una API key con el rol y, opcionalmente, los permisos y el ámbito de países y clusters indicados. Sin ámbito propio la clave hereda el de quien la crea, y no puede salir de él. El valor de la clave solo se muestra en esta respuesta.
+// @Tags admin-api-keys
+// @Accept json
+// @Produce json
+// @Param request body models.CreateAPIKeyRequest true "Datos de la API key"
+// @Success 201 {object} models.APIResponse[models.APIKeyCreatedResponse]
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/api-keys [post]
+// @Security BearerAuth
+func (h *APIKeyHandler) Create(c *gin.Context) {
+	var req models.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondWithError(c, models.ErrInvalidInput(err.Error()))
+		return
+	}
+
+	createdBy, _ := middleware.GetUserIDFromContext(c)
+	created, err := h.apiKeyService.Create(c.Request.Context(), createdBy, &req)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusCreated, created, "API key creada exitosamente")
+}
+
+// Revoke godoc
+// @Summary Revoca una API key
+// @Description Revoca la API key; las peticiones posteriores con ella se rechazan
+// @Tags admin-api-keys
+// @Accept json
+// @Produce json
+// @Param id path int true "ID de la API key"
+// @Success 200 {object} models.APIResponse[models.APIKey]
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/api-keys/{id} [delete]
+// @Security BearerAuth
+func (h *APIKeyHandler) Revoke(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondWithError(c, models.ErrInvalidID)
+		return
+	}
+
+	key, err := h.apiKeyService.Revoke(c.Request.Context(), id)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, key, "API key revocada exitosamente")
+}
@@ -0,0 +1,156 @@
+// Package handlers contiene el handler del log de auditoría.
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/application/services"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/gin-gonic/gin"
+)
+
+// AuditHandler maneja las consultas del log de auditoría
+type AuditHandler struct {
+	auditService services.AuditService
+}
+
+// NewAuditHandler crea una nueva instancia
+func NewAuditHandler(service services.AuditService) *AuditHandler {
+	return &AuditHandler{auditService: service}
+}
+
+// List godoc
+// @Summary Consulta el log de auditoría
+// @Description Retorna los cambios registrados, del más reciente al más antiguo, filtrados por actor, acción, entidad, petición o fechas
+// @Tags admin-audit
+// @Accept json
+// @Produce json
+// @Param actor_user_id query int false "ID del usuario autor"
+// @Param actor_api_key_id query int false "ID de la API key autora"
+// @Param action query string false "Acción (p. ej. shop.deleted)"
+// @Param entity_type query string false "Tipo de entidad (p. ej. shop)"
+// @Param entity_id query string false "ID de la entidad"
+// @Param request_id query string false "ID de la petición"
+// @Param from query string false "Desde (RFC 3339)"
+// @Param to query string false "Hasta, excluido (RFC 3339)"
+// @Param page query int false "Página" default(1)
+// @Param page_size query int false "Tamaño de página" default(50)
+// @Success 200 {object} models.APIResponse[models.PaginatedResponse[models.AuditEntry]]
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/audit-log [get]
+// @Security BearerAuth
+func (h *AuditHandler) List(c *gin.Context) {
+	var filter models.AuditFilterRequest
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		respondWithError(c, models.ErrInvalidInput(err.Error()))
+		return
+	}
+
+	entries, err := h.auditService.List(c.Request.Context(), &filter)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, entries, "")
+}
+
+// Export godoc
+// @Summary Exporta el log de auditoría
+// @Description Descarga todas las entradas que cumplen los filtros en CSV o JSON. Se ignora la paginación.
+// @Tags admin-audit
+// @Produce text/csv
+// @Produce json
+// @Param format query string false "Formato (csv, json)" default(csv)
+// @Param actor_user_id query int false "ID del usuario autor"
+// @Param actor_api_key_id query int false "ID de la API key autora"
+// @Param action query string false "Acción"
+// @Param entity_type query string false "Tipo de entidad"
+// @Param entity_id query string false "ID de la entidad"
+// @Param request_id query string false "ID de la petición"
+// @Param from query string false "Desde (RFC 3339)"
+// @Param to query string false "Hasta, excluido (RFC 3339)"
+// @Success 200 {file} file
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/audit-log/export [get]
+// @Security BearerAuth
+func (h *AuditHandler) Export(c *gin.Context) {
+	var filter models.AuditFilterRequest
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		respondWithError(c, models.ErrInvalidInput(err.Error()))
+		return
+	}
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "json" {
+		respondWithError(c, models.ErrInvalidInput("Formato no soportado. Use: csv o json"))
+		return
+	}
+
+	entries, err := h.auditService.Export(c.Request.Context(), &filter)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	filename := "audit-log-" + time.Now().UTC().Format("20060102-150405") + "." + format
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+	if format == "json" {
+		if entries == nil {
+			entries = []models.AuditEntry{}
+		}
+		c.JSON(http.StatusOK, entries)
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write([]string{"id", "created_at", "actor_user_id", "actor_api_key_id", "actor_email", "actor_role",
+		"action", "entity_type", "entity_id", "request_id", "before", "after"})
+	for _, e := range entries {
+		_ = w.Write([]string{
+			strconv.FormatInt(e.ID, 10),
+			e.CreatedAt.UTC().Format(time.RFC3339),
+			formatOptionalID(e.ActorUserID),
+			formatOptionalID(e.ActorAPIKeyID),
+			e.ActorEmail,
+			string(e.ActorRole),
+			string(e.Action),
+			string(e.EntityType),
+			e.EntityID,
+			e.RequestID,
+			string(compactJSON(e.Before)),
+			string(compactJSON(e.After)),
+		})
+	}
+	w.Flush()
+}
+
+// formatOptionalID convierte un ID opcional en texto; vacío si no existe
+func formatOptionalID(id *int64) string {
+	if id == nil {
+		return ""
+	}
+	return strconv.FormatInt(*id, 10)
+}
+
+// compactJSON retorna el JSON sin espacios para que quepa en una celda del CSV
+func compactJSON(value json.RawMessage) json.RawMessage {
+	if len(value) == 0 {
+		return nil
+	}
+	compacted, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	return compacted
+}
@@ -2,33 +2,43 @@
 package handlers
 
 import (
-	"context"
+	"crypto/subtle"
 	"net/http"
+	"time"
 
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/application/services"
 	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
-	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/repository"
 	"github.com/d1mo22/climate-invest-optimizer/backend/internal/interfaces/http/middleware"
 	"github.com/gin-gonic/gin"
-	"golang.org/x/crypto/bcrypt"
+)
+
+// ssoStateCookie guarda el state del inicio de sesión único en el navegador que lo
+// inició, para que el callback solo se complete en ese mismo navegador
+const (
+	ssoStateCookie     = "oidc_state"
+	ssoStateCookiePath = "/api/v1/auth/oidc"
 )
 
 // AuthHandler maneja las peticiones de autenticación
 type AuthHandler struct {
-	userRepo   repository.UserRepository
-	jwtService *middleware.JWTService
+	authService services.AuthService
+	ssoService  services.SSOService
 }
 
-// NewAuthHandler crea una nueva instancia de AuthHandler
-func NewAuthHandler(userRepo repository.UserRepository, jwtService *middleware.JWTService) *AuthHandler {
-	return &AuthHandler{
-		userRepo:   userRepo,
-		jwtService: jwtService,
-	}
+// NewAuthHandler crea una nueva instancia de AuthHandler. ssoService puede ser nil
+// si el inicio de sesión único no está configurado.
+func NewAuthHandler(authService services.AuthService, ssoService services.SSOService) *AuthHandler {
+	return &AuthHandler{authService: authService, ssoService: ssoService}
+}
+
+// SSOEnabled indica si el inicio de sesión único con OIDC está disponible
+func (h *AuthHandler) SSOEnabled() bool {
+	return h.ssoService != nil
 }
 
 // Login godoc
 // @Summary Iniciar sesión
-// @Description Autentica un usuario y retorna un token JWT
+// @Description Autentica un usuario, inicia una sesión y retorna un access token JWT y un refresh token
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -45,41 +55,125 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	// Buscar usuario por email
-	user, err := h.userRepo.GetByEmail(c.Request.Context(), req.Email)
+	response, err := h.authService.Login(c.Request.Context(), &req)
 	if err != nil {
-		respondWithError(c, models.ErrDatabase(err))
+		respondWithError(c, err)
 		return
 	}
-	if user == nil {
-		respondWithError(c, models.ErrInvalidCredentials)
+
+	respondWithSuccess(c, http.StatusOK, response, "Inicio de sesión exitoso")
+}
+
+// OIDCLogin godoc
+// @Summary Iniciar sesión con el proveedor de identidad
+// @Description Inicia el flujo authorization code con PKCE de OpenID Connect y redirige al proveedor de identidad
+// @Tags auth
+// @Success 302 "Redirección al proveedor de identidad"
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse "SSO no configurado"
+// @Failure 503 {object} models.ErrorResponse "Demasiados inicios de sesión en curso"
+// @Router /auth/oidc/login [get]
+func (h *AuthHandler) OIDCLogin(c *gin.Context) {
+	if h.ssoService == nil {
+		respondWithError(c, models.ErrSSODisabled)
 		return
 	}
 
-	// Verificar contraseña
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		respondWithError(c, models.ErrInvalidCredentials)
+	redirect, err := h.ssoService.Start(c.Request.Context())
+	if err != nil {
+		respondWithError(c, err)
 		return
 	}
 
-	// Generar token
-	token, expiresAt, err := h.jwtService.GenerateToken(user)
-	if err != nil {
-		respondWithError(c, models.ErrInternal.WithInternal(err))
+	setSSOStateCookie(c, redirect.State, time.Until(redirect.ExpiresAt))
+	c.Redirect(http.StatusFound, redirect.AuthURL)
+}
+
+// OIDCCallback godoc
+// @Summary Completar el inicio de sesión con el proveedor de identidad
+// @Description Canjea el código del proveedor de identidad y retorna los tokens de la sesión del usuario vinculado a la identidad; si no hay ninguno se le da de alta, salvo que ya exista una cuenta con su email (409: debe vincularse desde esa cuenta)
+// @Tags auth
+// @Produce json
+// @Param code query string true "Código de autorización"
+// @Param state query string true "State del inicio de sesión"
+// @Success 200 {object} models.APIResponse[models.AuthResponse]
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse "Usuario desactivado o sin grupos con acceso a la aplicación o a ningún país o cluster"
+// @Failure 404 {object} models.ErrorResponse "SSO no configurado"
+// @Failure 409 {object} models.ErrorResponse "Cuenta existente sin vincular o identidad vinculada a otra cuenta"
+// @Router /auth/oidc/callback [get]
+func (h *AuthHandler) OIDCCallback(c *gin.Context) {
+	if h.ssoService == nil {
+		respondWithError(c, models.ErrSSODisabled)
+		return
+	}
+
+	// El IdP informa de errores (p. ej. acceso denegado por el usuario) en la query
+	if idpError := c.Query("error"); idpError != "" {
+		respondWithError(c, models.ErrSSOFailed)
 		return
 	}
 
-	response := models.AuthResponse{
-		Token:     token,
-		ExpiresAt: expiresAt,
+	// El state debe coincidir con el de la cookie del navegador que inició el flujo
+	cookieState, _ := c.Cookie(ssoStateCookie)
+	setSSOStateCookie(c, "", -time.Second)
+
+	code, state := c.Query("code"), c.Query("state")
+	if code == "" || state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(cookieState)) != 1 {
+		respondWithError(c, models.ErrInvalidSSOState)
+		return
+	}
+
+	response, err := h.ssoService.Complete(c.Request.Context(), state, code)
+	if err != nil {
+		respondWithError(c, err)
+		return
 	}
-	response.User.ID = user.ID
-	response.User.Email = user.Email
-	response.User.Role = user.Role
 
 	respondWithSuccess(c, http.StatusOK, response, "Inicio de sesión exitoso")
 }
 
+// OIDCLink godoc
+// @Summary Vincular la cuenta con el proveedor de identidad
+// @Description Inicia el flujo de OpenID Connect para vincular la identidad del proveedor a la cuenta del usuario autenticado. Retorna la URL del proveedor a la que redirigir; al completar el callback la cuenta queda vinculada y se inicia sesión con ella
+// @Tags auth
+// @Produce json
+// @Success 200 {object} models.APIResponse[models.SSOLinkResponse]
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse "SSO no configurado"
+// @Failure 503 {object} models.ErrorResponse "Demasiados inicios de sesión en curso"
+// @Router /auth/oidc/link [post]
+// @Security BearerAuth
+func (h *AuthHandler) OIDCLink(c *gin.Context) {
+	if h.ssoService == nil {
+		respondWithError(c, models.ErrSSODisabled)
+		return
+	}
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		respondWithError(c, models.ErrUnauthorized)
+		return
+	}
+
+	redirect, err := h.ssoService.StartLink(c.Request.Context(), userID)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	setSSOStateCookie(c, redirect.State, time.Until(redirect.ExpiresAt))
+	respondWithSuccess(c, http.StatusOK, models.SSOLinkResponse{AuthURL: redirect.AuthURL}, "")
+}
+
+// setSSOStateCookie guarda (o la borra, con maxAge negativo) la cookie del state. Es
+// SameSite=Lax para que el navegador la envíe en la redirección del IdP al callback.
+func setSSOStateCookie(c *gin.Context, state string, maxAge time.Duration) {
+	secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(ssoStateCookie, state, int(maxAge.Seconds()), ssoStateCookiePath, "", secure, true)
+}
+
 // Register godoc
 // @Summary Registrar nuevo usuario
 // @Description Crea una nueva cuenta de usuario
@@ -99,98 +193,39 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	// Verificar si el email ya existe
-	exists, err := h.userRepo.EmailExists(c.Request.Context(), req.Email)
-	if err != nil {
-		respondWithError(c, models.ErrDatabase(err))
-		return
-	}
-	if exists {
-		respondWithError(c, models.ErrDuplicateEmail)
-		return
-	}
-
-	// Hash de la contraseña
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
-	if err != nil {
-		respondWithError(c, models.ErrInternal.WithInternal(err))
-		return
-	}
-
-	// Asignar rol por defecto
-	role := req.Role
-	if role == "" {
-		role = models.RoleViewer
-	}
-
-	// Crear usuario
-	user := &models.User{
-		Email:    req.Email,
-		Password: string(hashedPassword),
-		Role:     role,
-	}
-
-	if err := h.userRepo.Create(c.Request.Context(), user); err != nil {
-		respondWithError(c, models.ErrDatabase(err))
-		return
-	}
-
-	// Generar token
-	token, expiresAt, err := h.jwtService.GenerateToken(user)
+	response, err := h.authService.Register(c.Request.Context(), &req)
 	if err != nil {
-		respondWithError(c, models.ErrInternal.WithInternal(err))
+		respondWithError(c, err)
 		return
 	}
 
-	response := models.AuthResponse{
-		Token:     token,
-		ExpiresAt: expiresAt,
-	}
-	response.User.ID = user.ID
-	response.User.Email = user.Email
-	response.User.Role = user.Role
-
 	respondWithSuccess(c, http.StatusCreated, response, "Usuario registrado exitosamente")
 }
 
 // RefreshToken godoc
 // @Summary Refrescar token
-// @Description Genera un nuevo token JWT a partir de uno existente
+// @Description Canjea un refresh token por un nuevo par de tokens. Cada refresh token es de un solo uso; reutilizarlo revoca la sesión.
 // @Tags auth
 // @Accept json
 // @Produce json
-// @Param Authorization header string true "Bearer token"
+// @Param request body models.RefreshTokenRequest true "Refresh token"
 // @Success 200 {object} models.APIResponse[models.AuthResponse]
+// @Failure 400 {object} models.ErrorResponse
 // @Failure 401 {object} models.ErrorResponse
 // @Router /auth/refresh [post]
-// @Security BearerAuth
 func (h *AuthHandler) RefreshToken(c *gin.Context) {
-	// Obtener token del header
-	authHeader := c.GetHeader("Authorization")
-	if len(authHeader) < 8 {
-		respondWithError(c, models.ErrUnauthorized)
+	var req models.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondWithError(c, models.ErrInvalidInput(err.Error()))
 		return
 	}
-	tokenString := authHeader[7:] // Quitar "Bearer "
 
-	// Refrescar token
-	newToken, expiresAt, err := h.jwtService.RefreshToken(tokenString)
+	response, err := h.authService.Refresh(c.Request.Context(), req.RefreshToken)
 	if err != nil {
-		respondWithError(c, models.ErrInvalidToken)
+		respondWithError(c, err)
 		return
 	}
 
-	// Obtener claims para la respuesta
-	claims, _ := h.jwtService.ValidateToken(newToken)
-
-	response := models.AuthResponse{
-		Token:     newToken,
-		ExpiresAt: expiresAt,
-	}
-	response.User.ID = claims.UserID
-	response.User.Email = claims.Email
-	response.User.Role = claims.Role
-
 	respondWithSuccess(c, http.StatusOK, response, "Token refrescado exitosamente")
 }
 
@@ -205,51 +240,54 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 // @Router /auth/me [get]
 // @Security BearerAuth
 func (h *AuthHandler) GetCurrentUser(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
 		respondWithError(c, models.ErrUnauthorized)
 		return
 	}
 
-	user, err := h.userRepo.GetByID(c.Request.Context(), userID.(int64))
+	user, err := h.authService.GetCurrentUser(c.Request.Context(), userID)
 	if err != nil {
-		respondWithError(c, models.ErrDatabase(err))
+		respondWithError(c, err)
 		return
 	}
-	if user == nil {
-		respondWithError(c, models.ErrUserNotFound)
-		return
-	}
-
-	// No exponer password
-	user.Password = ""
 
 	respondWithSuccess(c, http.StatusOK, user, "")
 }
 
 // Logout godoc
 // @Summary Cerrar sesión
-// @Description Invalida el token actual (nota: JWT es stateless, esto es simbólico)
+// @Description Revoca la sesión actual (o todas las del usuario con all_devices); sus access y refresh tokens dejan de ser válidos
 // @Tags auth
 // @Accept json
 // @Produce json
+// @Param request body models.LogoutRequest false "Opciones de cierre de sesión"
 // @Success 200 {object} models.APIResponse[string]
+// @Failure 400 {object} models.ErrorResponse
 // @Failure 401 {object} models.ErrorResponse
 // @Router /auth/logout [post]
 // @Security BearerAuth
 func (h *AuthHandler) Logout(c *gin.Context) {
-	// JWT es stateless, así que no podemos invalidar el token del servidor
-	// En una implementación real, podríamos:
-	// 1. Añadir el token a una blacklist en Redis
-	// 2. Implementar tokens de corta duración con refresh tokens
-	// Por ahora, simplemente respondemos exitosamente
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		respondWithError(c, models.ErrUnauthorized)
+		return
+	}
+	sessionID, _ := middleware.GetSessionIDFromContext(c)
 
-	respondWithSuccess(c, http.StatusOK, "ok", "Sesión cerrada exitosamente")
-}
+	// El cuerpo es opcional
+	var req models.LogoutRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondWithError(c, models.ErrInvalidInput(err.Error()))
+			return
+		}
+	}
 
-// AuthService interface para testing
-type AuthService interface {
-	Login(ctx context.Context, email, password string) (*models.AuthResponse, error)
-	Register(ctx context.Context, req *models.RegisterRequest) (*models.AuthResponse, error)
-	RefreshToken(ctx context.Context, token string) (*models.AuthResponse, error)
+	if err := h.authService.Logout(c.Request.Context(), userID, sessionID, req.AllDevices); err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, "ok", "Sesión cerrada exitosamente")
 }
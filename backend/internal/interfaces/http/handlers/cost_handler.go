@@ -0,0 +1,116 @@
+// Package handlers contiene el handler del coste real de las medidas.
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/application/services"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/gin-gonic/gin"
+)
+
+// CostHandler maneja las facturas y la desviación de costes de las medidas
+type CostHandler struct {
+	costService services.CostService
+}
+
+// NewCostHandler crea una nueva instancia
+func NewCostHandler(service services.CostService) *CostHandler {
+	return &CostHandler{costService: service}
+}
+
+// RecordInvoice godoc
+// @Summary Registra una factura de una medida
+// @Description Registra el coste facturado de una medida de la tienda. El coste real de la medida pasa a ser la suma de sus facturas. La medida debe estar en ejecución o en una fase posterior.
+// @Tags shops
+// @Accept json
+// @Produce json
+// @Param id path int true "ID de la tienda"
+// @Param invoice body models.RecordInvoiceRequest true "Datos de la factura"
+// @Success 201 {object} models.APIResponse[models.MeasureInvoice]
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /shops/{id}/measures/invoices [post]
+// @Security BearerAuth
+func (h *CostHandler) RecordInvoice(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondWithError(c, models.ErrInvalidID)
+		return
+	}
+
+	var req models.RecordInvoiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondWithError(c, models.ErrInvalidInput(err.Error()))
+		return
+	}
+
+	invoice, err := h.costService.RecordInvoice(c.Request.Context(), id, &req)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusCreated, invoice, "Factura registrada")
+}
+
+// ListInvoices godoc
+// @Summary Lista las facturas de las medidas de una tienda
+// @Description Retorna las facturas registradas de las medidas de la tienda, de la más reciente a la más antigua
+// @Tags shops
+// @Accept json
+// @Produce json
+// @Param id path int true "ID de la tienda"
+// @Success 200 {object} models.APIResponse[[]models.MeasureInvoice]
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /shops/{id}/measures/invoices [get]
+// @Security BearerAuth
+func (h *CostHandler) ListInvoices(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondWithError(c, models.ErrInvalidID)
+		return
+	}
+
+	invoices, err := h.costService.ListInvoices(c.Request.Context(), id)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, invoices, "")
+}
+
+// GetVariance godoc
+// @Summary Informe de desviación de costes
+// @Description Compara el coste estimado para cada tienda con el coste real de las medidas completadas con facturas registradas, agrupado por medida, tipo o país
+// @Tags dashboard
+// @Accept json
+// @Produce json
+// @Param group_by query string false "Agrupación (measure, type, country)" default(measure)
+// @Success 200 {object} models.APIResponse[models.CostVarianceReport]
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /dashboard/cost-variance [get]
+// @Security BearerAuth
+func (h *CostHandler) GetVariance(c *gin.Context) {
+	var query models.CostVarianceQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondWithError(c, models.ErrInvalidInput(err.Error()))
+		return
+	}
+
+	report, err := h.costService.GetVarianceReport(c.Request.Context(), query.GroupBy)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, report, "")
+}
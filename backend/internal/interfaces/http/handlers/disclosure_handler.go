@@ -0,0 +1,111 @@
+// Package handlers contiene el handler del informe de divulgación de riesgos físicos.
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/application/services"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/infrastructure/report"
+	"github.com/gin-gonic/gin"
+)
+
+// DisclosureHandler maneja los informes de divulgación de riesgos físicos
+type DisclosureHandler struct {
+	disclosureService services.DisclosureService
+}
+
+// NewDisclosureHandler crea una nueva instancia
+func NewDisclosureHandler(service services.DisclosureService) *DisclosureHandler {
+	return &DisclosureHandler{disclosureService: service}
+}
+
+// Generate godoc
+// @Summary Informe de divulgación de riesgos físicos
+// @Description Genera el informe de riesgos climáticos físicos de la cartera para CSRD (ESRS E1) y TCFD: exposición por riesgo y país, tiendas de alto riesgo, plan de adaptación e inversión, análisis de escenarios y datos utilizados. La inversión facturada, las medidas terminadas y la evolución del riesgo se limitan al periodo. Los datos se leen en un único instante y el informe se guarda bajo su suma de comprobación (data.checksum), con la que se puede volver a consultar con GET /reports/disclosure/{checksum}.
+// @Tags reports
+// @Produce json
+// @Produce text/markdown
+// @Produce application/pdf
+// @Param from query string false "Primer día del periodo (YYYY-MM-DD); por defecto el 1 de enero del año en curso"
+// @Param to query string false "Último día del periodo (YYYY-MM-DD); por defecto hoy"
+// @Param format query string false "Formato (json, markdown, pdf)" default(json)
+// @Success 200 {object} models.APIResponse[models.DisclosureReport]
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /reports/disclosure [post]
+// @Security BearerAuth
+func (h *DisclosureHandler) Generate(c *gin.Context) {
+	var query models.DisclosureReportQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondWithError(c, models.ErrInvalidInput(err.Error()))
+		return
+	}
+
+	disclosure, err := h.disclosureService.Generate(c.Request.Context(), &query)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+	respondWithDisclosure(c, disclosure, query.Format)
+}
+
+// Get godoc
+// @Summary Informe de divulgación guardado
+// @Description Obtiene un informe de divulgación generado antes a partir de su suma de comprobación, tal como se generó. Solo se devuelve si todas sus tiendas están dentro del ámbito del usuario.
+// @Tags reports
+// @Produce json
+// @Produce text/markdown
+// @Produce application/pdf
+// @Param checksum path string true "Suma de comprobación del informe (data.checksum)"
+// @Param format query string false "Formato (json, markdown, pdf)" default(json)
+// @Success 200 {object} models.APIResponse[models.DisclosureReport]
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /reports/disclosure/{checksum} [get]
+// @Security BearerAuth
+func (h *DisclosureHandler) Get(c *gin.Context) {
+	var query models.DisclosureFormatQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondWithError(c, models.ErrInvalidInput(err.Error()))
+		return
+	}
+
+	disclosure, err := h.disclosureService.Get(c.Request.Context(), c.Param("checksum"))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+	respondWithDisclosure(c, disclosure, query.Format)
+}
+
+// respondWithDisclosure responde con el informe en el formato indicado
+func respondWithDisclosure(c *gin.Context, disclosure *models.DisclosureReport, format string) {
+	if format == "json" {
+		respondWithSuccess(c, http.StatusOK, disclosure, "")
+		return
+	}
+
+	var buf bytes.Buffer
+	var err error
+	contentType, extension := "text/markdown; charset=utf-8", "md"
+	if format == "pdf" {
+		contentType, extension = "application/pdf", "pdf"
+		err = report.WriteDisclosurePDF(&buf, disclosure)
+	} else {
+		err = report.WriteDisclosureMarkdown(&buf, disclosure)
+	}
+	if err != nil {
+		respondWithError(c, models.ErrInternal)
+		return
+	}
+
+	filename := "disclosure-" + disclosure.Period.Start.Format("20060102") + "-" + disclosure.Period.End.Format("20060102") + "." + extension
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	// La cabecera JSON de los middlewares prevalece sobre la de c.Data
+	c.Header("Content-Type", contentType)
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
@@ -6,7 +6,9 @@ import (
 	"strconv"
 
 	"github.com/d1mo22/climate-invest-optimizer/backend/internal/application/services"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/authz"
 	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/interfaces/http/middleware"
 	"github.com/gin-gonic/gin"
 )
 
@@ -108,6 +110,89 @@ func (h *ClusterHandler) GetByID(c *gin.Context) {
 	respondWithSuccess(c, http.StatusOK, cluster, "")
 }
 
+// GetRiskProjections godoc
+// @Summary Obtiene las proyecciones de riesgo de un cluster
+// @Description Retorna los niveles de riesgo proyectados del cluster para un escenario climático
+// @Tags clusters
+// @Accept json
+// @Produce json
+// @Param id path int true "ID del cluster"
+// @Param scenario query string true "Escenario climático (ssp1-2.6, ssp2-4.5, ssp5-8.5)"
+// @Success 200 {object} models.APIResponse[[]models.ClusterRiskProjection]
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /clusters/{id}/risk-projections [get]
+// @Security BearerAuth
+func (h *ClusterHandler) GetRiskProjections(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondWithError(c, models.ErrInvalidID)
+		return
+	}
+
+	var query models.RiskScenarioQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondWithError(c, models.ErrInvalidInput(err.Error()))
+		return
+	}
+	sel := models.ScenarioSelection{Scenario: models.ClimateScenario(query.Scenario)}
+	if sel.IsCurrent() {
+		respondWithError(c, models.ErrInvalidInput("Debe indicar un escenario climático"))
+		return
+	}
+
+	projections, err := h.clusterService.GetRiskProjections(c.Request.Context(), id, sel.Scenario)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, projections, "")
+}
+
+// SetRiskProjection godoc
+// @Summary Carga la proyección de un riesgo de un cluster
+// @Description Crea o reemplaza los niveles proyectados de un riesgo para un escenario y horizonte
+// @Tags clusters
+// @Accept json
+// @Produce json
+// @Param id path int true "ID del cluster"
+// @Param riskId path int true "ID del riesgo"
+// @Param projection body models.SetRiskProjectionRequest true "Niveles proyectados"
+// @Success 200 {object} models.APIResponse[models.ClusterRiskProjection]
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/clusters/{id}/risk-projections/{riskId} [put]
+// @Security BearerAuth
+func (h *ClusterHandler) SetRiskProjection(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondWithError(c, models.ErrInvalidID)
+		return
+	}
+	riskID, err := strconv.ParseInt(c.Param("riskId"), 10, 64)
+	if err != nil {
+		respondWithError(c, models.ErrInvalidID)
+		return
+	}
+
+	var req models.SetRiskProjectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondWithError(c, models.ErrInvalidInput(err.Error()))
+		return
+	}
+
+	projection, err := h.clusterService.SetRiskProjection(c.Request.Context(), id, riskID, &req)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, projection, "Proyección de riesgo guardada exitosamente")
+}
+
 // MeasureHandler maneja las peticiones de medidas
 type MeasureHandler struct {
 	measureService services.MeasureService
@@ -125,17 +210,29 @@ func NewMeasureHandler(service services.MeasureService) *MeasureHandler {
 // @Accept json
 // @Produce json
 // @Param type query string false "Filtrar por tipo (natural, material, immaterial)"
+// @Param include_deleted query bool false "Incluir medidas eliminadas (solo administradores)"
 // @Success 200 {object} models.APIResponse[[]models.Measure]
+// @Failure 403 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /measures [get]
 // @Security BearerAuth
 func (h *MeasureHandler) List(c *gin.Context) {
 	measureType := c.Query("type")
+	includeDeleted := c.Query("include_deleted") == "true"
+	if includeDeleted && !middleware.HasPermission(c, authz.CatalogWrite) {
+		respondWithError(c, models.ErrForbidden)
+		return
+	}
 	
 	var measures []models.Measure
 	var err error
 
-	if measureType != "" {
+	if includeDeleted {
+		measures, err = h.measureService.ListIncludingDeleted(c.Request.Context())
+		if err == nil && measureType != "" {
+			measures = filterMeasuresByType(measures, models.MeasureType(measureType))
+		}
+	} else if measureType != "" {
 		measures, err = h.measureService.GetByType(c.Request.Context(), models.MeasureType(measureType))
 	} else {
 		measures, err = h.measureService.List(c.Request.Context())
@@ -177,6 +274,71 @@ func (h *MeasureHandler) GetByName(c *gin.Context) {
 	respondWithSuccess(c, http.StatusOK, measure, "")
 }
 
+// Delete godoc
+// @Summary Elimina una medida del catálogo
+// @Description Elimina una medida. Deja de ofrecerse y de contar en las tiendas, cuyo riesgo se recalcula sin ella; sus relaciones se conservan y puede restaurarse. Si falla el recálculo la medida queda eliminada y recalculation_failed lo indica.
+// @Tags admin-measures
+// @Accept json
+// @Produce json
+// @Param name path string true "Nombre de la medida"
+// @Success 200 {object} models.APIResponse[models.MeasureChangeResponse]
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/measures/{name} [delete]
+// @Security BearerAuth
+func (h *MeasureHandler) Delete(c *gin.Context) {
+	result, err := h.measureService.Delete(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, result, measureChangeMessage(result, "Medida eliminada exitosamente"))
+}
+
+// Restore godoc
+// @Summary Restaura una medida eliminada
+// @Description Devuelve al catálogo una medida eliminada, con sus relaciones con tiendas y riesgos, y recalcula el riesgo de las tiendas donde está aplicada. Si falla el recálculo la medida queda restaurada y recalculation_failed lo indica.
+// @Tags admin-measures
+// @Accept json
+// @Produce json
+// @Param name path string true "Nombre de la medida"
+// @Success 200 {object} models.APIResponse[models.MeasureChangeResponse]
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/measures/{name}/restore [post]
+// @Security BearerAuth
+func (h *MeasureHandler) Restore(c *gin.Context) {
+	result, err := h.measureService.Restore(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, result, measureChangeMessage(result, "Medida restaurada exitosamente"))
+}
+
+// measureChangeMessage retorna el mensaje de un cambio de medida, avisando si no se
+// pudo recalcular el riesgo de todas sus tiendas
+func measureChangeMessage(result *models.MeasureChangeResponse, message string) string {
+	if result.RecalculationFailed {
+		return message + ", pero no se pudo recalcular el riesgo de todas las tiendas donde está aplicada"
+	}
+	return message
+}
+
+// filterMeasuresByType retorna las medidas del tipo indicado
+func filterMeasuresByType(measures []models.Measure, measureType models.MeasureType) []models.Measure {
+	filtered := make([]models.Measure, 0, len(measures))
+	for _, m := range measures {
+		if m.Type == measureType {
+			filtered = append(filtered, m)
+		}
+	}
+	return filtered
+}
+
 // GetApplicableForShop godoc
 // @Summary Obtiene medidas aplicables a una tienda
 // @Description Retorna medidas que aún no han sido aplicadas a una tienda específica
@@ -335,16 +497,28 @@ func NewDashboardHandler(service services.DashboardService) *DashboardHandler {
 
 // GetStats godoc
 // @Summary Obtiene estadísticas del dashboard
-// @Description Retorna métricas generales para el dashboard principal
+// @Description Retorna métricas generales para el dashboard principal y las series de inversión y riesgo medio por periodo, leídas de una misma foto de la base de datos. Los niveles de riesgo usan los umbrales del scoring vigente. El periodo limita las medidas, la inversión y las series. Las medidas aplicadas y la cobertura solo cuentan las medidas completadas o verificadas; la inversión incluye también las pendientes.
 // @Tags dashboard
 // @Accept json
 // @Produce json
+// @Param cluster_id query int false "Filtrar por cluster"
+// @Param country query string false "Filtrar por país"
+// @Param from query string false "Inicio del periodo (YYYY-MM-DD)"
+// @Param to query string false "Fin del periodo, incluido (YYYY-MM-DD)"
+// @Param interval query string false "Agrupación de las series (day, week, month)" default(month)
 // @Success 200 {object} models.APIResponse[models.DashboardStats]
+// @Failure 400 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /dashboard/stats [get]
 // @Security BearerAuth
 func (h *DashboardHandler) GetStats(c *gin.Context) {
-	stats, err := h.dashboardService.GetStats(c.Request.Context())
+	var query models.DashboardStatsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondWithError(c, models.ErrInvalidInput(err.Error()))
+		return
+	}
+
+	stats, err := h.dashboardService.GetStats(c.Request.Context(), &query)
 	if err != nil {
 		respondWithError(c, err)
 		return
@@ -353,6 +527,109 @@ func (h *DashboardHandler) GetStats(c *gin.Context) {
 	respondWithSuccess(c, http.StatusOK, stats, "")
 }
 
+// GetRiskTrend godoc
+// @Summary Obtiene la evolución del riesgo
+// @Description Retorna el riesgo medio de las tiendas por periodo a partir de su historial de snapshots
+// @Tags dashboard
+// @Accept json
+// @Produce json
+// @Param interval query string false "Agrupación temporal (day, week, month)" default(month)
+// @Param since query string false "Fecha de inicio (YYYY-MM-DD), por defecto hace un año"
+// @Success 200 {object} models.APIResponse[[]models.RiskTrendPoint]
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /dashboard/risk-trend [get]
+// @Security BearerAuth
+func (h *DashboardHandler) GetRiskTrend(c *gin.Context) {
+	var query models.RiskTrendQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondWithError(c, models.ErrInvalidInput(err.Error()))
+		return
+	}
+
+	trend, err := h.dashboardService.GetRiskTrend(c.Request.Context(), &query)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, trend, "")
+}
+
+// GetAnalytics godoc
+// @Summary Obtiene la analítica de la cartera
+// @Description Agrega el histograma de riesgo, el riesgo medio y máximo, la cobertura de riesgos y la inversión de las tiendas por país, cluster, riesgo o tipo de medida. Por riesgo se usa el score de cada riesgo en el último snapshot de las tiendas expuestas.
+// @Tags dashboard
+// @Accept json
+// @Produce json
+// @Param group_by query string false "Agrupación (country, cluster, risk, measure_type)" default(country)
+// @Param bins query int false "Intervalos del histograma de riesgo (2-20)" default(10)
+// @Param cluster_id query int false "Filtrar por cluster"
+// @Param country query string false "Filtrar por país"
+// @Param min_risk query number false "Riesgo mínimo"
+// @Param max_risk query number false "Riesgo máximo"
+// @Param min_surface query number false "Superficie mínima"
+// @Param max_surface query number false "Superficie máxima"
+// @Param q query string false "Búsqueda por localización"
+// @Success 200 {object} models.APIResponse[models.PortfolioAnalytics]
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /dashboard/analytics [get]
+// @Security BearerAuth
+func (h *DashboardHandler) GetAnalytics(c *gin.Context) {
+	var query models.PortfolioAnalyticsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondWithError(c, models.ErrInvalidInput(err.Error()))
+		return
+	}
+
+	analytics, err := h.dashboardService.GetAnalytics(c.Request.Context(), &query)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, analytics, "")
+}
+
+// GetRiskMatrix godoc
+// @Summary Obtiene la matriz de probabilidad y consecuencia
+// @Description Cuenta los pares tienda-riesgo de cada celda de la matriz 5x5 de probabilidad y consecuencia, con los niveles del cluster (inherent) o tras las medidas completadas (residual): las naturales bajan un nivel la probabilidad y las materiales e inmateriales la consecuencia. Con probability o consequence se detallan las tiendas de las celdas correspondientes.
+// @Tags dashboard
+// @Accept json
+// @Produce json
+// @Param stage query string false "Niveles antes (inherent) o después (residual) de las medidas" default(residual)
+// @Param probability query string false "Detallar las celdas de esta probabilidad (very_low, low, medium, high, very_high)"
+// @Param consequence query string false "Detallar las celdas de esta consecuencia (very_low, low, medium, high, very_high)"
+// @Param risk_id query int false "Filtrar por riesgo"
+// @Param cluster_id query int false "Filtrar por cluster"
+// @Param country query string false "Filtrar por país"
+// @Param min_risk query number false "Riesgo mínimo"
+// @Param max_risk query number false "Riesgo máximo"
+// @Param min_surface query number false "Superficie mínima"
+// @Param max_surface query number false "Superficie máxima"
+// @Param q query string false "Búsqueda por localización"
+// @Success 200 {object} models.APIResponse[models.RiskMatrix]
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /dashboard/risk-matrix [get]
+// @Security BearerAuth
+func (h *DashboardHandler) GetRiskMatrix(c *gin.Context) {
+	var query models.RiskMatrixQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondWithError(c, models.ErrInvalidInput(err.Error()))
+		return
+	}
+
+	matrix, err := h.dashboardService.GetRiskMatrix(c.Request.Context(), &query)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, matrix, "")
+}
+
 // HealthHandler maneja las peticiones de salud del sistema
 type HealthHandler struct{}
 
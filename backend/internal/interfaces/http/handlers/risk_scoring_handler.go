@@ -0,0 +1,148 @@
+// Package handlers contiene el handler de configuración del scoring de riesgos.
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/application/services"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/gin-gonic/gin"
+)
+
+// RiskScoringHandler maneja las peticiones de configuración del modelo de scoring
+type RiskScoringHandler struct {
+	scoringService services.RiskScoringService
+}
+
+// NewRiskScoringHandler crea una nueva instancia
+func NewRiskScoringHandler(service services.RiskScoringService) *RiskScoringHandler {
+	return &RiskScoringHandler{scoringService: service}
+}
+
+// List godoc
+// @Summary Lista las configuraciones de scoring
+// @Description Retorna todas las versiones de la configuración del modelo de scoring de riesgos
+// @Tags risk-scoring
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.APIResponse[[]models.RiskScoringConfig]
+// @Failure 500 {object} models.ErrorResponse
+// @Router /risk-scoring/configs [get]
+// @Security BearerAuth
+func (h *RiskScoringHandler) List(c *gin.Context) {
+	configs, err := h.scoringService.ListConfigs(c.Request.Context())
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, configs, "")
+}
+
+// GetActive godoc
+// @Summary Obtiene la configuración de scoring activa
+// @Description Retorna la configuración vigente (versión 0 si se usa la configuración por defecto)
+// @Tags risk-scoring
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.APIResponse[models.RiskScoringConfig]
+// @Failure 500 {object} models.ErrorResponse
+// @Router /risk-scoring/configs/active [get]
+// @Security BearerAuth
+func (h *RiskScoringHandler) GetActive(c *gin.Context) {
+	cfg, err := h.scoringService.GetActiveConfig(c.Request.Context())
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, cfg, "")
+}
+
+// GetByVersion godoc
+// @Summary Obtiene una configuración de scoring
+// @Description Retorna una versión concreta de la configuración de scoring
+// @Tags risk-scoring
+// @Accept json
+// @Produce json
+// @Param version path int true "Versión de la configuración"
+// @Success 200 {object} models.APIResponse[models.RiskScoringConfig]
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /risk-scoring/configs/{version} [get]
+// @Security BearerAuth
+func (h *RiskScoringHandler) GetByVersion(c *gin.Context) {
+	version, err := strconv.ParseInt(c.Param("version"), 10, 64)
+	if err != nil {
+		respondWithError(c, models.ErrInvalidID)
+		return
+	}
+
+	cfg, err := h.scoringService.GetConfig(c.Request.Context(), version)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, cfg, "")
+}
+
+// Create godoc
+// @Summary Crea una nueva versión de scoring
+// @Description Crea una configuración de scoring (metodología, valores de nivel, pesos y umbrales)
+// @Tags risk-scoring
+// @Accept json
+// @Produce json
+// @Param config body models.CreateRiskScoringConfigRequest true "Configuración de scoring"
+// @Success 201 {object} models.APIResponse[models.RiskScoringConfig]
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /risk-scoring/configs [post]
+// @Security BearerAuth
+func (h *RiskScoringHandler) Create(c *gin.Context) {
+	var req models.CreateRiskScoringConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondWithError(c, models.ErrInvalidInput(err.Error()))
+		return
+	}
+
+	cfg, err := h.scoringService.CreateConfig(c.Request.Context(), &req)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusCreated, cfg, "Configuración de scoring creada exitosamente")
+}
+
+// Activate godoc
+// @Summary Activa una versión de scoring
+// @Description Establece la versión indicada como modelo de scoring vigente y recalcula con ella el riesgo de todas las tiendas
+// @Tags risk-scoring
+// @Accept json
+// @Produce json
+// @Param version path int true "Versión de la configuración"
+// @Success 200 {object} models.APIResponse[models.RiskScoringConfig]
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /risk-scoring/configs/{version}/activate [post]
+// @Security BearerAuth
+func (h *RiskScoringHandler) Activate(c *gin.Context) {
+	version, err := strconv.ParseInt(c.Param("version"), 10, 64)
+	if err != nil {
+		respondWithError(c, models.ErrInvalidID)
+		return
+	}
+
+	cfg, err := h.scoringService.ActivateConfig(c.Request.Context(), version)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, cfg, "Configuración de scoring activada")
+}
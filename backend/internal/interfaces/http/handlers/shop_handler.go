@@ -2,13 +2,17 @@
 package handlers
 
 import (
+	"bytes"
 	"log"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"github.com/d1mo22/climate-invest-optimizer/backend/internal/application/services"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/authz"
 	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/infrastructure/report"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/interfaces/http/middleware"
 	"github.com/gin-gonic/gin"
 )
 
@@ -31,11 +35,14 @@ func NewShopHandler(service services.ShopService) *ShopHandler {
 // @Param page query int false "Número de página" default(1)
 // @Param page_size query int false "Tamaño de página" default(20)
 // @Param cluster_id query int false "Filtrar por cluster"
+// @Param country query string false "Filtrar por país"
 // @Param min_risk query number false "Riesgo mínimo"
 // @Param max_risk query number false "Riesgo máximo"
 // @Param q query string false "Búsqueda por localización"
+// @Param include_deleted query bool false "Incluir tiendas eliminadas (solo administradores)"
 // @Success 200 {object} models.APIResponse[models.PaginatedResponse[models.ShopResponse]]
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /shops [get]
 // @Security BearerAuth
@@ -45,6 +52,10 @@ func (h *ShopHandler) List(c *gin.Context) {
 		respondWithError(c, models.ErrInvalidInput(err.Error()))
 		return
 	}
+	if filter.IncludeDeleted && !middleware.HasPermission(c, authz.ShopsWrite) {
+		respondWithError(c, models.ErrForbidden)
+		return
+	}
 
 	result, err := h.shopService.List(c.Request.Context(), &filter)
 	if err != nil {
@@ -115,7 +126,7 @@ func (h *ShopHandler) Create(c *gin.Context) {
 
 // Update godoc
 // @Summary Actualiza una tienda
-// @Description Actualiza parcialmente una tienda existente
+// @Description Actualiza parcialmente una tienda existente. Si cambia su cluster, su país o su superficie se recalculan su riesgo y su cobertura de la Taxonomía.
 // @Tags shops
 // @Accept json
 // @Produce json
@@ -151,7 +162,7 @@ func (h *ShopHandler) Update(c *gin.Context) {
 
 // Delete godoc
 // @Summary Elimina una tienda
-// @Description Elimina una tienda y sus medidas asociadas
+// @Description Elimina una tienda. Sus medidas, ajustes de riesgo e historial se conservan y puede restaurarse.
 // @Tags shops
 // @Accept json
 // @Produce json
@@ -177,6 +188,36 @@ func (h *ShopHandler) Delete(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// Restore godoc
+// @Summary Restaura una tienda eliminada
+// @Description Devuelve a su estado activo una tienda eliminada, con sus medidas, ajustes de riesgo e historial
+// @Tags admin-shops
+// @Accept json
+// @Produce json
+// @Param id path int true "ID de la tienda"
+// @Success 200 {object} models.APIResponse[models.Shop]
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/shops/{id}/restore [post]
+// @Security BearerAuth
+func (h *ShopHandler) Restore(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondWithError(c, models.ErrInvalidID)
+		return
+	}
+
+	shop, err := h.shopService.Restore(c.Request.Context(), id)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, shop, "Tienda restaurada exitosamente")
+}
+
 // ApplyMeasures godoc
 // @Summary Aplica medidas a una tienda
 // @Description Aplica una o más medidas preventivas a una tienda
@@ -214,7 +255,7 @@ func (h *ShopHandler) ApplyMeasures(c *gin.Context) {
 
 // RemoveMeasure godoc
 // @Summary Elimina una medida de una tienda
-// @Description Elimina una medida previamente aplicada a una tienda
+// @Description Elimina una medida previamente aplicada a una tienda. Las medidas con facturas registradas no se pueden eliminar.
 // @Tags shops
 // @Accept json
 // @Produce json
@@ -223,6 +264,7 @@ func (h *ShopHandler) ApplyMeasures(c *gin.Context) {
 // @Success 204 "Sin contenido"
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /shops/{id}/measures/{measureName} [delete]
 // @Security BearerAuth
@@ -249,13 +291,52 @@ func (h *ShopHandler) RemoveMeasure(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// TransitionMeasure godoc
+// @Summary Avanza una medida en su ciclo de vida
+// @Description Pasa una medida de la tienda a la siguiente fase (planned → approved → in_progress → completed → verified) y registra fechas, coste real y notas. El coste real de las medidas con facturas es la suma de sus facturas y no se puede indicar. Solo las medidas completadas cuentan en la cobertura de riesgos.
+// @Tags shops
+// @Accept json
+// @Produce json
+// @Param id path int true "ID de la tienda"
+// @Param transition body models.MeasureTransitionRequest true "Nuevo estado y datos de implantación"
+// @Success 200 {object} models.APIResponse[models.ShopMeasure]
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /shops/{id}/measures/transition [post]
+// @Security BearerAuth
+func (h *ShopHandler) TransitionMeasure(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondWithError(c, models.ErrInvalidID)
+		return
+	}
+
+	var req models.MeasureTransitionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondWithError(c, models.ErrInvalidInput(err.Error()))
+		return
+	}
+
+	measure, err := h.shopService.TransitionMeasure(c.Request.Context(), id, &req)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, measure, "Estado de la medida actualizado")
+}
+
 // GetRiskAssessment godoc
 // @Summary Obtiene evaluación de riesgos
-// @Description Retorna la evaluación de riesgos climáticos de una tienda
+// @Description Retorna la evaluación de riesgos climáticos de una tienda, actual o proyectada a un escenario
 // @Tags shops
 // @Accept json
 // @Produce json
 // @Param id path int true "ID de la tienda"
+// @Param scenario query string false "Escenario climático (current, ssp1-2.6, ssp2-4.5, ssp5-8.5)"
+// @Param horizon query int false "Horizonte temporal (2030, 2050)"
 // @Success 200 {object} models.APIResponse[models.RiskAssessmentResponse]
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
@@ -269,7 +350,16 @@ func (h *ShopHandler) GetRiskAssessment(c *gin.Context) {
 		return
 	}
 
-	assessment, err := h.shopService.GetRiskAssessment(c.Request.Context(), id)
+	var query models.RiskScenarioQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondWithError(c, models.ErrInvalidInput(err.Error()))
+		return
+	}
+
+	assessment, err := h.shopService.GetRiskAssessment(c.Request.Context(), id, models.ScenarioSelection{
+		Scenario: models.ClimateScenario(query.Scenario),
+		Horizon:  query.Horizon,
+	})
 	if err != nil {
 		respondWithError(c, err)
 		return
@@ -280,12 +370,12 @@ func (h *ShopHandler) GetRiskAssessment(c *gin.Context) {
 
 // GetAppliedMeasures godoc
 // @Summary Obtiene medidas aplicadas a una tienda
-// @Description Retorna todas las medidas que ya han sido aplicadas a una tienda
+// @Description Retorna todas las medidas aplicadas a una tienda con su estado de implantación, fechas y coste real
 // @Tags shops
 // @Accept json
 // @Produce json
 // @Param id path int true "ID de la tienda"
-// @Success 200 {object} models.APIResponse[[]models.Measure]
+// @Success 200 {object} models.APIResponse[[]models.ShopMeasure]
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
@@ -364,3 +454,181 @@ func (h *ShopHandler) GetRiskCoverage(c *gin.Context) {
 
 	respondWithSuccess(c, http.StatusOK, coverage, "")
 }
+
+// GetReportPDF godoc
+// @Summary Ficha de riesgo e inversión de una tienda en PDF
+// @Description Genera una ficha imprimible con los datos de la tienda, la puntuación de cada riesgo, la cobertura, las medidas aplicadas, las próximas medidas recomendadas y un mapa de situación entre las tiendas de su cluster
+// @Tags shops
+// @Produce application/pdf
+// @Param id path int true "ID de la tienda"
+// @Success 200 {file} file
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /shops/{id}/report.pdf [get]
+// @Security BearerAuth
+func (h *ShopHandler) GetReportPDF(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondWithError(c, models.ErrInvalidID)
+		return
+	}
+
+	dossier, err := h.shopService.GetDossier(c.Request.Context(), id)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteShopDossierPDF(&buf, dossier); err != nil {
+		respondWithError(c, models.ErrInternal)
+		return
+	}
+
+	c.Header("Content-Disposition", `inline; filename="shop-`+strconv.FormatInt(id, 10)+`.pdf"`)
+	// La cabecera JSON de los middlewares prevalece sobre la de c.Data
+	c.Header("Content-Type", "application/pdf")
+	c.Data(http.StatusOK, "application/pdf", buf.Bytes())
+}
+
+// GetRiskHistory godoc
+// @Summary Obtiene el historial de riesgo de una tienda
+// @Description Retorna los snapshots de riesgo guardados en cada recálculo, del más reciente al más antiguo
+// @Tags shops
+// @Accept json
+// @Produce json
+// @Param id path int true "ID de la tienda"
+// @Param limit query int false "Número máximo de snapshots" default(50)
+// @Success 200 {object} models.APIResponse[[]models.RiskSnapshot]
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /shops/{id}/risk-history [get]
+// @Security BearerAuth
+func (h *ShopHandler) GetRiskHistory(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondWithError(c, models.ErrInvalidID)
+		return
+	}
+
+	var query models.RiskHistoryQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondWithError(c, models.ErrInvalidInput(err.Error()))
+		return
+	}
+
+	history, err := h.shopService.GetRiskHistory(c.Request.Context(), id, query.Limit)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, history, "")
+}
+
+// GetRiskOverrides godoc
+// @Summary Obtiene los ajustes de riesgo de una tienda
+// @Description Retorna los ajustes de exposición/sensibilidad que la tienda aplica sobre los riesgos de su cluster
+// @Tags shops
+// @Accept json
+// @Produce json
+// @Param id path int true "ID de la tienda"
+// @Success 200 {object} models.APIResponse[[]models.ShopRiskOverride]
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /shops/{id}/risk-overrides [get]
+// @Security BearerAuth
+func (h *ShopHandler) GetRiskOverrides(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondWithError(c, models.ErrInvalidID)
+		return
+	}
+
+	overrides, err := h.shopService.GetRiskOverrides(c.Request.Context(), id)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, overrides, "")
+}
+
+// SetRiskOverride godoc
+// @Summary Ajusta un riesgo de una tienda
+// @Description Crea o reemplaza el ajuste de exposición y/o sensibilidad de la tienda para un riesgo de su cluster
+// @Tags shops
+// @Accept json
+// @Produce json
+// @Param id path int true "ID de la tienda"
+// @Param riskId path int true "ID del riesgo"
+// @Param override body models.SetShopRiskOverrideRequest true "Ajuste del riesgo"
+// @Success 200 {object} models.APIResponse[models.ShopRiskOverride]
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /shops/{id}/risk-overrides/{riskId} [put]
+// @Security BearerAuth
+func (h *ShopHandler) SetRiskOverride(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondWithError(c, models.ErrInvalidID)
+		return
+	}
+	riskID, err := strconv.ParseInt(c.Param("riskId"), 10, 64)
+	if err != nil {
+		respondWithError(c, models.ErrInvalidID)
+		return
+	}
+
+	var req models.SetShopRiskOverrideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondWithError(c, models.ErrInvalidInput(err.Error()))
+		return
+	}
+
+	override, err := h.shopService.SetRiskOverride(c.Request.Context(), id, riskID, &req)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, override, "Ajuste de riesgo guardado exitosamente")
+}
+
+// RemoveRiskOverride godoc
+// @Summary Elimina el ajuste de un riesgo de una tienda
+// @Description La tienda vuelve a heredar los valores del cluster para el riesgo indicado
+// @Tags shops
+// @Accept json
+// @Produce json
+// @Param id path int true "ID de la tienda"
+// @Param riskId path int true "ID del riesgo"
+// @Success 204 "Sin contenido"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /shops/{id}/risk-overrides/{riskId} [delete]
+// @Security BearerAuth
+func (h *ShopHandler) RemoveRiskOverride(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondWithError(c, models.ErrInvalidID)
+		return
+	}
+	riskID, err := strconv.ParseInt(c.Param("riskId"), 10, 64)
+	if err != nil {
+		respondWithError(c, models.ErrInvalidID)
+		return
+	}
+
+	if err := h.shopService.RemoveRiskOverride(c.Request.Context(), id, riskID); err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,155 @@
+// Package handlers contiene el handler de la Taxonomía de la UE.
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/application/services"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/gin-gonic/gin"
+)
+
+// TaxonomyHandler maneja la correspondencia de las medidas con la Taxonomía de la UE y
+// los informes de alineación
+type TaxonomyHandler struct {
+	taxonomyService services.TaxonomyService
+}
+
+// NewTaxonomyHandler crea una nueva instancia
+func NewTaxonomyHandler(service services.TaxonomyService) *TaxonomyHandler {
+	return &TaxonomyHandler{taxonomyService: service}
+}
+
+// ListActivities godoc
+// @Summary Lista las actividades de la Taxonomía
+// @Description Retorna las actividades del anexo de adaptación de la Taxonomía de la UE a las que pueden asignarse las medidas
+// @Tags taxonomy
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.APIResponse[[]models.TaxonomyActivity]
+// @Router /taxonomy/activities [get]
+// @Security BearerAuth
+func (h *TaxonomyHandler) ListActivities(c *gin.Context) {
+	respondWithSuccess(c, http.StatusOK, h.taxonomyService.ListActivities(), "")
+}
+
+// ListMappings godoc
+// @Summary Lista la correspondencia de las medidas con la Taxonomía
+// @Description Retorna la actividad de la Taxonomía y el cumplimiento de los criterios DNSH de cada medida que los tiene asignados
+// @Tags taxonomy
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.APIResponse[[]models.MeasureTaxonomy]
+// @Failure 500 {object} models.ErrorResponse
+// @Router /taxonomy/measures [get]
+// @Security BearerAuth
+func (h *TaxonomyHandler) ListMappings(c *gin.Context) {
+	mappings, err := h.taxonomyService.ListMappings(c.Request.Context())
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, mappings, "")
+}
+
+// SetMapping godoc
+// @Summary Asigna una medida a una actividad de la Taxonomía
+// @Description Crea o reemplaza la actividad de la Taxonomía de una medida y el cumplimiento de sus criterios DNSH (no causar un perjuicio significativo) y recalcula la cobertura de la Taxonomía guardada de las tiendas que tienen aplicada la medida
+// @Tags admin-taxonomy
+// @Accept json
+// @Produce json
+// @Param name path string true "Nombre de la medida"
+// @Param mapping body models.SetMeasureTaxonomyRequest true "Actividad y criterios DNSH"
+// @Success 200 {object} models.APIResponse[models.MeasureTaxonomy]
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/taxonomy/measures/{name} [put]
+// @Security BearerAuth
+func (h *TaxonomyHandler) SetMapping(c *gin.Context) {
+	var req models.SetMeasureTaxonomyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondWithError(c, models.ErrInvalidInput(err.Error()))
+		return
+	}
+
+	mapping, err := h.taxonomyService.SetMapping(c.Request.Context(), c.Param("name"), &req)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, mapping, "Correspondencia con la Taxonomía actualizada")
+}
+
+// DeleteMapping godoc
+// @Summary Elimina la correspondencia de una medida con la Taxonomía
+// @Description La medida deja de ser elegible para la Taxonomía y se recalcula la cobertura de la Taxonomía guardada de las tiendas que la tienen aplicada
+// @Tags admin-taxonomy
+// @Accept json
+// @Produce json
+// @Param name path string true "Nombre de la medida"
+// @Success 204 "Sin contenido"
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/taxonomy/measures/{name} [delete]
+// @Security BearerAuth
+func (h *TaxonomyHandler) DeleteMapping(c *gin.Context) {
+	if err := h.taxonomyService.DeleteMapping(c.Request.Context(), c.Param("name")); err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetShopReport godoc
+// @Summary Informe de alineación de una tienda con la Taxonomía
+// @Description Evalúa los riesgos físicos materiales de la tienda y si las medidas implantadas, elegibles y conformes con los criterios DNSH, los reducen
+// @Tags shops
+// @Accept json
+// @Produce json
+// @Param id path int true "ID de la tienda"
+// @Success 200 {object} models.APIResponse[models.ShopTaxonomyReport]
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /shops/{id}/taxonomy [get]
+// @Security BearerAuth
+func (h *TaxonomyHandler) GetShopReport(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondWithError(c, models.ErrInvalidID)
+		return
+	}
+
+	report, err := h.taxonomyService.GetShopReport(c.Request.Context(), id)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, report, "")
+}
+
+// GetPortfolioReport godoc
+// @Summary Informe de alineación de la cartera con la Taxonomía
+// @Description Agrega la alineación con la Taxonomía de todas las tiendas: tiendas por estado, alineación media y CapEx alineado
+// @Tags dashboard
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.APIResponse[models.TaxonomyPortfolioReport]
+// @Failure 500 {object} models.ErrorResponse
+// @Router /dashboard/taxonomy [get]
+// @Security BearerAuth
+func (h *TaxonomyHandler) GetPortfolioReport(c *gin.Context) {
+	report, err := h.taxonomyService.GetPortfolioReport(c.Request.Context())
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, report, "")
+}
@@ -0,0 +1,246 @@
+// Package handlers contiene el handler de administración de usuarios.
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/application/services"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/interfaces/http/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// UserHandler maneja las peticiones de administración de usuarios
+type UserHandler struct {
+	userService services.UserService
+}
+
+// NewUserHandler crea una nueva instancia
+func NewUserHandler(service services.UserService) *UserHandler {
+	return &UserHandler{userService: service}
+}
+
+// List godoc
+// @Summary Lista los usuarios
+// @Description Retorna los usuarios del sistema, opcionalmente filtrados por rol, estado o email
+// @Tags admin-users
+// @Accept json
+// @Produce json
+// @Param role query string false "Rol (admin, manager, viewer)"
+// @Param active query bool false "Estado de la cuenta"
+// @Param q query string false "Búsqueda por email"
+// @Success 200 {object} models.APIResponse[[]models.User]
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/users [get]
+// @Security BearerAuth
+func (h *UserHandler) List(c *gin.Context) {
+	var filter models.UserFilterRequest
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		respondWithError(c, models.ErrInvalidInput(err.Error()))
+		return
+	}
+
+	users, err := h.userService.List(c.Request.Context(), &filter)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, users, "")
+}
+
+// Invite godoc
+// @Summary Da de alta un usuario
+// @Description Crea un usuario con el rol indicado y retorna una contraseña temporal que solo se muestra una vez
+// @Tags admin-users
+// @Accept json
+// @Produce json
+// @Param user body models.InviteUserRequest true "Datos del usuario"
+// @Success 201 {object} models.APIResponse[models.UserCredentialsResponse]
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse "Email ya registrado"
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/users [post]
+// @Security BearerAuth
+func (h *UserHandler) Invite(c *gin.Context) {
+	var req models.InviteUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondWithError(c, models.ErrInvalidInput(err.Error()))
+		return
+	}
+
+	credentials, err := h.userService.Invite(c.Request.Context(), &req)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusCreated, credentials, "Usuario creado exitosamente")
+}
+
+// ChangeRole godoc
+// @Summary Cambia el rol de un usuario
+// @Description Asigna un nuevo rol y cierra las sesiones activas del usuario
+// @Tags admin-users
+// @Accept json
+// @Produce json
+// @Param id path int true "ID del usuario"
+// @Param request body models.ChangeUserRoleRequest true "Nuevo rol"
+// @Success 200 {object} models.APIResponse[models.User]
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/users/{id}/role [patch]
+// @Security BearerAuth
+func (h *UserHandler) ChangeRole(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondWithError(c, models.ErrInvalidID)
+		return
+	}
+
+	var req models.ChangeUserRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondWithError(c, models.ErrInvalidInput(err.Error()))
+		return
+	}
+
+	actorID, _ := middleware.GetUserIDFromContext(c)
+	user, err := h.userService.ChangeRole(c.Request.Context(), actorID, id, req.Role)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, user, "Rol actualizado exitosamente")
+}
+
+// SetScope godoc
+// @Summary Limita el ámbito de un usuario
+// @Description Restringe el usuario a las tiendas de los países y/o clusters indicados y cierra sus sesiones activas. Las listas vacías dan acceso global.
+// @Tags admin-users
+// @Accept json
+// @Produce json
+// @Param id path int true "ID del usuario"
+// @Param request body models.SetUserScopeRequest true "Países y clusters permitidos"
+// @Success 200 {object} models.APIResponse[models.User]
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/users/{id}/scope [put]
+// @Security BearerAuth
+func (h *UserHandler) SetScope(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondWithError(c, models.ErrInvalidID)
+		return
+	}
+
+	var req models.SetUserScopeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondWithError(c, models.ErrInvalidInput(err.Error()))
+		return
+	}
+
+	user, err := h.userService.SetScope(c.Request.Context(), id, &req)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, user, "Ámbito actualizado exitosamente")
+}
+
+// Deactivate godoc
+// @Summary Desactiva un usuario
+// @Description Impide el inicio de sesión del usuario y cierra sus sesiones activas
+// @Tags admin-users
+// @Accept json
+// @Produce json
+// @Param id path int true "ID del usuario"
+// @Success 200 {object} models.APIResponse[models.User]
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 422 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/users/{id}/deactivate [post]
+// @Security BearerAuth
+func (h *UserHandler) Deactivate(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondWithError(c, models.ErrInvalidID)
+		return
+	}
+
+	actorID, _ := middleware.GetUserIDFromContext(c)
+	user, err := h.userService.Deactivate(c.Request.Context(), actorID, id)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, user, "Usuario desactivado exitosamente")
+}
+
+// Reactivate godoc
+// @Summary Reactiva un usuario
+// @Description Vuelve a permitir el inicio de sesión de un usuario desactivado
+// @Tags admin-users
+// @Accept json
+// @Produce json
+// @Param id path int true "ID del usuario"
+// @Success 200 {object} models.APIResponse[models.User]
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/users/{id}/reactivate [post]
+// @Security BearerAuth
+func (h *UserHandler) Reactivate(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondWithError(c, models.ErrInvalidID)
+		return
+	}
+
+	user, err := h.userService.Reactivate(c.Request.Context(), id)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, user, "Usuario reactivado exitosamente")
+}
+
+// ResetPassword godoc
+// @Summary Restablece la contraseña de un usuario
+// @Description Genera una contraseña temporal (solo se muestra una vez) y cierra las sesiones del usuario
+// @Tags admin-users
+// @Accept json
+// @Produce json
+// @Param id path int true "ID del usuario"
+// @Success 200 {object} models.APIResponse[models.UserCredentialsResponse]
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /admin/users/{id}/reset-password [post]
+// @Security BearerAuth
+func (h *UserHandler) ResetPassword(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondWithError(c, models.ErrInvalidID)
+		return
+	}
+
+	credentials, err := h.userService.ResetPassword(c.Request.Context(), id)
+	if err != nil {
+		respondWithError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, credentials, "Contraseña restablecida exitosamente")
+}
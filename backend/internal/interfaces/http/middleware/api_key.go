@@ -0,0 +1,111 @@
+// Package middleware contiene la autenticación por API key.
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/audit"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/authz"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHeader es el header con el que las integraciones envían su API key
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyAuthenticator valida una API key en claro y retorna la clave registrada
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, key string) (*models.APIKey, error)
+}
+
+// apiKeyLimiter limita las peticiones por minuto de cada API key según su RateLimit
+type apiKeyLimiter struct {
+	mu      sync.Mutex
+	windows map[int64]*apiKeyWindow
+}
+
+type apiKeyWindow struct {
+	count     int
+	resetTime time.Time
+}
+
+func newAPIKeyLimiter() *apiKeyLimiter {
+	return &apiKeyLimiter{windows: make(map[int64]*apiKeyWindow)}
+}
+
+// allow registra una petición de la clave y retorna si está dentro de su límite y
+// cuántas peticiones le quedan en la ventana actual
+func (l *apiKeyLimiter) allow(key *models.APIKey, now time.Time) (bool, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	window, exists := l.windows[key.ID]
+	if !exists || now.After(window.resetTime) {
+		window = &apiKeyWindow{resetTime: now.Add(time.Minute)}
+		l.windows[key.ID] = window
+	}
+	window.count++
+
+	if window.count > key.RateLimit {
+		return false, 0
+	}
+	return true, key.RateLimit - window.count
+}
+
+// authenticateAPIKey autentica la petición con una API key y aplica su límite de peticiones
+func authenticateAPIKey(c *gin.Context, apiKeys APIKeyAuthenticator, limiter *apiKeyLimiter, value string) {
+	key, err := apiKeys.Authenticate(c.Request.Context(), value)
+	if err != nil {
+		appErr, ok := err.(*models.AppError)
+		if !ok {
+			appErr = models.ErrInternal
+		}
+		c.JSON(appErr.HTTPStatus, appErr.ToResponse())
+		c.Abort()
+		return
+	}
+
+	allowed, remaining := limiter.allow(key, time.Now())
+	c.Header("X-RateLimit-Limit", strconv.Itoa(key.RateLimit))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	if !allowed {
+		c.Header("Retry-After", "60")
+		c.JSON(http.StatusTooManyRequests, models.ErrorResponse{
+			Error: models.ErrorDetail{
+				Code:    "RATE_LIMIT_EXCEEDED",
+				Message: "La API key ha superado su límite de peticiones. Intente de nuevo en un minuto.",
+			},
+		})
+		c.Abort()
+		return
+	}
+
+	permissions := make([]authz.Permission, len(key.Permissions))
+	for i, p := range key.Permissions {
+		permissions[i] = authz.Permission(p)
+	}
+
+	// Las API keys no son usuarios: solo aportan rol, permisos y ámbito de datos
+	c.Set("api_key_id", key.ID)
+	c.Set("api_key_permissions", permissions)
+	c.Set("user_role", key.Role)
+	ctx := authz.WithScope(c.Request.Context(), authz.APIKeyScope(key))
+	ctx = audit.WithActor(ctx, audit.Actor{APIKeyID: key.ID, Role: key.Role})
+	c.Request = c.Request.WithContext(ctx)
+
+	c.Next()
+}
+
+// GetAPIKeyIDFromContext obtiene el ID de la API key con la que se autenticó la petición
+func GetAPIKeyIDFromContext(c *gin.Context) (int64, bool) {
+	apiKeyID, exists := c.Get("api_key_id")
+	if !exists {
+		return 0, false
+	}
+	id, ok := apiKeyID.(int64)
+	return id, ok
+}
@@ -2,11 +2,14 @@
 package middleware
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/audit"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/authz"
 	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
@@ -22,30 +25,49 @@ type JWTConfig struct {
 
 // JWTClaims representa los claims del token JWT
 type JWTClaims struct {
-	UserID int64           `json:"user_id"`
-	Email  string          `json:"email"`
-	Role   models.UserRole `json:"role"`
+	UserID    int64           `json:"user_id"`
+	Email     string          `json:"email"`
+	Role      models.UserRole `json:"role"`
+	SessionID int64           `json:"sid"`
+	// Ámbito de datos del usuario (vacío = global)
+	Countries  []string `json:"countries,omitempty"`
+	ClusterIDs []int64  `json:"cluster_ids,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// RevocationList permite consultar si la sesión de un token ha sido revocada
+type RevocationList interface {
+	IsSessionRevoked(ctx context.Context, sessionID int64) (bool, error)
+}
+
 // JWTService maneja la generación y validación de tokens JWT
 type JWTService struct {
-	config JWTConfig
+	config      JWTConfig
+	revocations RevocationList
 }
 
-// NewJWTService crea una nueva instancia de JWTService
-func NewJWTService(config JWTConfig) *JWTService {
-	return &JWTService{config: config}
+// NewJWTService crea una nueva instancia de JWTService. Si revocations es nil no se
+// comprueba la revocación de sesiones.
+func NewJWTService(config JWTConfig, revocations RevocationList) *JWTService {
+	return &JWTService{config: config, revocations: revocations}
 }
 
-// GenerateToken genera un nuevo token JWT
-func (s *JWTService) GenerateToken(user *models.User) (string, int64, error) {
+// RefreshExpiry retorna la duración de los refresh tokens
+func (s *JWTService) RefreshExpiry() time.Duration {
+	return s.config.RefreshExpiry
+}
+
+// GenerateToken genera un nuevo access token JWT para la sesión indicada
+func (s *JWTService) GenerateToken(user *models.User, sessionID int64) (string, int64, error) {
 	expiresAt := time.Now().Add(s.config.TokenExpiry)
 
 	claims := JWTClaims{
-		UserID: user.ID,
-		Email:  user.Email,
-		Role:   user.Role,
+		UserID:     user.ID,
+		Email:      user.Email,
+		Role:       user.Role,
+		SessionID:  sessionID,
+		Countries:  user.Countries,
+		ClusterIDs: user.ClusterIDs,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -86,26 +108,32 @@ func (s *JWTService) ValidateToken(tokenString string) (*JWTClaims, error) {
 	return claims, nil
 }
 
-// RefreshToken genera un nuevo token a partir de uno existente (antes de que expire)
-func (s *JWTService) RefreshToken(tokenString string) (string, int64, error) {
-	claims, err := s.ValidateToken(tokenString)
-	if err != nil {
-		return "", 0, err
+// IsRevoked indica si la sesión a la que pertenece el token ha sido revocada
+func (s *JWTService) IsRevoked(ctx context.Context, claims *JWTClaims) (bool, error) {
+	if s.revocations == nil {
+		return false, nil
 	}
-
-	// Crear usuario temporal para generar nuevo token
-	user := &models.User{
-		ID:    claims.UserID,
-		Email: claims.Email,
-		Role:  claims.Role,
+	// Los tokens sin sesión no se pueden revocar, por lo que no se aceptan
+	if claims.SessionID == 0 {
+		return true, nil
 	}
-
-	return s.GenerateToken(user)
+	return s.revocations.IsSessionRevoked(ctx, claims.SessionID)
 }
 
-// AuthMiddleware verifica que la petición tenga un token JWT válido
-func AuthMiddleware(jwtService *JWTService) gin.HandlerFunc {
+// AuthMiddleware verifica que la petición tenga un token JWT válido o, si apiKeys
+// no es nil, una API key válida en el header X-API-Key
+func AuthMiddleware(jwtService *JWTService, apiKeys APIKeyAuthenticator) gin.HandlerFunc {
+	limiter := newAPIKeyLimiter()
+
 	return func(c *gin.Context) {
+		// Integraciones máquina a máquina
+		if apiKeys != nil {
+			if value := c.GetHeader(APIKeyHeader); value != "" {
+				authenticateAPIKey(c, apiKeys, limiter, value)
+				return
+			}
+		}
+
 		// Obtener el header Authorization
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -147,16 +175,51 @@ func AuthMiddleware(jwtService *JWTService) gin.HandlerFunc {
 			return
 		}
 
+		// Verificar que la sesión no haya sido cerrada
+		revoked, err := jwtService.IsRevoked(c.Request.Context(), claims)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+				Error: models.ErrorDetail{
+					Code:    "INTERNAL_ERROR",
+					Message: "Error interno del servidor",
+				},
+			})
+			c.Abort()
+			return
+		}
+		if revoked {
+			c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+				Error: models.ErrorDetail{
+					Code:    "TOKEN_REVOKED",
+					Message: "La sesión ha sido cerrada",
+				},
+			})
+			c.Abort()
+			return
+		}
+
 		// Guardar claims en el contexto para uso posterior
 		c.Set("user_id", claims.UserID)
 		c.Set("user_email", claims.Email)
 		c.Set("user_role", claims.Role)
+		c.Set("session_id", claims.SessionID)
 		c.Set("claims", claims)
 
+		// Los repositorios filtran los datos según el ámbito del contexto y los
+		// servicios registran al usuario como autor de sus cambios
+		ctx := authz.WithScope(c.Request.Context(), claims.Scope())
+		ctx = audit.WithActor(ctx, audit.Actor{UserID: claims.UserID, Email: claims.Email, Role: claims.Role})
+		c.Request = c.Request.WithContext(ctx)
+
 		c.Next()
 	}
 }
 
+// Scope retorna el ámbito de datos del token
+func (c *JWTClaims) Scope() authz.Scope {
+	return authz.Scope{Countries: c.Countries, ClusterIDs: c.ClusterIDs}
+}
+
 // RequireRole middleware que verifica que el usuario tenga uno de los roles requeridos
 func RequireRole(roles ...models.UserRole) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -190,6 +253,57 @@ func RequireRole(roles ...models.UserRole) gin.HandlerFunc {
 	}
 }
 
+// RequirePermission middleware que verifica que el rol del usuario tenga el permiso
+// indicado según la matriz de authz
+func RequirePermission(perm authz.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, ok := GetUserRoleFromContext(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+				Error: models.ErrorDetail{
+					Code:    "UNAUTHORIZED",
+					Message: "No autenticado",
+				},
+			})
+			c.Abort()
+			return
+		}
+
+		if !hasPermission(c, role, perm) {
+			c.JSON(http.StatusForbidden, models.ErrorResponse{
+				Error: models.ErrorDetail{
+					Code:    "FORBIDDEN",
+					Message: "No tiene permisos para realizar esta acción",
+				},
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// HasPermission indica si el usuario o la API key autenticados tienen el permiso
+// indicado. Permite a los handlers restringir opciones concretas de una ruta.
+func HasPermission(c *gin.Context, perm authz.Permission) bool {
+	role, ok := GetUserRoleFromContext(c)
+	if !ok {
+		return false
+	}
+	return hasPermission(c, role, perm)
+}
+
+// hasPermission comprueba el permiso del rol, limitado a los permisos de la API key si la hay
+func hasPermission(c *gin.Context, role models.UserRole, perm authz.Permission) bool {
+	if _, isAPIKey := GetAPIKeyIDFromContext(c); isAPIKey {
+		restrict, _ := c.Get("api_key_permissions")
+		perms, _ := restrict.([]authz.Permission)
+		return authz.AllowedForAPIKey(role, perms, perm)
+	}
+	return authz.Allowed(role, perm)
+}
+
 // OptionalAuth middleware que intenta extraer el usuario pero no falla si no hay token
 func OptionalAuth(jwtService *JWTService) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -210,13 +324,21 @@ func OptionalAuth(jwtService *JWTService) gin.HandlerFunc {
 			c.Next()
 			return
 		}
+		if revoked, err := jwtService.IsRevoked(c.Request.Context(), claims); err != nil || revoked {
+			c.Next()
+			return
+		}
 
 		// Token válido - guardar en contexto
 		c.Set("user_id", claims.UserID)
 		c.Set("user_email", claims.Email)
 		c.Set("user_role", claims.Role)
+		c.Set("session_id", claims.SessionID)
 		c.Set("claims", claims)
 		c.Set("authenticated", true)
+		ctx := authz.WithScope(c.Request.Context(), claims.Scope())
+		ctx = audit.WithActor(ctx, audit.Actor{UserID: claims.UserID, Email: claims.Email, Role: claims.Role})
+		c.Request = c.Request.WithContext(ctx)
 
 		c.Next()
 	}
@@ -232,6 +354,16 @@ func GetUserIDFromContext(c *gin.Context) (int64, bool) {
 	return id, ok
 }
 
+// GetSessionIDFromContext obtiene el ID de la sesión del token del contexto
+func GetSessionIDFromContext(c *gin.Context) (int64, bool) {
+	sessionID, exists := c.Get("session_id")
+	if !exists {
+		return 0, false
+	}
+	id, ok := sessionID.(int64)
+	return id, ok
+}
+
 // GetUserRoleFromContext obtiene el rol del usuario del contexto
 func GetUserRoleFromContext(c *gin.Context) (models.UserRole, bool) {
 	role, exists := c.Get("user_role")
@@ -5,8 +5,10 @@ import (
 	"log"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/audit"
 	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
 	"github.com/gin-gonic/gin"
 )
@@ -55,6 +57,8 @@ func RequestID() gin.HandlerFunc {
 
 		c.Set("request_id", requestID)
 		c.Header("X-Request-ID", requestID)
+		// Los servicios lo registran en auditoría a través del contexto
+		c.Request = c.Request.WithContext(audit.WithRequestID(c.Request.Context(), requestID))
 		c.Next()
 	}
 }
@@ -100,7 +104,9 @@ func Logger() gin.HandlerFunc {
 	}
 }
 
-// RateLimiter implementa un rate limiter básico por IP
+// RateLimiter implementa un rate limiter básico por IP. Todas las peticiones cuentan
+// para el límite de su IP; las que AuthMiddleware autentica con una API key válida
+// se descuentan al terminar, ya que esas claves tienen su propio límite.
 func RateLimiter(requestsPerMinute int) gin.HandlerFunc {
 	// Mapa simple para almacenar contadores por IP
 	// En producción usar Redis o similar
@@ -108,34 +114,45 @@ func RateLimiter(requestsPerMinute int) gin.HandlerFunc {
 		count     int
 		resetTime time.Time
 	}
+	var mu sync.Mutex
 	limits := make(map[string]*rateLimitEntry)
 
 	return func(c *gin.Context) {
 		clientIP := c.ClientIP()
 		now := time.Now()
 
+		mu.Lock()
 		entry, exists := limits[clientIP]
 		if !exists || now.After(entry.resetTime) {
-			limits[clientIP] = &rateLimitEntry{
-				count:     1,
-				resetTime: now.Add(time.Minute),
-			}
-		} else {
-			entry.count++
-			if entry.count > requestsPerMinute {
-				c.Header("Retry-After", "60")
-				c.JSON(http.StatusTooManyRequests, models.ErrorResponse{
-					Error: models.ErrorDetail{
-						Code:    "RATE_LIMIT_EXCEEDED",
-						Message: "Demasiadas solicitudes. Intente de nuevo en un minuto.",
-					},
-				})
-				c.Abort()
-				return
-			}
+			entry = &rateLimitEntry{resetTime: now.Add(time.Minute)}
+			limits[clientIP] = entry
+		}
+		entry.count++
+		exceeded := entry.count > requestsPerMinute
+		mu.Unlock()
+
+		if exceeded {
+			c.Header("Retry-After", "60")
+			c.JSON(http.StatusTooManyRequests, models.ErrorResponse{
+				Error: models.ErrorDetail{
+					Code:    "RATE_LIMIT_EXCEEDED",
+					Message: "Demasiadas solicitudes. Intente de nuevo en un minuto.",
+				},
+			})
+			c.Abort()
+			return
 		}
 
 		c.Next()
+
+		// Las rutas públicas y las claves rechazadas siguen contando para la IP
+		if _, ok := GetAPIKeyIDFromContext(c); ok {
+			mu.Lock()
+			if entry.count > 0 {
+				entry.count--
+			}
+			mu.Unlock()
+		}
 	}
 }
 
@@ -2,6 +2,7 @@
 package router
 
 import (
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/authz"
 	"github.com/d1mo22/climate-invest-optimizer/backend/internal/interfaces/http/handlers"
 	"github.com/d1mo22/climate-invest-optimizer/backend/internal/interfaces/http/middleware"
 	"github.com/gin-gonic/gin"
@@ -15,9 +16,17 @@ type Config struct {
 	RiskHandler         *handlers.RiskHandler
 	OptimizationHandler *handlers.OptimizationHandler
 	DashboardHandler    *handlers.DashboardHandler
+	RiskScoringHandler  *handlers.RiskScoringHandler
 	AuthHandler         *handlers.AuthHandler
+	UserHandler         *handlers.UserHandler
+	APIKeyHandler       *handlers.APIKeyHandler
+	AuditHandler        *handlers.AuditHandler
+	CostHandler         *handlers.CostHandler
+	TaxonomyHandler     *handlers.TaxonomyHandler
+	DisclosureHandler   *handlers.DisclosureHandler
 	HealthHandler       *handlers.HealthHandler
 	JWTService          *middleware.JWTService
+	APIKeys             middleware.APIKeyAuthenticator // nil desactiva la autenticación por API key
 	AllowedOrigins      []string
 }
 
@@ -37,9 +46,15 @@ func Setup(r *gin.Engine, cfg *Config) {
 	// API v1
 	v1 := r.Group("/api/v1")
 	{
-		// Rate limiting para toda la API
+		// Rate limiting para toda la API (las peticiones con API key válida tienen su propio límite)
 		v1.Use(middleware.RateLimiter(100)) // 100 requests por minuto
 
+		// Una sola instancia para que el límite de cada API key sea común a todas las rutas
+		var authenticate gin.HandlerFunc
+		if cfg.JWTService != nil {
+			authenticate = middleware.AuthMiddleware(cfg.JWTService, cfg.APIKeys)
+		}
+
 		// Rutas públicas (sin autenticación)
 		public := v1.Group("")
 		{
@@ -48,103 +63,193 @@ func Setup(r *gin.Engine, cfg *Config) {
 				public.POST("/auth/login", cfg.AuthHandler.Login)
 				public.POST("/auth/register", cfg.AuthHandler.Register)
 				public.POST("/auth/refresh", cfg.AuthHandler.RefreshToken)
+				if cfg.AuthHandler.SSOEnabled() {
+					public.GET("/auth/oidc/login", cfg.AuthHandler.OIDCLogin)
+					public.GET("/auth/oidc/callback", cfg.AuthHandler.OIDCCallback)
+				}
 			}
 
 			// Health
 			public.GET("/health", cfg.HealthHandler.Check)
 		}
 
-		// Rutas protegidas (requieren autenticación)
+		// Rutas protegidas (requieren autenticación). Cada ruta declara el permiso
+		// que necesita; la asignación de permisos a roles está en authz.
+		can := middleware.RequirePermission
 		protected := v1.Group("")
-		if cfg.JWTService != nil {
-			protected.Use(middleware.AuthMiddleware(cfg.JWTService))
+		if authenticate != nil {
+			protected.Use(authenticate)
 		}
 		{
 			// ==================== SHOPS ====================
 			shops := protected.Group("/shops")
 			{
-				shops.GET("", cfg.ShopHandler.List)
-				shops.GET("/:id", cfg.ShopHandler.GetByID)
-				shops.POST("", cfg.ShopHandler.Create)
-				shops.PATCH("/:id", cfg.ShopHandler.Update)
-				shops.DELETE("/:id", cfg.ShopHandler.Delete)
+				shops.GET("", can(authz.ShopsRead), cfg.ShopHandler.List)
+				shops.GET("/:id", can(authz.ShopsRead), cfg.ShopHandler.GetByID)
+				shops.POST("", can(authz.ShopsWrite), cfg.ShopHandler.Create)
+				shops.PATCH("/:id", can(authz.ShopsWrite), cfg.ShopHandler.Update)
+				shops.DELETE("/:id", can(authz.ShopsWrite), cfg.ShopHandler.Delete)
 
 				// Medidas de una tienda
-				shops.GET("/:id/measures", cfg.ShopHandler.GetAppliedMeasures)
-				shops.POST("/:id/measures", cfg.ShopHandler.ApplyMeasures)
+				shops.GET("/:id/measures", can(authz.ShopsRead), cfg.ShopHandler.GetAppliedMeasures)
+				shops.POST("/:id/measures", can(authz.ShopMeasuresWrite), cfg.ShopHandler.ApplyMeasures)
+				shops.POST("/:id/measures/transition", can(authz.ShopMeasuresWrite), cfg.ShopHandler.TransitionMeasure)
+				shops.GET("/:id/measures/invoices", can(authz.ShopsRead), cfg.CostHandler.ListInvoices)
+				shops.POST("/:id/measures/invoices", can(authz.ShopMeasuresWrite), cfg.CostHandler.RecordInvoice)
 				// Soportar nombres con '/' (p.ej. "costera/fluvial/pluvial").
 				// Nota: Gin no permite coexistir '*measureName' con ':measureName' en el mismo prefijo.
-				shops.DELETE("/:id/measures/*measureName", cfg.ShopHandler.RemoveMeasure)
+				shops.DELETE("/:id/measures/*measureName", can(authz.ShopMeasuresWrite), cfg.ShopHandler.RemoveMeasure)
 
 				// Evaluación de riesgos
-				shops.GET("/:id/risk-assessment", cfg.ShopHandler.GetRiskAssessment)
+				shops.GET("/:id/risk-assessment", can(authz.ShopsRead), cfg.ShopHandler.GetRiskAssessment)
 
 				// Cobertura de riesgos
-				shops.GET("/:id/risk-coverage", cfg.ShopHandler.GetRiskCoverage)
+				shops.GET("/:id/risk-coverage", can(authz.ShopsRead), cfg.ShopHandler.GetRiskCoverage)
+
+				// Ficha imprimible de la tienda
+				shops.GET("/:id/report.pdf", can(authz.ShopsRead), cfg.ShopHandler.GetReportPDF)
+
+				// Alineación con la Taxonomía de la UE
+				shops.GET("/:id/taxonomy", can(authz.ShopsRead), cfg.TaxonomyHandler.GetShopReport)
+
+				// Ajustes de riesgo propios de la tienda
+				shops.GET("/:id/risk-history", can(authz.ShopsRead), cfg.ShopHandler.GetRiskHistory)
+				shops.GET("/:id/risk-overrides", can(authz.ShopsRead), cfg.ShopHandler.GetRiskOverrides)
+				shops.PUT("/:id/risk-overrides/:riskId", can(authz.ShopRisksWrite), cfg.ShopHandler.SetRiskOverride)
+				shops.DELETE("/:id/risk-overrides/:riskId", can(authz.ShopRisksWrite), cfg.ShopHandler.RemoveRiskOverride)
 
 				// Medidas aplicables
-				shops.GET("/:id/applicable-measures", cfg.MeasureHandler.GetApplicableForShop)
+				shops.GET("/:id/applicable-measures", can(authz.ShopsRead), cfg.MeasureHandler.GetApplicableForShop)
 			}
 
 			// ==================== CLUSTERS ====================
 			clusters := protected.Group("/clusters")
 			{
-				clusters.GET("", cfg.ClusterHandler.List)
-				clusters.GET("/:id", cfg.ClusterHandler.GetByID)
+				clusters.GET("", can(authz.CatalogRead), cfg.ClusterHandler.List)
+				clusters.GET("/:id", can(authz.CatalogRead), cfg.ClusterHandler.GetByID)
 
 				// Tiendas de un cluster
-				clusters.GET("/:clusterId/shops", cfg.ShopHandler.GetByCluster)
+				clusters.GET("/:id/shops", can(authz.ShopsRead), cfg.ShopHandler.GetByCluster)
 
 				// Riesgos de un cluster
-				clusters.GET("/:clusterId/risks", cfg.RiskHandler.GetByCluster)
+				clusters.GET("/:id/risks", can(authz.CatalogRead), cfg.RiskHandler.GetByCluster)
+
+				// Proyecciones de riesgo por escenario climático
+				clusters.GET("/:id/risk-projections", can(authz.CatalogRead), cfg.ClusterHandler.GetRiskProjections)
 			}
 
 			// ==================== MEASURES ====================
 			measures := protected.Group("/measures")
 			{
-				measures.GET("", cfg.MeasureHandler.List)
-				measures.GET("/:name", cfg.MeasureHandler.GetByName)
+				measures.GET("", can(authz.CatalogRead), cfg.MeasureHandler.List)
+				measures.GET("/:name", can(authz.CatalogRead), cfg.MeasureHandler.GetByName)
 			}
 
 			// ==================== RISKS ====================
 			risks := protected.Group("/risks")
 			{
-				risks.GET("", cfg.RiskHandler.List)
-				risks.GET("/:id", cfg.RiskHandler.GetByID)
-				risks.GET("/:id/measures", cfg.MeasureHandler.GetByRisk)
+				risks.GET("", can(authz.CatalogRead), cfg.RiskHandler.List)
+				risks.GET("/:id", can(authz.CatalogRead), cfg.RiskHandler.GetByID)
+				risks.GET("/:id/measures", can(authz.CatalogRead), cfg.MeasureHandler.GetByRisk)
+			}
+
+			// ==================== TAXONOMY ====================
+			taxonomy := protected.Group("/taxonomy")
+			{
+				taxonomy.GET("/activities", can(authz.CatalogRead), cfg.TaxonomyHandler.ListActivities)
+				taxonomy.GET("/measures", can(authz.CatalogRead), cfg.TaxonomyHandler.ListMappings)
+			}
+
+			// ==================== RISK SCORING ====================
+			scoring := protected.Group("/risk-scoring")
+			{
+				scoring.GET("/configs", can(authz.CatalogRead), cfg.RiskScoringHandler.List)
+				scoring.GET("/configs/active", can(authz.CatalogRead), cfg.RiskScoringHandler.GetActive)
+				scoring.GET("/configs/:version", can(authz.CatalogRead), cfg.RiskScoringHandler.GetByVersion)
 			}
 
 			// ==================== OPTIMIZATION ====================
 			optimization := protected.Group("/optimization")
 			{
-				optimization.POST("/budget", cfg.OptimizationHandler.OptimizeBudget)
+				optimization.POST("/budget", can(authz.OptimizationRun), cfg.OptimizationHandler.OptimizeBudget)
 			}
 
 			// ==================== DASHBOARD ====================
 			dashboard := protected.Group("/dashboard")
 			{
-				dashboard.GET("/stats", cfg.DashboardHandler.GetStats)
+				dashboard.GET("/stats", can(authz.DashboardRead), cfg.DashboardHandler.GetStats)
+				dashboard.GET("/risk-trend", can(authz.DashboardRead), cfg.DashboardHandler.GetRiskTrend)
+				dashboard.GET("/analytics", can(authz.DashboardRead), cfg.DashboardHandler.GetAnalytics)
+				dashboard.GET("/risk-matrix", can(authz.DashboardRead), cfg.DashboardHandler.GetRiskMatrix)
+				dashboard.GET("/cost-variance", can(authz.DashboardRead), cfg.CostHandler.GetVariance)
+				dashboard.GET("/taxonomy", can(authz.DashboardRead), cfg.TaxonomyHandler.GetPortfolioReport)
+			}
+
+			// ==================== REPORTS ====================
+			reports := protected.Group("/reports")
+			{
+				reports.POST("/disclosure", can(authz.ReportsGenerate), cfg.DisclosureHandler.Generate)
+				reports.GET("/disclosure/:checksum", can(authz.DashboardRead), cfg.DisclosureHandler.Get)
 			}
 
 			// ==================== AUTH (protegidas) ====================
+			// Disponibles para cualquier usuario autenticado sobre su propia cuenta
 			if cfg.AuthHandler != nil {
 				auth := protected.Group("/auth")
 				{
 					auth.GET("/me", cfg.AuthHandler.GetCurrentUser)
 					auth.POST("/logout", cfg.AuthHandler.Logout)
+					if cfg.AuthHandler.SSOEnabled() {
+						auth.POST("/oidc/link", cfg.AuthHandler.OIDCLink)
+					}
 				}
 			}
 		}
 
 		// Rutas de administrador
-		if cfg.JWTService != nil {
+		if authenticate != nil {
 			admin := v1.Group("/admin")
-			admin.Use(middleware.AuthMiddleware(cfg.JWTService))
-			admin.Use(middleware.RequireRole("admin"))
+			admin.Use(authenticate)
 			{
-				// Rutas administrativas futuras
-				// admin.GET("/users", ...)
-				// admin.DELETE("/users/:id", ...)
+				// Modelo de scoring de riesgos
+				admin.POST("/risk-scoring/configs", can(authz.CatalogWrite), cfg.RiskScoringHandler.Create)
+				admin.POST("/risk-scoring/configs/:version/activate", can(authz.CatalogWrite), cfg.RiskScoringHandler.Activate)
+
+				// Proyecciones climáticas de los clusters
+				admin.PUT("/clusters/:id/risk-projections/:riskId", can(authz.CatalogWrite), cfg.ClusterHandler.SetRiskProjection)
+
+				// Restauración de tiendas y medidas eliminadas
+				admin.POST("/shops/:id/restore", can(authz.ShopsWrite), cfg.ShopHandler.Restore)
+				admin.DELETE("/measures/:name", can(authz.CatalogWrite), cfg.MeasureHandler.Delete)
+				admin.POST("/measures/:name/restore", can(authz.CatalogWrite), cfg.MeasureHandler.Restore)
+
+				// Correspondencia de las medidas con la Taxonomía de la UE
+				admin.PUT("/taxonomy/measures/:name", can(authz.CatalogWrite), cfg.TaxonomyHandler.SetMapping)
+				admin.DELETE("/taxonomy/measures/:name", can(authz.CatalogWrite), cfg.TaxonomyHandler.DeleteMapping)
+
+				// Gestión de usuarios
+				if cfg.UserHandler != nil {
+					admin.GET("/users", can(authz.UsersManage), cfg.UserHandler.List)
+					admin.POST("/users", can(authz.UsersManage), cfg.UserHandler.Invite)
+					admin.PATCH("/users/:id/role", can(authz.UsersManage), cfg.UserHandler.ChangeRole)
+					admin.PUT("/users/:id/scope", can(authz.UsersManage), cfg.UserHandler.SetScope)
+					admin.POST("/users/:id/deactivate", can(authz.UsersManage), cfg.UserHandler.Deactivate)
+					admin.POST("/users/:id/reactivate", can(authz.UsersManage), cfg.UserHandler.Reactivate)
+					admin.POST("/users/:id/reset-password", can(authz.UsersManage), cfg.UserHandler.ResetPassword)
+				}
+
+				// API keys para integraciones
+				if cfg.APIKeyHandler != nil {
+					admin.GET("/api-keys", can(authz.APIKeysManage), cfg.APIKeyHandler.List)
+					admin.POST("/api-keys", can(authz.APIKeysManage), cfg.APIKeyHandler.Create)
+					admin.DELETE("/api-keys/:id", can(authz.APIKeysManage), cfg.APIKeyHandler.Revoke)
+				}
+
+				// Log de auditoría
+				if cfg.AuditHandler != nil {
+					admin.GET("/audit-log", can(authz.AuditRead), cfg.AuditHandler.List)
+					admin.GET("/audit-log/export", can(authz.AuditRead), cfg.AuditHandler.Export)
+				}
 			}
 		}
 	}
@@ -193,11 +298,20 @@ func SetupSimple(r *gin.Engine, cfg *Config) {
 		v1.DELETE("/shops/:id", cfg.ShopHandler.Delete)
 		v1.GET("/shops/:id/measures", cfg.ShopHandler.GetAppliedMeasures)
 		v1.POST("/shops/:id/measures", cfg.ShopHandler.ApplyMeasures)
+		v1.POST("/shops/:id/measures/transition", cfg.ShopHandler.TransitionMeasure)
+		v1.GET("/shops/:id/measures/invoices", cfg.CostHandler.ListInvoices)
+		v1.POST("/shops/:id/measures/invoices", cfg.CostHandler.RecordInvoice)
 		// Soportar nombres con '/' (p.ej. "costera/fluvial/pluvial").
 		// Nota: Gin no permite coexistir '*measureName' con ':measureName' en el mismo prefijo.
 		v1.DELETE("/shops/:id/measures/*measureName", cfg.ShopHandler.RemoveMeasure)
 		v1.GET("/shops/:id/risk-assessment", cfg.ShopHandler.GetRiskAssessment)
 		v1.GET("/shops/:id/risk-coverage", cfg.ShopHandler.GetRiskCoverage)
+		v1.GET("/shops/:id/report.pdf", cfg.ShopHandler.GetReportPDF)
+		v1.GET("/shops/:id/taxonomy", cfg.TaxonomyHandler.GetShopReport)
+		v1.GET("/shops/:id/risk-history", cfg.ShopHandler.GetRiskHistory)
+		v1.GET("/shops/:id/risk-overrides", cfg.ShopHandler.GetRiskOverrides)
+		v1.PUT("/shops/:id/risk-overrides/:riskId", cfg.ShopHandler.SetRiskOverride)
+		v1.DELETE("/shops/:id/risk-overrides/:riskId", cfg.ShopHandler.RemoveRiskOverride)
 		v1.GET("/shops/:id/applicable-measures", cfg.MeasureHandler.GetApplicableForShop)
 
 		// Clusters
@@ -205,6 +319,8 @@ func SetupSimple(r *gin.Engine, cfg *Config) {
 		v1.GET("/clusters/:id", cfg.ClusterHandler.GetByID)
 		v1.GET("/clusters/:id/shops", cfg.ShopHandler.GetByCluster)
 		v1.GET("/clusters/:id/risks", cfg.RiskHandler.GetByCluster)
+		v1.GET("/clusters/:id/risk-projections", cfg.ClusterHandler.GetRiskProjections)
+		v1.PUT("/clusters/:id/risk-projections/:riskId", cfg.ClusterHandler.SetRiskProjection)
 
 		// Measures
 		v1.GET("/measures", cfg.MeasureHandler.List)
@@ -215,11 +331,33 @@ func SetupSimple(r *gin.Engine, cfg *Config) {
 		v1.GET("/risks/:id", cfg.RiskHandler.GetByID)
 		v1.GET("/risks/:id/measures", cfg.MeasureHandler.GetByRisk)
 
+		// Taxonomy
+		v1.GET("/taxonomy/activities", cfg.TaxonomyHandler.ListActivities)
+		v1.GET("/taxonomy/measures", cfg.TaxonomyHandler.ListMappings)
+		v1.PUT("/taxonomy/measures/:name", cfg.TaxonomyHandler.SetMapping)
+		v1.DELETE("/taxonomy/measures/:name", cfg.TaxonomyHandler.DeleteMapping)
+
+		// Risk scoring
+		v1.GET("/risk-scoring/configs", cfg.RiskScoringHandler.List)
+		v1.GET("/risk-scoring/configs/active", cfg.RiskScoringHandler.GetActive)
+		v1.GET("/risk-scoring/configs/:version", cfg.RiskScoringHandler.GetByVersion)
+		v1.POST("/risk-scoring/configs", cfg.RiskScoringHandler.Create)
+		v1.POST("/risk-scoring/configs/:version/activate", cfg.RiskScoringHandler.Activate)
+
 		// Optimization
 		v1.POST("/optimization/budget", cfg.OptimizationHandler.OptimizeBudget)
 
 		// Dashboard
 		v1.GET("/dashboard/stats", cfg.DashboardHandler.GetStats)
+		v1.GET("/dashboard/risk-trend", cfg.DashboardHandler.GetRiskTrend)
+		v1.GET("/dashboard/analytics", cfg.DashboardHandler.GetAnalytics)
+		v1.GET("/dashboard/risk-matrix", cfg.DashboardHandler.GetRiskMatrix)
+		v1.GET("/dashboard/cost-variance", cfg.CostHandler.GetVariance)
+		v1.GET("/dashboard/taxonomy", cfg.TaxonomyHandler.GetPortfolioReport)
+
+		// Reports
+		v1.POST("/reports/disclosure", cfg.DisclosureHandler.Generate)
+		v1.GET("/reports/disclosure/:checksum", cfg.DisclosureHandler.Get)
 	}
 
 	// 404 handler
@@ -0,0 +1,182 @@
+package authz_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/authz"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/interfaces/http/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// API KEY TESTS
+// ============================================================================
+
+// fakeAPIKeys valida las claves contra un mapa en memoria
+type fakeAPIKeys map[string]*models.APIKey
+
+func (f fakeAPIKeys) Authenticate(ctx context.Context, key string) (*models.APIKey, error) {
+	if k, ok := f[key]; ok {
+		return k, nil
+	}
+	return nil, models.ErrInvalidAPIKey
+}
+
+func newAPIKeyRouter(keys fakeAPIKeys) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	jwtService := middleware.NewJWTService(middleware.JWTConfig{SecretKey: "test-secret", TokenExpiry: time.Minute}, nil)
+
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r := gin.New()
+	group := r.Group("", middleware.AuthMiddleware(jwtService, keys))
+	group.GET("/shops", middleware.RequirePermission(authz.ShopsRead), ok)
+	group.GET("/dashboard", middleware.RequirePermission(authz.DashboardRead), ok)
+	group.POST("/optimization", middleware.RequirePermission(authz.OptimizationRun), ok)
+	group.GET("/users", middleware.RequirePermission(authz.UsersManage), ok)
+	return r
+}
+
+func doWithKey(r *gin.Engine, method, path, key string) int {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set(middleware.APIKeyHeader, key)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestAPIKey_PermissionsFollowRoleAndRestriction(t *testing.T) {
+	r := newAPIKeyRouter(fakeAPIKeys{
+		"bi":  {ID: 1, Role: models.RoleViewer, RateLimit: 100},
+		"erp": {ID: 2, Role: models.RoleAdmin, Permissions: []string{string(authz.ShopsRead)}, RateLimit: 100},
+	})
+
+	cases := []struct {
+		key    string
+		method string
+		path   string
+		status int
+	}{
+		{"bi", http.MethodGet, "/shops", http.StatusOK},
+		{"bi", http.MethodPost, "/optimization", http.StatusForbidden},
+		{"erp", http.MethodGet, "/shops", http.StatusOK},
+		{"erp", http.MethodGet, "/dashboard", http.StatusForbidden},
+		{"erp", http.MethodGet, "/users", http.StatusForbidden},
+		{"desconocida", http.MethodGet, "/shops", http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		if got := doWithKey(r, tc.method, tc.path, tc.key); got != tc.status {
+			t.Errorf("%s %s con %s: status %d, want %d", tc.method, tc.path, tc.key, got, tc.status)
+		}
+	}
+}
+
+func TestAPIKey_AdminKeyCannotManageUsers(t *testing.T) {
+	r := newAPIKeyRouter(fakeAPIKeys{"admin": {ID: 1, Role: models.RoleAdmin, RateLimit: 100}})
+
+	if got := doWithKey(r, http.MethodGet, "/users", "admin"); got != http.StatusForbidden {
+		t.Errorf("Una API key no debería gestionar usuarios, got %d", got)
+	}
+	if !authz.AllowedForAPIKey(models.RoleAdmin, nil, authz.ShopsWrite) {
+		t.Error("Una API key admin debería conservar el resto de permisos del rol")
+	}
+}
+
+func TestAPIKey_OwnRateLimit(t *testing.T) {
+	r := newAPIKeyRouter(fakeAPIKeys{
+		"limitada": {ID: 1, Role: models.RoleViewer, RateLimit: 2},
+		"otra":     {ID: 2, Role: models.RoleViewer, RateLimit: 2},
+	})
+
+	for i := 0; i < 2; i++ {
+		if got := doWithKey(r, http.MethodGet, "/shops", "limitada"); got != http.StatusOK {
+			t.Fatalf("Petición %d: status %d", i+1, got)
+		}
+	}
+	if got := doWithKey(r, http.MethodGet, "/shops", "limitada"); got != http.StatusTooManyRequests {
+		t.Errorf("Se esperaba 429 al superar el límite de la clave, got %d", got)
+	}
+	if got := doWithKey(r, http.MethodGet, "/shops", "otra"); got != http.StatusOK {
+		t.Errorf("El límite es por clave; otra clave debería seguir activa, got %d", got)
+	}
+
+	t.Log("✓ Límite de peticiones aplicado por API key")
+}
+
+func TestRateLimiter_OnlyValidAPIKeysSkipIPLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jwtService := middleware.NewJWTService(middleware.JWTConfig{SecretKey: "test-secret", TokenExpiry: time.Minute}, nil)
+	keys := fakeAPIKeys{"valida": {ID: 1, Role: models.RoleViewer, RateLimit: 100}}
+
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r := gin.New()
+	v1 := r.Group("", middleware.RateLimiter(2))
+	v1.POST("/auth/login", ok)
+	v1.GET("/shops", middleware.AuthMiddleware(jwtService, keys), middleware.RequirePermission(authz.ShopsRead), ok)
+
+	// Las peticiones con una clave válida no consumen el límite de la IP
+	for i := 0; i < 5; i++ {
+		if got := doWithKey(r, http.MethodGet, "/shops", "valida"); got != http.StatusOK {
+			t.Fatalf("Petición %d con clave válida: status %d", i+1, got)
+		}
+	}
+
+	// Una ruta pública con una clave inventada cuenta para la IP
+	for i := 0; i < 2; i++ {
+		if got := doWithKey(r, http.MethodPost, "/auth/login", "inventada"); got != http.StatusOK {
+			t.Fatalf("Petición %d: status %d", i+1, got)
+		}
+	}
+	if got := doWithKey(r, http.MethodPost, "/auth/login", "inventada"); got != http.StatusTooManyRequests {
+		t.Errorf("Una clave no validada no debería saltarse el límite por IP, got %d", got)
+	}
+	if got := doWithKey(r, http.MethodGet, "/shops", "inventada"); got != http.StatusTooManyRequests {
+		t.Errorf("Las claves rechazadas deberían contar para la IP, got %d", got)
+	}
+
+	t.Log("✓ Solo las API keys válidas quedan fuera del límite por IP")
+}
+
+func TestAPIKey_ScopeInContext(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jwtService := middleware.NewJWTService(middleware.JWTConfig{SecretKey: "test-secret", TokenExpiry: time.Minute}, nil)
+	keys := fakeAPIKeys{
+		"global":  {ID: 1, Role: models.RoleViewer, RateLimit: 100},
+		"espana":  {ID: 2, Role: models.RoleViewer, RateLimit: 100, Countries: []string{"ES"}},
+		"cluster": {ID: 3, Role: models.RoleManager, RateLimit: 100, ClusterIDs: []int64{7}},
+	}
+
+	var scope authz.Scope
+	r := gin.New()
+	r.GET("/shops", middleware.AuthMiddleware(jwtService, keys), func(c *gin.Context) {
+		scope = authz.ScopeFromContext(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+
+	cases := []struct {
+		key     string
+		country string
+		cluster int64
+		allowed bool
+	}{
+		{"global", "FR", 1, true},
+		{"espana", "ES", 1, true},
+		{"espana", "FR", 1, false},
+		{"cluster", "FR", 7, true},
+		{"cluster", "FR", 1, false},
+	}
+	for _, tc := range cases {
+		if got := doWithKey(r, http.MethodGet, "/shops", tc.key); got != http.StatusOK {
+			t.Fatalf("Clave %s: status %d", tc.key, got)
+		}
+		if got := scope.AllowsShop(tc.country, tc.cluster); got != tc.allowed {
+			t.Errorf("Clave %s, tienda %s/%d: allowed %v, want %v", tc.key, tc.country, tc.cluster, got, tc.allowed)
+		}
+	}
+
+	t.Log("✓ Las API keys aplican su ámbito de datos")
+}
@@ -0,0 +1,253 @@
+// Package authz_test contiene tests de la matriz de permisos y de su aplicación en las rutas.
+package authz_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/authz"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/interfaces/http/handlers"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/interfaces/http/middleware"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/interfaces/http/router"
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// MATRIX TESTS
+// ============================================================================
+
+func TestAllowed_RoleHierarchy(t *testing.T) {
+	cases := []struct {
+		role    models.UserRole
+		perm    authz.Permission
+		allowed bool
+	}{
+		{models.RoleViewer, authz.ShopsRead, true},
+		{models.RoleViewer, authz.DashboardRead, true},
+		{models.RoleViewer, authz.ShopMeasuresWrite, false},
+		{models.RoleViewer, authz.OptimizationRun, false},
+		{models.RoleViewer, authz.ShopsWrite, false},
+		{models.RoleManager, authz.ShopMeasuresWrite, true},
+		{models.RoleManager, authz.ShopRisksWrite, true},
+		{models.RoleManager, authz.OptimizationRun, true},
+		{models.RoleViewer, authz.ReportsGenerate, false},
+		{models.RoleManager, authz.ReportsGenerate, true},
+		{models.RoleManager, authz.ShopsWrite, false},
+		{models.RoleManager, authz.CatalogWrite, false},
+		{models.RoleManager, authz.UsersManage, false},
+		{models.RoleAdmin, authz.ShopsWrite, true},
+		{models.RoleAdmin, authz.CatalogWrite, true},
+		{models.RoleAdmin, authz.UsersManage, true},
+		{models.RoleAdmin, authz.APIKeysManage, true},
+		{models.RoleManager, authz.APIKeysManage, false},
+		{models.UserRole("unknown"), authz.ShopsRead, false},
+	}
+
+	for _, tc := range cases {
+		if got := authz.Allowed(tc.role, tc.perm); got != tc.allowed {
+			t.Errorf("Allowed(%s, %s) = %v, want %v", tc.role, tc.perm, got, tc.allowed)
+		}
+	}
+}
+
+func TestPermissions_EachRoleIncludesLowerRoles(t *testing.T) {
+	for _, perm := range authz.Permissions(models.RoleViewer) {
+		if !authz.Allowed(models.RoleManager, perm) {
+			t.Errorf("manager debería tener el permiso de viewer %s", perm)
+		}
+	}
+	for _, perm := range authz.Permissions(models.RoleManager) {
+		if !authz.Allowed(models.RoleAdmin, perm) {
+			t.Errorf("admin debería tener el permiso de manager %s", perm)
+		}
+	}
+}
+
+// ============================================================================
+// ROUTE ENFORCEMENT TESTS
+// ============================================================================
+
+// minRole indica el rol mínimo necesario para cada ruta protegida
+var minRole = map[string]models.UserRole{
+	"GET /api/v1/shops":                                         models.RoleViewer,
+	"GET /api/v1/shops/:id":                                     models.RoleViewer,
+	"POST /api/v1/shops":                                        models.RoleAdmin,
+	"PATCH /api/v1/shops/:id":                                   models.RoleAdmin,
+	"DELETE /api/v1/shops/:id":                                  models.RoleAdmin,
+	"GET /api/v1/shops/:id/measures":                            models.RoleViewer,
+	"POST /api/v1/shops/:id/measures":                           models.RoleManager,
+	"POST /api/v1/shops/:id/measures/transition":                models.RoleManager,
+	"GET /api/v1/shops/:id/measures/invoices":                   models.RoleViewer,
+	"POST /api/v1/shops/:id/measures/invoices":                  models.RoleManager,
+	"DELETE /api/v1/shops/:id/measures/*measureName":            models.RoleManager,
+	"GET /api/v1/shops/:id/risk-assessment":                     models.RoleViewer,
+	"GET /api/v1/shops/:id/risk-coverage":                       models.RoleViewer,
+	"GET /api/v1/shops/:id/report.pdf":                          models.RoleViewer,
+	"GET /api/v1/shops/:id/risk-history":                        models.RoleViewer,
+	"GET /api/v1/shops/:id/risk-overrides":                      models.RoleViewer,
+	"PUT /api/v1/shops/:id/risk-overrides/:riskId":              models.RoleManager,
+	"DELETE /api/v1/shops/:id/risk-overrides/:riskId":           models.RoleManager,
+	"GET /api/v1/shops/:id/applicable-measures":                 models.RoleViewer,
+	"GET /api/v1/shops/:id/taxonomy":                            models.RoleViewer,
+	"GET /api/v1/clusters":                                      models.RoleViewer,
+	"GET /api/v1/clusters/:id":                                  models.RoleViewer,
+	"GET /api/v1/clusters/:id/shops":                            models.RoleViewer,
+	"GET /api/v1/clusters/:id/risks":                            models.RoleViewer,
+	"GET /api/v1/clusters/:id/risk-projections":                 models.RoleViewer,
+	"GET /api/v1/measures":                                      models.RoleViewer,
+	"GET /api/v1/measures/:name":                                models.RoleViewer,
+	"GET /api/v1/risks":                                         models.RoleViewer,
+	"GET /api/v1/risks/:id":                                     models.RoleViewer,
+	"GET /api/v1/risks/:id/measures":                            models.RoleViewer,
+	"GET /api/v1/taxonomy/activities":                           models.RoleViewer,
+	"GET /api/v1/taxonomy/measures":                             models.RoleViewer,
+	"GET /api/v1/risk-scoring/configs":                          models.RoleViewer,
+	"GET /api/v1/risk-scoring/configs/active":                   models.RoleViewer,
+	"GET /api/v1/risk-scoring/configs/:version":                 models.RoleViewer,
+	"POST /api/v1/optimization/budget":                          models.RoleManager,
+	"GET /api/v1/dashboard/stats":                               models.RoleViewer,
+	"GET /api/v1/dashboard/risk-trend":                          models.RoleViewer,
+	"GET /api/v1/dashboard/analytics":                           models.RoleViewer,
+	"GET /api/v1/dashboard/risk-matrix":                         models.RoleViewer,
+	"GET /api/v1/dashboard/cost-variance":                       models.RoleViewer,
+	"GET /api/v1/dashboard/taxonomy":                            models.RoleViewer,
+	"POST /api/v1/reports/disclosure":                           models.RoleManager,
+	"GET /api/v1/reports/disclosure/:checksum":                  models.RoleViewer,
+	"POST /api/v1/admin/risk-scoring/configs":                   models.RoleAdmin,
+	"POST /api/v1/admin/risk-scoring/configs/:version/activate": models.RoleAdmin,
+	"PUT /api/v1/admin/clusters/:id/risk-projections/:riskId":   models.RoleAdmin,
+	"POST /api/v1/admin/shops/:id/restore":                      models.RoleAdmin,
+	"DELETE /api/v1/admin/measures/:name":                       models.RoleAdmin,
+	"POST /api/v1/admin/measures/:name/restore":                 models.RoleAdmin,
+	"PUT /api/v1/admin/taxonomy/measures/:name":                 models.RoleAdmin,
+	"DELETE /api/v1/admin/taxonomy/measures/:name":              models.RoleAdmin,
+	"GET /api/v1/admin/users":                                   models.RoleAdmin,
+	"POST /api/v1/admin/users":                                  models.RoleAdmin,
+	"PATCH /api/v1/admin/users/:id/role":                        models.RoleAdmin,
+	"PUT /api/v1/admin/users/:id/scope":                         models.RoleAdmin,
+	"POST /api/v1/admin/users/:id/deactivate":                   models.RoleAdmin,
+	"POST /api/v1/admin/users/:id/reactivate":                   models.RoleAdmin,
+	"POST /api/v1/admin/users/:id/reset-password":               models.RoleAdmin,
+	"GET /api/v1/admin/api-keys":                                models.RoleAdmin,
+	"POST /api/v1/admin/api-keys":                               models.RoleAdmin,
+	"DELETE /api/v1/admin/api-keys/:id":                         models.RoleAdmin,
+	"GET /api/v1/admin/audit-log":                               models.RoleAdmin,
+	"GET /api/v1/admin/audit-log/export":                        models.RoleAdmin,
+}
+
+// unrestricted son las rutas que no dependen del rol (públicas o sobre la propia cuenta)
+var unrestricted = map[string]bool{
+	"GET /health":                    true,
+	"GET /api/v1/health":             true,
+	"POST /api/v1/auth/login":        true,
+	"POST /api/v1/auth/register":     true,
+	"POST /api/v1/auth/refresh":      true,
+	"GET /api/v1/auth/oidc/login":    true,
+	"GET /api/v1/auth/oidc/callback": true,
+	"GET /api/v1/auth/me":            true,
+	"POST /api/v1/auth/logout":       true,
+}
+
+var roleRank = map[models.UserRole]int{
+	models.RoleViewer:  1,
+	models.RoleManager: 2,
+	models.RoleAdmin:   3,
+}
+
+func newRouter(jwtService *middleware.JWTService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	router.Setup(r, &router.Config{
+		ShopHandler:         &handlers.ShopHandler{},
+		ClusterHandler:      &handlers.ClusterHandler{},
+		MeasureHandler:      &handlers.MeasureHandler{},
+		RiskHandler:         &handlers.RiskHandler{},
+		OptimizationHandler: &handlers.OptimizationHandler{},
+		DashboardHandler:    &handlers.DashboardHandler{},
+		RiskScoringHandler:  &handlers.RiskScoringHandler{},
+		AuthHandler:         &handlers.AuthHandler{},
+		UserHandler:         &handlers.UserHandler{},
+		APIKeyHandler:       &handlers.APIKeyHandler{},
+		AuditHandler:        &handlers.AuditHandler{},
+		CostHandler:         &handlers.CostHandler{},
+		TaxonomyHandler:     &handlers.TaxonomyHandler{},
+		DisclosureHandler:   &handlers.DisclosureHandler{},
+		HealthHandler:       handlers.NewHealthHandler(),
+		JWTService:          jwtService,
+	})
+	return r
+}
+
+// concretePath sustituye los parámetros de una ruta por valores no numéricos, de
+// modo que los handlers autorizados rechacen la petición sin acceder a servicios
+func concretePath(path string) string {
+	parts := strings.Split(path, "/")
+	for i, p := range parts {
+		if strings.HasPrefix(p, ":") || strings.HasPrefix(p, "*") {
+			parts[i] = "x"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+func TestRouter_EveryRouteHasExpectedPermission(t *testing.T) {
+	jwtService := middleware.NewJWTService(middleware.JWTConfig{
+		SecretKey:   "test-secret",
+		TokenExpiry: time.Minute,
+	}, nil)
+
+	routes := newRouter(jwtService).Routes()
+	for _, route := range routes {
+		key := route.Method + " " + route.Path
+		if _, ok := minRole[key]; !ok && !unrestricted[key] {
+			t.Errorf("La ruta %s no tiene un permiso esperado en el test", key)
+		}
+	}
+
+	for role, rank := range roleRank {
+		// Un router por rol para no agotar el rate limiter
+		r := newRouter(jwtService)
+		token, _, err := jwtService.GenerateToken(&models.User{ID: 1, Email: "test@example.com", Role: role}, 1)
+		if err != nil {
+			t.Fatalf("Error inesperado: %v", err)
+		}
+
+		for _, route := range routes {
+			key := route.Method + " " + route.Path
+			required, ok := minRole[key]
+			if !ok {
+				continue
+			}
+
+			req := httptest.NewRequest(route.Method, concretePath(route.Path), strings.NewReader("{}"))
+			req.Header.Set("Authorization", "Bearer "+token)
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			forbidden := w.Code == http.StatusForbidden
+			if shouldAllow := rank >= roleRank[required]; shouldAllow == forbidden {
+				t.Errorf("%s como %s: status %d (rol mínimo %s)", key, role, w.Code, required)
+			}
+		}
+	}
+
+	t.Logf("✓ %d rutas verificadas para %d roles", len(minRole), len(roleRank))
+}
+
+func TestRouter_RequiresAuthentication(t *testing.T) {
+	jwtService := middleware.NewJWTService(middleware.JWTConfig{SecretKey: "test-secret", TokenExpiry: time.Minute}, nil)
+	r := newRouter(jwtService)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/shops/1", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Se esperaba 401 sin token, got %d", w.Code)
+	}
+}
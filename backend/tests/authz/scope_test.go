@@ -0,0 +1,107 @@
+package authz_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/authz"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/interfaces/http/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// SCOPE TESTS
+// ============================================================================
+
+func TestScope_AllowsShop(t *testing.T) {
+	cases := []struct {
+		name      string
+		scope     authz.Scope
+		country   string
+		clusterID int64
+		allowed   bool
+	}{
+		{"global", authz.Scope{}, "FR", 7, true},
+		{"país permitido", authz.Scope{Countries: []string{"ES"}}, "es", 7, true},
+		{"país no permitido", authz.Scope{Countries: []string{"ES"}}, "FR", 7, false},
+		{"cluster permitido", authz.Scope{ClusterIDs: []int64{7}}, "FR", 7, true},
+		{"cluster no permitido", authz.Scope{ClusterIDs: []int64{7}}, "FR", 8, false},
+		{"ambas dimensiones", authz.Scope{Countries: []string{"ES"}, ClusterIDs: []int64{7}}, "FR", 7, false},
+	}
+
+	for _, tc := range cases {
+		if got := tc.scope.AllowsShop(tc.country, tc.clusterID); got != tc.allowed {
+			t.Errorf("%s: AllowsShop(%s, %d) = %v, want %v", tc.name, tc.country, tc.clusterID, got, tc.allowed)
+		}
+	}
+}
+
+func TestScope_Within(t *testing.T) {
+	parent := authz.Scope{Countries: []string{"ES", "PT"}}
+
+	cases := []struct {
+		scope  authz.Scope
+		within bool
+	}{
+		{authz.Scope{Countries: []string{"es"}}, true},
+		{authz.Scope{Countries: []string{"ES"}, ClusterIDs: []int64{1}}, true},
+		{authz.Scope{Countries: []string{"FR"}}, false},
+		{authz.Scope{ClusterIDs: []int64{1}}, false},
+		{authz.Scope{}, false},
+	}
+	for _, tc := range cases {
+		if got := tc.scope.Within(parent); got != tc.within {
+			t.Errorf("%+v.Within(%+v) = %v, want %v", tc.scope, parent, got, tc.within)
+		}
+	}
+	if !(authz.Scope{}).Within(authz.Scope{}) {
+		t.Error("Un ámbito global debería estar dentro de otro global")
+	}
+}
+
+func TestScopeFromContext_DefaultsToGlobal(t *testing.T) {
+	if !authz.ScopeFromContext(context.Background()).IsGlobal() {
+		t.Error("Un contexto sin ámbito debería ser global")
+	}
+}
+
+func TestAuthMiddleware_PropagatesScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	jwtService := middleware.NewJWTService(middleware.JWTConfig{SecretKey: "test-secret", TokenExpiry: time.Minute}, nil)
+
+	var scope authz.Scope
+	r := gin.New()
+	r.GET("/scope", middleware.AuthMiddleware(jwtService, nil), func(c *gin.Context) {
+		scope = authz.ScopeFromContext(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+
+	token, _, err := jwtService.GenerateToken(&models.User{
+		ID:         1,
+		Email:      "regional@example.com",
+		Role:       models.RoleManager,
+		Countries:  []string{"ES", "PT"},
+		ClusterIDs: []int64{3},
+	}, 1)
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/scope", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Status inesperado: %d", w.Code)
+	}
+	if len(scope.Countries) != 2 || len(scope.ClusterIDs) != 1 || scope.ClusterIDs[0] != 3 {
+		t.Errorf("Ámbito inesperado en el contexto: %+v", scope)
+	}
+
+	t.Logf("✓ Ámbito propagado: países=%v clusters=%v", scope.Countries, scope.ClusterIDs)
+}
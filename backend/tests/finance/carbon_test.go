@@ -0,0 +1,69 @@
+package finance_test
+
+import (
+	"testing"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/finance"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+)
+
+// ============================================================================
+// CARBON TESTS
+// ============================================================================
+
+func TestParametersFromRequest_CarbonPrice(t *testing.T) {
+	if p := finance.ParametersFromRequest(&models.OptimizeBudgetRequest{}); p.CarbonPrice != finance.DefaultCarbonPrice {
+		t.Errorf("CarbonPrice=%v, esperado %v", p.CarbonPrice, finance.DefaultCarbonPrice)
+	}
+
+	zero := 0.0
+	if p := finance.ParametersFromRequest(&models.OptimizeBudgetRequest{CarbonPrice: &zero}); p.CarbonPrice != 0 {
+		t.Errorf("Se esperaba un precio del carbono nulo, se obtuvo %v", p.CarbonPrice)
+	}
+
+	t.Logf("✓ ParametersFromRequest usa el precio del carbono por defecto y respeta un precio nulo")
+}
+
+func TestProjectedFootprint_CombinesReductions(t *testing.T) {
+	measures := []models.Measure{
+		{Name: "Aislamiento", CarbonReduction: 0.5},
+		{Name: "BMS", CarbonReduction: 0.5},
+		{Name: "Plan emergencia"},
+	}
+
+	// 100 × 0,5 × 0,5
+	if got := finance.ProjectedFootprint(100, measures); !almostEqual(got, 25) {
+		t.Errorf("ProjectedFootprint=%v, esperado 25", got)
+	}
+	if got := finance.AnnualCarbonReduction(measures[0], 100); !almostEqual(got, 50) {
+		t.Errorf("AnnualCarbonReduction=%v, esperado 50", got)
+	}
+	if got := finance.ProjectedFootprint(100, []models.Measure{{CarbonReduction: 2}}); got != 0 {
+		t.Errorf("La huella no debería quedar por debajo de cero, se obtuvo %v", got)
+	}
+
+	t.Logf("✓ ProjectedFootprint combina reducciones de forma multiplicativa")
+}
+
+func TestCarbonImpact(t *testing.T) {
+	p := finance.Parameters{DiscountRate: 0, Years: 10, CarbonPrice: 100}
+
+	impact := p.CarbonImpact(100, []models.Measure{
+		{Name: "Aislamiento", CarbonReduction: 0.2, EmbodiedCarbon: 30},
+	})
+
+	// 20 tCO2e/año durante 10 años menos 30 tCO2e de implantación
+	if !almostEqual(impact.ProjectedFootprint, 80) || !almostEqual(impact.AnnualReduction, 20) {
+		t.Errorf("Huella inesperada: %+v", impact)
+	}
+	if !almostEqual(impact.NetReduction, 170) || !almostEqual(impact.CarbonValue, 17000) {
+		t.Errorf("NetReduction=%v, CarbonValue=%v, esperado 170 y 17000", impact.NetReduction, impact.CarbonValue)
+	}
+
+	total := p.AddCarbonImpact(impact, p.CarbonImpact(50, nil))
+	if !almostEqual(total.CurrentFootprint, 150) || !almostEqual(total.ProjectedFootprint, 130) || !almostEqual(total.NetReduction, 170) {
+		t.Errorf("Impacto agregado inesperado: %+v", total)
+	}
+
+	t.Logf("✓ CarbonImpact: %.0f → %.0f tCO2e/año, neto %.0f tCO2e", impact.CurrentFootprint, impact.ProjectedFootprint, impact.NetReduction)
+}
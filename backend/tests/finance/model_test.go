@@ -0,0 +1,139 @@
+// Package finance_test contiene tests unitarios para el modelo financiero de las medidas.
+package finance_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/finance"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+)
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 0.01
+}
+
+// ============================================================================
+// PARAMETERS TESTS
+// ============================================================================
+
+func TestParametersFromRequest_Defaults(t *testing.T) {
+	p := finance.ParametersFromRequest(&models.OptimizeBudgetRequest{})
+	if p != finance.DefaultParameters() {
+		t.Errorf("Sin parámetros se esperaban los valores por defecto, se obtuvo %+v", p)
+	}
+
+	zero := 0.0
+	p = finance.ParametersFromRequest(&models.OptimizeBudgetRequest{DiscountRate: &zero, EvaluationYears: 10, AssetValuePerM2: 1000})
+	if p.DiscountRate != 0 || p.Years != 10 || p.AssetValuePerM2 != 1000 {
+		t.Errorf("Se esperaban los parámetros de la solicitud, se obtuvo %+v", p)
+	}
+
+	t.Logf("✓ ParametersFromRequest usa los valores por defecto y respeta una tasa nula")
+}
+
+// ============================================================================
+// LOSS TESTS
+// ============================================================================
+
+func TestExpectedAnnualLoss(t *testing.T) {
+	p := finance.DefaultParameters()
+	risk := models.RiskDetail{
+		Exposure:    models.LevelHigh,
+		Sensitivity: models.LevelMedium,
+		Consequence: models.LevelHigh,
+		Probability: models.LevelMedium,
+	}
+
+	// 1000 m² × 2500 €/m² × 0,8 × 0,05 × 0,2 × 1
+	if got := p.ExpectedAnnualLoss(risk, 1000); !almostEqual(got, 20000) {
+		t.Errorf("ExpectedAnnualLoss=%v, esperado 20000", got)
+	}
+	if got := p.ExpectedAnnualLoss(risk, 0); got != 0 {
+		t.Errorf("Una tienda sin superficie no debería tener pérdida esperada, se obtuvo %v", got)
+	}
+
+	t.Logf("✓ ExpectedAnnualLoss combina valor expuesto, probabilidad y tasa de daño")
+}
+
+func TestAvoidedAnnualLoss_CombinesMitigations(t *testing.T) {
+	losses := map[string]float64{"Inundación": 10000, "Ola de calor": 4000}
+
+	avoided := finance.AvoidedAnnualLoss(losses, []finance.Mitigation{
+		{Risks: []string{"Inundación"}, Reduction: 0.5},
+		{Risks: []string{"Inundación", "Ola de calor"}, Reduction: 0.5},
+		{Risks: []string{"Granizo"}, Reduction: 0.9}, // Riesgo sin pérdida esperada
+	})
+
+	// Inundación: 10000 × (1 - 0,5 × 0,5) = 7500; Ola de calor: 4000 × 0,5 = 2000
+	if !almostEqual(avoided, 9500) {
+		t.Errorf("AvoidedAnnualLoss=%v, esperado 9500", avoided)
+	}
+
+	full := finance.AvoidedAnnualLoss(losses, []finance.Mitigation{
+		{Risks: []string{"Inundación"}, Reduction: 1},
+		{Risks: []string{"Inundación"}, Reduction: 1},
+	})
+	if !almostEqual(full, 10000) {
+		t.Errorf("No se debería evitar más de la pérdida esperada: %v", full)
+	}
+
+	t.Logf("✓ AvoidedAnnualLoss combina reducciones sin superar la pérdida esperada")
+}
+
+// ============================================================================
+// INVESTMENT METRICS TESTS
+// ============================================================================
+
+func TestNPV(t *testing.T) {
+	// Anualidad de 1000 € durante 3 años al 10 %: 2486,85 €
+	if got := finance.NPV(0.10, 2000, 1000, 3); !almostEqual(got, 486.85) {
+		t.Errorf("NPV=%v, esperado 486.85", got)
+	}
+	if got := finance.NPV(0, 2000, 1000, 3); !almostEqual(got, 1000) {
+		t.Errorf("NPV sin descuento=%v, esperado 1000", got)
+	}
+
+	t.Logf("✓ NPV descuenta los beneficios anuales")
+}
+
+func TestIRR(t *testing.T) {
+	irr, ok := finance.IRR(2000, 1000, 3)
+	if !ok {
+		t.Fatal("Se esperaba una TIR")
+	}
+	if npv := finance.NPV(irr, 2000, 1000, 3); math.Abs(npv) > 0.01 {
+		t.Errorf("El VAN a la TIR (%.4f) debería ser nulo, se obtuvo %v", irr, npv)
+	}
+	if !almostEqual(irr*100, 23.38) {
+		t.Errorf("IRR=%.4f, esperado 0.2338", irr)
+	}
+
+	if _, ok := finance.IRR(2000, 0, 3); ok {
+		t.Error("Sin beneficio no debería existir TIR")
+	}
+
+	t.Logf("✓ IRR anula el VAN: %.2f%%", irr*100)
+}
+
+func TestEvaluate(t *testing.T) {
+	p := finance.Parameters{DiscountRate: 0.10, Years: 3, AssetValuePerM2: 1}
+
+	metrics := p.Evaluate(2000, 5000, 1000)
+	if !almostEqual(metrics.NPV, 486.85) || metrics.ExpectedAnnualLoss != 5000 || metrics.AvoidedAnnualLoss != 1000 {
+		t.Errorf("Métricas inesperadas: %+v", metrics)
+	}
+	if metrics.PaybackYears == nil || !almostEqual(*metrics.PaybackYears, 2) {
+		t.Errorf("PaybackYears=%v, esperado 2", metrics.PaybackYears)
+	}
+	if metrics.IRR == nil {
+		t.Error("Se esperaba una TIR")
+	}
+
+	none := p.Evaluate(2000, 5000, 0)
+	if none.PaybackYears != nil || none.IRR != nil {
+		t.Errorf("Sin pérdida evitada no hay recuperación ni TIR: %+v", none)
+	}
+
+	t.Logf("✓ Evaluate calcula VAN, TIR y plazo de recuperación")
+}
@@ -4,10 +4,12 @@ package optimization_test
 import (
 	"context"
 	"fmt"
+	"math"
 	"testing"
 
 	"github.com/d1mo22/climate-invest-optimizer/backend/internal/application/services"
 	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/scoring"
 )
 
 // ============================================================================
@@ -40,6 +42,10 @@ func (m *mockShopRepository) GetByID(ctx context.Context, id int64) (*models.Sho
 }
 func (m *mockShopRepository) Update(ctx context.Context, shop *models.Shop) error { return nil }
 func (m *mockShopRepository) Delete(ctx context.Context, id int64) error          { return nil }
+func (m *mockShopRepository) GetDeletedByID(ctx context.Context, id int64) (*models.Shop, error) {
+	return nil, nil
+}
+func (m *mockShopRepository) Restore(ctx context.Context, id int64) error { return nil }
 func (m *mockShopRepository) List(ctx context.Context, filter *models.ShopFilterRequest) ([]models.Shop, int64, error) {
 	var result []models.Shop
 	for _, shop := range m.shops {
@@ -63,18 +69,37 @@ func (m *mockShopRepository) GetWithDetails(ctx context.Context, id int64) (*mod
 	}
 	return &models.ShopWithDetails{Shop: *shop}, nil
 }
+func (m *mockShopRepository) ListActiveIDs(ctx context.Context) ([]int64, error) { return nil, nil }
+func (m *mockShopRepository) ListIDsWithMeasure(ctx context.Context, measureName string) ([]int64, error) {
+	return nil, nil
+}
 func (m *mockShopRepository) GetAppliedMeasures(ctx context.Context, shopID int64) ([]models.Measure, error) {
 	return []models.Measure{}, nil
 }
+func (m *mockShopRepository) GetCompletedMeasures(ctx context.Context, shopID int64) ([]models.Measure, error) {
+	return nil, nil
+}
+func (m *mockShopRepository) GetShopMeasures(ctx context.Context, shopID int64) ([]models.ShopMeasure, error) {
+	return nil, nil
+}
+func (m *mockShopRepository) GetShopMeasure(ctx context.Context, shopID int64, measureName string) (*models.ShopMeasure, error) {
+	return nil, nil
+}
+func (m *mockShopRepository) UpdateShopMeasure(ctx context.Context, measure *models.ShopMeasure) error {
+	return nil
+}
 func (m *mockShopRepository) ApplyMeasure(ctx context.Context, shopID int64, measureName string) error {
 	return nil
 }
 func (m *mockShopRepository) RemoveMeasure(ctx context.Context, shopID int64, measureName string) error {
 	return nil
 }
-func (m *mockShopRepository) GetStats(ctx context.Context) (*models.DashboardStats, error) {
+func (m *mockShopRepository) GetStats(ctx context.Context, query *models.DashboardStatsQuery, thresholds models.RiskLevelThresholds) (*models.DashboardStats, error) {
 	return &models.DashboardStats{TotalShops: int64(len(m.shops))}, nil
 }
+func (m *mockShopRepository) GetRiskCoverage(ctx context.Context, shopID int64) (*models.RiskCoverageResponse, error) {
+	return &models.RiskCoverageResponse{ShopID: shopID}, nil
+}
 
 // mockMeasureRepository implementa repository.MeasureRepository para testing
 type mockMeasureRepository struct {
@@ -112,9 +137,16 @@ func (m *mockMeasureRepository) GetByName(ctx context.Context, name string) (*mo
 }
 func (m *mockMeasureRepository) Update(ctx context.Context, measure *models.Measure) error { return nil }
 func (m *mockMeasureRepository) Delete(ctx context.Context, name string) error             { return nil }
+func (m *mockMeasureRepository) GetDeletedByName(ctx context.Context, name string) (*models.Measure, error) {
+	return nil, nil
+}
+func (m *mockMeasureRepository) Restore(ctx context.Context, name string) error { return nil }
 func (m *mockMeasureRepository) List(ctx context.Context) ([]models.Measure, error) {
 	return m.measures, nil
 }
+func (m *mockMeasureRepository) ListIncludingDeleted(ctx context.Context) ([]models.Measure, error) {
+	return m.measures, nil
+}
 func (m *mockMeasureRepository) GetByType(ctx context.Context, measureType models.MeasureType) ([]models.Measure, error) {
 	var result []models.Measure
 	for _, measure := range m.measures {
@@ -151,8 +183,11 @@ func newMockRiskRepo() *mockRiskRepository {
 	riskDetails := make([]models.RiskDetail, len(risks))
 	for i, r := range risks {
 		riskDetails[i] = models.RiskDetail{
-			Risk:     r,
-			Exposure: models.LevelMedium,
+			Risk:        r,
+			Exposure:    models.LevelMedium,
+			Sensitivity: models.LevelMedium,
+			Consequence: models.LevelMedium,
+			Probability: models.LevelMedium,
 		}
 	}
 
@@ -186,18 +221,87 @@ func (m *mockRiskRepository) GetByClusterID(ctx context.Context, clusterID int64
 	return m.riskDetails, nil
 }
 
+// mockOverrideRepository implementa repository.ShopRiskOverrideRepository para testing
+type mockOverrideRepository struct{}
+
+func (m *mockOverrideRepository) Upsert(ctx context.Context, override *models.ShopRiskOverride) error {
+	return nil
+}
+func (m *mockOverrideRepository) Delete(ctx context.Context, shopID, riskID int64) error { return nil }
+func (m *mockOverrideRepository) GetByShop(ctx context.Context, shopID int64) ([]models.ShopRiskOverride, error) {
+	return nil, nil
+}
+
+// mockCountryRepository implementa repository.CountryRepository para testing
+type mockCountryRepository struct {
+	countries map[string]*models.Country
+}
+
+func (m *mockCountryRepository) Create(ctx context.Context, country *models.Country) error { return nil }
+func (m *mockCountryRepository) GetByName(ctx context.Context, name string) (*models.Country, error) {
+	return m.countries[name], nil
+}
+func (m *mockCountryRepository) List(ctx context.Context) ([]models.Country, error) { return nil, nil }
+func (m *mockCountryRepository) Delete(ctx context.Context, name string) error      { return nil }
+
+// mockScenarioRepository implementa repository.ClusterRiskScenarioRepository para testing
+type mockScenarioRepository struct{}
+
+func (m *mockScenarioRepository) Upsert(ctx context.Context, projection *models.ClusterRiskProjection) error {
+	return nil
+}
+func (m *mockScenarioRepository) GetByCluster(ctx context.Context, clusterID int64, scenario models.ClimateScenario) ([]models.ClusterRiskProjection, error) {
+	return nil, nil
+}
+
 // ============================================================================
 // TEST HELPERS
 // ============================================================================
 
 func createTestService() services.OptimizationService {
+	return createTestServiceWith(newMockShopRepo(), newMockMeasureRepo(), &mockCountryRepository{})
+}
+
+func createTestServiceWith(shopRepo *mockShopRepository, measureRepo *mockMeasureRepository, countryRepo *mockCountryRepository) services.OptimizationService {
 	return services.NewOptimizationService(
-		newMockShopRepo(),
-		newMockMeasureRepo(),
+		shopRepo,
+		measureRepo,
 		newMockRiskRepo(),
+		countryRepo,
+		&mockOverrideRepository{},
+		&mockScenarioRepository{},
+		scoring.Static(scoring.Default()),
+		doublingCostEstimator{},
+		noopAuditRecorder{},
 	)
 }
 
+// doublingCostEstimator simula un histórico en que todas las medidas costaron el doble
+type doublingCostEstimator struct{}
+
+func (doublingCostEstimator) EstimateCosts(ctx context.Context, measures []models.Measure) ([]models.Measure, []models.MeasureCostEstimate, error) {
+	corrected := make([]models.Measure, len(measures))
+	estimates := make([]models.MeasureCostEstimate, len(measures))
+	for i, m := range measures {
+		corrected[i] = m
+		corrected[i].EstimatedCost = m.EstimatedCost * 2
+		estimates[i] = models.MeasureCostEstimate{
+			MeasureName:   m.Name,
+			CatalogCost:   m.EstimatedCost,
+			EstimatedCost: corrected[i].EstimatedCost,
+			Basis:         models.CostVarianceByMeasure,
+			Samples:       3,
+		}
+	}
+	return corrected, estimates, nil
+}
+
+// noopAuditRecorder descarta los registros de auditoría
+type noopAuditRecorder struct{}
+
+func (noopAuditRecorder) Record(ctx context.Context, action models.AuditAction, entityType models.AuditEntityType, entityID string, before, after interface{}) {
+}
+
 // ============================================================================
 // GREEDY ALGORITHM TESTS
 // ============================================================================
@@ -808,6 +912,357 @@ func TestMetrics_ROI(t *testing.T) {
 	t.Logf("✓ EstimatedROI: %.2f", result.OptimizationMetrics.ROI)
 }
 
+func TestActualCosts_CorrectsCatalogCost(t *testing.T) {
+	service := createTestService()
+	ctx := context.Background()
+
+	result, err := service.OptimizeBudget(ctx, &models.OptimizeBudgetRequest{
+		ShopIDs:        []int64{1},
+		MaxBudget:      15000,
+		Strategy:       "greedy",
+		UseActualCosts: true,
+	})
+
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+
+	if len(result.CostEstimates) != len(newMockMeasureRepo().measures) {
+		t.Errorf("CostEstimates=%d, esperado una estimación por medida", len(result.CostEstimates))
+	}
+
+	// Las medidas recomendadas usan el coste corregido
+	catalog := make(map[string]float64)
+	for _, m := range newMockMeasureRepo().measures {
+		catalog[m.Name] = m.EstimatedCost
+	}
+	for _, rec := range result.RecommendedMeasures {
+		if rec.Measure.EstimatedCost != catalog[rec.Measure.Name]*2 {
+			t.Errorf("%s: coste=%v, esperado %v", rec.Measure.Name, rec.Measure.EstimatedCost, catalog[rec.Measure.Name]*2)
+		}
+	}
+
+	if result.TotalCost > 15000 {
+		t.Errorf("TotalCost=%v excede presupuesto 15000", result.TotalCost)
+	}
+
+	t.Logf("✓ ActualCosts: Coste=€%.0f, Medidas=%d", result.TotalCost, len(result.RecommendedMeasures))
+}
+
+func TestActualCosts_DisabledByDefault(t *testing.T) {
+	service := createTestService()
+	ctx := context.Background()
+
+	result, err := service.OptimizeBudget(ctx, &models.OptimizeBudgetRequest{
+		ShopIDs:   []int64{1},
+		MaxBudget: 15000,
+		Strategy:  "greedy",
+	})
+
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+
+	if result.CostEstimates != nil {
+		t.Errorf("CostEstimates=%v, esperado nil sin use_actual_costs", result.CostEstimates)
+	}
+
+	t.Logf("✓ ActualCosts desactivado por defecto")
+}
+
+func TestCostModels_ResolvedPerShop(t *testing.T) {
+	shopRepo := newMockShopRepo()
+	shopRepo.shops[1].Country = "Francia"
+	measureRepo := newMockMeasureRepo()
+	for i, m := range measureRepo.measures {
+		if m.Name == "Aislamiento térmico" {
+			measureRepo.measures[i].CostModel = models.CostModelFixedPlusVariable
+			measureRepo.measures[i].CostPerM2 = 2
+		}
+	}
+	countryRepo := &mockCountryRepository{countries: map[string]*models.Country{
+		"Francia": {Name: "Francia", PriceIndex: 1.2},
+	}}
+	service := createTestServiceWith(shopRepo, measureRepo, countryRepo)
+
+	result, err := service.OptimizeBudget(context.Background(), &models.OptimizeBudgetRequest{
+		ShopIDs:   []int64{1},
+		MaxBudget: 200000,
+		Strategy:  "greedy",
+	})
+
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+
+	// (1500 fijo + 2 €/m² × 500 m²) × 1,2 y el resto de medidas × 1,2
+	expected := map[string]float64{"Aislamiento térmico": 3000, "BMS": 1200}
+	found := 0
+	for _, rec := range result.RecommendedMeasures {
+		if cost, ok := expected[rec.Measure.Name]; ok {
+			found++
+			if math.Abs(rec.Measure.EstimatedCost-cost) > 0.01 {
+				t.Errorf("%s: coste=%v, esperado %v", rec.Measure.Name, rec.Measure.EstimatedCost, cost)
+			}
+		}
+	}
+	if found != len(expected) {
+		t.Fatalf("Se esperaban %d medidas recomendadas para comprobar su coste, se encontraron %d", len(expected), found)
+	}
+
+	t.Logf("✓ CostModels: Coste=€%.0f, Medidas=%d", result.TotalCost, len(result.RecommendedMeasures))
+}
+
+func TestMetrics_Financials(t *testing.T) {
+	service := createTestService()
+	ctx := context.Background()
+	rate := 0.08
+
+	result, err := service.OptimizeBudget(ctx, &models.OptimizeBudgetRequest{
+		ShopIDs:         []int64{1, 2, 3},
+		MaxBudget:       25000,
+		Strategy:        "greedy",
+		DiscountRate:    &rate,
+		EvaluationYears: 10,
+	})
+
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+
+	total := result.OptimizationMetrics.Financials
+	if total.DiscountRate != rate || total.Years != 10 {
+		t.Errorf("Parámetros no aplicados: tasa=%v años=%d", total.DiscountRate, total.Years)
+	}
+
+	var avoided, npv float64
+	for _, rec := range result.ShopRecommendations {
+		avoided += rec.Financials.AvoidedAnnualLoss
+		npv += rec.Financials.NPV
+		if rec.Financials.AvoidedAnnualLoss > rec.Financials.ExpectedAnnualLoss {
+			t.Errorf("Shop %d: pérdida evitada %.0f mayor que la esperada %.0f",
+				rec.ShopID, rec.Financials.AvoidedAnnualLoss, rec.Financials.ExpectedAnnualLoss)
+		}
+	}
+	if math.Abs(avoided-total.AvoidedAnnualLoss) > 0.01 || math.Abs(npv-total.NPV) > 0.01 {
+		t.Errorf("Los totales (evitada=%.2f, VAN=%.2f) no coinciden con la suma por tienda (%.2f, %.2f)",
+			total.AvoidedAnnualLoss, total.NPV, avoided, npv)
+	}
+	if math.Abs(result.OptimizationMetrics.ROI-total.NPV/result.TotalCost) > 0.0001 {
+		t.Errorf("ROI=%.4f, esperado VAN/coste=%.4f", result.OptimizationMetrics.ROI, total.NPV/result.TotalCost)
+	}
+
+	t.Logf("✓ Financials: EAL=€%.0f, Evitada=€%.0f/año, VAN=€%.0f", total.ExpectedAnnualLoss, total.AvoidedAnnualLoss, total.NPV)
+}
+
+// ============================================================================
+// OBJECTIVE TESTS
+// ============================================================================
+
+func TestObjectives_RespectBudget(t *testing.T) {
+	objectives := []models.OptimizationObjective{
+		models.ObjectiveRiskReduction,
+		models.ObjectiveAvoidedLoss,
+		models.ObjectiveNPV,
+		models.ObjectiveCoverage,
+		models.ObjectiveRiskCarbon,
+	}
+
+	for _, objective := range objectives {
+		for _, strategy := range []string{"greedy", "knapsack", "weighted"} {
+			t.Run(string(objective)+"/"+strategy, func(t *testing.T) {
+				service := createTestService()
+				result, err := service.OptimizeBudget(context.Background(), &models.OptimizeBudgetRequest{
+					ShopIDs:    []int64{1, 2, 3},
+					MaxBudget:  20000,
+					Strategy:   strategy,
+					Objective:  objective,
+					Priorities: []int64{1},
+				})
+				if err != nil {
+					t.Fatalf("Error inesperado: %v", err)
+				}
+
+				if result.TotalCost > 20000 {
+					t.Errorf("TotalCost=%v excede presupuesto 20000", result.TotalCost)
+				}
+				if result.OptimizationMetrics.Objective != objective {
+					t.Errorf("Objective=%q, esperado %q", result.OptimizationMetrics.Objective, objective)
+				}
+				if len(result.RecommendedMeasures) == 0 || result.OptimizationMetrics.ObjectiveValue <= 0 {
+					t.Errorf("Se esperaban medidas con valor positivo, se obtuvo %d medidas y valor %v",
+						len(result.RecommendedMeasures), result.OptimizationMetrics.ObjectiveValue)
+				}
+			})
+		}
+	}
+
+	t.Logf("✓ Todos los objetivos respetan el presupuesto con todas las estrategias")
+}
+
+func TestObjectives_DefaultIsRiskReduction(t *testing.T) {
+	service := createTestService()
+
+	result, err := service.OptimizeBudget(context.Background(), &models.OptimizeBudgetRequest{
+		ShopIDs:   []int64{1},
+		MaxBudget: 10000,
+	})
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+
+	if result.OptimizationMetrics.Objective != models.ObjectiveRiskReduction {
+		t.Errorf("Objective=%q, esperado %q", result.OptimizationMetrics.Objective, models.ObjectiveRiskReduction)
+	}
+	if math.Abs(result.OptimizationMetrics.ObjectiveValue*100-result.TotalRiskReduction) > 0.0001 {
+		t.Errorf("ObjectiveValue=%v no coincide con TotalRiskReduction=%v", result.OptimizationMetrics.ObjectiveValue, result.TotalRiskReduction)
+	}
+
+	t.Logf("✓ Objetivo por defecto: reducción de riesgo")
+}
+
+func TestObjectives_NPVSkipsUnprofitableMeasures(t *testing.T) {
+	service := createTestService()
+	rate := 0.06
+
+	result, err := service.OptimizeBudget(context.Background(), &models.OptimizeBudgetRequest{
+		ShopIDs:         []int64{1, 2, 3, 4, 5},
+		MaxBudget:       1000000,
+		Strategy:        "greedy",
+		Objective:       models.ObjectiveNPV,
+		DiscountRate:    &rate,
+		EvaluationYears: 5,
+	})
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+
+	// Con presupuesto holgado solo se descartan las medidas con VAN negativo
+	for _, rec := range result.RecommendedMeasures {
+		npv := -rec.Measure.EstimatedCost
+		for year := 1; year <= 5; year++ {
+			npv += rec.AvoidedAnnualLoss / math.Pow(1+rate, float64(year))
+		}
+		if npv <= 0 {
+			t.Errorf("%s: VAN=%.0f, no debería recomendarse", rec.Measure.Name, npv)
+		}
+	}
+
+	t.Logf("✓ NPV: %d medidas rentables, VAN total=€%.0f", len(result.RecommendedMeasures), result.OptimizationMetrics.ObjectiveValue)
+}
+
+// ============================================================================
+// CARBON TESTS
+// ============================================================================
+
+// newCarbonTestService crea un servicio con huella de carbono en las tiendas 1 y 2 y
+// dos medidas que reducen emisiones
+func newCarbonTestService() services.OptimizationService {
+	shopRepo := newMockShopRepo()
+	shopRepo.shops[1].CarbonFootprint = 100
+	shopRepo.shops[2].CarbonFootprint = 50
+	measureRepo := newMockMeasureRepo()
+	for i, m := range measureRepo.measures {
+		switch m.Name {
+		case "Aislamiento térmico":
+			measureRepo.measures[i].CarbonReduction = 0.2
+			measureRepo.measures[i].EmbodiedCarbon = 5
+		case "BMS":
+			measureRepo.measures[i].CarbonReduction = 0.1
+		}
+	}
+	return createTestServiceWith(shopRepo, measureRepo, &mockCountryRepository{})
+}
+
+func TestCarbon_ProjectedFootprint(t *testing.T) {
+	service := newCarbonTestService()
+
+	result, err := service.OptimizeBudget(context.Background(), &models.OptimizeBudgetRequest{
+		ShopIDs:   []int64{1, 2, 3},
+		MaxBudget: 200000,
+		Strategy:  "greedy",
+		Objective: models.ObjectiveCarbon,
+	})
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+
+	// Solo las medidas que reducen emisiones aportan al objetivo
+	for _, rec := range result.RecommendedMeasures {
+		if rec.Measure.CarbonReduction <= 0 {
+			t.Errorf("%s no reduce emisiones y no debería recomendarse", rec.Measure.Name)
+		}
+	}
+
+	// Tienda 1: 100 × 0,8 × 0,9; tienda 2: 50 × 0,8 × 0,9
+	projected := map[int64]float64{1: 72, 2: 36}
+	for _, rec := range result.ShopRecommendations {
+		expected, ok := projected[rec.ShopID]
+		if !ok {
+			t.Errorf("La tienda %d no tiene huella y no debería tener recomendaciones", rec.ShopID)
+			continue
+		}
+		if math.Abs(rec.Carbon.ProjectedFootprint-expected) > 0.01 {
+			t.Errorf("Tienda %d: ProjectedFootprint=%v, esperado %v", rec.ShopID, rec.Carbon.ProjectedFootprint, expected)
+		}
+	}
+
+	total := result.OptimizationMetrics.Carbon
+	if math.Abs(total.CurrentFootprint-150) > 0.01 || math.Abs(total.ProjectedFootprint-108) > 0.01 || math.Abs(total.EmbodiedCarbon-10) > 0.01 {
+		t.Errorf("Impacto de la cartera inesperado: %+v", total)
+	}
+
+	// El objetivo suma la reducción de cada medida por sí sola durante los 15 años por
+	// defecto; en la cartera las reducciones de una tienda se combinan y evitan menos
+	var objective float64
+	for _, rec := range result.RecommendedMeasures {
+		objective += rec.AnnualCarbonReduction*15 - rec.Measure.EmbodiedCarbon
+	}
+	if math.Abs(result.OptimizationMetrics.ObjectiveValue-objective) > 0.01 {
+		t.Errorf("ObjectiveValue=%v, esperado %v", result.OptimizationMetrics.ObjectiveValue, objective)
+	}
+	if total.NetReduction > objective {
+		t.Errorf("NetReduction=%v no debería superar la suma de las medidas %v", total.NetReduction, objective)
+	}
+
+	t.Logf("✓ Carbon: %.0f → %.0f tCO2e/año, neto %.0f tCO2e", total.CurrentFootprint, total.ProjectedFootprint, total.NetReduction)
+}
+
+func TestCarbon_PriceValuesEmissions(t *testing.T) {
+	optimize := func(price float64) *models.OptimizationResult {
+		result, err := newCarbonTestService().OptimizeBudget(context.Background(), &models.OptimizeBudgetRequest{
+			ShopIDs:     []int64{1},
+			MaxBudget:   200000,
+			Strategy:    "greedy",
+			Objective:   models.ObjectiveRiskCarbon,
+			CarbonPrice: &price,
+		})
+		if err != nil {
+			t.Fatalf("Error inesperado: %v", err)
+		}
+		return result
+	}
+
+	free := optimize(0)
+	priced := optimize(100)
+
+	if free.OptimizationMetrics.Carbon.CarbonValue != 0 || priced.OptimizationMetrics.Carbon.CarbonPrice != 100 {
+		t.Errorf("Precio del carbono no aplicado: %+v / %+v", free.OptimizationMetrics.Carbon, priced.OptimizationMetrics.Carbon)
+	}
+
+	// Con presupuesto holgado se recomiendan las mismas medidas: la diferencia de valor
+	// es el de las emisiones que evita cada medida, que como mínimo es el de la tienda
+	diff := priced.OptimizationMetrics.ObjectiveValue - free.OptimizationMetrics.ObjectiveValue
+	if len(priced.RecommendedMeasures) != len(free.RecommendedMeasures) {
+		t.Errorf("Se esperaban las mismas medidas: %d / %d", len(priced.RecommendedMeasures), len(free.RecommendedMeasures))
+	}
+	if diff <= 0 || diff < priced.OptimizationMetrics.Carbon.CarbonValue {
+		t.Errorf("Diferencia de valor=%v, esperado al menos CarbonValue=%v", diff, priced.OptimizationMetrics.Carbon.CarbonValue)
+	}
+
+	t.Logf("✓ RiskCarbon: las emisiones evitadas valen €%.0f a 100 €/tCO2e", diff)
+}
+
 // ============================================================================
 // BENCHMARKS
 // ============================================================================
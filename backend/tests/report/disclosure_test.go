@@ -0,0 +1,98 @@
+package report_test
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/infrastructure/report"
+)
+
+// ============================================================================
+// DISCLOSURE REPORT RENDERING TESTS
+// ============================================================================
+
+func sampleDisclosure() *models.DisclosureReport {
+	return &models.DisclosureReport{
+		Period: models.ReportingPeriod{
+			Start: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			End:   time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC),
+		},
+		GeneratedAt: time.Date(2026, 1, 15, 10, 30, 0, 0, time.UTC),
+		Summary:     models.DisclosureSummary{TotalShops: 1200, HighRiskShops: 1, HighRiskPercentage: 0.08},
+		ExposureByRisk: []models.RiskExposure{
+			{RiskID: 1, RiskName: "Inundación", ShopsAssessed: 1200, MaterialShops: 40, ShopsByLevel: map[models.Level]int64{models.LevelHigh: 1}},
+		},
+		HighRiskShops: []models.HighRiskShop{
+			{ShopID: 7, ShopLocation: "Valencia | Puerto", Country: "España", TotalRisk: 0.81, RiskLevel: models.LevelVeryHigh, MaterialRisks: []string{"Inundación"}},
+		},
+		AdaptationPlan: models.AdaptationPlan{
+			MeasuresByStatus: map[models.MeasureStatus]int64{models.MeasureStatusCompleted: 3},
+			PeriodSpend:      1234567,
+		},
+		Scenarios: []models.ScenarioResult{
+			{Scenario: models.ScenarioSelection{Scenario: models.ScenarioCurrent}},
+			{Scenario: models.ScenarioSelection{Scenario: models.ScenarioSSP585, Horizon: 2050}, AverageRisk: 0.5},
+		},
+		Data: models.DisclosureDataSnapshot{Checksum: strings.Repeat("ab", 32)},
+	}
+}
+
+func TestWriteDisclosureMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := report.WriteDisclosureMarkdown(&buf, sampleDisclosure()); err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	md := buf.String()
+
+	for _, want := range []string{
+		"# Informe de riesgos climáticos físicos",
+		"Periodo del 01/01/2025 al 31/12/2025",
+		"## 3. Tiendas de alto riesgo",
+		`Valencia \| Puerto`,
+		"| Tiendas evaluadas | 1.200 |",
+		"1.234.567 €",
+		"SSP5-8.5 (2050)",
+		"Alto 1",
+		strings.Repeat("ab", 32),
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("El Markdown no contiene %q", want)
+		}
+	}
+
+	t.Logf("✓ WriteDisclosureMarkdown genera las secciones y formatea las cifras")
+}
+
+func TestWriteDisclosurePDF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := report.WriteDisclosurePDF(&buf, sampleDisclosure()); err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	out := buf.Bytes()
+
+	if !bytes.HasPrefix(out, []byte("%PDF-1.4")) || !bytes.HasSuffix(out, []byte("%%EOF\n")) {
+		t.Fatal("El documento no tiene la cabecera y el final de un PDF")
+	}
+
+	// La tabla de referencias cruzadas debe estar donde indica startxref
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(out)
+	if m == nil {
+		t.Fatal("Falta startxref")
+	}
+	offset, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(out[offset:], []byte("xref")) {
+		t.Errorf("startxref apunta a %d, donde no empieza la tabla xref", offset)
+	}
+
+	// Los textos se codifican en WinAnsiEncoding: "Inundación" con ó = \363
+	if !bytes.Contains(out, []byte(`Inundaci\363n`)) {
+		t.Error("El PDF no contiene el texto codificado en WinAnsiEncoding")
+	}
+
+	t.Logf("✓ WriteDisclosurePDF genera un PDF de %d bytes con xref válida", len(out))
+}
@@ -0,0 +1,69 @@
+package report_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/infrastructure/report"
+)
+
+// ============================================================================
+// SHOP DOSSIER RENDERING TESTS
+// ============================================================================
+
+func sampleDossier() *models.ShopDossier {
+	cost := 420.0
+	finished := time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC)
+	risk := models.Risk{ID: 1, Name: "Inundación"}
+
+	return &models.ShopDossier{
+		// Madrid en Web Mercator
+		Shop:    models.Shop{ID: 7, Location: "Madrid Centro", Country: "España", UtmEast: -411926.644, UtmNorth: 4926301.901, Surface: 1200},
+		Cluster: models.Cluster{ID: 1, Name: "Centro Urbano", UtmEast: -410000, UtmNorth: 4925000},
+		Neighbours: []models.Shop{
+			{ID: 8, UtmEast: -405000, UtmNorth: 4930000},
+		},
+		Assessment: models.RiskAssessmentResponse{
+			ShopID: 7, OverallRiskScore: 0.42, RiskLevel: models.LevelMedium,
+			Risks: []models.RiskDetail{{Risk: risk, Exposure: models.LevelHigh, RiskScore: 0.42, OverrideReason: "Sótano"}},
+		},
+		Coverage: models.RiskCoverageResponse{
+			ShopID: 7, TotalRisks: 1, CoveredRisks: 1, CoveragePercentage: 100,
+			Risks: []models.RiskCoverageItem{{RiskID: 1, RiskName: "Inundación", IsCovered: true}},
+		},
+		Measures: []models.ShopMeasure{{
+			ShopID: 7, Measure: models.Measure{Name: "Revisión sistemas pluviales", EstimatedCost: 400},
+			Status: models.MeasureStatusCompleted, ActualEndDate: &finished, ActualCost: &cost,
+		}},
+		GeneratedAt: time.Date(2026, 1, 15, 10, 30, 0, 0, time.UTC),
+	}
+}
+
+func TestWriteShopDossierPDF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := report.WriteShopDossierPDF(&buf, sampleDossier()); err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	out := buf.Bytes()
+
+	if !bytes.HasPrefix(out, []byte("%PDF-1.4")) || !bytes.HasSuffix(out, []byte("%%EOF\n")) {
+		t.Fatal("El documento no tiene la cabecera y el final de un PDF")
+	}
+
+	for _, want := range []string{
+		"Madrid Centro",
+		`40.4141\260 N, 3.7004\260 O`, // Coordenadas geográficas de la tienda
+		"Centro del cluster",          // Leyenda del mapa de situación
+		"Revisi\\363n sistemas pluviales",
+		"420 \\200",
+		"Todos los riesgos de la tienda", // Sin recomendaciones
+	} {
+		if !bytes.Contains(out, []byte(want)) {
+			t.Errorf("El PDF no contiene %q", want)
+		}
+	}
+
+	t.Logf("✓ WriteShopDossierPDF genera la ficha de %d bytes con el mapa de situación", len(out))
+}
@@ -0,0 +1,117 @@
+// Package scenario_test contiene tests unitarios para las proyecciones de escenarios climáticos.
+package scenario_test
+
+import (
+	"testing"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/scenario"
+)
+
+func level(l models.Level) *models.Level {
+	return &l
+}
+
+func currentRisks() []models.RiskDetail {
+	return []models.RiskDetail{
+		{
+			Risk:        models.Risk{ID: 1, Name: "Inundación"},
+			Exposure:    models.LevelMedium,
+			Sensitivity: models.LevelMedium,
+			Consequence: models.LevelMedium,
+			Probability: models.LevelLow,
+		},
+		{
+			Risk:        models.Risk{ID: 2, Name: "Ola de calor"},
+			Exposure:    models.LevelLow,
+			Sensitivity: models.LevelLow,
+			Consequence: models.LevelLow,
+			Probability: models.LevelLow,
+		},
+	}
+}
+
+func projections() []models.ClusterRiskProjection {
+	return []models.ClusterRiskProjection{
+		{RiskID: 1, Scenario: models.ScenarioSSP585, Horizon: models.Horizon2030, Probability: level(models.LevelMedium), Exposure: level(models.LevelHigh)},
+		{RiskID: 1, Scenario: models.ScenarioSSP585, Horizon: models.Horizon2050, Probability: level(models.LevelVeryHigh)},
+		{RiskID: 2, Scenario: models.ScenarioSSP126, Horizon: models.Horizon2050, Probability: level(models.LevelHigh)},
+	}
+}
+
+// ============================================================================
+// NORMALIZE TESTS
+// ============================================================================
+
+func TestNormalize_Defaults(t *testing.T) {
+	if got := scenario.Normalize(models.ScenarioSelection{}); got.Scenario != models.ScenarioCurrent || got.Horizon != 0 {
+		t.Errorf("Selección vacía: got %+v", got)
+	}
+
+	got := scenario.Normalize(models.ScenarioSelection{Scenario: models.ScenarioSSP245})
+	if got.Horizon != scenario.DefaultHorizon {
+		t.Errorf("Horizonte por defecto esperado %d, got %d", scenario.DefaultHorizon, got.Horizon)
+	}
+
+	got = scenario.Normalize(models.ScenarioSelection{Scenario: models.ScenarioCurrent, Horizon: models.Horizon2050})
+	if got.Horizon != 0 {
+		t.Errorf("El escenario actual no tiene horizonte, got %d", got.Horizon)
+	}
+}
+
+// ============================================================================
+// APPLY TESTS
+// ============================================================================
+
+func TestApply_ExactHorizon(t *testing.T) {
+	risks := scenario.Apply(currentRisks(), projections(), models.ScenarioSelection{
+		Scenario: models.ScenarioSSP585,
+		Horizon:  models.Horizon2050,
+	})
+
+	if risks[0].Probability != models.LevelVeryHigh {
+		t.Errorf("Probability esperada very_high, got %s", risks[0].Probability)
+	}
+	t.Logf("✓ Horizonte exacto: Probability=%s", risks[0].Probability)
+}
+
+func TestApply_FallsBackToEarlierHorizon(t *testing.T) {
+	risks := scenario.Apply(currentRisks(), projections(), models.ScenarioSelection{
+		Scenario: models.ScenarioSSP585,
+		Horizon:  models.Horizon2050,
+	})
+
+	// La exposición no está proyectada a 2050: se usa la de 2030
+	if risks[0].Exposure != models.LevelHigh {
+		t.Errorf("Exposure esperada high (2030), got %s", risks[0].Exposure)
+	}
+	// La sensibilidad no está proyectada: se mantiene el nivel actual
+	if risks[0].Sensitivity != models.LevelMedium {
+		t.Errorf("Sensitivity esperada medium (actual), got %s", risks[0].Sensitivity)
+	}
+}
+
+func TestApply_IgnoresLaterHorizonsAndOtherScenarios(t *testing.T) {
+	risks := scenario.Apply(currentRisks(), projections(), models.ScenarioSelection{
+		Scenario: models.ScenarioSSP585,
+		Horizon:  models.Horizon2030,
+	})
+
+	if risks[0].Probability != models.LevelMedium {
+		t.Errorf("Probability esperada medium (2030), got %s", risks[0].Probability)
+	}
+	// El riesgo 2 solo tiene proyección en SSP1-2.6
+	if risks[1].Probability != models.LevelLow {
+		t.Errorf("Probability esperada low (actual), got %s", risks[1].Probability)
+	}
+}
+
+func TestApply_CurrentScenarioUnchanged(t *testing.T) {
+	risks := scenario.Apply(currentRisks(), projections(), models.ScenarioSelection{})
+
+	for i, r := range currentRisks() {
+		if risks[i] != r {
+			t.Errorf("El escenario actual no debería modificar los riesgos: got %+v, want %+v", risks[i], r)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+// Package scoring_test contiene tests unitarios para los modelos de scoring de riesgos.
+package scoring_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/scoring"
+)
+
+const epsilon = 1e-9
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < epsilon
+}
+
+func sampleRisk() models.RiskDetail {
+	return models.RiskDetail{
+		Risk:        models.Risk{ID: 1, Name: "Inundación"},
+		Exposure:    models.LevelHigh,     // 0.7
+		Sensitivity: models.LevelMedium,   // 0.5
+		Consequence: models.LevelVeryHigh, // 0.9
+		Probability: models.LevelLow,      // 0.3
+	}
+}
+
+func scorerWithMethod(t *testing.T, method models.RiskScoringMethod) scoring.RiskScorer {
+	t.Helper()
+	cfg := scoring.DefaultConfig()
+	cfg.Method = method
+	scorer, err := scoring.New(cfg)
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	return scorer
+}
+
+// ============================================================================
+// DEFAULT CONFIG TESTS
+// ============================================================================
+
+func TestDefault_MatchesLegacyFormula(t *testing.T) {
+	scorer := scoring.Default()
+
+	got := scorer.Score(sampleRisk())
+	want := (0.7 * 0.5) * (0.9 * 0.3)
+	if !almostEqual(got, want) {
+		t.Errorf("Score=%v, se esperaba %v", got, want)
+	}
+
+	t.Logf("✓ Default: Score=%.4f", got)
+}
+
+func TestDefault_UnknownLevelUsesMedium(t *testing.T) {
+	scorer := scoring.Default()
+
+	got := scorer.Score(models.RiskDetail{})
+	want := math.Pow(0.5, 4)
+	if !almostEqual(got, want) {
+		t.Errorf("Score=%v, se esperaba %v", got, want)
+	}
+}
+
+func TestDefault_LevelThresholds(t *testing.T) {
+	scorer := scoring.Default()
+
+	cases := map[float64]models.Level{
+		0.0:  models.LevelVeryLow,
+		0.19: models.LevelVeryLow,
+		0.2:  models.LevelLow,
+		0.45: models.LevelMedium,
+		0.7:  models.LevelHigh,
+		0.8:  models.LevelVeryHigh,
+		1.0:  models.LevelVeryHigh,
+	}
+	for score, want := range cases {
+		if got := scorer.Level(score); got != want {
+			t.Errorf("Level(%v)=%q, se esperaba %q", score, got, want)
+		}
+	}
+}
+
+// ============================================================================
+// METHOD TESTS
+// ============================================================================
+
+func TestMethods_Score(t *testing.T) {
+	cases := []struct {
+		method models.RiskScoringMethod
+		want   float64
+	}{
+		{models.ScoringMultiplicative, 0.7 * 0.5 * 0.9 * 0.3},
+		{models.ScoringAdditiveWeighted, (0.7 + 0.5 + 0.9 + 0.3) / 4},
+		{models.ScoringMaxBased, 0.9},
+		{models.ScoringIPCC, 0.3 * 0.7 * ((0.5 + 0.9) / 2)},
+	}
+
+	for _, tc := range cases {
+		t.Run(string(tc.method), func(t *testing.T) {
+			got := scorerWithMethod(t, tc.method).Score(sampleRisk())
+			if !almostEqual(got, tc.want) {
+				t.Errorf("Score=%v, se esperaba %v", got, tc.want)
+			}
+			t.Logf("✓ %s: Score=%.4f", tc.method, got)
+		})
+	}
+}
+
+func TestMethods_ScoreWithinRange(t *testing.T) {
+	worst := models.RiskDetail{
+		Exposure:    models.LevelVeryHigh,
+		Sensitivity: models.LevelVeryHigh,
+		Consequence: models.LevelVeryHigh,
+		Probability: models.LevelVeryHigh,
+	}
+	for _, method := range []models.RiskScoringMethod{
+		models.ScoringMultiplicative, models.ScoringAdditiveWeighted, models.ScoringMaxBased, models.ScoringIPCC,
+	} {
+		got := scorerWithMethod(t, method).Score(worst)
+		if got < 0 || got > 1 {
+			t.Errorf("%s: Score=%v fuera de [0, 1]", method, got)
+		}
+	}
+}
+
+func TestRiskWeights_OverrideDefaults(t *testing.T) {
+	cfg := scoring.DefaultConfig()
+	cfg.Method = models.ScoringAdditiveWeighted
+	cfg.RiskWeights = map[int64]models.RiskFactorWeights{
+		1: {Exposure: 0, Sensitivity: 0, Consequence: 1, Probability: 0},
+	}
+	scorer, err := scoring.New(cfg)
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+
+	risk := sampleRisk()
+	if got := scorer.Score(risk); !almostEqual(got, 0.9) {
+		t.Errorf("Riesgo con pesos propios: Score=%v, se esperaba 0.9", got)
+	}
+
+	risk.ID = 2
+	if got := scorer.Score(risk); !almostEqual(got, 0.6) {
+		t.Errorf("Riesgo con pesos por defecto: Score=%v, se esperaba 0.6", got)
+	}
+}
+
+// ============================================================================
+// VALIDATION TESTS
+// ============================================================================
+
+func TestValidate_Errors(t *testing.T) {
+	cases := map[string]func(cfg *models.RiskScoringConfig){
+		"método desconocido": func(cfg *models.RiskScoringConfig) {
+			cfg.Method = "unknown"
+		},
+		"nivel ausente": func(cfg *models.RiskScoringConfig) {
+			delete(cfg.LevelValues, models.LevelHigh)
+		},
+		"valores no crecientes": func(cfg *models.RiskScoringConfig) {
+			cfg.LevelValues[models.LevelLow] = 0.6
+		},
+		"peso negativo": func(cfg *models.RiskScoringConfig) {
+			cfg.Weights.Exposure = -1
+		},
+		"pesos a cero": func(cfg *models.RiskScoringConfig) {
+			cfg.Weights = models.RiskFactorWeights{}
+		},
+		"umbrales no crecientes": func(cfg *models.RiskScoringConfig) {
+			cfg.Thresholds.Medium = 0.3
+		},
+	}
+
+	for name, mutate := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := scoring.DefaultConfig()
+			mutate(&cfg)
+			if _, err := scoring.New(cfg); err == nil {
+				t.Error("Se esperaba error de validación")
+			}
+		})
+	}
+
+	if err := scoring.Validate(scoring.DefaultConfig()); err != nil {
+		t.Errorf("La configuración por defecto debería ser válida: %v", err)
+	}
+}
+
+// ============================================================================
+// AGGREGATION TESTS
+// ============================================================================
+
+func scoredRisks() []models.RiskDetail {
+	return []models.RiskDetail{
+		{Risk: models.Risk{ID: 1}, RiskScore: 0.9},
+		{Risk: models.Risk{ID: 2}, RiskScore: 0.1},
+		{Risk: models.Risk{ID: 3}, RiskScore: 0.2},
+	}
+}
+
+func TestAggregate_Methods(t *testing.T) {
+	cases := []struct {
+		method models.RiskAggregationMethod
+		want   float64
+	}{
+		{models.AggregationMean, (0.9 + 0.1 + 0.2) / 3},
+		{models.AggregationMax, 0.9},
+		{models.AggregationWeighted, (3*0.9 + 0.1 + 0*0.2) / 4},
+		{models.AggregationProbabilistic, 1 - (0.1 * 0.9 * 0.8)},
+	}
+
+	for _, tc := range cases {
+		t.Run(string(tc.method), func(t *testing.T) {
+			cfg := scoring.DefaultConfig()
+			cfg.Aggregation = tc.method
+			cfg.RiskImportance = map[int64]float64{1: 3, 3: 0}
+			scorer, err := scoring.New(cfg)
+			if err != nil {
+				t.Fatalf("Error inesperado: %v", err)
+			}
+
+			got := scorer.Aggregate(scoredRisks())
+			if !almostEqual(got, tc.want) {
+				t.Errorf("Aggregate=%v, se esperaba %v", got, tc.want)
+			}
+			t.Logf("✓ %s: TotalRisk=%.4f", tc.method, got)
+		})
+	}
+}
+
+func TestAggregate_NoRisks(t *testing.T) {
+	if got := scoring.Default().Aggregate(nil); got != 0 {
+		t.Errorf("Aggregate(nil)=%v, se esperaba 0", got)
+	}
+}
+
+func TestAggregate_InvalidConfig(t *testing.T) {
+	cfg := scoring.DefaultConfig()
+	cfg.Aggregation = "median"
+	if _, err := scoring.New(cfg); err == nil {
+		t.Error("Se esperaba error por método de agregación desconocido")
+	}
+
+	cfg = scoring.DefaultConfig()
+	cfg.RiskImportance = map[int64]float64{1: -1}
+	if _, err := scoring.New(cfg); err == nil {
+		t.Error("Se esperaba error por importancia negativa")
+	}
+}
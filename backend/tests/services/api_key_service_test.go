@@ -0,0 +1,206 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/application/services"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/authz"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/repository"
+)
+
+// ============================================================================
+// MOCK REPOSITORY PARA API KEY SERVICE
+// ============================================================================
+
+type mockAPIKeyRepo struct {
+	keys   map[int64]*models.APIKey
+	nextID int64
+}
+
+func newMockAPIKeyRepo() *mockAPIKeyRepo {
+	return &mockAPIKeyRepo{keys: make(map[int64]*models.APIKey), nextID: 1}
+}
+
+func (m *mockAPIKeyRepo) Create(ctx context.Context, key *models.APIKey) error {
+	key.ID = m.nextID
+	m.nextID++
+	key.CreatedAt = time.Now()
+	copied := *key
+	m.keys[key.ID] = &copied
+	return nil
+}
+func (m *mockAPIKeyRepo) GetByID(ctx context.Context, id int64) (*models.APIKey, error) {
+	if key, ok := m.keys[id]; ok {
+		copied := *key
+		return &copied, nil
+	}
+	return nil, nil
+}
+func (m *mockAPIKeyRepo) GetByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	for _, key := range m.keys {
+		if key.KeyHash == hash {
+			copied := *key
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+func (m *mockAPIKeyRepo) List(ctx context.Context) ([]models.APIKey, error) {
+	var result []models.APIKey
+	for _, key := range m.keys {
+		result = append(result, *key)
+	}
+	return result, nil
+}
+func (m *mockAPIKeyRepo) Revoke(ctx context.Context, id int64) error {
+	key, ok := m.keys[id]
+	if !ok {
+		return repository.ErrAPIKeyNotFound
+	}
+	if key.RevokedAt == nil {
+		now := time.Now()
+		key.RevokedAt = &now
+	}
+	return nil
+}
+func (m *mockAPIKeyRepo) TouchLastUsed(ctx context.Context, id int64, usedAt time.Time) error {
+	if key, ok := m.keys[id]; ok {
+		key.LastUsedAt = &usedAt
+	}
+	return nil
+}
+
+// ============================================================================
+// API KEY SERVICE TESTS
+// ============================================================================
+
+func TestAPIKeyService_Create_HashedAtRest(t *testing.T) {
+	repo := newMockAPIKeyRepo()
+	svc := services.NewAPIKeyService(repo, &mockAuditRecorder{})
+	ctx := context.Background()
+
+	created, err := svc.Create(ctx, adminID, &models.CreateAPIKeyRequest{Name: "BI", Role: models.RoleViewer})
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if !strings.HasPrefix(created.Key, created.APIKey.Prefix) {
+		t.Errorf("El prefijo %q debería identificar la clave", created.APIKey.Prefix)
+	}
+	if created.APIKey.RateLimit <= 0 {
+		t.Error("La clave debería tener un límite de peticiones por defecto")
+	}
+
+	stored := repo.keys[created.APIKey.ID]
+	if stored.KeyHash == "" || strings.Contains(stored.KeyHash, created.Key) {
+		t.Error("La clave solo debería guardarse hasheada")
+	}
+
+	key, err := svc.Authenticate(ctx, created.Key)
+	if err != nil {
+		t.Fatalf("La clave recién creada debería ser válida: %v", err)
+	}
+	if key.LastUsedAt == nil || repo.keys[key.ID].LastUsedAt == nil {
+		t.Error("Se debería registrar el último uso")
+	}
+
+	t.Logf("✓ API key creada: %s…", created.APIKey.Prefix)
+}
+
+func TestAPIKeyService_Revoke(t *testing.T) {
+	svc := services.NewAPIKeyService(newMockAPIKeyRepo(), &mockAuditRecorder{})
+	ctx := context.Background()
+
+	created, err := svc.Create(ctx, adminID, &models.CreateAPIKeyRequest{Name: "ERP", Role: models.RoleManager})
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+
+	revoked, err := svc.Revoke(ctx, created.APIKey.ID)
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if revoked.RevokedAt == nil {
+		t.Error("La clave debería quedar revocada")
+	}
+
+	if _, err := svc.Authenticate(ctx, created.Key); !errors.Is(err, models.ErrInvalidAPIKey) {
+		t.Errorf("Se esperaba ErrInvalidAPIKey para una clave revocada, got %v", err)
+	}
+	if _, err := svc.Revoke(ctx, 999); !errors.Is(err, models.ErrAPIKeyNotFound) {
+		t.Errorf("Se esperaba ErrAPIKeyNotFound, got %v", err)
+	}
+}
+
+func TestAPIKeyService_Create_ValidatesPermissions(t *testing.T) {
+	svc := services.NewAPIKeyService(newMockAPIKeyRepo(), &mockAuditRecorder{})
+	ctx := context.Background()
+
+	_, err := svc.Create(ctx, adminID, &models.CreateAPIKeyRequest{
+		Name:        "BI",
+		Role:        models.RoleViewer,
+		Permissions: []string{string(authz.OptimizationRun)},
+	})
+	if err == nil {
+		t.Error("No debería concederse un permiso ajeno al rol de la clave")
+	}
+
+	_, err = svc.Create(ctx, adminID, &models.CreateAPIKeyRequest{
+		Name:        "Provisioning",
+		Role:        models.RoleAdmin,
+		Permissions: []string{string(authz.UsersManage)},
+	})
+	if err == nil {
+		t.Error("Las API keys no deberían poder gestionar usuarios")
+	}
+
+	past := time.Now().Add(-time.Hour)
+	_, err = svc.Create(ctx, adminID, &models.CreateAPIKeyRequest{Name: "Caducada", Role: models.RoleViewer, ExpiresAt: &past})
+	if err == nil {
+		t.Error("Se esperaba error para una expiración en el pasado")
+	}
+}
+
+func TestAPIKeyService_Create_ScopeWithinCreator(t *testing.T) {
+	repo := newMockAPIKeyRepo()
+	svc := services.NewAPIKeyService(repo, &mockAuditRecorder{})
+	ctx := authz.WithScope(context.Background(), authz.Scope{Countries: []string{"ES", "PT"}})
+
+	// Sin ámbito propio la clave hereda el de quien la crea
+	inherited, err := svc.Create(ctx, adminID, &models.CreateAPIKeyRequest{Name: "BI", Role: models.RoleViewer})
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if got := repo.keys[inherited.APIKey.ID].Countries; len(got) != 2 {
+		t.Errorf("La clave debería heredar los países de quien la crea, got %v", got)
+	}
+
+	narrowed, err := svc.Create(ctx, adminID, &models.CreateAPIKeyRequest{
+		Name:       "ERP Portugal",
+		Role:       models.RoleManager,
+		Countries:  []string{"pt"},
+		ClusterIDs: []int64{3},
+	})
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if scope := authz.APIKeyScope(repo.keys[narrowed.APIKey.ID]); !scope.AllowsShop("PT", 3) || scope.AllowsShop("PT", 4) {
+		t.Errorf("Ámbito de la clave inesperado: %+v", scope)
+	}
+
+	// Un ámbito más amplio que el de quien la crea se rechaza
+	for _, req := range []*models.CreateAPIKeyRequest{
+		{Name: "Francia", Role: models.RoleViewer, Countries: []string{"FR"}},
+		{Name: "Clusters", Role: models.RoleViewer, ClusterIDs: []int64{1}},
+	} {
+		if _, err := svc.Create(ctx, adminID, req); err == nil {
+			t.Errorf("Se esperaba error para la clave %s fuera del ámbito", req.Name)
+		}
+	}
+
+	t.Log("✓ El ámbito de una API key queda dentro del de quien la crea")
+}
@@ -0,0 +1,351 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/application/services"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/interfaces/http/middleware"
+)
+
+// ============================================================================
+// MOCK REPOSITORIES PARA AUTH SERVICE
+// ============================================================================
+
+type mockUserRepoForAuth struct {
+	users  map[int64]*models.User
+	nextID int64
+}
+
+func newMockUserRepoForAuth() *mockUserRepoForAuth {
+	return &mockUserRepoForAuth{users: make(map[int64]*models.User), nextID: 1}
+}
+
+func (m *mockUserRepoForAuth) Create(ctx context.Context, user *models.User) error {
+	user.ID = m.nextID
+	m.nextID++
+	copied := *user
+	m.users[user.ID] = &copied
+	return nil
+}
+func (m *mockUserRepoForAuth) GetByID(ctx context.Context, id int64) (*models.User, error) {
+	if user, ok := m.users[id]; ok {
+		copied := *user
+		return &copied, nil
+	}
+	return nil, nil
+}
+func (m *mockUserRepoForAuth) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	for _, user := range m.users {
+		if strings.EqualFold(user.Email, email) {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+func (m *mockUserRepoForAuth) GetByOIDCIdentity(ctx context.Context, issuer, subject string) (*models.User, error) {
+	for _, user := range m.users {
+		if user.OIDCIssuer == issuer && user.OIDCSubject == subject {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+func (m *mockUserRepoForAuth) Update(ctx context.Context, user *models.User) error {
+	copied := *user
+	m.users[user.ID] = &copied
+	return nil
+}
+func (m *mockUserRepoForAuth) Delete(ctx context.Context, id int64) error {
+	delete(m.users, id)
+	return nil
+}
+func (m *mockUserRepoForAuth) List(ctx context.Context, filter *models.UserFilterRequest) ([]models.User, error) {
+	var result []models.User
+	for _, user := range m.users {
+		if filter != nil && filter.Role != "" && user.Role != filter.Role {
+			continue
+		}
+		if filter != nil && filter.Active != nil && user.Active != *filter.Active {
+			continue
+		}
+		result = append(result, *user)
+	}
+	return result, nil
+}
+func (m *mockUserRepoForAuth) EmailExists(ctx context.Context, email string) (bool, error) {
+	user, _ := m.GetByEmail(ctx, email)
+	return user != nil, nil
+}
+
+type mockSessionRepoForAuth struct {
+	sessions map[int64]*models.AuthSession
+	tokens   map[string]*models.RefreshToken
+	nextID   int64
+}
+
+func newMockSessionRepoForAuth() *mockSessionRepoForAuth {
+	return &mockSessionRepoForAuth{
+		sessions: make(map[int64]*models.AuthSession),
+		tokens:   make(map[string]*models.RefreshToken),
+		nextID:   1,
+	}
+}
+
+func (m *mockSessionRepoForAuth) CreateSession(ctx context.Context, session *models.AuthSession) error {
+	session.ID = m.nextID
+	m.nextID++
+	session.CreatedAt = time.Now()
+	copied := *session
+	m.sessions[session.ID] = &copied
+	return nil
+}
+func (m *mockSessionRepoForAuth) GetSession(ctx context.Context, id int64) (*models.AuthSession, error) {
+	if session, ok := m.sessions[id]; ok {
+		copied := *session
+		return &copied, nil
+	}
+	return nil, nil
+}
+func (m *mockSessionRepoForAuth) RevokeSession(ctx context.Context, id int64) error {
+	if session, ok := m.sessions[id]; ok && session.RevokedAt == nil {
+		now := time.Now()
+		session.RevokedAt = &now
+	}
+	return nil
+}
+func (m *mockSessionRepoForAuth) RevokeUserSessions(ctx context.Context, userID int64) error {
+	for id, session := range m.sessions {
+		if session.UserID == userID {
+			_ = m.RevokeSession(ctx, id)
+		}
+	}
+	return nil
+}
+func (m *mockSessionRepoForAuth) IsSessionRevoked(ctx context.Context, id int64) (bool, error) {
+	session, ok := m.sessions[id]
+	return !ok || session.RevokedAt != nil, nil
+}
+func (m *mockSessionRepoForAuth) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	token.ID = m.nextID
+	m.nextID++
+	copied := *token
+	m.tokens[token.TokenHash] = &copied
+	return nil
+}
+func (m *mockSessionRepoForAuth) GetRefreshTokenByHash(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	if token, ok := m.tokens[hash]; ok {
+		copied := *token
+		return &copied, nil
+	}
+	return nil, nil
+}
+func (m *mockSessionRepoForAuth) MarkRefreshTokenUsed(ctx context.Context, id int64) (bool, error) {
+	for _, token := range m.tokens {
+		if token.ID == id {
+			if token.UsedAt != nil {
+				return false, nil
+			}
+			now := time.Now()
+			token.UsedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ============================================================================
+// HELPER
+// ============================================================================
+
+func createAuthService(t *testing.T) (services.AuthService, *middleware.JWTService, *mockSessionRepoForAuth) {
+	t.Helper()
+	svc, jwtService, _, sessions := createAuthServiceWithRepos(t)
+	return svc, jwtService, sessions
+}
+
+func createAuthServiceWithRepos(t *testing.T) (services.AuthService, *middleware.JWTService, *mockUserRepoForAuth, *mockSessionRepoForAuth) {
+	t.Helper()
+	users := newMockUserRepoForAuth()
+	sessions := newMockSessionRepoForAuth()
+	jwtService := middleware.NewJWTService(middleware.JWTConfig{
+		SecretKey:     "test-secret",
+		TokenExpiry:   15 * time.Minute,
+		RefreshExpiry: 24 * time.Hour,
+		Issuer:        "test",
+	}, sessions)
+	svc := services.NewAuthService(users, sessions, jwtService, &mockAuditRecorder{})
+
+	if _, err := svc.Register(context.Background(), &models.RegisterRequest{
+		Email:    "ana@example.com",
+		Password: "password123",
+	}); err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	return svc, jwtService, users, sessions
+}
+
+func login(t *testing.T, svc services.AuthService) *models.AuthResponse {
+	t.Helper()
+	resp, err := svc.Login(context.Background(), &models.LoginRequest{Email: "ana@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	return resp
+}
+
+func isRevoked(t *testing.T, jwtService *middleware.JWTService, token string) bool {
+	t.Helper()
+	claims, err := jwtService.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	revoked, err := jwtService.IsRevoked(context.Background(), claims)
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	return revoked
+}
+
+// ============================================================================
+// AUTH SERVICE TESTS
+// ============================================================================
+
+func TestAuthService_Login_ReturnsRefreshToken(t *testing.T) {
+	svc, _, sessions := createAuthService(t)
+
+	resp := login(t, svc)
+	if resp.Token == "" || resp.RefreshToken == "" {
+		t.Fatal("Se esperaban access token y refresh token")
+	}
+
+	// El refresh token solo se guarda hasheado
+	for hash := range sessions.tokens {
+		if hash == resp.RefreshToken {
+			t.Error("El refresh token no debería guardarse en claro")
+		}
+	}
+
+	t.Logf("✓ Login: refresh token expira en %v", time.Unix(resp.RefreshExpiresAt, 0))
+}
+
+func TestAuthService_Register_AlwaysViewer(t *testing.T) {
+	svc, _, _ := createAuthService(t)
+
+	resp, err := svc.Register(context.Background(), &models.RegisterRequest{
+		Email:    "nuevo@example.com",
+		Password: "password123",
+	})
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if resp.User.Role != models.RoleViewer {
+		t.Errorf("El registro público debería asignar viewer, got %s", resp.User.Role)
+	}
+}
+
+func TestAuthService_Refresh_RotatesToken(t *testing.T) {
+	svc, _, _ := createAuthService(t)
+	ctx := context.Background()
+	first := login(t, svc)
+
+	second, err := svc.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Error("El refresh token debería rotar en cada uso")
+	}
+
+	if _, err := svc.Refresh(ctx, second.RefreshToken); err != nil {
+		t.Fatalf("El nuevo refresh token debería ser válido: %v", err)
+	}
+
+	t.Log("✓ Refresh: token rotado correctamente")
+}
+
+func TestAuthService_Refresh_ReuseRevokesSession(t *testing.T) {
+	svc, jwtService, _ := createAuthService(t)
+	ctx := context.Background()
+	first := login(t, svc)
+
+	second, err := svc.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+
+	// Reutilizar el token ya canjeado revoca toda la sesión
+	_, err = svc.Refresh(ctx, first.RefreshToken)
+	if !errors.Is(err, models.ErrRefreshTokenReused) {
+		t.Fatalf("Se esperaba ErrRefreshTokenReused, got %v", err)
+	}
+
+	if _, err := svc.Refresh(ctx, second.RefreshToken); err == nil {
+		t.Error("El último refresh token de la sesión debería haberse invalidado")
+	}
+	if !isRevoked(t, jwtService, second.Token) {
+		t.Error("El access token de la sesión debería estar revocado")
+	}
+
+	t.Logf("✓ Reutilización detectada: %v", err)
+}
+
+func TestAuthService_Refresh_InvalidToken(t *testing.T) {
+	svc, _, _ := createAuthService(t)
+
+	_, err := svc.Refresh(context.Background(), "no-existe")
+	if !errors.Is(err, models.ErrInvalidToken) {
+		t.Errorf("Se esperaba ErrInvalidToken, got %v", err)
+	}
+}
+
+func TestAuthService_Logout_CurrentSession(t *testing.T) {
+	svc, jwtService, _ := createAuthService(t)
+	ctx := context.Background()
+	laptop := login(t, svc)
+	phone := login(t, svc)
+
+	claims, _ := jwtService.ValidateToken(laptop.Token)
+	if err := svc.Logout(ctx, claims.UserID, claims.SessionID, false); err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+
+	if !isRevoked(t, jwtService, laptop.Token) {
+		t.Error("La sesión cerrada debería estar revocada")
+	}
+	if _, err := svc.Refresh(ctx, laptop.RefreshToken); err == nil {
+		t.Error("El refresh token de la sesión cerrada no debería ser válido")
+	}
+	if isRevoked(t, jwtService, phone.Token) {
+		t.Error("Las demás sesiones deberían seguir activas")
+	}
+
+	t.Log("✓ Logout: solo la sesión actual se revoca")
+}
+
+func TestAuthService_Logout_AllDevices(t *testing.T) {
+	svc, jwtService, _ := createAuthService(t)
+	ctx := context.Background()
+	laptop := login(t, svc)
+	phone := login(t, svc)
+
+	claims, _ := jwtService.ValidateToken(laptop.Token)
+	if err := svc.Logout(ctx, claims.UserID, claims.SessionID, true); err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+
+	if !isRevoked(t, jwtService, laptop.Token) || !isRevoked(t, jwtService, phone.Token) {
+		t.Error("Todas las sesiones del usuario deberían estar revocadas")
+	}
+	if _, err := svc.Refresh(ctx, phone.RefreshToken); err == nil {
+		t.Error("Los refresh tokens de todas las sesiones deberían invalidarse")
+	}
+
+	t.Log("✓ Logout: todas las sesiones revocadas")
+}
@@ -0,0 +1,279 @@
+package services_test
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/application/services"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/repository"
+)
+
+// ============================================================================
+// MOCK REPOSITORIES PARA COST SERVICE
+// ============================================================================
+
+type mockCostRepo struct {
+	invoices []models.MeasureInvoice
+	variance map[models.CostVarianceGroup][]models.CostVarianceRow
+	shopRepo *mockShopRepoForService
+	nextID   int64
+}
+
+func newMockCostRepo(shopRepo *mockShopRepoForService) *mockCostRepo {
+	return &mockCostRepo{
+		variance: make(map[models.CostVarianceGroup][]models.CostVarianceRow),
+		shopRepo: shopRepo,
+		nextID:   1,
+	}
+}
+
+func (m *mockCostRepo) CreateInvoice(ctx context.Context, invoice *models.MeasureInvoice) error {
+	// Como el índice único de la tabla de facturas
+	for _, inv := range m.invoices {
+		if inv.ShopID == invoice.ShopID && inv.MeasureName == invoice.MeasureName && inv.InvoiceNumber == invoice.InvoiceNumber {
+			return repository.ErrDuplicateInvoiceNumber
+		}
+	}
+
+	invoice.ID = m.nextID
+	invoice.CreatedAt = time.Now()
+	m.nextID++
+	m.invoices = append(m.invoices, *invoice)
+
+	var total float64
+	for _, inv := range m.invoices {
+		if inv.ShopID == invoice.ShopID && inv.MeasureName == invoice.MeasureName {
+			total += inv.Amount
+		}
+	}
+	measure := m.shopRepo.shopMeasures[invoice.ShopID][invoice.MeasureName]
+	measure.ActualCost = &total
+	measure.Invoices++
+	return nil
+}
+
+func (m *mockCostRepo) ListInvoices(ctx context.Context, shopID int64) ([]models.MeasureInvoice, error) {
+	var result []models.MeasureInvoice
+	for _, inv := range m.invoices {
+		if inv.ShopID == shopID {
+			result = append(result, inv)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockCostRepo) GetVariance(ctx context.Context, groupBy models.CostVarianceGroup) ([]models.CostVarianceRow, error) {
+	return m.variance[groupBy], nil
+}
+
+// setShopMeasure aplica una medida a una tienda con el estado indicado
+func (m *mockShopRepoForService) setShopMeasure(shopID int64, name string, status models.MeasureStatus) {
+	if m.shopMeasures[shopID] == nil {
+		m.shopMeasures[shopID] = make(map[string]*models.ShopMeasure)
+	}
+	m.shopMeasures[shopID][name] = &models.ShopMeasure{
+		ShopID:  shopID,
+		Measure: models.Measure{Name: name},
+		Status:  status,
+	}
+}
+
+// ============================================================================
+// COST SERVICE TESTS
+// ============================================================================
+
+func TestCostService_RecordInvoice(t *testing.T) {
+	shopRepo := newMockShopRepoForService()
+	shopRepo.setShopMeasure(1, "BMS", models.MeasureStatusInProgress)
+	costRepo := newMockCostRepo(shopRepo)
+	recorder := &mockAuditRecorder{}
+	svc := services.NewCostService(costRepo, shopRepo, recorder)
+	ctx := context.Background()
+	invoicedAt := time.Now().AddDate(0, 0, -1)
+
+	for i, amount := range []float64{600, 650} {
+		_, err := svc.RecordInvoice(ctx, 1, &models.RecordInvoiceRequest{
+			MeasureName:   "BMS",
+			Amount:        amount,
+			InvoiceNumber: []string{"F-001", "F-002"}[i],
+			InvoicedAt:    invoicedAt,
+		})
+		if err != nil {
+			t.Fatalf("Error inesperado: %v", err)
+		}
+	}
+
+	if cost := shopRepo.shopMeasures[1]["BMS"].ActualCost; cost == nil || *cost != 1250 {
+		t.Errorf("ActualCost=%v, esperado la suma de las facturas (1250)", cost)
+	}
+
+	invoices, err := svc.ListInvoices(ctx, 1)
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if len(invoices) != 2 {
+		t.Errorf("Se esperaban 2 facturas, se obtuvieron %d", len(invoices))
+	}
+
+	if len(recorder.entries) != 2 || recorder.entries[0].action != models.AuditMeasureInvoiced {
+		t.Errorf("Se esperaban 2 registros %s, se obtuvo %v", models.AuditMeasureInvoiced, recorder.actions())
+	}
+
+	t.Logf("✓ RecordInvoice acumula el coste real y audita cada factura")
+}
+
+func TestCostService_RecordInvoice_DuplicateNumber(t *testing.T) {
+	shopRepo := newMockShopRepoForService()
+	shopRepo.setShopMeasure(1, "BMS", models.MeasureStatusInProgress)
+	svc := services.NewCostService(newMockCostRepo(shopRepo), shopRepo, &mockAuditRecorder{})
+	ctx := context.Background()
+	req := &models.RecordInvoiceRequest{MeasureName: "BMS", Amount: 600, InvoiceNumber: "F-001", InvoicedAt: time.Now().AddDate(0, 0, -1)}
+
+	if _, err := svc.RecordInvoice(ctx, 1, req); err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if _, err := svc.RecordInvoice(ctx, 1, req); !hasErrorCode(err, models.ErrDuplicateInvoice) {
+		t.Fatalf("Se esperaba DUPLICATE_INVOICE, se obtuvo %v", err)
+	}
+
+	t.Logf("✓ RecordInvoice rechaza números de factura repetidos con un conflicto")
+}
+
+func TestCostService_InvoicesOwnActualCost(t *testing.T) {
+	shopRepo := newMockShopRepoForService()
+	shopRepo.setShopMeasure(1, "BMS", models.MeasureStatusInProgress)
+	costSvc := services.NewCostService(newMockCostRepo(shopRepo), shopRepo, &mockAuditRecorder{})
+	shopSvc := createShopServiceWithRepo(shopRepo)
+	ctx := context.Background()
+
+	_, err := costSvc.RecordInvoice(ctx, 1, &models.RecordInvoiceRequest{
+		MeasureName: "BMS", Amount: 600, InvoiceNumber: "F-001", InvoicedAt: time.Now().AddDate(0, 0, -1),
+	})
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+
+	// Con facturas el coste real no se puede indicar al cambiar de estado
+	cost := 900.0
+	_, err = shopSvc.TransitionMeasure(ctx, 1, &models.MeasureTransitionRequest{
+		MeasureName: "BMS", Status: models.MeasureStatusCompleted, ActualCost: &cost,
+	})
+	if !hasErrorCode(err, models.ErrInvalidMeasureTransition("")) {
+		t.Fatalf("Se esperaba error de transición, se obtuvo %v", err)
+	}
+
+	// Sin coste explícito la medida se completa con el total facturado
+	measure, err := shopSvc.TransitionMeasure(ctx, 1, &models.MeasureTransitionRequest{
+		MeasureName: "BMS", Status: models.MeasureStatusCompleted,
+	})
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if measure.ActualCost == nil || *measure.ActualCost != 600 {
+		t.Errorf("ActualCost=%v, esperado el total facturado (600)", measure.ActualCost)
+	}
+
+	t.Logf("✓ El coste real de una medida con facturas solo lo fijan sus facturas")
+}
+
+func TestCostService_RecordInvoice_Rules(t *testing.T) {
+	shopRepo := newMockShopRepoForService()
+	shopRepo.setShopMeasure(1, "BMS", models.MeasureStatusApproved)
+	svc := services.NewCostService(newMockCostRepo(shopRepo), shopRepo, &mockAuditRecorder{})
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		req     models.RecordInvoiceRequest
+		wantErr *models.AppError
+	}{
+		{"medida no aplicada", models.RecordInvoiceRequest{MeasureName: "Plan emergencia", Amount: 100, InvoiceNumber: "F-1", InvoicedAt: time.Now().AddDate(0, 0, -1)}, models.ErrMeasureNotApplied},
+		{"medida no iniciada", models.RecordInvoiceRequest{MeasureName: "BMS", Amount: 100, InvoiceNumber: "F-2", InvoicedAt: time.Now().AddDate(0, 0, -1)}, models.ErrMeasureNotStarted},
+		{"fecha futura", models.RecordInvoiceRequest{MeasureName: "BMS", Amount: 100, InvoiceNumber: "F-3", InvoicedAt: time.Now().AddDate(0, 0, 2)}, models.ErrInvalidInput("")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.RecordInvoice(ctx, 1, &tt.req)
+			if !hasErrorCode(err, tt.wantErr) {
+				t.Errorf("Se esperaba %v, se obtuvo %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	t.Logf("✓ RecordInvoice valida el estado de la medida y la fecha")
+}
+
+func TestCostService_GetVarianceReport(t *testing.T) {
+	shopRepo := newMockShopRepoForService()
+	costRepo := newMockCostRepo(shopRepo)
+	costRepo.variance[models.CostVarianceByType] = []models.CostVarianceRow{
+		{Group: "material", ShopMeasures: 4, EstimatedCost: 4000, ActualCost: 5000},
+		{Group: "natural", ShopMeasures: 1, EstimatedCost: 1000, ActualCost: 900},
+	}
+	svc := services.NewCostService(costRepo, shopRepo, &mockAuditRecorder{})
+
+	report, err := svc.GetVarianceReport(context.Background(), models.CostVarianceByType)
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+
+	if report.Groups[0].Variance != 1000 || report.Groups[0].VariancePercentage != 25 {
+		t.Errorf("material: variance=%v (%.2f%%), esperado 1000 (25%%)", report.Groups[0].Variance, report.Groups[0].VariancePercentage)
+	}
+	if report.Groups[1].Variance != -100 {
+		t.Errorf("natural: variance=%v, esperado -100", report.Groups[1].Variance)
+	}
+	if report.Total.ShopMeasures != 5 || report.Total.Variance != 900 || report.Total.VariancePercentage != 18 {
+		t.Errorf("Total=%+v, esperado 5 medidas y desviación 900 (18%%)", report.Total)
+	}
+
+	t.Logf("✓ GetVarianceReport calcula la desviación por grupo y total")
+}
+
+func TestCostService_EstimateCosts(t *testing.T) {
+	shopRepo := newMockShopRepoForService()
+	costRepo := newMockCostRepo(shopRepo)
+	costRepo.variance[models.CostVarianceByMeasure] = []models.CostVarianceRow{
+		{Group: "BMS", ShopMeasures: 3, EstimatedCost: 3000, ActualCost: 4500},
+		{Group: "Deshumidificador", ShopMeasures: 1, EstimatedCost: 800, ActualCost: 2400},
+	}
+	costRepo.variance[models.CostVarianceByType] = []models.CostVarianceRow{
+		{Group: string(models.MeasureTypeMaterial), ShopMeasures: 4, EstimatedCost: 3800, ActualCost: 4180},
+	}
+	svc := services.NewCostService(costRepo, shopRepo, &mockAuditRecorder{})
+
+	measures := []models.Measure{
+		{Name: "BMS", EstimatedCost: 1000, Type: models.MeasureTypeMaterial},
+		{Name: "Deshumidificador", EstimatedCost: 800, Type: models.MeasureTypeMaterial},
+		{Name: "Jardín de lluvia", EstimatedCost: 4400, Type: models.MeasureTypeNatural},
+	}
+
+	corrected, estimates, err := svc.EstimateCosts(context.Background(), measures)
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+
+	tests := []struct {
+		cost  float64
+		basis models.CostVarianceGroup
+	}{
+		{1500, models.CostVarianceByMeasure}, // Histórico propio suficiente
+		{880, models.CostVarianceByType},     // Pocas muestras propias: se usa el tipo
+		{4400, ""},                           // Sin histórico: coste de catálogo
+	}
+	for i, tt := range tests {
+		if math.Abs(corrected[i].EstimatedCost-tt.cost) > 0.01 || estimates[i].Basis != tt.basis {
+			t.Errorf("%s: coste=%v base=%q, esperado %v base=%q",
+				measures[i].Name, corrected[i].EstimatedCost, estimates[i].Basis, tt.cost, tt.basis)
+		}
+	}
+	if measures[0].EstimatedCost != 1000 {
+		t.Error("EstimateCosts no debería modificar las medidas de entrada")
+	}
+
+	t.Logf("✓ EstimateCosts corrige por medida, por tipo o mantiene el catálogo")
+}
@@ -0,0 +1,249 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/application/services"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/repository"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/scoring"
+)
+
+// ============================================================================
+// MOCK REPOSITORIES PARA DASHBOARD SERVICE
+// ============================================================================
+
+type mockAnalyticsRepo struct {
+	groups    []models.PortfolioGroup
+	pairs     []models.RiskMatrixPair
+	err       error
+	lastQuery *models.PortfolioAnalyticsQuery
+}
+
+func (m *mockAnalyticsRepo) GetPortfolioGroups(ctx context.Context, query *models.PortfolioAnalyticsQuery) ([]models.PortfolioGroup, error) {
+	m.lastQuery = query
+	return m.groups, m.err
+}
+
+func (m *mockAnalyticsRepo) GetRiskMatrixPairs(ctx context.Context, query *models.RiskMatrixQuery) ([]models.RiskMatrixPair, error) {
+	return m.pairs, m.err
+}
+
+// mockStatsShopRepo devuelve unas estadísticas fijas y registra la consulta y los umbrales recibidos
+type mockStatsShopRepo struct {
+	*mockShopRepoForService
+	stats      *models.DashboardStats
+	err        error
+	query      *models.DashboardStatsQuery
+	thresholds models.RiskLevelThresholds
+}
+
+func (m *mockStatsShopRepo) GetStats(ctx context.Context, query *models.DashboardStatsQuery, thresholds models.RiskLevelThresholds) (*models.DashboardStats, error) {
+	m.query = query
+	m.thresholds = thresholds
+	return m.stats, m.err
+}
+
+func newDashboardService(shopRepo repository.ShopRepository, analyticsRepo *mockAnalyticsRepo) services.DashboardService {
+	return services.NewDashboardService(shopRepo, &mockSnapshotRepoForService{}, analyticsRepo, scoring.Static(scoring.Default()))
+}
+
+// ============================================================================
+// DASHBOARD SERVICE TESTS
+// ============================================================================
+
+func TestDashboardService_GetStats(t *testing.T) {
+	repo := &mockStatsShopRepo{
+		mockShopRepoForService: newMockShopRepoForService(),
+		stats: &models.DashboardStats{TotalShops: 6, ShopsByLevel: map[models.Level]int64{
+			models.LevelVeryLow:  1,
+			models.LevelMedium:   2,
+			models.LevelHigh:     2,
+			models.LevelVeryHigh: 1,
+		}},
+	}
+	svc := newDashboardService(repo, &mockAnalyticsRepo{})
+
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	stats, err := svc.GetStats(context.Background(), &models.DashboardStatsQuery{Country: "España", From: &from})
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+
+	// Las tiendas de alto riesgo son las de nivel alto o muy alto según los umbrales vigentes
+	if stats.HighRiskShops != 3 {
+		t.Errorf("Se esperaban 3 tiendas de alto riesgo, se obtuvo %d", stats.HighRiskShops)
+	}
+	if repo.thresholds != scoring.DefaultConfig().Thresholds {
+		t.Errorf("Umbrales inesperados: %+v", repo.thresholds)
+	}
+	if repo.query.Interval != "month" || repo.query.Country != "España" {
+		t.Errorf("Consulta inesperada: %+v", repo.query)
+	}
+
+	t.Logf("✓ GetStats: %d de %d tiendas de alto riesgo", stats.HighRiskShops, stats.TotalShops)
+}
+
+func TestDashboardService_GetStats_InvalidPeriod(t *testing.T) {
+	repo := &mockStatsShopRepo{mockShopRepoForService: newMockShopRepoForService()}
+	svc := newDashboardService(repo, &mockAnalyticsRepo{})
+
+	from := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 5, 31, 0, 0, 0, 0, time.UTC)
+	_, err := svc.GetStats(context.Background(), &models.DashboardStatsQuery{From: &from, To: &to})
+	if !hasErrorCode(err, models.ErrInvalidInput("")) {
+		t.Errorf("Se esperaba error de entrada inválida, se obtuvo %v", err)
+	}
+	if repo.query != nil {
+		t.Error("No se debería consultar el repositorio con un periodo inválido")
+	}
+
+	t.Logf("✓ GetStats rechaza periodos con el final anterior al inicio")
+}
+
+func TestDashboardService_GetStats_DatabaseError(t *testing.T) {
+	repo := &mockStatsShopRepo{mockShopRepoForService: newMockShopRepoForService(), err: errors.New("connection refused")}
+	svc := newDashboardService(repo, &mockAnalyticsRepo{})
+
+	_, err := svc.GetStats(context.Background(), &models.DashboardStatsQuery{})
+	if !hasErrorCode(err, models.ErrDatabase(nil)) {
+		t.Errorf("Se esperaba error de base de datos, se obtuvo %v", err)
+	}
+
+	t.Logf("✓ GetStats propaga los errores de base de datos")
+}
+
+func TestDashboardService_GetAnalytics(t *testing.T) {
+	repo := &mockAnalyticsRepo{groups: []models.PortfolioGroup{
+		{Key: "España", Name: "España", Shops: 3, RiskPairs: 8, CoveredPairs: 2},
+		{Key: "Francia", Name: "Francia", Shops: 1},
+	}}
+	svc := newDashboardService(newMockShopRepoForService(), repo)
+
+	clusterID := int64(2)
+	query := &models.PortfolioAnalyticsQuery{ShopFilter: models.ShopFilter{ClusterID: &clusterID}}
+	analytics, err := svc.GetAnalytics(context.Background(), query)
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+
+	// Sin agrupación ni intervalos se usan los valores por defecto
+	if analytics.GroupBy != models.PortfolioByCountry || repo.lastQuery.Bins != 10 || *repo.lastQuery.ClusterID != 2 {
+		t.Errorf("Consulta inesperada: %+v", repo.lastQuery)
+	}
+	if len(analytics.Groups) != 2 || analytics.Groups[0].CoveragePercentage != 25 || analytics.Groups[1].CoveragePercentage != 0 {
+		t.Errorf("Cobertura inesperada: %+v", analytics.Groups)
+	}
+
+	t.Logf("✓ GetAnalytics: %d grupos, cobertura de %s %.0f%%", len(analytics.Groups), analytics.Groups[0].Name, analytics.Groups[0].CoveragePercentage)
+}
+
+func TestDashboardService_GetAnalytics_DatabaseError(t *testing.T) {
+	svc := newDashboardService(newMockShopRepoForService(), &mockAnalyticsRepo{err: errors.New("connection refused")})
+
+	_, err := svc.GetAnalytics(context.Background(), &models.PortfolioAnalyticsQuery{GroupBy: models.PortfolioByRisk})
+	if !hasErrorCode(err, models.ErrDatabase(nil)) {
+		t.Errorf("Se esperaba error de base de datos, se obtuvo %v", err)
+	}
+
+	t.Logf("✓ GetAnalytics propaga los errores de base de datos")
+}
+
+// riskMatrixCell busca la celda de la matriz con la probabilidad y la consecuencia indicadas
+func riskMatrixCell(t *testing.T, matrix *models.RiskMatrix, probability, consequence models.Level) models.RiskMatrixCell {
+	t.Helper()
+	for _, cell := range matrix.Cells {
+		if cell.Probability == probability && cell.Consequence == consequence {
+			return cell
+		}
+	}
+	t.Fatalf("La matriz no tiene la celda %s/%s", probability, consequence)
+	return models.RiskMatrixCell{}
+}
+
+func riskMatrixRepo() *mockAnalyticsRepo {
+	return &mockAnalyticsRepo{pairs: []models.RiskMatrixPair{
+		{ShopID: 1, ShopLocation: "Madrid", RiskID: 1, RiskName: "Inundación", Probability: models.LevelHigh, Consequence: models.LevelVeryHigh,
+			MeasureTypes: []models.MeasureType{models.MeasureTypeMaterial, models.MeasureTypeNatural}},
+		{ShopID: 1, ShopLocation: "Madrid", RiskID: 2, RiskName: "Ola de calor", Probability: models.LevelHigh, Consequence: models.LevelVeryHigh,
+			MeasureTypes: []models.MeasureType{}},
+		{ShopID: 2, ShopLocation: "Sevilla", RiskID: 2, RiskName: "Ola de calor", Probability: models.LevelVeryLow, Consequence: models.LevelLow,
+			MeasureTypes: []models.MeasureType{models.MeasureTypeNatural, models.MeasureTypeImmaterial}},
+	}}
+}
+
+func TestDashboardService_GetRiskMatrix(t *testing.T) {
+	svc := newDashboardService(newMockShopRepoForService(), riskMatrixRepo())
+
+	inherent, err := svc.GetRiskMatrix(context.Background(), &models.RiskMatrixQuery{Stage: models.RiskMatrixInherent})
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if len(inherent.Cells) != 25 || inherent.TotalPairs != 3 {
+		t.Fatalf("Matriz inesperada: %d celdas, %d pares", len(inherent.Cells), inherent.TotalPairs)
+	}
+	if inherent.Cells[0].Probability != models.LevelVeryHigh || inherent.Cells[0].Consequence != models.LevelVeryLow {
+		t.Errorf("Orden de celdas inesperado: %+v", inherent.Cells[0])
+	}
+	if cell := riskMatrixCell(t, inherent, models.LevelHigh, models.LevelVeryHigh); cell.Pairs != 2 || cell.Shops != 1 || cell.Items != nil {
+		t.Errorf("Celda alta/muy alta inesperada: %+v", cell)
+	}
+
+	// Sin etapa se usan los niveles residuales: la inundación de Madrid baja un nivel en
+	// los dos ejes y los niveles de Sevilla no bajan del mínimo
+	residual, err := svc.GetRiskMatrix(context.Background(), &models.RiskMatrixQuery{})
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if residual.Stage != models.RiskMatrixResidual {
+		t.Errorf("Etapa inesperada: %s", residual.Stage)
+	}
+	if cell := riskMatrixCell(t, residual, models.LevelMedium, models.LevelHigh); cell.Pairs != 1 {
+		t.Errorf("Celda media/alta inesperada: %+v", cell)
+	}
+	if cell := riskMatrixCell(t, residual, models.LevelVeryLow, models.LevelVeryLow); cell.Pairs != 1 {
+		t.Errorf("Celda muy baja/muy baja inesperada: %+v", cell)
+	}
+
+	t.Logf("✓ GetRiskMatrix: %d pares en %d celdas antes y después de las medidas", residual.TotalPairs, len(residual.Cells))
+}
+
+func TestDashboardService_GetRiskMatrix_DrillDown(t *testing.T) {
+	svc := newDashboardService(newMockShopRepoForService(), riskMatrixRepo())
+
+	matrix, err := svc.GetRiskMatrix(context.Background(), &models.RiskMatrixQuery{
+		Stage:       models.RiskMatrixInherent,
+		Probability: models.LevelHigh,
+	})
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+
+	// Se detallan todas las celdas de la fila de probabilidad alta y ninguna otra
+	cell := riskMatrixCell(t, matrix, models.LevelHigh, models.LevelVeryHigh)
+	if len(cell.Items) != 2 || cell.Items[0].ShopLocation != "Madrid" {
+		t.Fatalf("Detalle inesperado: %+v", cell.Items)
+	}
+	if cell.Items[0].ResidualProbability != models.LevelMedium || cell.Items[0].ResidualConsequence != models.LevelHigh {
+		t.Errorf("Niveles residuales inesperados: %+v", cell.Items[0])
+	}
+	if other := riskMatrixCell(t, matrix, models.LevelVeryLow, models.LevelLow); other.Pairs != 1 || other.Items != nil {
+		t.Errorf("Celda no detallada inesperada: %+v", other)
+	}
+
+	t.Logf("✓ GetRiskMatrix detalla %d pares de la celda %s/%s", len(cell.Items), cell.Probability, cell.Consequence)
+}
+
+func TestDashboardService_GetRiskMatrix_DatabaseError(t *testing.T) {
+	svc := newDashboardService(newMockShopRepoForService(), &mockAnalyticsRepo{err: errors.New("connection refused")})
+
+	_, err := svc.GetRiskMatrix(context.Background(), &models.RiskMatrixQuery{})
+	if !hasErrorCode(err, models.ErrDatabase(nil)) {
+		t.Errorf("Se esperaba error de base de datos, se obtuvo %v", err)
+	}
+
+	t.Logf("✓ GetRiskMatrix propaga los errores de base de datos")
+}
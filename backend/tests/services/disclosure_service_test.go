@@ -0,0 +1,205 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/application/services"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/scoring"
+)
+
+// ============================================================================
+// MOCK DISCLOSURE REPOSITORY
+// ============================================================================
+
+// mockDisclosureRepo compone los datos del informe a partir de los mocks del resto
+// de repositorios y guarda los informes en memoria
+type mockDisclosureRepo struct {
+	scoringConfig *models.RiskScoringConfig
+	shopRepo      *mockShopRepoForService
+	riskRepo      *mockRiskRepoForService
+	overrideRepo  *mockOverrideRepoForService
+	scenarioRepo  *mockScenarioRepoForService
+	costRepo      *mockCostRepo
+	reports       map[string]models.DisclosureReport
+}
+
+func (m *mockDisclosureRepo) LoadInputs(ctx context.Context, trendSince time.Time) (*models.DisclosureInputs, error) {
+	inputs := &models.DisclosureInputs{
+		PortfolioShopData: *portfolioShopData(ctx, m.shopRepo, m.riskRepo, m.overrideRepo),
+		ScoringConfig:     m.scoringConfig,
+		Projections:       make(map[int64][]models.ClusterRiskProjection),
+		Invoices:          make(map[int64][]models.MeasureInvoice),
+	}
+	for _, shop := range inputs.Shops {
+		inputs.Projections[shop.ClusterID], _ = m.scenarioRepo.GetByCluster(ctx, shop.ClusterID, models.ScenarioSSP585)
+		inputs.Invoices[shop.ID], _ = m.costRepo.ListInvoices(ctx, shop.ID)
+	}
+	return inputs, nil
+}
+
+func (m *mockDisclosureRepo) SaveReport(ctx context.Context, report *models.DisclosureReport, shops []models.Shop) error {
+	if _, ok := m.reports[report.Data.Checksum]; !ok {
+		m.reports[report.Data.Checksum] = *report
+	}
+	return nil
+}
+
+func (m *mockDisclosureRepo) GetReport(ctx context.Context, checksum string) (*models.DisclosureReport, error) {
+	if report, ok := m.reports[checksum]; ok {
+		return &report, nil
+	}
+	return nil, nil
+}
+
+// ============================================================================
+// DISCLOSURE SERVICE TESTS
+// ============================================================================
+
+func newDisclosureService(shopRepo *mockShopRepoForService, costRepo *mockCostRepo) services.DisclosureService {
+	// Con el método max, la ola de calor (exposición alta) deja ambas tiendas en nivel alto
+	cfg := scoring.DefaultConfig()
+	cfg.Method = models.ScoringMaxBased
+
+	return services.NewDisclosureService(&mockDisclosureRepo{
+		scoringConfig: &cfg,
+		shopRepo:      shopRepo,
+		riskRepo:      newMockRiskRepoForService(),
+		overrideRepo:  newMockOverrideRepoForService(),
+		scenarioRepo:  &mockScenarioRepoForService{},
+		costRepo:      costRepo,
+		reports:       make(map[string]models.DisclosureReport),
+	})
+}
+
+func TestDisclosureService_Generate(t *testing.T) {
+	shopRepo := newMockShopRepoForService()
+	costRepo := newMockCostRepo(shopRepo)
+	now := time.Now().UTC()
+	finished := now.AddDate(0, 0, -5)
+
+	shopRepo.setShopMeasure(1, "Revisión sistemas pluviales", models.MeasureStatusInProgress)
+	shopRepo.setShopMeasure(1, "Aislamiento térmico", models.MeasureStatusPlanned)
+	shopRepo.shopMeasures[1]["Aislamiento térmico"].EstimatedCost = 1500
+	if err := costRepo.CreateInvoice(context.Background(), &models.MeasureInvoice{
+		ShopID: 1, MeasureName: "Revisión sistemas pluviales", Amount: 450, InvoiceNumber: "F-1", InvoicedAt: finished,
+	}); err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	// Factura anterior al periodo
+	if err := costRepo.CreateInvoice(context.Background(), &models.MeasureInvoice{
+		ShopID: 1, MeasureName: "Revisión sistemas pluviales", Amount: 100, InvoiceNumber: "F-0", InvoicedAt: now.AddDate(0, -6, 0),
+	}); err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	pluvial := shopRepo.shopMeasures[1]["Revisión sistemas pluviales"]
+	pluvial.Status = models.MeasureStatusCompleted
+	pluvial.ActualEndDate = &finished
+
+	svc := newDisclosureService(shopRepo, costRepo)
+	from := now.AddDate(0, -1, 0)
+	report, err := svc.Generate(context.Background(), &models.DisclosureReportQuery{From: &from, To: &now})
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+
+	if report.Summary.TotalShops != 2 || report.Summary.HighRiskShops != 2 || len(report.HighRiskShops) != 2 {
+		t.Errorf("Resumen inesperado: %+v", report.Summary)
+	}
+	// Ambos riesgos son materiales en las dos tiendas
+	if report.Summary.MaterialRisks != 4 || len(report.ExposureByRisk) != 2 || report.ExposureByRisk[0].MaterialShops != 2 {
+		t.Errorf("Exposición inesperada: %d materiales, %+v", report.Summary.MaterialRisks, report.ExposureByRisk)
+	}
+
+	plan := report.AdaptationPlan
+	if plan.PeriodSpend != 450 || plan.ImplementedInvestment != 550 || plan.PlannedInvestment != 1500 || plan.CompletedInPeriod != 1 {
+		t.Errorf("Plan de adaptación inesperado: %+v", plan)
+	}
+	if len(report.Scenarios) != 7 || report.Scenarios[0].HighRiskShops != 2 {
+		t.Errorf("Se esperaban 7 escenarios, se obtuvieron %+v", report.Scenarios)
+	}
+	if report.Data.Shops != 2 || report.Data.Invoices != 2 || len(report.Data.Checksum) != 64 {
+		t.Errorf("Datos utilizados inesperados: %+v", report.Data)
+	}
+
+	t.Logf("✓ Generate: %d tiendas de alto riesgo, %.0f € facturados en el periodo", report.Summary.HighRiskShops, plan.PeriodSpend)
+}
+
+func TestDisclosureService_Checksum(t *testing.T) {
+	shopRepo := newMockShopRepoForService()
+	svc := newDisclosureService(shopRepo, newMockCostRepo(shopRepo))
+	ctx := context.Background()
+
+	first, err := svc.Generate(ctx, &models.DisclosureReportQuery{})
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	second, err := svc.Generate(ctx, &models.DisclosureReportQuery{})
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if first.Data.Checksum != second.Data.Checksum {
+		t.Error("Los mismos datos deberían producir la misma suma de comprobación")
+	}
+
+	shopRepo.setShopMeasure(2, "Aislamiento térmico", models.MeasureStatusPlanned)
+	third, err := svc.Generate(ctx, &models.DisclosureReportQuery{})
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if third.Data.Checksum == first.Data.Checksum {
+		t.Error("Datos distintos deberían producir otra suma de comprobación")
+	}
+
+	t.Logf("✓ La suma de comprobación identifica los datos utilizados")
+}
+
+func TestDisclosureService_InvalidPeriod(t *testing.T) {
+	shopRepo := newMockShopRepoForService()
+	svc := newDisclosureService(shopRepo, newMockCostRepo(shopRepo))
+
+	to := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	from := to.AddDate(0, 0, 1)
+	_, err := svc.Generate(context.Background(), &models.DisclosureReportQuery{From: &from, To: &to})
+	if !hasErrorCode(err, models.ErrInvalidInput("")) {
+		t.Errorf("Se esperaba error de periodo, se obtuvo %v", err)
+	}
+
+	t.Logf("✓ Generate rechaza un periodo que termina antes de empezar")
+}
+
+func TestDisclosureService_StoresReportByChecksum(t *testing.T) {
+	shopRepo := newMockShopRepoForService()
+	svc := newDisclosureService(shopRepo, newMockCostRepo(shopRepo))
+	ctx := context.Background()
+
+	generated, err := svc.Generate(ctx, &models.DisclosureReportQuery{})
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	stored, err := svc.Get(ctx, generated.Data.Checksum)
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if stored.Summary != generated.Summary || !stored.GeneratedAt.Equal(generated.GeneratedAt) {
+		t.Errorf("El informe guardado no coincide con el generado: %+v", stored.Summary)
+	}
+
+	// Otro periodo con los mismos datos es otro informe
+	from := time.Now().UTC().AddDate(-1, 0, 0)
+	other, err := svc.Generate(ctx, &models.DisclosureReportQuery{From: &from})
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if other.Data.Checksum == generated.Data.Checksum {
+		t.Error("Periodos distintos deberían producir otra suma de comprobación")
+	}
+
+	if _, err := svc.Get(ctx, "desconocido"); !hasErrorCode(err, models.ErrDisclosureNotFound) {
+		t.Errorf("Se esperaba DISCLOSURE_NOT_FOUND, se obtuvo %v", err)
+	}
+
+	t.Logf("✓ El informe generado se recupera por su suma de comprobación")
+}
@@ -0,0 +1,94 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/application/services"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/scoring"
+)
+
+// ============================================================================
+// MEASURE SERVICE TESTS
+// ============================================================================
+
+func TestMeasureService_DeleteAndRestoreRecalculateShops(t *testing.T) {
+	shopRepo := newMockShopRepoForService()
+	shopRepo.setShopMeasure(1, "Aislamiento térmico", models.MeasureStatusCompleted)
+	riskRepo := newMockRiskRepoForService()
+	overrideRepo := newMockOverrideRepoForService()
+	scenarioRepo := &mockScenarioRepoForService{}
+	snapshotRepo := &mockSnapshotRepoForService{}
+	recalculator := services.NewShopRiskRecalculator(shopRepo, riskRepo, overrideRepo, scenarioRepo, snapshotRepo, newMockTaxonomyRepo())
+	svc := services.NewMeasureService(newMockMeasureRepoForService(), shopRepo, riskRepo,
+		scoring.Static(scoring.Default()), recalculator, &mockAuditRecorder{})
+	ctx := context.Background()
+
+	deleted, err := svc.Delete(ctx, "Aislamiento térmico")
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if deleted.RecalculationFailed || deleted.Measure.DeletedAt == nil {
+		t.Errorf("Resultado de la eliminación inesperado: %+v", deleted)
+	}
+	restored, err := svc.Restore(ctx, "Aislamiento térmico")
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if restored.RecalculationFailed || restored.Measure.DeletedAt != nil {
+		t.Errorf("Resultado de la restauración inesperado: %+v", restored)
+	}
+
+	// Solo se recalcula la tienda que tiene aplicada la medida, una vez por operación
+	expected := []models.SnapshotTrigger{models.TriggerMeasureDeleted, models.TriggerMeasureRestored}
+	if len(snapshotRepo.snapshots) != len(expected) {
+		t.Fatalf("Se esperaban %d snapshots, obtenidos %d", len(expected), len(snapshotRepo.snapshots))
+	}
+	for i, snapshot := range snapshotRepo.snapshots {
+		if snapshot.ShopID != 1 || snapshot.Trigger != expected[i] {
+			t.Errorf("Snapshot %d inesperado: tienda %d, trigger %s", i, snapshot.ShopID, snapshot.Trigger)
+		}
+	}
+
+	t.Log("✓ Eliminar y restaurar una medida recalcula las tiendas donde está aplicada")
+}
+
+func TestMeasureService_DeleteAndRestoreReportFailedRecalculation(t *testing.T) {
+	shopRepo := newMockShopRepoForService()
+	shopRepo.setShopMeasure(1, "Aislamiento térmico", models.MeasureStatusCompleted)
+	riskRepo := newMockRiskRepoForService()
+	overrideRepo := newMockOverrideRepoForService()
+	snapshotRepo := &mockSnapshotRepoForService{createErr: errors.New("connection reset")}
+	recalculator := services.NewShopRiskRecalculator(shopRepo, riskRepo, overrideRepo, &mockScenarioRepoForService{}, snapshotRepo, newMockTaxonomyRepo())
+	measureRepo := newMockMeasureRepoForService()
+	svc := services.NewMeasureService(measureRepo, shopRepo, riskRepo,
+		scoring.Static(scoring.Default()), recalculator, &mockAuditRecorder{})
+	ctx := context.Background()
+
+	// La eliminación ya está guardada: el fallo del recálculo se informa aparte
+	deleted, err := svc.Delete(ctx, "Aislamiento térmico")
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if !deleted.RecalculationFailed {
+		t.Error("Se esperaba que el resultado indicara el fallo del recálculo")
+	}
+	if _, ok := measureRepo.deleted["Aislamiento térmico"]; !ok {
+		t.Error("La medida debería seguir eliminada")
+	}
+
+	restored, err := svc.Restore(ctx, "Aislamiento térmico")
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if !restored.RecalculationFailed {
+		t.Error("Se esperaba que el resultado indicara el fallo del recálculo")
+	}
+	if _, ok := measureRepo.deleted["Aislamiento térmico"]; ok {
+		t.Error("La medida debería seguir restaurada")
+	}
+
+	t.Log("✓ Un fallo del recálculo no deshace la eliminación ni la restauración")
+}
@@ -0,0 +1,111 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/application/services"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+)
+
+// ============================================================================
+// MOCKS PARA SCORING
+// ============================================================================
+
+// mockScoringConfigRepo guarda las versiones de scoring en memoria
+type mockScoringConfigRepo struct {
+	configs map[int64]*models.RiskScoringConfig
+	nextID  int64
+}
+
+func newMockScoringConfigRepo() *mockScoringConfigRepo {
+	return &mockScoringConfigRepo{configs: make(map[int64]*models.RiskScoringConfig)}
+}
+
+func (m *mockScoringConfigRepo) Create(ctx context.Context, cfg *models.RiskScoringConfig) error {
+	m.nextID++
+	cfg.Version = m.nextID
+	stored := *cfg
+	m.configs[cfg.Version] = &stored
+	return nil
+}
+func (m *mockScoringConfigRepo) GetByVersion(ctx context.Context, version int64) (*models.RiskScoringConfig, error) {
+	if cfg, ok := m.configs[version]; ok {
+		result := *cfg
+		return &result, nil
+	}
+	return nil, nil
+}
+func (m *mockScoringConfigRepo) GetActive(ctx context.Context) (*models.RiskScoringConfig, error) {
+	for _, cfg := range m.configs {
+		if cfg.Active {
+			result := *cfg
+			return &result, nil
+		}
+	}
+	return nil, nil
+}
+func (m *mockScoringConfigRepo) List(ctx context.Context) ([]models.RiskScoringConfig, error) {
+	var result []models.RiskScoringConfig
+	for _, cfg := range m.configs {
+		result = append(result, *cfg)
+	}
+	return result, nil
+}
+func (m *mockScoringConfigRepo) Activate(ctx context.Context, version int64) error {
+	for v, cfg := range m.configs {
+		cfg.Active = v == version
+	}
+	return nil
+}
+
+// ============================================================================
+// RISK SCORING SERVICE TESTS
+// ============================================================================
+
+func TestRiskScoringService_ActivateConfig_RecalculatesShops(t *testing.T) {
+	ctx := context.Background()
+	shopRepo := newMockShopRepoForService()
+	snapshotRepo := &mockSnapshotRepoForService{}
+	recalculator := services.NewShopRiskRecalculator(
+		shopRepo,
+		newMockRiskRepoForService(),
+		newMockOverrideRepoForService(),
+		&mockScenarioRepoForService{},
+		snapshotRepo,
+		newMockTaxonomyRepo(),
+	)
+	service := services.NewRiskScoringService(newMockScoringConfigRepo(), recalculator, &mockAuditRecorder{})
+
+	cfg, err := service.CreateConfig(ctx, &models.CreateRiskScoringConfigRequest{
+		Name:        "peor caso",
+		Method:      models.ScoringMultiplicative,
+		Aggregation: models.AggregationMax,
+		Activate:    true,
+	})
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+
+	// Todas las tiendas quedan calculadas con la versión activada
+	for id, shop := range shopRepo.shops {
+		if shop.TotalRiskScoringVersion != cfg.Version || shop.TotalRiskMethod != models.AggregationMax {
+			t.Errorf("Tienda %d calculada con versión %d (%s), se esperaba %d (max)",
+				id, shop.TotalRiskScoringVersion, shop.TotalRiskMethod, cfg.Version)
+		}
+	}
+
+	if len(snapshotRepo.snapshots) != len(shopRepo.shops) {
+		t.Fatalf("Se esperaba un snapshot por tienda, obtenidos %d", len(snapshotRepo.snapshots))
+	}
+	for _, snapshot := range snapshotRepo.snapshots {
+		if snapshot.Trigger != models.TriggerScoringActivated {
+			t.Errorf("Se esperaba trigger %s, obtenido %s", models.TriggerScoringActivated, snapshot.Trigger)
+		}
+		if snapshot.ScoringVersion != cfg.Version {
+			t.Errorf("Se esperaba versión %d en el snapshot, obtenida %d", cfg.Version, snapshot.ScoringVersion)
+		}
+	}
+
+	t.Logf("✓ Activar la versión %d recalcula las %d tiendas", cfg.Version, len(shopRepo.shops))
+}
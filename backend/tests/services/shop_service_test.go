@@ -3,10 +3,16 @@ package services_test
 
 import (
 	"context"
+	"errors"
+	"sort"
 	"testing"
+	"time"
 
 	"github.com/d1mo22/climate-invest-optimizer/backend/internal/application/services"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/authz"
 	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/repository"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/scoring"
 )
 
 // ============================================================================
@@ -14,9 +20,13 @@ import (
 // ============================================================================
 
 type mockShopRepoForService struct {
-	shops      map[int64]*models.Shop
-	nextID     int64
-	lastFilter *models.ShopFilterRequest
+	shops        map[int64]*models.Shop
+	deleted      map[int64]*models.Shop
+	shopMeasures map[int64]map[string]*models.ShopMeasure
+	coverage     map[int64][]models.RiskCoverageItem // Riesgos de GetRiskCoverage por tienda
+	removeErr    error                               // Error de RemoveMeasure
+	nextID       int64
+	lastFilter   *models.ShopFilterRequest
 }
 
 func newMockShopRepoForService() *mockShopRepoForService {
@@ -25,7 +35,9 @@ func newMockShopRepoForService() *mockShopRepoForService {
 			1: {ID: 1, Location: "Madrid Centro", ClusterID: 1, TotalRisk: 0.75, Surface: 500},
 			2: {ID: 2, Location: "Barcelona", ClusterID: 2, TotalRisk: 0.45, Surface: 800},
 		},
-		nextID: 3,
+		deleted:      make(map[int64]*models.Shop),
+		shopMeasures: make(map[int64]map[string]*models.ShopMeasure),
+		nextID:       3,
 	}
 }
 
@@ -52,13 +64,36 @@ func (m *mockShopRepoForService) Update(ctx context.Context, shop *models.Shop)
 }
 
 func (m *mockShopRepoForService) Delete(ctx context.Context, id int64) error {
-	if _, ok := m.shops[id]; !ok {
+	shop, ok := m.shops[id]
+	if !ok {
 		return models.ErrShopNotFound
 	}
+	now := time.Now()
+	shop.DeletedAt = &now
+	m.deleted[id] = shop
 	delete(m.shops, id)
 	return nil
 }
 
+func (m *mockShopRepoForService) GetDeletedByID(ctx context.Context, id int64) (*models.Shop, error) {
+	if shop, ok := m.deleted[id]; ok {
+		copied := *shop
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (m *mockShopRepoForService) Restore(ctx context.Context, id int64) error {
+	shop, ok := m.deleted[id]
+	if !ok {
+		return models.ErrShopNotFound
+	}
+	shop.DeletedAt = nil
+	m.shops[id] = shop
+	delete(m.deleted, id)
+	return nil
+}
+
 func (m *mockShopRepoForService) List(ctx context.Context, filter *models.ShopFilterRequest) ([]models.Shop, int64, error) {
 	m.lastFilter = filter
 	var result []models.Shop
@@ -86,22 +121,97 @@ func (m *mockShopRepoForService) GetWithDetails(ctx context.Context, id int64) (
 	return &models.ShopWithDetails{Shop: *shop}, nil
 }
 
+func (m *mockShopRepoForService) ListActiveIDs(ctx context.Context) ([]int64, error) {
+	ids := make([]int64, 0, len(m.shops))
+	for id := range m.shops {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
+
+func (m *mockShopRepoForService) ListIDsWithMeasure(ctx context.Context, measureName string) ([]int64, error) {
+	var ids []int64
+	for shopID, measures := range m.shopMeasures {
+		if _, ok := measures[measureName]; ok && m.shops[shopID] != nil {
+			ids = append(ids, shopID)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
+
 func (m *mockShopRepoForService) GetAppliedMeasures(ctx context.Context, shopID int64) ([]models.Measure, error) {
 	return []models.Measure{}, nil
 }
 
+func (m *mockShopRepoForService) GetCompletedMeasures(ctx context.Context, shopID int64) ([]models.Measure, error) {
+	var completed []models.Measure
+	for _, sm := range m.shopMeasures[shopID] {
+		if sm.Status.IsCompleted() {
+			completed = append(completed, sm.Measure)
+		}
+	}
+	return completed, nil
+}
+
+func (m *mockShopRepoForService) GetShopMeasures(ctx context.Context, shopID int64) ([]models.ShopMeasure, error) {
+	var measures []models.ShopMeasure
+	for _, sm := range m.shopMeasures[shopID] {
+		measures = append(measures, *sm)
+	}
+	return measures, nil
+}
+
+func (m *mockShopRepoForService) GetShopMeasure(ctx context.Context, shopID int64, measureName string) (*models.ShopMeasure, error) {
+	if sm, ok := m.shopMeasures[shopID][measureName]; ok {
+		copied := *sm
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (m *mockShopRepoForService) UpdateShopMeasure(ctx context.Context, measure *models.ShopMeasure) error {
+	if _, ok := m.shopMeasures[measure.ShopID][measure.Name]; !ok {
+		return repository.ErrMeasureNotAppliedToShop
+	}
+	measure.StatusUpdatedAt = time.Now()
+	copied := *measure
+	m.shopMeasures[measure.ShopID][measure.Name] = &copied
+	return nil
+}
+
 func (m *mockShopRepoForService) ApplyMeasure(ctx context.Context, shopID int64, measureName string) error {
+	if m.shopMeasures[shopID] == nil {
+		m.shopMeasures[shopID] = make(map[string]*models.ShopMeasure)
+	}
+	if _, ok := m.shopMeasures[shopID][measureName]; ok {
+		return repository.ErrMeasureAlreadyAppliedToShop
+	}
+	m.shopMeasures[shopID][measureName] = &models.ShopMeasure{
+		ShopID:  shopID,
+		Measure: models.Measure{Name: measureName},
+		Status:  models.MeasureStatusPlanned,
+	}
 	return nil
 }
 
 func (m *mockShopRepoForService) RemoveMeasure(ctx context.Context, shopID int64, measureName string) error {
-	return nil
+	return m.removeErr
 }
 
-func (m *mockShopRepoForService) GetStats(ctx context.Context) (*models.DashboardStats, error) {
+func (m *mockShopRepoForService) GetStats(ctx context.Context, query *models.DashboardStatsQuery, thresholds models.RiskLevelThresholds) (*models.DashboardStats, error) {
 	return &models.DashboardStats{TotalShops: int64(len(m.shops))}, nil
 }
 
+func (m *mockShopRepoForService) GetRiskCoverage(ctx context.Context, shopID int64) (*models.RiskCoverageResponse, error) {
+	shop, ok := m.shops[shopID]
+	if !ok {
+		return nil, nil
+	}
+	return &models.RiskCoverageResponse{ShopID: shopID, ShopLocation: shop.Location, ShopCountry: shop.Country, Risks: m.coverage[shopID]}, nil
+}
+
 // mockClusterRepo para ShopService
 type mockClusterRepoForService struct {
 	clusters map[int64]*models.Cluster
@@ -187,14 +297,15 @@ func (m *mockRiskRepoForService) List(ctx context.Context) ([]models.Risk, error
 }
 func (m *mockRiskRepoForService) GetByClusterID(ctx context.Context, clusterID int64) ([]models.RiskDetail, error) {
 	return []models.RiskDetail{
-		{Risk: m.risks[0], Exposure: models.LevelMedium},
-		{Risk: m.risks[1], Exposure: models.LevelHigh},
+		{Risk: m.risks[0], Exposure: models.LevelMedium, Probability: models.LevelMedium},
+		{Risk: m.risks[1], Exposure: models.LevelHigh, Probability: models.LevelMedium},
 	}, nil
 }
 
 // mockMeasureRepo para ShopService
 type mockMeasureRepoForService struct {
 	measures []models.Measure
+	deleted  map[string]models.Measure
 }
 
 func newMockMeasureRepoForService() *mockMeasureRepoForService {
@@ -220,10 +331,41 @@ func (m *mockMeasureRepoForService) GetByName(ctx context.Context, name string)
 func (m *mockMeasureRepoForService) Update(ctx context.Context, measure *models.Measure) error {
 	return nil
 }
-func (m *mockMeasureRepoForService) Delete(ctx context.Context, name string) error { return nil }
+func (m *mockMeasureRepoForService) Delete(ctx context.Context, name string) error {
+	for i, measure := range m.measures {
+		if measure.Name == name {
+			if m.deleted == nil {
+				m.deleted = make(map[string]models.Measure)
+			}
+			now := time.Now()
+			measure.DeletedAt = &now
+			m.deleted[name] = measure
+			m.measures = append(m.measures[:i], m.measures[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+func (m *mockMeasureRepoForService) GetDeletedByName(ctx context.Context, name string) (*models.Measure, error) {
+	if measure, ok := m.deleted[name]; ok {
+		return &measure, nil
+	}
+	return nil, nil
+}
+func (m *mockMeasureRepoForService) Restore(ctx context.Context, name string) error {
+	if measure, ok := m.deleted[name]; ok {
+		measure.DeletedAt = nil
+		m.measures = append(m.measures, measure)
+		delete(m.deleted, name)
+	}
+	return nil
+}
 func (m *mockMeasureRepoForService) List(ctx context.Context) ([]models.Measure, error) {
 	return m.measures, nil
 }
+func (m *mockMeasureRepoForService) ListIncludingDeleted(ctx context.Context) ([]models.Measure, error) {
+	return m.measures, nil
+}
 func (m *mockMeasureRepoForService) GetByType(ctx context.Context, measureType models.MeasureType) ([]models.Measure, error) {
 	return m.measures, nil
 }
@@ -234,16 +376,187 @@ func (m *mockMeasureRepoForService) GetApplicableForShop(ctx context.Context, sh
 	return m.measures, nil
 }
 
+// mockOverrideRepo para ShopService
+type mockOverrideRepoForService struct {
+	overrides map[int64]map[int64]models.ShopRiskOverride
+}
+
+func newMockOverrideRepoForService() *mockOverrideRepoForService {
+	return &mockOverrideRepoForService{overrides: make(map[int64]map[int64]models.ShopRiskOverride)}
+}
+
+func (m *mockOverrideRepoForService) Upsert(ctx context.Context, override *models.ShopRiskOverride) error {
+	if m.overrides[override.ShopID] == nil {
+		m.overrides[override.ShopID] = make(map[int64]models.ShopRiskOverride)
+	}
+	m.overrides[override.ShopID][override.RiskID] = *override
+	return nil
+}
+func (m *mockOverrideRepoForService) Delete(ctx context.Context, shopID, riskID int64) error {
+	if _, ok := m.overrides[shopID][riskID]; !ok {
+		return repository.ErrRiskOverrideNotFound
+	}
+	delete(m.overrides[shopID], riskID)
+	return nil
+}
+func (m *mockOverrideRepoForService) GetByShop(ctx context.Context, shopID int64) ([]models.ShopRiskOverride, error) {
+	var result []models.ShopRiskOverride
+	for _, o := range m.overrides[shopID] {
+		result = append(result, o)
+	}
+	return result, nil
+}
+
+// mockScenarioRepo para ShopService: proyecta Ola de calor en SSP5-8.5
+type mockScenarioRepoForService struct{}
+
+func (m *mockScenarioRepoForService) Upsert(ctx context.Context, projection *models.ClusterRiskProjection) error {
+	return nil
+}
+func (m *mockScenarioRepoForService) GetByCluster(ctx context.Context, clusterID int64, scenario models.ClimateScenario) ([]models.ClusterRiskProjection, error) {
+	if scenario != models.ScenarioSSP585 {
+		return nil, nil
+	}
+	veryHigh := models.LevelVeryHigh
+	return []models.ClusterRiskProjection{
+		{ClusterID: clusterID, RiskID: 2, Scenario: scenario, Horizon: models.Horizon2050, Probability: &veryHigh},
+	}, nil
+}
+
+// mockTaxonomyRepo guarda en memoria la correspondencia de las medidas con la Taxonomía
+type mockTaxonomyRepo struct {
+	mappings     map[string]models.MeasureTaxonomy
+	riskMeasures map[string][]string
+	// Repositorios con que se componen los datos de la cartera
+	shopRepo     *mockShopRepoForService
+	riskRepo     *mockRiskRepoForService
+	overrideRepo *mockOverrideRepoForService
+}
+
+func newMockTaxonomyRepo() *mockTaxonomyRepo {
+	allMet := models.DNSHCriteria{ClimateMitigation: true, Water: true, CircularEconomy: true, Pollution: true, Biodiversity: true}
+	return &mockTaxonomyRepo{
+		mappings: map[string]models.MeasureTaxonomy{
+			"Revisión sistemas pluviales": {MeasureName: "Revisión sistemas pluviales", ActivityCode: "7.2", DNSH: allMet},
+		},
+		riskMeasures: map[string][]string{
+			"Inundación":   {"Revisión sistemas pluviales"},
+			"Ola de calor": {"Aislamiento térmico"},
+		},
+	}
+}
+
+func (m *mockTaxonomyRepo) ListMappings(ctx context.Context) ([]models.MeasureTaxonomy, error) {
+	var result []models.MeasureTaxonomy
+	for _, mapping := range m.mappings {
+		result = append(result, mapping)
+	}
+	return result, nil
+}
+func (m *mockTaxonomyRepo) GetMapping(ctx context.Context, measureName string) (*models.MeasureTaxonomy, error) {
+	if mapping, ok := m.mappings[measureName]; ok {
+		return &mapping, nil
+	}
+	return nil, nil
+}
+func (m *mockTaxonomyRepo) UpsertMapping(ctx context.Context, mapping *models.MeasureTaxonomy) error {
+	mapping.UpdatedAt = time.Now()
+	m.mappings[mapping.MeasureName] = *mapping
+	return nil
+}
+func (m *mockTaxonomyRepo) DeleteMapping(ctx context.Context, measureName string) error {
+	if _, ok := m.mappings[measureName]; !ok {
+		return repository.ErrTaxonomyMappingNotFound
+	}
+	delete(m.mappings, measureName)
+	return nil
+}
+func (m *mockTaxonomyRepo) GetRiskMeasures(ctx context.Context) (map[string][]string, error) {
+	return m.riskMeasures, nil
+}
+func (m *mockTaxonomyRepo) GetPortfolioShopData(ctx context.Context) (*models.PortfolioShopData, error) {
+	return portfolioShopData(ctx, m.shopRepo, m.riskRepo, m.overrideRepo), nil
+}
+
+// portfolioShopData compone los datos de la cartera a partir de los mocks de los repositorios
+func portfolioShopData(ctx context.Context, shopRepo *mockShopRepoForService, riskRepo *mockRiskRepoForService, overrideRepo *mockOverrideRepoForService) *models.PortfolioShopData {
+	data := &models.PortfolioShopData{
+		ClusterRisks: make(map[int64][]models.RiskDetail),
+		Overrides:    make(map[int64][]models.ShopRiskOverride),
+		ShopMeasures: make(map[int64][]models.ShopMeasure),
+	}
+	if shopRepo == nil {
+		return data
+	}
+	data.Shops, _, _ = shopRepo.List(ctx, nil)
+	sort.Slice(data.Shops, func(i, j int) bool { return data.Shops[i].ID < data.Shops[j].ID })
+
+	for _, shop := range data.Shops {
+		data.ClusterRisks[shop.ClusterID], _ = riskRepo.GetByClusterID(ctx, shop.ClusterID)
+		data.Overrides[shop.ID], _ = overrideRepo.GetByShop(ctx, shop.ID)
+		data.ShopMeasures[shop.ID], _ = shopRepo.GetShopMeasures(ctx, shop.ID)
+	}
+	return data
+}
+
+// mockSnapshotRepo para ShopService: guarda los snapshots en memoria
+type mockSnapshotRepoForService struct {
+	snapshots []models.RiskSnapshot
+	nextID    int64
+	createErr error // Error de Create
+}
+
+func (m *mockSnapshotRepoForService) Create(ctx context.Context, snapshot *models.RiskSnapshot) error {
+	if m.createErr != nil {
+		return m.createErr
+	}
+	m.nextID++
+	snapshot.ID = m.nextID
+	snapshot.CreatedAt = time.Now()
+	m.snapshots = append(m.snapshots, *snapshot)
+	return nil
+}
+func (m *mockSnapshotRepoForService) GetLatest(ctx context.Context, shopID int64) (*models.RiskSnapshot, error) {
+	for i := len(m.snapshots) - 1; i >= 0; i-- {
+		if m.snapshots[i].ShopID == shopID {
+			return &m.snapshots[i], nil
+		}
+	}
+	return nil, nil
+}
+func (m *mockSnapshotRepoForService) ListByShop(ctx context.Context, shopID int64, limit int) ([]models.RiskSnapshot, error) {
+	var result []models.RiskSnapshot
+	for i := len(m.snapshots) - 1; i >= 0 && len(result) < limit; i-- {
+		if m.snapshots[i].ShopID == shopID {
+			result = append(result, m.snapshots[i])
+		}
+	}
+	return result, nil
+}
+func (m *mockSnapshotRepoForService) GetTrend(ctx context.Context, interval string, since time.Time) ([]models.RiskTrendPoint, error) {
+	return nil, nil
+}
+
 // ============================================================================
 // HELPER
 // ============================================================================
 
 func createShopService() services.ShopService {
+	return createShopServiceWithRepo(newMockShopRepoForService())
+}
+
+func createShopServiceWithRepo(repo *mockShopRepoForService) services.ShopService {
 	return services.NewShopService(
-		newMockShopRepoForService(),
+		repo,
 		newMockClusterRepoForService(),
 		newMockRiskRepoForService(),
 		newMockMeasureRepoForService(),
+		newMockOverrideRepoForService(),
+		&mockScenarioRepoForService{},
+		&mockSnapshotRepoForService{},
+		newMockTaxonomyRepo(),
+		scoring.Static(scoring.Default()),
+		&mockAuditRecorder{},
 	)
 }
 
@@ -284,6 +597,44 @@ func TestShopService_Create_Success(t *testing.T) {
 	t.Logf("✓ Create: Shop ID=%d, Location=%s", shop.ID, shop.Location)
 }
 
+func TestShopService_Create_RecalculationFails(t *testing.T) {
+	repo := newMockShopRepoForService()
+	service := services.NewShopService(
+		repo,
+		newMockClusterRepoForService(),
+		newMockRiskRepoForService(),
+		newMockMeasureRepoForService(),
+		newMockOverrideRepoForService(),
+		&mockScenarioRepoForService{},
+		&mockSnapshotRepoForService{createErr: errors.New("connection reset")},
+		newMockTaxonomyRepo(),
+		scoring.Static(scoring.Default()),
+		&mockAuditRecorder{},
+	)
+
+	// La tienda ya está guardada: un fallo del cálculo inicial no debe hacer que el
+	// cliente la dé por no creada y la duplique al reintentar
+	shop, err := service.Create(context.Background(), &models.CreateShopRequest{
+		Location:  "Valencia Centro",
+		Surface:   600,
+		ClusterID: 1,
+	})
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if shop == nil || shop.ID == 0 {
+		t.Fatal("Se esperaba la tienda creada")
+	}
+	if stored, ok := repo.shops[shop.ID]; !ok || stored.Location != "Valencia Centro" {
+		t.Error("La tienda debería seguir guardada")
+	}
+	if len(repo.shops) != 3 {
+		t.Errorf("Se esperaban 3 tiendas, hay %d", len(repo.shops))
+	}
+
+	t.Logf("✓ Create retorna la tienda aunque falle el recálculo: ID=%d", shop.ID)
+}
+
 func TestShopService_Create_InvalidCluster(t *testing.T) {
 	service := createShopService()
 	ctx := context.Background()
@@ -407,6 +758,29 @@ func TestShopService_ApplyMeasures_Success(t *testing.T) {
 	t.Log("✓ ApplyMeasures: Medida aplicada correctamente")
 }
 
+func TestShopService_ApplyMeasures_RecalculationFails(t *testing.T) {
+	service := services.NewShopService(
+		newMockShopRepoForService(),
+		newMockClusterRepoForService(),
+		newMockRiskRepoForService(),
+		newMockMeasureRepoForService(),
+		newMockOverrideRepoForService(),
+		&mockScenarioRepoForService{},
+		&mockSnapshotRepoForService{createErr: errors.New("connection reset")},
+		newMockTaxonomyRepo(),
+		scoring.Static(scoring.Default()),
+		&mockAuditRecorder{},
+	)
+
+	// Un riesgo guardado sin recalcular no debe darse por bueno en silencio
+	err := service.ApplyMeasures(context.Background(), 1, []string{"Revisión sistemas pluviales"})
+	if !hasErrorCode(err, models.ErrDatabase(nil)) {
+		t.Errorf("Se esperaba error de base de datos, se obtuvo %v", err)
+	}
+
+	t.Logf("✓ ApplyMeasures devuelve el error del recálculo: %v", err)
+}
+
 func TestShopService_ApplyMeasures_ShopNotFound(t *testing.T) {
 	service := createShopService()
 	ctx := context.Background()
@@ -431,11 +805,114 @@ func TestShopService_RemoveMeasure_Success(t *testing.T) {
 	t.Log("✓ RemoveMeasure: Medida eliminada correctamente")
 }
 
+func TestShopService_RemoveMeasure_WithInvoices(t *testing.T) {
+	repo := newMockShopRepoForService()
+	repo.removeErr = repository.ErrMeasureHasInvoices
+	service := createShopServiceWithRepo(repo)
+
+	err := service.RemoveMeasure(context.Background(), 1, "Revisión sistemas pluviales")
+	if !hasErrorCode(err, models.ErrMeasureHasInvoices) {
+		t.Fatalf("Se esperaba MEASURE_HAS_INVOICES, se obtuvo %v", err)
+	}
+
+	t.Log("✓ RemoveMeasure: no se eliminan medidas con facturas")
+}
+
+func TestShopService_TransitionMeasure_Lifecycle(t *testing.T) {
+	repo := newMockShopRepoForService()
+	recorder := &mockAuditRecorder{}
+	svc := services.NewShopService(
+		repo,
+		newMockClusterRepoForService(),
+		newMockRiskRepoForService(),
+		newMockMeasureRepoForService(),
+		newMockOverrideRepoForService(),
+		&mockScenarioRepoForService{},
+		&mockSnapshotRepoForService{},
+		newMockTaxonomyRepo(),
+		scoring.Static(scoring.Default()),
+		recorder,
+	)
+	ctx := context.Background()
+	name := "Revisión sistemas pluviales"
+
+	if err := svc.ApplyMeasures(ctx, 1, []string{name}); err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if repo.shops[1].TaxonomyCoverage != 0 {
+		t.Errorf("Una medida planificada no debería contar en la cobertura, se obtuvo %.2f", repo.shops[1].TaxonomyCoverage)
+	}
+
+	for _, status := range []models.MeasureStatus{models.MeasureStatusApproved, models.MeasureStatusInProgress} {
+		if _, err := svc.TransitionMeasure(ctx, 1, &models.MeasureTransitionRequest{MeasureName: name, Status: status}); err != nil {
+			t.Fatalf("Error inesperado pasando a %s: %v", status, err)
+		}
+	}
+
+	// Completar exige el coste real
+	_, err := svc.TransitionMeasure(ctx, 1, &models.MeasureTransitionRequest{MeasureName: name, Status: models.MeasureStatusCompleted})
+	if err == nil {
+		t.Fatal("Se esperaba error al completar sin coste real")
+	}
+
+	cost := 450.0
+	measure, err := svc.TransitionMeasure(ctx, 1, &models.MeasureTransitionRequest{
+		MeasureName: name, Status: models.MeasureStatusCompleted, ActualCost: &cost,
+	})
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if measure.ActualStartDate == nil || measure.ActualEndDate == nil || *measure.ActualCost != cost {
+		t.Errorf("Se esperaban fechas reales y coste registrados: %+v", measure)
+	}
+	// La medida alineada reduce la inundación, pero no la ola de calor
+	if repo.shops[1].TaxonomyCoverage != 50 {
+		t.Errorf("TaxonomyCoverage=%.2f, esperado 50", repo.shops[1].TaxonomyCoverage)
+	}
+
+	if n := len(recorder.actions()); n != 4 {
+		t.Errorf("Se esperaban 4 entradas de auditoría (aplicar y 3 transiciones), se obtuvieron %d", n)
+	}
+
+	t.Logf("✓ Ciclo de vida: %s completada, cobertura %.2f%%", measure.Name, repo.shops[1].TaxonomyCoverage)
+}
+
+func TestShopService_TransitionMeasure_InvalidTransition(t *testing.T) {
+	svc := createShopService()
+	ctx := context.Background()
+	name := "Aislamiento térmico"
+
+	if err := svc.ApplyMeasures(ctx, 1, []string{name}); err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+
+	// No se puede saltar fases
+	_, err := svc.TransitionMeasure(ctx, 1, &models.MeasureTransitionRequest{MeasureName: name, Status: models.MeasureStatusCompleted})
+	if !hasErrorCode(err, models.ErrInvalidMeasureTransition("")) {
+		t.Errorf("Se esperaba INVALID_MEASURE_TRANSITION, se obtuvo %v", err)
+	}
+
+	// Los datos reales no se aceptan antes de empezar la obra
+	cost := 100.0
+	_, err = svc.TransitionMeasure(ctx, 1, &models.MeasureTransitionRequest{MeasureName: name, Status: models.MeasureStatusApproved, ActualCost: &cost})
+	if !hasErrorCode(err, models.ErrInvalidMeasureTransition("")) {
+		t.Errorf("Se esperaba INVALID_MEASURE_TRANSITION, se obtuvo %v", err)
+	}
+
+	// Medida no aplicada a la tienda
+	_, err = svc.TransitionMeasure(ctx, 2, &models.MeasureTransitionRequest{MeasureName: name, Status: models.MeasureStatusApproved})
+	if !hasErrorCode(err, models.ErrMeasureNotApplied) {
+		t.Errorf("Se esperaba %v, se obtuvo %v", models.ErrMeasureNotApplied, err)
+	}
+
+	t.Logf("✓ Transiciones inválidas rechazadas")
+}
+
 func TestShopService_GetRiskAssessment_Success(t *testing.T) {
 	service := createShopService()
 	ctx := context.Background()
 
-	assessment, err := service.GetRiskAssessment(ctx, 1)
+	assessment, err := service.GetRiskAssessment(ctx, 1, models.ScenarioSelection{})
 	if err != nil {
 		t.Fatalf("Error inesperado: %v", err)
 	}
@@ -452,7 +929,7 @@ func TestShopService_GetRiskAssessment_ShopNotFound(t *testing.T) {
 	service := createShopService()
 	ctx := context.Background()
 
-	_, err := service.GetRiskAssessment(ctx, 999)
+	_, err := service.GetRiskAssessment(ctx, 999, models.ScenarioSelection{})
 	if err == nil {
 		t.Error("Se esperaba error para tienda inexistente")
 	}
@@ -460,6 +937,191 @@ func TestShopService_GetRiskAssessment_ShopNotFound(t *testing.T) {
 	t.Logf("✓ Error esperado: %v", err)
 }
 
+func TestShopService_GetRiskAssessment_Scenario(t *testing.T) {
+	service := createShopService()
+	ctx := context.Background()
+
+	current, err := service.GetRiskAssessment(ctx, 1, models.ScenarioSelection{})
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+
+	projected, err := service.GetRiskAssessment(ctx, 1, models.ScenarioSelection{
+		Scenario: models.ScenarioSSP585,
+		Horizon:  models.Horizon2050,
+	})
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+
+	if projected.Scenario.Scenario != models.ScenarioSSP585 || projected.Scenario.Horizon != models.Horizon2050 {
+		t.Errorf("Escenario inesperado en la respuesta: %+v", projected.Scenario)
+	}
+	if projected.Risks[1].Probability != models.LevelVeryHigh {
+		t.Errorf("Probability proyectada esperada very_high, got %s", projected.Risks[1].Probability)
+	}
+	if projected.OverallRiskScore <= current.OverallRiskScore {
+		t.Errorf("El riesgo proyectado debería ser mayor: actual=%.4f, proyectado=%.4f",
+			current.OverallRiskScore, projected.OverallRiskScore)
+	}
+
+	// En 2030 no hay proyección: se mantienen los niveles actuales
+	early, err := service.GetRiskAssessment(ctx, 1, models.ScenarioSelection{
+		Scenario: models.ScenarioSSP585,
+		Horizon:  models.Horizon2030,
+	})
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if early.OverallRiskScore != current.OverallRiskScore {
+		t.Errorf("Sin proyección debería usarse el nivel actual: actual=%.4f, 2030=%.4f",
+			current.OverallRiskScore, early.OverallRiskScore)
+	}
+
+	t.Logf("✓ Scenario: actual=%.4f, SSP5-8.5/2050=%.4f", current.OverallRiskScore, projected.OverallRiskScore)
+}
+
+// ============================================================================
+// RISK OVERRIDE TESTS
+// ============================================================================
+
+func TestShopService_RiskHistory_RecordedOnRecalculation(t *testing.T) {
+	service := createShopService()
+	ctx := context.Background()
+
+	// Sin recálculos no hay fecha de actualización
+	assessment, err := service.GetRiskAssessment(ctx, 1, models.ScenarioSelection{})
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if assessment.LastUpdated != nil {
+		t.Errorf("LastUpdated debería ser nil sin historial, got %v", assessment.LastUpdated)
+	}
+
+	if err := service.ApplyMeasures(ctx, 1, []string{"Revisión sistemas pluviales"}); err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if err := service.RemoveMeasure(ctx, 1, "Revisión sistemas pluviales"); err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+
+	history, err := service.GetRiskHistory(ctx, 1, 50)
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("Se esperaban 2 snapshots, got %d", len(history))
+	}
+	if history[0].Trigger != models.TriggerMeasureRemoved || history[1].Trigger != models.TriggerMeasuresApplied {
+		t.Errorf("Orden de snapshots inesperado: %s, %s", history[0].Trigger, history[1].Trigger)
+	}
+	if len(history[0].Risks) == 0 {
+		t.Error("El snapshot debería incluir el score de cada riesgo")
+	}
+
+	assessment, err = service.GetRiskAssessment(ctx, 1, models.ScenarioSelection{})
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if assessment.LastUpdated == nil || !assessment.LastUpdated.Equal(history[0].CreatedAt) {
+		t.Errorf("LastUpdated debería ser la fecha del último snapshot, got %v", assessment.LastUpdated)
+	}
+
+	t.Logf("✓ Historial: %d snapshots, LastUpdated=%v", len(history), assessment.LastUpdated)
+}
+
+func TestShopService_SetRiskOverride_MergedIntoAssessment(t *testing.T) {
+	service := createShopService()
+	ctx := context.Background()
+
+	before, err := service.GetRiskAssessment(ctx, 1, models.ScenarioSelection{})
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+
+	exposure := models.LevelVeryHigh
+	_, err = service.SetRiskOverride(ctx, 1, 1, &models.SetShopRiskOverrideRequest{
+		Exposure: &exposure,
+		Reason:   "Planta baja junto al cauce",
+	})
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+
+	after, err := service.GetRiskAssessment(ctx, 1, models.ScenarioSelection{})
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+
+	flood := after.Risks[0]
+	if flood.Exposure != models.LevelVeryHigh {
+		t.Errorf("Exposure esperada very_high, got %s", flood.Exposure)
+	}
+	if flood.OverrideReason == "" {
+		t.Error("Se esperaba el motivo del ajuste en el riesgo")
+	}
+	if flood.RiskScore <= before.Risks[0].RiskScore {
+		t.Errorf("El score debería aumentar: antes=%.4f, después=%.4f", before.Risks[0].RiskScore, flood.RiskScore)
+	}
+	if after.Risks[1].OverrideReason != "" {
+		t.Error("El resto de riesgos debería heredar los valores del cluster")
+	}
+
+	t.Logf("✓ RiskOverride: Score %.4f → %.4f", before.Risks[0].RiskScore, flood.RiskScore)
+}
+
+func TestShopService_SetRiskOverride_RequiresLevel(t *testing.T) {
+	service := createShopService()
+	ctx := context.Background()
+
+	_, err := service.SetRiskOverride(ctx, 1, 1, &models.SetShopRiskOverrideRequest{Reason: "Sin cambios"})
+	if err == nil {
+		t.Error("Se esperaba error si no se indica exposición ni sensibilidad")
+	}
+}
+
+func TestShopService_SetRiskOverride_UnknownRisk(t *testing.T) {
+	service := createShopService()
+	ctx := context.Background()
+
+	sensitivity := models.LevelLow
+	_, err := service.SetRiskOverride(ctx, 1, 99, &models.SetShopRiskOverrideRequest{
+		Sensitivity: &sensitivity,
+		Reason:      "Riesgo inexistente",
+	})
+	if err != models.ErrRiskNotFound {
+		t.Errorf("Se esperaba ErrRiskNotFound, got %v", err)
+	}
+}
+
+func TestShopService_RemoveRiskOverride(t *testing.T) {
+	service := createShopService()
+	ctx := context.Background()
+
+	if err := service.RemoveRiskOverride(ctx, 1, 1); err != models.ErrRiskOverrideNotFound {
+		t.Errorf("Se esperaba ErrRiskOverrideNotFound, got %v", err)
+	}
+
+	sensitivity := models.LevelLow
+	if _, err := service.SetRiskOverride(ctx, 1, 2, &models.SetShopRiskOverrideRequest{
+		Sensitivity: &sensitivity,
+		Reason:      "Local climatizado",
+	}); err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if err := service.RemoveRiskOverride(ctx, 1, 2); err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+
+	overrides, err := service.GetRiskOverrides(ctx, 1)
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if len(overrides) != 0 {
+		t.Errorf("Se esperaban 0 ajustes, got %d", len(overrides))
+	}
+}
+
 // ============================================================================
 // UPDATE TESTS
 // ============================================================================
@@ -487,6 +1149,47 @@ func TestShopService_Update_Success(t *testing.T) {
 	t.Logf("✓ Update: Shop Location=%s, Surface=%.0f", shop.Location, shop.Surface)
 }
 
+func TestShopService_Update_RecalculatesRisk(t *testing.T) {
+	snapshotRepo := &mockSnapshotRepoForService{}
+	service := services.NewShopService(
+		newMockShopRepoForService(),
+		newMockClusterRepoForService(),
+		newMockRiskRepoForService(),
+		newMockMeasureRepoForService(),
+		newMockOverrideRepoForService(),
+		&mockScenarioRepoForService{},
+		snapshotRepo,
+		newMockTaxonomyRepo(),
+		scoring.Static(scoring.Default()),
+		&mockAuditRecorder{},
+	)
+	ctx := context.Background()
+
+	// Cambiar el nombre no afecta al riesgo
+	newLocation := "Madrid Gran Vía"
+	if _, err := service.Update(ctx, 1, &models.UpdateShopRequest{Location: &newLocation}); err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if len(snapshotRepo.snapshots) != 0 {
+		t.Fatalf("No se esperaba recálculo, obtenidos %d snapshots", len(snapshotRepo.snapshots))
+	}
+
+	// Cambiar el cluster cambia los riesgos de la tienda
+	newCluster := int64(3)
+	shop, err := service.Update(ctx, 1, &models.UpdateShopRequest{ClusterID: &newCluster})
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if len(snapshotRepo.snapshots) != 1 || snapshotRepo.snapshots[0].Trigger != models.TriggerShopUpdated {
+		t.Fatalf("Se esperaba un snapshot %s, obtenidos %+v", models.TriggerShopUpdated, snapshotRepo.snapshots)
+	}
+	if shop.TotalRiskMethod == "" || snapshotRepo.snapshots[0].TotalRisk != shop.TotalRisk {
+		t.Errorf("Riesgo no recalculado: %.2f (%s)", shop.TotalRisk, shop.TotalRiskMethod)
+	}
+
+	t.Logf("✓ Update recalcula el riesgo al cambiar el cluster: %.2f", shop.TotalRisk)
+}
+
 func TestShopService_Update_NotFound(t *testing.T) {
 	service := createShopService()
 	ctx := context.Background()
@@ -510,6 +1213,12 @@ func TestShopService_Delete_Success(t *testing.T) {
 		newMockClusterRepoForService(),
 		newMockRiskRepoForService(),
 		newMockMeasureRepoForService(),
+		newMockOverrideRepoForService(),
+		&mockScenarioRepoForService{},
+		&mockSnapshotRepoForService{},
+		newMockTaxonomyRepo(),
+		scoring.Static(scoring.Default()),
+		&mockAuditRecorder{},
 	)
 	ctx := context.Background()
 
@@ -527,6 +1236,104 @@ func TestShopService_Delete_Success(t *testing.T) {
 	t.Log("✓ Delete: Tienda eliminada correctamente")
 }
 
+func TestShopService_Audit_RecordsMutations(t *testing.T) {
+	recorder := &mockAuditRecorder{}
+	svc := services.NewShopService(
+		newMockShopRepoForService(),
+		newMockClusterRepoForService(),
+		newMockRiskRepoForService(),
+		newMockMeasureRepoForService(),
+		newMockOverrideRepoForService(),
+		&mockScenarioRepoForService{},
+		&mockSnapshotRepoForService{},
+		newMockTaxonomyRepo(),
+		scoring.Static(scoring.Default()),
+		recorder,
+	)
+	ctx := context.Background()
+
+	location := "Barcelona Centro"
+	if _, err := svc.Update(ctx, 1, &models.UpdateShopRequest{Location: &location}); err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if err := svc.Delete(ctx, 1); err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	// Las lecturas y las operaciones fallidas no se auditan
+	_, _ = svc.GetByID(ctx, 2)
+	_ = svc.Delete(ctx, 999)
+
+	actions := recorder.actions()
+	if len(actions) != 2 || actions[0] != models.AuditShopUpdated || actions[1] != models.AuditShopDeleted {
+		t.Fatalf("Se esperaban update y delete, se obtuvo %v", actions)
+	}
+
+	updated := recorder.entries[0]
+	before, ok := updated.before.(models.Shop)
+	if !ok || before.Location == location || updated.after.(*models.Shop).Location != location {
+		t.Errorf("El update debería registrar el estado anterior y el nuevo: %+v", updated)
+	}
+	if recorder.entries[1].before == nil || recorder.entries[1].after != nil {
+		t.Error("El delete debería registrar solo el estado anterior")
+	}
+
+	t.Logf("✓ Auditoría: %v", actions)
+}
+
+func TestShopService_Restore_Success(t *testing.T) {
+	repo := newMockShopRepoForService()
+	recorder := &mockAuditRecorder{}
+	svc := services.NewShopService(
+		repo,
+		newMockClusterRepoForService(),
+		newMockRiskRepoForService(),
+		newMockMeasureRepoForService(),
+		newMockOverrideRepoForService(),
+		&mockScenarioRepoForService{},
+		&mockSnapshotRepoForService{},
+		newMockTaxonomyRepo(),
+		scoring.Static(scoring.Default()),
+		recorder,
+	)
+	ctx := context.Background()
+
+	if err := svc.Delete(ctx, 1); err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if repo.deleted[1] == nil || repo.deleted[1].DeletedAt == nil {
+		t.Fatal("La tienda debería quedar marcada como eliminada")
+	}
+
+	shop, err := svc.Restore(ctx, 1)
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if shop.ID != 1 || shop.DeletedAt != nil {
+		t.Errorf("Se esperaba la tienda 1 activa, se obtuvo %+v", shop)
+	}
+	if _, err := svc.GetByID(ctx, 1); err != nil {
+		t.Errorf("La tienda restaurada debería poder consultarse: %v", err)
+	}
+
+	actions := recorder.actions()
+	if len(actions) != 2 || actions[1] != models.AuditShopRestored {
+		t.Errorf("Se esperaban delete y restore, se obtuvo %v", actions)
+	}
+
+	t.Logf("✓ Restore: tienda %d restaurada", shop.ID)
+}
+
+func TestShopService_Restore_NotDeleted(t *testing.T) {
+	service := createShopService()
+
+	_, err := service.Restore(context.Background(), 2)
+	if !hasErrorCode(err, models.ErrShopNotDeleted) {
+		t.Errorf("Se esperaba %v, se obtuvo %v", models.ErrShopNotDeleted, err)
+	}
+
+	t.Logf("✓ Error esperado: %v", err)
+}
+
 func TestShopService_Delete_NotFound(t *testing.T) {
 	service := createShopService()
 	ctx := context.Background()
@@ -586,3 +1393,128 @@ func BenchmarkShopService_Create(b *testing.B) {
 		_, _ = svc.Create(ctx, req)
 	}
 }
+
+// ============================================================================
+// SCOPE TESTS
+// ============================================================================
+
+// createScopedShopService crea el servicio con la tienda 1 en España y la 2 en Francia
+func createScopedShopService() services.ShopService {
+	shopRepo := newMockShopRepoForService()
+	shopRepo.shops[1].Country = "ES"
+	shopRepo.shops[2].Country = "FR"
+	return services.NewShopService(
+		shopRepo,
+		newMockClusterRepoForService(),
+		newMockRiskRepoForService(),
+		newMockMeasureRepoForService(),
+		newMockOverrideRepoForService(),
+		&mockScenarioRepoForService{},
+		&mockSnapshotRepoForService{},
+		newMockTaxonomyRepo(),
+		scoring.Static(scoring.Default()),
+		&mockAuditRecorder{},
+	)
+}
+
+func TestShopService_Scope_OutOfScopeShopNotFound(t *testing.T) {
+	service := createScopedShopService()
+	ctx := authz.WithScope(context.Background(), authz.Scope{Countries: []string{"es"}})
+
+	if _, err := service.GetByID(ctx, 1); err != nil {
+		t.Fatalf("La tienda de su país debería ser visible: %v", err)
+	}
+
+	if _, err := service.GetByID(ctx, 2); !errors.Is(err, models.ErrShopNotFound) {
+		t.Errorf("Se esperaba ErrShopNotFound para una tienda de otro país, got %v", err)
+	}
+	if _, err := service.GetRiskAssessment(ctx, 2, models.ScenarioSelection{}); !errors.Is(err, models.ErrShopNotFound) {
+		t.Errorf("Se esperaba ErrShopNotFound en la evaluación, got %v", err)
+	}
+	if err := service.ApplyMeasures(ctx, 2, []string{"Revisión sistemas pluviales"}); !errors.Is(err, models.ErrShopNotFound) {
+		t.Errorf("No debería poder modificar tiendas fuera de su ámbito, got %v", err)
+	}
+
+	t.Log("✓ Ámbito por país: las tiendas de otros países no existen para el usuario")
+}
+
+func TestShopService_Scope_Clusters(t *testing.T) {
+	service := createScopedShopService()
+	ctx := authz.WithScope(context.Background(), authz.Scope{ClusterIDs: []int64{1}})
+
+	if _, err := service.GetByCluster(ctx, 2); !errors.Is(err, models.ErrClusterNotFound) {
+		t.Errorf("Se esperaba ErrClusterNotFound para un cluster fuera del ámbito, got %v", err)
+	}
+
+	_, err := service.Create(ctx, &models.CreateShopRequest{Location: "Niza", ClusterID: 2, Country: "FR", Surface: 100})
+	if !errors.Is(err, models.ErrClusterNotFound) {
+		t.Errorf("No debería poder crear tiendas en clusters fuera de su ámbito, got %v", err)
+	}
+
+	countryCtx := authz.WithScope(context.Background(), authz.Scope{Countries: []string{"ES"}})
+	newCountry := "FR"
+	_, err = service.Update(countryCtx, 1, &models.UpdateShopRequest{Country: &newCountry})
+	if !errors.Is(err, models.ErrOutOfScope) {
+		t.Errorf("Se esperaba ErrOutOfScope al mover la tienda fuera de su ámbito, got %v", err)
+	}
+
+	t.Log("✓ Ámbito por cluster aplicado a lecturas y escrituras")
+}
+
+func TestShopService_GetDossier(t *testing.T) {
+	shopRepo := newMockShopRepoForService()
+	shopRepo.shops[3] = &models.Shop{ID: 3, Location: "Madrid Norte", ClusterID: 1}
+	shopRepo.setShopMeasure(1, "Aislamiento térmico", models.MeasureStatusPlanned)
+	shopRepo.coverage = map[int64][]models.RiskCoverageItem{
+		1: {
+			{RiskID: 1, RiskName: "Inundación", AvailableMeasures: []models.Measure{
+				{Name: "Jardín de lluvia", EstimatedCost: 900},
+				{Name: "Revisión sistemas pluviales", EstimatedCost: 300},
+			}},
+			// La ola de calor ya tiene una medida planificada
+			{RiskID: 2, RiskName: "Ola de calor", AvailableMeasures: []models.Measure{
+				{Name: "Aislamiento térmico", EstimatedCost: 1500},
+				{Name: "Toldos", EstimatedCost: 200},
+			}},
+		},
+	}
+	service := services.NewShopService(
+		shopRepo,
+		newMockClusterRepoForService(),
+		newMockRiskRepoForService(),
+		newMockMeasureRepoForService(),
+		newMockOverrideRepoForService(),
+		&mockScenarioRepoForService{},
+		&mockSnapshotRepoForService{},
+		newMockTaxonomyRepo(),
+		scoring.Static(scoring.Default()),
+		&mockAuditRecorder{},
+	)
+
+	dossier, err := service.GetDossier(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+
+	if dossier.Cluster.Name != "Centro Urbano" || len(dossier.Neighbours) != 1 || dossier.Neighbours[0].ID != 3 {
+		t.Errorf("Cluster o tiendas vecinas inesperados: %+v, %+v", dossier.Cluster, dossier.Neighbours)
+	}
+	if len(dossier.Assessment.Risks) != 2 || len(dossier.Coverage.Risks) != 2 || len(dossier.Measures) != 1 {
+		t.Errorf("Evaluación, cobertura o medidas inesperadas: %+v", dossier)
+	}
+
+	// Solo se recomiendan medidas para la inundación, primero la más barata
+	recs := dossier.Recommendations
+	if len(recs) != 2 || recs[0].Name != "Revisión sistemas pluviales" || recs[1].Name != "Jardín de lluvia" {
+		t.Fatalf("Recomendaciones inesperadas: %+v", recs)
+	}
+	if len(recs[0].Risks) != 1 || recs[0].Risks[0] != "Inundación" || recs[0].RiskScore <= 0 {
+		t.Errorf("La recomendación debería indicar el riesgo que reduce: %+v", recs[0])
+	}
+
+	if _, err := service.GetDossier(context.Background(), 99); err == nil {
+		t.Error("Se esperaba error para tienda inexistente")
+	}
+
+	t.Logf("✓ GetDossier reúne la ficha de la tienda con %d medidas recomendadas", len(recs))
+}
@@ -0,0 +1,485 @@
+package services_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/application/services"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/infrastructure/oidc"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ============================================================================
+// MOCK IDP
+// ============================================================================
+
+// mockIdP es un proveedor de identidad OpenID Connect mínimo: publica el documento
+// de descubrimiento y el JWKS, autoriza al usuario configurado sin pedir credenciales
+// y firma ID tokens RS256 comprobando el code verifier PKCE en el canje.
+type mockIdP struct {
+	server     *httptest.Server
+	key        *rsa.PrivateKey
+	signingKey *rsa.PrivateKey // clave con la que se firman los ID tokens
+
+	mu     sync.Mutex
+	email  string
+	groups []string
+	codes  map[string]mockAuthorization
+	nextID int
+}
+
+type mockAuthorization struct {
+	nonce     string
+	challenge string
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+
+	idp := &mockIdP{key: key, signingKey: key, codes: make(map[string]mockAuthorization)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", idp.discovery)
+	mux.HandleFunc("/authorize", idp.authorize)
+	mux.HandleFunc("/token", idp.token)
+	mux.HandleFunc("/jwks", idp.jwks)
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+// loginAs configura el usuario que el IdP autoriza en el siguiente inicio de sesión
+func (p *mockIdP) loginAs(email string, groups ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.email = email
+	p.groups = groups
+}
+
+func (p *mockIdP) discovery(w http.ResponseWriter, r *http.Request) {
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"issuer":                 p.server.URL,
+		"authorization_endpoint": p.server.URL + "/authorize",
+		"token_endpoint":         p.server.URL + "/token",
+		"jwks_uri":               p.server.URL + "/jwks",
+	})
+}
+
+func (p *mockIdP) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		http.Error(w, "pkce required", http.StatusBadRequest)
+		return
+	}
+
+	p.mu.Lock()
+	p.nextID++
+	code := fmt.Sprintf("code-%d", p.nextID)
+	p.codes[code] = mockAuthorization{nonce: q.Get("nonce"), challenge: q.Get("code_challenge")}
+	p.mu.Unlock()
+
+	redirect := q.Get("redirect_uri") + "?" + url.Values{"code": {code}, "state": {q.Get("state")}}.Encode()
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+func (p *mockIdP) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	p.mu.Lock()
+	auth, ok := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	email, groups := p.email, p.groups
+	p.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != auth.challenge {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            p.server.URL,
+		"aud":            "climate-invest",
+		"sub":            "idp|" + email,
+		"email":          email,
+		"email_verified": true,
+		"groups":         groups,
+		"nonce":          auth.nonce,
+		"iat":            time.Now().Unix(),
+		"exp":            time.Now().Add(5 * time.Minute).Unix(),
+	})
+	token.Header["kid"] = "test-key"
+	signed, err := token.SignedString(p.signingKey)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]string{"id_token": signed, "token_type": "Bearer"})
+}
+
+func (p *mockIdP) jwks(w http.ResponseWriter, r *http.Request) {
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test-key",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+		}},
+	})
+}
+
+// ============================================================================
+// HELPERS
+// ============================================================================
+
+var testRoleMapping = services.OIDCRoleMapping{
+	AdminGroups:   []string{"cio-admins"},
+	ManagerGroups: []string{"cio-managers"},
+	ViewerGroups:  []string{"cio-viewers"},
+	CountryGroups: map[string][]string{"cio-spain": {"ES"}, "cio-iberia": {"ES", "PT"}},
+	ClusterGroups: map[string][]int64{"cio-north": {3}},
+	GlobalGroups:  []string{"cio-global"},
+}
+
+func createSSOService(t *testing.T, idp *mockIdP, roles services.OIDCRoleMapping) (services.SSOService, services.AuthService, *mockUserRepoForAuth, *mockSessionRepoForAuth) {
+	t.Helper()
+	authService, jwtService, users, sessions := createAuthServiceWithRepos(t)
+	client := oidc.NewClient(oidc.Config{
+		IssuerURL:   idp.server.URL,
+		ClientID:    "climate-invest",
+		RedirectURL: "http://localhost:8080/api/v1/auth/oidc/callback",
+	})
+	return services.NewSSOService(client, roles, users, sessions, jwtService, &mockAuditRecorder{}), authService, users, sessions
+}
+
+// authorizeAtIdP sigue la URL de inicio de sesión en el IdP y retorna el state y el
+// código con los que el IdP redirige al callback
+func authorizeAtIdP(t *testing.T, authURL string) (string, string) {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("Se esperaba redirección del IdP, se obtuvo %d", resp.StatusCode)
+	}
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	return location.Query().Get("state"), location.Query().Get("code")
+}
+
+func ssoLogin(t *testing.T, svc services.SSOService) (*models.AuthResponse, error) {
+	t.Helper()
+	redirect, err := svc.Start(context.Background())
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	state, code := authorizeAtIdP(t, redirect.AuthURL)
+	return svc.Complete(context.Background(), state, code)
+}
+
+// hasErrorCode compara por código, ya que los fallos del IdP se retornan con el error interno adjunto
+func hasErrorCode(err error, target *models.AppError) bool {
+	var appErr *models.AppError
+	return errors.As(err, &appErr) && appErr.Code == target.Code
+}
+
+// ============================================================================
+// SSO SERVICE TESTS
+// ============================================================================
+
+func TestSSOService_ProvisionsUserWithGroupRole(t *testing.T) {
+	idp := newMockIdP(t)
+	svc, authService, users, _ := createSSOService(t, idp, testRoleMapping)
+	idp.loginAs("luis@example.com", "everyone", "cio-managers", "cio-spain")
+
+	resp, err := ssoLogin(t, svc)
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if resp.User.Role != models.RoleManager || resp.Token == "" || resp.RefreshToken == "" {
+		t.Errorf("Se esperaba sesión de manager, se obtuvo rol %s", resp.User.Role)
+	}
+
+	user, _ := users.GetByEmail(context.Background(), "luis@example.com")
+	if user == nil || user.Password != "" {
+		t.Fatal("Se esperaba un usuario dado de alta sin contraseña local")
+	}
+
+	// Sin contraseña local el login con contraseña no es posible
+	_, err = authService.Login(context.Background(), &models.LoginRequest{Email: "luis@example.com", Password: "password123"})
+	if !errors.Is(err, models.ErrInvalidCredentials) {
+		t.Errorf("Se esperaba ErrInvalidCredentials, se obtuvo %v", err)
+	}
+
+	t.Logf("✓ SSO: usuario %s dado de alta con rol %s", resp.User.Email, resp.User.Role)
+}
+
+func TestSSOService_LinksExistingAccountOnlyExplicitly(t *testing.T) {
+	idp := newMockIdP(t)
+	svc, authService, users, _ := createSSOService(t, idp, testRoleMapping)
+	previous := login(t, authService)
+	idp.loginAs("ana@example.com", "cio-admins")
+
+	// Un email coincidente no basta para entrar en una cuenta local
+	_, err := ssoLogin(t, svc)
+	if !errors.Is(err, models.ErrSSOAccountNotLinked) {
+		t.Fatalf("Se esperaba ErrSSOAccountNotLinked, se obtuvo %v", err)
+	}
+
+	// El propietario de la cuenta la vincula desde su sesión
+	redirect, err := svc.StartLink(context.Background(), previous.User.ID)
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	state, code := authorizeAtIdP(t, redirect.AuthURL)
+	resp, err := svc.Complete(context.Background(), state, code)
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if resp.User.ID != previous.User.ID || resp.User.Role != previous.User.Role {
+		t.Errorf("Se esperaba el usuario %d con rol %s, se obtuvo %d con %s",
+			previous.User.ID, previous.User.Role, resp.User.ID, resp.User.Role)
+	}
+
+	user, _ := users.GetByID(context.Background(), previous.User.ID)
+	if user.OIDCIssuer != idp.server.URL || user.OIDCSubject != "idp|ana@example.com" {
+		t.Errorf("Se esperaba la identidad del IdP vinculada, se obtuvo %q %q", user.OIDCIssuer, user.OIDCSubject)
+	}
+
+	// Una vez vinculada, el inicio de sesión único entra en la cuenta por su identidad
+	resp, err = ssoLogin(t, svc)
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if resp.User.ID != previous.User.ID {
+		t.Errorf("Se esperaba el usuario %d, se obtuvo %d", previous.User.ID, resp.User.ID)
+	}
+
+	t.Logf("✓ SSO: la cuenta %s solo se vincula de forma explícita", previous.User.Email)
+}
+
+func TestSSOService_KeepsRoleSetByAdmin(t *testing.T) {
+	idp := newMockIdP(t)
+	svc, _, users, _ := createSSOService(t, idp, testRoleMapping)
+	idp.loginAs("luis@example.com", "cio-admins")
+
+	resp, err := ssoLogin(t, svc)
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+
+	// Un administrador rebaja el rol asignado en el alta
+	user, _ := users.GetByID(context.Background(), resp.User.ID)
+	user.Role = models.RoleViewer
+	_ = users.Update(context.Background(), user)
+
+	resp, err = ssoLogin(t, svc)
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if resp.User.Role != models.RoleViewer {
+		t.Errorf("Se esperaba conservar el rol viewer asignado por el administrador, se obtuvo %s", resp.User.Role)
+	}
+
+	t.Log("✓ SSO: los grupos del IdP no sobrescriben el rol asignado por un administrador")
+}
+
+func TestSSOService_RejectsIdentityLinkedToAnotherAccount(t *testing.T) {
+	idp := newMockIdP(t)
+	svc, authService, _, _ := createSSOService(t, idp, testRoleMapping)
+	ana := login(t, authService)
+	idp.loginAs("luis@example.com", "cio-viewers", "cio-global")
+
+	if _, err := ssoLogin(t, svc); err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+
+	redirect, err := svc.StartLink(context.Background(), ana.User.ID)
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	state, code := authorizeAtIdP(t, redirect.AuthURL)
+	_, err = svc.Complete(context.Background(), state, code)
+	if !errors.Is(err, models.ErrSSOIdentityConflict) {
+		t.Errorf("Se esperaba ErrSSOIdentityConflict, se obtuvo %v", err)
+	}
+
+	t.Log("✓ SSO: una identidad del IdP solo se vincula a una cuenta")
+}
+
+func TestSSOService_StateIsSingleUse(t *testing.T) {
+	idp := newMockIdP(t)
+	svc, _, _, _ := createSSOService(t, idp, testRoleMapping)
+	idp.loginAs("luis@example.com", "cio-viewers", "cio-global")
+
+	redirect, err := svc.Start(context.Background())
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	state, code := authorizeAtIdP(t, redirect.AuthURL)
+	if state != redirect.State {
+		t.Fatalf("Se esperaba el state %q en la redirección del IdP, se obtuvo %q", redirect.State, state)
+	}
+	if _, err := svc.Complete(context.Background(), state, code); err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+
+	_, err = svc.Complete(context.Background(), state, code)
+	if !errors.Is(err, models.ErrInvalidSSOState) {
+		t.Errorf("Se esperaba ErrInvalidSSOState, se obtuvo %v", err)
+	}
+
+	t.Log("✓ SSO: un state no se puede reutilizar")
+}
+
+func TestSSOService_LimitsPendingLogins(t *testing.T) {
+	idp := newMockIdP(t)
+	svc, _, _, _ := createSSOService(t, idp, testRoleMapping)
+
+	// Los inicios de sesión abandonados no pueden hacer crecer la memoria sin límite
+	var err error
+	started := 0
+	for ; started <= 10000 && err == nil; started++ {
+		_, err = svc.Start(context.Background())
+	}
+	if !errors.Is(err, models.ErrSSOBusy) {
+		t.Fatalf("Se esperaba ErrSSOBusy, se obtuvo %v", err)
+	}
+
+	t.Logf("✓ SSO: se rechazan nuevos inicios de sesión tras %d en curso", started-1)
+}
+
+func TestSSOService_RejectsCodeFromAnotherLogin(t *testing.T) {
+	idp := newMockIdP(t)
+	svc, _, _, _ := createSSOService(t, idp, testRoleMapping)
+	idp.loginAs("luis@example.com", "cio-viewers", "cio-global")
+
+	urlA, _ := svc.Start(context.Background())
+	urlB, _ := svc.Start(context.Background())
+	stateA, _ := authorizeAtIdP(t, urlA.AuthURL)
+	_, codeB := authorizeAtIdP(t, urlB.AuthURL)
+
+	// El code verifier del inicio A no corresponde al code challenge del código B
+	_, err := svc.Complete(context.Background(), stateA, codeB)
+	if !hasErrorCode(err, models.ErrSSOFailed) {
+		t.Errorf("Se esperaba ErrSSOFailed, se obtuvo %v", err)
+	}
+
+	t.Log("✓ SSO: PKCE impide canjear un código interceptado")
+}
+
+func TestSSOService_RejectsTokenWithUnknownSignature(t *testing.T) {
+	idp := newMockIdP(t)
+	svc, _, users, _ := createSSOService(t, idp, testRoleMapping)
+	idp.loginAs("luis@example.com", "cio-admins")
+
+	forged, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	idp.signingKey = forged
+
+	_, err = ssoLogin(t, svc)
+	if !hasErrorCode(err, models.ErrSSOFailed) {
+		t.Errorf("Se esperaba ErrSSOFailed, se obtuvo %v", err)
+	}
+	if user, _ := users.GetByEmail(context.Background(), "luis@example.com"); user != nil {
+		t.Error("No debería darse de alta un usuario con un ID token inválido")
+	}
+
+	t.Log("✓ SSO: se rechazan ID tokens no firmados por el IdP")
+}
+
+func TestSSOService_UnmappedGroups(t *testing.T) {
+	idp := newMockIdP(t)
+	idp.loginAs("luis@example.com", "marketing", "cio-spain")
+
+	svc, _, _, _ := createSSOService(t, idp, testRoleMapping)
+	_, err := ssoLogin(t, svc)
+	if !errors.Is(err, models.ErrSSORoleNotMapped) {
+		t.Errorf("Sin rol por defecto se esperaba ErrSSORoleNotMapped, se obtuvo %v", err)
+	}
+
+	withDefault := testRoleMapping
+	withDefault.DefaultRole = models.RoleViewer
+	svc, _, _, _ = createSSOService(t, idp, withDefault)
+	resp, err := ssoLogin(t, svc)
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if resp.User.Role != models.RoleViewer {
+		t.Errorf("Se esperaba el rol por defecto viewer, se obtuvo %s", resp.User.Role)
+	}
+
+	t.Log("✓ SSO: grupos sin mapear usan el rol por defecto o se rechazan")
+}
+
+func TestSSOService_ProvisionsScopeFromGroups(t *testing.T) {
+	idp := newMockIdP(t)
+	svc, _, users, _ := createSSOService(t, idp, testRoleMapping)
+
+	// Sin grupos de ámbito un manager tendría acceso a todos los datos: se rechaza
+	idp.loginAs("luis@example.com", "cio-managers")
+	_, err := ssoLogin(t, svc)
+	if !errors.Is(err, models.ErrSSOScopeNotMapped) {
+		t.Fatalf("Se esperaba ErrSSOScopeNotMapped, se obtuvo %v", err)
+	}
+	if user, _ := users.GetByEmail(context.Background(), "luis@example.com"); user != nil {
+		t.Error("No debería darse de alta un usuario sin ámbito")
+	}
+
+	// El ámbito es la unión de los países y clusters de sus grupos
+	idp.loginAs("luis@example.com", "cio-managers", "cio-spain", "cio-iberia", "cio-north")
+	resp, err := ssoLogin(t, svc)
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if len(resp.User.Countries) != 2 || resp.User.Countries[0] != "ES" || resp.User.Countries[1] != "PT" {
+		t.Errorf("Se esperaban los países [ES PT], se obtuvo %v", resp.User.Countries)
+	}
+	if len(resp.User.ClusterIDs) != 1 || resp.User.ClusterIDs[0] != 3 {
+		t.Errorf("Se esperaba el cluster [3], se obtuvo %v", resp.User.ClusterIDs)
+	}
+
+	// Los admins no necesitan grupos de ámbito
+	idp.loginAs("marta@example.com", "cio-admins")
+	resp, err = ssoLogin(t, svc)
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if len(resp.User.Countries) != 0 || len(resp.User.ClusterIDs) != 0 {
+		t.Errorf("Se esperaba un admin con ámbito global, se obtuvo %v %v", resp.User.Countries, resp.User.ClusterIDs)
+	}
+
+	t.Log("✓ SSO: el ámbito de datos del alta se asigna según los grupos del IdP")
+}
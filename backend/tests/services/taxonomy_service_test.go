@@ -0,0 +1,169 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/application/services"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/scoring"
+)
+
+// ============================================================================
+// TAXONOMY SERVICE TESTS
+// ============================================================================
+
+func newTaxonomyService(shopRepo *mockShopRepoForService, taxonomyRepo *mockTaxonomyRepo, recorder *mockAuditRecorder) services.TaxonomyService {
+	return newTaxonomyServiceWithSnapshots(shopRepo, taxonomyRepo, &mockSnapshotRepoForService{}, recorder)
+}
+
+func newTaxonomyServiceWithSnapshots(shopRepo *mockShopRepoForService, taxonomyRepo *mockTaxonomyRepo, snapshotRepo *mockSnapshotRepoForService, recorder *mockAuditRecorder) services.TaxonomyService {
+	riskRepo := newMockRiskRepoForService()
+	overrideRepo := newMockOverrideRepoForService()
+	scenarioRepo := &mockScenarioRepoForService{}
+	taxonomyRepo.shopRepo, taxonomyRepo.riskRepo, taxonomyRepo.overrideRepo = shopRepo, riskRepo, overrideRepo
+	return services.NewTaxonomyService(
+		taxonomyRepo,
+		shopRepo,
+		newMockMeasureRepoForService(),
+		riskRepo,
+		overrideRepo,
+		scenarioRepo,
+		scoring.Static(scoring.Default()),
+		services.NewShopRiskRecalculator(shopRepo, riskRepo, overrideRepo, scenarioRepo, snapshotRepo, taxonomyRepo),
+		recorder,
+	)
+}
+
+func TestTaxonomyService_SetMapping(t *testing.T) {
+	taxonomyRepo := newMockTaxonomyRepo()
+	recorder := &mockAuditRecorder{}
+	svc := newTaxonomyService(newMockShopRepoForService(), taxonomyRepo, recorder)
+	ctx := context.Background()
+
+	_, err := svc.SetMapping(ctx, "Aislamiento térmico", &models.SetMeasureTaxonomyRequest{ActivityCode: "4.1"})
+	if !hasErrorCode(err, models.ErrInvalidInput("")) {
+		t.Errorf("Se esperaba error por actividad desconocida, se obtuvo %v", err)
+	}
+
+	mapping, err := svc.SetMapping(ctx, "Aislamiento térmico", &models.SetMeasureTaxonomyRequest{ActivityCode: "7.2"})
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if _, ok := taxonomyRepo.mappings["Aislamiento térmico"]; !ok || mapping.ActivityCode != "7.2" {
+		t.Errorf("La correspondencia no se guardó: %+v", mapping)
+	}
+
+	if err := svc.DeleteMapping(ctx, "Aislamiento térmico"); err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if err := svc.DeleteMapping(ctx, "Aislamiento térmico"); !hasErrorCode(err, models.ErrTaxonomyNotMapped) {
+		t.Errorf("Se esperaba %v, se obtuvo %v", models.ErrTaxonomyNotMapped, err)
+	}
+
+	actions := recorder.actions()
+	if len(actions) != 2 || actions[0] != models.AuditTaxonomyMappingSet || actions[1] != models.AuditTaxonomyMappingRemoved {
+		t.Errorf("Auditoría inesperada: %v", actions)
+	}
+
+	t.Logf("✓ SetMapping valida la actividad y audita los cambios")
+}
+
+func TestTaxonomyService_MappingChangesRecalculateShops(t *testing.T) {
+	shopRepo := newMockShopRepoForService()
+	shopRepo.setShopMeasure(1, "Aislamiento térmico", models.MeasureStatusCompleted)
+	snapshotRepo := &mockSnapshotRepoForService{}
+	svc := newTaxonomyServiceWithSnapshots(shopRepo, newMockTaxonomyRepo(), snapshotRepo, &mockAuditRecorder{})
+	ctx := context.Background()
+
+	// Con la correspondencia el aislamiento cubre la ola de calor: uno de los dos riesgos
+	allMet := models.DNSHCriteria{ClimateMitigation: true, Water: true, CircularEconomy: true, Pollution: true, Biodiversity: true}
+	if _, err := svc.SetMapping(ctx, "Aislamiento térmico", &models.SetMeasureTaxonomyRequest{ActivityCode: "7.2", DNSH: allMet}); err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if coverage := shopRepo.shops[1].TaxonomyCoverage; coverage != 50 {
+		t.Errorf("TaxonomyCoverage=%.2f tras asignar la actividad, esperado 50", coverage)
+	}
+
+	if err := svc.DeleteMapping(ctx, "Aislamiento térmico"); err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if coverage := shopRepo.shops[1].TaxonomyCoverage; coverage != 0 {
+		t.Errorf("TaxonomyCoverage=%.2f tras eliminar la actividad, esperado 0", coverage)
+	}
+
+	// Solo se recalcula la tienda que tiene aplicada la medida
+	if len(snapshotRepo.snapshots) != 2 {
+		t.Fatalf("Se esperaban 2 snapshots, obtenidos %d", len(snapshotRepo.snapshots))
+	}
+	for _, snapshot := range snapshotRepo.snapshots {
+		if snapshot.ShopID != 1 || snapshot.Trigger != models.TriggerTaxonomyChanged {
+			t.Errorf("Snapshot inesperado: tienda %d, trigger %s", snapshot.ShopID, snapshot.Trigger)
+		}
+	}
+
+	t.Log("✓ Los cambios de correspondencia recalculan la cobertura guardada de las tiendas afectadas")
+}
+
+func TestTaxonomyService_GetShopReport(t *testing.T) {
+	shopRepo := newMockShopRepoForService()
+	shopRepo.setShopMeasure(1, "Revisión sistemas pluviales", models.MeasureStatusCompleted)
+	shopRepo.setShopMeasure(1, "Aislamiento térmico", models.MeasureStatusVerified)
+	shopRepo.shopMeasures[1]["Revisión sistemas pluviales"].EstimatedCost = 400
+	shopRepo.shopMeasures[1]["Aislamiento térmico"].EstimatedCost = 1500
+
+	// El aislamiento es elegible pero no cumple el criterio DNSH de agua
+	taxonomyRepo := newMockTaxonomyRepo()
+	taxonomyRepo.mappings["Aislamiento térmico"] = models.MeasureTaxonomy{
+		MeasureName:  "Aislamiento térmico",
+		ActivityCode: "7.2",
+		DNSH:         models.DNSHCriteria{ClimateMitigation: true, CircularEconomy: true, Pollution: true, Biodiversity: true},
+	}
+	svc := newTaxonomyService(shopRepo, taxonomyRepo, &mockAuditRecorder{})
+
+	report, err := svc.GetShopReport(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+
+	if report.Status != models.TaxonomyPartiallyAligned || report.AlignmentPercentage != 50 {
+		t.Errorf("Status=%s (%.2f%%), esperado %s (50%%)", report.Status, report.AlignmentPercentage, models.TaxonomyPartiallyAligned)
+	}
+	if report.AlignedCapEx != 400 || report.TotalCapEx != 1900 {
+		t.Errorf("CapEx alineado %v de %v, esperado 400 de 1900", report.AlignedCapEx, report.TotalCapEx)
+	}
+	for _, m := range report.Measures {
+		if m.MeasureName == "Aislamiento térmico" && (m.Aligned || len(m.UnmetDNSH) != 1 || m.UnmetDNSH[0] != "water") {
+			t.Errorf("El aislamiento no debería estar alineado por el criterio de agua: %+v", m)
+		}
+	}
+
+	t.Logf("✓ GetShopReport: %s, %.0f%% de riesgos materiales cubiertos", report.Status, report.AlignmentPercentage)
+}
+
+func TestTaxonomyService_GetPortfolioReport(t *testing.T) {
+	shopRepo := newMockShopRepoForService()
+	shopRepo.setShopMeasure(1, "Revisión sistemas pluviales", models.MeasureStatusCompleted)
+	shopRepo.shopMeasures[1]["Revisión sistemas pluviales"].EstimatedCost = 400
+	shopRepo.setShopMeasure(2, "Revisión sistemas pluviales", models.MeasureStatusInProgress)
+	shopRepo.shopMeasures[2]["Revisión sistemas pluviales"].EstimatedCost = 600
+	svc := newTaxonomyService(shopRepo, newMockTaxonomyRepo(), &mockAuditRecorder{})
+
+	report, err := svc.GetPortfolioReport(context.Background())
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+
+	if report.TotalShops != 2 || report.ShopsByStatus[models.TaxonomyPartiallyAligned] != 1 || report.ShopsByStatus[models.TaxonomyNotAligned] != 1 {
+		t.Errorf("Tiendas por estado inesperadas: %v", report.ShopsByStatus)
+	}
+	if report.AverageAlignment != 25 {
+		t.Errorf("AverageAlignment=%.2f, esperado 25", report.AverageAlignment)
+	}
+	// Solo cuentan las medidas implantadas
+	if report.TotalCapEx != 400 || report.AlignedCapExPercentage != 100 {
+		t.Errorf("CapEx %v (%.2f%% alineado), esperado 400 (100%%)", report.TotalCapEx, report.AlignedCapExPercentage)
+	}
+
+	t.Logf("✓ GetPortfolioReport: %d tiendas, alineación media %.0f%%", report.TotalShops, report.AverageAlignment)
+}
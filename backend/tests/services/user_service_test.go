@@ -0,0 +1,163 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/application/services"
+	"github.com/d1mo22/climate-invest-optimizer/backend/internal/domain/models"
+)
+
+// adminID es el usuario que realiza las operaciones de administración en los tests
+const adminID int64 = 100
+
+func createUserService(t *testing.T) (services.UserService, services.AuthService, *mockUserRepoForAuth) {
+	t.Helper()
+	authSvc, _, users, sessions := createAuthServiceWithRepos(t)
+	users.users[adminID] = &models.User{ID: adminID, Email: "admin@example.com", Role: models.RoleAdmin, Active: true}
+	return services.NewUserService(users, sessions, &mockAuditRecorder{}), authSvc, users
+}
+
+// ============================================================================
+// USER SERVICE TESTS
+// ============================================================================
+
+func TestUserService_Invite_TemporaryPassword(t *testing.T) {
+	svc, authSvc, _ := createUserService(t)
+	ctx := context.Background()
+
+	credentials, err := svc.Invite(ctx, &models.InviteUserRequest{Email: "gestor@example.com", Role: models.RoleManager})
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if credentials.User.Role != models.RoleManager || credentials.User.Password != "" {
+		t.Errorf("Usuario inesperado: %+v", credentials.User)
+	}
+
+	// La contraseña temporal permite iniciar sesión
+	resp, err := authSvc.Login(ctx, &models.LoginRequest{Email: "gestor@example.com", Password: credentials.TemporaryPassword})
+	if err != nil {
+		t.Fatalf("La contraseña temporal debería ser válida: %v", err)
+	}
+	if resp.User.Role != models.RoleManager {
+		t.Errorf("Rol esperado manager, got %s", resp.User.Role)
+	}
+
+	_, err = svc.Invite(ctx, &models.InviteUserRequest{Email: "gestor@example.com", Role: models.RoleViewer})
+	if !errors.Is(err, models.ErrDuplicateEmail) {
+		t.Errorf("Se esperaba ErrDuplicateEmail, got %v", err)
+	}
+
+	t.Log("✓ Invite: usuario creado con contraseña temporal")
+}
+
+func TestUserService_ChangeRole_RevokesSessions(t *testing.T) {
+	svc, authSvc, _ := createUserService(t)
+	ctx := context.Background()
+	session := login(t, authSvc)
+
+	user, err := svc.ChangeRole(ctx, adminID, session.User.ID, models.RoleManager)
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if user.Role != models.RoleManager {
+		t.Errorf("Rol esperado manager, got %s", user.Role)
+	}
+
+	// El refresh token emitido con el rol anterior ya no es válido
+	if _, err := authSvc.Refresh(ctx, session.RefreshToken); err == nil {
+		t.Error("Las sesiones del usuario deberían haberse revocado")
+	}
+
+	t.Logf("✓ ChangeRole: %s -> %s", session.User.Role, user.Role)
+}
+
+func TestUserService_SelfModificationRejected(t *testing.T) {
+	svc, _, _ := createUserService(t)
+	ctx := context.Background()
+
+	if _, err := svc.ChangeRole(ctx, adminID, adminID, models.RoleViewer); !errors.Is(err, models.ErrSelfModification) {
+		t.Errorf("Se esperaba ErrSelfModification al cambiar el propio rol, got %v", err)
+	}
+	if _, err := svc.Deactivate(ctx, adminID, adminID); !errors.Is(err, models.ErrSelfModification) {
+		t.Errorf("Se esperaba ErrSelfModification al desactivarse, got %v", err)
+	}
+}
+
+func TestUserService_Deactivate_BlocksLogin(t *testing.T) {
+	svc, authSvc, _ := createUserService(t)
+	ctx := context.Background()
+	session := login(t, authSvc)
+
+	if _, err := svc.Deactivate(ctx, adminID, session.User.ID); err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+
+	_, err := authSvc.Login(ctx, &models.LoginRequest{Email: "ana@example.com", Password: "password123"})
+	if !errors.Is(err, models.ErrUserInactive) {
+		t.Errorf("Se esperaba ErrUserInactive, got %v", err)
+	}
+	if _, err := authSvc.Refresh(ctx, session.RefreshToken); err == nil {
+		t.Error("El usuario desactivado no debería poder refrescar su sesión")
+	}
+
+	if _, err := svc.Reactivate(ctx, session.User.ID); err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	login(t, authSvc)
+
+	t.Log("✓ Deactivate: login bloqueado hasta la reactivación")
+}
+
+func TestUserService_ResetPassword(t *testing.T) {
+	svc, authSvc, _ := createUserService(t)
+	ctx := context.Background()
+	session := login(t, authSvc)
+
+	credentials, err := svc.ResetPassword(ctx, session.User.ID)
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+
+	if _, err := authSvc.Login(ctx, &models.LoginRequest{Email: "ana@example.com", Password: "password123"}); err == nil {
+		t.Error("La contraseña anterior no debería ser válida")
+	}
+	if _, err := authSvc.Login(ctx, &models.LoginRequest{Email: "ana@example.com", Password: credentials.TemporaryPassword}); err != nil {
+		t.Errorf("La contraseña temporal debería ser válida: %v", err)
+	}
+}
+
+func TestUserService_NotFound(t *testing.T) {
+	svc, _, _ := createUserService(t)
+
+	if _, err := svc.ResetPassword(context.Background(), 999); !errors.Is(err, models.ErrUserNotFound) {
+		t.Errorf("Se esperaba ErrUserNotFound, got %v", err)
+	}
+}
+
+func TestUserService_SetScope_IssuedInNewTokens(t *testing.T) {
+	svc, authSvc, _ := createUserService(t)
+	ctx := context.Background()
+	session := login(t, authSvc)
+
+	user, err := svc.SetScope(ctx, session.User.ID, &models.SetUserScopeRequest{Countries: []string{"ES"}, ClusterIDs: []int64{2}})
+	if err != nil {
+		t.Fatalf("Error inesperado: %v", err)
+	}
+	if len(user.Countries) != 1 || len(user.ClusterIDs) != 1 {
+		t.Errorf("Ámbito inesperado: %+v", user)
+	}
+
+	// Los tokens previos no llevan el ámbito, por lo que se revocan
+	if _, err := authSvc.Refresh(ctx, session.RefreshToken); err == nil {
+		t.Error("Las sesiones del usuario deberían haberse revocado")
+	}
+
+	resp := login(t, authSvc)
+	if len(resp.User.Countries) != 1 || resp.User.Countries[0] != "ES" {
+		t.Errorf("El nuevo inicio de sesión debería incluir el ámbito, got %+v", resp.User)
+	}
+
+	t.Logf("✓ SetScope: países=%v clusters=%v", user.Countries, user.ClusterIDs)
+}